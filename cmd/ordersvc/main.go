@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+
+	"binance-redis-streamer/pkg/api"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/messaging"
+	"binance-redis-streamer/pkg/metrics"
+	"binance-redis-streamer/pkg/storage"
+)
+
+// ordersvc serves the read API over the data the streamer ingests. It runs
+// as the web dyno while cmd/streamer runs as the worker.
+func main() {
+	configPath := flag.String("config", "", "Path to a YAML config file (environment variables override it)")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: Error loading .env file: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	if *configPath != "" {
+		var err error
+		if cfg, err = config.LoadFile(*configPath); err != nil {
+			log.Fatalf("Failed to load configuration: %v", err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, cfg); err != nil {
+		log.Printf("Order service error: %v", err)
+		os.Exit(1)
+	}
+	log.Printf("Order service stopped")
+}
+
+// run serves the API until ctx is cancelled
+func run(ctx context.Context, cfg *config.Config) error {
+	redisStore, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create Redis store: %w", err)
+	}
+	defer redisStore.Close()
+
+	bus := messaging.NewRedisPubSub(redisStore.GetRedisClient())
+	bus.SetExchange(cfg.Exchange)
+
+	// Trades are stored by the streamer, so drop cached reads as they are published
+	store := storage.NewCachedTradeStore(redisStore, cfg.Cache)
+	go store.InvalidateOnTrades(ctx, bus)
+
+	exporter := metrics.NewMetricsExporter(cfg, redisStore.GetRedisClient())
+	go exporter.Start(ctx)
+
+	server := api.NewServer(cfg, store, bus)
+	// Candles of periods the trade history no longer covers come from
+	// PostgreSQL, when the streamer's database is configured
+	if os.Getenv("DATABASE_URL") != "" {
+		postgresStore, err := storage.NewPostgresStore()
+		if err != nil {
+			return fmt.Errorf("failed to create PostgreSQL store: %w", err)
+		}
+		defer postgresStore.Close()
+		postgresStore.SetExchange(cfg.Exchange)
+		postgresStore.SetDebug(cfg.Debug)
+		server.SetCandleStore(postgresStore)
+	}
+
+	return server.Start(ctx)
+}
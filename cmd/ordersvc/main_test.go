@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+// freeAddr returns a local address nothing is listening on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestRun_ServesEndpoints(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.API.Addr = freeAddr(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, cfg) }()
+
+	base := "http://" + cfg.API.Addr
+	waitFor := func(path string) int {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			resp, err := http.Get(base + path)
+			if err == nil {
+				resp.Body.Close()
+				return resp.StatusCode
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Service did not respond on %s: %v", path, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	for _, path := range []string{"/healthz", "/readyz", "/metrics", "/api/v1/symbols"} {
+		if status := waitFor(path); status != http.StatusOK {
+			t.Errorf("Expected 200 from %s, got %d", path, status)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Service did not shut down")
+	}
+}
@@ -3,52 +3,247 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/go-redis/redis/v8"
+
 	"binance-redis-streamer/pkg/config"
 	"binance-redis-streamer/pkg/storage"
 )
 
+// volumeEntry is a symbol and its 24h quote volume
+type volumeEntry struct {
+	Symbol string
+	Volume float64
+}
+
 func main() {
-	var symbol string
+	var (
+		cmd     string
+		symbol  string
+		limit   int
+		top     int
+		pattern string
+		venue   string
+	)
+	flag.StringVar(&cmd, "cmd", "trades", "Command to run: trades, latest, history, volumerank or keys")
 	flag.StringVar(&symbol, "symbol", "", "Symbol to view trades for (e.g., BTCUSDT)")
+	flag.IntVar(&limit, "limit", 10, "Maximum number of history entries to show (0 for all)")
+	flag.IntVar(&top, "top", 10, "Number of symbols to show in the volume ranking (0 for all)")
+	flag.StringVar(&pattern, "pattern", "", "Key pattern for the keys command (default: <prefix>*)")
+	flag.StringVar(&venue, "exchange", "binance", "Exchange whose data to read")
 	flag.Parse()
 
-	if symbol == "" {
-		fmt.Println("Please specify a symbol using -symbol flag")
-		os.Exit(1)
-	}
-
 	cfg := config.DefaultConfig()
+	cfg.SetExchange(venue)
 	store, err := storage.NewRedisStore(cfg)
 	if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 	defer store.Close()
 
-	// Get latest trade
-	trade, err := store.GetLatestTrade(context.Background(), symbol)
+	ctx := context.Background()
+
+	switch cmd {
+	case "trades":
+		requireSymbol(symbol)
+		showLatest(ctx, store, symbol)
+		showHistory(ctx, store, symbol, limit)
+	case "latest":
+		requireSymbol(symbol)
+		showLatest(ctx, store, symbol)
+	case "history":
+		requireSymbol(symbol)
+		showHistory(ctx, store, symbol, limit)
+	case "volumerank":
+		err = showVolumeRank(ctx, store.GetRedisClient(), cfg.Redis.KeyPrefix, top)
+	case "keys":
+		if pattern == "" {
+			pattern = cfg.Redis.KeyPrefix + "*"
+		}
+		err = showKeys(ctx, store.GetRedisClient(), pattern)
+	default:
+		fmt.Printf("Unknown command %q\n", cmd)
+		flag.Usage()
+		os.Exit(1)
+	}
+
 	if err != nil {
-		log.Printf("No latest trade found for %s: %v", symbol, err)
-	} else {
-		fmt.Printf("Latest trade for %s:\n", symbol)
-		printJSON(trade)
+		log.Fatalf("%s failed: %v", cmd, err)
+	}
+}
+
+func requireSymbol(symbol string) {
+	if symbol == "" {
+		fmt.Println("Please specify a symbol using -symbol flag")
+		os.Exit(1)
+	}
+}
+
+// showLatest prints the latest trade of a symbol
+func showLatest(ctx context.Context, store *storage.RedisStore, symbol string) {
+	trade, err := store.GetLatestTrade(ctx, symbol)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Printf("No latest trade found for %s", symbol)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get latest trade for %s: %v", symbol, err)
+		return
 	}
+	fmt.Printf("Latest trade for %s:\n", symbol)
+	printJSON(trade)
+}
 
-	// Get trade history
+// showHistory prints up to limit trades of the last hour
+func showHistory(ctx context.Context, store *storage.RedisStore, symbol string, limit int) {
 	end := time.Now()
 	start := end.Add(-1 * time.Hour)
-	history, err := store.GetTradeHistory(context.Background(), symbol, start, end)
+	history, err := store.GetTradeHistory(ctx, symbol, start, end)
 	if err != nil {
 		log.Printf("Failed to get trade history for %s: %v", symbol, err)
+		return
+	}
+
+	if limit > 0 && limit < len(history) {
+		history = history[:limit]
+	}
+
+	fmt.Printf("\nTrade history for %s (last hour, %d trades):\n", symbol, len(history))
+	for _, event := range history {
+		printJSON(event)
+	}
+}
+
+// showVolumeRank prints tracked symbols ranked by 24h volume
+func showVolumeRank(ctx context.Context, client redis.UniversalClient, prefix string, top int) error {
+	ranking, err := fetchVolumeRanking(ctx, client, prefix)
+	if err != nil {
+		return err
+	}
+	if len(ranking) == 0 {
+		fmt.Println("No 24h volume data found")
+		return nil
+	}
+
+	if top > 0 && top < len(ranking) {
+		ranking = ranking[:top]
+	}
+
+	fmt.Printf("%-6s %-12s %20s\n", "Rank", "Symbol", "24h Volume")
+	fmt.Println(strings.Repeat("-", 40))
+	for i, entry := range ranking {
+		fmt.Printf("%-6d %-12s %20.2f\n", i+1, entry.Symbol, entry.Volume)
+	}
+	return nil
+}
+
+// fetchVolumeRanking reads the 24h volume of every tracked symbol in a single
+// pipeline and returns them sorted by volume, highest first. Symbols without
+// volume data are skipped.
+func fetchVolumeRanking(ctx context.Context, client redis.UniversalClient, prefix string) ([]volumeEntry, error) {
+	symbols, err := client.SMembers(ctx, prefix+"symbols").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get symbols: %w", err)
+	}
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+
+	pipe := client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(symbols))
+	for i, symbol := range symbols {
+		cmds[i] = pipe.Get(ctx, fmt.Sprintf("%s%s:volume:24h", prefix, strings.ToUpper(symbol)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get volumes: %w", err)
+	}
+
+	ranking := make([]volumeEntry, 0, len(symbols))
+	for i, cmd := range cmds {
+		value, err := cmd.Result()
+		if err != nil {
+			continue
+		}
+		volume, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		ranking = append(ranking, volumeEntry{Symbol: strings.ToUpper(symbols[i]), Volume: volume})
+	}
+
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].Volume != ranking[j].Volume {
+			return ranking[i].Volume > ranking[j].Volume
+		}
+		return ranking[i].Symbol < ranking[j].Symbol
+	})
+	return ranking, nil
+}
+
+// showKeys prints keys matching pattern
+func showKeys(ctx context.Context, client redis.UniversalClient, pattern string) error {
+	keys, err := scanKeys(ctx, client, pattern)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		fmt.Println(key)
+	}
+	fmt.Printf("\n%d keys match %q\n", len(keys), pattern)
+	return nil
+}
+
+// scanKeys iterates keys with SCAN instead of KEYS so large production
+// databases are not blocked. SCAN is node-local, so in a cluster every
+// master is scanned.
+func scanKeys(ctx context.Context, client redis.UniversalClient, pattern string) ([]string, error) {
+	var keys []string
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		var mu sync.Mutex
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			nodeKeys, err := scanNode(ctx, node, pattern)
+			mu.Lock()
+			keys = append(keys, nodeKeys...)
+			mu.Unlock()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
 	} else {
-		fmt.Printf("\nTrade history for %s (last hour):\n", symbol)
-		for _, event := range history {
-			printJSON(event)
+		var err error
+		if keys, err = scanNode(ctx, client, pattern); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// scanNode returns the keys of a single node matching pattern
+func scanNode(ctx context.Context, client redis.UniversalClient, pattern string) ([]string, error) {
+	var keys []string
+	var cursor uint64
+	for {
+		batch, next, err := client.Scan(ctx, cursor, pattern, 100).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan keys: %w", err)
+		}
+		keys = append(keys, batch...)
+
+		cursor = next
+		if cursor == 0 {
+			return keys, nil
 		}
 	}
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func setupTestClient(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+	return client, mr
+}
+
+func TestFetchVolumeRanking(t *testing.T) {
+	client, mr := setupTestClient(t)
+
+	mr.SAdd("binance:symbols", "BTCUSDT", "ETHUSDT", "SOLUSDT", "NEWUSDT")
+	mr.Set("binance:BTCUSDT:volume:24h", "900.50")
+	mr.Set("binance:ETHUSDT:volume:24h", "1200.00")
+	mr.Set("binance:SOLUSDT:volume:24h", "15.25")
+
+	ranking, err := fetchVolumeRanking(context.Background(), client, "binance:")
+	if err != nil {
+		t.Fatalf("fetchVolumeRanking failed: %v", err)
+	}
+
+	var got []string
+	for _, entry := range ranking {
+		got = append(got, entry.Symbol)
+	}
+	if strings.Join(got, ",") != "ETHUSDT,BTCUSDT,SOLUSDT" {
+		t.Errorf("Expected ranking ETHUSDT,BTCUSDT,SOLUSDT (symbols without volume skipped), got %v", got)
+	}
+	if ranking[0].Volume != 1200 {
+		t.Errorf("Expected top volume 1200, got %v", ranking[0].Volume)
+	}
+}
+
+func TestScanKeys(t *testing.T) {
+	client, mr := setupTestClient(t)
+
+	for i := 0; i < 250; i++ {
+		mr.Set("binance:key:"+strings.Repeat("x", i%5)+string(rune('a'+i%26))+string(rune('a'+i/26)), "1")
+	}
+	mr.Set("other:key", "1")
+
+	keys, err := scanKeys(context.Background(), client, "binance:*")
+	if err != nil {
+		t.Fatalf("scanKeys failed: %v", err)
+	}
+	if len(keys) != 250 {
+		t.Errorf("Expected 250 matching keys across scan pages, got %d", len(keys))
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "binance:") {
+			t.Errorf("Unexpected key %q", key)
+		}
+	}
+}
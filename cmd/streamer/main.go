@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
@@ -11,23 +14,38 @@ import (
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"binance-redis-streamer/internal/jsoncodec"
 	"binance-redis-streamer/pkg/binance"
 	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/debug"
+	"binance-redis-streamer/pkg/exchange"
 	"binance-redis-streamer/pkg/ingestion"
-	"binance-redis-streamer/pkg/metrics"
 	"binance-redis-streamer/pkg/processor"
 	"binance-redis-streamer/pkg/storage"
+	"binance-redis-streamer/pkg/version"
 )
 
 func main() {
+	configPath := flag.String("config", "", "Path to a YAML config file (environment variables override it)")
+	flag.Parse()
+	log.Printf("Starting streamer v%s (JSON codec: %s)", version.Version, jsoncodec.Name)
+
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Warning: Error loading .env file: %v", err)
 	}
 
 	// Load configuration
-	cfg := loadConfig()
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	// Checked after the environment overrides, which can set invalid values
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// Create Redis store
 	redisStore, err := storage.NewRedisStore(cfg)
@@ -42,18 +60,33 @@ func main() {
 		log.Fatalf("Failed to create PostgreSQL store: %v", err)
 	}
 	defer postgresStore.Close()
+	postgresStore.SetExchange(cfg.Exchange)
+	postgresStore.SetWriter(cfg.Postgres.CandleWriter)
+	if cfg.Postgres.CompressAfter > 0 {
+		if err := postgresStore.EnableTimescaleCompression(context.Background(), cfg.Postgres.CompressAfter); err != nil {
+			log.Printf("Warning: candle compression not enabled: %v", err)
+		}
+	}
 
 	// Create trade aggregator
 	aggregator := storage.NewTradeAggregator(redisStore, postgresStore)
+	aggregator.SetCandleRetention(cfg.Postgres)
+	aggregator.SetMaxBufferedCandles(cfg.Postgres.MaxBufferedCandles)
+	aggregator.SetWriteBreaker(cfg.Breaker)
 
-	// Create metrics exporter
-	exporter := metrics.NewMetricsExporter(cfg, redisStore.GetRedisClient())
-
-	// Create Binance client
-	client := binance.NewClient(cfg, redisStore)
+	// Create exchange client
+	client, err := newExchangeClient(cfg, redisStore)
+	if err != nil {
+		log.Fatalf("Failed to create exchange client: %v", err)
+	}
 
 	// Create ingestion service
 	ingestService := ingestion.NewService(cfg, client, redisStore)
+	symbolSource, err := exchange.NewSymbolSource(cfg.Binance, client)
+	if err != nil {
+		log.Fatalf("Failed to set up symbol source: %v", err)
+	}
+	ingestService.SetSymbolSource(symbolSource)
 
 	// Create processor service
 	processService := processor.NewService(cfg, redisStore, aggregator)
@@ -62,25 +95,49 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Start metrics collection
-	go exporter.Start(ctx)
+	// Start debug server
+	if cfg.DebugAddr != "" {
+		debugServer := debug.NewServer(cfg.DebugAddr)
+		debugServer.Handle("/metrics", promhttp.Handler())
+		debugServer.Handle("/debug/symbols", debug.JSON(func(r *http.Request) (interface{}, error) {
+			return processService.Snapshot(r.URL.Query().Get("reset") == "true"), nil
+		}))
+		debugServer.Handle("/debug/connections", debug.JSON(func(r *http.Request) (interface{}, error) {
+			return ingestService.Connections(), nil
+		}))
 
-	// Start trade aggregator
-	go aggregator.Start(ctx)
+		go func() {
+			if err := debugServer.Start(ctx); err != nil {
+				log.Printf("Debug server error: %v", err)
+			}
+		}()
+	}
 
-	// Start processor service
-	go func() {
-		if err := processService.Start(ctx); err != nil {
-			log.Printf("Processor service error: %v", err)
-			cancel()
-		}
-	}()
+	// Process trades and write candles alongside streaming, so with leader
+	// election only the leader does
+	ingestService.AddLeaderTask(func(ctx context.Context) error {
+		return runProcessing(ctx, processService, aggregator)
+	})
+
+	// Track the account's own orders and balances when an API key is set
+	if binanceClient, ok := client.(*binance.Client); ok && cfg.Binance.APIKey != "" {
+		log.Printf("Streaming user data (orders and balances) for the configured API key")
+		ingestService.AddLeaderTask(func(ctx context.Context) error {
+			if err := binanceClient.StreamUserData(ctx, redisStore); err != nil && ctx.Err() == nil {
+				log.Printf("User-data stream error: %v", err)
+			}
+			return nil
+		})
+	}
 
-	// Start ingestion service
+	// Start ingestion service; a failure here exits non-zero so the platform restarts us
+	ingestErr := make(chan error, 1)
+	ingestDone := make(chan struct{})
 	go func() {
-		if err := ingestService.Start(ctx); err != nil {
+		defer close(ingestDone)
+		if err := ingestService.Start(ctx); err != nil && ctx.Err() == nil {
 			log.Printf("Ingestion service error: %v", err)
-			cancel()
+			ingestErr <- err
 		}
 	}()
 
@@ -88,20 +145,80 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	sig := <-sigChan
-	log.Printf("Received signal %v, shutting down...", sig)
+	exitCode := 0
+	select {
+	case sig := <-sigChan:
+		log.Printf("Received signal %v, shutting down...", sig)
+	case <-ingestErr:
+		log.Printf("Ingestion failed, shutting down...")
+		exitCode = 1
+	}
 	cancel()
 
+	// Ingestion returns once its trades were processed and their candles
+	// written; bound the wait so a stuck write cannot block shutdown
+	select {
+	case <-ingestDone:
+	case <-time.After(shutdownTimeout):
+		log.Printf("Timed out waiting for ingestion to stop")
+	}
+
 	// Stop services
-	processService.Stop()
 	ingestService.Stop()
+	processService.Stop()
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// shutdownTimeout bounds how long shutdown waits for trades in flight to be
+// processed and their candles written
+const shutdownTimeout = 30 * time.Second
+
+// runProcessing processes the streamed trades and aggregates them into
+// candles until ctx is cancelled, then writes every buffered candle so
+// another replica can take over
+func runProcessing(ctx context.Context, processService *processor.Service, aggregator *storage.TradeAggregator) error {
+	aggregatorDone := make(chan struct{})
+	go func() {
+		defer close(aggregatorDone)
+		aggregator.Start(ctx)
+	}()
 
-	// Allow some time for cleanup
-	time.Sleep(5 * time.Second)
+	err := processService.Start(ctx)
+	if ctx.Err() == nil {
+		return fmt.Errorf("processor service: %w", err)
+	}
+	<-aggregatorDone
+
+	processService.FlushPending()
+	flushCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := aggregator.FlushAll(flushCtx); err != nil {
+		log.Printf("Error flushing candles: %v", err)
+	}
+	return nil
+}
+
+// newExchangeClient returns the client for the configured exchange
+func newExchangeClient(cfg *config.Config, store *storage.RedisStore) (exchange.Client, error) {
+	switch cfg.Exchange {
+	case "binance":
+		return binance.NewClient(cfg, store), nil
+	default:
+		return nil, fmt.Errorf("unsupported exchange %q", cfg.Exchange)
+	}
 }
 
-func loadConfig() *config.Config {
+func loadConfig(path string) (*config.Config, error) {
 	cfg := config.DefaultConfig()
+	if path != "" {
+		var err error
+		if cfg, err = config.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
 
 	// Override configuration from environment variables
 	if maxSymbols := os.Getenv("MAX_SYMBOLS"); maxSymbols != "" {
@@ -116,5 +233,71 @@ func loadConfig() *config.Config {
 		}
 	}
 
-	return cfg
+	if maxWrites := os.Getenv("REDIS_MAX_WRITES_PER_SEC"); maxWrites != "" {
+		if val, err := strconv.ParseFloat(maxWrites, 64); err == nil {
+			cfg.Redis.MaxWritesPerSec = val
+		}
+	}
+
+	if batchSize := os.Getenv("REDIS_PUBLISH_BATCH_SIZE"); batchSize != "" {
+		if val, err := strconv.Atoi(batchSize); err == nil {
+			cfg.Redis.PublishBatchSize = val
+		}
+	}
+
+	if batchDelay := os.Getenv("REDIS_PUBLISH_BATCH_DELAY"); batchDelay != "" {
+		if val, err := time.ParseDuration(batchDelay); err == nil {
+			cfg.Redis.PublishBatchDelay = val
+		}
+	}
+
+	if candleDays := os.Getenv("CANDLE_RETENTION_DAYS"); candleDays != "" {
+		if val, err := strconv.Atoi(candleDays); err == nil {
+			cfg.Postgres.CandleRetention = time.Duration(val) * 24 * time.Hour
+		}
+	}
+
+	if compressDays := os.Getenv("TIMESCALE_COMPRESS_AFTER_DAYS"); compressDays != "" {
+		if val, err := strconv.Atoi(compressDays); err == nil {
+			cfg.Postgres.CompressAfter = time.Duration(val) * 24 * time.Hour
+		}
+	}
+
+	if refreshInterval := os.Getenv("SYMBOL_REFRESH_INTERVAL"); refreshInterval != "" {
+		if val, err := time.ParseDuration(refreshInterval); err == nil {
+			cfg.Binance.SymbolRefreshInterval = val
+		}
+	}
+
+	if silence := os.Getenv("WATCHDOG_SILENCE"); silence != "" {
+		if val, err := time.ParseDuration(silence); err == nil {
+			cfg.Ingestion.WatchdogSilence = val
+		}
+	}
+
+	if maxRestarts := os.Getenv("WATCHDOG_MAX_RESTARTS"); maxRestarts != "" {
+		if val, err := strconv.Atoi(maxRestarts); err == nil {
+			cfg.Ingestion.WatchdogMaxRestarts = val
+		}
+	}
+
+	if queueSize := os.Getenv("PUBLISH_QUEUE_SIZE"); queueSize != "" {
+		if val, err := strconv.Atoi(queueSize); err == nil {
+			cfg.Ingestion.PublishQueueSize = val
+		}
+	}
+
+	if workers := os.Getenv("PUBLISH_WORKERS"); workers != "" {
+		if val, err := strconv.Atoi(workers); err == nil {
+			cfg.Ingestion.PublishWorkers = val
+		}
+	}
+
+	if threshold := os.Getenv("DLQ_ALERT_THRESHOLD"); threshold != "" {
+		if val, err := strconv.ParseInt(threshold, 10, 64); err == nil {
+			cfg.Processor.DLQAlertThreshold = val
+		}
+	}
+
+	return cfg, nil
 }
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// versionFile is the Go file whose Version constant --bump keeps in step
+// with project.json
+const versionFile = "pkg/version/version.go"
+
 type Project struct {
 	Name         string            `json:"name"`
 	Version      string            `json:"version"`
@@ -25,78 +35,201 @@ type Repository struct {
 	URL  string `json:"url"`
 }
 
+// VersionInfo describes the current build
+type VersionInfo struct {
+	Module    string `json:"module"`
+	GoVersion string `json:"go_version"`
+	GitTag    string `json:"git_tag"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+}
+
 func main() {
 	bumpType := flag.String("bump", "", "Version bump type: major, minor, or patch")
+	info := flag.Bool("info", false, "Print version info as JSON")
+	fromGoMod := flag.Bool("from-gomod", false, "Read the module and Go version for --info from go.mod instead of project.json")
 	flag.Parse()
 
+	if *info {
+		versionInfo, err := loadVersionInfo(*fromGoMod)
+		if err != nil {
+			fmt.Printf("Error reading version info: %v\n", err)
+			os.Exit(1)
+		}
+		data, _ := json.MarshalIndent(versionInfo, "", "    ")
+		fmt.Println(string(data))
+		return
+	}
+
 	if *bumpType == "" {
 		fmt.Println("Please specify a bump type: major, minor, or patch")
 		os.Exit(1)
 	}
 
-	// Read project.json
-	data, err := os.ReadFile("project.json")
+	project, err := readProject()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	version, err := bumpVersion(project.Version, *bumpType)
 	if err != nil {
-		fmt.Printf("Error reading project.json: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Update version
+	project.Version = version
+
+	// Write back to project.json
+	updatedData, err := json.MarshalIndent(project, "", "    ")
+	if err != nil {
+		fmt.Printf("Error encoding project data: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile("project.json", updatedData, 0600); err != nil {
+		fmt.Printf("Error writing project.json: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Keep the Go constant in step
+	src, err := os.ReadFile(versionFile)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", versionFile, err)
+		os.Exit(1)
+	}
+	src, err = setVersionConst(src, project.Version)
+	if err != nil {
+		fmt.Printf("Error updating %s: %v\n", versionFile, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(versionFile, src, 0600); err != nil {
+		fmt.Printf("Error writing %s: %v\n", versionFile, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Version bumped to %s\n", project.Version)
+}
+
+// readProject reads project.json
+func readProject() (*Project, error) {
+	data, err := os.ReadFile("project.json")
+	if err != nil {
+		return nil, fmt.Errorf("reading project.json: %w", err)
+	}
+
 	var project Project
 	if err := json.Unmarshal(data, &project); err != nil {
-		fmt.Printf("Error parsing project.json: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("parsing project.json: %w", err)
 	}
+	return &project, nil
+}
 
-	// Parse current version
-	parts := strings.Split(project.Version, ".")
+// bumpVersion returns version with its major, minor or patch part bumped
+func bumpVersion(version, bumpType string) (string, error) {
+	parts := strings.Split(version, ".")
 	if len(parts) != 3 {
-		fmt.Println("Invalid version format in project.json")
-		os.Exit(1)
+		return "", fmt.Errorf("invalid version format %q", version)
 	}
 
-	major := parts[0]
-	minor := parts[1]
-	patch := parts[2]
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid version format %q", version)
+		}
+		nums[i] = n
+	}
 
-	// Bump version
-	switch *bumpType {
+	switch bumpType {
 	case "major":
-		major = fmt.Sprintf("%d", atoi(major)+1)
-		minor = "0"
-		patch = "0"
+		nums = [3]int{nums[0] + 1, 0, 0}
 	case "minor":
-		minor = fmt.Sprintf("%d", atoi(minor)+1)
-		patch = "0"
+		nums = [3]int{nums[0], nums[1] + 1, 0}
 	case "patch":
-		patch = fmt.Sprintf("%d", atoi(patch)+1)
+		nums[2]++
 	default:
-		fmt.Printf("Invalid bump type: %s\n", *bumpType)
-		os.Exit(1)
+		return "", fmt.Errorf("invalid bump type: %s", bumpType)
 	}
+	return fmt.Sprintf("%d.%d.%d", nums[0], nums[1], nums[2]), nil
+}
 
-	// Update version
-	project.Version = fmt.Sprintf("%s.%s.%s", major, minor, patch)
+// versionConst matches the Version constant of versionFile
+var versionConst = regexp.MustCompile(`(?m)^(const Version = )"[^"]*"`)
 
-	// Write back to project.json
-	updatedData, err := json.MarshalIndent(project, "", "    ")
-	if err != nil {
-		fmt.Printf("Error encoding project data: %v\n", err)
-		os.Exit(1)
+// setVersionConst rewrites the Version constant in src to version
+func setVersionConst(src []byte, version string) ([]byte, error) {
+	if !versionConst.Match(src) {
+		return nil, fmt.Errorf("no Version constant found")
 	}
+	return versionConst.ReplaceAll(src, []byte(`${1}"`+version+`"`)), nil
+}
 
-	if err := os.WriteFile("project.json", updatedData, 0600); err != nil {
-		fmt.Printf("Error writing project.json: %v\n", err)
-		os.Exit(1)
+// parseGoMod returns the module path and go directive of a go.mod file
+func parseGoMod(data []byte) (module, goVersion string, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "module":
+			module = strings.Trim(fields[1], `"`)
+		case "go":
+			goVersion = fields[1]
+		}
 	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+	if module == "" {
+		return "", "", fmt.Errorf("no module directive")
+	}
+	return module, goVersion, nil
+}
 
-	fmt.Printf("Version bumped to %s\n", project.Version)
+// loadVersionInfo describes the working tree, taking the module and Go
+// version from go.mod or project.json and the tag and commit from git
+func loadVersionInfo(fromGoMod bool) (*VersionInfo, error) {
+	info := &VersionInfo{
+		GitTag:    gitOutput("describe", "--tags"),
+		GitCommit: gitOutput("rev-parse", "--short", "HEAD"),
+		BuildTime: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if fromGoMod {
+		data, err := os.ReadFile("go.mod")
+		if err != nil {
+			return nil, fmt.Errorf("reading go.mod: %w", err)
+		}
+		if info.Module, info.GoVersion, err = parseGoMod(data); err != nil {
+			return nil, fmt.Errorf("parsing go.mod: %w", err)
+		}
+		return info, nil
+	}
+
+	project, err := readProject()
+	if err != nil {
+		return nil, err
+	}
+	info.Module = project.Name
+	info.GoVersion = project.Dependencies["go"]
+	return info, nil
 }
 
-func atoi(s string) int {
-	var n int
-	if _, err := fmt.Sscanf(s, "%d", &n); err != nil {
-		fmt.Printf("Error parsing number: %v\n", err)
-		os.Exit(1)
+// gitOutput runs git with args and returns its trimmed output, or "" when
+// git fails, e.g. outside a repository or without tags
+func gitOutput(args ...string) string {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return ""
 	}
-	return n
+	return strings.TrimSpace(string(out))
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBumpVersion(t *testing.T) {
+	tests := []struct {
+		version, bump, want string
+	}{
+		{"1.1.5", "patch", "1.1.6"},
+		{"1.1.5", "minor", "1.2.0"},
+		{"1.1.5", "major", "2.0.0"},
+		{"0.9.9", "patch", "0.9.10"},
+	}
+	for _, tt := range tests {
+		got, err := bumpVersion(tt.version, tt.bump)
+		if err != nil || got != tt.want {
+			t.Errorf("bumpVersion(%q, %q) = %q, %v; want %q", tt.version, tt.bump, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range [][2]string{{"1.1", "patch"}, {"1.x.5", "patch"}, {"1.1.5", "huge"}} {
+		if _, err := bumpVersion(bad[0], bad[1]); err == nil {
+			t.Errorf("Expected an error for bumpVersion(%q, %q)", bad[0], bad[1])
+		}
+	}
+}
+
+func TestSetVersionConst(t *testing.T) {
+	src := []byte("package version\n\n// Version is the release version\nconst Version = \"1.1.5\"\n")
+
+	got, err := setVersionConst(src, "1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(string(src), "1.1.5", "1.2.0", 1); string(got) != want {
+		t.Errorf("Unexpected file after bump:\n%s", got)
+	}
+
+	if _, err := setVersionConst([]byte("package version\n"), "1.2.0"); err == nil {
+		t.Error("Expected an error without a Version constant")
+	}
+}
+
+func TestParseGoMod(t *testing.T) {
+	data := []byte(`// Streams Binance trades
+module "binance-redis-streamer" // quoted paths are allowed
+
+go 1.20
+
+require (
+	github.com/go-redis/redis/v8 v8.11.5
+)
+`)
+	module, goVersion, err := parseGoMod(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if module != "binance-redis-streamer" || goVersion != "1.20" {
+		t.Errorf("Expected binance-redis-streamer and 1.20, got %q and %q", module, goVersion)
+	}
+
+	if _, _, err := parseGoMod([]byte("go 1.20\n")); err == nil {
+		t.Error("Expected an error without a module directive")
+	}
+}
@@ -0,0 +1,10 @@
+// Package jsoncodec is the JSON codec of the trade hot path: encoding and
+// decoding trade events and stored trades. It uses encoding/json unless the
+// binaries are built with the gojson tag, which switches to the faster but
+// drop-in compatible github.com/goccy/go-json:
+//
+//	go build -tags gojson ./cmd/streamer
+//
+// Both honour json.Marshaler and json.Unmarshaler, so custom methods such as
+// AggTradeEvent.UnmarshalJSON behave the same under either codec.
+package jsoncodec
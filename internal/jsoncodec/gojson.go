@@ -0,0 +1,18 @@
+//go:build gojson
+
+package jsoncodec
+
+import json "github.com/goccy/go-json"
+
+// Name identifies the codec in use
+const Name = "goccy/go-json"
+
+// Marshal returns the JSON encoding of v
+func Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes the JSON data into v
+func Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Anomaly kinds
+const (
+	AnomalyOutsizedTrade = "outsized_trade"
+	AnomalyPriceJump     = "price_jump"
+)
+
+// AnomalyEvent is a trade flagged by the anomaly detector
+type AnomalyEvent struct {
+	Kind    string    `json:"kind"` // AnomalyOutsizedTrade or AnomalyPriceJump
+	Symbol  string    `json:"symbol"`
+	Time    time.Time `json:"time"` // Time of the flagged trade
+	TradeID int64     `json:"trade_id"`
+	Price   float64   `json:"price"`
+
+	// Outsized trades: the trade's notional, the average it is compared
+	// with and how many standard deviations above it the trade is
+	Notional     float64 `json:"notional,omitempty"`
+	MeanNotional float64 `json:"mean_notional,omitempty"`
+	Sigmas       float64 `json:"sigmas,omitempty"`
+
+	// Price jumps: the window's high or low the price moved from, the move
+	// in percent (negative for drops) and the window
+	ReferencePrice float64       `json:"reference_price,omitempty"`
+	ChangePercent  float64       `json:"change_percent,omitempty"`
+	Window         time.Duration `json:"window,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// BookTicker is a bookTicker update: the best bid and ask of a symbol
+type BookTicker struct {
+	UpdateID int64  `json:"u"`
+	Symbol   string `json:"s"`
+	BidPrice string `json:"b"`
+	BidQty   string `json:"B"`
+	AskPrice string `json:"a"`
+	AskQty   string `json:"A"`
+}
+
+// BookTickerEvent is a bookTicker update on a combined stream
+type BookTickerEvent struct {
+	Stream string     `json:"stream"`
+	Data   BookTicker `json:"data"`
+}
+
+// TopOfBook returns the ticker's spread (ask - bid) and its top-of-book
+// imbalance, (bid qty - ask qty) / (bid qty + ask qty), from -1 when only
+// asks are quoted to 1 when only bids are
+func (t *BookTicker) TopOfBook() (spread, imbalance float64, err error) {
+	values := make([]float64, 4)
+	for i, field := range []struct{ name, value string }{
+		{"bid price", t.BidPrice},
+		{"bid quantity", t.BidQty},
+		{"ask price", t.AskPrice},
+		{"ask quantity", t.AskQty},
+	} {
+		values[i], err = strconv.ParseFloat(field.value, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s %q: %w", field.name, field.value, err)
+		}
+	}
+	bid, bidQty, ask, askQty := values[0], values[1], values[2], values[3]
+
+	if total := bidQty + askQty; total > 0 {
+		imbalance = (bidQty - askQty) / total
+	}
+	return ask - bid, imbalance, nil
+}
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Candle JSON is shared by the chart's /api/data and the REST API's
+// /api/v1/symbols/{symbol}/candles:
+//
+//	{
+//	  "timestamp": "2024-01-01T00:00:00Z", // Candle start, RFC3339 in UTC
+//	  "time_ms": 1704067200000,            // Candle start, Unix milliseconds
+//	  "open": "42000.5",                   // Prices and volume are decimal
+//	  "high": "42100",                     // strings, or JSON numbers with
+//	  "low": "41950.25",                   // the numeric encoding
+//	  "close": "42050",
+//	  "volume": "12.5",
+//	  "trade_count": 42
+//	}
+//
+// Strings keep the exact decimals stored; numbers suit clients that chart
+// them directly. A candle without trades has empty prices, or null ones
+// with the numeric encoding.
+
+// candleJSON is the JSON shape of a Candle
+type candleJSON struct {
+	Timestamp  string          `json:"timestamp"`
+	TimeMs     int64           `json:"time_ms"`
+	Open       json.RawMessage `json:"open"`
+	High       json.RawMessage `json:"high"`
+	Low        json.RawMessage `json:"low"`
+	Close      json.RawMessage `json:"close"`
+	Volume     json.RawMessage `json:"volume"`
+	TradeCount int64           `json:"trade_count"`
+}
+
+// MarshalJSON encodes the candle with string prices and volume
+func (c Candle) MarshalJSON() ([]byte, error) {
+	return c.marshalJSON(false)
+}
+
+// marshalJSON encodes the candle, with prices and volume as JSON numbers
+// when numeric is set
+func (c Candle) marshalJSON(numeric bool) ([]byte, error) {
+	out := candleJSON{
+		Timestamp:  c.Timestamp.UTC().Format(time.RFC3339),
+		TimeMs:     c.Timestamp.UnixMilli(),
+		TradeCount: c.TradeCount,
+	}
+
+	fields := []struct {
+		dst   *json.RawMessage
+		name  string
+		value string
+	}{
+		{&out.Open, "open", c.OpenPrice},
+		{&out.High, "high", c.HighPrice},
+		{&out.Low, "low", c.LowPrice},
+		{&out.Close, "close", c.ClosePrice},
+		{&out.Volume, "volume", c.Volume},
+	}
+	for _, f := range fields {
+		encoded, err := encodeDecimal(f.value, numeric)
+		if err != nil {
+			return nil, fmt.Errorf("invalid candle %s: %w", f.name, err)
+		}
+		*f.dst = encoded
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a candle in either encoding. The timestamp is taken
+// from "timestamp", or from "time_ms" when it is missing.
+func (c *Candle) UnmarshalJSON(data []byte) error {
+	var in candleJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	candle := Candle{TradeCount: in.TradeCount}
+	switch {
+	case in.Timestamp != "":
+		timestamp, err := time.Parse(time.RFC3339, in.Timestamp)
+		if err != nil {
+			return fmt.Errorf("invalid candle timestamp: %w", err)
+		}
+		candle.Timestamp = timestamp
+	default:
+		candle.Timestamp = time.UnixMilli(in.TimeMs).UTC()
+	}
+
+	fields := []struct {
+		dst  *string
+		name string
+		raw  json.RawMessage
+	}{
+		{&candle.OpenPrice, "open", in.Open},
+		{&candle.HighPrice, "high", in.High},
+		{&candle.LowPrice, "low", in.Low},
+		{&candle.ClosePrice, "close", in.Close},
+		{&candle.Volume, "volume", in.Volume},
+	}
+	for _, f := range fields {
+		value, err := decodeDecimal(f.raw)
+		if err != nil {
+			return fmt.Errorf("invalid candle %s: %w", f.name, err)
+		}
+		*f.dst = value
+	}
+
+	*c = candle
+	return nil
+}
+
+// encodeDecimal encodes a decimal string as a JSON string or, when numeric
+// is set, as a JSON number with the same digits
+func encodeDecimal(value string, numeric bool) (json.RawMessage, error) {
+	if !numeric {
+		return json.Marshal(value)
+	}
+	if value == "" {
+		return json.RawMessage("null"), nil
+	}
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return nil, err
+	}
+	// ParseFloat accepts forms JSON numbers do not, such as "Inf" or "0x1p3"
+	var number json.Number
+	if err := json.Unmarshal([]byte(value), &number); err != nil {
+		return nil, fmt.Errorf("%q is not a JSON number", value)
+	}
+	return json.RawMessage(value), nil
+}
+
+// decodeDecimal decodes a JSON string, number or null into a decimal string
+func decodeDecimal(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	if raw[0] == '"' {
+		var value string
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	}
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err != nil {
+		return "", err
+	}
+	return number.String(), nil
+}
+
+// CandleSeries is the JSON response of candle endpoints: a symbol and its
+// candles, oldest first
+type CandleSeries struct {
+	Symbol  string    `json:"symbol"`
+	Candles []*Candle `json:"candles"`
+	Style   string    `json:"style,omitempty"` // How the candles are drawn, e.g. by the chart command
+	Numeric bool      `json:"-"`               // Encode prices and volume as JSON numbers
+}
+
+// MarshalJSON encodes the series, with an empty list when there are no
+// candles
+func (s CandleSeries) MarshalJSON() ([]byte, error) {
+	candles := make([]json.RawMessage, len(s.Candles))
+	for i, candle := range s.Candles {
+		encoded, err := candle.marshalJSON(s.Numeric)
+		if err != nil {
+			return nil, err
+		}
+		candles[i] = encoded
+	}
+
+	return json.Marshal(struct {
+		Symbol  string            `json:"symbol"`
+		Candles []json.RawMessage `json:"candles"`
+		Style   string            `json:"style,omitempty"`
+	}{s.Symbol, candles, s.Style})
+}
+
+// MinuteCandles groups trades, in any order, into one-minute candles, oldest
+// first
+func MinuteCandles(trades []AggTradeEvent) []*Candle {
+	// Opens and closes follow trade time, whatever order history is read in
+	sorted := make([]*TradeData, len(trades))
+	for i := range trades {
+		sorted[i] = &trades[i].Data
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].TradeTime < sorted[j].TradeTime })
+
+	byMinute := make(map[time.Time]*Candle)
+	for _, data := range sorted {
+		trade := data.ToTrade()
+		minute := trade.Time.Truncate(time.Minute)
+		candle, ok := byMinute[minute]
+		if !ok {
+			candle = NewCandle(minute)
+			byMinute[minute] = candle
+		}
+		candle.UpdateFromTrade(trade)
+	}
+
+	candles := make([]*Candle, 0, len(byMinute))
+	for _, candle := range byMinute {
+		candles = append(candles, candle)
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Timestamp.Before(candles[j].Timestamp) })
+	return candles
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "Rewrite golden files")
+
+// testSeries has a traded candle and one without trades
+func testSeries(numeric bool) CandleSeries {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return CandleSeries{
+		Symbol: "BTCUSDT",
+		Candles: []*Candle{
+			{Timestamp: start, OpenPrice: "42000.5", HighPrice: "42100", LowPrice: "41950.25", ClosePrice: "42050", Volume: "12.5", TradeCount: 42},
+			NewCandle(start.Add(time.Minute)),
+		},
+		Numeric: numeric,
+	}
+}
+
+// checkGolden compares data, indented, with testdata/name
+func checkGolden(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	indented.WriteByte('\n')
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, indented.Bytes(), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(indented.Bytes(), want) {
+		t.Errorf("%s mismatch (run with -update to accept):\ngot:\n%s\nwant:\n%s", name, indented.Bytes(), want)
+	}
+}
+
+func TestCandleSeriesJSON_Golden(t *testing.T) {
+	for name, numeric := range map[string]bool{"candles.golden.json": false, "candles_numeric.golden.json": true} {
+		t.Run(name, func(t *testing.T) {
+			series := testSeries(numeric)
+			data, err := json.Marshal(series)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, name, data)
+
+			// Either encoding decodes back to the same candles
+			var decoded CandleSeries
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			decoded.Numeric = numeric
+			if !reflect.DeepEqual(decoded, series) {
+				t.Errorf("Round trip = %+v, want %+v", decoded, series)
+			}
+		})
+	}
+}
+
+func TestCandleJSON_TimeMsOnly(t *testing.T) {
+	var candle Candle
+	if err := json.Unmarshal([]byte(`{"time_ms":1704067260000,"open":1.5,"volume":"2"}`), &candle); err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC)
+	if !candle.Timestamp.Equal(want) || candle.OpenPrice != "1.5" || candle.Volume != "2" {
+		t.Errorf("Unexpected candle %+v", candle)
+	}
+}
+
+func TestCandleJSON_InvalidDecimals(t *testing.T) {
+	for _, price := range []string{"Inf", "0x1p3", "abc"} {
+		series := CandleSeries{Candles: []*Candle{{OpenPrice: price, Volume: "1"}}, Numeric: true}
+		if _, err := json.Marshal(series); err == nil {
+			t.Errorf("Expected an error encoding price %q as a number", price)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"timestamp":"yesterday"}`), &Candle{}); err == nil {
+		t.Error("Expected an error for an invalid timestamp")
+	}
+}
+
+func TestMinuteCandles(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	event := func(offset time.Duration, price, quantity string) AggTradeEvent {
+		return AggTradeEvent{Data: TradeData{
+			Symbol:    "BTCUSDT",
+			Price:     price,
+			Quantity:  quantity,
+			TradeTime: start.Add(offset).UnixMilli(),
+		}}
+	}
+	// Newest first, as trade history is read
+	trades := []AggTradeEvent{
+		event(90*time.Second, "101", "1"),
+		event(20*time.Second, "102", "1"),
+		event(10*time.Second, "100", "2"),
+	}
+
+	candles := MinuteCandles(trades)
+	if len(candles) != 2 {
+		t.Fatalf("Expected 2 candles, got %d", len(candles))
+	}
+	first, second := candles[0], candles[1]
+	if !first.Timestamp.Equal(start) || first.OpenPrice != "100" || first.ClosePrice != "102" ||
+		first.HighPrice != "102" || first.Volume != "3" || first.TradeCount != 2 {
+		t.Errorf("Unexpected first candle %+v", first)
+	}
+	if !second.Timestamp.Equal(start.Add(time.Minute)) || second.ClosePrice != "101" || second.TradeCount != 1 {
+		t.Errorf("Unexpected second candle %+v", second)
+	}
+}
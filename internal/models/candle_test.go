@@ -0,0 +1,120 @@
+package models
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+)
+
+// tradesGen generates non-empty trade sequences whose prices span several
+// orders of magnitude, so string and numeric ordering disagree
+func tradesGen() *rapid.Generator[[]*Trade] {
+	trade := rapid.Custom(func(t *rapid.T) *Trade {
+		cents := rapid.Int64Range(1, 10_000_000).Draw(t, "cents")
+		lots := rapid.Int64Range(0, 1_000_000).Draw(t, "lots")
+		return &Trade{
+			Symbol:   "BTCUSDT",
+			Price:    strconv.FormatFloat(float64(cents)/100, 'f', -1, 64),
+			Quantity: strconv.FormatFloat(float64(lots)/1000, 'f', -1, 64),
+		}
+	})
+	return rapid.SliceOfN(trade, 1, 50)
+}
+
+// parse reads a decimal field of a candle
+func parse(t *rapid.T, field, value string) float64 {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		t.Fatalf("%s %q is not a number: %v", field, value, err)
+	}
+	return v
+}
+
+// checkCandle verifies a candle built from trades, in order
+func checkCandle(t *rapid.T, c *Candle, trades []*Trade) {
+	open, high := parse(t, "open", c.OpenPrice), parse(t, "high", c.HighPrice)
+	low, closePrice := parse(t, "low", c.LowPrice), parse(t, "close", c.ClosePrice)
+	volume := parse(t, "volume", c.Volume)
+
+	if high < math.Max(open, closePrice) {
+		t.Fatalf("high %v below max(open %v, close %v)", high, open, closePrice)
+	}
+	if low > math.Min(open, closePrice) {
+		t.Fatalf("low %v above min(open %v, close %v)", low, open, closePrice)
+	}
+	if volume < 0 {
+		t.Fatalf("negative volume %v", volume)
+	}
+	if c.TradeCount != int64(len(trades)) {
+		t.Fatalf("trade count %d, want %d", c.TradeCount, len(trades))
+	}
+	if c.OpenPrice != trades[0].Price {
+		t.Fatalf("open %s, want first price %s", c.OpenPrice, trades[0].Price)
+	}
+	if c.ClosePrice != trades[len(trades)-1].Price {
+		t.Fatalf("close %s, want last price %s", c.ClosePrice, trades[len(trades)-1].Price)
+	}
+
+	wantHigh, wantLow, wantVolume := math.Inf(-1), math.Inf(1), 0.0
+	for _, trade := range trades {
+		price, _ := strconv.ParseFloat(trade.Price, 64)
+		quantity, _ := strconv.ParseFloat(trade.Quantity, 64)
+		wantHigh = math.Max(wantHigh, price)
+		wantLow = math.Min(wantLow, price)
+		wantVolume += quantity
+	}
+	if high != wantHigh || low != wantLow {
+		t.Fatalf("high/low %v/%v, want %v/%v", high, low, wantHigh, wantLow)
+	}
+	if math.Abs(volume-wantVolume) > 1e-6*math.Max(1, wantVolume) {
+		t.Fatalf("volume %v, want %v", volume, wantVolume)
+	}
+}
+
+// candleFrom builds a candle at timestamp from trades
+func candleFrom(timestamp time.Time, trades []*Trade) *Candle {
+	c := NewCandle(timestamp)
+	for _, trade := range trades {
+		c.UpdateFromTrade(trade)
+	}
+	return c
+}
+
+func TestCandleUpdateFromTrade_Properties(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		trades := tradesGen().Draw(t, "trades")
+		checkCandle(t, candleFrom(time.Unix(0, 0), trades), trades)
+	})
+}
+
+func TestCandleMergeWith_Properties(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		trades := tradesGen().Draw(t, "trades")
+		split := rapid.IntRange(0, len(trades)).Draw(t, "split")
+
+		start := time.Unix(0, 0)
+		earlier := func() *Candle { return candleFrom(start, trades[:split]) }
+		later := func() *Candle { return candleFrom(start.Add(time.Minute), trades[split:]) }
+
+		// Merging is order independent: the timestamps decide open and close
+		forward := earlier()
+		forward.MergeWith(later())
+		checkCandle(t, forward, trades)
+
+		backward := later()
+		backward.MergeWith(earlier())
+		checkCandle(t, backward, trades)
+
+		// The merged candle starts with the earliest candle that has trades
+		want := start
+		if split == 0 {
+			want = start.Add(time.Minute)
+		}
+		if !forward.Timestamp.Equal(want) || !backward.Timestamp.Equal(want) {
+			t.Fatalf("merged timestamps %v and %v, want %v", forward.Timestamp, backward.Timestamp, want)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Connection event kinds. A group is stopped when it is regrouped, removed
+// or shut down, and streaming starts with no group open, so both end any
+// outage left open before them.
+const (
+	ConnectionUp      = "connected"
+	ConnectionDown    = "disconnected"
+	ConnectionStopped = "stopped"
+	StreamingStarted  = "started" // Recorded without a group
+)
+
+// ConnectionEvent is a stream connection of a symbol group going up or down
+type ConnectionEvent struct {
+	Kind  string    `json:"kind"`  // ConnectionUp or ConnectionDown
+	Group string    `json:"group"` // Symbol group sharing the connection, e.g. btcusdt-200
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"` // Why the connection dropped
+}
+
+// StreamGroup is the assignment of a symbol group to a WebSocket connection,
+// as the ingestion process carrying it last reported
+type StreamGroup struct {
+	Group       string    `json:"group"`      // Symbol group sharing the connection, e.g. btcusdt-200
+	Connection  string    `json:"connection"` // Unique across processes and regroups, e.g. host:pid/3
+	Symbols     []string  `json:"symbols"`
+	Process     string    `json:"process"`      // host:pid of the ingestion process
+	ConnectedAt time.Time `json:"connected_at"` // Of the current connection; zero before the first
+	Reconnects  int       `json:"reconnects"`   // Connections after the first
+	Heartbeat   time.Time `json:"heartbeat"`
+	ExpiresAt   time.Time `json:"expires_at"` // Stale unless refreshed by then
+}
@@ -0,0 +1,20 @@
+package models
+
+// MiniTicker is a miniTicker update: a symbol's rolling 24h statistics
+type MiniTicker struct {
+	EventType   string `json:"e"`
+	EventTime   int64  `json:"E"`
+	Symbol      string `json:"s"`
+	ClosePrice  string `json:"c"`
+	OpenPrice   string `json:"o"`
+	HighPrice   string `json:"h"`
+	LowPrice    string `json:"l"`
+	Volume      string `json:"v"` // Base asset volume
+	QuoteVolume string `json:"q"`
+}
+
+// MiniTickerEvent is a miniTicker update on a combined stream
+type MiniTickerEvent struct {
+	Stream string     `json:"stream"`
+	Data   MiniTicker `json:"data"`
+}
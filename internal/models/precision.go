@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Exchange filter types giving the price and quantity steps of a symbol
+const (
+	FilterPrice   = "PRICE_FILTER"
+	FilterLotSize = "LOT_SIZE"
+)
+
+// SymbolFilter is a trading rule of a symbol in exchangeInfo. Only the
+// fields of the price and lot size filters are decoded.
+type SymbolFilter struct {
+	FilterType string `json:"filterType"`
+	TickSize   string `json:"tickSize"` // PRICE_FILTER: prices are multiples of this
+	StepSize   string `json:"stepSize"` // LOT_SIZE: quantities are multiples of this
+}
+
+// Precision is the number of decimals a symbol's prices and quantities are
+// quoted with
+type Precision struct {
+	Price    int `json:"price"`
+	Quantity int `json:"quantity"`
+}
+
+// Precision returns the decimals of the symbol's tick size and lot step. It
+// reports false when the symbol has no usable PRICE_FILTER; a missing
+// LOT_SIZE leaves Quantity 0.
+func (s Symbol) Precision() (Precision, bool) {
+	var p Precision
+	found := false
+	for _, filter := range s.Filters {
+		switch filter.FilterType {
+		case FilterPrice:
+			if decimals, ok := StepDecimals(filter.TickSize); ok {
+				p.Price, found = decimals, true
+			}
+		case FilterLotSize:
+			if decimals, ok := StepDecimals(filter.StepSize); ok {
+				p.Quantity = decimals
+			}
+		}
+	}
+	return p, found
+}
+
+// StepDecimals returns the decimals of a step such as a tick size, e.g. 4 for
+// "0.00010000" and 0 for "1.00000000". It reports false for steps that are
+// not positive decimal numbers.
+func StepDecimals(step string) (int, bool) {
+	if value, err := strconv.ParseFloat(step, 64); err != nil || value <= 0 {
+		return 0, false
+	}
+	_, fraction, found := strings.Cut(step, ".")
+	if !found {
+		return 0, true
+	}
+	return len(strings.TrimRight(fraction, "0")), true
+}
+
+// FormatPrice formats price with the symbol's price decimals
+func (p Precision) FormatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', p.Price, 64)
+}
+
+// FormatQuantity formats quantity with the symbol's quantity decimals
+func (p Precision) FormatQuantity(quantity float64) string {
+	return strconv.FormatFloat(quantity, 'f', p.Quantity, 64)
+}
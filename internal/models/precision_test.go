@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSymbolPrecision(t *testing.T) {
+	// Trimmed from exchangeInfo, with the filters Precision ignores
+	data := []byte(`{"symbols": [
+		{"symbol": "BTCUSDT", "status": "TRADING", "filters": [
+			{"filterType": "PRICE_FILTER", "minPrice": "0.01000000", "maxPrice": "1000000.00000000", "tickSize": "0.01000000"},
+			{"filterType": "LOT_SIZE", "minQty": "0.00001000", "maxQty": "9000.00000000", "stepSize": "0.00001000"},
+			{"filterType": "NOTIONAL", "minNotional": "5.00000000"}
+		]},
+		{"symbol": "XRPUSDT", "status": "TRADING", "filters": [
+			{"filterType": "PRICE_FILTER", "tickSize": "0.00010000"},
+			{"filterType": "LOT_SIZE", "stepSize": "0.10000000"}
+		]},
+		{"symbol": "SHIBUSDT", "status": "TRADING", "filters": [
+			{"filterType": "PRICE_FILTER", "tickSize": "0.00000001"},
+			{"filterType": "LOT_SIZE", "stepSize": "1.00000000"}
+		]},
+		{"symbol": "NOFILTERS", "status": "TRADING"}
+	]}`)
+
+	var info ExchangeInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		want     Precision
+		price    string
+		quantity string
+	}{
+		{Precision{Price: 2, Quantity: 5}, "97123.46", "0.12346"},
+		{Precision{Price: 4, Quantity: 1}, "0.5123", "12.3"},
+		{Precision{Price: 8, Quantity: 0}, "0.00001235", "1234568"},
+	}
+	for i, tt := range tests {
+		symbol := info.Symbols[i]
+		got, ok := symbol.Precision()
+		if !ok || got != tt.want {
+			t.Errorf("%s: Precision() = %+v, %v, want %+v", symbol.Symbol, got, ok, tt.want)
+		}
+	}
+
+	prices := []float64{97123.456, 0.51234, 0.0000123456}
+	quantities := []float64{0.123456, 12.34, 1234567.8}
+	for i, tt := range tests {
+		if got := tt.want.FormatPrice(prices[i]); got != tt.price {
+			t.Errorf("%s: FormatPrice(%v) = %s, want %s", info.Symbols[i].Symbol, prices[i], got, tt.price)
+		}
+		if got := tt.want.FormatQuantity(quantities[i]); got != tt.quantity {
+			t.Errorf("%s: FormatQuantity(%v) = %s, want %s", info.Symbols[i].Symbol, quantities[i], got, tt.quantity)
+		}
+	}
+
+	if _, ok := info.Symbols[3].Precision(); ok {
+		t.Error("Expected no precision for a symbol without filters")
+	}
+}
+
+func TestStepDecimals(t *testing.T) {
+	tests := []struct {
+		step string
+		want int
+		ok   bool
+	}{
+		{"0.01000000", 2, true},
+		{"0.00010000", 4, true},
+		{"0.00000001", 8, true},
+		{"1.00000000", 0, true},
+		{"10", 0, true},
+		{"0.00000000", 0, false},
+		{"", 0, false},
+		{"abc", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := StepDecimals(tt.step)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("StepDecimals(%q) = %d, %v, want %d, %v", tt.step, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// QuarantinedPayload is a message rejected before storage, kept for
+// inspection
+type QuarantinedPayload struct {
+	Reason  string    `json:"reason"` // Metric label of the rejection, e.g. missing_trade_time
+	Error   string    `json:"error"`
+	Payload string    `json:"payload"` // The message, truncated to its first bytes
+	Time    time.Time `json:"time"`
+}
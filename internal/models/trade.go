@@ -1,17 +1,61 @@
 package models
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"strconv"
+	"strings"
 	"time"
+
+	"binance-redis-streamer/internal/jsoncodec"
 )
 
 // Symbol represents a trading symbol
 type Symbol struct {
-	Symbol string `json:"symbol"`
-	Status string `json:"status"`
+	Symbol     string `json:"symbol"`
+	Status     string `json:"status"`
+	QuoteAsset string `json:"quoteAsset"`
+	// Trading permissions such as SPOT, MARGIN or LEVERAGED. Newer
+	// exchangeInfo responses list them in PermissionSets instead.
+	Permissions          []string       `json:"permissions"`
+	PermissionSets       [][]string     `json:"permissionSets"`
+	IsSpotTradingAllowed *bool          `json:"isSpotTradingAllowed"` // Nil when the response omits it
+	Filters              []SymbolFilter `json:"filters"`
+}
+
+// HasPermission reports whether the symbol lists permission, in either
+// permissions or permissionSets
+func (s Symbol) HasPermission(permission string) bool {
+	for _, p := range s.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	for _, set := range s.PermissionSets {
+		for _, p := range set {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsSpotTradable reports whether the symbol trades on the spot market and is
+// not a leveraged token. Missing fields do not exclude a symbol.
+func (s Symbol) IsSpotTradable() bool {
+	if s.IsSpotTradingAllowed != nil && !*s.IsSpotTradingAllowed {
+		return false
+	}
+	if s.HasPermission("LEVERAGED") {
+		return false
+	}
+	if len(s.Permissions) > 0 || len(s.PermissionSets) > 0 {
+		return s.HasPermission("SPOT")
+	}
+	return true
 }
 
 // ExchangeInfo represents the exchange information response
@@ -40,10 +84,14 @@ func (e *AggTradeEvent) UnmarshalJSON(data []byte) error {
 	}{
 		Alias: (*Alias)(e),
 	}
-	if err := json.Unmarshal(data, aux); err != nil {
+	if err := jsoncodec.Unmarshal(data, aux); err != nil {
 		return fmt.Errorf("failed to unmarshal trade data: %w", err)
 	}
 
+	// The "a" field means aggregate trade ID on aggTrade streams but seller
+	// order ID on trade streams
+	e.normalize()
+
 	if e.debug {
 		// Debug: Print unmarshaled data
 		log.Printf("Unmarshaled trade data: stream=%s, symbol=%s, IsBuyerMaker=%v",
@@ -54,24 +102,150 @@ func (e *AggTradeEvent) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// normalize moves the "a" field of trade events from the aggregate trade ID
+// to the seller order ID, where it belongs on trade streams
+func (e *AggTradeEvent) normalize() {
+	if e.Data.EventType == EventTypeTrade {
+		e.Data.SellerOrderID = e.Data.AggregateTradeID
+		e.Data.AggregateTradeID = 0
+	}
+}
+
+// Errors of trade events that cannot be stored, wrapped with the offending
+// value
+var (
+	ErrMissingSymbol    = errors.New("missing symbol")
+	ErrMissingTradeTime = errors.New("missing trade time")
+	ErrInvalidNumber    = errors.New("invalid number")
+)
+
+// Validate checks that the event has what storing it relies on: a symbol to
+// key it by and a trade time to score its history by. A zero trade time is
+// what an absent T field decodes to.
+func (e *AggTradeEvent) Validate() error {
+	switch {
+	case strings.TrimSpace(e.Data.Symbol) == "":
+		return fmt.Errorf("%w: s is %q", ErrMissingSymbol, e.Data.Symbol)
+	case e.Data.TradeTime <= 0:
+		return fmt.Errorf("%w: T is %d", ErrMissingTradeTime, e.Data.TradeTime)
+	}
+	return nil
+}
+
 // SetDebug sets the debug flag for the event
 func (e *AggTradeEvent) SetDebug(debug bool) {
 	e.debug = debug
 }
 
+// Stream event types
+const (
+	EventTypeTrade    = "trade"
+	EventTypeAggTrade = "aggTrade"
+)
+
 // TradeData represents the actual trade data
 type TradeData struct {
-	EventType     string `json:"e"`
-	EventTime     int64  `json:"E"`
-	Symbol        string `json:"s"`
-	TradeID       int64  `json:"t"`
-	Price         string `json:"p"`
-	Quantity      string `json:"q"`
-	BuyerOrderID  int64  `json:"b"`
-	SellerOrderID int64  `json:"a"`
-	TradeTime     int64  `json:"T"`
-	IsBuyerMaker  bool   `json:"m"`
-	Ignore        bool   `json:"M"`
+	EventType        string `json:"e"`
+	EventTime        int64  `json:"E"`
+	Symbol           string `json:"s"`
+	TradeID          int64  `json:"t"`
+	AggregateTradeID int64  `json:"a"` // Only set for aggTrade events
+	Price            string `json:"p"`
+	Quantity         string `json:"q"`
+	BuyerOrderID     int64  `json:"b"`
+	SellerOrderID    int64  `json:"-"` // Carried in "a" for trade events
+	TradeTime        int64  `json:"T"`
+	IsBuyerMaker     bool   `json:"m"`
+	Ignore           bool   `json:"M"`
+	// Price and Quantity parsed by ParseNumeric before the trade is
+	// published, so consumers of the bus need not parse them again
+	Numeric *NumericTrade `json:"num,omitempty"`
+}
+
+// NumericTrade is a trade's price and quantity as numbers. The decimal
+// strings stay the exact record; these are for arithmetic.
+type NumericTrade struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// Notional returns the trade's quote value, price * quantity
+func (n NumericTrade) Notional() float64 {
+	return n.Price * n.Quantity
+}
+
+// parseFloat parses decimal strings; tests swap it to count parses
+var parseFloat = strconv.ParseFloat
+
+// ParseNumericTrade parses a trade's price and quantity. It returns zero for
+// a field that does not parse to a finite number, along with an error
+// wrapping ErrInvalidNumber.
+func ParseNumericTrade(price, quantity string) (NumericTrade, error) {
+	var n NumericTrade
+	var priceErr, quantityErr error
+	n.Price, priceErr = parseFinite(price)
+	n.Quantity, quantityErr = parseFinite(quantity)
+	switch {
+	case priceErr != nil:
+		return n, fmt.Errorf("%w: price %q: %w", ErrInvalidNumber, price, priceErr)
+	case quantityErr != nil:
+		return n, fmt.Errorf("%w: quantity %q: %w", ErrInvalidNumber, quantity, quantityErr)
+	}
+	return n, nil
+}
+
+// errNotFinite rejects the NaN and infinities strconv.ParseFloat accepts
+var errNotFinite = errors.New("not a finite number")
+
+// parseFinite parses a decimal string, as zero when it does not parse to a
+// finite number
+func parseFinite(s string) (float64, error) {
+	x, err := parseFloat(s, 64)
+	switch {
+	case err != nil:
+		return 0, err
+	case math.IsNaN(x) || math.IsInf(x, 0):
+		return 0, errNotFinite
+	}
+	return x, nil
+}
+
+// ParseNumeric sets Numeric from Price and Quantity unless it is already
+// set. Numeric stays nil when they do not parse.
+func (td *TradeData) ParseNumeric() error {
+	if td.Numeric != nil {
+		return nil
+	}
+	n, err := ParseNumericTrade(td.Price, td.Quantity)
+	if err != nil {
+		return err
+	}
+	td.Numeric = &n
+	return nil
+}
+
+// numeric returns Numeric, parsing Price and Quantity when the trade was not
+// published with it
+func (td *TradeData) numeric() *NumericTrade {
+	if td.Numeric != nil {
+		return td.Numeric
+	}
+	n, err := ParseNumericTrade(td.Price, td.Quantity)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// MarshalJSON encodes the "a" field according to the event type, mirroring
+// AggTradeEvent.UnmarshalJSON
+func (td TradeData) MarshalJSON() ([]byte, error) {
+	type Alias TradeData
+	aux := Alias(td)
+	if td.EventType == EventTypeTrade {
+		aux.AggregateTradeID = td.SellerOrderID
+	}
+	return jsoncodec.Marshal(aux)
 }
 
 // Trade represents a processed trade ready for storage
@@ -83,19 +257,56 @@ type Trade struct {
 	Time         time.Time
 	EventTime    time.Time
 	IsBuyerMaker bool
+	// Price and Quantity as numbers, set by ToTrade; not stored
+	Numeric *NumericTrade `json:"-"`
+}
+
+// Numbers returns the trade's price and quantity as numbers: Numeric when
+// set, otherwise parsed from the strings
+func (t *Trade) Numbers() (NumericTrade, error) {
+	if t.Numeric != nil {
+		return *t.Numeric, nil
+	}
+	return ParseNumericTrade(t.Price, t.Quantity)
+}
+
+// TradeSchemaVersion is the version of stored Trade JSON. Bump it whenever
+// Trade changes shape and teach DecodeTrade to migrate the older versions.
+const TradeSchemaVersion = 1
+
+// storedTrade is the stored JSON form of a Trade: its fields plus the
+// schema version they were written with
+type storedTrade struct {
+	Version int `json:"version"`
+	*Trade
+}
+
+// EncodeTrade returns the stored JSON form of trade, tagged with
+// TradeSchemaVersion
+func EncodeTrade(trade *Trade) ([]byte, error) {
+	return jsoncodec.Marshal(storedTrade{Version: TradeSchemaVersion, Trade: trade})
+}
+
+// DecodeTrade parses stored trade JSON. Records written before versioning
+// carry the bare Trade fields and are read as version 0.
+func DecodeTrade(data []byte) (*Trade, error) {
+	stored := storedTrade{Trade: &Trade{}}
+	if err := jsoncodec.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trade: %w", err)
+	}
+
+	switch stored.Version {
+	case 0, 1:
+		// Version 1 only added the version field
+		return stored.Trade, nil
+	default:
+		return nil, fmt.Errorf("unsupported trade schema version %d", stored.Version)
+	}
 }
 
 // ToTrade converts an AggTradeEvent to a Trade
 func (e *AggTradeEvent) ToTrade() *Trade {
-	return &Trade{
-		Symbol:       e.Data.Symbol,
-		Price:        e.Data.Price,
-		Quantity:     e.Data.Quantity,
-		TradeID:      e.Data.TradeID,
-		Time:         time.UnixMilli(e.Data.TradeTime),
-		EventTime:    time.UnixMilli(e.Data.EventTime),
-		IsBuyerMaker: e.Data.IsBuyerMaker,
-	}
+	return e.Data.ToTrade()
 }
 
 // Candle represents aggregated trade data for a time period
@@ -124,27 +335,74 @@ func NewCandle(timestamp time.Time) *Candle {
 
 // UpdateFromTrade updates the candle with data from a new trade
 func (c *Candle) UpdateFromTrade(trade *Trade) {
+	// Unparsable fields count as zero
+	n, _ := trade.Numbers()
+
 	if c.OpenPrice == "" {
 		c.OpenPrice = trade.Price
 	}
-	if c.HighPrice == "" || trade.Price > c.HighPrice {
+	// Prices are decimal strings, so compare them numerically: as strings
+	// "9.5" would sort above "10.2"
+	if c.HighPrice == "" || parseDecimal(c.HighPrice) < n.Price {
 		c.HighPrice = trade.Price
 	}
-	if c.LowPrice == "" || trade.Price < c.LowPrice {
+	if c.LowPrice == "" || n.Price < parseDecimal(c.LowPrice) {
 		c.LowPrice = trade.Price
 	}
 	c.ClosePrice = trade.Price
 
 	// Update volume
-	currentVolume, _ := strconv.ParseFloat(c.Volume, 64)
-	tradeVolume, _ := strconv.ParseFloat(trade.Quantity, 64)
-	newVolume := currentVolume + tradeVolume
+	currentVolume := parseDecimal(c.Volume)
+	newVolume := currentVolume + n.Quantity
 	c.Volume = strconv.FormatFloat(newVolume, 'f', -1, 64)
 
 	c.TradeCount++
 }
 
-// ToTrade converts TradeData to Trade
+// MergeWith folds other into c, as when combining adjacent candles into a
+// coarser one. The earlier candle supplies the open and the timestamp, the
+// later one the close; candles without trades leave the other unchanged.
+func (c *Candle) MergeWith(other *Candle) {
+	if other.TradeCount == 0 {
+		return
+	}
+	if c.TradeCount == 0 {
+		*c = *other
+		return
+	}
+
+	if other.Timestamp.Before(c.Timestamp) {
+		c.Timestamp = other.Timestamp
+		c.OpenPrice = other.OpenPrice
+	} else {
+		c.ClosePrice = other.ClosePrice
+	}
+	if priceLess(c.HighPrice, other.HighPrice) {
+		c.HighPrice = other.HighPrice
+	}
+	if priceLess(other.LowPrice, c.LowPrice) {
+		c.LowPrice = other.LowPrice
+	}
+
+	volume, _ := strconv.ParseFloat(c.Volume, 64)
+	otherVolume, _ := strconv.ParseFloat(other.Volume, 64)
+	c.Volume = strconv.FormatFloat(volume+otherVolume, 'f', -1, 64)
+	c.TradeCount += other.TradeCount
+}
+
+// priceLess reports whether decimal price a is below b
+func priceLess(a, b string) bool {
+	return parseDecimal(a) < parseDecimal(b)
+}
+
+// parseDecimal parses a decimal string, as zero when it does not parse
+func parseDecimal(s string) float64 {
+	x, _ := parseFloat(s, 64)
+	return x
+}
+
+// ToTrade converts TradeData to Trade, parsing its price and quantity once
+// unless they were published already parsed
 func (td *TradeData) ToTrade() *Trade {
 	return &Trade{
 		Symbol:       td.Symbol,
@@ -154,5 +412,6 @@ func (td *TradeData) ToTrade() *Trade {
 		Time:         time.UnixMilli(td.TradeTime),
 		EventTime:    time.UnixMilli(td.EventTime),
 		IsBuyerMaker: td.IsBuyerMaker,
+		Numeric:      td.numeric(),
 	}
 }
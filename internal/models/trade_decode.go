@@ -0,0 +1,374 @@
+package models
+
+import (
+	"strconv"
+	"sync"
+)
+
+// aggTradeEventPool recycles trade events between decoding a message and
+// publishing it
+var aggTradeEventPool = sync.Pool{
+	New: func() interface{} { return new(AggTradeEvent) },
+}
+
+// AcquireAggTradeEvent returns an empty trade event from the pool shared
+// with ReleaseAggTradeEvent
+func AcquireAggTradeEvent() *AggTradeEvent {
+	return aggTradeEventPool.Get().(*AggTradeEvent)
+}
+
+// ReleaseAggTradeEvent clears e and returns it to the pool. Neither e nor
+// anything still pointing into it may be used afterwards.
+func ReleaseAggTradeEvent(e *AggTradeEvent) {
+	*e = AggTradeEvent{}
+	aggTradeEventPool.Put(e)
+}
+
+// DecodeAggTradeEvent decodes a combined-stream trade or aggTrade message
+// into e, with the same result as e.UnmarshalJSON. The usual message shape
+// is read by a minimal scanner that only extracts the fields of
+// AggTradeEvent; anything it does not handle, such as escaped strings,
+// nulls, nested values in unknown fields or fields of the wrong type, falls
+// back to UnmarshalJSON, which also reports every malformed message.
+func DecodeAggTradeEvent(data []byte, e *AggTradeEvent) error {
+	if e.debug || !e.decodeFast(data) {
+		return e.UnmarshalJSON(data)
+	}
+	return nil
+}
+
+// decodeFast decodes data with the scanner and reports whether it could.
+// Like encoding/json, fields missing from data keep their value in e; e is
+// left untouched when the scanner gives up.
+func (e *AggTradeEvent) decodeFast(data []byte) bool {
+	out := *e
+	s := tradeScanner{data: data}
+	if !s.object(func(key []byte) bool {
+		switch string(key) {
+		case "stream":
+			var ok bool
+			out.Stream, ok = s.string()
+			return ok
+		case "data":
+			return s.tradeData(&out.Data)
+		}
+		return !foldMatches(key, "stream", "data") && s.skip()
+	}) {
+		return false
+	}
+	if s.ws(); s.pos != len(data) {
+		return false
+	}
+
+	out.normalize()
+	out.Raw = data
+	*e = out
+	return true
+}
+
+// tradeDataKeys are the keys of TradeData, which encoding/json also matches
+// case-insensitively
+var tradeDataKeys = []string{"e", "E", "s", "t", "a", "p", "q", "b", "T", "m", "M", "num"}
+
+// tradeScanner reads the JSON of trade messages. Its methods return false
+// for anything they do not handle, which need not be invalid JSON.
+type tradeScanner struct {
+	data []byte
+	pos  int
+	// text is data as a string, made on the first string value so that all
+	// of a message's strings share one allocation
+	text string
+}
+
+// tradeData reads the data object of a trade message into td
+func (s *tradeScanner) tradeData(td *TradeData) bool {
+	return s.object(func(key []byte) bool {
+		var ok bool
+		switch string(key) {
+		case "e":
+			var eventType []byte
+			if eventType, ok = s.stringBytes(); ok {
+				// Almost every event is one of two types; share their strings
+				switch string(eventType) {
+				case EventTypeTrade:
+					td.EventType = EventTypeTrade
+				case EventTypeAggTrade:
+					td.EventType = EventTypeAggTrade
+				default:
+					td.EventType = string(eventType)
+				}
+			}
+		case "E":
+			td.EventTime, ok = s.int()
+		case "s":
+			td.Symbol, ok = s.string()
+		case "t":
+			td.TradeID, ok = s.int()
+		case "a":
+			td.AggregateTradeID, ok = s.int()
+		case "p":
+			td.Price, ok = s.string()
+		case "q":
+			td.Quantity, ok = s.string()
+		case "b":
+			td.BuyerOrderID, ok = s.int()
+		case "T":
+			td.TradeTime, ok = s.int()
+		case "m":
+			td.IsBuyerMaker, ok = s.bool()
+		case "M":
+			td.Ignore, ok = s.bool()
+		case "num":
+			var n NumericTrade
+			if td.Numeric != nil {
+				n = *td.Numeric
+			}
+			if ok = s.numeric(&n); ok {
+				td.Numeric = &n
+			}
+		default:
+			ok = !foldMatches(key, tradeDataKeys...) && s.skip()
+		}
+		return ok
+	})
+}
+
+// numeric reads a num object into n
+func (s *tradeScanner) numeric(n *NumericTrade) bool {
+	return s.object(func(key []byte) bool {
+		var ok bool
+		switch string(key) {
+		case "price":
+			n.Price, ok = s.float()
+		case "quantity":
+			n.Quantity, ok = s.float()
+		default:
+			ok = !foldMatches(key, "price", "quantity") && s.skip()
+		}
+		return ok
+	})
+}
+
+// object reads an object, calling field for each key with the scanner at
+// its value
+func (s *tradeScanner) object(field func(key []byte) bool) bool {
+	if s.ws(); !s.consume('{') {
+		return false
+	}
+	if s.ws(); s.consume('}') {
+		return true
+	}
+	for {
+		s.ws()
+		key, ok := s.stringBytes()
+		if !ok {
+			return false
+		}
+		if s.ws(); !s.consume(':') {
+			return false
+		}
+		if s.ws(); !field(key) {
+			return false
+		}
+		s.ws()
+		if s.consume('}') {
+			return true
+		}
+		if !s.consume(',') {
+			return false
+		}
+	}
+}
+
+// ws skips whitespace
+func (s *tradeScanner) ws() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume skips c if it is the next byte
+func (s *tradeScanner) consume(c byte) bool {
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// stringBytes reads a string of printable ASCII without escapes, returning
+// its contents
+func (s *tradeScanner) stringBytes() ([]byte, bool) {
+	if !s.consume('"') {
+		return nil, false
+	}
+	start := s.pos
+	for ; s.pos < len(s.data); s.pos++ {
+		switch c := s.data[s.pos]; {
+		case c == '"':
+			s.pos++
+			return s.data[start : s.pos-1], true
+		case c == '\\' || c < 0x20 || c >= 0x80:
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// string reads a string as stringBytes does. The result shares its memory
+// with the other strings of the message.
+func (s *tradeScanner) string() (string, bool) {
+	b, ok := s.stringBytes()
+	if !ok {
+		return "", false
+	}
+	if s.text == "" {
+		s.text = string(s.data)
+	}
+	end := s.pos - 1
+	return s.text[end-len(b) : end], true
+}
+
+// number reads a JSON number, returning its text and whether it is an
+// integer
+func (s *tradeScanner) number() (text []byte, integer, ok bool) {
+	start := s.pos
+	s.consume('-')
+	switch {
+	case s.consume('0'):
+	case s.digits() == 0:
+		return nil, false, false
+	}
+	integer = true
+	if s.consume('.') {
+		integer = false
+		if s.digits() == 0 {
+			return nil, false, false
+		}
+	}
+	if s.consume('e') || s.consume('E') {
+		integer = false
+		if !s.consume('+') {
+			s.consume('-')
+		}
+		if s.digits() == 0 {
+			return nil, false, false
+		}
+	}
+	return s.data[start:s.pos], integer, true
+}
+
+// digits skips decimal digits and returns how many there were
+func (s *tradeScanner) digits() int {
+	start := s.pos
+	for s.pos < len(s.data) && s.data[s.pos] >= '0' && s.data[s.pos] <= '9' {
+		s.pos++
+	}
+	return s.pos - start
+}
+
+// int reads an integer that fits an int64 for certain: up to 18 digits
+func (s *tradeScanner) int() (int64, bool) {
+	text, integer, ok := s.number()
+	if !ok || !integer {
+		return 0, false
+	}
+	negative := text[0] == '-'
+	if negative {
+		text = text[1:]
+	}
+	if len(text) > 18 {
+		return 0, false
+	}
+	var n int64
+	for _, c := range text {
+		n = n*10 + int64(c-'0')
+	}
+	if negative {
+		n = -n
+	}
+	return n, true
+}
+
+// float reads a number as a float64
+func (s *tradeScanner) float() (float64, bool) {
+	text, _, ok := s.number()
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(string(text), 64)
+	return f, err == nil
+}
+
+// bool reads true or false
+func (s *tradeScanner) bool() (bool, bool) {
+	switch {
+	case s.literal("true"):
+		return true, true
+	case s.literal("false"):
+		return false, true
+	}
+	return false, false
+}
+
+// literal skips lit if it comes next
+func (s *tradeScanner) literal(lit string) bool {
+	if len(s.data)-s.pos < len(lit) || string(s.data[s.pos:s.pos+len(lit)]) != lit {
+		return false
+	}
+	s.pos += len(lit)
+	return true
+}
+
+// skip skips the value of a field AggTradeEvent does not have. Only strings,
+// numbers and booleans are skipped.
+func (s *tradeScanner) skip() bool {
+	if s.pos == len(s.data) {
+		return false
+	}
+	switch c := s.data[s.pos]; {
+	case c == '"':
+		_, ok := s.stringBytes()
+		return ok
+	case c == '-' || (c >= '0' && c <= '9'):
+		_, _, ok := s.number()
+		return ok
+	default:
+		_, ok := s.bool()
+		return ok
+	}
+}
+
+// foldMatches reports whether the ASCII key equals one of names ignoring
+// case, as encoding/json matches keys to fields when no name is exact
+func foldMatches(key []byte, names ...string) bool {
+	for _, name := range names {
+		if len(key) != len(name) {
+			continue
+		}
+		match := true
+		for i := range key {
+			if lowerASCII(key[i]) != lowerASCII(name[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerASCII lower-cases an ASCII letter
+func lowerASCII(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+// tradeMessages are the seeds of the decoder parity tests: the messages of
+// each stream type, and shapes the scanner leaves to encoding/json
+var tradeMessages = []string{
+	`{"stream":"btcusdt@trade","data":{"e":"trade","E":1672515782136,"s":"BTCUSDT","t":12345,"p":"50000.00","q":"1.5","b":88,"a":50,"T":1672515782136,"m":true,"M":true}}`,
+	`{"stream":"btcusdt@aggTrade","data":{"e":"aggTrade","E":1,"s":"BTCUSDT","a":26129,"p":"50000.00","q":"1.5","f":100,"l":105,"T":1,"m":false}}`,
+	`{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","p":"1","q":"2","num":{"price":1,"quantity":2e0}}}`,
+	" {\n\t\"data\" : { \"s\" : \"ETHUSDT\" , \"t\" : -0 } , \"stream\" : \"ethusdt@trade\" } ",
+	`{}`,
+	`{"data":{"s":"A"},"data":{"p":"2"}}`,
+	`{"stream":"btcusdt@trade","data":{"s":"BTCUSDT"}}`,
+
+	// Left to encoding/json
+	`{"id":1,"result":null}`,
+	`{"Stream":"btcusdt@trade","data":{"S":"BTCUSDT","P":"1"}}`,
+	`{"data":{"s":"BTCUSDT","t":null,"x":[1,2],"y":{"z":1}}}`,
+	`{"data":{"s":"BTCÜSDT","t":123456789012345678901}}`,
+	`{"data":{"E":1.5}}`,
+
+	// Malformed
+	``,
+	`null`,
+	`[]`,
+	`{"data":{"t":"12"}}`,
+	`{"data":{"m":1}}`,
+	`{"data":{"t":01}}`,
+	`{"data":{"t":1,}}`,
+	`{"stream":"x"} trailing`,
+	`{"stream":"x"`,
+	`{"data":{"m":tru}}`,
+	`{"data":{"p":"1`,
+	`{"data":{"num":{"price":1e999}}}`,
+}
+
+// assertDecodeParity checks that DecodeAggTradeEvent decodes message into
+// base like UnmarshalJSON
+func assertDecodeParity(t *testing.T, message []byte, base AggTradeEvent) {
+	t.Helper()
+
+	want, got := base, base
+	wantErr := want.UnmarshalJSON(message)
+	gotErr := DecodeAggTradeEvent(message, &got)
+	if (gotErr == nil) != (wantErr == nil) {
+		t.Fatalf("DecodeAggTradeEvent(%q) error = %v, UnmarshalJSON error = %v", message, gotErr, wantErr)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DecodeAggTradeEvent(%q) = %+v, UnmarshalJSON = %+v", message, got, want)
+	}
+}
+
+func TestDecodeAggTradeEvent_MatchesUnmarshalJSON(t *testing.T) {
+	// Fields missing from a message keep their value, as with encoding/json
+	filled := AggTradeEvent{Stream: "old", Data: TradeData{EventType: EventTypeTrade, Symbol: "OLD", AggregateTradeID: 9, Numeric: &NumericTrade{Price: 3}}}
+	for _, message := range tradeMessages {
+		assertDecodeParity(t, []byte(message), AggTradeEvent{})
+		assertDecodeParity(t, []byte(message), filled)
+	}
+}
+
+func TestDecodeAggTradeEvent_FastPath(t *testing.T) {
+	for i, message := range tradeMessages[:7] {
+		var event AggTradeEvent
+		if !event.decodeFast([]byte(message)) {
+			t.Errorf("Expected message %d to take the fast path: %s", i, message)
+		}
+	}
+	for _, message := range tradeMessages[7:] {
+		var event AggTradeEvent
+		if event.decodeFast([]byte(message)) {
+			t.Errorf("Expected %s to fall back to encoding/json", message)
+		}
+	}
+}
+
+func TestReleaseAggTradeEvent(t *testing.T) {
+	event := AcquireAggTradeEvent()
+	if err := DecodeAggTradeEvent([]byte(tradeMessages[0]), event); err != nil {
+		t.Fatal(err)
+	}
+	ReleaseAggTradeEvent(event)
+	if !reflect.DeepEqual(*event, AggTradeEvent{}) {
+		t.Errorf("Expected a released event to be cleared, got %+v", *event)
+	}
+}
+
+func FuzzDecodeAggTradeEvent(f *testing.F) {
+	for _, message := range tradeMessages {
+		f.Add([]byte(message))
+	}
+	f.Fuzz(func(t *testing.T, message []byte) {
+		assertDecodeParity(t, message, AggTradeEvent{})
+	})
+}
+
+// BenchmarkDecodeAggTradeEvent compares decoding a trade message with
+// encoding/json and with the scanner, with and without pooled events
+func BenchmarkDecodeAggTradeEvent(b *testing.B) {
+	message := []byte(tradeMessages[0])
+
+	b.Run("UnmarshalJSON", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			event := new(AggTradeEvent)
+			if err := event.UnmarshalJSON(message); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Scanner", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			event := new(AggTradeEvent)
+			if err := DecodeAggTradeEvent(message, event); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("ScannerPooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			event := AcquireAggTradeEvent()
+			if err := DecodeAggTradeEvent(message, event); err != nil {
+				b.Fatal(err)
+			}
+			ReleaseAggTradeEvent(event)
+		}
+	})
+}
@@ -1,8 +1,14 @@
 package models
 
 import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"binance-redis-streamer/internal/jsoncodec"
 )
 
 func TestNewCandle(t *testing.T) {
@@ -105,3 +111,268 @@ func TestCandleUpdate(t *testing.T) {
 		t.Errorf("TradeCount = %v, want 3", candle.TradeCount)
 	}
 }
+
+func TestAggTradeEventUnmarshal_StreamTypes(t *testing.T) {
+	tests := []struct {
+		name              string
+		message           string
+		wantAggTradeID    int64
+		wantSellerOrderID int64
+		wantBuyerOrderID  int64
+	}{
+		{
+			name:           "aggTrade maps a to aggregate trade ID",
+			message:        `{"stream":"btcusdt@aggTrade","data":{"e":"aggTrade","E":1,"s":"BTCUSDT","a":26129,"p":"50000.00","q":"1.5","f":100,"l":105,"T":1,"m":true}}`,
+			wantAggTradeID: 26129,
+		},
+		{
+			name:              "trade maps a to seller order ID",
+			message:           `{"stream":"btcusdt@trade","data":{"e":"trade","E":1,"s":"BTCUSDT","t":12345,"p":"50000.00","q":"1.5","b":88,"a":50,"T":1,"m":true}}`,
+			wantSellerOrderID: 50,
+			wantBuyerOrderID:  88,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event AggTradeEvent
+			if err := event.UnmarshalJSON([]byte(tt.message)); err != nil {
+				t.Fatalf("UnmarshalJSON() error = %v", err)
+			}
+
+			if event.Data.AggregateTradeID != tt.wantAggTradeID {
+				t.Errorf("AggregateTradeID = %v, want %v", event.Data.AggregateTradeID, tt.wantAggTradeID)
+			}
+			if event.Data.SellerOrderID != tt.wantSellerOrderID {
+				t.Errorf("SellerOrderID = %v, want %v", event.Data.SellerOrderID, tt.wantSellerOrderID)
+			}
+			if event.Data.BuyerOrderID != tt.wantBuyerOrderID {
+				t.Errorf("BuyerOrderID = %v, want %v", event.Data.BuyerOrderID, tt.wantBuyerOrderID)
+			}
+
+			// Round-tripping through the codec must preserve the mapping
+			data, err := jsoncodec.Marshal(&event)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var decoded AggTradeEvent
+			if err := jsoncodec.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if decoded.Data.AggregateTradeID != tt.wantAggTradeID || decoded.Data.SellerOrderID != tt.wantSellerOrderID {
+				t.Errorf("Round trip = (agg %v, seller %v), want (agg %v, seller %v)",
+					decoded.Data.AggregateTradeID, decoded.Data.SellerOrderID, tt.wantAggTradeID, tt.wantSellerOrderID)
+			}
+		})
+	}
+}
+
+func TestAggTradeEvent_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		wantErr error
+	}{
+		{"valid trade", `{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","t":1,"p":"50000.00","q":"1.5","T":1672515782136}}`, nil},
+		{"no trade time", `{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","t":1,"p":"50000.00","q":"1.5"}}`, ErrMissingTradeTime},
+		{"zero trade time", `{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","p":"50000.00","q":"1.5","T":0}}`, ErrMissingTradeTime},
+		{"no symbol", `{"stream":"btcusdt@trade","data":{"e":"trade","p":"50000.00","q":"1.5","T":1672515782136}}`, ErrMissingSymbol},
+		{"blank symbol", `{"stream":"btcusdt@trade","data":{"e":"trade","s":" ","p":"50000.00","q":"1.5","T":1672515782136}}`, ErrMissingSymbol},
+		{"non-numeric price", `{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","p":"abc","q":"1.5","T":1672515782136}}`, ErrInvalidNumber},
+		{"NaN quantity", `{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","p":"1","q":"NaN","T":1672515782136}}`, ErrInvalidNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event AggTradeEvent
+			if err := event.UnmarshalJSON([]byte(tt.message)); err != nil {
+				t.Fatalf("UnmarshalJSON() error = %v", err)
+			}
+			err := event.Validate()
+			if err == nil {
+				err = event.Data.ParseNumeric()
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() and ParseNumeric() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func FuzzAggTradeEventUnmarshal(f *testing.F) {
+	for _, message := range tradeMessages {
+		f.Add([]byte(message))
+	}
+	f.Fuzz(func(t *testing.T, message []byte) {
+		var event AggTradeEvent
+		if event.UnmarshalJSON(message) != nil {
+			return
+		}
+		if err := event.Validate(); err == nil {
+			if strings.TrimSpace(event.Data.Symbol) == "" || event.Data.TradeTime <= 0 {
+				t.Errorf("Validate() accepted %q", message)
+			}
+		} else if !errors.Is(err, ErrMissingSymbol) && !errors.Is(err, ErrMissingTradeTime) {
+			t.Errorf("Validate() returned an untyped error: %v", err)
+		}
+
+		if err := event.Data.ParseNumeric(); err != nil {
+			if !errors.Is(err, ErrInvalidNumber) {
+				t.Errorf("ParseNumeric() returned an untyped error: %v", err)
+			}
+			return
+		}
+		n := event.Data.Numeric
+		if math.IsNaN(n.Price) || math.IsInf(n.Price, 0) || math.IsNaN(n.Quantity) || math.IsInf(n.Quantity, 0) {
+			t.Errorf("ParseNumeric() of %q = %+v, want finite numbers", message, *n)
+		}
+	})
+}
+
+// BenchmarkAggTradeEventCodec measures the hot-path codec; compare builds
+// with and without -tags gojson
+func BenchmarkAggTradeEventCodec(b *testing.B) {
+	message := []byte(`{"stream":"btcusdt@trade","data":{"e":"trade","E":1672515782136,"s":"BTCUSDT","t":12345,"p":"50000.00","q":"1.5","b":88,"a":50,"T":1672515782136,"m":true,"M":true}}`)
+	var event AggTradeEvent
+	if err := jsoncodec.Unmarshal(message, &event); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("Unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var decoded AggTradeEvent
+			if err := jsoncodec.Unmarshal(message, &decoded); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := jsoncodec.Marshal(event); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestToTrade_NumericMatchesStrings(t *testing.T) {
+	for _, tt := range []struct{ price, quantity string }{
+		{"50000.00", "1.5"},
+		{"0.00001234", "123456789"},
+		{"1e3", ".5"},
+	} {
+		data := TradeData{Symbol: "BTCUSDT", Price: tt.price, Quantity: tt.quantity}
+		trade := data.ToTrade()
+		if trade.Numeric == nil {
+			t.Fatalf("ToTrade(%s, %s) left Numeric unset", tt.price, tt.quantity)
+		}
+
+		wantPrice, _ := strconv.ParseFloat(tt.price, 64)
+		wantQuantity, _ := strconv.ParseFloat(tt.quantity, 64)
+		if trade.Numeric.Price != wantPrice || trade.Numeric.Quantity != wantQuantity {
+			t.Errorf("ToTrade(%s, %s) Numeric = %+v, want %v and %v", tt.price, tt.quantity, *trade.Numeric, wantPrice, wantQuantity)
+		}
+		if trade.Price != tt.price || trade.Quantity != tt.quantity {
+			t.Errorf("ToTrade changed the strings to %s and %s", trade.Price, trade.Quantity)
+		}
+	}
+
+	// Trades that do not parse keep their strings and parse on use
+	trade := (&TradeData{Price: "bad", Quantity: "2"}).ToTrade()
+	if trade.Numeric != nil {
+		t.Errorf("Expected no Numeric for an unparsable price, got %+v", *trade.Numeric)
+	}
+	if numbers, err := trade.Numbers(); err == nil || numbers.Quantity != 2 {
+		t.Errorf("Numbers() = %+v, %v, want an error with quantity 2", numbers, err)
+	}
+}
+
+func TestTradeData_NumericRoundTrip(t *testing.T) {
+	data := TradeData{EventType: EventTypeTrade, Symbol: "BTCUSDT", Price: "50000.10", Quantity: "0.25", TradeID: 7}
+	if err := data.ParseNumeric(); err != nil {
+		t.Fatalf("ParseNumeric() error = %v", err)
+	}
+
+	encoded, err := jsoncodec.Marshal(AggTradeEvent{Stream: "btcusdt@trade", Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(encoded), `"num":{"price":50000.1,"quantity":0.25}`) {
+		t.Errorf("Expected the numbers in %s", encoded)
+	}
+
+	var decoded AggTradeEvent
+	if err := decoded.UnmarshalJSON(encoded); err != nil {
+		t.Fatal(err)
+	}
+
+	// Published numbers are used as they are, without parsing again
+	parses := 0
+	defer func(orig func(string, int) (float64, error)) { parseFloat = orig }(parseFloat)
+	parseFloat = func(s string, bitSize int) (float64, error) {
+		parses++
+		return strconv.ParseFloat(s, bitSize)
+	}
+	trade := decoded.ToTrade()
+	if parses != 0 {
+		t.Errorf("ToTrade parsed %d times, want none", parses)
+	}
+	if trade.Numeric == nil || trade.Numeric.Price != 50000.1 || trade.Numeric.Quantity != 0.25 || trade.Price != "50000.10" {
+		t.Errorf("Unexpected trade %+v", trade)
+	}
+
+	// Without published numbers the strings are parsed once
+	bare := TradeData{Price: "1", Quantity: "2"}
+	bare.ToTrade()
+	if parses != 2 {
+		t.Errorf("ToTrade parsed %d times, want 2", parses)
+	}
+}
+
+// BenchmarkTradeConsumers counts the price and quantity parses of a trade
+// on its way through the processor: the stored volume, anomaly detection
+// and the candle each need its numbers. Before numbers were published with
+// the trade, each consumer parsed the strings itself.
+func BenchmarkTradeConsumers(b *testing.B) {
+	data := TradeData{Symbol: "BTCUSDT", Price: "50000.00", Quantity: "1.5", TradeTime: 1672515782136}
+	published := data
+	if err := published.ParseNumeric(); err != nil {
+		b.Fatal(err)
+	}
+
+	var parses int
+	defer func(orig func(string, int) (float64, error)) { parseFloat = orig }(parseFloat)
+	parseFloat = func(s string, bitSize int) (float64, error) {
+		parses++
+		return strconv.ParseFloat(s, bitSize)
+	}
+
+	consume := func(trade *Trade, candle *Candle) {
+		trade.Numbers() // Stored 24h volume
+		trade.Numbers() // Anomaly detection
+		candle.UpdateFromTrade(trade)
+	}
+
+	for _, bc := range []struct {
+		name    string
+		toTrade func() *Trade
+	}{
+		// A trade with only its strings, as ToTrade used to return
+		{"Strings", func() *Trade {
+			return &Trade{Symbol: data.Symbol, Price: data.Price, Quantity: data.Quantity, Time: time.UnixMilli(data.TradeTime)}
+		}},
+		{"Numeric", published.ToTrade},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			parses = 0
+			candle := NewCandle(time.UnixMilli(data.TradeTime).Truncate(time.Minute))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				consume(bc.toTrade(), candle)
+			}
+			b.ReportMetric(float64(parses)/float64(b.N), "parses/op")
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package models
+
+// User-data stream event types
+const (
+	EventTypeExecutionReport  = "executionReport"
+	EventTypeAccountPosition  = "outboundAccountPosition"
+	EventTypeListenKeyExpired = "listenKeyExpired"
+)
+
+// OrderUpdate is an executionReport event: a change to one of the account's
+// orders, such as a new order, a fill or a cancellation.
+// encoding/json matches keys case-insensitively when there is no exact
+// match, so every key of the event is declared to keep e.g. "I" out of "i".
+type OrderUpdate struct {
+	EventType           string `json:"e"`
+	EventTime           int64  `json:"E"`
+	Symbol              string `json:"s"`
+	ClientOrderID       string `json:"c"`
+	Side                string `json:"S"` // BUY or SELL
+	OrderType           string `json:"o"`
+	TimeInForce         string `json:"f"`
+	Quantity            string `json:"q"`
+	Price               string `json:"p"`
+	StopPrice           string `json:"P"`
+	IcebergQuantity     string `json:"F"`
+	OrderListID         int64  `json:"g"`
+	OrigClientOrderID   string `json:"C"` // Set for cancellations
+	ExecutionType       string `json:"x"` // NEW, TRADE, CANCELED, EXPIRED, ...
+	Status              string `json:"X"` // NEW, PARTIALLY_FILLED, FILLED, CANCELED, ...
+	RejectReason        string `json:"r"`
+	OrderID             int64  `json:"i"`
+	LastQuantity        string `json:"l"` // Quantity of the last fill
+	FilledQuantity      string `json:"z"` // Cumulative filled quantity
+	LastPrice           string `json:"L"` // Price of the last fill
+	Commission          string `json:"n"`
+	CommissionAsset     string `json:"N"`
+	TransactionTime     int64  `json:"T"`
+	TradeID             int64  `json:"t"` // -1 when the update is not a fill
+	Ignore              int64  `json:"I"`
+	IsOnBook            bool   `json:"w"`
+	IsMaker             bool   `json:"m"`
+	IgnoreFlag          bool   `json:"M"`
+	CreationTime        int64  `json:"O"`
+	FilledQuoteQty      string `json:"Z"` // Cumulative filled quote quantity
+	LastQuoteQty        string `json:"Y"`
+	QuoteOrderQuantity  string `json:"Q"`
+	WorkingTime         int64  `json:"W"`
+	SelfTradePrevention string `json:"V"`
+}
+
+// AccountPosition is an outboundAccountPosition event: the balances of the
+// assets that changed
+type AccountPosition struct {
+	EventType  string    `json:"e"`
+	EventTime  int64     `json:"E"`
+	LastUpdate int64     `json:"u"`
+	Balances   []Balance `json:"B"`
+}
+
+// Balance is the free and locked amount of one asset
+type Balance struct {
+	Asset  string `json:"a"`
+	Free   string `json:"f"`
+	Locked string `json:"l"`
+}
@@ -0,0 +1,164 @@
+package analysis
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+)
+
+// AnomalyDetector flags outsized trades and price jumps per symbol. It keeps
+// a rolling window of each symbol's trade notionals and of its prices within
+// the price jump window, and is safe for concurrent use.
+type AnomalyDetector struct {
+	cfg config.AnomalyConfig
+
+	mu      sync.Mutex
+	symbols map[string]*symbolWindow
+}
+
+// symbolWindow holds the recent trades of one symbol
+type symbolWindow struct {
+	// Ring buffer of the last SizeWindow notionals with their running sums
+	notionals []float64
+	next      int
+	count     int
+	sum       float64
+	sumSq     float64
+
+	// Prices within the jump window as monotonic deques: maxQ holds
+	// decreasing and minQ increasing prices, so the front is the extreme
+	maxQ []pricePoint
+	minQ []pricePoint
+}
+
+type pricePoint struct {
+	time  time.Time
+	price float64
+}
+
+// NewAnomalyDetector creates a detector with the thresholds in cfg
+func NewAnomalyDetector(cfg config.AnomalyConfig) *AnomalyDetector {
+	return &AnomalyDetector{
+		cfg:     cfg,
+		symbols: make(map[string]*symbolWindow),
+	}
+}
+
+// Observe adds a trade to its symbol's windows and returns the anomalies it
+// is flagged for, if any. Trades with unparsable prices or quantities are
+// ignored.
+func (d *AnomalyDetector) Observe(trade *models.Trade) []*models.AnomalyEvent {
+	numbers, err := trade.Numbers()
+	if err != nil || numbers.Price <= 0 || numbers.Quantity < 0 {
+		return nil
+	}
+	price, quantity := numbers.Price, numbers.Quantity
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w, ok := d.symbols[trade.Symbol]
+	if !ok {
+		w = &symbolWindow{notionals: make([]float64, d.cfg.SizeWindow)}
+		d.symbols[trade.Symbol] = w
+	}
+
+	var events []*models.AnomalyEvent
+	if ev := d.checkSize(w, trade, price, price*quantity); ev != nil {
+		events = append(events, ev)
+	}
+	if ev := d.checkPrice(w, trade, price); ev != nil {
+		events = append(events, ev)
+	}
+	return events
+}
+
+// checkSize compares a trade's notional with the window's average before
+// adding it, so an outsized trade does not dilute its own comparison
+func (d *AnomalyDetector) checkSize(w *symbolWindow, trade *models.Trade, price, notional float64) *models.AnomalyEvent {
+	var event *models.AnomalyEvent
+	if w.count >= d.cfg.SizeMinSamples {
+		n := float64(w.count)
+		mean := w.sum / n
+		// Clamp rounding noise in the running sums below zero
+		stddev := math.Sqrt(math.Max(w.sumSq/n-mean*mean, 0))
+		if stddev > 0 {
+			if sigmas := (notional - mean) / stddev; sigmas > d.cfg.SizeSigmas {
+				event = &models.AnomalyEvent{
+					Kind:         models.AnomalyOutsizedTrade,
+					Symbol:       trade.Symbol,
+					Time:         trade.Time,
+					TradeID:      trade.TradeID,
+					Price:        price,
+					Notional:     notional,
+					MeanNotional: mean,
+					Sigmas:       sigmas,
+				}
+			}
+		}
+	}
+
+	if w.count == len(w.notionals) {
+		old := w.notionals[w.next]
+		w.sum -= old
+		w.sumSq -= old * old
+	} else {
+		w.count++
+	}
+	w.notionals[w.next] = notional
+	w.sum += notional
+	w.sumSq += notional * notional
+	w.next = (w.next + 1) % len(w.notionals)
+	return event
+}
+
+// checkPrice compares a trade's price with the highest and lowest prices
+// within the window before it. Once a jump is flagged the window restarts at
+// the trade, so one move is not reported again by every trade after it.
+func (d *AnomalyDetector) checkPrice(w *symbolWindow, trade *models.Trade, price float64) *models.AnomalyEvent {
+	cutoff := trade.Time.Add(-d.cfg.PriceJumpWindow)
+	for len(w.maxQ) > 0 && w.maxQ[0].time.Before(cutoff) {
+		w.maxQ = w.maxQ[1:]
+	}
+	for len(w.minQ) > 0 && w.minQ[0].time.Before(cutoff) {
+		w.minQ = w.minQ[1:]
+	}
+
+	var event *models.AnomalyEvent
+	if len(w.minQ) > 0 {
+		low, high := w.minQ[0].price, w.maxQ[0].price
+		reference := 0.0
+		if up := (price - low) / low * 100; up > d.cfg.PriceJumpPercent {
+			reference = low
+		} else if down := (price - high) / high * 100; -down > d.cfg.PriceJumpPercent {
+			reference = high
+		}
+		if reference > 0 {
+			event = &models.AnomalyEvent{
+				Kind:           models.AnomalyPriceJump,
+				Symbol:         trade.Symbol,
+				Time:           trade.Time,
+				TradeID:        trade.TradeID,
+				Price:          price,
+				ReferencePrice: reference,
+				ChangePercent:  (price - reference) / reference * 100,
+				Window:         d.cfg.PriceJumpWindow,
+			}
+			w.maxQ, w.minQ = w.maxQ[:0], w.minQ[:0]
+		}
+	}
+
+	point := pricePoint{time: trade.Time, price: price}
+	for len(w.maxQ) > 0 && w.maxQ[len(w.maxQ)-1].price <= price {
+		w.maxQ = w.maxQ[:len(w.maxQ)-1]
+	}
+	w.maxQ = append(w.maxQ, point)
+	for len(w.minQ) > 0 && w.minQ[len(w.minQ)-1].price >= price {
+		w.minQ = w.minQ[:len(w.minQ)-1]
+	}
+	w.minQ = append(w.minQ, point)
+	return event
+}
@@ -0,0 +1,155 @@
+package analysis
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+)
+
+func testAnomalyConfig() config.AnomalyConfig {
+	return config.AnomalyConfig{
+		Enabled:          true,
+		SizeSigmas:       4,
+		SizeWindow:       200,
+		SizeMinSamples:   50,
+		PriceJumpPercent: 1,
+		PriceJumpWindow:  time.Minute,
+		MaxEvents:        100,
+	}
+}
+
+// seededTrades returns a deterministic stream of trades one second apart
+// whose prices wander less than 0.1% around 100 and whose sizes vary between
+// 0.5 and 1.5
+func seededTrades(seed int64, n int, start time.Time) []*models.Trade {
+	rng := rand.New(rand.NewSource(seed))
+	trades := make([]*models.Trade, n)
+	for i := range trades {
+		trades[i] = &models.Trade{
+			Symbol:   "BTCUSDT",
+			Price:    strconv.FormatFloat(100+rng.Float64()*0.05, 'f', 4, 64),
+			Quantity: strconv.FormatFloat(0.5+rng.Float64(), 'f', 4, 64),
+			TradeID:  int64(i + 1),
+			Time:     start.Add(time.Duration(i) * time.Second),
+		}
+	}
+	return trades
+}
+
+func observeAll(d *AnomalyDetector, trades []*models.Trade) []*models.AnomalyEvent {
+	var events []*models.AnomalyEvent
+	for _, trade := range trades {
+		events = append(events, d.Observe(trade)...)
+	}
+	return events
+}
+
+func TestAnomalyDetector_QuietStreamHasNoEvents(t *testing.T) {
+	start := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	for seed := int64(1); seed <= 5; seed++ {
+		d := NewAnomalyDetector(testAnomalyConfig())
+		if events := observeAll(d, seededTrades(seed, 1000, start)); len(events) != 0 {
+			t.Errorf("seed %d: expected no anomalies, got %d (first %+v)", seed, len(events), events[0])
+		}
+	}
+}
+
+func TestAnomalyDetector_OutsizedTrade(t *testing.T) {
+	start := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	d := NewAnomalyDetector(testAnomalyConfig())
+	trades := seededTrades(42, 100, start)
+	observeAll(d, trades)
+
+	whale := &models.Trade{Symbol: "BTCUSDT", Price: "100.01", Quantity: "25", TradeID: 101, Time: start.Add(100 * time.Second)}
+	events := d.Observe(whale)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %d", len(events))
+	}
+	event := events[0]
+	if event.Kind != models.AnomalyOutsizedTrade || event.TradeID != 101 {
+		t.Errorf("Got %s for trade %d, want outsized_trade for trade 101", event.Kind, event.TradeID)
+	}
+	if event.Notional != 2500.25 || event.Sigmas <= 4 {
+		t.Errorf("Notional = %v at %.1fσ, want 2500.25 above 4σ", event.Notional, event.Sigmas)
+	}
+	if event.MeanNotional < 50 || event.MeanNotional > 150 {
+		t.Errorf("MeanNotional = %v, want around 100", event.MeanNotional)
+	}
+
+	// Other symbols keep their own windows
+	other := &models.Trade{Symbol: "ETHUSDT", Price: "100", Quantity: "1000", Time: start}
+	if events := d.Observe(other); len(events) != 0 {
+		t.Errorf("Expected no anomalies for a new symbol, got %d", len(events))
+	}
+}
+
+func TestAnomalyDetector_NeedsMinSamples(t *testing.T) {
+	start := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	d := NewAnomalyDetector(testAnomalyConfig())
+	observeAll(d, seededTrades(7, 49, start))
+
+	whale := &models.Trade{Symbol: "BTCUSDT", Price: "100.01", Quantity: "25", Time: start.Add(49 * time.Second)}
+	if events := d.Observe(whale); len(events) != 0 {
+		t.Errorf("Expected no anomalies before %d samples, got %d", 50, len(events))
+	}
+}
+
+func TestAnomalyDetector_PriceJump(t *testing.T) {
+	start := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	d := NewAnomalyDetector(testAnomalyConfig())
+	trades := seededTrades(3, 30, start)
+	observeAll(d, trades)
+
+	// 2% below the window's high
+	drop := &models.Trade{Symbol: "BTCUSDT", Price: "98", Quantity: "1", TradeID: 31, Time: start.Add(30 * time.Second)}
+	events := d.Observe(drop)
+	if len(events) != 1 || events[0].Kind != models.AnomalyPriceJump {
+		t.Fatalf("Expected 1 price jump, got %+v", events)
+	}
+	event := events[0]
+	if event.ReferencePrice < 100 || event.ChangePercent > -1.9 || event.Window != time.Minute {
+		t.Errorf("Got %+.2f%% from %v within %v, want about -2%% from the high within 1m",
+			event.ChangePercent, event.ReferencePrice, event.Window)
+	}
+
+	// The window restarts at the jump, so trading on at the new level is quiet
+	next := &models.Trade{Symbol: "BTCUSDT", Price: "98.01", Quantity: "1", TradeID: 32, Time: start.Add(31 * time.Second)}
+	if events := d.Observe(next); len(events) != 0 {
+		t.Errorf("Expected the jump to be reported once, got %+v", events)
+	}
+}
+
+func TestAnomalyDetector_PriceJumpOutsideWindow(t *testing.T) {
+	start := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	d := NewAnomalyDetector(testAnomalyConfig())
+	d.Observe(&models.Trade{Symbol: "BTCUSDT", Price: "100", Quantity: "1", Time: start})
+
+	// The same 2% move spread over more than the window is not a jump
+	late := &models.Trade{Symbol: "BTCUSDT", Price: "102", Quantity: "1", Time: start.Add(61 * time.Second)}
+	if events := d.Observe(late); len(events) != 0 {
+		t.Errorf("Expected no anomalies, got %+v", events)
+	}
+
+	rise := &models.Trade{Symbol: "BTCUSDT", Price: "103.5", Quantity: "1", Time: start.Add(90 * time.Second)}
+	events := d.Observe(rise)
+	if len(events) != 1 || events[0].ReferencePrice != 102 || events[0].ChangePercent <= 1 {
+		t.Errorf("Expected a rise from 102, got %+v", events)
+	}
+}
+
+func TestAnomalyDetector_IgnoresInvalidTrades(t *testing.T) {
+	d := NewAnomalyDetector(testAnomalyConfig())
+	for _, trade := range []*models.Trade{
+		{Symbol: "BTCUSDT", Price: "abc", Quantity: "1"},
+		{Symbol: "BTCUSDT", Price: "0", Quantity: "1"},
+		{Symbol: "BTCUSDT", Price: "100", Quantity: "-1"},
+	} {
+		if events := d.Observe(trade); events != nil {
+			t.Errorf("Observe(%s x %s) = %+v, want nil", trade.Price, trade.Quantity, events)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// Indicators computed from candles by name
+const (
+	IndicatorRSI    = "rsi"
+	IndicatorATR    = "atr"
+	IndicatorZScore = "zscore"
+)
+
+// defaultIndicatorPeriods is the period of an indicator given without one
+var defaultIndicatorPeriods = map[string]int{
+	IndicatorRSI:    14,
+	IndicatorATR:    14,
+	IndicatorZScore: 20,
+}
+
+// Indicator is an indicator computed from candles over a period
+type Indicator struct {
+	Name   string // IndicatorRSI, IndicatorATR or IndicatorZScore
+	Period int
+}
+
+// String renders the indicator as a column title, e.g. RSI(14)
+func (ind Indicator) String() string {
+	return fmt.Sprintf("%s(%d)", strings.ToUpper(ind.Name), ind.Period)
+}
+
+// Lookback returns how many candles the indicator needs before its first
+// value. RSI and ATR keep smoothing after that, so more history moves them
+// closer to values computed over all candles.
+func (ind Indicator) Lookback() int {
+	if ind.Name == IndicatorRSI {
+		return ind.Period + 1
+	}
+	return ind.Period
+}
+
+// Compute returns the indicator for candles, which must be oldest first. RSI
+// and the z-score are computed from closes, ATR from highs, lows and closes.
+func (ind Indicator) Compute(candles []*models.Candle) ([]float64, error) {
+	highs := make([]float64, len(candles))
+	lows := make([]float64, len(candles))
+	closes := make([]float64, len(candles))
+	for i, candle := range candles {
+		highs[i], _ = strconv.ParseFloat(candle.HighPrice, 64)
+		lows[i], _ = strconv.ParseFloat(candle.LowPrice, 64)
+		closes[i], _ = strconv.ParseFloat(candle.ClosePrice, 64)
+	}
+
+	switch ind.Name {
+	case IndicatorRSI:
+		return RSI(closes, ind.Period)
+	case IndicatorATR:
+		return ATR(highs, lows, closes, ind.Period)
+	case IndicatorZScore:
+		return ZScore(closes, ind.Period)
+	default:
+		return nil, fmt.Errorf("unknown indicator %q", ind.Name)
+	}
+}
+
+// ParseIndicator parses name:period, e.g. rsi:14, atr:14 or zscore:20. The
+// period may be left out for the usual one.
+func ParseIndicator(spec string) (Indicator, error) {
+	name, period, hasPeriod := strings.Cut(strings.ToLower(strings.TrimSpace(spec)), ":")
+	ind := Indicator{Name: name, Period: defaultIndicatorPeriods[name]}
+	if ind.Period == 0 {
+		return Indicator{}, fmt.Errorf("invalid indicator %q: must be rsi, atr or zscore, e.g. rsi:14", spec)
+	}
+	if hasPeriod {
+		n, err := strconv.Atoi(period)
+		if err != nil || n <= 0 {
+			return Indicator{}, fmt.Errorf("invalid indicator %q: the period must be a positive number", spec)
+		}
+		ind.Period = n
+	}
+	if ind.Name == IndicatorZScore && ind.Period < 2 {
+		return Indicator{}, fmt.Errorf("invalid indicator %q: the z-score period must be at least 2", spec)
+	}
+	return ind, nil
+}
+
+// ParseIndicators parses a comma-separated list of indicators as accepted by
+// ParseIndicator
+func ParseIndicators(spec string) ([]Indicator, error) {
+	var out []Indicator
+	for _, part := range strings.Split(spec, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		ind, err := ParseIndicator(part)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ind)
+	}
+	return out, nil
+}
+
+// Condition holds when an indicator crosses a threshold, upwards when Above
+// is set and downwards otherwise
+type Condition struct {
+	Indicator Indicator
+	Above     bool
+	Threshold float64
+}
+
+// String renders the condition as ParseCondition accepts it, e.g. rsi:14>70
+func (c Condition) String() string {
+	op := "<"
+	if c.Above {
+		op = ">"
+	}
+	return fmt.Sprintf("%s:%d%s%s", c.Indicator.Name, c.Indicator.Period, op, strconv.FormatFloat(c.Threshold, 'f', -1, 64))
+}
+
+// ParseCondition parses an indicator, > or < and a threshold, e.g. rsi:14>70
+// for RSI rising above 70 or zscore<-2 for the z-score falling below -2
+func ParseCondition(spec string) (Condition, error) {
+	i := strings.IndexAny(spec, "<>")
+	if i < 0 {
+		return Condition{}, fmt.Errorf("invalid condition %q: use an indicator, > or < and a threshold, e.g. rsi:14>70", spec)
+	}
+	ind, err := ParseIndicator(spec[:i])
+	if err != nil {
+		return Condition{}, fmt.Errorf("invalid condition %q: %w", spec, err)
+	}
+	threshold, err := strconv.ParseFloat(strings.TrimSpace(spec[i+1:]), 64)
+	if err != nil || math.IsNaN(threshold) || math.IsInf(threshold, 0) {
+		return Condition{}, fmt.Errorf("invalid condition %q: the threshold must be a number", spec)
+	}
+	return Condition{Indicator: ind, Above: spec[i] == '>', Threshold: threshold}, nil
+}
+
+// Crossed reports whether the indicator moved from prev to cur across the
+// threshold in the condition's direction. Warm-up NaNs never cross.
+func (c Condition) Crossed(prev, cur float64) bool {
+	if math.IsNaN(prev) || math.IsNaN(cur) {
+		return false
+	}
+	if c.Above {
+		return prev <= c.Threshold && cur > c.Threshold
+	}
+	return prev >= c.Threshold && cur < c.Threshold
+}
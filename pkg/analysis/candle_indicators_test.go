@@ -0,0 +1,159 @@
+package analysis
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// pricedCandles returns minute candles with the given highs, lows and closes
+func pricedCandles(highs, lows, closes []float64) []*models.Candle {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]*models.Candle, len(closes))
+	for i := range closes {
+		candles[i] = &models.Candle{
+			Timestamp:  base.Add(time.Duration(i) * time.Minute),
+			OpenPrice:  strconv.FormatFloat(closes[i], 'f', -1, 64),
+			HighPrice:  strconv.FormatFloat(highs[i], 'f', -1, 64),
+			LowPrice:   strconv.FormatFloat(lows[i], 'f', -1, 64),
+			ClosePrice: strconv.FormatFloat(closes[i], 'f', -1, 64),
+		}
+	}
+	return candles
+}
+
+func TestIndicatorCompute(t *testing.T) {
+	nan := math.NaN()
+
+	// The start of Wilder's RSI example, see TestRSI
+	closes := []float64{
+		44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+		45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03,
+	}
+	rsi, err := Indicator{Name: IndicatorRSI, Period: 14}.Compute(pricedCandles(closes, closes, closes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSeries(t, "RSI", rsi, append(nanSeries(14), 70.53, 66.32, 66.55), 0.1)
+
+	// The candles of TestATR
+	candles := pricedCandles(
+		[]float64{10, 12, 11, 15, 15},
+		[]float64{8, 11, 7, 14, 14},
+		[]float64{9, 11.5, 8, 14.5, 14.5},
+	)
+	atr, err := Indicator{Name: IndicatorATR, Period: 3}.Compute(candles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSeries(t, "ATR", atr, []float64{nan, nan, 3.1667, 4.4444, 3.2963}, 1e-4)
+
+	zscore, err := Indicator{Name: IndicatorZScore, Period: 3}.Compute(candles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := ZScore([]float64{9, 11.5, 8, 14.5, 14.5}, 3)
+	assertSeries(t, "ZScore", zscore, want, 1e-9)
+
+	if _, err := (Indicator{Name: "vwap", Period: 3}).Compute(candles); err == nil {
+		t.Error("expected an error for an unknown indicator")
+	}
+}
+
+func TestParseIndicators(t *testing.T) {
+	got, err := ParseIndicators("rsi:14, ATR:7,zscore,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Indicator{
+		{Name: IndicatorRSI, Period: 14},
+		{Name: IndicatorATR, Period: 7},
+		{Name: IndicatorZScore, Period: 20},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseIndicators() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Indicator %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if got[0].String() != "RSI(14)" || got[0].Lookback() != 15 || got[1].Lookback() != 7 {
+		t.Errorf("RSI(14) renders as %s with lookback %d", got[0], got[0].Lookback())
+	}
+
+	for _, spec := range []string{"macd:12", "rsi:0", "rsi:x", "atr:-3", "zscore:1"} {
+		if _, err := ParseIndicators(spec); err == nil {
+			t.Errorf("ParseIndicators(%q) should fail", spec)
+		}
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Condition
+		wantErr string
+	}{
+		{spec: "rsi:14>70", want: Condition{Indicator{IndicatorRSI, 14}, true, 70}},
+		{spec: "rsi<30", want: Condition{Indicator{IndicatorRSI, 14}, false, 30}},
+		{spec: "zscore:50<-2.5", want: Condition{Indicator{IndicatorZScore, 50}, false, -2.5}},
+		{spec: "atr:14 > 120", want: Condition{Indicator{IndicatorATR, 14}, true, 120}},
+
+		{spec: "rsi:14", wantErr: "use an indicator"},
+		{spec: "rsi:14>", wantErr: "must be a number"},
+		{spec: "rsi:14>NaN", wantErr: "must be a number"},
+		{spec: "obv>1", wantErr: "must be rsi, atr or zscore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseCondition(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseCondition(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCondition(%q) returned error: %v", tt.spec, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCondition(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+			if again, err := ParseCondition(got.String()); err != nil || again != got {
+				t.Errorf("ParseCondition(%q) = %+v, %v, want %+v", got.String(), again, err, got)
+			}
+		})
+	}
+}
+
+func TestConditionCrossed(t *testing.T) {
+	above := Condition{Indicator: Indicator{IndicatorRSI, 14}, Above: true, Threshold: 70}
+	below := Condition{Indicator: Indicator{IndicatorRSI, 14}, Above: false, Threshold: 30}
+	nan := math.NaN()
+
+	tests := []struct {
+		cond      Condition
+		prev, cur float64
+		want      bool
+	}{
+		{above, 65, 71, true},
+		{above, 70, 71, true},
+		{above, 71, 72, false}, // Already above
+		{above, 71, 65, false},
+		{above, nan, 71, false},
+		{below, 35, 29, true},
+		{below, 29, 25, false},
+		{below, 25, 35, false},
+		{below, 35, nan, false},
+	}
+	for _, tt := range tests {
+		if got := tt.cond.Crossed(tt.prev, tt.cur); got != tt.want {
+			t.Errorf("%s Crossed(%v, %v) = %v, want %v", tt.cond, tt.prev, tt.cur, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// TimedValue is a value observed at a time, e.g. a candle close
+type TimedValue struct {
+	Time  time.Time
+	Value float64
+}
+
+// CorrelationMatrix holds the pairwise correlations of several return series
+type CorrelationMatrix struct {
+	// Values[i][j] is the Pearson correlation of series i and j, NaN when
+	// they share too few returns or one of them does not vary
+	Values [][]float64
+	// Observations[i][j] is the number of returns series i and j share
+	Observations [][]int
+}
+
+// LogReturns returns the log returns of closes taken every step, stamped
+// with the time of the later close. A return is only computed between closes
+// exactly one step apart, so a missing bucket drops the returns on either
+// side of it instead of spanning the gap. Non-positive closes are skipped.
+func LogReturns(closes []TimedValue, step time.Duration) []TimedValue {
+	sorted := append([]TimedValue(nil), closes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	var returns []TimedValue
+	for i := 1; i < len(sorted); i++ {
+		prev, cur := sorted[i-1], sorted[i]
+		if cur.Time.Sub(prev.Time) != step || prev.Value <= 0 || cur.Value <= 0 {
+			continue
+		}
+		returns = append(returns, TimedValue{Time: cur.Time, Value: math.Log(cur.Value / prev.Value)})
+	}
+	return returns
+}
+
+// AlignReturns pairs the returns of a and b taken at the same times, both
+// sorted by time, dropping returns only one of them has
+func AlignReturns(a, b []TimedValue) (x, y []float64) {
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i].Time.Before(b[j].Time):
+			i++
+		case b[j].Time.Before(a[i].Time):
+			j++
+		default:
+			x = append(x, a[i].Value)
+			y = append(y, b[j].Value)
+			i++
+			j++
+		}
+	}
+	return x, y
+}
+
+// Pearson returns the Pearson correlation coefficient of x and y
+func Pearson(x, y []float64) (float64, error) {
+	if len(x) != len(y) {
+		return 0, fmt.Errorf("series lengths differ: %d and %d", len(x), len(y))
+	}
+	if len(x) < 2 {
+		return 0, fmt.Errorf("need at least 2 values, got %d", len(x))
+	}
+
+	n := float64(len(x))
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0, fmt.Errorf("a series does not vary")
+	}
+
+	// Rounding can push perfectly correlated series just past ±1
+	return math.Max(-1, math.Min(1, cov/math.Sqrt(varX*varY))), nil
+}
+
+// Correlations returns the pairwise correlations of the return series, each
+// sorted by time, over the returns each pair shares. Pairs sharing fewer
+// than minObservations returns are NaN.
+func Correlations(returns [][]TimedValue, minObservations int) *CorrelationMatrix {
+	n := len(returns)
+	m := &CorrelationMatrix{Values: make([][]float64, n), Observations: make([][]int, n)}
+	for i := range returns {
+		m.Values[i] = make([]float64, n)
+		m.Observations[i] = make([]int, n)
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			x, y := AlignReturns(returns[i], returns[j])
+			value := math.NaN()
+			if len(x) >= minObservations {
+				if r, err := Pearson(x, y); err == nil {
+					value = r
+				}
+			}
+			m.Values[i][j], m.Values[j][i] = value, value
+			m.Observations[i][j], m.Observations[j][i] = len(x), len(x)
+		}
+	}
+	return m
+}
@@ -0,0 +1,112 @@
+package analysis
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var corrStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// pricePath turns log returns into hourly closes starting at 100
+func pricePath(returns []float64) []TimedValue {
+	closes := []TimedValue{{Time: corrStart, Value: 100}}
+	for i, r := range returns {
+		closes = append(closes, TimedValue{
+			Time:  corrStart.Add(time.Duration(i+1) * time.Hour),
+			Value: closes[i].Value * math.Exp(r),
+		})
+	}
+	return closes
+}
+
+func TestLogReturns(t *testing.T) {
+	closes := []TimedValue{
+		{Time: corrStart.Add(3 * time.Hour), Value: 110}, // Two steps after 100: no return
+		{Time: corrStart, Value: 100},
+		{Time: corrStart.Add(time.Hour), Value: 100},
+		{Time: corrStart.Add(4 * time.Hour), Value: 121},
+	}
+
+	returns := LogReturns(closes, time.Hour)
+	if len(returns) != 2 {
+		t.Fatalf("Expected 2 returns, got %+v", returns)
+	}
+	if !returns[0].Time.Equal(corrStart.Add(time.Hour)) || returns[0].Value != 0 {
+		t.Errorf("First return = %+v, want 0 at 01:00", returns[0])
+	}
+	if !returns[1].Time.Equal(corrStart.Add(4*time.Hour)) || math.Abs(returns[1].Value-math.Log(1.1)) > 1e-12 {
+		t.Errorf("Second return = %+v, want log(1.1) at 04:00", returns[1])
+	}
+}
+
+func TestPearson(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5}
+	if r, err := Pearson(x, []float64{2, 4, 6, 8, 10}); err != nil || r != 1 {
+		t.Errorf("Pearson(x, 2x) = %v, %v, want 1", r, err)
+	}
+	if r, err := Pearson(x, []float64{5, 4, 3, 2, 1}); err != nil || r != -1 {
+		t.Errorf("Pearson(x, -x) = %v, %v, want -1", r, err)
+	}
+	// Hand-computed: co-deviation 4, squared deviations 10 and 5.2
+	if r, err := Pearson(x, []float64{2, 1, 4, 3, 3}); err != nil || math.Abs(r-4/math.Sqrt(52)) > 1e-12 {
+		t.Errorf("Pearson = %v, %v, want %v", r, err, 4/math.Sqrt(52))
+	}
+
+	if _, err := Pearson(x, x[:3]); err == nil {
+		t.Error("Expected an error for series of different lengths")
+	}
+	if _, err := Pearson([]float64{1}, []float64{1}); err == nil {
+		t.Error("Expected an error for a single value")
+	}
+	if _, err := Pearson(x, []float64{3, 3, 3, 3, 3}); err == nil {
+		t.Error("Expected an error for a constant series")
+	}
+}
+
+func TestCorrelations_SyntheticSeries(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const n = 2000
+
+	// b follows a with correlation 0.8; c is independent of both
+	a, b, c := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := 0; i < n; i++ {
+		a[i] = rng.NormFloat64() * 0.01
+		b[i] = 0.8*a[i] + 0.6*rng.NormFloat64()*0.01
+		c[i] = rng.NormFloat64() * 0.01
+	}
+
+	pathB := pricePath(b)
+	// B misses a bucket, which drops its returns on both sides
+	pathB = append(pathB[:500], pathB[501:]...)
+
+	series := [][]TimedValue{
+		LogReturns(pricePath(a), time.Hour),
+		LogReturns(pathB, time.Hour),
+		LogReturns(pricePath(c), time.Hour),
+		nil, // A symbol without data
+	}
+	m := Correlations(series, 3)
+
+	if got := m.Values[0][1]; math.Abs(got-0.8) > 0.05 {
+		t.Errorf("corr(a, b) = %v, want about 0.8", got)
+	}
+	if got := m.Values[0][2]; math.Abs(got) > 0.1 {
+		t.Errorf("corr(a, c) = %v, want about 0", got)
+	}
+	if m.Values[1][0] != m.Values[0][1] {
+		t.Error("Expected a symmetric matrix")
+	}
+	if m.Values[0][0] != 1 || m.Values[1][1] != 1 {
+		t.Errorf("Expected ones on the diagonal, got %v and %v", m.Values[0][0], m.Values[1][1])
+	}
+	if m.Observations[0][0] != n || m.Observations[0][1] != n-2 {
+		t.Errorf("Observations = %d and %d, want %d and %d", m.Observations[0][0], m.Observations[0][1], n, n-2)
+	}
+	for j := 0; j < 4; j++ {
+		if !math.IsNaN(m.Values[3][j]) || m.Observations[3][j] != 0 {
+			t.Errorf("Expected no correlation with the empty series, got %v over %d", m.Values[3][j], m.Observations[3][j])
+		}
+	}
+}
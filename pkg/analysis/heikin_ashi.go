@@ -0,0 +1,46 @@
+package analysis
+
+import (
+	"math"
+	"strconv"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// HeikinAshi returns the Heikin-Ashi candles of candles, which must be oldest
+// first. Each close is the average of the candle's open, high, low and close,
+// each open the midpoint of the previous Heikin-Ashi open and close (of the
+// candle's own open and close for the first), and the high and low extend the
+// candle's range to cover them. Timestamps, volume and trade counts are kept.
+func HeikinAshi(candles []*models.Candle) []*models.Candle {
+	out := make([]*models.Candle, len(candles))
+	var prevOpen, prevClose float64
+	for i, candle := range candles {
+		open, _ := strconv.ParseFloat(candle.OpenPrice, 64)
+		high, _ := strconv.ParseFloat(candle.HighPrice, 64)
+		low, _ := strconv.ParseFloat(candle.LowPrice, 64)
+		closePrice, _ := strconv.ParseFloat(candle.ClosePrice, 64)
+
+		haClose := (open + high + low + closePrice) / 4
+		haOpen := (open + closePrice) / 2
+		if i > 0 {
+			haOpen = (prevOpen + prevClose) / 2
+		}
+		haHigh := math.Max(high, math.Max(haOpen, haClose))
+		haLow := math.Min(low, math.Min(haOpen, haClose))
+		prevOpen, prevClose = haOpen, haClose
+
+		ha := *candle
+		ha.OpenPrice = formatPrice(haOpen)
+		ha.HighPrice = formatPrice(haHigh)
+		ha.LowPrice = formatPrice(haLow)
+		ha.ClosePrice = formatPrice(haClose)
+		out[i] = &ha
+	}
+	return out
+}
+
+// formatPrice formats a computed price the way candles store prices
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64)
+}
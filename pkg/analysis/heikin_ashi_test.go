@@ -0,0 +1,60 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestHeikinAshi(t *testing.T) {
+	base := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	candle := func(minute int, open, high, low, closePrice string) *models.Candle {
+		return &models.Candle{
+			Timestamp:  base.Add(time.Duration(minute) * time.Minute),
+			OpenPrice:  open,
+			HighPrice:  high,
+			LowPrice:   low,
+			ClosePrice: closePrice,
+			Volume:     "1.5",
+			TradeCount: int64(minute + 1),
+		}
+	}
+	candles := []*models.Candle{
+		candle(0, "10", "12", "9", "11"),
+		candle(1, "11", "13", "10", "12"),
+		candle(2, "12", "12.5", "8", "9"),
+		candle(3, "9", "9.5", "8.5", "9"),
+	}
+
+	// Worked by hand: the first open is the midpoint of the candle's own open
+	// and close, the last high is raised to its Heikin-Ashi open
+	want := [][4]string{
+		{"10.5", "12", "9", "10.5"},
+		{"10.5", "13", "10", "11.5"},
+		{"11", "12.5", "8", "10.375"},
+		{"10.6875", "10.6875", "8.5", "9"},
+	}
+
+	got := HeikinAshi(candles)
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d candles, got %d", len(want), len(got))
+	}
+	for i, ha := range got {
+		ohlc := [4]string{ha.OpenPrice, ha.HighPrice, ha.LowPrice, ha.ClosePrice}
+		if ohlc != want[i] {
+			t.Errorf("Candle %d: expected OHLC %v, got %v", i, want[i], ohlc)
+		}
+		if !ha.Timestamp.Equal(candles[i].Timestamp) || ha.Volume != "1.5" || ha.TradeCount != candles[i].TradeCount {
+			t.Errorf("Candle %d: expected time, volume and trades to be kept, got %+v", i, *ha)
+		}
+	}
+
+	// The input candles are left alone
+	if candles[0].OpenPrice != "10" {
+		t.Errorf("Expected input candles to be unchanged, got open %s", candles[0].OpenPrice)
+	}
+	if len(HeikinAshi(nil)) != 0 {
+		t.Error("Expected no candles for no input")
+	}
+}
@@ -0,0 +1,248 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+)
+
+// Indicator series are aligned with their input: element i is the value at
+// input i, and is NaN during the warm-up before enough values are available.
+
+// MACD holds the MACD line, its signal line and their difference
+type MACD struct {
+	Line      []float64
+	Signal    []float64
+	Histogram []float64
+}
+
+// BollingerBands holds a moving average with bands k standard deviations around it
+type BollingerBands struct {
+	Upper  []float64
+	Middle []float64
+	Lower  []float64
+}
+
+// SMA returns the simple moving average of values over period
+func SMA(values []float64, period int) ([]float64, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("SMA period must be positive")
+	}
+
+	out := nanSeries(len(values))
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out, nil
+}
+
+// EMA returns the exponential moving average of values over period, using a
+// smoothing factor of 2/(period+1) and seeded with the SMA of the first period values
+func EMA(values []float64, period int) ([]float64, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("EMA period must be positive")
+	}
+	return ema(values, period, 2/float64(period+1)), nil
+}
+
+// RSI returns the relative strength index of values over period, using
+// Wilder's smoothing of average gains and losses
+func RSI(values []float64, period int) ([]float64, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("RSI period must be positive")
+	}
+
+	out := nanSeries(len(values))
+	if len(values) <= period {
+		return out, nil
+	}
+
+	var avgGain, avgLoss float64
+	for i := 1; i <= period; i++ {
+		gain, loss := change(values[i-1], values[i])
+		avgGain += gain
+		avgLoss += loss
+	}
+	avgGain /= float64(period)
+	avgLoss /= float64(period)
+	out[period] = rsi(avgGain, avgLoss)
+
+	for i := period + 1; i < len(values); i++ {
+		gain, loss := change(values[i-1], values[i])
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		out[i] = rsi(avgGain, avgLoss)
+	}
+	return out, nil
+}
+
+// MACDSeries returns the MACD of values: the fast EMA minus the slow EMA,
+// with a signal EMA of that difference. The usual periods are 12, 26 and 9.
+func MACDSeries(values []float64, fast, slow, signal int) (*MACD, error) {
+	if fast <= 0 || slow <= 0 || signal <= 0 {
+		return nil, fmt.Errorf("MACD periods must be positive")
+	}
+	if fast >= slow {
+		return nil, fmt.Errorf("MACD fast period must be shorter than the slow period")
+	}
+
+	fastEMA := ema(values, fast, 2/float64(fast+1))
+	slowEMA := ema(values, slow, 2/float64(slow+1))
+
+	line := nanSeries(len(values))
+	for i := slow - 1; i < len(values); i++ {
+		line[i] = fastEMA[i] - slowEMA[i]
+	}
+
+	// The signal line only starts once the MACD line is defined
+	signalLine := nanSeries(len(values))
+	if len(values) >= slow {
+		copy(signalLine[slow-1:], ema(line[slow-1:], signal, 2/float64(signal+1)))
+	}
+
+	histogram := nanSeries(len(values))
+	for i := range values {
+		histogram[i] = line[i] - signalLine[i]
+	}
+
+	return &MACD{Line: line, Signal: signalLine, Histogram: histogram}, nil
+}
+
+// Bollinger returns Bollinger Bands of values: the SMA over period with bands
+// k population standard deviations above and below it
+func Bollinger(values []float64, period int, k float64) (*BollingerBands, error) {
+	middle, err := SMA(values, period)
+	if err != nil {
+		return nil, err
+	}
+
+	bands := &BollingerBands{
+		Upper:  nanSeries(len(values)),
+		Middle: middle,
+		Lower:  nanSeries(len(values)),
+	}
+	for i := period - 1; i < len(values); i++ {
+		variance := 0.0
+		for _, v := range values[i-period+1 : i+1] {
+			variance += (v - middle[i]) * (v - middle[i])
+		}
+		stddev := math.Sqrt(variance / float64(period))
+
+		bands.Upper[i] = middle[i] + k*stddev
+		bands.Lower[i] = middle[i] - k*stddev
+	}
+	return bands, nil
+}
+
+// ZScore returns how many population standard deviations each value is
+// from the mean of the period values ending with it. A window without any
+// spread has a z-score of 0.
+func ZScore(values []float64, period int) ([]float64, error) {
+	if period < 2 {
+		return nil, fmt.Errorf("z-score period must be at least 2")
+	}
+	mean, err := SMA(values, period)
+	if err != nil {
+		return nil, err
+	}
+
+	out := nanSeries(len(values))
+	for i := period - 1; i < len(values); i++ {
+		variance := 0.0
+		for _, v := range values[i-period+1 : i+1] {
+			variance += (v - mean[i]) * (v - mean[i])
+		}
+		stddev := math.Sqrt(variance / float64(period))
+		if stddev == 0 {
+			out[i] = 0
+			continue
+		}
+		out[i] = (values[i] - mean[i]) / stddev
+	}
+	return out, nil
+}
+
+// TrueRange returns the true range of each candle: the largest of its
+// high-low range and its distance from the previous close. The first candle
+// has no previous close, so its true range is its high-low range.
+func TrueRange(highs, lows, closes []float64) ([]float64, error) {
+	if len(lows) != len(highs) || len(closes) != len(highs) {
+		return nil, fmt.Errorf("highs, lows and closes must have the same length")
+	}
+
+	out := make([]float64, len(highs))
+	for i := range highs {
+		out[i] = highs[i] - lows[i]
+		if i > 0 {
+			out[i] = math.Max(out[i], math.Max(math.Abs(highs[i]-closes[i-1]), math.Abs(lows[i]-closes[i-1])))
+		}
+	}
+	return out, nil
+}
+
+// ATR returns the average true range over period, using Wilder's smoothing
+// seeded with the mean of the first period true ranges
+func ATR(highs, lows, closes []float64, period int) ([]float64, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("ATR period must be positive")
+	}
+	tr, err := TrueRange(highs, lows, closes)
+	if err != nil {
+		return nil, err
+	}
+	return ema(tr, period, 1/float64(period)), nil
+}
+
+// ema computes an exponential moving average with smoothing factor alpha,
+// seeded with the SMA of the first period values
+func ema(values []float64, period int, alpha float64) []float64 {
+	out := nanSeries(len(values))
+	if len(values) < period {
+		return out
+	}
+
+	seed := 0.0
+	for _, v := range values[:period] {
+		seed += v
+	}
+	out[period-1] = seed / float64(period)
+
+	for i := period; i < len(values); i++ {
+		out[i] = alpha*values[i] + (1-alpha)*out[i-1]
+	}
+	return out
+}
+
+// change splits the move from prev to cur into a gain and a loss, both non-negative
+func change(prev, cur float64) (gain, loss float64) {
+	if cur > prev {
+		return cur - prev, 0
+	}
+	return 0, prev - cur
+}
+
+// rsi converts average gains and losses to an index between 0 and 100
+func rsi(avgGain, avgLoss float64) float64 {
+	if avgLoss == 0 {
+		if avgGain == 0 {
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+avgGain/avgLoss)
+}
+
+// nanSeries returns n NaN values
+func nanSeries(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
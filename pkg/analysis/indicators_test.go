@@ -0,0 +1,252 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+// assertSeries compares got against want, where NaN in want means the value
+// must still be in warm-up
+func assertSeries(t *testing.T, name string, got, want []float64, tolerance float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %d values, got %d", name, len(want), len(got))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) {
+			if !math.IsNaN(got[i]) {
+				t.Errorf("%s[%d]: expected warm-up NaN, got %v", name, i, got[i])
+			}
+			continue
+		}
+		if math.Abs(got[i]-want[i]) > tolerance {
+			t.Errorf("%s[%d]: expected %v, got %v", name, i, want[i], got[i])
+		}
+	}
+}
+
+func TestSMA(t *testing.T) {
+	nan := math.NaN()
+	got, err := SMA([]float64{1, 2, 3, 4, 5, 6}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSeries(t, "SMA", got, []float64{nan, nan, 2, 3, 4, 5}, 1e-9)
+}
+
+func TestEMA(t *testing.T) {
+	nan := math.NaN()
+	// Seed is SMA(2,4,6)=4, alpha is 0.5
+	got, err := EMA([]float64{2, 4, 6, 8, 4, 10}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSeries(t, "EMA", got, []float64{nan, nan, 4, 6, 5, 7.5}, 1e-9)
+}
+
+func TestEMAShorterThanPeriod(t *testing.T) {
+	got, err := EMA([]float64{1, 2}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range got {
+		if !math.IsNaN(v) {
+			t.Errorf("EMA[%d]: expected NaN, got %v", i, v)
+		}
+	}
+}
+
+func TestRSI(t *testing.T) {
+	// Reference closes and 14-period RSI values from Wilder's worked example
+	// as published by StockCharts. The published RSI was computed from closes
+	// with more precision than the two decimals shown, hence the tolerance.
+	closes := []float64{
+		44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+		45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+		46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57,
+		43.42, 42.66, 43.13,
+	}
+	reference := []float64{
+		70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+		54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77,
+	}
+
+	want := nanSeries(14)
+	want = append(want, reference...)
+
+	got, err := RSI(closes, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSeries(t, "RSI", got, want, 0.1)
+}
+
+func TestRSIOneSided(t *testing.T) {
+	rising, _ := RSI([]float64{1, 2, 3, 4}, 3)
+	if rising[3] != 100 {
+		t.Errorf("expected RSI 100 for only gains, got %v", rising[3])
+	}
+
+	falling, _ := RSI([]float64{4, 3, 2, 1}, 3)
+	if falling[3] != 0 {
+		t.Errorf("expected RSI 0 for only losses, got %v", falling[3])
+	}
+
+	flat, _ := RSI([]float64{5, 5, 5, 5}, 3)
+	if flat[3] != 50 {
+		t.Errorf("expected RSI 50 for no movement, got %v", flat[3])
+	}
+}
+
+func TestMACDLinearTrend(t *testing.T) {
+	// An SMA-seeded EMA of a straight line lags it by exactly (period-1)/2,
+	// so MACD(12,26,9) of a line with slope 1 is (25-11)/2 = 7 throughout
+	values := make([]float64, 60)
+	for i := range values {
+		values[i] = float64(i)
+	}
+
+	macd, err := MACDSeries(values, 12, 26, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range values {
+		switch {
+		case i < 25:
+			if !math.IsNaN(macd.Line[i]) {
+				t.Errorf("Line[%d]: expected warm-up NaN, got %v", i, macd.Line[i])
+			}
+		case math.Abs(macd.Line[i]-7) > 1e-9:
+			t.Errorf("Line[%d]: expected 7, got %v", i, macd.Line[i])
+		}
+
+		switch {
+		case i < 33:
+			if !math.IsNaN(macd.Signal[i]) || !math.IsNaN(macd.Histogram[i]) {
+				t.Errorf("Signal[%d]: expected warm-up NaN, got %v", i, macd.Signal[i])
+			}
+		case math.Abs(macd.Signal[i]-7) > 1e-9 || math.Abs(macd.Histogram[i]) > 1e-9:
+			t.Errorf("Signal[%d]: expected 7 with zero histogram, got %v and %v", i, macd.Signal[i], macd.Histogram[i])
+		}
+	}
+}
+
+func TestMACDMatchesEMAs(t *testing.T) {
+	values := []float64{10, 11, 12, 11, 13, 15, 14, 16, 18, 17, 19, 21}
+
+	macd, err := MACDSeries(values, 3, 6, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, _ := EMA(values, 3)
+	slow, _ := EMA(values, 6)
+
+	for i := 5; i < len(values); i++ {
+		if want := fast[i] - slow[i]; math.Abs(macd.Line[i]-want) > 1e-9 {
+			t.Errorf("Line[%d]: expected %v, got %v", i, want, macd.Line[i])
+		}
+	}
+
+	// First signal value is the SMA of the first three MACD values
+	wantSignal := (macd.Line[5] + macd.Line[6] + macd.Line[7]) / 3
+	if math.Abs(macd.Signal[7]-wantSignal) > 1e-9 {
+		t.Errorf("Signal[7]: expected %v, got %v", wantSignal, macd.Signal[7])
+	}
+}
+
+func TestMACDInvalidPeriods(t *testing.T) {
+	if _, err := MACDSeries([]float64{1}, 26, 12, 9); err == nil {
+		t.Error("expected error when fast period is not shorter than slow")
+	}
+	if _, err := MACDSeries([]float64{1}, 12, 26, 0); err == nil {
+		t.Error("expected error for zero signal period")
+	}
+}
+
+func TestBollinger(t *testing.T) {
+	// Mean 5 and population standard deviation 2
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+
+	bands, err := Bollinger(values, 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bands.Middle[7] != 5 || bands.Upper[7] != 9 || bands.Lower[7] != 1 {
+		t.Errorf("expected bands 9/5/1, got %v/%v/%v", bands.Upper[7], bands.Middle[7], bands.Lower[7])
+	}
+	if !math.IsNaN(bands.Upper[6]) || !math.IsNaN(bands.Lower[6]) {
+		t.Error("expected warm-up NaN before a full period")
+	}
+}
+
+func TestTrueRange(t *testing.T) {
+	// Gaps above and below the previous close widen the range
+	got, err := TrueRange(
+		[]float64{10, 12, 11, 15},
+		[]float64{8, 11, 7, 14},
+		[]float64{9, 11.5, 8, 14.5},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSeries(t, "TrueRange", got, []float64{2, 3, 4.5, 7}, 1e-9)
+
+	if _, err := TrueRange([]float64{1, 2}, []float64{1}, []float64{1, 2}); err == nil {
+		t.Error("expected TrueRange error for mismatched lengths")
+	}
+}
+
+func TestATR(t *testing.T) {
+	nan := math.NaN()
+	// True ranges 2, 3, 4.5, 7 and 1: the seed is mean(2, 3, 4.5) = 3.1667,
+	// then (3.1667*2+7)/3 = 4.4444 and (4.4444*2+1)/3 = 3.2963
+	got, err := ATR(
+		[]float64{10, 12, 11, 15, 15},
+		[]float64{8, 11, 7, 14, 14},
+		[]float64{9, 11.5, 8, 14.5, 14.5},
+		3,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSeries(t, "ATR", got, []float64{nan, nan, 3.1667, 4.4444, 3.2963}, 1e-4)
+}
+
+func TestZScore(t *testing.T) {
+	nan := math.NaN()
+	// Windows (1, 2, 3) and (2, 3, 4) put the last value 1/sqrt(2/3) above
+	// the mean; in (3, 4, 10) 10 is 4.3333 above 5.6667 with a deviation of
+	// sqrt(9.5556)
+	got, err := ZScore([]float64{1, 2, 3, 4, 10}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSeries(t, "ZScore", got, []float64{nan, nan, 1.2247, 1.2247, 1.4018}, 1e-4)
+
+	flat, _ := ZScore([]float64{5, 5, 5}, 3)
+	assertSeries(t, "ZScore", flat, []float64{nan, nan, 0}, 1e-9)
+
+	if _, err := ZScore(nil, 1); err == nil {
+		t.Error("expected ZScore error for a period of 1")
+	}
+}
+
+func TestInvalidPeriods(t *testing.T) {
+	if _, err := SMA(nil, 0); err == nil {
+		t.Error("expected SMA error for zero period")
+	}
+	if _, err := EMA(nil, -1); err == nil {
+		t.Error("expected EMA error for negative period")
+	}
+	if _, err := RSI(nil, 0); err == nil {
+		t.Error("expected RSI error for zero period")
+	}
+	if _, err := Bollinger(nil, 0, 2); err == nil {
+		t.Error("expected Bollinger error for zero period")
+	}
+	if _, err := ATR(nil, nil, nil, 0); err == nil {
+		t.Error("expected ATR error for zero period")
+	}
+}
@@ -0,0 +1,206 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// ValueAreaShare is the share of the total volume the value area holds
+const ValueAreaShare = 0.70
+
+// PriceLevel holds the traded volume within one price bucket
+type PriceLevel struct {
+	Low    float64
+	High   float64
+	Volume float64
+}
+
+// Mid returns the midpoint price of the bucket
+func (l PriceLevel) Mid() float64 {
+	return (l.Low + l.High) / 2
+}
+
+// Profile is the distribution of traded volume across price levels
+type Profile struct {
+	Levels         []PriceLevel // Ordered from lowest to highest price
+	PointOfControl float64      // Midpoint of the highest-volume level
+	POCIndex       int          // Index of the highest-volume level
+	TotalVolume    float64
+
+	// The value area is the contiguous run of levels around the point of
+	// control holding ValueAreaShare of the volume
+	ValueAreaLow  float64 // Low of the value area's lowest level
+	ValueAreaHigh float64 // High of the value area's highest level
+	VALIndex      int     // Index of the value area's lowest level
+	VAHIndex      int     // Index of the value area's highest level
+}
+
+// InValueArea reports whether level i is part of the value area
+func (p *Profile) InValueArea(i int) bool {
+	return i >= p.VALIndex && i <= p.VAHIndex
+}
+
+// VolumeProfile buckets the traded base volume of trades into equally sized
+// price levels spanning the traded range, and finds the point of control and
+// value area
+func VolumeProfile(trades []*models.Trade, buckets int) (*Profile, error) {
+	if buckets <= 0 {
+		return nil, fmt.Errorf("bucket count must be positive")
+	}
+
+	prices := make([]float64, 0, len(trades))
+	volumes := make([]float64, 0, len(trades))
+	low, high := math.Inf(1), math.Inf(-1)
+
+	for _, trade := range trades {
+		numbers, err := trade.Numbers()
+		if err != nil || numbers.Price <= 0 || numbers.Quantity < 0 {
+			continue
+		}
+
+		prices = append(prices, numbers.Price)
+		volumes = append(volumes, numbers.Quantity)
+		low = math.Min(low, numbers.Price)
+		high = math.Max(high, numbers.Price)
+	}
+
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("no valid trades to profile")
+	}
+
+	profile := newProfile(low, high, buckets)
+	for i, price := range prices {
+		profile.Levels[profile.levelIndex(price)].Volume += volumes[i]
+		profile.TotalVolume += volumes[i]
+	}
+	profile.summarize()
+
+	return profile, nil
+}
+
+// CandleVolumeProfile approximates the volume profile from candles, for
+// periods whose raw trades are no longer kept. Each candle's volume is spread
+// evenly across its low-high range, since where within the range it traded is
+// unknown. Volume is never placed outside the range of the candle it traded
+// in, so the error shrinks with the candle interval: with 1m candles a level
+// is off by at most the volume of the candles whose range overlaps it only
+// partly, and levels wider than a typical candle range match the trade
+// profile closely.
+func CandleVolumeProfile(candles []*models.Candle, buckets int) (*Profile, error) {
+	if buckets <= 0 {
+		return nil, fmt.Errorf("bucket count must be positive")
+	}
+
+	type span struct{ low, high, volume float64 }
+	spans := make([]span, 0, len(candles))
+	low, high := math.Inf(1), math.Inf(-1)
+
+	for _, candle := range candles {
+		candleLow, err := strconv.ParseFloat(candle.LowPrice, 64)
+		if err != nil || candleLow <= 0 {
+			continue
+		}
+		candleHigh, err := strconv.ParseFloat(candle.HighPrice, 64)
+		if err != nil || candleHigh < candleLow {
+			continue
+		}
+		volume, err := strconv.ParseFloat(candle.Volume, 64)
+		if err != nil || volume < 0 {
+			continue
+		}
+
+		spans = append(spans, span{candleLow, candleHigh, volume})
+		low = math.Min(low, candleLow)
+		high = math.Max(high, candleHigh)
+	}
+
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("no valid candles to profile")
+	}
+
+	profile := newProfile(low, high, buckets)
+	for _, s := range spans {
+		profile.TotalVolume += s.volume
+
+		first, last := profile.levelIndex(s.low), profile.levelIndex(s.high)
+		if first == last {
+			profile.Levels[first].Volume += s.volume
+			continue
+		}
+		for i := first; i <= last; i++ {
+			level := &profile.Levels[i]
+			overlap := math.Min(level.High, s.high) - math.Max(level.Low, s.low)
+			level.Volume += s.volume * overlap / (s.high - s.low)
+		}
+	}
+	profile.summarize()
+
+	return profile, nil
+}
+
+// newProfile returns empty levels of equal width spanning low to high. A
+// single price collapses into one level.
+func newProfile(low, high float64, buckets int) *Profile {
+	if high == low {
+		buckets = 1
+	}
+	width := (high - low) / float64(buckets)
+
+	profile := &Profile{Levels: make([]PriceLevel, buckets)}
+	for i := range profile.Levels {
+		profile.Levels[i].Low = low + float64(i)*width
+		profile.Levels[i].High = low + float64(i+1)*width
+	}
+	profile.Levels[buckets-1].High = high
+	return profile
+}
+
+// levelIndex returns the index of the level price falls in
+func (p *Profile) levelIndex(price float64) int {
+	last := len(p.Levels) - 1
+	low, width := p.Levels[0].Low, p.Levels[0].High-p.Levels[0].Low
+	if width <= 0 {
+		return last
+	}
+	idx := int((price - low) / width)
+	if idx > last {
+		idx = last // The top of the range belongs to the last level
+	}
+	if idx < 0 {
+		idx = 0
+	}
+	return idx
+}
+
+// summarize finds the point of control and grows the value area from it,
+// adding whichever neighbouring level holds more volume until the area holds
+// ValueAreaShare of the total
+func (p *Profile) summarize() {
+	for i, level := range p.Levels {
+		if level.Volume > p.Levels[p.POCIndex].Volume {
+			p.POCIndex = i
+		}
+	}
+	p.PointOfControl = p.Levels[p.POCIndex].Mid()
+
+	p.VALIndex, p.VAHIndex = p.POCIndex, p.POCIndex
+	volume := p.Levels[p.POCIndex].Volume
+	for volume < p.TotalVolume*ValueAreaShare {
+		below, above := p.VALIndex-1, p.VAHIndex+1
+		if below < 0 && above >= len(p.Levels) {
+			break
+		}
+		if above >= len(p.Levels) || below >= 0 && p.Levels[below].Volume > p.Levels[above].Volume {
+			p.VALIndex = below
+			volume += p.Levels[below].Volume
+		} else {
+			p.VAHIndex = above
+			volume += p.Levels[above].Volume
+		}
+	}
+	p.ValueAreaLow = p.Levels[p.VALIndex].Low
+	p.ValueAreaHigh = p.Levels[p.VAHIndex].High
+}
@@ -0,0 +1,142 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func makeTrades(pairs ...string) []*models.Trade {
+	trades := make([]*models.Trade, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		trades = append(trades, &models.Trade{Symbol: "BTCUSDT", Price: pairs[i], Quantity: pairs[i+1]})
+	}
+	return trades
+}
+
+func TestVolumeProfile(t *testing.T) {
+	// Range 100-200 in 4 buckets of width 25
+	trades := makeTrades(
+		"100", "1", // bucket 0
+		"110", "2", // bucket 0
+		"130", "1", // bucket 1
+		"160", "5", // bucket 2
+		"170", "4", // bucket 2
+		"200", "1", // top of range lands in bucket 3
+	)
+
+	profile, err := VolumeProfile(trades, 4)
+	if err != nil {
+		t.Fatalf("VolumeProfile() error = %v", err)
+	}
+
+	wantVolumes := []float64{3, 1, 9, 1}
+	if len(profile.Levels) != len(wantVolumes) {
+		t.Fatalf("Expected %d levels, got %d", len(wantVolumes), len(profile.Levels))
+	}
+	for i, want := range wantVolumes {
+		if profile.Levels[i].Volume != want {
+			t.Errorf("Level %d volume = %v, want %v", i, profile.Levels[i].Volume, want)
+		}
+	}
+
+	if profile.Levels[0].Low != 100 || profile.Levels[3].High != 200 {
+		t.Errorf("Levels span %v-%v, want 100-200", profile.Levels[0].Low, profile.Levels[3].High)
+	}
+	if profile.POCIndex != 2 {
+		t.Errorf("POCIndex = %d, want 2", profile.POCIndex)
+	}
+	if math.Abs(profile.PointOfControl-162.5) > 1e-9 {
+		t.Errorf("PointOfControl = %v, want 162.5", profile.PointOfControl)
+	}
+	if profile.TotalVolume != 14 {
+		t.Errorf("TotalVolume = %v, want 14", profile.TotalVolume)
+	}
+
+	// 70% of 14 is 9.8: the POC holds 9, and of its equal neighbours the
+	// higher one is added first
+	if profile.VALIndex != 2 || profile.VAHIndex != 3 {
+		t.Errorf("Value area levels = %d-%d, want 2-3", profile.VALIndex, profile.VAHIndex)
+	}
+	if profile.ValueAreaLow != 150 || profile.ValueAreaHigh != 200 {
+		t.Errorf("Value area = %v-%v, want 150-200", profile.ValueAreaLow, profile.ValueAreaHigh)
+	}
+	if profile.InValueArea(1) || !profile.InValueArea(3) {
+		t.Error("InValueArea() disagrees with the value area levels")
+	}
+}
+
+func TestVolumeProfile_ValueAreaGrowsTowardsVolume(t *testing.T) {
+	// 10 buckets of width 1 over 100-110
+	trades := makeTrades(
+		"100", "1",
+		"102", "6",
+		"103", "8",
+		"104", "10", // POC
+		"105", "2",
+		"106", "1",
+		"110", "2",
+	)
+
+	profile, err := VolumeProfile(trades, 10)
+	if err != nil {
+		t.Fatalf("VolumeProfile() error = %v", err)
+	}
+
+	// 70% of 30 is 21: 104 (10), then 103 (8) over 105 (2), then 102 (6)
+	if profile.VALIndex != 2 || profile.VAHIndex != 4 {
+		t.Errorf("Value area levels = %d-%d, want 2-4", profile.VALIndex, profile.VAHIndex)
+	}
+	if profile.ValueAreaLow != 102 || profile.ValueAreaHigh != 105 {
+		t.Errorf("Value area = %v-%v, want 102-105", profile.ValueAreaLow, profile.ValueAreaHigh)
+	}
+}
+
+func TestCandleVolumeProfile(t *testing.T) {
+	candles := []*models.Candle{
+		// Spread evenly over the four levels of 100-200
+		{LowPrice: "100", HighPrice: "200", Volume: "4"},
+		// No range: all in the level of its price
+		{LowPrice: "150", HighPrice: "150", Volume: "2"},
+		// Half in each of the two levels it spans
+		{LowPrice: "115", HighPrice: "135", Volume: "2"},
+		{LowPrice: "bad", HighPrice: "135", Volume: "2"},
+	}
+
+	profile, err := CandleVolumeProfile(candles, 4)
+	if err != nil {
+		t.Fatalf("CandleVolumeProfile() error = %v", err)
+	}
+
+	wantVolumes := []float64{2, 2, 3, 1}
+	for i, want := range wantVolumes {
+		if math.Abs(profile.Levels[i].Volume-want) > 1e-9 {
+			t.Errorf("Level %d volume = %v, want %v", i, profile.Levels[i].Volume, want)
+		}
+	}
+	if profile.TotalVolume != 8 || profile.POCIndex != 2 {
+		t.Errorf("TotalVolume = %v, POCIndex = %d, want 8 and 2", profile.TotalVolume, profile.POCIndex)
+	}
+
+	if _, err := CandleVolumeProfile(candles[3:], 4); err == nil {
+		t.Error("Expected error when no candle parses")
+	}
+}
+
+func TestVolumeProfile_EdgeCases(t *testing.T) {
+	if _, err := VolumeProfile(makeTrades("100", "1"), 0); err == nil {
+		t.Error("Expected error for zero buckets")
+	}
+	if _, err := VolumeProfile(makeTrades("bad", "1"), 10); err == nil {
+		t.Error("Expected error when no trade parses")
+	}
+
+	profile, err := VolumeProfile(makeTrades("100", "1", "100", "2"), 10)
+	if err != nil {
+		t.Fatalf("VolumeProfile() error = %v", err)
+	}
+	if len(profile.Levels) != 1 || profile.Levels[0].Volume != 3 || profile.PointOfControl != 100 {
+		t.Errorf("Single price profile = %+v, want one level of volume 3 at 100", profile)
+	}
+}
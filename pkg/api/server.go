@@ -0,0 +1,362 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/messaging"
+	"binance-redis-streamer/pkg/storage"
+	"binance-redis-streamer/pkg/symbolutil"
+	"binance-redis-streamer/pkg/timeutil"
+)
+
+const (
+	// readyTimeout bounds the Redis ping behind /readyz
+	readyTimeout = 2 * time.Second
+	// streamWriteTimeout drops WebSocket clients that stop reading
+	streamWriteTimeout = 10 * time.Second
+	// defaultCandlePeriod is how far back candles go without ?period=
+	defaultCandlePeriod = time.Hour
+)
+
+// Server is the read API over the streamer's data: latest trades, 24h
+// volumes, recent candles and tracked symbols over REST, live trades over WebSocket, plus
+// health checks and Prometheus metrics
+type Server struct {
+	cfg      *config.Config
+	store    storage.TradeStore
+	bus      messaging.MessageBus
+	srv      *http.Server
+	upgrader websocket.Upgrader
+	candles  CandleStore // Optional, see SetCandleStore
+}
+
+// CandleStore reads stored minute candles, typically a storage.PostgresStore
+type CandleStore interface {
+	GetAggregatedCandles(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*models.Candle, error)
+}
+
+// NewServer creates an API server listening on cfg.API.Addr. Reads go to
+// store, which is typically a storage.CachedTradeStore, and the trade stream
+// is fed from bus.
+func NewServer(cfg *config.Config, store storage.TradeStore, bus messaging.MessageBus) *Server {
+	s := &Server{
+		cfg:   cfg,
+		store: store,
+		bus:   bus,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/healthz", s.handleHealth).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", s.handleReady).Methods(http.MethodGet)
+	r.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+
+	v1 := r.PathPrefix("/api/v1").Subrouter()
+	v1.HandleFunc("/symbols", s.handleSymbols).Methods(http.MethodGet)
+	v1.HandleFunc("/symbols/{symbol}/latest", s.handleLatestTrade).Methods(http.MethodGet)
+	v1.HandleFunc("/symbols/{symbol}/volume", s.handleVolume).Methods(http.MethodGet)
+	v1.HandleFunc("/symbols/{symbol}/candles", s.handleCandles).Methods(http.MethodGet)
+	v1.HandleFunc("/stream", s.handleStream).Methods(http.MethodGet)
+
+	s.srv = &http.Server{
+		Addr:              cfg.API.Addr,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// SetCandleStore makes /candles read stored minute candles for periods the
+// Redis trade history no longer covers, as it keeps a limited number of
+// trades per symbol. Must be called before Start.
+func (s *Server) SetCandleStore(store CandleStore) {
+	s.candles = store
+}
+
+// Handler returns the server's request router
+func (s *Server) Handler() http.Handler {
+	return s.srv.Handler
+}
+
+// Start serves requests until ctx is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := s.srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down API server: %v", err)
+		}
+	}()
+
+	log.Printf("API server listening on %s", s.srv.Addr)
+	if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("API server failed: %w", err)
+	}
+	return nil
+}
+
+// handleHealth reports that the process is up
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReady reports whether Redis is reachable
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	if err := s.store.GetRedisClient().Ping(ctx).Err(); err != nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("redis unavailable: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// handleSymbols lists the symbols the streamer is tracking
+func (s *Server) handleSymbols(w http.ResponseWriter, r *http.Request) {
+	symbolsKey := fmt.Sprintf("%ssymbols", s.cfg.Redis.KeyPrefix)
+	symbols, err := s.store.GetRedisClient().SMembers(r.Context(), symbolsKey).Result()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get symbols: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"symbols": symbols})
+}
+
+// handleLatestTrade returns the most recent trade of a symbol
+func (s *Server) handleLatestTrade(w http.ResponseWriter, r *http.Request) {
+	symbol, ok := s.symbolParam(w, r)
+	if !ok {
+		return
+	}
+
+	trade, err := s.store.GetLatestTrade(r.Context(), symbol)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		writeError(w, http.StatusNotFound, fmt.Errorf("no trades for %s", symbol))
+		return
+	case errors.Is(err, storage.ErrUnavailable):
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, trade)
+}
+
+// handleVolume returns the 24h volume of a symbol
+func (s *Server) handleVolume(w http.ResponseWriter, r *http.Request) {
+	symbol, ok := s.symbolParam(w, r)
+	if !ok {
+		return
+	}
+
+	volume, err := s.store.Get24hVolume(r.Context(), symbol)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"symbol": symbol, "volume_24h": volume})
+}
+
+// handleCandles returns one-minute candles of a symbol built from its trade
+// history over ?period= (default 1h, at most the Redis retention period).
+// ?numeric=true encodes prices and volume as JSON numbers.
+func (s *Server) handleCandles(w http.ResponseWriter, r *http.Request) {
+	symbol, ok := s.symbolParam(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+
+	period := defaultCandlePeriod
+	if value := query.Get("period"); value != "" {
+		var err error
+		if period, err = timeutil.ParseDuration(value, s.cfg.Redis.RetentionPeriod); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	var numeric bool
+	if value := query.Get("numeric"); value != "" {
+		var err error
+		if numeric, err = strconv.ParseBool(value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid numeric %q", value))
+			return
+		}
+	}
+
+	end := time.Now()
+	start := end.Add(-period)
+	if s.candles != nil {
+		covered, err := s.store.HistoryCovers(r.Context(), symbol, start)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if !covered {
+			s.writeStoredCandles(w, r, symbol, start, end, numeric)
+			return
+		}
+	}
+
+	trades, err := s.store.GetTradeHistory(r.Context(), symbol, start, end)
+	var total int64
+	if err == nil {
+		total, err = s.store.CountTradesInRange(r.Context(), symbol, start, end)
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	// Candles are built from at most the latest trades GetTradeHistory
+	// returns; the header tells clients how many the period really has
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	writeJSON(w, http.StatusOK, models.CandleSeries{Symbol: symbol, Candles: models.MinuteCandles(trades), Numeric: numeric})
+}
+
+// writeStoredCandles answers /candles from the candle store, with the trades
+// of the returned candles in X-Total-Count
+func (s *Server) writeStoredCandles(w http.ResponseWriter, r *http.Request, symbol string, start, end time.Time, numeric bool) {
+	candles, err := s.candles.GetAggregatedCandles(r.Context(), symbol, start, end, "1m")
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	var total int64
+	for _, candle := range candles {
+		total += candle.TradeCount
+	}
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	writeJSON(w, http.StatusOK, models.CandleSeries{Symbol: symbol, Candles: candles, Numeric: numeric})
+}
+
+// writeStoreError answers with 503 when the store is unreachable and 500
+// for any other error
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+// handleStream upgrades to a WebSocket and forwards trade envelopes from the
+// bus, optionally filtered by a comma-separated symbols query parameter
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	filter := make(map[string]bool)
+	for _, symbol := range strings.Split(r.URL.Query().Get("symbols"), ",") {
+		if symbol = strings.TrimSpace(symbol); symbol == "" {
+			continue
+		}
+		normalized, err := symbolutil.Normalize(symbol)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		filter[normalized] = true
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade stream connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Clients only listen; reading detects when they go away
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// A single symbol only needs its own channel
+	var only string
+	if len(filter) == 1 {
+		for symbol := range filter {
+			only = symbol
+		}
+	}
+
+	err = s.bus.SubscribeSymbol(ctx, only, func(env *messaging.Envelope) error {
+		if len(filter) > 0 && !filter[strings.ToUpper(env.Payload.Data.Symbol)] {
+			return nil
+		}
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if err := conn.WriteJSON(env); err != nil {
+			cancel()
+			return fmt.Errorf("failed to write to stream client: %w", err)
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Trade stream ended: %v", err)
+	}
+}
+
+// symbolParam returns the normalized {symbol} of the request. It responds
+// 400 and returns false when the symbol is malformed or, not being tracked,
+// is close to tracked symbols, e.g. BTCUSD for BTCUSDT. Other untracked
+// symbols pass, as they may just have no trades yet.
+func (s *Server) symbolParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	symbol, err := symbolutil.Normalize(mux.Vars(r)["symbol"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return "", false
+	}
+
+	symbolsKey := fmt.Sprintf("%ssymbols", s.cfg.Redis.KeyPrefix)
+	tracked, err := s.store.GetRedisClient().SMembers(r.Context(), symbolsKey).Result()
+	if err != nil {
+		// The handler reports the outage when it reads the symbol's data
+		return symbol, true
+	}
+	var unknown *symbolutil.UnknownSymbolError
+	if _, err := symbolutil.Validate(symbol, tracked); errors.As(err, &unknown) && len(unknown.Suggestions) > 0 {
+		writeError(w, http.StatusBadRequest, err)
+		return "", false
+	}
+	return symbol, true
+}
+
+// writeJSON encodes v as the response body with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to encode API response: %v", err)
+	}
+}
+
+// writeError responds with a JSON error message
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
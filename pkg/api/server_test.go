@@ -0,0 +1,351 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/messaging"
+	"binance-redis-streamer/pkg/storage"
+)
+
+// setupTestServer serves the API from a store backed by miniredis
+func setupTestServer(t *testing.T) (*httptest.Server, *storage.RedisStore, *messaging.RedisPubSub, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.SetExchange("test")
+
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := messaging.NewRedisPubSub(store.GetRedisClient())
+
+	srv := httptest.NewServer(NewServer(cfg, store, bus).Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		store.Close()
+		mr.Close()
+	})
+	return srv, store, bus, mr
+}
+
+// getJSON fetches path and decodes the JSON body into v, returning the status
+func getJSON(t *testing.T, srv *httptest.Server, path string, v interface{}) int {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: failed to decode body: %v", path, err)
+	}
+	return resp.StatusCode
+}
+
+func TestServer_RESTEndpoints(t *testing.T) {
+	srv, store, _, mr := setupTestServer(t)
+
+	trade := &models.Trade{Symbol: "BTCUSDT", Price: "50000.00", Quantity: "0.5", Time: time.Now()}
+	if err := store.StoreTrade(context.Background(), trade); err != nil {
+		t.Fatal(err)
+	}
+	mr.Set("test:ETHUSDT:volume:24h", "1234.5")
+
+	var health map[string]string
+	if status := getJSON(t, srv, "/healthz", &health); status != http.StatusOK || health["status"] != "ok" {
+		t.Errorf("Expected healthy response, got %d %v", status, health)
+	}
+	if status := getJSON(t, srv, "/readyz", &health); status != http.StatusOK {
+		t.Errorf("Expected ready response, got %d %v", status, health)
+	}
+
+	var symbols map[string][]string
+	getJSON(t, srv, "/api/v1/symbols", &symbols)
+	if len(symbols["symbols"]) != 1 || symbols["symbols"][0] != "BTCUSDT" {
+		t.Errorf("Expected [BTCUSDT], got %v", symbols["symbols"])
+	}
+
+	var latest models.Trade
+	if status := getJSON(t, srv, "/api/v1/symbols/btcusdt/latest", &latest); status != http.StatusOK || latest.Price != "50000.00" {
+		t.Errorf("Expected latest price 50000.00, got %d %+v", status, latest)
+	}
+
+	var volume map[string]interface{}
+	getJSON(t, srv, "/api/v1/symbols/ethusdt/volume", &volume)
+	if volume["volume_24h"] != 1234.5 {
+		t.Errorf("Expected 24h volume 1234.5, got %v", volume["volume_24h"])
+	}
+
+	var missing map[string]string
+	if status := getJSON(t, srv, "/api/v1/symbols/ETHUSDT/latest", &missing); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for a symbol without trades, got %d", status)
+	}
+}
+
+func TestServer_LatestTradeAfterMiss(t *testing.T) {
+	srv, store, _, _ := setupTestServer(t)
+
+	var missing map[string]string
+	if status := getJSON(t, srv, "/api/v1/symbols/SOLUSDT/latest", &missing); status != http.StatusNotFound || missing["error"] == "" {
+		t.Fatalf("Expected 404 with an error for a symbol without trades, got %d %v", status, missing)
+	}
+
+	// The first trade of the symbol is served right away
+	now := time.Now()
+	if err := store.StoreTrade(context.Background(), &models.Trade{Symbol: "SOLUSDT", Price: "150", Quantity: "1", Time: now, EventTime: now}); err != nil {
+		t.Fatal(err)
+	}
+	var latest models.Trade
+	if status := getJSON(t, srv, "/api/v1/symbols/SOLUSDT/latest", &latest); status != http.StatusOK || latest.Price != "150" {
+		t.Errorf("Expected the new trade after a miss, got %d %+v", status, latest)
+	}
+}
+
+func TestServer_Candles(t *testing.T) {
+	srv, store, _, _ := setupTestServer(t)
+	ctx := context.Background()
+
+	minute := time.Now().Add(-time.Minute).Truncate(time.Minute)
+	for i, price := range []string{"100", "105", "99"} {
+		tradeTime := minute.Add(time.Duration(i) * time.Second)
+		err := store.StoreTrade(ctx, &models.Trade{Symbol: "BTCUSDT", Price: price, Quantity: "2", TradeID: int64(i + 1), Time: tradeTime, EventTime: tradeTime})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var series models.CandleSeries
+	if status := getJSON(t, srv, "/api/v1/symbols/btcusdt/candles?period=1h", &series); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if series.Symbol != "BTCUSDT" || len(series.Candles) != 1 {
+		t.Fatalf("Expected one BTCUSDT candle, got %+v", series)
+	}
+	if candle := series.Candles[0]; !candle.Timestamp.Equal(minute) || candle.OpenPrice != "100" || candle.HighPrice != "105" ||
+		candle.LowPrice != "99" || candle.ClosePrice != "99" || candle.Volume != "6" || candle.TradeCount != 3 {
+		t.Errorf("Unexpected candle %+v", candle)
+	}
+
+	// The numeric encoding sends prices as numbers
+	var raw struct {
+		Candles []map[string]interface{} `json:"candles"`
+	}
+	getJSON(t, srv, "/api/v1/symbols/BTCUSDT/candles?numeric=true", &raw)
+	if len(raw.Candles) != 1 || raw.Candles[0]["open"] != 100.0 {
+		t.Errorf("Expected numeric prices, got %+v", raw.Candles)
+	}
+
+	// The total trade count of the period is sent as a header
+	resp, err := http.Get(srv.URL + "/api/v1/symbols/BTCUSDT/candles?period=1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("X-Total-Count"); got != "3" {
+		t.Errorf("Expected X-Total-Count 3, got %q", got)
+	}
+
+	var empty map[string]interface{}
+	if status := getJSON(t, srv, "/api/v1/symbols/ETHUSDT/candles", &empty); status != http.StatusOK || len(empty["candles"].([]interface{})) != 0 {
+		t.Errorf("Expected an empty candle list for a symbol without trades, got %d %v", status, empty)
+	}
+
+	var body map[string]string
+	for _, query := range []string{"period=2y", "period=soon", "numeric=maybe"} {
+		if status := getJSON(t, srv, "/api/v1/symbols/BTCUSDT/candles?"+query, &body); status != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, status)
+		}
+	}
+}
+
+// fakeCandleStore returns fixed candles and records the ranges read
+type fakeCandleStore struct {
+	candles []*models.Candle
+	reads   int
+}
+
+func (f *fakeCandleStore) GetAggregatedCandles(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*models.Candle, error) {
+	f.reads++
+	return f.candles, nil
+}
+
+func TestServer_CandlesFallBackToStoredCandles(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.Redis.MaxTradesPerKey = 2
+	cfg.SetExchange("test")
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	stored := &fakeCandleStore{candles: []*models.Candle{{Timestamp: time.Now().Add(-50 * time.Minute).Truncate(time.Minute), OpenPrice: "90", HighPrice: "95", LowPrice: "89", ClosePrice: "94", Volume: "7", TradeCount: 12}}}
+	server := NewServer(cfg, store, messaging.NewRedisPubSub(store.GetRedisClient()))
+	server.SetCandleStore(stored)
+	srv := httptest.NewServer(server.Handler())
+	defer srv.Close()
+
+	// The history keeps the last two of three trades, so it no longer
+	// reaches back to the start of the period
+	ctx := context.Background()
+	base := time.Now().Add(-40 * time.Minute)
+	for i := 0; i < 3; i++ {
+		tradeTime := base.Add(time.Duration(i) * 10 * time.Minute)
+		if err := store.StoreTrade(ctx, &models.Trade{Symbol: "BTCUSDT", Price: "100", Quantity: "1", TradeID: int64(i + 1), Time: tradeTime, EventTime: tradeTime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/api/v1/symbols/BTCUSDT/candles?period=1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var series struct {
+		Candles []map[string]interface{} `json:"candles"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&series)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.reads != 1 || len(series.Candles) != 1 || series.Candles[0]["open"] != "90" {
+		t.Errorf("Expected the stored candle, got %d reads and %+v", stored.reads, series.Candles)
+	}
+	if got := resp.Header.Get("X-Total-Count"); got != "12" {
+		t.Errorf("Expected X-Total-Count 12, got %q", got)
+	}
+
+	// A period the history covers is still built from its trades
+	getJSON(t, srv, "/api/v1/symbols/BTCUSDT/candles?period=25m", &series)
+	if stored.reads != 1 || len(series.Candles) != 1 || series.Candles[0]["open"] != "100" {
+		t.Errorf("Expected a candle from the trade history, got %d reads and %+v", stored.reads, series.Candles)
+	}
+}
+
+func TestServer_InvalidSymbols(t *testing.T) {
+	srv, store, _, _ := setupTestServer(t)
+
+	now := time.Now()
+	if err := store.StoreTrade(context.Background(), &models.Trade{Symbol: "BTCUSDT", Price: "50000", Quantity: "1", Time: now, EventTime: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	var latest models.Trade
+	if status := getJSON(t, srv, "/api/v1/symbols/btc-usdt/latest", &latest); status != http.StatusOK || latest.Price != "50000" {
+		t.Errorf("Expected btc-usdt to be read as BTCUSDT, got %d %+v", status, latest)
+	}
+
+	var body map[string]string
+	for _, path := range []string{"/api/v1/symbols/BTC$USDT/latest", "/api/v1/symbols/btc*/volume", "/api/v1/symbols/BTCUSD/candles", "/api/v1/stream?symbols=BTCUSDT,b@d"} {
+		if status := getJSON(t, srv, path, &body); status != http.StatusBadRequest || body["error"] == "" {
+			t.Errorf("Expected 400 with an error for %s, got %d %v", path, status, body)
+		}
+	}
+	getJSON(t, srv, "/api/v1/symbols/BTCUSD/latest", &body)
+	if !strings.Contains(body["error"], "did you mean BTCUSDT?") {
+		t.Errorf("Expected a suggestion for BTCUSD, got %q", body["error"])
+	}
+}
+
+func TestServer_ReadyWhenRedisDown(t *testing.T) {
+	srv, _, _, mr := setupTestServer(t)
+	mr.Close()
+
+	var body map[string]string
+	if status := getJSON(t, srv, "/readyz", &body); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with Redis down, got %d", status)
+	}
+}
+
+func TestServer_LatestTradeWhenRedisDown(t *testing.T) {
+	srv, _, _, mr := setupTestServer(t)
+	mr.Close()
+
+	// An outage is not reported as a missing symbol
+	var body map[string]string
+	if status := getJSON(t, srv, "/api/v1/symbols/BTCUSDT/latest", &body); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with Redis down, got %d", status)
+	}
+}
+
+func TestServer_Metrics(t *testing.T) {
+	srv, _, _, _ := setupTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected metrics to be served, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_StreamFiltersSymbols(t *testing.T) {
+	srv, _, bus, _ := setupTestServer(t)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/stream?symbols=ethusdt"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer conn.Close()
+
+	// Publish until the server's subscription is up and forwards a trade
+	received := make(chan *messaging.Envelope, 1)
+	go func() {
+		var env messaging.Envelope
+		if err := conn.ReadJSON(&env); err == nil {
+			received <- &env
+		}
+	}()
+
+	ctx := context.Background()
+	deadline := time.After(2 * time.Second)
+	for {
+		for _, symbol := range []string{"BTCUSDT", "ETHUSDT"} {
+			trade := &models.AggTradeEvent{Data: models.TradeData{Symbol: symbol, Price: "1"}}
+			if err := bus.Publish(ctx, trade); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		select {
+		case env := <-received:
+			if env.Payload.Data.Symbol != "ETHUSDT" {
+				t.Errorf("Expected only ETHUSDT trades, got %s", env.Payload.Data.Symbol)
+			}
+			return
+		case <-deadline:
+			t.Fatal("Expected a trade on the stream")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package binance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"binance-redis-streamer/internal/jsoncodec"
+	"binance-redis-streamer/internal/models"
+)
+
+// BookTickerStore keeps the spread and imbalance series of book ticker
+// updates, e.g. *storage.RedisStore
+type BookTickerStore interface {
+	StoreBookTicker(ctx context.Context, ticker *models.BookTicker, at time.Time) error
+}
+
+// bookTickerStream marks the stream name of a bookTicker message
+var bookTickerStream = []byte(StreamBookTicker + `"`)
+
+// isBookTicker reports whether a combined-stream message is a book ticker
+// update, without decoding it
+func isBookTicker(message []byte) bool {
+	return bytes.Contains(message, bookTickerStream)
+}
+
+// bookTickerHandler stores book ticker updates as of their arrival, since
+// spot bookTicker events carry no event time
+func bookTickerHandler(store BookTickerStore) MessageHandler {
+	return MessageHandlerFunc(func(ctx context.Context, message []byte) error {
+		var event models.BookTickerEvent
+		if err := jsoncodec.Unmarshal(message, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal book ticker: %w", err)
+		}
+		if err := store.StoreBookTicker(ctx, &event.Data, time.Now()); err != nil {
+			return fmt.Errorf("failed to store book ticker: %w", err)
+		}
+		return nil
+	})
+}
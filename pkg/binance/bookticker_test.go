@@ -0,0 +1,89 @@
+package binance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+)
+
+// bookTickerRecorder is a trade store that also keeps book tickers
+type bookTickerRecorder struct {
+	*mockStore
+	mu      sync.Mutex
+	tickers []models.BookTicker
+}
+
+func (r *bookTickerRecorder) StoreBookTicker(ctx context.Context, ticker *models.BookTicker, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tickers = append(r.tickers, *ticker)
+	return nil
+}
+
+func TestStreamTrades_BookTicker(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RawQuery
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"stream":"btcusdt@bookTicker","data":{"u":400900217,"s":"BTCUSDT","b":"25.35190000","B":"31.21000000","a":"25.36520000","A":"40.66000000"}}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"stream":"btcusdt@trade","data":{"e":"trade","E":1,"s":"BTCUSDT","t":1,"p":"25.36","q":"1","T":1,"m":false}}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Binance.BookTicker = true
+	store := &bookTickerRecorder{mockStore: newMockStore()}
+	client := NewTestClient(cfg, store)
+	client.streamURL = "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trades := make(chan []byte, 2)
+	var tickers atomic.Int32
+	go client.StreamTrades(ctx, []string{"BTCUSDT"}, func(message []byte) error {
+		if message == nil {
+			tickers.Add(1)
+			return nil
+		}
+		trades <- message
+		return nil
+	})
+
+	select {
+	case message := <-trades:
+		if !strings.Contains(string(message), "@trade") {
+			t.Errorf("Expected only trades to reach the handler, got %s", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the trade")
+	}
+	// Sent before the trade, so already handled
+	if got := tickers.Load(); got != 1 {
+		t.Errorf("Expected the ticker passed to the handler as one nil message, got %d", got)
+	}
+
+	if want := "streams=btcusdt@trade/btcusdt@bookTicker"; requested != want {
+		t.Errorf("Expected %q, got %q", want, requested)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.tickers) != 1 || store.tickers[0].BidQty != "31.21000000" || store.tickers[0].AskPrice != "25.36520000" {
+		t.Errorf("Expected the book ticker to be stored, got %+v", store.tickers)
+	}
+}
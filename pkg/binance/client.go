@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,113 +15,221 @@ import (
 
 	"github.com/gorilla/websocket"
 
+	"binance-redis-streamer/internal/jsoncodec"
 	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/breaker"
 	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/exchange"
 	"binance-redis-streamer/pkg/storage"
 )
 
 // Client represents a Binance WebSocket client
 type Client struct {
-	config  *config.Config
-	store   storage.TradeStore
-	baseURL string
-	wsConn  *websocket.Conn
-	mu      sync.RWMutex
-	isTest  bool
-	debug   bool
+	config    *config.Config
+	store     storage.TradeStore
+	baseURL   string
+	streamURL string
+	wsConn    *websocket.Conn
+	mu        sync.RWMutex // Guards volumes and streamTypes
+	isTest    bool
+	debug     bool
+	router    *MessageRouter
+	rest      *breaker.Breaker   // Guards REST calls during Binance outages
+	connects  *connLimiter       // Keeps WebSocket connection attempts under the Binance cap
+	backfills *connLimiter       // Keeps gap recovery under aggTradesPerSecond requests
+	volumes   map[string]float64 // 24h quote volumes fetched by the last GetSymbols
+	// Stream type overrides read by the last GetSymbolStreamConfigs, keyed by
+	// upper-case symbol
+	streamTypes map[string]string
+	http        *http.Client      // REST calls, through the configured proxy
+	dialer      *websocket.Dialer // Stream connections, through the configured proxy
+
+	userDataKeepAlive time.Duration // How often the user-data listen key is extended
 }
 
+var (
+	_ exchange.Client           = (*Client)(nil)
+	_ exchange.StreamURLBuilder = (*Client)(nil)
+	_ exchange.VolumeReporter   = (*Client)(nil)
+	_ exchange.GapRecoverer     = (*Client)(nil)
+)
+
 // NewClient creates a new Binance client
 func NewClient(cfg *config.Config, store storage.TradeStore) *Client {
-	return &Client{
-		config:  cfg,
-		store:   store,
-		baseURL: cfg.Binance.BaseURL,
-		debug:   cfg.Debug,
-	}
+	ep := resolveEndpoints(cfg.Binance)
+	c := &Client{
+		config:    cfg,
+		store:     store,
+		baseURL:   ep.rest,
+		streamURL: ep.stream,
+		debug:     cfg.Debug,
+		rest:      breaker.New("binance-rest", cfg.Breaker),
+		connects:  newConnLimiter(connectAttemptLimit, connectAttemptWindow),
+		backfills: newConnLimiter(aggTradesPerSecond, time.Second),
+		http:      NewHTTPClient(cfg.Binance),
+		dialer:    newWSDialer(cfg.Binance),
+
+		userDataKeepAlive: listenKeyKeepAlive,
+	}
+	c.registerDefaultHandlers()
+	return c
 }
 
 // NewTestClient creates a new Binance client for testing
 func NewTestClient(cfg *config.Config, store storage.TradeStore) *Client {
-	return &Client{
-		config:  cfg,
-		store:   store,
-		baseURL: cfg.Binance.BaseURL,
-		isTest:  true,
-		debug:   cfg.Debug,
+	ep := resolveEndpoints(cfg.Binance)
+	c := &Client{
+		config:    cfg,
+		store:     store,
+		baseURL:   ep.rest,
+		streamURL: ep.stream,
+		isTest:    true,
+		debug:     cfg.Debug,
+		rest:      breaker.New("binance-rest", cfg.Breaker),
+		connects:  newConnLimiter(connectAttemptLimit, connectAttemptWindow),
+		backfills: newConnLimiter(aggTradesPerSecond, time.Second),
+		http:      NewHTTPClient(cfg.Binance),
+		dialer:    newWSDialer(cfg.Binance),
+
+		userDataKeepAlive: listenKeyKeepAlive,
+	}
+	c.registerDefaultHandlers()
+	return c
+}
+
+// registerDefaultHandlers routes trade and aggTrade streams to the trade
+// handler, and bookTicker and miniTicker streams to the store when it keeps
+// them
+func (c *Client) registerDefaultHandlers() {
+	c.router = NewMessageRouter()
+	c.router.Register(StreamTrade, MessageHandlerFunc(c.handleTrade))
+	c.router.Register(StreamAggTrade, MessageHandlerFunc(c.handleTrade))
+	if store, ok := c.store.(BookTickerStore); ok {
+		c.router.Register(StreamBookTicker, bookTickerHandler(store))
 	}
+	if store, ok := c.store.(MiniTickerStore); ok {
+		c.router.Register(StreamMiniTicker, miniTickerHandler(store))
+	}
+}
+
+// RegisterHandler routes messages of a stream type suffix such as "@depth" or
+// "@bookTicker" to handler. Registering "@trade" or "@aggTrade" replaces the
+// default trade handler.
+func (c *Client) RegisterHandler(streamSuffix string, handler MessageHandler) {
+	c.router.Register(streamSuffix, handler)
 }
 
-// GetSymbols fetches all available symbols from Binance
+// GetSymbols fetches all available symbols from Binance. Main symbols come
+// first and, with MainSymbolsAlwaysOn, are streamed whatever their volume,
+// status or MaxSymbols; otherwise they are dropped like other pairs when not
+// trading or below MinDailyVolume, and count toward MaxSymbols.
 func (c *Client) GetSymbols(ctx context.Context) ([]string, error) {
 	if c.debug {
 		log.Println("Fetching symbols from Binance...")
 	}
 
-	// If main symbols are configured and no additional symbols are allowed
-	if len(c.config.Binance.MainSymbols) > 0 && c.config.Binance.MaxSymbols <= len(c.config.Binance.MainSymbols) {
-		symbols := make([]string, len(c.config.Binance.MainSymbols))
-		for i, s := range c.config.Binance.MainSymbols {
-			symbols[i] = strings.ToLower(s)
-		}
-		if c.debug {
-			log.Printf("Using configured main symbols only: %v", symbols)
-		}
-		return symbols, nil
-	}
+	mainSymbols := c.prioritySymbols(ctx)
+	alwaysOn := c.config.Binance.MainSymbolsAlwaysOn
+	// MaxSymbols of 0 or less leaves the number of symbols unlimited
+	limit := c.config.Binance.MaxSymbols
+	capped := limit > 0
+	// Main symbols leave no room for others
+	mainOnly := len(mainSymbols) > 0 && capped && limit <= len(mainSymbols)
 
 	// First get exchange info
-	url := fmt.Sprintf("%s/api/v3/exchangeInfo", c.config.Binance.BaseURL)
-	exchangeInfo, err := c.fetchExchangeInfo(ctx, url)
+	url := fmt.Sprintf("%s/api/v3/exchangeInfo", c.baseURL)
+	var exchangeInfo *models.ExchangeInfo
+	err := c.rest.Do(func() (err error) {
+		exchangeInfo, err = c.fetchExchangeInfo(ctx, url)
+		return err
+	})
 	if err != nil {
+		if mainOnly && alwaysOn {
+			log.Printf("Warning: streaming main symbols without checking their status: %v", err)
+			return mainSymbols, nil
+		}
 		return nil, err
 	}
+	c.cachePrecisions(ctx, exchangeInfo)
+	mainSymbols = c.checkMainSymbols(exchangeInfo, mainSymbols)
 
-	// Get 24hr ticker data if volume filtering is enabled
-	var volumeData map[string]float64
-	if c.config.Binance.MinDailyVolume > 0 {
-		volumeData, err = c.fetch24hVolume(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch volume data: %w", err)
+	if mainOnly && alwaysOn {
+		if c.debug {
+			log.Printf("Using configured main symbols only: %v", mainSymbols)
 		}
+		return mainSymbols, nil
 	}
 
 	// First, add main symbols
 	symbolMap := make(map[string]bool)
-	for _, s := range c.config.Binance.MainSymbols {
-		symbolMap[strings.ToLower(s)] = true
-	}
-
-	// Then add additional symbols up to MaxSymbols
-	remainingSlots := c.config.Binance.MaxSymbols - len(symbolMap)
-	if remainingSlots > 0 {
-		for _, sym := range exchangeInfo.Symbols {
-			symbol := strings.ToLower(sym.Symbol)
-			// Skip if already added or not a USDT pair or not trading
-			if symbolMap[symbol] || !strings.HasSuffix(symbol, "usdt") || sym.Status != "TRADING" {
-				continue
-			}
+	for _, s := range mainSymbols {
+		symbolMap[s] = true
+	}
 
-			// Check volume if filtering is enabled
-			if c.config.Binance.MinDailyVolume > 0 {
-				volume, exists := volumeData[symbol]
-				if !exists || volume < c.config.Binance.MinDailyVolume {
-					continue
-				}
+	// Then collect trading pairs quoted in the configured assets, and only
+	// spot-tradable ones with SpotOnly
+	var candidates []string
+	for _, sym := range exchangeInfo.Symbols {
+		symbol := strings.ToLower(sym.Symbol)
+		if symbolMap[symbol] || sym.Status != "TRADING" || !c.hasQuoteAsset(sym) {
+			continue
+		}
+		if c.config.Binance.SpotOnly && !sym.IsSpotTradable() {
+			continue
+		}
+		candidates = append(candidates, symbol)
+	}
+
+	// Get 24hr ticker data when filtering by volume, choosing among more
+	// candidates than there are slots or balancing groups by volume, and keep
+	// the highest-volume symbols
+	if c.config.Binance.MinDailyVolume > 0 || c.config.Binance.BalanceGroupsByVolume ||
+		(capped && len(candidates) > limit-len(symbolMap)) {
+		var volumeData map[string]float64
+		err = c.rest.Do(func() (err error) {
+			volumeData, err = c.fetch24hVolume(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch volume data: %w", err)
+		}
+		c.mu.Lock()
+		c.volumes = volumeData
+		c.mu.Unlock()
+
+		qualifying := candidates[:0]
+		for _, symbol := range candidates {
+			if volumeData[symbol] >= c.config.Binance.MinDailyVolume {
+				qualifying = append(qualifying, symbol)
 			}
+		}
+		candidates = qualifying
 
-			// Add symbol if we haven't reached the limit
-			if len(symbolMap) < c.config.Binance.MaxSymbols {
-				symbolMap[symbol] = true
-			} else {
-				break
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return volumeData[candidates[i]] > volumeData[candidates[j]]
+		})
+
+		if !alwaysOn {
+			qualifying := mainSymbols[:0]
+			for _, symbol := range mainSymbols {
+				if volumeData[symbol] >= c.config.Binance.MinDailyVolume {
+					qualifying = append(qualifying, symbol)
+				}
 			}
+			mainSymbols = qualifying
 		}
 	}
 
-	// Convert map to slice
-	symbols := make([]string, 0, len(symbolMap))
-	for symbol := range symbolMap {
+	// Main symbols in priority order, then the candidates filling the
+	// remaining slots
+	symbols := append([]string{}, mainSymbols...)
+	if !alwaysOn && capped && len(symbols) > limit {
+		symbols = symbols[:limit]
+	}
+	for _, symbol := range candidates {
+		if capped && len(symbols) >= limit {
+			break
+		}
 		symbols = append(symbols, symbol)
 	}
 
@@ -134,6 +243,108 @@ func (c *Client) GetSymbols(ctx context.Context) ([]string, error) {
 	return symbols, nil
 }
 
+// checkMainSymbols warns about main symbols exchangeInfo does not list as
+// trading, whose streams would carry no trades. They are kept when main
+// symbols are always on and dropped otherwise.
+func (c *Client) checkMainSymbols(exchangeInfo *models.ExchangeInfo, mainSymbols []string) []string {
+	statuses := make(map[string]string, len(exchangeInfo.Symbols))
+	for _, sym := range exchangeInfo.Symbols {
+		statuses[strings.ToLower(sym.Symbol)] = sym.Status
+	}
+
+	kept := make([]string, 0, len(mainSymbols))
+	for _, symbol := range mainSymbols {
+		status, listed := statuses[symbol]
+		switch {
+		case status == "TRADING":
+			kept = append(kept, symbol)
+			continue
+		case !listed:
+			log.Printf("Warning: main symbol %s is not listed on Binance", strings.ToUpper(symbol))
+		default:
+			log.Printf("Warning: main symbol %s is %s on Binance, not TRADING", strings.ToUpper(symbol), status)
+		}
+		if c.config.Binance.MainSymbolsAlwaysOn {
+			kept = append(kept, symbol)
+		}
+	}
+	return kept
+}
+
+// ListSymbols returns every symbol Binance currently trades, upper-cased,
+// whether or not it is streamed
+func (c *Client) ListSymbols(ctx context.Context) ([]string, error) {
+	url := fmt.Sprintf("%s/api/v3/exchangeInfo", c.baseURL)
+	var exchangeInfo *models.ExchangeInfo
+	err := c.rest.Do(func() (err error) {
+		exchangeInfo, err = c.fetchExchangeInfo(ctx, url)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := make([]string, 0, len(exchangeInfo.Symbols))
+	for _, sym := range exchangeInfo.Symbols {
+		if sym.Status == "TRADING" {
+			symbols = append(symbols, strings.ToUpper(sym.Symbol))
+		}
+	}
+	return symbols, nil
+}
+
+// SymbolVolumes returns the 24h quote volumes fetched by the last GetSymbols
+// call, keyed by lowercased symbol
+func (c *Client) SymbolVolumes() map[string]float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.volumes
+}
+
+// hasQuoteAsset reports whether a pair is quoted in one of the configured
+// quote assets, falling back to the symbol suffix when the exchange omits it
+func (c *Client) hasQuoteAsset(sym models.Symbol) bool {
+	for _, asset := range c.config.Binance.QuoteAssets {
+		if sym.QuoteAsset != "" {
+			if strings.EqualFold(sym.QuoteAsset, asset) {
+				return true
+			}
+		} else if strings.HasSuffix(strings.ToUpper(sym.Symbol), strings.ToUpper(asset)) {
+			return true
+		}
+	}
+	return false
+}
+
+// prioritySymbols merges the configured MainSymbols with the priority set
+// managed in Redis, lowercased and deduplicated. Redis errors fall back to the
+// configured list.
+func (c *Client) prioritySymbols(ctx context.Context) []string {
+	candidates := append([]string{}, c.config.Binance.MainSymbols...)
+
+	dynamic, err := c.store.GetPrioritySymbols(ctx)
+	if err != nil {
+		log.Printf("Warning: using configured main symbols only: %v", err)
+	}
+	candidates = append(candidates, dynamic...)
+
+	seen := make(map[string]bool, len(candidates))
+	symbols := make([]string, 0, len(candidates))
+	for _, s := range candidates {
+		symbol := strings.ToLower(strings.TrimSpace(s))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		if err := checkSymbols([]string{symbol}); err != nil {
+			log.Printf("Warning: ignoring priority symbol: %v", err)
+			continue
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
 // fetchExchangeInfo fetches exchange information from Binance
 func (c *Client) fetchExchangeInfo(ctx context.Context, url string) (*models.ExchangeInfo, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, http.NoBody)
@@ -141,7 +352,7 @@ func (c *Client) fetchExchangeInfo(ctx context.Context, url string) (*models.Exc
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch symbols: %w", err)
 	}
@@ -153,7 +364,7 @@ func (c *Client) fetchExchangeInfo(ctx context.Context, url string) (*models.Exc
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, statusError(resp.StatusCode, body)
 	}
 
 	var exchangeInfo models.ExchangeInfo
@@ -166,25 +377,34 @@ func (c *Client) fetchExchangeInfo(ctx context.Context, url string) (*models.Exc
 
 // fetch24hVolume fetches 24h volume data for all symbols
 func (c *Client) fetch24hVolume(ctx context.Context) (map[string]float64, error) {
-	url := fmt.Sprintf("%s/api/v3/ticker/24hr", c.config.Binance.BaseURL)
+	url := fmt.Sprintf("%s/api/v3/ticker/24hr", c.baseURL)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch volume data: %w", err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp.StatusCode, body)
+	}
+
 	var tickers []struct {
 		Symbol      string `json:"symbol"`
 		QuoteVolume string `json:"quoteVolume"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&tickers); err != nil {
+	if err := json.Unmarshal(body, &tickers); err != nil {
 		return nil, fmt.Errorf("failed to decode volume data: %w", err)
 	}
 
@@ -201,120 +421,100 @@ func (c *Client) fetch24hVolume(ctx context.Context) (map[string]float64, error)
 	return volumeData, nil
 }
 
-// StreamTrades starts streaming trades for the given symbols
-func (c *Client) StreamTrades(ctx context.Context) error {
-	symbols, err := c.GetSymbols(ctx)
-	if err != nil {
-		log.Printf("Error getting symbols: %v", err)
-		// Don't return error, try again after delay
-		time.Sleep(c.config.WebSocket.ReconnectDelay)
-		return c.StreamTrades(ctx)
-	}
+// Name returns the exchange identifier
+func (c *Client) Name() string {
+	return exchange.DefaultName
+}
 
-	if len(symbols) == 0 {
-		log.Println("No symbols to stream, retrying after delay...")
-		time.Sleep(c.config.WebSocket.ReconnectDelay)
-		return c.StreamTrades(ctx)
+// StreamTrades streams trades for symbols over one combined-stream connection
+// until ctx is cancelled or the connection fails. Each connection re-reads the
+// per-symbol stream types first. Connection attempts are rate limited to stay
+// under the Binance connection cap.
+func (c *Client) StreamTrades(ctx context.Context, symbols []string, handler exchange.MessageHandler) error {
+	if streams := len(symbols) * c.config.Binance.StreamsPerSymbol(); streams > config.MaxBinanceStreamsPerConn {
+		return fmt.Errorf("%d streams exceed the limit of %d per connection", streams, config.MaxBinanceStreamsPerConn)
 	}
-
-	var wg sync.WaitGroup
-	errChan := make(chan error, 1)
-
-	// Split symbols into groups
-	for i := 0; i < len(symbols); i += c.config.Binance.MaxStreamsPerConn {
-		end := i + c.config.Binance.MaxStreamsPerConn
-		if end > len(symbols) {
-			end = len(symbols)
-		}
-
-		symbolGroup := symbols[i:end]
-		wg.Add(1)
-		go func(symbols []string) {
-			defer wg.Done()
-			if err := c.handleSymbolGroup(ctx, symbols); err != nil {
-				select {
-				case errChan <- err:
-				default:
-				}
-			}
-		}(symbolGroup)
+	if err := checkSymbols(symbols); err != nil {
+		return err
 	}
-
-	// Wait for error or context cancellation
-	go func() {
-		wg.Wait()
-		close(errChan)
-	}()
-
-	select {
-	case err := <-errChan:
-		if err != nil {
-			log.Printf("Streaming error: %v, reconnecting...", err)
-			time.Sleep(c.config.WebSocket.ReconnectDelay)
-			return c.StreamTrades(ctx)
-		}
-	case <-ctx.Done():
-		return ctx.Err()
+	if _, err := c.GetSymbolStreamConfigs(ctx); err != nil {
+		log.Printf("Warning: keeping previous stream types: %v", err)
 	}
-
-	return nil
-}
-
-func (c *Client) handleSymbolGroup(ctx context.Context, symbols []string) error {
-	url := c.buildStreamURL(symbols)
-	if c.debug {
-		log.Printf("Connecting to stream URL for %d symbols", len(symbols))
+	streamURL := c.BuildStreamURL(symbols)
+	if len(streamURL) > exchange.MaxStreamURLLength {
+		return fmt.Errorf("stream URL for %d symbols is %d bytes, over the %d byte limit; stream fewer symbols per connection",
+			len(symbols), len(streamURL), exchange.MaxStreamURLLength)
 	}
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			if err := c.connectAndStream(ctx, url); err != nil {
-				if c.debug {
-					log.Printf("Stream error: %v, reconnecting...", err)
-				}
-				continue
-			}
-		}
+	if err := c.connects.Wait(ctx); err != nil {
+		return err
 	}
-}
 
-func (c *Client) buildStreamURL(symbols []string) string {
-	streams := make([]string, 0, len(symbols))
-	for _, symbol := range symbols {
-		streams = append(streams, fmt.Sprintf("%s@trade", symbol))
+	if c.debug {
+		log.Printf("Connecting to stream URL for %d symbols", len(symbols))
 	}
-	return fmt.Sprintf("wss://stream.binance.com:9443/stream?streams=%s", strings.Join(streams, "/"))
-}
 
-func (c *Client) connectAndStream(ctx context.Context, url string) error {
-	wsConn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	wsConn, _, err := c.dialer.DialContext(ctx, streamURL, nil)
 	if err != nil {
 		return fmt.Errorf("websocket dial error: %w", err)
 	}
 	defer wsConn.Close()
 
+	// Close the connection on cancellation so the blocking read returns
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			wsConn.Close()
+		case <-done:
+		}
+	}()
+
 	// Set up ping handler
 	go c.handlePing(ctx, wsConn)
 
 	// Process messages
 	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			_, message, err := wsConn.ReadMessage()
-			if err != nil {
-				return fmt.Errorf("websocket read error: %w", err)
+		_, message, err := wsConn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
+			return fmt.Errorf("websocket read error: %w", err)
+		}
 
-			if err := c.processMessage(ctx, message); err != nil {
-				log.Printf("Failed to process message: %v", err)
+		// Book and mini tickers are stored here; handler only gets trades,
+		// and nil for tickers so a ticker-only connection is not silent
+		if isBookTicker(message) || isMiniTicker(message) {
+			if err := c.router.Route(ctx, message); err != nil {
+				log.Printf("Failed to handle ticker: %v", err)
 			}
+			message = nil
+		}
+
+		if err := handler(message); err != nil {
+			log.Printf("Failed to handle message: %v", err)
+		}
+	}
+}
+
+// ParseTrade decodes a combined-stream trade or aggTrade message. With
+// fast decoding the event comes from the models.AcquireAggTradeEvent pool.
+func (c *Client) ParseTrade(message []byte) (*models.AggTradeEvent, error) {
+	if !c.config.Ingestion.FastDecode {
+		var event models.AggTradeEvent
+		if err := event.UnmarshalJSON(message); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal message: %w", err)
 		}
+		return &event, nil
+	}
+
+	event := models.AcquireAggTradeEvent()
+	if err := models.DecodeAggTradeEvent(message, event); err != nil {
+		models.ReleaseAggTradeEvent(event)
+		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
 	}
+	return event, nil
 }
 
 func (c *Client) handlePing(ctx context.Context, conn *websocket.Conn) {
@@ -334,14 +534,20 @@ func (c *Client) handlePing(ctx context.Context, conn *websocket.Conn) {
 	}
 }
 
+// processMessage dispatches a message to the handler of its stream type
 func (c *Client) processMessage(ctx context.Context, message []byte) error {
 	if c.debug {
 		// Debug: Print raw message
 		log.Printf("Raw WebSocket message: %s", string(message))
 	}
 
+	return c.router.Route(ctx, message)
+}
+
+// handleTrade stores a trade or aggTrade message
+func (c *Client) handleTrade(ctx context.Context, message []byte) error {
 	var event models.AggTradeEvent
-	if err := json.Unmarshal(message, &event); err != nil {
+	if err := jsoncodec.Unmarshal(message, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal message: %w", err)
 	}
 
@@ -372,11 +578,8 @@ func (c *Client) processMessage(ctx context.Context, message []byte) error {
 	return nil
 }
 
-// BuildStreamURL builds the WebSocket stream URL for the given symbols
+// BuildStreamURL builds the WebSocket stream URL for the given symbols, each
+// streamed with its stream type
 func (c *Client) BuildStreamURL(symbols []string) string {
-	streams := make([]string, 0, len(symbols))
-	for _, symbol := range symbols {
-		streams = append(streams, fmt.Sprintf("%s@trade", strings.ToLower(symbol)))
-	}
-	return fmt.Sprintf("wss://stream.binance.com:9443/stream?streams=%s", strings.Join(streams, "/"))
+	return combinedStreamURL(c.streamURL, symbols, c.symbolStreamType, c.config.Binance.BookTicker)
 }
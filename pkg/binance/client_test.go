@@ -1,10 +1,13 @@
 package binance
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"sort"
 	"strings"
 	"sync"
@@ -57,6 +60,7 @@ type mockStore struct {
 	mu        sync.RWMutex
 	trades    map[string]*models.Trade
 	rawTrades map[string][]byte
+	priority  []string
 }
 
 func newMockStore() *mockStore {
@@ -84,17 +88,25 @@ func (m *mockStore) GetTradeHistory(ctx context.Context, symbol string, start, e
 	return nil, nil
 }
 
+func (m *mockStore) CountTradesInRange(ctx context.Context, symbol string, start, end time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockStore) HistoryCovers(ctx context.Context, symbol string, start time.Time) (bool, error) {
+	return true, nil
+}
+
 func (m *mockStore) GetLatestTrade(ctx context.Context, symbol string) (*models.Trade, error) {
 	m.mu.RLock()
 	trade, ok := m.trades[symbol]
 	m.mu.RUnlock()
 	if !ok {
-		return nil, fmt.Errorf("no trade found")
+		return nil, storage.ErrNotFound
 	}
 	return trade, nil
 }
 
-func (m *mockStore) GetRedisClient() *redis.Client {
+func (m *mockStore) GetRedisClient() redis.UniversalClient {
 	return nil
 }
 
@@ -102,6 +114,10 @@ func (m *mockStore) Close() error {
 	return nil
 }
 
+func (m *mockStore) GetPrioritySymbols(ctx context.Context) ([]string, error) {
+	return m.priority, nil
+}
+
 func BenchmarkGetSymbols(b *testing.B) {
 	cfg := config.DefaultConfig()
 	cfg.Redis.URL = "redis://localhost:6379/0"
@@ -148,7 +164,7 @@ func BenchmarkProcessMessage(b *testing.B) {
 	cfg := config.DefaultConfig()
 	cfg.Redis.URL = "redis://localhost:6379/0"
 
-	store := &mockStore{}
+	store := newMockStore()
 	client := NewClient(cfg, store)
 
 	msg := []byte(`{"stream":"btcusdt@aggTrade","data":{"e":"aggTrade","E":1625232862,"s":"BTCUSDT","p":"50000.00","q":"1.5"}}`)
@@ -164,6 +180,60 @@ func BenchmarkProcessMessage(b *testing.B) {
 	})
 }
 
+func TestParseTrade_FastDecode(t *testing.T) {
+	messages := []string{
+		`{"stream":"btcusdt@trade","data":{"e":"trade","E":1672515782136,"s":"BTCUSDT","t":12345,"p":"50000.00","q":"1.5","b":88,"a":50,"T":1672515782136,"m":true,"M":true}}`,
+		`{"stream":"btcusdt@aggTrade","data":{"e":"aggTrade","E":1,"s":"BTCUSDT","a":26129,"p":"50000.00","q":"1.5","f":100,"l":105,"T":1,"m":false}}`,
+		`{"stream":"btcusdt@trade","data":{"s":"BTC\u0055SDT"}}`,
+	}
+
+	cfg := config.DefaultConfig()
+	client := NewClient(cfg, newMockStore())
+	fast := NewClient(config.DefaultConfig(), newMockStore())
+	fast.config.Ingestion.FastDecode = true
+
+	for _, message := range messages {
+		want, err := client.ParseTrade([]byte(message))
+		if err != nil {
+			t.Fatalf("ParseTrade(%s) error = %v", message, err)
+		}
+		got, err := fast.ParseTrade([]byte(message))
+		if err != nil {
+			t.Fatalf("ParseTrade(%s) with fast decoding error = %v", message, err)
+		}
+		if got.Stream != want.Stream || got.Data != want.Data || string(got.Raw) != message {
+			t.Errorf("ParseTrade(%s) with fast decoding = %+v, want %+v", message, got, want)
+		}
+		models.ReleaseAggTradeEvent(got)
+	}
+
+	if _, err := fast.ParseTrade([]byte(`{"data":{"t":"1"}}`)); err == nil {
+		t.Error("Expected an error for a trade ID of the wrong type")
+	}
+}
+
+func BenchmarkParseTrade(b *testing.B) {
+	msg := []byte(`{"stream":"btcusdt@trade","data":{"e":"trade","E":1672515782136,"s":"BTCUSDT","t":12345,"p":"50000.00","q":"1.5","b":88,"a":50,"T":1672515782136,"m":true,"M":true}}`)
+
+	for _, fastDecode := range []bool{false, true} {
+		cfg := config.DefaultConfig()
+		cfg.Ingestion.FastDecode = fastDecode
+		client := NewClient(cfg, newMockStore())
+
+		b.Run(fmt.Sprintf("FastDecode=%v", fastDecode), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				event, err := client.ParseTrade(msg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				// As the ingestion service does once the trade is published
+				models.ReleaseAggTradeEvent(event)
+			}
+		})
+	}
+}
+
 func TestGetSymbols(t *testing.T) {
 	server, cfg := setupTestServer()
 	defer server.Close()
@@ -193,6 +263,318 @@ func TestGetSymbols(t *testing.T) {
 	}
 }
 
+func TestGetSymbols_MergesPrioritySymbols(t *testing.T) {
+	server, cfg := setupTestServer()
+	defer server.Close()
+
+	cfg.Binance.MainSymbols = []string{"BTCUSDT"}
+	cfg.Binance.MaxSymbols = 5
+	cfg.Binance.MinDailyVolume = 1e12 // no symbol passes the volume filter
+
+	store := newMockStore()
+	store.priority = []string{"ETHUSDT", "btcusdt", "SOLUSDT"}
+	client := NewClient(cfg, store)
+
+	symbols, err := client.GetSymbols(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get symbols: %v", err)
+	}
+	sort.Strings(symbols)
+
+	// Priority symbols bypass volume filtering and are deduplicated
+	expected := []string{"btcusdt", "ethusdt", "solusdt"}
+	if strings.Join(symbols, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected symbols %v, got %v", expected, symbols)
+	}
+
+	// When priority symbols fill MaxSymbols they are streamed even if the
+	// exchange cannot be reached to check them
+	cfg.Binance.MaxSymbols = 3
+	server.Close()
+	symbols, err = client.GetSymbols(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get priority-only symbols: %v", err)
+	}
+	if len(symbols) != 3 {
+		t.Errorf("Expected 3 priority symbols, got %v", symbols)
+	}
+}
+
+// setupFixtureServer serves ten symbols with varying volumes, statuses and
+// quote assets for symbol selection tests
+func setupFixtureServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(r.URL.Path, "/api/v3/ticker/24hr") {
+			w.Write([]byte(`[
+				{"symbol":"BTCUSDT","quoteVolume":"1000000.00"},
+				{"symbol":"ETHUSDT","quoteVolume":"500000.00"},
+				{"symbol":"BNBUSDT","quoteVolume":"800000.00"},
+				{"symbol":"SOLUSDT","quoteVolume":"300000.00"},
+				{"symbol":"XRPUSDT","quoteVolume":"2000000.00"},
+				{"symbol":"ADAUSDT","quoteVolume":"100000.00"},
+				{"symbol":"DOGEUSDT","quoteVolume":"50000.00"},
+				{"symbol":"LUNAUSDT","quoteVolume":"10000.00"},
+				{"symbol":"ETHBTC","quoteVolume":"900000.00"},
+				{"symbol":"BNBBTC","quoteVolume":"400000.00"}
+			]`))
+			return
+		}
+
+		w.Write([]byte(`{
+			"symbols": [
+				{"symbol":"ETHUSDT","status":"TRADING","quoteAsset":"USDT"},
+				{"symbol":"SOLUSDT","status":"TRADING","quoteAsset":"USDT"},
+				{"symbol":"BTCUSDT","status":"TRADING","quoteAsset":"USDT","filters":[
+					{"filterType":"PRICE_FILTER","tickSize":"0.01000000"},
+					{"filterType":"LOT_SIZE","stepSize":"0.00001000"}
+				]},
+				{"symbol":"XRPUSDT","status":"BREAK","quoteAsset":"USDT","filters":[
+					{"filterType":"PRICE_FILTER","tickSize":"0.00010000"},
+					{"filterType":"LOT_SIZE","stepSize":"1.00000000"}
+				]},
+				{"symbol":"ADAUSDT","status":"TRADING","quoteAsset":"USDT"},
+				{"symbol":"BNBUSDT","status":"TRADING","quoteAsset":"USDT"},
+				{"symbol":"DOGEUSDT","status":"TRADING","quoteAsset":"USDT"},
+				{"symbol":"LUNAUSDT","status":"HALT","quoteAsset":"USDT","filters":[
+					{"filterType":"PRICE_FILTER","tickSize":"0.00000001"}
+				]},
+				{"symbol":"ETHBTC","status":"TRADING","quoteAsset":"BTC"},
+				{"symbol":"BNBBTC","status":"TRADING","quoteAsset":"BTC"}
+			]
+		}`))
+	}))
+}
+
+// precisionStore records the symbol precisions cached through it
+type precisionStore struct {
+	*mockStore
+	precisions map[string]models.Precision
+}
+
+func (p *precisionStore) StoreSymbolPrecisions(ctx context.Context, precisions map[string]models.Precision) error {
+	p.precisions = precisions
+	return nil
+}
+
+func TestGetSymbols_CachesPrecisions(t *testing.T) {
+	server := setupFixtureServer()
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Binance.BaseURL = server.URL
+	store := &precisionStore{mockStore: newMockStore()}
+	if _, err := NewClient(cfg, store).GetSymbols(context.Background()); err != nil {
+		t.Fatalf("GetSymbols failed: %v", err)
+	}
+
+	// Symbols are cached whatever their status, and only with a tick size
+	want := map[string]models.Precision{
+		"BTCUSDT":  {Price: 2, Quantity: 5},
+		"XRPUSDT":  {Price: 4, Quantity: 0},
+		"LUNAUSDT": {Price: 8, Quantity: 0},
+	}
+	if fmt.Sprint(store.precisions) != fmt.Sprint(want) {
+		t.Errorf("Cached %v, want %v", store.precisions, want)
+	}
+}
+
+func TestGetSymbols_Filtering(t *testing.T) {
+	server := setupFixtureServer()
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		mainSymbols    []string
+		filterMain     bool // Main symbols filtered like other pairs
+		maxSymbols     int
+		minDailyVolume float64
+		quoteAssets    []string
+		expected       []string
+	}{
+		{
+			name:       "max symbols keeps the highest volume",
+			maxSymbols: 1,
+			expected:   []string{"btcusdt"},
+		},
+		{
+			name:           "min daily volume excludes low volume pairs",
+			maxSymbols:     10,
+			minDailyVolume: 750000,
+			expected:       []string{"bnbusdt", "btcusdt"},
+		},
+		{
+			name:        "main symbols fill max symbols",
+			mainSymbols: []string{"BTCUSDT"},
+			maxSymbols:  1,
+			expected:    []string{"btcusdt"},
+		},
+		{
+			name:       "symbols not trading are excluded",
+			maxSymbols: 10,
+			expected:   []string{"adausdt", "bnbusdt", "btcusdt", "dogeusdt", "ethusdt", "solusdt"},
+		},
+		{
+			name:       "zero max symbols is unlimited",
+			maxSymbols: 0,
+			expected:   []string{"adausdt", "bnbusdt", "btcusdt", "dogeusdt", "ethusdt", "solusdt"},
+		},
+		{
+			name:        "zero max symbols with main symbols filtered",
+			mainSymbols: []string{"BTCUSDT"},
+			filterMain:  true,
+			maxSymbols:  0,
+			expected:    []string{"adausdt", "bnbusdt", "btcusdt", "dogeusdt", "ethusdt", "solusdt"},
+		},
+		{
+			name:        "other quote assets when configured",
+			maxSymbols:  3,
+			quoteAssets: []string{"USDT", "BTC"},
+			expected:    []string{"bnbusdt", "btcusdt", "ethbtc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Binance.BaseURL = server.URL
+			cfg.Binance.MainSymbols = tt.mainSymbols
+			cfg.Binance.MainSymbolsAlwaysOn = !tt.filterMain
+			cfg.Binance.MaxSymbols = tt.maxSymbols
+			cfg.Binance.MinDailyVolume = tt.minDailyVolume
+			if tt.quoteAssets != nil {
+				cfg.Binance.QuoteAssets = tt.quoteAssets
+			}
+
+			client := NewClient(cfg, newMockStore())
+			symbols, err := client.GetSymbols(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to get symbols: %v", err)
+			}
+			sort.Strings(symbols)
+
+			if strings.Join(symbols, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected symbols %v, got %v", tt.expected, symbols)
+			}
+		})
+	}
+}
+
+func TestGetSymbols_MainSymbolsAlwaysOn(t *testing.T) {
+	server := setupFixtureServer()
+	defer server.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	tests := []struct {
+		name       string
+		alwaysOn   bool
+		maxSymbols int
+		expected   []string
+		warnings   []string
+	}{
+		{
+			// DOGEUSDT is below the volume threshold, LUNAUSDT halted and
+			// GONEUSDT delisted
+			name:       "always on",
+			alwaysOn:   true,
+			maxSymbols: 4,
+			expected:   []string{"btcusdt", "dogeusdt", "goneusdt", "lunausdt"},
+			warnings:   []string{"main symbol LUNAUSDT is HALT on Binance", "main symbol GONEUSDT is not listed on Binance"},
+		},
+		{
+			name:       "always on beyond max symbols",
+			alwaysOn:   true,
+			maxSymbols: 1,
+			expected:   []string{"dogeusdt", "goneusdt", "lunausdt"},
+			warnings:   []string{"main symbol LUNAUSDT is HALT on Binance", "main symbol GONEUSDT is not listed on Binance"},
+		},
+		{
+			name:       "filtered like other pairs",
+			alwaysOn:   false,
+			maxSymbols: 4,
+			expected:   []string{"bnbusdt", "btcusdt"},
+			warnings:   []string{"main symbol LUNAUSDT is HALT on Binance"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			cfg := config.DefaultConfig()
+			cfg.Binance.BaseURL = server.URL
+			cfg.Binance.MainSymbols = []string{"DOGEUSDT", "LUNAUSDT", "GONEUSDT"}
+			cfg.Binance.MainSymbolsAlwaysOn = tt.alwaysOn
+			cfg.Binance.MaxSymbols = tt.maxSymbols
+			cfg.Binance.MinDailyVolume = 750000
+
+			symbols, err := NewClient(cfg, newMockStore()).GetSymbols(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to get symbols: %v", err)
+			}
+			sort.Strings(symbols)
+			if strings.Join(symbols, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected symbols %v, got %v", tt.expected, symbols)
+			}
+			for _, warning := range tt.warnings {
+				if !strings.Contains(logs.String(), warning) {
+					t.Errorf("Expected warning %q, got:\n%s", warning, logs.String())
+				}
+			}
+		})
+	}
+}
+
+func TestGetSymbols_SpotOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(r.URL.Path, "/api/v3/ticker/24hr") {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`{
+			"symbols": [
+				{"symbol":"BTCUSDT","status":"TRADING","quoteAsset":"USDT","isSpotTradingAllowed":true,"permissions":["SPOT","MARGIN"]},
+				{"symbol":"ETHUSDT","status":"TRADING","quoteAsset":"USDT","isSpotTradingAllowed":true,"permissions":[],"permissionSets":[["SPOT","MARGIN","TRD_GRP_004"]]},
+				{"symbol":"SOLUSDT","status":"TRADING","quoteAsset":"USDT"},
+				{"symbol":"MRGUSDT","status":"TRADING","quoteAsset":"USDT","isSpotTradingAllowed":false,"permissions":["MARGIN"]},
+				{"symbol":"BTCUPUSDT","status":"TRADING","quoteAsset":"USDT","isSpotTradingAllowed":true,"permissions":["SPOT","LEVERAGED"]},
+				{"symbol":"ETHDOWNUSDT","status":"TRADING","quoteAsset":"USDT","permissions":["LEVERAGED"]},
+				{"symbol":"GRPUSDT","status":"TRADING","quoteAsset":"USDT","permissionSets":[["MARGIN","TRD_GRP_005"]]}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		spotOnly bool
+		expected []string
+	}{
+		{false, []string{"btcupusdt", "btcusdt", "ethdownusdt", "ethusdt", "grpusdt", "mrgusdt", "solusdt"}},
+		// Symbols without permission fields are kept
+		{true, []string{"btcusdt", "ethusdt", "solusdt"}},
+	} {
+		cfg := config.DefaultConfig()
+		cfg.Binance.BaseURL = server.URL
+		cfg.Binance.MainSymbols = nil
+		cfg.Binance.MaxSymbols = 10
+		cfg.Binance.MinDailyVolume = 0
+		cfg.Binance.SpotOnly = tt.spotOnly
+
+		symbols, err := NewClient(cfg, newMockStore()).GetSymbols(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to get symbols: %v", err)
+		}
+		sort.Strings(symbols)
+		if strings.Join(symbols, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("SpotOnly=%v: expected symbols %v, got %v", tt.spotOnly, tt.expected, symbols)
+		}
+	}
+}
+
 func TestProcessMessage(t *testing.T) {
 	_, cfg := setupTestServer()
 	store := newMockStore()
@@ -0,0 +1,65 @@
+package binance
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Binance allows 300 connection attempts per 5 minutes per IP
+const (
+	connectAttemptLimit  = 300
+	connectAttemptWindow = 5 * time.Minute
+)
+
+// connLimiter holds back connection attempts, or requests, so that at most
+// limit of them start within any window
+type connLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	attempts []time.Time // Start times of attempts within the window, oldest first
+	now      func() time.Time
+}
+
+func newConnLimiter(limit int, window time.Duration) *connLimiter {
+	return &connLimiter{limit: limit, window: window, now: time.Now}
+}
+
+// Wait blocks until another attempt fits in the window and records it
+func (l *connLimiter) Wait(ctx context.Context) error {
+	for {
+		wait := l.reserve()
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve records an attempt if one is allowed now, otherwise it returns how
+// long until the oldest attempt leaves the window
+func (l *connLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	expired := 0
+	for expired < len(l.attempts) && now.Sub(l.attempts[expired]) >= l.window {
+		expired++
+	}
+	l.attempts = l.attempts[expired:]
+
+	if len(l.attempts) < l.limit {
+		l.attempts = append(l.attempts, now)
+		return 0
+	}
+	return l.window - now.Sub(l.attempts[0])
+}
@@ -0,0 +1,60 @@
+package binance
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConnLimiter_HoldsBackAttemptsOverLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newConnLimiter(2, 5*time.Minute)
+	l.now = func() time.Time { return now }
+
+	if wait := l.reserve(); wait != 0 {
+		t.Fatalf("Expected the first attempt to be allowed, got wait %s", wait)
+	}
+	now = now.Add(time.Minute)
+	if wait := l.reserve(); wait != 0 {
+		t.Fatalf("Expected the second attempt to be allowed, got wait %s", wait)
+	}
+
+	// The third attempt must wait until the first leaves the window
+	now = now.Add(time.Minute)
+	if wait := l.reserve(); wait != 3*time.Minute {
+		t.Errorf("Expected a 3m wait, got %s", wait)
+	}
+
+	now = now.Add(3 * time.Minute)
+	if wait := l.reserve(); wait != 0 {
+		t.Errorf("Expected an attempt once the window slid, got wait %s", wait)
+	}
+}
+
+func TestConnLimiter_WaitHonoursContext(t *testing.T) {
+	l := newConnLimiter(1, time.Hour)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected the deadline to stop the wait, got %v", err)
+	}
+}
+
+func TestConnLimiter_WaitResumesAfterWindow(t *testing.T) {
+	l := newConnLimiter(1, 30*time.Millisecond)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected the second attempt to wait out the window, took %s", elapsed)
+	}
+}
@@ -0,0 +1,87 @@
+package binance
+
+import (
+	"fmt"
+	"strings"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+const (
+	mainnetRESTURL   = "https://api.binance.com"
+	mainnetStreamURL = "wss://stream.binance.com:9443"
+	testnetRESTURL   = "https://testnet.binance.vision"
+	testnetStreamURL = "wss://stream.testnet.binance.vision"
+
+	mainnetFuturesRESTURL   = "https://fapi.binance.com"
+	mainnetFuturesStreamURL = "wss://fstream.binance.com"
+	testnetFuturesRESTURL   = "https://testnet.binancefuture.com"
+	testnetFuturesStreamURL = "wss://stream.binancefuture.com"
+)
+
+// endpoints holds the REST and WebSocket hosts of a Binance environment, for
+// spot and USDⓈ-M futures markets
+type endpoints struct {
+	rest          string
+	stream        string
+	futuresREST   string
+	futuresStream string
+}
+
+// resolveEndpoints picks production or testnet hosts: the spot testnet
+// (testnet.binance.vision) and the futures testnet (binancefuture.com). An
+// explicitly configured BaseURL or StreamURL (e.g. a mock server) always
+// wins for spot REST calls or streams.
+func resolveEndpoints(cfg config.BinanceConfig) endpoints {
+	ep := endpoints{
+		rest:          mainnetRESTURL,
+		stream:        mainnetStreamURL,
+		futuresREST:   mainnetFuturesRESTURL,
+		futuresStream: mainnetFuturesStreamURL,
+	}
+	if cfg.UseTestnet {
+		ep = endpoints{
+			rest:          testnetRESTURL,
+			stream:        testnetStreamURL,
+			futuresREST:   testnetFuturesRESTURL,
+			futuresStream: testnetFuturesStreamURL,
+		}
+	}
+
+	if cfg.BaseURL != "" && cfg.BaseURL != mainnetRESTURL {
+		ep.rest = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	if cfg.StreamURL != "" {
+		ep.stream = strings.TrimSuffix(cfg.StreamURL, "/")
+	}
+	return ep
+}
+
+// RESTURL returns the REST host the client would use for cfg
+func RESTURL(cfg config.BinanceConfig) string {
+	return resolveEndpoints(cfg).rest
+}
+
+// FuturesRESTURL returns the USDⓈ-M futures REST host for cfg
+func FuturesRESTURL(cfg config.BinanceConfig) string {
+	return resolveEndpoints(cfg).futuresREST
+}
+
+// FuturesStreamURL returns the USDⓈ-M futures WebSocket host for cfg
+func FuturesStreamURL(cfg config.BinanceConfig) string {
+	return resolveEndpoints(cfg).futuresStream
+}
+
+// combinedStreamURL builds the combined stream URL for symbols, streaming
+// each with the type streamType returns for it, and their @bookTicker streams
+// too when bookTicker is set
+func combinedStreamURL(host string, symbols []string, streamType func(symbol string) string, bookTicker bool) string {
+	streams := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		streams = append(streams, fmt.Sprintf("%s@%s", strings.ToLower(symbol), streamType(symbol)))
+		if bookTicker {
+			streams = append(streams, strings.ToLower(symbol)+StreamBookTicker)
+		}
+	}
+	return fmt.Sprintf("%s/stream?streams=%s", host, strings.Join(streams, "/"))
+}
@@ -0,0 +1,102 @@
+package binance
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+func TestResolveEndpoints(t *testing.T) {
+	tests := []struct {
+		name              string
+		cfg               config.BinanceConfig
+		wantREST          string
+		wantStream        string
+		wantFuturesREST   string
+		wantFuturesStream string
+	}{
+		{
+			name:              "production",
+			cfg:               config.BinanceConfig{BaseURL: mainnetRESTURL},
+			wantREST:          "https://api.binance.com",
+			wantStream:        "wss://stream.binance.com:9443",
+			wantFuturesREST:   "https://fapi.binance.com",
+			wantFuturesStream: "wss://fstream.binance.com",
+		},
+		{
+			name:              "testnet",
+			cfg:               config.BinanceConfig{BaseURL: mainnetRESTURL, UseTestnet: true},
+			wantREST:          "https://testnet.binance.vision",
+			wantStream:        "wss://stream.testnet.binance.vision",
+			wantFuturesREST:   "https://testnet.binancefuture.com",
+			wantFuturesStream: "wss://stream.binancefuture.com",
+		},
+		{
+			name:              "custom base URL wins",
+			cfg:               config.BinanceConfig{BaseURL: "http://127.0.0.1:8080/", UseTestnet: true},
+			wantREST:          "http://127.0.0.1:8080",
+			wantStream:        "wss://stream.testnet.binance.vision",
+			wantFuturesREST:   "https://testnet.binancefuture.com",
+			wantFuturesStream: "wss://stream.binancefuture.com",
+		},
+		{
+			name:              "custom stream URL wins",
+			cfg:               config.BinanceConfig{BaseURL: mainnetRESTURL, StreamURL: "ws://127.0.0.1:8081/"},
+			wantREST:          "https://api.binance.com",
+			wantStream:        "ws://127.0.0.1:8081",
+			wantFuturesREST:   "https://fapi.binance.com",
+			wantFuturesStream: "wss://fstream.binance.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep := resolveEndpoints(tt.cfg)
+			if ep.rest != tt.wantREST {
+				t.Errorf("REST URL = %q, want %q", ep.rest, tt.wantREST)
+			}
+			if ep.stream != tt.wantStream {
+				t.Errorf("Stream URL = %q, want %q", ep.stream, tt.wantStream)
+			}
+			if got := FuturesRESTURL(tt.cfg); got != tt.wantFuturesREST {
+				t.Errorf("Futures REST URL = %q, want %q", got, tt.wantFuturesREST)
+			}
+			if got := FuturesStreamURL(tt.cfg); got != tt.wantFuturesStream {
+				t.Errorf("Futures stream URL = %q, want %q", got, tt.wantFuturesStream)
+			}
+		})
+	}
+}
+
+func TestBuildStreamURL_Testnet(t *testing.T) {
+	cfg := config.DefaultConfig()
+	symbols := []string{"BTCUSDT", "ethusdt"}
+
+	cfg.Binance.UseTestnet = false
+	if got, want := NewClient(cfg, newMockStore()).BuildStreamURL(symbols),
+		"wss://stream.binance.com:9443/stream?streams=btcusdt@trade/ethusdt@trade"; got != want {
+		t.Errorf("Production stream URL = %q, want %q", got, want)
+	}
+
+	cfg.Binance.UseTestnet = true
+	if got, want := NewClient(cfg, newMockStore()).BuildStreamURL(symbols),
+		"wss://stream.testnet.binance.vision/stream?streams=btcusdt@trade/ethusdt@trade"; got != want {
+		t.Errorf("Testnet stream URL = %q, want %q", got, want)
+	}
+}
+
+func TestStreamTrades_RejectsLongURL(t *testing.T) {
+	symbols := make([]string, 500)
+	for i := range symbols {
+		symbols[i] = strings.Repeat("A", 20) + strconv.Itoa(i)
+	}
+
+	client := NewTestClient(config.DefaultConfig(), newMockStore())
+	err := client.StreamTrades(context.Background(), symbols, func([]byte) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "byte limit") {
+		t.Errorf("Expected a URL length error, got %v", err)
+	}
+}
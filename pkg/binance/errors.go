@@ -0,0 +1,61 @@
+package binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"binance-redis-streamer/pkg/exchange"
+)
+
+// Errors returned by the client; they are the exchange package errors, so
+// venue-neutral callers can branch on them too
+var (
+	// ErrRateLimited is returned when Binance answers a REST call with 429
+	// or, once an IP is banned for ignoring 429s, 418
+	ErrRateLimited = exchange.ErrRateLimited
+	// ErrBadSymbol is returned for symbols that cannot be streamed or that
+	// Binance reports as invalid
+	ErrBadSymbol = exchange.ErrBadSymbol
+)
+
+// invalidSymbolCode is the Binance API error code for unknown symbols
+const invalidSymbolCode = -1121
+
+// validSymbol matches symbols that can be put in a stream name
+var validSymbol = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// apiError is the body of a failed Binance REST call
+type apiError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// statusError describes a failed REST response, wrapping ErrRateLimited or
+// ErrBadSymbol when the status or error code says so
+func statusError(status int, body []byte) error {
+	if status == http.StatusTooManyRequests || status == http.StatusTeapot {
+		return fmt.Errorf("%w: status %d", ErrRateLimited, status)
+	}
+
+	var apiErr apiError
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Code != 0 {
+		if apiErr.Code == invalidSymbolCode {
+			return fmt.Errorf("%w: %s", ErrBadSymbol, apiErr.Msg)
+		}
+		return fmt.Errorf("unexpected status code: %d (%d: %s)", status, apiErr.Code, apiErr.Msg)
+	}
+	return fmt.Errorf("unexpected status code: %d", status)
+}
+
+// checkSymbols returns ErrBadSymbol for the first symbol that cannot be
+// streamed
+func checkSymbols(symbols []string) error {
+	for _, symbol := range symbols {
+		if !validSymbol.MatchString(symbol) {
+			return fmt.Errorf("%w: %q", ErrBadSymbol, symbol)
+		}
+	}
+	return nil
+}
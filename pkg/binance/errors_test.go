@@ -0,0 +1,69 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+func TestGetSymbols_ErrorIdentities(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"too many requests", http.StatusTooManyRequests, `{"code":-1003,"msg":"Too many requests"}`, ErrRateLimited},
+		{"banned", http.StatusTeapot, `{"code":-1003,"msg":"Way too many requests; IP banned"}`, ErrRateLimited},
+		{"invalid symbol", http.StatusBadRequest, `{"code":-1121,"msg":"Invalid symbol."}`, ErrBadSymbol},
+		{"server error", http.StatusInternalServerError, `oops`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			cfg := config.DefaultConfig()
+			cfg.Binance.BaseURL = server.URL
+
+			_, err := NewClient(cfg, newMockStore()).GetSymbols(context.Background())
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, sentinel := range []error{ErrRateLimited, ErrBadSymbol} {
+				if got, want := errors.Is(err, sentinel), sentinel == tt.want; got != want {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", err, sentinel, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamTrades_BadSymbol(t *testing.T) {
+	client := NewTestClient(config.DefaultConfig(), newMockStore())
+
+	err := client.StreamTrades(context.Background(), []string{"btcusdt", "eth/usdt"}, func([]byte) error { return nil })
+	if !errors.Is(err, ErrBadSymbol) {
+		t.Errorf("Expected ErrBadSymbol, got %v", err)
+	}
+}
+
+func TestPrioritySymbols_SkipsBadSymbols(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Binance.MainSymbols = []string{"btcusdt"}
+	store := newMockStore()
+	store.priority = []string{"eth usdt", "SOLUSDT"}
+
+	symbols := NewClient(cfg, store).prioritySymbols(context.Background())
+	if len(symbols) != 2 || symbols[0] != "btcusdt" || symbols[1] != "solusdt" {
+		t.Errorf("Expected [btcusdt solusdt], got %v", symbols)
+	}
+}
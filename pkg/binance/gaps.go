@@ -0,0 +1,275 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+)
+
+// Limits of the REST aggTrades and historicalTrades endpoints. Gap recovery keeps to
+// aggTradesPerSecond requests, well under the request weight Binance allows
+// per IP, so streams reconnecting together do not get the IP banned.
+const (
+	aggTradesPerSecond = 5
+	aggTradesLimit     = 1000      // Most trades per response
+	aggTradesMaxSpan   = time.Hour // Widest startTime to endTime range accepted
+)
+
+// restAggTrade is an entry of the REST aggTrades response
+type restAggTrade struct {
+	AggregateTradeID int64  `json:"a"`
+	FirstTradeID     int64  `json:"f"`
+	Price            string `json:"p"`
+	Quantity         string `json:"q"`
+	TradeTime        int64  `json:"T"`
+	IsBuyerMaker     bool   `json:"m"`
+}
+
+// toEvent converts the aggregate trade to an aggTrade stream event of symbol
+func (t restAggTrade) toEvent(symbol string) *models.AggTradeEvent {
+	return &models.AggTradeEvent{
+		Stream: strings.ToLower(symbol) + "@aggTrade",
+		Data: models.TradeData{
+			EventType:        models.EventTypeAggTrade,
+			EventTime:        t.TradeTime,
+			Symbol:           symbol,
+			AggregateTradeID: t.AggregateTradeID,
+			Price:            t.Price,
+			Quantity:         t.Quantity,
+			TradeTime:        t.TradeTime,
+			IsBuyerMaker:     t.IsBuyerMaker,
+			Numeric:          numericTrade(t.Price, t.Quantity),
+		},
+	}
+}
+
+// restTrade is an entry of the REST historicalTrades response
+type restTrade struct {
+	ID           int64  `json:"id"`
+	Price        string `json:"price"`
+	Quantity     string `json:"qty"`
+	Time         int64  `json:"time"`
+	IsBuyerMaker bool   `json:"isBuyerMaker"`
+}
+
+// toEvent converts the trade to a trade stream event of symbol
+func (t restTrade) toEvent(symbol string) *models.AggTradeEvent {
+	return &models.AggTradeEvent{
+		Stream: strings.ToLower(symbol) + "@trade",
+		Data: models.TradeData{
+			EventType:    models.EventTypeTrade,
+			EventTime:    t.Time,
+			Symbol:       symbol,
+			TradeID:      t.ID,
+			Price:        t.Price,
+			Quantity:     t.Quantity,
+			TradeTime:    t.Time,
+			IsBuyerMaker: t.IsBuyerMaker,
+			Numeric:      numericTrade(t.Price, t.Quantity),
+		},
+	}
+}
+
+// numericTrade parses price and quantity, or returns nil when they do not
+// parse
+func numericTrade(price, quantity string) *models.NumericTrade {
+	n, err := models.ParseNumericTrade(price, quantity)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// errGapWalked stops eachAggTrade early
+var errGapWalked = errors.New("gap walked")
+
+// RecoverGap fetches the trades of symbol after gapStart and up to gapEnd,
+// such as those missed while a connection was down, and passes them to
+// handle oldest first, as events of the symbol's stream: trades from the
+// REST historicalTrades endpoint for trade streams, so their IDs match the
+// streamed ones, and aggregate trades from aggTrades for aggTrade streams.
+// Symbols streaming no trades have none to recover. Trades older than the
+// retention period are skipped, as the history would trim them at once.
+func (c *Client) RecoverGap(ctx context.Context, symbol string, gapStart, gapEnd time.Time, handle func(*models.AggTradeEvent) error) error {
+	symbol = strings.ToUpper(symbol)
+	if oldest := gapEnd.Add(-c.config.Redis.RetentionPeriod); gapStart.Before(oldest) {
+		gapStart = oldest
+	}
+
+	var recovered int
+	var err error
+	switch c.symbolStreamType(symbol) {
+	case config.StreamTypeAggTrade:
+		err = c.eachAggTrade(ctx, symbol, gapStart, gapEnd, func(trade restAggTrade) error {
+			recovered++
+			return handle(trade.toEvent(symbol))
+		})
+	case config.StreamTypeTrade:
+		recovered, err = c.recoverTrades(ctx, symbol, gapStart, gapEnd, handle)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to recover %s trades: %w", symbol, err)
+	}
+
+	if recovered > 0 {
+		log.Printf("Recovered %d %s trades between %s and %s", recovered, symbol,
+			gapStart.UTC().Format(time.RFC3339), gapEnd.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// recoverTrades passes the trades of symbol after gapStart and up to gapEnd
+// to handle and returns how many it passed. The first one is found by time
+// through aggTrades, which historicalTrades cannot search by.
+func (c *Client) recoverTrades(ctx context.Context, symbol string, gapStart, gapEnd time.Time, handle func(*models.AggTradeEvent) error) (int, error) {
+	var fromID int64 = -1
+	err := c.eachAggTrade(ctx, symbol, gapStart, gapEnd, func(trade restAggTrade) error {
+		fromID = trade.FirstTradeID
+		return errGapWalked
+	})
+	if err != nil || fromID < 0 {
+		return 0, err
+	}
+
+	recovered := 0
+	for {
+		trades, err := c.fetchTrades(ctx, symbol, fromID)
+		if err != nil {
+			return recovered, err
+		}
+		for _, trade := range trades {
+			if trade.Time > gapEnd.UnixMilli() {
+				return recovered, nil
+			}
+			if err := handle(trade.toEvent(symbol)); err != nil {
+				return recovered, err
+			}
+			recovered++
+		}
+		if len(trades) < aggTradesLimit {
+			return recovered, nil
+		}
+		fromID = trades[len(trades)-1].ID + 1
+	}
+}
+
+// eachAggTrade passes the aggregate trades of symbol after gapStart and up
+// to gapEnd to fn, oldest first, until fn returns an error; errGapWalked
+// stops without one
+func (c *Client) eachAggTrade(ctx context.Context, symbol string, gapStart, gapEnd time.Time, fn func(restAggTrade) error) error {
+	// Find the first trade of the gap by time, a window at a time; gapStart
+	// is the last trade already stored. From a full page on, page by trade
+	// ID instead, as trades within one millisecond may fill several pages.
+	start := gapStart.Add(time.Millisecond)
+	var fromID int64
+	for !start.After(gapEnd) {
+		end := gapEnd
+		if span := start.Add(aggTradesMaxSpan - time.Millisecond); span.Before(end) {
+			end = span
+		}
+		query := fmt.Sprintf("startTime=%d&endTime=%d", start.UnixMilli(), end.UnixMilli())
+		if fromID > 0 {
+			query = fmt.Sprintf("fromId=%d", fromID)
+		}
+
+		trades, err := c.fetchAggTrades(ctx, symbol, query)
+		if err != nil {
+			return fmt.Errorf("failed to fetch trades from %s: %w", start.UTC().Format(time.RFC3339), err)
+		}
+		for _, trade := range trades {
+			if trade.TradeTime > gapEnd.UnixMilli() {
+				break
+			}
+			if err := fn(trade); errors.Is(err, errGapWalked) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case len(trades) == aggTradesLimit:
+			last := trades[len(trades)-1]
+			fromID = last.AggregateTradeID + 1
+			start = time.UnixMilli(last.TradeTime)
+		case fromID > 0:
+			// The last page by ID reached the newest trade
+			start = gapEnd.Add(time.Millisecond)
+		default:
+			start = end.Add(time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// fetchAggTrades fetches up to aggTradesLimit aggregate trades of symbol
+// selected by query, either a startTime and endTime, both inclusive, or a
+// fromId, oldest first
+func (c *Client) fetchAggTrades(ctx context.Context, symbol, query string) ([]restAggTrade, error) {
+	if err := c.backfills.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/api/v3/aggTrades?symbol=%s&%s&limit=%d", c.baseURL, symbol, query, aggTradesLimit)
+	var trades []restAggTrade
+	err := c.fetchJSON(ctx, url, &trades)
+	return trades, err
+}
+
+// fetchTrades fetches up to aggTradesLimit trades of symbol from fromID on,
+// oldest first
+func (c *Client) fetchTrades(ctx context.Context, symbol string, fromID int64) ([]restTrade, error) {
+	if err := c.backfills.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/api/v3/historicalTrades?symbol=%s&fromId=%d&limit=%d", c.baseURL, symbol, fromID, aggTradesLimit)
+	var trades []restTrade
+	err := c.fetchJSON(ctx, url, &trades)
+	return trades, err
+}
+
+// fetchJSON decodes the response to a GET of url into v, through the REST
+// circuit breaker. The API key is sent when set, as some market data
+// endpoints have required it.
+func (c *Client) fetchJSON(ctx context.Context, url string, v interface{}) error {
+	return c.rest.Do(func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, http.NoBody)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		if c.config.Binance.APIKey != "" {
+			req.Header.Set("X-MBX-APIKEY", c.config.Binance.APIKey)
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch trades: %w", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return statusError(resp.StatusCode, body)
+		}
+
+		if err := json.Unmarshal(body, v); err != nil {
+			return fmt.Errorf("failed to decode trades: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,211 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+)
+
+// aggTradesServer serves the aggTrades endpoint from trades, sorted by time
+// and ID, like Binance: up to limit trades between startTime and endTime, or
+// from fromId on. It serves historicalTrades from rawTrades, up to limit
+// from fromId on.
+type aggTradesServer struct {
+	t         *testing.T
+	trades    []restAggTrade
+	rawTrades []restTrade
+
+	mu       sync.Mutex
+	requests int
+}
+
+func (s *aggTradesServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	start, _ := strconv.ParseInt(query.Get("startTime"), 10, 64)
+	end, _ := strconv.ParseInt(query.Get("endTime"), 10, 64)
+	fromID, _ := strconv.ParseInt(query.Get("fromId"), 10, 64)
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if r.URL.Path == "/api/v3/historicalTrades" {
+		s.serveTrades(w, fromID, limit)
+		return
+	}
+	if r.URL.Path != "/api/v3/aggTrades" || query.Get("symbol") != "BTCUSDT" {
+		s.t.Errorf("Unexpected request %s", r.URL)
+	}
+	if fromID == 0 && time.Duration(end-start)*time.Millisecond >= aggTradesMaxSpan {
+		s.t.Errorf("Requested %s of trades, more than Binance accepts", time.Duration(end-start)*time.Millisecond)
+	}
+
+	s.mu.Lock()
+	s.requests++
+	s.mu.Unlock()
+
+	var entries []string
+	for _, trade := range s.trades {
+		selected := trade.TradeTime >= start && trade.TradeTime <= end
+		if fromID > 0 {
+			selected = trade.AggregateTradeID >= fromID
+		}
+		if selected && len(entries) < limit {
+			entries = append(entries, fmt.Sprintf(`{"a":%d,"p":%q,"q":%q,"f":%d,"l":%d,"T":%d,"m":%t,"M":true}`,
+				trade.AggregateTradeID, trade.Price, trade.Quantity, trade.FirstTradeID, trade.FirstTradeID, trade.TradeTime, trade.IsBuyerMaker))
+		}
+	}
+	fmt.Fprintf(w, "[%s]", strings.Join(entries, ","))
+}
+
+func (s *aggTradesServer) serveTrades(w http.ResponseWriter, fromID int64, limit int) {
+	s.mu.Lock()
+	s.requests++
+	s.mu.Unlock()
+
+	var entries []string
+	for _, trade := range s.rawTrades {
+		if trade.ID >= fromID && len(entries) < limit {
+			entries = append(entries, fmt.Sprintf(`{"id":%d,"price":%q,"qty":%q,"quoteQty":"0","time":%d,"isBuyerMaker":%t,"isBestMatch":true}`,
+				trade.ID, trade.Price, trade.Quantity, trade.Time, trade.IsBuyerMaker))
+		}
+	}
+	fmt.Fprintf(w, "[%s]", strings.Join(entries, ","))
+}
+
+// eventLog records every recovered trade
+type eventLog struct {
+	events []*models.AggTradeEvent
+}
+
+func (l *eventLog) handle(event *models.AggTradeEvent) error {
+	l.events = append(l.events, event)
+	return nil
+}
+
+func TestRecoverGap(t *testing.T) {
+	gapStart := time.Now().Add(-3 * time.Hour).Truncate(time.Millisecond)
+	gapEnd := gapStart.Add(3 * time.Hour)
+
+	// The trade at gapStart is already stored and the one after gapEnd is
+	// streamed. More trades than a page fall within one millisecond.
+	server := &aggTradesServer{t: t}
+	add := func(at time.Time, n int) {
+		for i := 0; i < n; i++ {
+			id := int64(len(server.trades) + 1)
+			server.trades = append(server.trades, restAggTrade{AggregateTradeID: id, Price: "50000.5", Quantity: "0.1", TradeTime: at.UnixMilli(), IsBuyerMaker: id%2 == 0})
+		}
+	}
+	add(gapStart, 1)
+	for i := 0; i < 500; i++ {
+		add(gapStart.Add(10*time.Minute+time.Duration(i)*time.Millisecond), 1)
+	}
+	add(gapStart.Add(90*time.Minute), 1200)
+	for i := 0; i < 300; i++ {
+		add(gapStart.Add(150*time.Minute+time.Duration(i)*time.Second), 1)
+	}
+	add(gapEnd, 1)
+	add(gapEnd.Add(time.Millisecond), 1)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	_, cfg := setupTestServer()
+	cfg.Binance.BaseURL = httpServer.URL
+	cfg.Binance.DefaultStreamType = config.StreamTypeAggTrade
+	client := NewClient(cfg, nil)
+	client.backfills = newConnLimiter(1000, time.Second)
+
+	recovered := &eventLog{}
+	if err := client.RecoverGap(context.Background(), "btcusdt", gapStart, gapEnd, recovered.handle); err != nil {
+		t.Fatal(err)
+	}
+
+	want := server.trades[1 : len(server.trades)-1]
+	if len(recovered.events) != len(want) {
+		t.Fatalf("Expected %d recovered trades, got %d", len(want), len(recovered.events))
+	}
+	for i, event := range recovered.events {
+		if event.Data.AggregateTradeID != want[i].AggregateTradeID || event.Data.TradeTime != want[i].TradeTime {
+			t.Fatalf("Trade %d = %d at %d, want %d at %d", i, event.Data.AggregateTradeID, event.Data.TradeTime, want[i].AggregateTradeID, want[i].TradeTime)
+		}
+	}
+	first := recovered.events[0]
+	if first.Stream != "btcusdt@aggTrade" || first.Data.EventType != models.EventTypeAggTrade || first.Data.Symbol != "BTCUSDT" ||
+		first.Data.Price != "50000.5" || first.Data.Numeric == nil || first.Data.Numeric.Quantity != 0.1 || !first.Data.IsBuyerMaker {
+		t.Errorf("Unexpected recovered trade %+v", first.Data)
+	}
+}
+
+func TestRecoverGap_TradeStream(t *testing.T) {
+	gapStart := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	gapEnd := gapStart.Add(time.Hour)
+
+	// Trade 100 at gapStart is already stored and trade 2601 after gapEnd is
+	// streamed. The gap's first aggregate trade starts at trade 101.
+	server := &aggTradesServer{t: t}
+	server.trades = []restAggTrade{
+		{AggregateTradeID: 10, FirstTradeID: 100, Price: "50000", Quantity: "1", TradeTime: gapStart.UnixMilli()},
+		{AggregateTradeID: 11, FirstTradeID: 101, Price: "50000", Quantity: "1", TradeTime: gapStart.Add(time.Minute).UnixMilli()},
+	}
+	for id := int64(100); id <= 2601; id++ {
+		at := gapStart.Add(time.Minute + time.Duration(id-101)*time.Millisecond)
+		if id == 100 {
+			at = gapStart
+		}
+		if id == 2601 {
+			at = gapEnd.Add(time.Millisecond)
+		}
+		server.rawTrades = append(server.rawTrades, restTrade{ID: id, Price: "50000.5", Quantity: "0.1", Time: at.UnixMilli(), IsBuyerMaker: true})
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	_, cfg := setupTestServer()
+	cfg.Binance.BaseURL = httpServer.URL
+	client := NewClient(cfg, nil)
+	client.backfills = newConnLimiter(1000, time.Second)
+
+	recovered := &eventLog{}
+	if err := client.RecoverGap(context.Background(), "BTCUSDT", gapStart, gapEnd, recovered.handle); err != nil {
+		t.Fatal(err)
+	}
+
+	// Raw trades keep the streamed trade IDs
+	if len(recovered.events) != 2500 {
+		t.Fatalf("Expected 2500 recovered trades, got %d", len(recovered.events))
+	}
+	for i, event := range recovered.events {
+		if event.Data.TradeID != int64(101+i) || event.Data.EventType != models.EventTypeTrade {
+			t.Fatalf("Trade %d = %s %d, want trade %d", i, event.Data.EventType, event.Data.TradeID, 101+i)
+		}
+	}
+	if first := recovered.events[0]; first.Stream != "btcusdt@trade" || first.Data.Numeric == nil || !first.Data.IsBuyerMaker {
+		t.Errorf("Unexpected recovered trade %+v", first.Data)
+	}
+}
+
+func TestRecoverGap_SkipsTradesBeyondRetention(t *testing.T) {
+	server := &aggTradesServer{t: t}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	_, cfg := setupTestServer()
+	cfg.Binance.BaseURL = httpServer.URL
+	cfg.Redis.RetentionPeriod = 2 * time.Hour
+	client := NewClient(cfg, nil)
+	client.backfills = newConnLimiter(1000, time.Second)
+
+	gapEnd := time.Now()
+	cfg.Binance.DefaultStreamType = config.StreamTypeAggTrade
+	if err := client.RecoverGap(context.Background(), "BTCUSDT", gapEnd.Add(-30*24*time.Hour), gapEnd, (&eventLog{}).handle); err != nil {
+		t.Fatal(err)
+	}
+	if server.requests != 2 {
+		t.Errorf("Expected the gap clipped to the 2h retention in 2 requests, got %d", server.requests)
+	}
+}
@@ -0,0 +1,39 @@
+package binance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"binance-redis-streamer/internal/jsoncodec"
+	"binance-redis-streamer/internal/models"
+)
+
+// MiniTickerStore keeps the latest 24h statistics of symbols streamed with
+// the miniTicker stream type, e.g. *storage.RedisStore
+type MiniTickerStore interface {
+	StoreMiniTicker(ctx context.Context, ticker *models.MiniTicker) error
+}
+
+// miniTickerStream marks the stream name of a miniTicker message
+var miniTickerStream = []byte(StreamMiniTicker + `"`)
+
+// isMiniTicker reports whether a combined-stream message is a mini ticker
+// update, without decoding it
+func isMiniTicker(message []byte) bool {
+	return bytes.Contains(message, miniTickerStream)
+}
+
+// miniTickerHandler stores mini ticker updates
+func miniTickerHandler(store MiniTickerStore) MessageHandler {
+	return MessageHandlerFunc(func(ctx context.Context, message []byte) error {
+		var event models.MiniTickerEvent
+		if err := jsoncodec.Unmarshal(message, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal mini ticker: %w", err)
+		}
+		if err := store.StoreMiniTicker(ctx, &event.Data); err != nil {
+			return fmt.Errorf("failed to store mini ticker: %w", err)
+		}
+		return nil
+	})
+}
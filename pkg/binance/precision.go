@@ -0,0 +1,34 @@
+package binance
+
+import (
+	"context"
+	"log"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// PrecisionStore caches the price and quantity decimals of symbols for
+// display, e.g. *storage.RedisStore
+type PrecisionStore interface {
+	StoreSymbolPrecisions(ctx context.Context, precisions map[string]models.Precision) error
+}
+
+// cachePrecisions stores the precisions of the symbols in exchangeInfo when
+// the store keeps them. Failures are only logged: they affect display, not
+// streaming.
+func (c *Client) cachePrecisions(ctx context.Context, exchangeInfo *models.ExchangeInfo) {
+	store, ok := c.store.(PrecisionStore)
+	if !ok {
+		return
+	}
+
+	precisions := make(map[string]models.Precision, len(exchangeInfo.Symbols))
+	for _, sym := range exchangeInfo.Symbols {
+		if precision, ok := sym.Precision(); ok {
+			precisions[sym.Symbol] = precision
+		}
+	}
+	if err := store.StoreSymbolPrecisions(ctx, precisions); err != nil {
+		log.Printf("Warning: failed to cache symbol precisions: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package binance
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/websocket"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+// proxyFunc returns a proxy selector for the proxy URL raw, or the standard
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY lookup when raw is empty or invalid.
+// Both net/http and the WebSocket dialer speak http(s):// and socks5://
+// proxies.
+func proxyFunc(raw string) func(*http.Request) (*url.URL, error) {
+	if raw == "" {
+		return http.ProxyFromEnvironment
+	}
+	proxyURL, err := config.ParseProxyURL(raw)
+	if err != nil {
+		log.Printf("Warning: ignoring proxy: %v", err)
+		return http.ProxyFromEnvironment
+	}
+	return http.ProxyURL(proxyURL)
+}
+
+// NewHTTPClient returns the client REST calls go through, using HTTPProxy
+func NewHTTPClient(cfg config.BinanceConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc(cfg.HTTPProxy)
+	return &http.Client{Transport: transport}
+}
+
+// newWSDialer returns the dialer of stream connections, using WSProxy or,
+// when that is empty, HTTPProxy
+func newWSDialer(cfg config.BinanceConfig) *websocket.Dialer {
+	raw := cfg.WSProxy
+	if raw == "" {
+		raw = cfg.HTTPProxy
+	}
+	dialer := *websocket.DefaultDialer
+	dialer.Proxy = proxyFunc(raw)
+	return &dialer
+}
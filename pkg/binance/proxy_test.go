@@ -0,0 +1,156 @@
+package binance
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// forwardProxy is an HTTP proxy that forwards plain requests and tunnels
+// CONNECT requests, recording the hosts it was asked for
+type forwardProxy struct {
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (p *forwardProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.hosts = append(p.hosts, r.Method+" "+r.Host)
+	p.mu.Unlock()
+
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	resp, err := http.DefaultTransport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func (p *forwardProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	go func() {
+		io.Copy(upstream, conn)
+		upstream.Close()
+	}()
+	io.Copy(conn, upstream)
+	conn.Close()
+}
+
+func (p *forwardProxy) requests() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.hosts...)
+}
+
+func TestClient_RESTThroughProxy(t *testing.T) {
+	server, cfg := setupTestServer()
+	defer server.Close()
+
+	proxy := &forwardProxy{}
+	proxyServer := httptest.NewServer(proxy)
+	defer proxyServer.Close()
+
+	cfg.Binance.HTTPProxy = proxyServer.URL
+	cfg.Binance.MainSymbols = nil
+	cfg.Binance.MaxSymbols = 10
+	cfg.Binance.MinDailyVolume = 0
+	client := NewTestClient(cfg, newMockStore())
+
+	symbols, err := client.GetSymbols(context.Background())
+	if err != nil {
+		t.Fatalf("GetSymbols() error = %v", err)
+	}
+	if len(symbols) != 2 {
+		t.Errorf("Expected 2 symbols, got %v", symbols)
+	}
+
+	target := strings.TrimPrefix(server.URL, "http://")
+	requests := proxy.requests()
+	if len(requests) == 0 {
+		t.Fatal("Expected REST calls to go through the proxy")
+	}
+	for _, request := range requests {
+		if request != "GET "+target {
+			t.Errorf("Proxy got %q, want GET %s", request, target)
+		}
+	}
+}
+
+func TestClient_StreamThroughProxy(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	wsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+	}))
+	defer wsServer.Close()
+
+	proxy := &forwardProxy{}
+	proxyServer := httptest.NewServer(proxy)
+	defer proxyServer.Close()
+
+	// Streams fall back to the REST proxy when no stream proxy is set
+	_, cfg := setupTestServer()
+	cfg.Binance.HTTPProxy = proxyServer.URL
+	client := NewTestClient(cfg, newMockStore())
+
+	wsURL := "ws" + strings.TrimPrefix(wsServer.URL, "http")
+	conn, _, err := client.dialer.DialContext(context.Background(), wsURL, nil)
+	if err != nil {
+		t.Fatalf("Dial through proxy failed: %v", err)
+	}
+	defer conn.Close()
+
+	if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != "hello" {
+		t.Errorf("ReadMessage() = %q, %v, want hello", msg, err)
+	}
+	target := strings.TrimPrefix(wsServer.URL, "http://")
+	if requests := proxy.requests(); len(requests) != 1 || requests[0] != "CONNECT "+target {
+		t.Errorf("Proxy got %v, want one CONNECT %s", requests, target)
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://api.binance.com/api/v3/ping", nil)
+
+	for _, raw := range []string{"http://proxy:3128", "socks5://proxy:1080"} {
+		proxyURL, err := proxyFunc(raw)(req)
+		if err != nil || proxyURL == nil || proxyURL.String() != raw {
+			t.Errorf("proxyFunc(%q) = %v, %v", raw, proxyURL, err)
+		}
+	}
+}
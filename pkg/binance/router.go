@@ -0,0 +1,98 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"binance-redis-streamer/internal/jsoncodec"
+)
+
+// Stream types of combined-stream messages
+const (
+	StreamTrade      = "@trade"
+	StreamAggTrade   = "@aggTrade"
+	StreamDepth      = "@depth"
+	StreamBookTicker = "@bookTicker"
+	StreamMiniTicker = "@miniTicker"
+)
+
+// ErrUnhandledStream is returned for messages whose stream type has no handler
+var ErrUnhandledStream = errors.New("no handler for stream")
+
+// MessageHandler handles the raw messages of one stream type
+type MessageHandler interface {
+	Handle(ctx context.Context, data []byte) error
+}
+
+// MessageHandlerFunc adapts a function to a MessageHandler
+type MessageHandlerFunc func(ctx context.Context, data []byte) error
+
+// Handle calls f(ctx, data)
+func (f MessageHandlerFunc) Handle(ctx context.Context, data []byte) error {
+	return f(ctx, data)
+}
+
+// MessageRouter dispatches combined-stream messages to handlers by the
+// stream type suffix of their "stream" field, e.g. "btcusdt@depth@100ms"
+// goes to the "@depth" handler
+type MessageRouter struct {
+	mu       sync.RWMutex
+	handlers map[string]MessageHandler
+}
+
+// NewMessageRouter creates a router without handlers
+func NewMessageRouter() *MessageRouter {
+	return &MessageRouter{handlers: make(map[string]MessageHandler)}
+}
+
+// Register sets the handler for a stream type suffix such as "@depth",
+// replacing any previous handler
+func (r *MessageRouter) Register(streamSuffix string, handler MessageHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[normalizeStreamSuffix(streamSuffix)] = handler
+}
+
+// Route dispatches a message to the handler of its stream type
+func (r *MessageRouter) Route(ctx context.Context, message []byte) error {
+	var envelope struct {
+		Stream string `json:"stream"`
+	}
+	if err := jsoncodec.Unmarshal(message, &envelope); err != nil {
+		return fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+
+	suffix := streamType(envelope.Stream)
+
+	r.mu.RLock()
+	handler, ok := r.handlers[suffix]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnhandledStream, envelope.Stream)
+	}
+
+	return handler.Handle(ctx, message)
+}
+
+// streamType extracts the stream type suffix of a stream name, dropping
+// update speeds and depth levels: "btcusdt@depth20@100ms" becomes "@depth"
+func streamType(stream string) string {
+	i := strings.Index(stream, "@")
+	if i < 0 {
+		return ""
+	}
+
+	kind := stream[i+1:]
+	if j := strings.Index(kind, "@"); j >= 0 {
+		kind = kind[:j]
+	}
+	return normalizeStreamSuffix(strings.TrimRight(kind, "0123456789"))
+}
+
+// normalizeStreamSuffix makes "depth" and "@depth" equivalent
+func normalizeStreamSuffix(suffix string) string {
+	return "@" + strings.TrimPrefix(suffix, "@")
+}
@@ -0,0 +1,92 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStreamType(t *testing.T) {
+	tests := map[string]string{
+		"btcusdt@trade":         StreamTrade,
+		"btcusdt@aggTrade":      StreamAggTrade,
+		"btcusdt@depth":         StreamDepth,
+		"btcusdt@depth@100ms":   StreamDepth,
+		"btcusdt@depth20@100ms": StreamDepth,
+		"btcusdt@bookTicker":    StreamBookTicker,
+		"btcusdt":               "",
+		"":                      "",
+	}
+	for stream, want := range tests {
+		if got := streamType(stream); got != want {
+			t.Errorf("streamType(%q) = %q, want %q", stream, got, want)
+		}
+	}
+}
+
+func TestMessageRouter_Route(t *testing.T) {
+	router := NewMessageRouter()
+
+	var got []string
+	record := func(name string) MessageHandler {
+		return MessageHandlerFunc(func(ctx context.Context, data []byte) error {
+			got = append(got, name)
+			return nil
+		})
+	}
+	router.Register("@depth", record("depth"))
+	router.Register("bookTicker", record("bookTicker")) // leading @ is optional
+
+	ctx := context.Background()
+	messages := []string{
+		`{"stream":"btcusdt@depth20@100ms","data":{}}`,
+		`{"stream":"ethusdt@bookTicker","data":{}}`,
+	}
+	for _, message := range messages {
+		if err := router.Route(ctx, []byte(message)); err != nil {
+			t.Fatalf("Route(%s) failed: %v", message, err)
+		}
+	}
+	if len(got) != 2 || got[0] != "depth" || got[1] != "bookTicker" {
+		t.Errorf("Expected [depth bookTicker], got %v", got)
+	}
+
+	err := router.Route(ctx, []byte(`{"stream":"btcusdt@kline_1m","data":{}}`))
+	if !errors.Is(err, ErrUnhandledStream) {
+		t.Errorf("Expected ErrUnhandledStream, got %v", err)
+	}
+	if err := router.Route(ctx, []byte(`not json`)); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestClient_RoutesNonTradeStreams(t *testing.T) {
+	_, cfg := setupTestServer()
+	store := newMockStore()
+	client := NewClient(cfg, store)
+
+	var depthMessages int
+	client.RegisterHandler(StreamDepth, MessageHandlerFunc(func(ctx context.Context, data []byte) error {
+		depthMessages++
+		return nil
+	}))
+
+	ctx := context.Background()
+	if err := client.processMessage(ctx, []byte(`{"stream":"btcusdt@depth@100ms","data":{"e":"depthUpdate","s":"BTCUSDT"}}`)); err != nil {
+		t.Fatalf("Failed to route depth message: %v", err)
+	}
+	if depthMessages != 1 {
+		t.Errorf("Expected depth handler to be called once, got %d", depthMessages)
+	}
+	if len(store.trades) != 0 {
+		t.Errorf("Expected depth message to not be stored as a trade, got %v", store.trades)
+	}
+
+	// The default trade handler stays registered
+	if err := client.processMessage(ctx, []byte(`{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","p":"1","q":"2","T":1625232862000}}`)); err != nil {
+		t.Fatalf("Failed to route trade message: %v", err)
+	}
+	if _, ok := store.trades["BTCUSDT"]; !ok {
+		t.Error("Expected trade message to reach the default trade handler")
+	}
+}
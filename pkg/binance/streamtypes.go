@@ -0,0 +1,69 @@
+package binance
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+// SymbolStreamConfig is the stream type a symbol is streamed with
+type SymbolStreamConfig struct {
+	Symbol     string
+	StreamType string // "trade", "aggTrade" or "miniTicker"
+}
+
+// StreamTypeStore keeps per-symbol stream type overrides keyed by upper-case
+// symbol, e.g. *storage.RedisStore
+type StreamTypeStore interface {
+	GetStreamTypes(ctx context.Context) (map[string]string, error)
+}
+
+// GetSymbolStreamConfigs reads the per-symbol stream type overrides from the
+// store, sorted by symbol, and uses them for the stream URLs built from then
+// on. Overrides of unsupported types are skipped; symbols without an override
+// stream BinanceConfig.DefaultStreamType.
+func (c *Client) GetSymbolStreamConfigs(ctx context.Context) ([]SymbolStreamConfig, error) {
+	store, ok := c.store.(StreamTypeStore)
+	if !ok {
+		return nil, nil
+	}
+	overrides, err := store.GetStreamTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]string, len(overrides))
+	configs := make([]SymbolStreamConfig, 0, len(overrides))
+	for symbol, streamType := range overrides {
+		if !config.IsStreamType(streamType) {
+			log.Printf("Warning: ignoring unsupported stream type %q of %s", streamType, symbol)
+			continue
+		}
+		symbol = strings.ToUpper(symbol)
+		types[symbol] = streamType
+		configs = append(configs, SymbolStreamConfig{Symbol: symbol, StreamType: streamType})
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Symbol < configs[j].Symbol })
+
+	c.mu.Lock()
+	c.streamTypes = types
+	c.mu.Unlock()
+	return configs, nil
+}
+
+// symbolStreamType returns the stream type symbol is streamed with
+func (c *Client) symbolStreamType(symbol string) string {
+	c.mu.RLock()
+	streamType, ok := c.streamTypes[strings.ToUpper(symbol)]
+	c.mu.RUnlock()
+	if ok {
+		return streamType
+	}
+	if c.config.Binance.DefaultStreamType != "" {
+		return c.config.Binance.DefaultStreamType
+	}
+	return config.StreamTypeTrade
+}
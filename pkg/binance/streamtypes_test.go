@@ -0,0 +1,141 @@
+package binance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+)
+
+// streamTypeStore is a trade store with stream type overrides that also
+// keeps mini tickers
+type streamTypeStore struct {
+	*mockStore
+	types   map[string]string
+	mu      sync.Mutex
+	tickers []models.MiniTicker
+}
+
+func (s *streamTypeStore) GetStreamTypes(ctx context.Context) (map[string]string, error) {
+	return s.types, nil
+}
+
+func (s *streamTypeStore) StoreMiniTicker(ctx context.Context, ticker *models.MiniTicker) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tickers = append(s.tickers, *ticker)
+	return nil
+}
+
+func TestGetSymbolStreamConfigs(t *testing.T) {
+	cfg := config.DefaultConfig()
+	store := &streamTypeStore{mockStore: newMockStore(), types: map[string]string{
+		"ETHUSDT": config.StreamTypeMiniTicker,
+		"btcusdt": config.StreamTypeAggTrade,
+		"BNBUSDT": "depth",
+	}}
+	client := NewClient(cfg, store)
+
+	symbols := []string{"BTCUSDT", "ETHUSDT", "BNBUSDT"}
+	if got, want := client.BuildStreamURL(symbols),
+		"wss://stream.binance.com:9443/stream?streams=btcusdt@trade/ethusdt@trade/bnbusdt@trade"; got != want {
+		t.Errorf("Before reading overrides got %q, want %q", got, want)
+	}
+
+	configs, err := client.GetSymbolStreamConfigs(context.Background())
+	if err != nil {
+		t.Fatalf("GetSymbolStreamConfigs() error = %v", err)
+	}
+	want := []SymbolStreamConfig{
+		{Symbol: "BTCUSDT", StreamType: config.StreamTypeAggTrade},
+		{Symbol: "ETHUSDT", StreamType: config.StreamTypeMiniTicker},
+	}
+	if len(configs) != len(want) {
+		t.Fatalf("GetSymbolStreamConfigs() = %+v, want %+v", configs, want)
+	}
+	for i := range want {
+		if configs[i] != want[i] {
+			t.Errorf("GetSymbolStreamConfigs() = %+v, want %+v", configs, want)
+		}
+	}
+
+	// The unsupported override falls back to the default type
+	if got, want := client.BuildStreamURL(symbols),
+		"wss://stream.binance.com:9443/stream?streams=btcusdt@aggTrade/ethusdt@miniTicker/bnbusdt@trade"; got != want {
+		t.Errorf("After reading overrides got %q, want %q", got, want)
+	}
+
+	cfg.Binance.DefaultStreamType = config.StreamTypeAggTrade
+	if got, want := client.BuildStreamURL([]string{"BNBUSDT"}),
+		"wss://stream.binance.com:9443/stream?streams=bnbusdt@aggTrade"; got != want {
+		t.Errorf("With an aggTrade default got %q, want %q", got, want)
+	}
+}
+
+func TestStreamTrades_MiniTicker(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RawQuery
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"stream":"ethusdt@miniTicker","data":{"e":"24hrMiniTicker","E":1,"s":"ETHUSDT","c":"3010.5","o":"2950.1","h":"3050","l":"2900","v":"1200.5","q":"3600000"}}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"stream":"btcusdt@aggTrade","data":{"e":"aggTrade","E":1,"s":"BTCUSDT","a":1,"p":"25.36","q":"1","T":1,"m":false}}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	store := &streamTypeStore{mockStore: newMockStore(), types: map[string]string{
+		"BTCUSDT": config.StreamTypeAggTrade,
+		"ETHUSDT": config.StreamTypeMiniTicker,
+	}}
+	client := NewTestClient(config.DefaultConfig(), store)
+	client.streamURL = "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trades := make(chan []byte, 2)
+	var tickers atomic.Int32
+	go client.StreamTrades(ctx, []string{"BTCUSDT", "ETHUSDT"}, func(message []byte) error {
+		if message == nil {
+			tickers.Add(1)
+			return nil
+		}
+		trades <- message
+		return nil
+	})
+
+	select {
+	case message := <-trades:
+		if !strings.Contains(string(message), "@aggTrade") {
+			t.Errorf("Expected only trades to reach the handler, got %s", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the trade")
+	}
+	// Sent before the trade, so already handled
+	if got := tickers.Load(); got != 1 {
+		t.Errorf("Expected the ticker passed to the handler as one nil message, got %d", got)
+	}
+
+	if want := "streams=btcusdt@aggTrade/ethusdt@miniTicker"; requested != want {
+		t.Errorf("Expected %q, got %q", want, requested)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.tickers) != 1 || store.tickers[0].ClosePrice != "3010.5" || store.tickers[0].QuoteVolume != "3600000" {
+		t.Errorf("Expected the mini ticker to be stored, got %+v", store.tickers)
+	}
+}
@@ -0,0 +1,225 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+const (
+	// userDataStreamPath is the REST endpoint managing listen keys
+	userDataStreamPath = "/api/v3/userDataStream"
+	// listenKeyKeepAlive is how often a listen key is extended; Binance
+	// expires keys after 60 minutes without a keep-alive
+	listenKeyKeepAlive = 30 * time.Minute
+	// listenKeyCloseTimeout bounds closing a listen key on shutdown
+	listenKeyCloseTimeout = 5 * time.Second
+)
+
+// UserDataStore persists user-data events, e.g. *storage.RedisStore
+type UserDataStore interface {
+	StoreOrderUpdate(ctx context.Context, update *models.OrderUpdate) error
+	StoreAccountPosition(ctx context.Context, position *models.AccountPosition) error
+}
+
+// StreamUserData streams the order and balance updates of the account owning
+// the configured API key into store until ctx is cancelled. Each connection
+// gets a fresh listen key, kept alive while connected and closed afterwards.
+func (c *Client) StreamUserData(ctx context.Context, store UserDataStore) error {
+	if c.config.Binance.APIKey == "" {
+		return fmt.Errorf("user-data stream requires an API key")
+	}
+
+	for ctx.Err() == nil {
+		if err := c.streamUserDataOnce(ctx, store); err != nil && ctx.Err() == nil {
+			log.Printf("User-data stream error: %v, reconnecting...", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.config.WebSocket.ReconnectDelay):
+		}
+	}
+	return ctx.Err()
+}
+
+// streamUserDataOnce runs one user-data connection until it fails or ctx is
+// cancelled
+func (c *Client) streamUserDataOnce(ctx context.Context, store UserDataStore) error {
+	listenKey, err := c.createListenKey(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), listenKeyCloseTimeout)
+		defer cancel()
+		if err := c.closeListenKey(closeCtx, listenKey); err != nil {
+			log.Printf("Warning: failed to close listen key: %v", err)
+		}
+	}()
+
+	if err := c.connects.Wait(ctx); err != nil {
+		return err
+	}
+	conn, _, err := c.dialer.DialContext(ctx, c.streamURL+"/ws/"+listenKey, nil)
+	if err != nil {
+		return fmt.Errorf("websocket dial error: %w", err)
+	}
+	defer conn.Close()
+
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-sessionCtx.Done()
+		conn.Close()
+	}()
+	go c.keepListenKeyAlive(sessionCtx, listenKey)
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("websocket read error: %w", err)
+		}
+		if err := c.handleUserData(ctx, store, message); err != nil {
+			return err
+		}
+	}
+}
+
+// keepListenKeyAlive extends listenKey every userDataKeepAlive until ctx is
+// cancelled
+func (c *Client) keepListenKeyAlive(ctx context.Context, listenKey string) {
+	ticker := time.NewTicker(c.userDataKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.userDataRequest(ctx, http.MethodPut, listenKey); err != nil && ctx.Err() == nil {
+				log.Printf("Warning: failed to keep listen key alive: %v", err)
+			}
+		}
+	}
+}
+
+// handleUserData stores one user-data event. An expired listen key ends the
+// connection so it is reopened with a new key; events that are not tracked
+// are ignored.
+func (c *Client) handleUserData(ctx context.Context, store UserDataStore, message []byte) error {
+	event, err := parseUserDataEvent(message)
+	if err != nil {
+		log.Printf("Warning: skipping user-data event: %v", err)
+		return nil
+	}
+
+	switch event := event.(type) {
+	case *models.OrderUpdate:
+		if err := store.StoreOrderUpdate(ctx, event); err != nil {
+			log.Printf("Failed to store order update for %s: %v", event.Symbol, err)
+		}
+	case *models.AccountPosition:
+		if err := store.StoreAccountPosition(ctx, event); err != nil {
+			log.Printf("Failed to store account position: %v", err)
+		}
+	case listenKeyExpired:
+		return fmt.Errorf("listen key expired")
+	}
+	return nil
+}
+
+// listenKeyExpired is the parsed form of a listenKeyExpired event
+type listenKeyExpired struct{}
+
+// parseUserDataEvent decodes a user-data event into *models.OrderUpdate,
+// *models.AccountPosition or listenKeyExpired. Other event types return nil.
+func parseUserDataEvent(message []byte) (interface{}, error) {
+	var header struct {
+		EventType string `json:"e"`
+		EventTime int64  `json:"E"` // Keeps "E" from matching "e" case-insensitively
+	}
+	if err := json.Unmarshal(message, &header); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user-data event: %w", err)
+	}
+
+	switch header.EventType {
+	case models.EventTypeExecutionReport:
+		var update models.OrderUpdate
+		if err := json.Unmarshal(message, &update); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal execution report: %w", err)
+		}
+		return &update, nil
+	case models.EventTypeAccountPosition:
+		var position models.AccountPosition
+		if err := json.Unmarshal(message, &position); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal account position: %w", err)
+		}
+		return &position, nil
+	case models.EventTypeListenKeyExpired:
+		return listenKeyExpired{}, nil
+	}
+	return nil, nil
+}
+
+// createListenKey opens a user-data stream and returns its listen key
+func (c *Client) createListenKey(ctx context.Context) (string, error) {
+	body, err := c.userDataRequest(ctx, http.MethodPost, "")
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		ListenKey string `json:"listenKey"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to decode listen key: %w", err)
+	}
+	if resp.ListenKey == "" {
+		return "", fmt.Errorf("no listen key in response")
+	}
+	return resp.ListenKey, nil
+}
+
+// closeListenKey closes the user-data stream of listenKey
+func (c *Client) closeListenKey(ctx context.Context, listenKey string) error {
+	_, err := c.userDataRequest(ctx, http.MethodDelete, listenKey)
+	return err
+}
+
+// userDataRequest calls the listen key endpoint with the configured API key
+// and returns the response body
+func (c *Client) userDataRequest(ctx context.Context, method, listenKey string) ([]byte, error) {
+	endpoint := c.baseURL + userDataStreamPath
+	if listenKey != "" {
+		endpoint += "?" + url.Values{"listenKey": {listenKey}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-MBX-APIKEY", c.config.Binance.APIKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("listen key request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp.StatusCode, body)
+	}
+	return body, nil
+}
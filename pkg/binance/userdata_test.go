@@ -0,0 +1,205 @@
+package binance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+)
+
+// Sample events from the Binance user-data stream documentation
+const (
+	executionReport = `{"e":"executionReport","E":1499405658658,"s":"ETHBTC","c":"mUvoqJxFIILMdfAW5iGSOW","S":"BUY","o":"LIMIT","f":"GTC","q":"1.00000000","p":"0.10264410","P":"0.00000000","F":"0.00000000","g":-1,"C":"","x":"TRADE","X":"PARTIALLY_FILLED","r":"NONE","i":4293153,"l":"0.40000000","z":"0.40000000","L":"0.10264410","n":"0.00004000","N":"ETH","T":1499405658657,"t":712,"I":8641984,"w":true,"m":false,"M":true,"O":1499405658657,"Z":"0.04105764","Y":"0.04105764","Q":"0.00000000","W":1499405658657,"V":"NONE"}`
+	accountPosition = `{"e":"outboundAccountPosition","E":1564034571105,"u":1564034571073,"B":[{"a":"ETH","f":"10000.000000","l":"0.000000"},{"a":"BTC","f":"1.5","l":"0.25"}]}`
+)
+
+func TestParseUserDataEvent(t *testing.T) {
+	event, err := parseUserDataEvent([]byte(executionReport))
+	if err != nil {
+		t.Fatalf("Failed to parse execution report: %v", err)
+	}
+	order, ok := event.(*models.OrderUpdate)
+	if !ok {
+		t.Fatalf("Expected an order update, got %T", event)
+	}
+	// "I" and "C" must not land in OrderID and ClientOrderID
+	if order.OrderID != 4293153 || order.ClientOrderID != "mUvoqJxFIILMdfAW5iGSOW" || order.OrigClientOrderID != "" {
+		t.Errorf("Unexpected order identity: %+v", order)
+	}
+	if order.Symbol != "ETHBTC" || order.Side != "BUY" || order.Status != "PARTIALLY_FILLED" || order.ExecutionType != "TRADE" {
+		t.Errorf("Unexpected order state: %+v", order)
+	}
+	if order.Price != "0.10264410" || order.StopPrice != "0.00000000" || order.LastQuantity != "0.40000000" || order.FilledQuantity != "0.40000000" {
+		t.Errorf("Unexpected order amounts: %+v", order)
+	}
+	if order.TradeID != 712 || order.TransactionTime != 1499405658657 || order.IsMaker || !order.IsOnBook {
+		t.Errorf("Unexpected fill details: %+v", order)
+	}
+
+	event, err = parseUserDataEvent([]byte(accountPosition))
+	if err != nil {
+		t.Fatalf("Failed to parse account position: %v", err)
+	}
+	position, ok := event.(*models.AccountPosition)
+	if !ok {
+		t.Fatalf("Expected an account position, got %T", event)
+	}
+	if position.LastUpdate != 1564034571073 || len(position.Balances) != 2 ||
+		position.Balances[1] != (models.Balance{Asset: "BTC", Free: "1.5", Locked: "0.25"}) {
+		t.Errorf("Unexpected account position: %+v", position)
+	}
+
+	if event, err := parseUserDataEvent([]byte(`{"e":"listenKeyExpired","E":1576653824250,"listenKey":"abc"}`)); err != nil || event != (listenKeyExpired{}) {
+		t.Errorf("Expected listenKeyExpired, got %v (%v)", event, err)
+	}
+	if event, err := parseUserDataEvent([]byte(`{"e":"balanceUpdate","E":1573200697110,"a":"BTC","d":"100.00000000"}`)); err != nil || event != nil {
+		t.Errorf("Expected untracked events to be ignored, got %v (%v)", event, err)
+	}
+	if _, err := parseUserDataEvent([]byte(`not json`)); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+// userDataRecorder stores user-data events in memory
+type userDataRecorder struct {
+	mu        sync.Mutex
+	orders    []*models.OrderUpdate
+	positions []*models.AccountPosition
+	received  chan struct{}
+}
+
+func (r *userDataRecorder) StoreOrderUpdate(ctx context.Context, update *models.OrderUpdate) error {
+	r.mu.Lock()
+	r.orders = append(r.orders, update)
+	r.mu.Unlock()
+	r.received <- struct{}{}
+	return nil
+}
+
+func (r *userDataRecorder) StoreAccountPosition(ctx context.Context, position *models.AccountPosition) error {
+	r.mu.Lock()
+	r.positions = append(r.positions, position)
+	r.mu.Unlock()
+	r.received <- struct{}{}
+	return nil
+}
+
+// listenKeyServer mocks the listen key endpoint and the user-data websocket
+type listenKeyServer struct {
+	mu        sync.Mutex
+	apiKeys   []string
+	created   int
+	keepAlive int
+	closed    []string
+}
+
+func (s *listenKeyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/ws/key-1" {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(executionReport))
+		conn.WriteMessage(websocket.TextMessage, []byte(accountPosition))
+		// Hold the connection until the client goes away
+		conn.ReadMessage()
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKeys = append(s.apiKeys, r.Header.Get("X-MBX-APIKEY"))
+	switch r.Method {
+	case http.MethodPost:
+		s.created++
+		w.Write([]byte(`{"listenKey":"key-1"}`))
+	case http.MethodPut:
+		s.keepAlive++
+		w.Write([]byte(`{}`))
+	case http.MethodDelete:
+		s.closed = append(s.closed, r.URL.Query().Get("listenKey"))
+		w.Write([]byte(`{}`))
+	}
+}
+
+func (s *listenKeyServer) keepAlives() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keepAlive
+}
+
+func TestStreamUserData_ListenKeyLifecycle(t *testing.T) {
+	mock := &listenKeyServer{}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Binance.BaseURL = server.URL
+	cfg.Binance.APIKey = "test-key"
+	client := NewTestClient(cfg, newMockStore())
+	client.streamURL = "ws" + strings.TrimPrefix(server.URL, "http")
+	client.userDataKeepAlive = 10 * time.Millisecond
+
+	store := &userDataRecorder{received: make(chan struct{}, 2)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- client.StreamUserData(ctx, store) }()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-store.received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for user-data events")
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for mock.keepAlives() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("StreamUserData did not return after cancellation")
+	}
+
+	if len(store.orders) != 1 || store.orders[0].OrderID != 4293153 {
+		t.Errorf("Expected the execution report to be stored, got %+v", store.orders)
+	}
+	if len(store.positions) != 1 || len(store.positions[0].Balances) != 2 {
+		t.Errorf("Expected the account position to be stored, got %+v", store.positions)
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if mock.created != 1 || mock.keepAlive == 0 {
+		t.Errorf("Expected one listen key kept alive, got %d created and %d keep-alives", mock.created, mock.keepAlive)
+	}
+	if len(mock.closed) != 1 || mock.closed[0] != "key-1" {
+		t.Errorf("Expected the listen key to be closed on shutdown, got %v", mock.closed)
+	}
+	for _, key := range mock.apiKeys {
+		if key != "test-key" {
+			t.Errorf("Expected every request to carry the API key, got %q", key)
+		}
+	}
+}
+
+func TestStreamUserData_RequiresAPIKey(t *testing.T) {
+	client := NewTestClient(config.DefaultConfig(), newMockStore())
+	client.config.Binance.APIKey = ""
+	if err := client.StreamUserData(context.Background(), &userDataRecorder{}); err == nil {
+		t.Error("Expected an error without an API key")
+	}
+}
@@ -0,0 +1,202 @@
+package breaker
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+// stateGauge is the state of each circuit breaker (0 closed, 1 open, 2
+// half-open). It lives here rather than in pkg/metrics so that storage can
+// use breakers without importing the metrics exporter.
+var stateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "binance_circuit_breaker_state",
+	Help: "Circuit breaker state: 0 closed, 1 open, 2 half-open.",
+}, []string{"breaker"})
+
+// State is the state of a circuit breaker
+type State int
+
+// Circuit breaker states
+const (
+	Closed   State = iota // Calls go through; failures are counted
+	Open                  // Calls are rejected until the cool-down ends
+	HalfOpen              // One probe call decides whether to close or reopen
+)
+
+// String returns the lowercase name of the state
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrOpen is returned for calls rejected while the circuit is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Breaker stops calls to a failing dependency. It opens after MaxFailures
+// consecutive failures within Window, rejects calls for Cooldown, then lets a
+// single probe through: success closes it again, failure reopens it.
+type Breaker struct {
+	name        string
+	maxFailures int
+	window      time.Duration
+	cooldown    time.Duration
+	now         func() time.Time
+
+	mu           sync.Mutex
+	state        State
+	failures     int
+	firstFailure time.Time
+	openedAt     time.Time
+	probing      bool
+}
+
+// New creates a closed breaker; name identifies it in logs and metrics
+func New(name string, cfg config.BreakerConfig) *Breaker {
+	b := &Breaker{
+		name:        name,
+		maxFailures: cfg.MaxFailures,
+		window:      cfg.Window,
+		cooldown:    cfg.Cooldown,
+		now:         time.Now,
+	}
+	stateGauge.WithLabelValues(name).Set(float64(Closed))
+	return b
+}
+
+// Allow reports whether a call may proceed, returning ErrOpen while the
+// circuit is open or a half-open probe is already in flight. Every allowed
+// call must be followed by Success, Failure or Cancel.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrOpen
+		}
+		b.setState(HalfOpen)
+		b.probing = true
+		return nil
+	case HalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Success records a successful call, closing a half-open circuit
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	if b.state != Closed {
+		b.setState(Closed)
+	}
+}
+
+// Failure records a failed call. A failed probe reopens the circuit; in the
+// closed state the circuit opens once enough failures fall within the window.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case HalfOpen:
+		b.probing = false
+		b.open(now)
+	case Closed:
+		if b.failures == 0 || now.Sub(b.firstFailure) > b.window {
+			b.failures = 0
+			b.firstFailure = now
+		}
+		b.failures++
+		if b.failures >= b.maxFailures {
+			b.open(now)
+		}
+	}
+}
+
+// Cancel records that an allowed call was abandoned before it had an
+// outcome, e.g. because its context was cancelled. A half-open probe is
+// released so the next call can probe instead.
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// Do runs fn if the breaker allows it and records its outcome
+func (b *Breaker) Do(fn func() error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		b.Failure()
+		return err
+	}
+	b.Success()
+	return nil
+}
+
+// State returns the current state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// RetryAfter returns how long until an open circuit lets a probe through,
+// or 0 when calls may be attempted now
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != Open {
+		return 0
+	}
+	if wait := b.cooldown - b.now().Sub(b.openedAt); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// open trips the circuit; the caller must hold mu
+func (b *Breaker) open(now time.Time) {
+	log.Printf("Warning: circuit breaker %s opened, retrying in %s", b.name, b.cooldown)
+	b.failures = 0
+	b.openedAt = now
+	b.setState(Open)
+}
+
+// setState changes state and updates the state gauge; the caller must hold mu
+func (b *Breaker) setState(state State) {
+	if state != Open {
+		log.Printf("Circuit breaker %s: %s -> %s", b.name, b.state, state)
+	}
+	b.state = state
+	stateGauge.WithLabelValues(b.name).Set(float64(state))
+}
@@ -0,0 +1,176 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+// newTestBreaker returns a breaker opening after 3 failures within a minute,
+// with a 30s cool-down, driven by a controllable clock
+func newTestBreaker() (*Breaker, *time.Time) {
+	b := New("test", config.BreakerConfig{MaxFailures: 3, Window: time.Minute, Cooldown: 30 * time.Second})
+	now := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func assertState(t *testing.T, b *Breaker, want State) {
+	t.Helper()
+	if got := b.State(); got != want {
+		t.Fatalf("Expected state %s, got %s", want, got)
+	}
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker()
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Expected closed breaker to allow calls, got %v", err)
+		}
+		b.Failure()
+		assertState(t, b, Closed)
+	}
+
+	b.Failure()
+	assertState(t, b, Open)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Expected ErrOpen while open, got %v", err)
+	}
+	if wait := b.RetryAfter(); wait != 30*time.Second {
+		t.Errorf("Expected 30s until retry, got %s", wait)
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b, _ := newTestBreaker()
+
+	b.Failure()
+	b.Failure()
+	b.Success()
+	b.Failure()
+	b.Failure()
+	assertState(t, b, Closed)
+}
+
+func TestBreakerFailuresOutsideWindowStartNewCount(t *testing.T) {
+	b, now := newTestBreaker()
+
+	b.Failure()
+	b.Failure()
+	*now = now.Add(61 * time.Second)
+	b.Failure()
+	assertState(t, b, Closed)
+
+	b.Failure()
+	b.Failure()
+	assertState(t, b, Open)
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	b, now := newTestBreaker()
+	for i := 0; i < 3; i++ {
+		b.Failure()
+	}
+
+	*now = now.Add(29 * time.Second)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Expected ErrOpen before the cool-down ends, got %v", err)
+	}
+
+	*now = now.Add(time.Second)
+	if b.RetryAfter() != 0 {
+		t.Errorf("Expected no wait once the cool-down ends, got %s", b.RetryAfter())
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Expected a probe after the cool-down, got %v", err)
+	}
+	assertState(t, b, HalfOpen)
+
+	// Only one probe at a time
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Expected ErrOpen for a second concurrent probe, got %v", err)
+	}
+
+	b.Success()
+	assertState(t, b, Closed)
+	if err := b.Allow(); err != nil {
+		t.Errorf("Expected closed breaker to allow calls, got %v", err)
+	}
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	b, now := newTestBreaker()
+	for i := 0; i < 3; i++ {
+		b.Failure()
+	}
+
+	*now = now.Add(30 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatal(err)
+	}
+	b.Failure()
+	assertState(t, b, Open)
+
+	// The cool-down restarts from the failed probe
+	*now = now.Add(29 * time.Second)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Expected ErrOpen during the new cool-down, got %v", err)
+	}
+	*now = now.Add(time.Second)
+	if err := b.Allow(); err != nil {
+		t.Errorf("Expected a new probe after the cool-down, got %v", err)
+	}
+}
+
+func TestBreakerCancelledProbeReleases(t *testing.T) {
+	b, now := newTestBreaker()
+	for i := 0; i < 3; i++ {
+		b.Failure()
+	}
+
+	*now = now.Add(30 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatal(err)
+	}
+	b.Cancel()
+
+	// Still half-open, and the next call may probe
+	assertState(t, b, HalfOpen)
+	if err := b.Allow(); err != nil {
+		t.Errorf("Expected a new probe after the last was cancelled, got %v", err)
+	}
+
+	// Cancelling a call of a closed breaker counts nothing
+	b.Success()
+	b.Failure()
+	b.Failure()
+	b.Cancel()
+	b.Failure()
+	assertState(t, b, Open)
+}
+
+func TestBreakerDo(t *testing.T) {
+	b, _ := newTestBreaker()
+	failing := errors.New("unavailable")
+
+	calls := 0
+	for i := 0; i < 5; i++ {
+		err := b.Do(func() error {
+			calls++
+			return failing
+		})
+		if i < 3 && !errors.Is(err, failing) {
+			t.Errorf("Call %d: expected the call's error, got %v", i, err)
+		}
+		if i >= 3 && !errors.Is(err, ErrOpen) {
+			t.Errorf("Call %d: expected ErrOpen, got %v", i, err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls before the circuit opened, got %d", calls)
+	}
+}
@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/analysis"
+	"binance-redis-streamer/pkg/timeutil"
+)
+
+// Bounds of the alert --interval and --poll
+const (
+	maxAlertInterval = 7 * 24 * time.Hour
+	minAlertPoll     = time.Second
+)
+
+// alertWarmup is how many times its lookback an indicator is computed over,
+// so RSI and ATR smoothing has settled by the candles checked
+const alertWarmup = 10
+
+// alertEvent is a condition met on a symbol's candle
+type alertEvent struct {
+	Symbol    string
+	Time      time.Time // Start of the candle the indicator crossed on
+	Condition analysis.Condition
+	Value     float64
+}
+
+func newAlertCmd() *cobra.Command {
+	var (
+		conditionSpecs []string
+		interval       string
+		poll           string
+		once           bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "alert [symbols...]",
+		Short: "Alert when an indicator crosses a threshold",
+		Long: `Watch the stored candles of symbols and print an alert each time an indicator
+crosses a threshold on a complete candle. Conditions are an indicator (rsi:N,
+atr:N or zscore:N for the rolling z-score of the close), > or < and a
+threshold: rsi:14>70 fires when RSI(14) rises above 70, rsi:14<30 when it
+falls below 30. Only candles completed after the command starts are checked;
+with --once the command checks the latest complete candle and exits.
+Example: binance-cli alert BTCUSDT ETHUSDT --when 'rsi:14>70' --when 'rsi:14<30'
+         binance-cli alert BTCUSDT --when 'zscore:60<-3' --interval 5m`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(conditionSpecs) == 0 {
+				return fmt.Errorf("at least one --when condition is required")
+			}
+			conditions := make([]analysis.Condition, len(conditionSpecs))
+			for i, spec := range conditionSpecs {
+				var err error
+				if conditions[i], err = analysis.ParseCondition(spec); err != nil {
+					return err
+				}
+			}
+			step, err := timeutil.ParseDuration(interval, maxAlertInterval)
+			if err != nil {
+				return fmt.Errorf("invalid interval: %w", err)
+			}
+			every, err := timeutil.ParseDuration(poll, 0)
+			if err != nil {
+				return fmt.Errorf("invalid poll interval: %w", err)
+			}
+			if every < minAlertPoll {
+				return fmt.Errorf("poll interval must be at least %s", minAlertPoll)
+			}
+			symbols, err := resolveSymbols(cmd.Context(), args)
+			if err != nil {
+				return err
+			}
+
+			postgresStore, err := newPostgresStore(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+			}
+			defer postgresStore.Close()
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, os.Interrupt)
+			go func() {
+				<-sigCh
+				cancel()
+			}()
+
+			out := cmd.OutOrStdout()
+
+			// Candles completed before now are history: start checking from
+			// the latest one with --once and after it otherwise
+			since := make(map[string]time.Time, len(symbols))
+			for _, symbol := range symbols {
+				since[symbol] = time.Now().Add(-step)
+				if once {
+					since[symbol] = since[symbol].Add(-step)
+				}
+			}
+
+			ticker := time.NewTicker(every)
+			defer ticker.Stop()
+			for {
+				for _, symbol := range symbols {
+					events, latest, err := pollAlerts(ctx, postgresStore, symbol, conditions, interval, step, since[symbol], time.Now())
+					if err != nil {
+						if ctx.Err() != nil {
+							return nil
+						}
+						return err
+					}
+					printAlerts(out, events)
+					if latest.After(since[symbol]) {
+						since[symbol] = latest
+					}
+				}
+				if once {
+					return nil
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&conditionSpecs, "when", nil, "Condition to alert on, e.g. rsi:14>70 (repeatable)")
+	cmd.Flags().StringVarP(&interval, "interval", "i", "1m", "Candle interval the indicators are computed on (e.g., 1m, 15m, 1h)")
+	cmd.Flags().StringVar(&poll, "poll", "30s", "How often to check for new candles")
+	cmd.Flags().BoolVar(&once, "once", false, "Check the latest complete candle and exit")
+	return cmd
+}
+
+// pollAlerts loads the recent candles of symbol and returns the conditions
+// met on complete candles starting after since, with the start of the latest
+// complete candle
+func pollAlerts(ctx context.Context, store candleLoader, symbol string, conditions []analysis.Condition, interval string, step time.Duration, since, now time.Time) ([]alertEvent, time.Time, error) {
+	lookback := 0
+	for _, cond := range conditions {
+		if n := cond.Indicator.Lookback(); n > lookback {
+			lookback = n
+		}
+	}
+
+	start := since.Add(-time.Duration(lookback*alertWarmup+1) * step)
+	candles, err := store.GetAggregatedCandles(ctx, symbol, start, now, interval)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to get candles for %s: %w", symbol, err)
+	}
+
+	// The last bucket may still be filling up
+	for len(candles) > 0 && candles[len(candles)-1].Timestamp.Add(step).After(now) {
+		candles = candles[:len(candles)-1]
+	}
+	if len(candles) == 0 {
+		return nil, since, nil
+	}
+
+	events, err := checkAlerts(symbol, candles, conditions, since)
+	return events, candles[len(candles)-1].Timestamp, err
+}
+
+// checkAlerts returns the conditions met on candles starting after since,
+// oldest first. Each candle is compared with the one before it, so a
+// condition fires once when crossed rather than on every candle beyond the
+// threshold.
+func checkAlerts(symbol string, candles []*models.Candle, conditions []analysis.Condition, since time.Time) ([]alertEvent, error) {
+	var events []alertEvent
+	for _, cond := range conditions {
+		values, err := cond.Indicator.Compute(candles)
+		if err != nil {
+			return nil, err
+		}
+		for i := 1; i < len(candles); i++ {
+			if candles[i].Timestamp.After(since) && cond.Crossed(values[i-1], values[i]) {
+				events = append(events, alertEvent{Symbol: symbol, Time: candles[i].Timestamp, Condition: cond, Value: values[i]})
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// printAlerts prints one line per alert
+func printAlerts(out io.Writer, events []alertEvent) {
+	for _, event := range events {
+		direction := "below"
+		if event.Condition.Above {
+			direction = "above"
+		}
+		fmt.Fprintf(out, "%s %-10s %s crossed %s %s: %s\n",
+			event.Time.Local().Format(time.DateTime), event.Symbol, event.Condition.Indicator, direction,
+			strconv.FormatFloat(event.Condition.Threshold, 'f', -1, 64), formatIndicator(event.Value))
+	}
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/pkg/analysis"
+)
+
+func TestPollAlerts(t *testing.T) {
+	// RSI(1) is 100 after a rise and 0 after a fall: [-, 0, 0, 100, 100, 0, 100]
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := fakeCandles{"BTCUSDT": hourlyCloses(10, 9, 8, 9, 10, 9, 10)}
+	var conditions []analysis.Condition
+	for _, spec := range []string{"rsi:1>70", "rsi:1<30"} {
+		cond, err := analysis.ParseCondition(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conditions = append(conditions, cond)
+	}
+
+	// The rise at 03:00 is before since; the fall at 05:00 and the rise at
+	// 06:00 are reported in order
+	events, latest, err := pollAlerts(context.Background(), store, "BTCUSDT", conditions, "1h", time.Hour, start.Add(3*time.Hour), start.Add(7*time.Hour))
+	if err != nil {
+		t.Fatalf("pollAlerts() error = %v", err)
+	}
+	if !latest.Equal(start.Add(6 * time.Hour)) {
+		t.Errorf("Latest complete candle = %v, want 06:00", latest)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 alerts, got %+v", events)
+	}
+	if !events[0].Time.Equal(start.Add(5*time.Hour)) || events[0].Condition.Above || events[0].Value != 0 {
+		t.Errorf("First alert = %+v, want RSI below 30 at 05:00", events[0])
+	}
+	if !events[1].Time.Equal(start.Add(6*time.Hour)) || !events[1].Condition.Above || events[1].Value != 100 {
+		t.Errorf("Second alert = %+v, want RSI above 70 at 06:00", events[1])
+	}
+
+	var out bytes.Buffer
+	printAlerts(&out, events[1:])
+	if !strings.Contains(out.String(), "BTCUSDT    RSI(1) crossed above 70: 100.0000") {
+		t.Errorf("Unexpected alert line: %q", out.String())
+	}
+
+	// The 06:00 candle is still filling up at 06:30
+	events, latest, err = pollAlerts(context.Background(), store, "BTCUSDT", conditions, "1h", time.Hour, start.Add(3*time.Hour), start.Add(6*time.Hour+30*time.Minute))
+	if err != nil {
+		t.Fatalf("pollAlerts() error = %v", err)
+	}
+	if len(events) != 1 || !latest.Equal(start.Add(5*time.Hour)) {
+		t.Errorf("Expected only the 05:00 alert before 07:00, got %+v (latest %v)", events, latest)
+	}
+
+	// Without candles nothing fires and since is kept
+	since := start.Add(3 * time.Hour)
+	if events, latest, err := pollAlerts(context.Background(), store, "ETHUSDT", conditions, "1h", time.Hour, since, start.Add(7*time.Hour)); err != nil || len(events) != 0 || !latest.Equal(since) {
+		t.Errorf("pollAlerts(ETHUSDT) = %+v, %v, %v, want nothing", events, latest, err)
+	}
+}
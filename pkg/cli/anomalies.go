@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/processor"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func newAnomaliesCmd() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "anomalies [symbol]",
+		Short: "List recent outsized trades and price jumps",
+		Long: `List the most recent anomalies flagged by the processor, newest first:
+trades whose notional is far above the symbol's average trade size, and price
+moves beyond the configured percentage within the jump window. Detection must
+be enabled with ANOMALY_DETECTION=true or processor.anomalies.enabled.
+Example: binance-cli anomalies BTCUSDT --limit 20`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var symbol string
+			if len(args) == 1 {
+				var err error
+				if symbol, err = resolveSymbol(cmd.Context(), args[0]); err != nil {
+					return err
+				}
+			}
+
+			return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+				svc := processor.NewService(configFromContext(cmd.Context()), store, nil)
+				events, err := svc.ListAnomalies(cmd.Context(), symbol, limit)
+				if err != nil {
+					return err
+				}
+				printAnomalies(cmd.OutOrStdout(), events)
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "l", 20, "Maximum number of events to show (0 for all)")
+	return cmd
+}
+
+// printAnomalies prints anomaly events as a table
+func printAnomalies(out io.Writer, events []*models.AnomalyEvent) {
+	if len(events) == 0 {
+		fmt.Fprintln(out, "No anomalies recorded")
+		return
+	}
+
+	fmt.Fprintf(out, "%-20s %-10s %-15s %14s  %s\n", "Time", "Symbol", "Kind", "Price", "Detail")
+	fmt.Fprintln(out, strings.Repeat("-", 90))
+	for _, event := range events {
+		fmt.Fprintf(out, "%-20s %-10s %-15s %14s  %s\n",
+			event.Time.Local().Format(time.DateTime),
+			event.Symbol, event.Kind, formatFloat(event.Price, 8), anomalyDetail(event))
+	}
+}
+
+// anomalyDetail describes why an event was flagged
+func anomalyDetail(event *models.AnomalyEvent) string {
+	switch event.Kind {
+	case models.AnomalyOutsizedTrade:
+		return fmt.Sprintf("notional %.2f is %.1fσ above the %.2f average", event.Notional, event.Sigmas, event.MeanNotional)
+	case models.AnomalyPriceJump:
+		return fmt.Sprintf("%+.2f%% from %s within %v", event.ChangePercent, formatFloat(event.ReferencePrice, 8), event.Window)
+	default:
+		return ""
+	}
+}
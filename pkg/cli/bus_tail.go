@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/pkg/messaging"
+	"binance-redis-streamer/pkg/storage"
+)
+
+// Message buses bus-tail can read
+const busRedis = "redis"
+
+func newBusTailCmd() *cobra.Command {
+	var (
+		symbol string
+		bus    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bus-tail",
+		Short: "Print the trades flowing on the message bus",
+		Long: `Subscribe to the trade message bus and print each trade as it is published,
+with its envelope (exchange, source, version), the delay from trade to
+ingestion, a running count and the rate since the tail started. Useful to
+check that ingestion publishes before looking at processing or storage.
+Only the Redis pub/sub bus is implemented.
+Example: binance-cli bus-tail
+         binance-cli bus-tail --symbol BTCUSDT`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if bus != busRedis {
+				return fmt.Errorf("unsupported bus %q: only %s is implemented", bus, busRedis)
+			}
+			if symbol != "" {
+				var err error
+				if symbol, err = resolveSymbol(cmd.Context(), symbol); err != nil {
+					return err
+				}
+			}
+
+			cfg := configFromContext(cmd.Context())
+			store, err := storage.NewRedisStore(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to connect to Redis: %w", err)
+			}
+			defer store.Close()
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, os.Interrupt)
+			go func() {
+				<-sigCh
+				cancel()
+			}()
+
+			if err := tailBus(ctx, messaging.NewRedisPubSub(store.GetRedisClient()), symbol, cmd.OutOrStdout()); err != nil && ctx.Err() == nil {
+				return fmt.Errorf("failed to tail the message bus: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&symbol, "symbol", "s", "", "Only print the trades of this symbol")
+	cmd.Flags().StringVar(&bus, "bus", busRedis, "Message bus to read (redis)")
+	return cmd
+}
+
+// tailBus prints the trades published on bus, or only those of symbol when
+// set, until ctx is cancelled
+func tailBus(ctx context.Context, bus messaging.MessageBus, symbol string, out io.Writer) error {
+	start := time.Now()
+	var count int64
+	return bus.SubscribeSymbol(ctx, symbol, func(env *messaging.Envelope) error {
+		count++
+		rate := float64(count) / max(time.Since(start).Seconds(), 1)
+		fmt.Fprintln(out, formatBusEvent(count, rate, env))
+		return nil
+	})
+}
+
+// formatBusEvent renders a trade from the bus as one line: its count, the
+// trade, its envelope and the rate of trades so far
+func formatBusEvent(count int64, rate float64, env *messaging.Envelope) string {
+	trade := env.Payload.Data
+	side := "BUY"
+	if trade.IsBuyerMaker {
+		side = "SELL"
+	}
+	id := trade.TradeID
+	if id == 0 {
+		id = trade.AggregateTradeID
+	}
+
+	tradeTime := time.UnixMilli(trade.TradeTime)
+	lag := "-"
+	if !env.IngestedAt.IsZero() {
+		lag = env.IngestedAt.Sub(tradeTime).Round(time.Millisecond).String()
+	}
+	exchange := env.Exchange
+	if exchange == "" {
+		exchange = "-"
+	}
+
+	return fmt.Sprintf("#%-6d %s %-10s %-4s %14s @ %-14s %-8s id=%-12d %s/%s v%d lag=%-8s %.1f/s",
+		count, tradeTime.Local().Format("15:04:05.000"), trade.Symbol, side, trade.Quantity, trade.Price,
+		trade.EventType, id, exchange, env.Source, env.Version, lag, rate)
+}
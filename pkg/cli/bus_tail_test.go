@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/messaging"
+)
+
+// syncBuffer is a bytes.Buffer safe to write and read from different
+// goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTailBus_PrintsTrades(t *testing.T) {
+	store, _, _ := newMiniredisStore(t)
+	bus := messaging.NewRedisPubSub(store.GetRedisClient())
+	bus.SetExchange("binance")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- tailBus(ctx, bus, "BTCUSDT", &out) }()
+	time.Sleep(100 * time.Millisecond)
+
+	now := time.Now()
+	for _, data := range []models.TradeData{
+		{EventType: models.EventTypeTrade, Symbol: "BTCUSDT", TradeID: 41, Price: "50000.00", Quantity: "0.5", TradeTime: now.UnixMilli()},
+		{EventType: models.EventTypeTrade, Symbol: "ETHUSDT", TradeID: 7, Price: "3000.00", Quantity: "2", TradeTime: now.UnixMilli()},
+		{EventType: models.EventTypeAggTrade, Symbol: "BTCUSDT", AggregateTradeID: 42, Price: "50001.00", Quantity: "1.25", TradeTime: now.UnixMilli(), IsBuyerMaker: true},
+	} {
+		if err := bus.Publish(ctx, &models.AggTradeEvent{Data: data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for strings.Count(out.String(), "\n") < 2 {
+		if ctx.Err() != nil {
+			t.Fatalf("Timed out waiting for two trades, got:\n%s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected the two BTCUSDT trades, got:\n%s", out.String())
+	}
+	for i, want := range [][]string{
+		{"#1 ", "BTCUSDT", "BUY", "0.5 @ 50000.00", "id=41", "binance/live v2"},
+		{"#2 ", "BTCUSDT", "SELL", "1.25 @ 50001.00", "aggTrade", "id=42"},
+	} {
+		for _, field := range want {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("Expected %q in line %d: %s", field, i+1, lines[i])
+			}
+		}
+	}
+	if strings.Contains(out.String(), "ETHUSDT") {
+		t.Errorf("Expected other symbols filtered out, got:\n%s", out.String())
+	}
+}
+
+func TestBusTailCmd_RejectsUnknownBus(t *testing.T) {
+	root := NewRootCmd()
+	root.SetArgs([]string{"bus-tail", "--bus", "nats"})
+	root.SilenceUsage = true
+	root.SilenceErrors = true
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), `unsupported bus "nats"`) {
+		t.Errorf("Expected an unsupported bus error, got %v", err)
+	}
+}
@@ -3,13 +3,17 @@ package cli
 import (
 	"context"
 	"embed"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -18,42 +22,111 @@ import (
 	"github.com/spf13/cobra"
 
 	"binance-redis-streamer/internal/models"
-	"binance-redis-streamer/pkg/storage"
+	"binance-redis-streamer/pkg/analysis"
+	"binance-redis-streamer/pkg/timeutil"
 )
 
-//go:embed templates
-var templateFS embed.FS
+// maxChartPeriod bounds the period of chart and of its CSV download
+const maxChartPeriod = 365 * 24 * time.Hour
 
-type ChartData struct {
-	Symbol string    `json:"symbol"`
-	Time   []string  `json:"time"`
-	Open   []string  `json:"open"`
-	High   []string  `json:"high"`
-	Low    []string  `json:"low"`
-	Close  []string  `json:"close"`
-	Volume []float64 `json:"volume"`
+// Chart styles selected with --style
+const (
+	chartStyleCandles    = "candles"
+	chartStyleHeikinAshi = "heikin-ashi"
+	chartStyleLine       = "line"
+)
+
+// chartSeries returns the series the chart draws for candles in style,
+// converting them to Heikin-Ashi candles when asked
+func chartSeries(symbol string, candles []*models.Candle, style string) (models.CandleSeries, error) {
+	switch style {
+	case chartStyleCandles, chartStyleLine:
+	case chartStyleHeikinAshi:
+		candles = analysis.HeikinAshi(candles)
+	default:
+		return models.CandleSeries{}, fmt.Errorf("invalid style %q: must be %s, %s or %s",
+			style, chartStyleCandles, chartStyleHeikinAshi, chartStyleLine)
+	}
+	// The chart plots prices directly, so send them as numbers
+	return models.CandleSeries{Symbol: symbol, Candles: candles, Style: style, Numeric: true}, nil
 }
 
+//go:embed templates
+var templateFS embed.FS
+
 func newChartCmd() *cobra.Command {
 	var port int
 	var period string
+	var output string
+	var style string
+	var logScale bool
+	var overlaySpec string
+	var renderer string
+	var width, height int
+	var library string
+	var profileBins int
+	var indicatorSpec string
+	var interval string
+	var boundary string
 
 	cmd := &cobra.Command{
 		Use:   "chart [symbol]",
 		Short: "View interactive price charts",
 		Long: `View interactive price charts in your web browser.
-Example: binance-cli chart BTCUSDT --period 24h`,
+With --output the chart is saved to an HTML file with its candles inlined
+instead of being served, e.g. for scripts or to share it. Pages inline the
+charting library bundled with the CLI, so they need no network; --library
+inlines another local copy of lightweight-charts instead. An
+output ending in .png is rendered as an image, in Go by default or with
+headless Chrome (--renderer browser, CHROME_PATH to pick the binary).
+--style draws regular candles, Heikin-Ashi candles or a line of closes, and
+--log-scale plots prices on a logarithmic axis. --overlays draws moving
+averages (maN over N candles) and Bollinger Bands over the prices; a volume
+pane is shown below. --indicators draws RSI, ATR or the rolling z-score of
+the close in a pane of their own, e.g. --indicators rsi:14,atr:14. --profile N adds the volume profile of the candles in N
+price bins to /api/data and marks its point of control and value area.
+--interval draws candles longer than a minute, with daily candles beginning
+at --day-boundary: UTC by default, or an offset such as UTC+8.
+Example: binance-cli chart BTCUSDT --period 24h
+         binance-cli chart BTCUSDT --period 3mo --interval 1d --day-boundary UTC+8
+         binance-cli chart BTCUSDT --period 7d --style heikin-ashi --log-scale
+         binance-cli chart BTCUSDT --period 24h --overlays ma20,ma50,bollinger
+         binance-cli chart BTCUSDT --period 7d --output btc.html
+         binance-cli chart BTCUSDT --period 7d --output btc.png --width 1600`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			symbol := strings.ToUpper(args[0])
+			// Check the style and overlays before connecting anywhere
+			if _, err := chartSeries("", nil, style); err != nil {
+				return err
+			}
+			overlays, err := parseOverlays(overlaySpec)
+			if err != nil {
+				return err
+			}
+			indicators, err := analysis.ParseIndicators(indicatorSpec)
+			if err != nil {
+				return err
+			}
+			symbol, err := resolveSymbol(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
 
 			// Parse time period
-			duration, err := parseDuration(period)
+			duration, err := timeutil.ParseDuration(period, maxChartPeriod)
 			if err != nil {
-				return fmt.Errorf("invalid period format: %w", err)
+				return fmt.Errorf("invalid period: %w", err)
+			}
+			step, err := timeutil.ParseDuration(interval, maxChartPeriod)
+			if err != nil {
+				return fmt.Errorf("invalid interval: %w", err)
+			}
+			offset, err := timeutil.ParseDayBoundary(boundary)
+			if err != nil {
+				return err
 			}
 
-			postgresStore, err := storage.NewPostgresStore()
+			postgresStore, err := newPostgresStore(cmd.Context())
 			if err != nil {
 				return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 			}
@@ -65,7 +138,12 @@ Example: binance-cli chart BTCUSDT --period 24h`,
 
 			log.Printf("Fetching candles for %s from %s to %s", symbol, start.Format(time.RFC3339), end.Format(time.RFC3339))
 
-			dbCandles, err := postgresStore.GetHistoricalCandles(context.Background(), symbol, start, end)
+			var dbCandles []*models.Candle
+			if step == time.Minute {
+				dbCandles, err = postgresStore.GetHistoricalCandles(cmd.Context(), symbol, start, end)
+			} else {
+				dbCandles, err = postgresStore.GetAggregatedCandlesAligned(cmd.Context(), symbol, start, end, interval, offset)
+			}
 			if err != nil {
 				log.Printf("Error fetching candles: %v", err)
 				return fmt.Errorf("failed to fetch candles: %w", err)
@@ -82,34 +160,45 @@ Example: binance-cli chart BTCUSDT --period 24h`,
 					dbCandles[0].Volume)
 			}
 
-			// Convert to chart data format
-			data := ChartData{
-				Symbol: symbol,
-				Time:   make([]string, len(dbCandles)),
-				Open:   make([]string, len(dbCandles)),
-				High:   make([]string, len(dbCandles)),
-				Low:    make([]string, len(dbCandles)),
-				Close:  make([]string, len(dbCandles)),
-				Volume: make([]float64, len(dbCandles)),
+			data, err := newChartData(symbol, dbCandles, style, overlays)
+			if err != nil {
+				return err
+			}
+			if data.Indicators, err = computeIndicatorLines(dbCandles, indicators); err != nil {
+				return err
+			}
+			if profileBins > 0 && len(dbCandles) > 0 {
+				profile, err := analysis.CandleVolumeProfile(dbCandles, profileBins)
+				if err != nil {
+					return fmt.Errorf("failed to compute volume profile: %w", err)
+				}
+				data.Profile = newProfileDataset(symbol, period, profileSourceCandles, profile)
 			}
 
-			for i, candle := range dbCandles {
-				// Convert timestamp to Unix timestamp in seconds
-				data.Time[i] = fmt.Sprintf("%d", candle.Timestamp.Unix())
-
-				// Convert string prices to float64 for proper JSON encoding
-				open, _ := strconv.ParseFloat(candle.OpenPrice, 64)
-				high, _ := strconv.ParseFloat(candle.HighPrice, 64)
-				low, _ := strconv.ParseFloat(candle.LowPrice, 64)
-				close, _ := strconv.ParseFloat(candle.ClosePrice, 64)
-
-				data.Open[i] = fmt.Sprintf("%.8f", open)
-				data.High[i] = fmt.Sprintf("%.8f", high)
-				data.Low[i] = fmt.Sprintf("%.8f", low)
-				data.Close[i] = fmt.Sprintf("%.8f", close)
-
-				vol, _ := strconv.ParseFloat(candle.Volume, 64)
-				data.Volume[i] = vol
+			priceFormat := newChartPriceFormat(connectPricePrecisions(cmd.Context()).priceDecimals(symbol))
+			script := bundledChartLibrary()
+			if library != "" {
+				content, err := os.ReadFile(library)
+				if err != nil {
+					return fmt.Errorf("failed to read charting library: %w", err)
+				}
+				script = template.JS(content)
+			}
+			if script == "" {
+				log.Printf("Warning: no charting library bundled, the chart loads lightweight-charts %s from unpkg (run go generate ./pkg/cli)", chartLibraryVersion)
+			}
+			if output != "" {
+				page := chartPage{Symbol: symbol, Period: period, LogScale: logScale, PriceFormat: priceFormat, Inline: &data, Library: script}
+				if strings.EqualFold(filepath.Ext(output), ".png") {
+					err = writeChartImage(cmd.Context(), output, renderer, page, width, height)
+				} else {
+					err = writeChartFile(output, page)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to write chart: %w", err)
+				}
+				fmt.Printf("Saved chart for %s to %s\n", symbol, output)
+				return nil
 			}
 
 			// Setup router
@@ -117,22 +206,7 @@ Example: binance-cli chart BTCUSDT --period 24h`,
 
 			// Serve static files
 			r.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
-				tmpl, err := template.ParseFS(templateFS, "templates/chart.html")
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-				data := struct {
-					Symbol string
-					Period string
-					Data   []*models.Candle
-				}{
-					Symbol: symbol,
-					Period: period,
-					Data:   dbCandles,
-				}
-
-				if err := tmpl.Execute(w, data); err != nil {
+				if err := renderChart(w, chartPage{Symbol: symbol, Period: period, LogScale: logScale, PriceFormat: priceFormat, Library: script}); err != nil {
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 					return
 				}
@@ -143,9 +217,10 @@ Example: binance-cli chart BTCUSDT --period 24h`,
 				w.Header().Set("Content-Type", "application/json")
 
 				// Log the data being sent for debugging
-				if len(data.Time) > 0 {
-					log.Printf("Sending %d candles. First candle: Time=%s, Open=%s, High=%s, Low=%s, Close=%s, Volume=%.2f",
-						len(data.Time), data.Time[0], data.Open[0], data.High[0], data.Low[0], data.Close[0], data.Volume[0])
+				if len(data.Series.Candles) > 0 {
+					first := data.Series.Candles[0]
+					log.Printf("Sending %d candles. First candle: Time=%s, Open=%s, High=%s, Low=%s, Close=%s, Volume=%s",
+						len(data.Series.Candles), first.Timestamp.Format(time.RFC3339), first.OpenPrice, first.HighPrice, first.LowPrice, first.ClosePrice, first.Volume)
 				} else {
 					log.Printf("Warning: No candle data available")
 				}
@@ -156,6 +231,9 @@ Example: binance-cli chart BTCUSDT --period 24h`,
 				}
 			})
 
+			// Streaming CSV download, optionally over a longer ?period=
+			r.Handle("/api/download.csv", newCandleCSVHandler(postgresStore, symbol, period))
+
 			// Start server
 			srv := &http.Server{
 				Addr:              fmt.Sprintf(":%d", port),
@@ -189,6 +267,147 @@ Example: binance-cli chart BTCUSDT --period 24h`,
 	}
 
 	cmd.Flags().IntVarP(&port, "port", "p", 8080, "Port to serve the web interface")
-	cmd.Flags().StringVarP(&period, "period", "t", "24h", "Time period (e.g., 1h, 24h, 7d)")
+	cmd.Flags().StringVarP(&period, "period", "t", "24h", "Time period (e.g., 1h, 24h, 7d, 2w, 3mo)")
+	cmd.Flags().StringVarP(&interval, "interval", "i", "1m", "Candle interval (e.g., 1m, 15m, 4h, 1d)")
+	cmd.Flags().StringVar(&boundary, "day-boundary", "UTC", "Where days begin: UTC or an offset such as UTC+8 or UTC-5")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Save the chart to this HTML file and exit instead of serving it")
+	cmd.Flags().StringVar(&style, "style", chartStyleCandles, "Chart style: candles, heikin-ashi or line")
+	cmd.Flags().BoolVar(&logScale, "log-scale", false, "Plot prices on a logarithmic scale")
+	cmd.Flags().StringVar(&overlaySpec, "overlays", "", "Comma-separated overlays on the prices: maN (e.g. ma20), bollinger")
+	cmd.Flags().StringVar(&indicatorSpec, "indicators", "", "Comma-separated indicators in their own pane: rsi:N, atr:N, zscore:N")
+	cmd.Flags().IntVar(&profileBins, "profile", 0, "Add the volume profile in this many price bins (0 for none)")
+	cmd.Flags().StringVar(&renderer, "renderer", rendererBuiltin, "Renderer of .png output: builtin or browser")
+	cmd.Flags().IntVar(&width, "width", defaultChartWidth, "Width of .png output in pixels")
+	cmd.Flags().IntVar(&height, "height", defaultChartHeight, "Height of .png output in pixels")
+	cmd.Flags().StringVar(&library, "library", "", "Local copy of lightweight-charts to inline instead of the bundled one")
 	return cmd
 }
+
+// chartPage is the data templates/chart.html is rendered with
+type chartPage struct {
+	Symbol      string
+	Period      string
+	LogScale    bool              // Plot prices on a logarithmic scale
+	PriceFormat *chartPriceFormat // Price axis format of the symbol; nil leaves the library default
+	Inline      *chartData        // Candles saved into the page; nil fetches /api/data
+	Library     template.JS       // Charting library inlined into the page; empty loads it from unpkg
+	Version     string            // lightweight-charts release loaded from unpkg without Library
+}
+
+// chartPriceFormat is the priceFormat of lightweight-charts price series
+type chartPriceFormat struct {
+	Type      string  `json:"type"`
+	Precision int     `json:"precision"`
+	MinMove   float64 `json:"minMove"`
+}
+
+// newChartPriceFormat returns the price format of a symbol quoted with
+// decimals, or nil when decimals is negative (unknown)
+func newChartPriceFormat(decimals int) *chartPriceFormat {
+	if decimals < 0 {
+		return nil
+	}
+	return &chartPriceFormat{Type: "price", Precision: decimals, MinMove: math.Pow10(-decimals)}
+}
+
+// priceDecimals returns the decimals of the page's price format, or -1 when
+// it has none
+func (p chartPage) priceDecimals() int {
+	if p.PriceFormat == nil {
+		return -1
+	}
+	return p.PriceFormat.Precision
+}
+
+// renderChart renders the chart page to w
+func renderChart(w io.Writer, page chartPage) error {
+	page.Version = chartLibraryVersion
+	tmpl, err := template.ParseFS(templateFS, "templates/chart.html")
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, page)
+}
+
+// writeChartFile renders a static chart page to path
+func writeChartFile(path string, page chartPage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := renderChart(f, page); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// candleStreamer streams stored candles row by row
+type candleStreamer interface {
+	StreamHistoricalCandles(ctx context.Context, symbol string, start, end time.Time, fn func(*models.Candle) error) error
+}
+
+// csvFlushRows is how many CSV rows are written between flushes to the client
+const csvFlushRows = 500
+
+// newCandleCSVHandler serves a symbol's candles as a CSV download, streamed
+// from the store as rows are read so large periods are never buffered. The
+// period query parameter overrides defaultPeriod.
+func newCandleCSVHandler(store candleStreamer, symbol, defaultPeriod string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		period := r.URL.Query().Get("period")
+		if period == "" {
+			period = defaultPeriod
+		}
+		duration, err := timeutil.ParseDuration(period, maxChartPeriod)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid period: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		end := time.Now()
+		start := end.Add(-duration)
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s_%s.csv", symbol, period)))
+
+		flusher, _ := w.(http.Flusher)
+		cw := csv.NewWriter(w)
+		rows := 0
+
+		// Headers go out with the first flush; without a Content-Length the
+		// response is sent with chunked transfer encoding
+		cw.Write([]string{"timestamp", "open", "high", "low", "close", "volume", "trades"})
+
+		err = store.StreamHistoricalCandles(r.Context(), symbol, start, end, func(candle *models.Candle) error {
+			cw.Write([]string{
+				candle.Timestamp.Format("2006-01-02 15:04:05"),
+				candle.OpenPrice,
+				candle.HighPrice,
+				candle.LowPrice,
+				candle.ClosePrice,
+				candle.Volume,
+				strconv.FormatInt(candle.TradeCount, 10),
+			})
+
+			rows++
+			if rows%csvFlushRows == 0 {
+				cw.Flush()
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return cw.Error()
+		})
+		if err != nil {
+			log.Printf("Error streaming candles for %s: %v", symbol, err)
+			// Only the buffered header exists before the first row; after
+			// that the status may already be sent and the download is cut short
+			if rows == 0 {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		cw.Flush()
+	})
+}
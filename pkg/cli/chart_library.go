@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"html/template"
+	"io/fs"
+)
+
+// The chart template is written against lightweight-charts 4: version 5
+// replaced addCandlestickSeries and friends with addSeries. Refresh the
+// bundled copy with go generate after changing chartLibraryVersion.
+//go:generate curl -fsSL -o templates/vendor/lightweight-charts.standalone.production.js https://unpkg.com/lightweight-charts@4.2.0/dist/lightweight-charts.standalone.production.js
+
+// chartLibraryVersion is the lightweight-charts release bundled with the CLI,
+// and the one loaded from unpkg when no copy is bundled
+const chartLibraryVersion = "4.2.0"
+
+// chartLibraryPath is where go generate puts the bundled library in
+// templateFS
+const chartLibraryPath = "templates/vendor/lightweight-charts.standalone.production.js"
+
+// bundledChartLibrary returns the charting library embedded in the binary,
+// or "" when it was built without one
+func bundledChartLibrary() template.JS {
+	script, err := fs.ReadFile(templateFS, chartLibraryPath)
+	if err != nil {
+		return ""
+	}
+	return template.JS(script)
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/analysis"
+)
+
+// Bollinger Bands drawn by the bollinger overlay
+const (
+	overlayBollingerPeriod = 20
+	overlayBollingerStddev = 2
+)
+
+// chartData is what the chart draws: its candles in the selected style, the
+// volume of each candle for the volume pane, the overlay lines drawn on the
+// price chart and, when asked for, indicator lines for their own pane and the
+// volume profile of the candles
+type chartData struct {
+	Series     models.CandleSeries
+	Volume     []float64 // Aligned with Series.Candles
+	Overlays   []chartOverlay
+	Indicators []chartOverlay
+	Profile    *profileDataset
+}
+
+// chartOverlay is a line drawn over the prices
+type chartOverlay struct {
+	Name   string         `json:"name"`
+	Points []overlayPoint `json:"points"`
+}
+
+// overlayPoint is a value of an overlay at a candle's time
+type overlayPoint struct {
+	TimeMs int64   `json:"time_ms"`
+	Value  float64 `json:"value"`
+}
+
+// MarshalJSON encodes the data as its candle series with volume and
+// overlays fields added, and indicators and profile fields when there are
+// any
+func (d chartData) MarshalJSON() ([]byte, error) {
+	encoded, err := json.Marshal(d.Series)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+
+	volume := d.Volume
+	if volume == nil {
+		volume = []float64{}
+	}
+	overlays := d.Overlays
+	if overlays == nil {
+		overlays = []chartOverlay{}
+	}
+	if fields["volume"], err = json.Marshal(volume); err != nil {
+		return nil, err
+	}
+	if fields["overlays"], err = json.Marshal(overlays); err != nil {
+		return nil, err
+	}
+	if len(d.Indicators) > 0 {
+		if fields["indicators"], err = json.Marshal(d.Indicators); err != nil {
+			return nil, err
+		}
+	}
+	if d.Profile != nil {
+		if fields["profile"], err = json.Marshal(d.Profile); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// parseOverlays parses a comma-separated --overlays list of moving averages,
+// maN for the N-candle simple moving average, and bollinger
+func parseOverlays(spec string) ([]string, error) {
+	var overlays []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "":
+			continue
+		case name == "bollinger":
+		case strings.HasPrefix(name, "ma"):
+			period, err := strconv.Atoi(strings.TrimPrefix(name, "ma"))
+			if err != nil || period <= 0 {
+				return nil, fmt.Errorf("invalid overlay %q: moving averages are maN with a positive N, e.g. ma20", name)
+			}
+		default:
+			return nil, fmt.Errorf("invalid overlay %q: must be maN (e.g. ma20) or bollinger", name)
+		}
+		overlays = append(overlays, name)
+	}
+	return overlays, nil
+}
+
+// computeOverlays computes the overlays, as parsed by parseOverlays, from the
+// closes of candles. Values in an indicator's warm-up are left out.
+func computeOverlays(candles []*models.Candle, overlays []string) ([]chartOverlay, error) {
+	closes := make([]float64, len(candles))
+	for i, candle := range candles {
+		closes[i], _ = strconv.ParseFloat(candle.ClosePrice, 64)
+	}
+
+	var out []chartOverlay
+	for _, name := range overlays {
+		if name == "bollinger" {
+			bands, err := analysis.Bollinger(closes, overlayBollingerPeriod, overlayBollingerStddev)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out,
+				overlayLine("BB upper", candles, bands.Upper),
+				overlayLine("BB middle", candles, bands.Middle),
+				overlayLine("BB lower", candles, bands.Lower),
+			)
+			continue
+		}
+
+		period, _ := strconv.Atoi(strings.TrimPrefix(name, "ma"))
+		values, err := analysis.SMA(closes, period)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, overlayLine(strings.ToUpper(name), candles, values))
+	}
+	return out, nil
+}
+
+// computeIndicatorLines computes indicators from candles, for the indicator
+// pane below the volume
+func computeIndicatorLines(candles []*models.Candle, indicators []analysis.Indicator) ([]chartOverlay, error) {
+	var out []chartOverlay
+	for _, ind := range indicators {
+		values, err := ind.Compute(candles)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, overlayLine(ind.String(), candles, values))
+	}
+	return out, nil
+}
+
+// overlayLine pairs values with the times of the candles they were computed
+// from, skipping warm-up NaNs
+func overlayLine(name string, candles []*models.Candle, values []float64) chartOverlay {
+	line := chartOverlay{Name: name, Points: []overlayPoint{}}
+	for i, value := range values {
+		if math.IsNaN(value) {
+			continue
+		}
+		line.Points = append(line.Points, overlayPoint{TimeMs: candles[i].Timestamp.UnixMilli(), Value: value})
+	}
+	return line
+}
+
+// newChartData builds what the chart draws for the stored candles. Volume
+// and overlays are computed from the stored candles whatever the style.
+func newChartData(symbol string, candles []*models.Candle, style string, overlays []string) (chartData, error) {
+	series, err := chartSeries(symbol, candles, style)
+	if err != nil {
+		return chartData{}, err
+	}
+	lines, err := computeOverlays(candles, overlays)
+	if err != nil {
+		return chartData{}, err
+	}
+
+	volume := make([]float64, len(candles))
+	for i, candle := range candles {
+		volume[i], _ = strconv.ParseFloat(candle.Volume, 64)
+	}
+	return chartData{Series: series, Volume: volume, Overlays: lines}, nil
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/analysis"
+)
+
+func TestParseOverlays(t *testing.T) {
+	got, err := parseOverlays(" MA20,ma50 ,bollinger,")
+	if err != nil {
+		t.Fatalf("parseOverlays() error = %v", err)
+	}
+	want := []string{"ma20", "ma50", "bollinger"}
+	if len(got) != len(want) {
+		t.Fatalf("parseOverlays() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseOverlays() = %v, want %v", got, want)
+		}
+	}
+
+	if overlays, err := parseOverlays(""); err != nil || len(overlays) != 0 {
+		t.Errorf("parseOverlays(\"\") = %v, %v, want none", overlays, err)
+	}
+	for _, spec := range []string{"ma", "ma0", "ma-5", "ema20", "vwap"} {
+		if _, err := parseOverlays(spec); err == nil {
+			t.Errorf("parseOverlays(%q) expected an error", spec)
+		}
+	}
+}
+
+func TestNewChartData(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var candles []*models.Candle
+	for i := 0; i < 25; i++ {
+		price := strconv.Itoa(100 + i)
+		candles = append(candles, &models.Candle{
+			Timestamp:  start.Add(time.Duration(i) * time.Minute),
+			OpenPrice:  price,
+			HighPrice:  price,
+			LowPrice:   price,
+			ClosePrice: price,
+			Volume:     strconv.Itoa(i + 1),
+			TradeCount: 1,
+		})
+	}
+
+	data, err := newChartData("BTCUSDT", candles, chartStyleHeikinAshi, []string{"ma5", "bollinger"})
+	if err != nil {
+		t.Fatalf("newChartData() error = %v", err)
+	}
+
+	if len(data.Volume) != len(candles) || data.Volume[0] != 1 || data.Volume[24] != 25 {
+		t.Errorf("Expected one volume per candle, got %v", data.Volume)
+	}
+
+	names := []string{"MA5", "BB upper", "BB middle", "BB lower"}
+	if len(data.Overlays) != len(names) {
+		t.Fatalf("Expected %d overlays, got %d", len(names), len(data.Overlays))
+	}
+	for i, name := range names {
+		if data.Overlays[i].Name != name {
+			t.Errorf("Overlay %d = %s, want %s", i, data.Overlays[i].Name, name)
+		}
+	}
+
+	// Warm-up values are left out; the first MA5 is the mean of 100..104,
+	// from the stored closes rather than the Heikin-Ashi ones
+	ma := data.Overlays[0].Points
+	if len(ma) != 21 || ma[0].TimeMs != candles[4].Timestamp.UnixMilli() || ma[0].Value != 102 {
+		t.Errorf("Unexpected MA5 points: %d, first %+v", len(ma), ma[0])
+	}
+	if middle := data.Overlays[2].Points; len(middle) != 6 || middle[0].Value != 109.5 {
+		t.Errorf("Unexpected Bollinger middle points: %d, first %+v", len(middle), middle[0])
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Symbol   string            `json:"symbol"`
+		Style    string            `json:"style"`
+		Candles  []json.RawMessage `json:"candles"`
+		Volume   []float64         `json:"volume"`
+		Overlays []chartOverlay    `json:"overlays"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Failed to decode %s: %v", encoded, err)
+	}
+	if decoded.Symbol != "BTCUSDT" || decoded.Style != chartStyleHeikinAshi || len(decoded.Candles) != 25 ||
+		len(decoded.Volume) != 25 || len(decoded.Overlays) != 4 {
+		t.Errorf("Unexpected chart data JSON: %s", encoded)
+	}
+
+	// Without candles or overlays the lists are empty rather than null
+	encoded, err = json.Marshal(chartData{Series: models.CandleSeries{Symbol: "BTCUSDT"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"candles":[],"overlays":[],"symbol":"BTCUSDT","volume":[]}`; string(encoded) != want {
+		t.Errorf("Expected %s, got %s", want, encoded)
+	}
+}
+
+func TestComputeIndicatorLines(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var candles []*models.Candle
+	for i := 0; i < 10; i++ {
+		price := strconv.Itoa(100 + i)
+		candles = append(candles, &models.Candle{
+			Timestamp:  start.Add(time.Duration(i) * time.Minute),
+			HighPrice:  price,
+			LowPrice:   price,
+			ClosePrice: price,
+		})
+	}
+	indicators, err := analysis.ParseIndicators("rsi:3,zscore:5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := computeIndicatorLines(candles, indicators)
+	if err != nil {
+		t.Fatalf("computeIndicatorLines() error = %v", err)
+	}
+	if len(lines) != 2 || lines[0].Name != "RSI(3)" || lines[1].Name != "ZSCORE(5)" {
+		t.Fatalf("Unexpected indicator lines: %+v", lines)
+	}
+	// RSI(3) starts at the fourth candle, a 5-candle z-score at the fifth
+	if rsi := lines[0].Points; len(rsi) != 7 || rsi[0].TimeMs != candles[3].Timestamp.UnixMilli() || rsi[0].Value != 100 {
+		t.Errorf("Unexpected RSI points: %+v", rsi)
+	}
+	if z := lines[1].Points; len(z) != 6 || z[0].TimeMs != candles[4].Timestamp.UnixMilli() {
+		t.Errorf("Unexpected z-score points: %+v", z)
+	}
+
+	encoded, err := json.Marshal(chartData{Series: models.CandleSeries{Symbol: "BTCUSDT"}, Indicators: lines})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Indicators []chartOverlay `json:"indicators"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil || len(decoded.Indicators) != 2 {
+		t.Errorf("Expected indicators in %s (%v)", encoded, err)
+	}
+}
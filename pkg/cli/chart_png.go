@@ -0,0 +1,353 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Chart image renderers selected with --renderer
+const (
+	rendererBuiltin = "builtin" // Draws the candle and volume panes in Go
+	rendererBrowser = "browser" // Screenshots the HTML chart with headless Chrome
+)
+
+// Default size of chart images
+const (
+	defaultChartWidth  = 1280
+	defaultChartHeight = 720
+)
+
+// browserRenderTimeout bounds a headless browser screenshot
+const browserRenderTimeout = time.Minute
+
+// Colors of the built-in renderer, matching templates/chart.html
+var (
+	chartBackground = color.RGBA{0x1e, 0x22, 0x2d, 0xff}
+	chartGrid       = color.RGBA{0x2a, 0x2e, 0x39, 0xff}
+	chartText       = color.RGBA{0xd1, 0xd4, 0xdc, 0xff}
+	chartUp         = color.RGBA{0x26, 0xa6, 0x9a, 0xff}
+	chartDown       = color.RGBA{0xef, 0x53, 0x50, 0xff}
+	chartLine       = color.RGBA{0x29, 0x62, 0xff, 0xff}
+	overlayPalette  = []color.RGBA{
+		{0xf7, 0xc9, 0x48, 0xff},
+		{0xab, 0x47, 0xbc, 0xff},
+		{0x29, 0xb6, 0xf6, 0xff},
+		{0xff, 0x70, 0x43, 0xff},
+		{0x66, 0xbb, 0x6a, 0xff},
+	}
+)
+
+// writeChartImage renders data to a PNG file at path with renderer
+func writeChartImage(ctx context.Context, path, renderer string, page chartPage, width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("image size must be positive, got %dx%d", width, height)
+	}
+
+	switch renderer {
+	case rendererBuiltin:
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := renderChartPNG(f, *page.Inline, width, height, page.LogScale, page.priceDecimals()); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	case rendererBrowser:
+		return screenshotChart(ctx, path, page, width, height)
+	default:
+		return fmt.Errorf("invalid renderer %q: must be %s or %s", renderer, rendererBuiltin, rendererBrowser)
+	}
+}
+
+// findBrowser returns the headless Chrome or Chromium to screenshot charts
+// with: CHROME_PATH, or the first one found on PATH
+func findBrowser() (string, error) {
+	if path := os.Getenv("CHROME_PATH"); path != "" {
+		return path, nil
+	}
+	for _, name := range []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no Chrome or Chromium found; set CHROME_PATH or use --renderer %s", rendererBuiltin)
+}
+
+// screenshotChart renders the HTML chart and screenshots it with a headless
+// browser, which draws exactly what a served chart shows but needs the
+// charting library to load
+func screenshotChart(ctx context.Context, path string, page chartPage, width, height int) error {
+	browser, err := findBrowser()
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "chart")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	htmlPath := filepath.Join(dir, "chart.html")
+	if err := writeChartFile(htmlPath, page); err != nil {
+		return err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, browserRenderTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, browser,
+		"--headless", "--disable-gpu", "--hide-scrollbars",
+		fmt.Sprintf("--window-size=%d,%d", width, height),
+		"--virtual-time-budget=10000",
+		"--screenshot="+absPath,
+		"file://"+htmlPath,
+	).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("browser screenshot failed: %w: %s", err, out)
+	}
+	return nil
+}
+
+// renderChartPNG draws the price pane, with overlays, above the volume pane
+// as a width x height PNG. Prices are labelled on the right at each grid
+// line, with priceDecimals, or when it is negative enough decimals to tell
+// the lines apart.
+func renderChartPNG(w io.Writer, data chartData, width, height int, logScale bool, priceDecimals int) error {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{chartBackground}, image.Point{}, draw.Src)
+
+	candles := data.Series.Candles
+	if len(candles) > 0 {
+		const (
+			margin     = 10
+			axisWidth  = 90 // Right of the panes, for price labels
+			paneGap    = 8
+			gridLines  = 5
+			labelScale = 2
+		)
+		plot := image.Rect(margin, margin, width-axisWidth, height-margin)
+		volumeHeight := plot.Dy() / 4
+		pricePane := image.Rect(plot.Min.X, plot.Min.Y, plot.Max.X, plot.Max.Y-volumeHeight-paneGap)
+		volumePane := image.Rect(plot.Min.X, plot.Max.Y-volumeHeight, plot.Max.X, plot.Max.Y)
+		if pricePane.Dx() <= 0 || pricePane.Dy() <= 0 || volumePane.Dy() <= 0 {
+			return fmt.Errorf("image size %dx%d is too small for a chart", width, height)
+		}
+
+		type ohlc struct{ open, high, low, close float64 }
+		bars := make([]ohlc, len(candles))
+		low, high := math.Inf(1), math.Inf(-1)
+		for i, candle := range candles {
+			bar := &bars[i]
+			bar.open, _ = strconv.ParseFloat(candle.OpenPrice, 64)
+			bar.high, _ = strconv.ParseFloat(candle.HighPrice, 64)
+			bar.low, _ = strconv.ParseFloat(candle.LowPrice, 64)
+			bar.close, _ = strconv.ParseFloat(candle.ClosePrice, 64)
+			low, high = math.Min(low, bar.low), math.Max(high, bar.high)
+		}
+		for _, overlay := range data.Overlays {
+			for _, point := range overlay.Points {
+				low, high = math.Min(low, point.Value), math.Max(high, point.Value)
+			}
+		}
+		if logScale && low <= 0 {
+			logScale = false
+		}
+
+		scale := func(price float64) float64 {
+			if logScale {
+				return math.Log(price)
+			}
+			return price
+		}
+		top, bottom := scale(high), scale(low)
+		if top == bottom {
+			top, bottom = top+1, bottom-1
+		}
+		priceY := func(price float64) int {
+			frac := (scale(price) - bottom) / (top - bottom)
+			return pricePane.Max.Y - 1 - int(math.Round(frac*float64(pricePane.Dy()-1)))
+		}
+
+		// The symbol's decimals, or enough to tell grid lines of low-priced
+		// symbols apart
+		decimals := priceDecimals
+		if decimals < 0 {
+			decimals = 2
+			if step := (high - low) / (gridLines - 1); step > 0 {
+				decimals = min(8, max(2, int(math.Ceil(-math.Log10(step)))+1))
+			}
+		}
+		for i := 0; i < gridLines; i++ {
+			frac := float64(i) / float64(gridLines-1)
+			y := pricePane.Min.Y + int(math.Round(frac*float64(pricePane.Dy()-1)))
+			fillRect(img, image.Rect(pricePane.Min.X, y, pricePane.Max.X, y+1), chartGrid)
+
+			price := top - frac*(top-bottom)
+			if logScale {
+				price = math.Exp(price)
+			}
+			drawText(img, pricePane.Max.X+6, y-glyphHeight*labelScale/2, formatFloat(price, decimals), labelScale, chartText)
+		}
+		fillRect(img, image.Rect(volumePane.Min.X, volumePane.Min.Y-paneGap/2, volumePane.Max.X, volumePane.Min.Y-paneGap/2+1), chartGrid)
+
+		slot := float64(pricePane.Dx()) / float64(len(candles))
+		body := int(math.Max(1, slot*0.7))
+		center := func(i int) int {
+			return pricePane.Min.X + int((float64(i)+0.5)*slot)
+		}
+		indexOf := make(map[int64]int, len(candles))
+		for i, candle := range candles {
+			indexOf[candle.Timestamp.UnixMilli()] = i
+		}
+
+		if data.Series.Style == chartStyleLine {
+			for i := 1; i < len(bars); i++ {
+				drawLine(img, center(i-1), priceY(bars[i-1].close), center(i), priceY(bars[i].close), chartLine)
+			}
+		} else {
+			for i, bar := range bars {
+				c := chartUp
+				if bar.close < bar.open {
+					c = chartDown
+				}
+				x := center(i)
+				fillRect(img, image.Rect(x, priceY(bar.high), x+1, priceY(bar.low)+1), c)
+				y0, y1 := priceY(math.Max(bar.open, bar.close)), priceY(math.Min(bar.open, bar.close))
+				fillRect(img, image.Rect(x-body/2, y0, x-body/2+body, y1+1), c)
+			}
+		}
+
+		for n, overlay := range data.Overlays {
+			c := overlayPalette[n%len(overlayPalette)]
+			prevX, prevY := -1, 0
+			for _, point := range overlay.Points {
+				i, ok := indexOf[point.TimeMs]
+				if !ok {
+					continue
+				}
+				x, y := center(i), priceY(point.Value)
+				if prevX >= 0 {
+					drawLine(img, prevX, prevY, x, y, c)
+				}
+				prevX, prevY = x, y
+			}
+		}
+
+		maxVolume := 0.0
+		for _, volume := range data.Volume {
+			maxVolume = math.Max(maxVolume, volume)
+		}
+		if maxVolume > 0 {
+			for i, volume := range data.Volume {
+				if i >= len(bars) {
+					break
+				}
+				c := chartDown
+				if bars[i].close > bars[i].open {
+					c = chartUp
+				}
+				barHeight := int(math.Round(volume / maxVolume * float64(volumePane.Dy())))
+				x := center(i)
+				fillRect(img, image.Rect(x-body/2, volumePane.Max.Y-barHeight, x-body/2+body, volumePane.Max.Y), c)
+			}
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// fillRect fills r, clipped to img, with c
+func fillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	draw.Draw(img, r.Intersect(img.Bounds()), &image.Uniform{c}, image.Point{}, draw.Src)
+}
+
+// drawLine draws a one pixel line from (x0, y0) to (x1, y1)
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := absInt(x1-x0), -absInt(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		img.SetRGBA(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Price labels are drawn with a 3x5 pixel font of the characters numbers
+// need, one row per string with '#' set
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+)
+
+var glyphs = map[rune][glyphHeight]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+	'-': {"...", "...", "###", "...", "..."},
+}
+
+// drawText draws s with its top left corner at (x, y), each font pixel
+// scale pixels wide; characters without a glyph leave a gap
+func drawText(img *image.RGBA, x, y int, s string, scale int, c color.RGBA) {
+	for _, r := range s {
+		if glyph, ok := glyphs[r]; ok {
+			for row, line := range glyph {
+				for col, bit := range line {
+					if bit == '#' {
+						px, py := x+col*scale, y+row*scale
+						fillRect(img, image.Rect(px, py, px+scale, py+scale), c)
+					}
+				}
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
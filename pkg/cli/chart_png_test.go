@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func testChartData(t *testing.T, style string) chartData {
+	t.Helper()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := []*models.Candle{
+		{Timestamp: start, OpenPrice: "100", HighPrice: "110", LowPrice: "95", ClosePrice: "108", Volume: "5", TradeCount: 3},
+		{Timestamp: start.Add(time.Minute), OpenPrice: "108", HighPrice: "112", LowPrice: "101", ClosePrice: "102", Volume: "8", TradeCount: 4},
+		{Timestamp: start.Add(2 * time.Minute), OpenPrice: "102", HighPrice: "106", LowPrice: "100", ClosePrice: "105", Volume: "2", TradeCount: 1},
+	}
+	data, err := newChartData("BTCUSDT", candles, style, []string{"ma2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// countPixels counts the pixels of img with the RGB color want
+func countPixels(img image.Image, want [3]uint32) int {
+	n := 0
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			if [3]uint32{r >> 8, g >> 8, b >> 8} == want {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestRenderChartPNG(t *testing.T) {
+	for _, style := range []string{chartStyleCandles, chartStyleLine} {
+		var buf bytes.Buffer
+		if err := renderChartPNG(&buf, testChartData(t, style), 640, 360, false, -1); err != nil {
+			t.Fatalf("renderChartPNG(%s) error = %v", style, err)
+		}
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatalf("Failed to decode PNG: %v", err)
+		}
+		if size := img.Bounds().Size(); size.X != 640 || size.Y != 360 {
+			t.Errorf("%s: expected a 640x360 image, got %v", style, size)
+		}
+
+		// Bullish and bearish volume bars are drawn in both styles, and
+		// price labels in the text color
+		up := countPixels(img, [3]uint32{0x26, 0xa6, 0x9a})
+		down := countPixels(img, [3]uint32{0xef, 0x53, 0x50})
+		text := countPixels(img, [3]uint32{0xd1, 0xd4, 0xdc})
+		if up == 0 || down == 0 || text == 0 {
+			t.Errorf("%s: expected up, down and label pixels, got %d, %d, %d", style, up, down, text)
+		}
+	}
+
+	// Log scale and an empty chart still render
+	var buf bytes.Buffer
+	if err := renderChartPNG(&buf, testChartData(t, chartStyleHeikinAshi), 320, 200, true, 4); err != nil {
+		t.Errorf("renderChartPNG(log scale) error = %v", err)
+	}
+	buf.Reset()
+	if err := renderChartPNG(&buf, chartData{}, 100, 100, false, -1); err != nil || buf.Len() == 0 {
+		t.Errorf("renderChartPNG(empty) = %v with %d bytes", err, buf.Len())
+	}
+	if err := renderChartPNG(&buf, testChartData(t, chartStyleCandles), 50, 20, false, -1); err == nil {
+		t.Error("Expected an error for an image too small to draw in")
+	}
+}
+
+func TestWriteChartImage(t *testing.T) {
+	data := testChartData(t, chartStyleCandles)
+	page := chartPage{Symbol: "BTCUSDT", Period: "3m", Inline: &data}
+	path := filepath.Join(t.TempDir(), "chart.png")
+
+	if err := writeChartImage(context.Background(), path, rendererBuiltin, page, 800, 450); err != nil {
+		t.Fatalf("writeChartImage() error = %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	config, err := png.DecodeConfig(f)
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %v", err)
+	}
+	if config.Width != 800 || config.Height != 450 {
+		t.Errorf("Expected an 800x450 image, got %dx%d", config.Width, config.Height)
+	}
+
+	if err := writeChartImage(context.Background(), path, "gnuplot", page, 800, 450); err == nil {
+		t.Error("Expected an error for an unknown renderer")
+	}
+	if err := writeChartImage(context.Background(), path, rendererBuiltin, page, 0, 450); err == nil {
+		t.Error("Expected an error for a zero width")
+	}
+}
+
+func TestWriteChartFile_InlineLibrary(t *testing.T) {
+	data := testChartData(t, chartStyleCandles)
+	page := chartPage{Symbol: "BTCUSDT", Period: "3m", Inline: &data, Library: "window.LightweightCharts = {};"}
+
+	path := filepath.Join(t.TempDir(), "chart.html")
+	if err := writeChartFile(path, page); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "<script>window.LightweightCharts = {};</script>") {
+		t.Error("Expected the library to be inlined")
+	}
+	if strings.Contains(string(content), "unpkg.com") {
+		t.Error("Expected a self-contained page not loading the library from unpkg")
+	}
+	if !strings.Contains(string(content), `"volume":[5,8,2]`) {
+		t.Error("Expected the volume to be inlined")
+	}
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// fakeCandleStreamer streams a fixed set of candles and records the range asked for
+type fakeCandleStreamer struct {
+	candles    []*models.Candle
+	err        error
+	start, end time.Time
+}
+
+func (f *fakeCandleStreamer) StreamHistoricalCandles(ctx context.Context, symbol string, start, end time.Time, fn func(*models.Candle) error) error {
+	f.start, f.end = start, end
+	for _, candle := range f.candles {
+		if err := fn(candle); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+func TestCandleCSVHandler(t *testing.T) {
+	base := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	store := &fakeCandleStreamer{candles: []*models.Candle{
+		{Timestamp: base, OpenPrice: "100", HighPrice: "105", LowPrice: "99", ClosePrice: "104", Volume: "12.5", TradeCount: 7},
+		{Timestamp: base.Add(time.Minute), OpenPrice: "104", HighPrice: "106", LowPrice: "103", ClosePrice: "103", Volume: "3", TradeCount: 2},
+	}}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/download.csv?period=30d", nil)
+	newCandleCSVHandler(store, "BTCUSDT", "24h").ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/csv" {
+		t.Errorf("Expected text/csv, got %s", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, "BTCUSDT_30d.csv") {
+		t.Errorf("Expected a BTCUSDT_30d.csv attachment, got %s", got)
+	}
+
+	want := "timestamp,open,high,low,close,volume,trades\n" +
+		"2024-12-26 10:00:00,100,105,99,104,12.5,7\n" +
+		"2024-12-26 10:01:00,104,106,103,103,3,2\n"
+	if rec.Body.String() != want {
+		t.Errorf("Unexpected CSV:\n%s\nwant:\n%s", rec.Body.String(), want)
+	}
+
+	// The period parameter overrides the chart's period
+	if span := store.end.Sub(store.start); span != 30*24*time.Hour {
+		t.Errorf("Expected a 30d range, got %s", span)
+	}
+}
+
+func TestCandleCSVHandler_Errors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/download.csv?period=bogus", nil)
+	newCandleCSVHandler(&fakeCandleStreamer{}, "BTCUSDT", "24h").ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid period, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/download.csv", nil)
+	newCandleCSVHandler(&fakeCandleStreamer{err: errors.New("connection lost")}, "BTCUSDT", "24h").ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 when the query fails before any row, got %d", rec.Code)
+	}
+}
+
+func TestWriteChartFile(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	series := models.CandleSeries{
+		Symbol: "BTCUSDT",
+		Candles: []*models.Candle{
+			{Timestamp: start, OpenPrice: "42000.5", HighPrice: "42100", LowPrice: "41950.25", ClosePrice: "42050", Volume: "12.5", TradeCount: 42},
+			{Timestamp: start.Add(time.Minute), OpenPrice: "42050", HighPrice: "42075", LowPrice: "42001", ClosePrice: "42060.75", Volume: "3", TradeCount: 7},
+		},
+		Numeric: true,
+	}
+
+	path := filepath.Join(t.TempDir(), "chart.html")
+	if err := writeChartFile(path, chartPage{Symbol: "BTCUSDT", Period: "2m", Inline: &chartData{Series: series}}); err != nil {
+		t.Fatalf("writeChartFile failed: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(content)
+
+	// Every candle is in the page, with numeric prices for the chart
+	for _, want := range []string{
+		`"symbol":"BTCUSDT"`,
+		`"time_ms":1704067200000,"open":42000.5,"high":42100,"low":41950.25,"close":42050,"volume":12.5,"trade_count":42`,
+		`"time_ms":1704067260000,"open":42050,"high":42075,"low":42001,"close":42060.75,"volume":3,"trade_count":7`,
+		"Period: 2m",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected the chart to contain %s", want)
+		}
+	}
+
+	// A served chart fetches its candles instead
+	var served strings.Builder
+	if err := renderChart(&served, chartPage{Symbol: "BTCUSDT", Period: "24h"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(served.String(), `"candles":`) || !strings.Contains(served.String(), "fetch('/api/data')") {
+		t.Error("Expected a served chart without inlined candles")
+	}
+	// Without a library to inline it loads the release the template is
+	// written against, never whatever unpkg serves as the latest
+	if !strings.Contains(served.String(), "unpkg.com/lightweight-charts@"+chartLibraryVersion+"/") {
+		t.Error("Expected the charting library to be pinned to chartLibraryVersion")
+	}
+}
+
+func TestChartSeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := []*models.Candle{
+		{Timestamp: start, OpenPrice: "10", HighPrice: "12", LowPrice: "9", ClosePrice: "11", Volume: "1", TradeCount: 1},
+		{Timestamp: start.Add(time.Minute), OpenPrice: "11", HighPrice: "13", LowPrice: "10", ClosePrice: "12", Volume: "2", TradeCount: 2},
+	}
+
+	for _, style := range []string{chartStyleCandles, chartStyleLine} {
+		series, err := chartSeries("BTCUSDT", candles, style)
+		if err != nil {
+			t.Fatalf("chartSeries(%s) error = %v", style, err)
+		}
+		if series.Style != style || series.Candles[1].OpenPrice != "11" {
+			t.Errorf("chartSeries(%s) = style %s, open %s, want the candles as stored", style, series.Style, series.Candles[1].OpenPrice)
+		}
+	}
+
+	series, err := chartSeries("BTCUSDT", candles, chartStyleHeikinAshi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := series.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second Heikin-Ashi candle opens at the first one's midpoint, 10.5
+	for _, want := range []string{`"style":"heikin-ashi"`, `"time_ms":1704067260000,"open":10.5,"high":13,"low":10,"close":11.5`} {
+		if !strings.Contains(string(encoded), want) {
+			t.Errorf("Expected the series to contain %s, got %s", want, encoded)
+		}
+	}
+
+	if _, err := chartSeries("BTCUSDT", candles, "renko"); err == nil {
+		t.Error("Expected an error for an unknown style")
+	}
+}
+
+func TestRenderChart_LogScale(t *testing.T) {
+	var linear, logarithmic strings.Builder
+	if err := renderChart(&linear, chartPage{Symbol: "BTCUSDT", Period: "24h"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := renderChart(&logarithmic, chartPage{Symbol: "BTCUSDT", Period: "24h", LogScale: true}); err != nil {
+		t.Fatal(err)
+	}
+	compact := func(page *strings.Builder) string { return strings.Join(strings.Fields(page.String()), "") }
+	if !strings.Contains(compact(&linear), "mode:false?") || !strings.Contains(compact(&logarithmic), "mode:true?") {
+		t.Errorf("Expected the price scale mode to follow LogScale")
+	}
+}
@@ -0,0 +1,443 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"binance-redis-streamer/pkg/binance"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/storage"
+)
+
+// checkTimeout bounds each connectivity check of config validate
+const checkTimeout = 5 * time.Second
+
+// redacted replaces secrets in printed configuration
+const redacted = "****"
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show, validate and create configuration",
+		Long: `Inspect the configuration commands run with after merging the config file,
+environment variables and flags, check that its services are reachable, or
+write a sample config file.
+Example: binance-cli config show --config streamer.yaml`,
+	}
+
+	cmd.AddCommand(newConfigShowCmd(), newConfigValidateCmd(), newConfigInitCmd())
+	return cmd
+}
+
+func newConfigShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration as YAML with secrets redacted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := renderConfig(configFromContext(cmd.Context()))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("# PostgreSQL (DATABASE_URL): %s\n", redactURL(storage.DatabaseURL()))
+			fmt.Print(string(out))
+			return nil
+		},
+	}
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration and check Redis, PostgreSQL and Binance connectivity",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Failed checks are not usage errors
+			cmd.SilenceUsage = true
+			checks := configChecks(configFromContext(cmd.Context()), storage.DatabaseURL())
+			return runConfigChecks(cmd.Context(), os.Stdout, checks)
+		},
+	}
+}
+
+func newConfigInitCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "init [path]",
+		Short: "Write a commented sample config file",
+		Long: `Write a commented sample config file with the default settings.
+Example: binance-cli config init streamer.yaml`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "streamer.yaml"
+			if len(args) > 0 {
+				path = args[0]
+			}
+			if err := writeSampleConfig(path, force); err != nil {
+				return err
+			}
+			fmt.Printf("Wrote sample configuration to %s\n", path)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing file")
+	return cmd
+}
+
+// renderConfig returns cfg as YAML keyed like the config file, in field
+// order, with durations readable and secrets redacted
+func renderConfig(cfg *config.Config) ([]byte, error) {
+	out, err := yaml.Marshal(configNode(reflect.ValueOf(*cfg), ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render configuration: %w", err)
+	}
+	return out, nil
+}
+
+// configNode converts a config value to a YAML node; name is the Go field
+// name, used to spot secrets
+func configNode(v reflect.Value, name string) *yaml.Node {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: d.String()}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			key := field.Tag.Get("mapstructure")
+			if key == "" || !field.IsExported() {
+				continue
+			}
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+				configNode(v.Field(i), field.Name),
+			)
+		}
+		return node
+	case reflect.Slice:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for i := 0; i < v.Len(); i++ {
+			node.Content = append(node.Content, configNode(v.Index(i), name))
+		}
+		return node
+	case reflect.String:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: redactValue(name, v.String())}
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(v.Interface()); err != nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(v.Interface())}
+		}
+		return node
+	}
+}
+
+// redactValue hides passwords and the credentials inside URLs
+func redactValue(name, value string) string {
+	switch {
+	case value == "":
+		return value
+	case strings.Contains(name, "Password"), strings.Contains(name, "Secret"), strings.Contains(name, "Token"),
+		strings.HasSuffix(name, "APIKey"):
+		return redacted
+	case strings.HasSuffix(name, "URL"):
+		return redactURL(value)
+	default:
+		return value
+	}
+}
+
+// redactURL replaces the password of a URL, keeping the rest readable
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	if u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); !ok {
+		return raw
+	}
+	// Set a placeholder that survives escaping, then swap in the mask
+	u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	return strings.Replace(u.String(), ":REDACTED@", ":"+redacted+"@", 1)
+}
+
+// configCheck is one named check run by config validate
+type configCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// configChecks returns the validation and connectivity checks for cfg, with
+// PostgreSQL reached at databaseURL
+func configChecks(cfg *config.Config, databaseURL string) []configCheck {
+	return []configCheck{
+		{"config", func(ctx context.Context) error {
+			return cfg.Validate()
+		}},
+		{"redis", func(ctx context.Context) error {
+			store, err := storage.NewRedisStore(cfg)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			return store.GetRedisClient().Ping(ctx).Err()
+		}},
+		{"postgres", func(ctx context.Context) error {
+			return pingPostgres(ctx, databaseURL)
+		}},
+		{"binance", func(ctx context.Context) error {
+			return checkExchangeInfo(ctx, binance.NewHTTPClient(cfg.Binance), binance.RESTURL(cfg.Binance))
+		}},
+	}
+}
+
+// runConfigChecks runs checks in order, printing a PASS or FAIL line for
+// each, and returns an error when any of them failed
+func runConfigChecks(ctx context.Context, w io.Writer, checks []configCheck) error {
+	failed := 0
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		start := time.Now()
+		err := check.run(checkCtx)
+		cancel()
+
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "FAIL  %-10s %v\n", check.name, err)
+			continue
+		}
+		fmt.Fprintf(w, "PASS  %-10s (%s)\n", check.name, time.Since(start).Round(time.Millisecond))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// pingPostgres connects to PostgreSQL without running migrations
+func pingPostgres(ctx context.Context, databaseURL string) error {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping %s: %w", redactURL(databaseURL), err)
+	}
+	return nil
+}
+
+// checkExchangeInfo checks that the Binance REST API at baseURL answers
+// through client, which goes through the configured proxy like the streamer
+func checkExchangeInfo(ctx context.Context, client *http.Client, baseURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/v3/exchangeInfo", http.NoBody)
+	if err != nil {
+		return fmt.Errorf("invalid Binance URL: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", baseURL, resp.Status)
+	}
+	return nil
+}
+
+// writeSampleConfig writes sampleConfig to path, refusing to replace an
+// existing file unless force is set
+func writeSampleConfig(path string, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+
+	f, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create config file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(sampleConfig); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// sampleConfig is the commented config file written by config init. Keys
+// match the mapstructure tags of config.Config; values are the defaults.
+const sampleConfig = `# Streamer configuration. Environment variables override these values, and
+# CLI flags override both. Durations use Go syntax (30s, 5m, 2h).
+
+# Venue to ingest from; namespaces Redis keys and PostgreSQL rows
+exchange: binance
+
+redis:
+  # Credentials and database number are taken from the URL
+  url: redis://localhost:6379
+  # How long trade history is kept in Redis
+  retention_period: 24h
+  cleanup_interval: 5m
+  max_trades_per_key: 500
+  use_compression: true
+  # Latest trades older than this count as missing (0 disables)
+  max_latest_trade_age: 5m
+  # Sentinel mode: set the addresses of the sentinels instead of a plain server
+  sentinel_addrs: []
+  sentinel_master_name: mymaster
+  # Treat url as a seed node of a Redis Cluster
+  cluster: false
+  # Publish trades on trades.<SYMBOL> channels instead of the shared trades channel
+  per_symbol_channels: false
+  # Publish up to this many trades per channel as one JSON array message
+  # (1 disables batching), waiting at most publish_batch_delay for a batch
+  publish_batch_size: 1
+  publish_batch_delay: 5ms
+  # Trades per second stored with history; above it only the latest trade is
+  # kept (0 disables), with bursts of write_burst (0 for one second's worth)
+  max_writes_per_sec: 0
+  write_burst: 0
+  # Rejected payloads kept in <prefix>quarantine:payloads
+  quarantine_max_len: 1000
+  # Compress raw trades in the history: none, gzip or zstd
+  compression_codec: none
+
+binance:
+  # Priority symbols that are always tracked
+  main_symbols: [BTCUSDT, ETHUSDT]
+  # Stream main symbols even below min_daily_volume, beyond max_symbols or
+  # when not trading (with a warning); false filters them like other pairs
+  main_symbols_always_on: true
+  # Maximum number of symbols to track (0 for unlimited)
+  max_symbols: 5
+  # Minimum 24h quote volume of discovered symbols (0 for unlimited)
+  min_daily_volume: 10000000
+  quote_assets: [USDT]
+  # Skip margin-only pairs and leveraged tokens
+  spot_only: false
+  # Proxies as http://host:port or socks5://host:port; streams use http_proxy
+  # unless ws_proxy is set, and HTTPS_PROXY/HTTP_PROXY apply when both are empty
+  http_proxy: ""
+  ws_proxy: ""
+  # Where symbols come from: "api" (discovery with the filters above),
+  # "static" (static_symbols) or "file" (symbols_file, reloaded on change)
+  symbol_source: api
+  static_symbols: []
+  symbols_file: ""
+  # Stream "trade", "aggTrade" or "miniTicker" unless overridden per symbol
+  # with binance-cli symbols stream-type set
+  default_stream_type: trade
+  # Streams per WebSocket connection; Binance allows at most 1024
+  max_streams_per_conn: 1000
+  # How often to rediscover symbols (0 disables)
+  symbol_refresh_interval: 1h
+  # Use the spot testnet instead of production endpoints
+  use_testnet: false
+  # Balance connections by 24h volume instead of splitting in discovery order
+  balance_groups_by_volume: false
+  # Also stream best bid/ask to keep spread and imbalance series (2 streams per symbol)
+  book_ticker: false
+  # API key for streaming your own orders and balances (prefer BINANCE_API_KEY)
+  api_key: ""
+  # Backfill the trades missed while a connection was down from the REST API
+  auto_recover_gaps: false
+
+websocket:
+  reconnect_delay: 5s
+  ping_interval: 1m
+
+ingestion:
+  # Archive raw messages as gzipped ndjson in this directory (empty disables)
+  record_dir: ""
+  # Rebuild all connections after this long without messages (0 disables)
+  watchdog_silence: 2m
+  watchdog_max_restarts: 3
+  # Trades buffered before the message bus; "block", "shed" or "drop_oldest" when full
+  publish_queue_size: 10000
+  publish_queue_policy: block
+  publish_workers: 4
+  # Decode trade messages without encoding/json, reusing pooled events
+  fast_decode: false
+  # Stream connects and disconnects kept for "binance-cli uptime"
+  connection_events_max: 10000
+  # Refresh interval of the group assignments shown by "binance-cli health --connections"
+  group_heartbeat: 15s
+  # Stream only while holding a Redis lease so replicas never ingest twice;
+  # a replica takes over within lease_ttl of the leader stopping
+  leader_election: false
+  lease_ttl: 15s
+
+processor:
+  dlq_max_len: 10000
+  dlq_alert_threshold: 100
+  anomalies:
+    enabled: false
+    # Outsized trades: notional this many standard deviations above the
+    # average of the last size_window trades
+    size_sigmas: 4
+    size_window: 500
+    size_min_samples: 50
+    # Price jumps: a move of this many percent within the window
+    price_jump_percent: 1
+    price_jump_window: 1m
+    max_events: 1000
+
+cache:
+  # How long latest prices and 24h volumes are served from memory
+  ttl: 1s
+  max_entries: 1000
+
+# Circuit breakers of exchange connections and PostgreSQL candle writes
+breaker:
+  max_failures: 5
+  window: 1m
+  cooldown: 30s
+
+postgres:
+  # Candles older than this are pruned, e.g. 2160h for 90 days (0 keeps
+  # them forever)
+  candle_retention: 0s
+  prune_interval: 1h
+  prune_batch_size: 10000
+  # With TimescaleDB, compress candle chunks older than this (0 disables)
+  compress_after: 0s
+  # How often daily candles (trade_candles_daily) are rolled up (0 disables)
+  rollup_interval: 1h
+  # Candles kept in memory before the oldest are written out, complete or
+  # not, or dropped while PostgreSQL is down (0 disables the cap)
+  max_buffered_candles: 100000
+
+api:
+  # Listen address of the read API (ordersvc)
+  addr: ":8080"
+
+debug: false
+# Debug HTTP server address (empty disables it)
+debug_addr: ":2112"
+`
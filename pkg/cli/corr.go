@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/analysis"
+	"binance-redis-streamer/pkg/timeutil"
+)
+
+// Bounds of the corr --period and --interval
+const (
+	maxCorrPeriod   = 365 * 24 * time.Hour
+	maxCorrInterval = 7 * 24 * time.Hour
+)
+
+// minCorrReturns is how many shared returns a pair needs for a correlation
+const minCorrReturns = 3
+
+// candleLoader loads candles aggregated to interval
+type candleLoader interface {
+	GetAggregatedCandles(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*models.Candle, error)
+}
+
+func newCorrCmd() *cobra.Command {
+	var (
+		period   string
+		interval string
+		format   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "corr [symbols...]",
+		Short: "Correlation matrix of symbol returns",
+		Long: `Compute the pairwise Pearson correlations of the log returns of candle closes.
+Returns are only taken between adjacent candles, and each pair is correlated
+over the candles both symbols have. Symbols without data for the period are
+reported and shown as n/a.
+Example: binance-cli corr BTCUSDT ETHUSDT SOLUSDT --period 7d --interval 1h`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch format {
+			case "table", "csv", "json":
+			default:
+				return fmt.Errorf("unsupported format: %s", format)
+			}
+
+			symbols, err := resolveSymbols(cmd.Context(), args)
+			if err != nil {
+				return err
+			}
+			duration, err := timeutil.ParseDuration(period, maxCorrPeriod)
+			if err != nil {
+				return fmt.Errorf("invalid period: %w", err)
+			}
+			step, err := timeutil.ParseDuration(interval, maxCorrInterval)
+			if err != nil {
+				return fmt.Errorf("invalid interval: %w", err)
+			}
+
+			postgresStore, err := newPostgresStore(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+			}
+			defer postgresStore.Close()
+
+			end := time.Now()
+			matrix, err := loadCorrelations(cmd.Context(), postgresStore, symbols, end.Add(-duration), end, interval, step, minCorrReturns)
+			if err != nil {
+				return err
+			}
+
+			switch format {
+			case "csv":
+				return writeCorrCSV(os.Stdout, symbols, matrix)
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(newCorrReport(symbols, period, interval, matrix))
+			default:
+				fmt.Printf("Correlation of %s log returns (last %s)\n", interval, period)
+				printCorrTable(os.Stdout, symbols, matrix)
+				return nil
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&period, "period", "p", "7d", "Time period (e.g., 24h, 7d, 2w, 3mo)")
+	cmd.Flags().StringVarP(&interval, "interval", "i", "1h", "Candle interval of the returns (e.g., 5m, 1h, 1d)")
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "Output format (table, csv, or json)")
+
+	return cmd
+}
+
+// loadCorrelations correlates the log returns of the symbols' candle closes,
+// fetching the candles of all symbols in parallel. Pairs sharing fewer than
+// minReturns returns are left uncorrelated, as are symbols without candles,
+// which are logged; it fails only when fewer than two symbols have any.
+func loadCorrelations(ctx context.Context, store candleLoader, symbols []string, start, end time.Time, interval string, step time.Duration, minReturns int) (*analysis.CorrelationMatrix, error) {
+	candles := make([][]*models.Candle, len(symbols))
+	errs := make([]error, len(symbols))
+	var wg sync.WaitGroup
+	for i, symbol := range symbols {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			candles[i], errs[i] = store.GetAggregatedCandles(ctx, symbol, start, end, interval)
+		}()
+	}
+	wg.Wait()
+
+	returns := make([][]analysis.TimedValue, len(symbols))
+	withData := 0
+	for i, symbol := range symbols {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("failed to get historical data for %s: %w", symbol, errs[i])
+		}
+		if len(candles[i]) == 0 {
+			log.Printf("Warning: no data found for %s in the specified period", symbol)
+			continue
+		}
+
+		closes := make([]analysis.TimedValue, 0, len(candles[i]))
+		for _, candle := range candles[i] {
+			price, err := strconv.ParseFloat(candle.ClosePrice, 64)
+			if err != nil {
+				continue
+			}
+			closes = append(closes, analysis.TimedValue{Time: candle.Timestamp, Value: price})
+		}
+		returns[i] = analysis.LogReturns(closes, step)
+		withData++
+	}
+	if withData < 2 {
+		return nil, fmt.Errorf("need data for at least two symbols, found it for %d", withData)
+	}
+	return analysis.Correlations(returns, minReturns), nil
+}
+
+// formatCorr formats a correlation, or n/a when there is none
+func formatCorr(r float64) string {
+	if math.IsNaN(r) {
+		return "n/a"
+	}
+	return strconv.FormatFloat(r, 'f', 3, 64)
+}
+
+// printCorrTable prints the matrix with a row and a column per symbol
+func printCorrTable(w io.Writer, symbols []string, matrix *analysis.CorrelationMatrix) {
+	width := 8
+	for _, symbol := range symbols {
+		width = max(width, len(symbol)+1)
+	}
+
+	fmt.Fprintf(w, "%-*s", width, "")
+	for _, symbol := range symbols {
+		fmt.Fprintf(w, " %*s", width, symbol)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, strings.Repeat("-", (width+1)*(len(symbols)+1)))
+
+	for i, symbol := range symbols {
+		fmt.Fprintf(w, "%-*s", width, symbol)
+		for j := range symbols {
+			fmt.Fprintf(w, " %*s", width, formatCorr(matrix.Values[i][j]))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// writeCorrCSV writes the matrix with a header row of symbols and a row per
+// symbol; missing correlations are empty
+func writeCorrCSV(w io.Writer, symbols []string, matrix *analysis.CorrelationMatrix) error {
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"symbol"}, symbols...))
+	for i, symbol := range symbols {
+		row := []string{symbol}
+		for j := range symbols {
+			cell := ""
+			if r := matrix.Values[i][j]; !math.IsNaN(r) {
+				cell = strconv.FormatFloat(r, 'f', -1, 64)
+			}
+			row = append(row, cell)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// corrReport is the JSON form of a correlation matrix
+type corrReport struct {
+	Period       string       `json:"period"`
+	Interval     string       `json:"interval"`
+	Symbols      []string     `json:"symbols"`
+	Matrix       [][]*float64 `json:"matrix"`       // Null where there is no correlation
+	Observations [][]int      `json:"observations"` // Returns shared by each pair
+}
+
+// newCorrReport converts matrix to its JSON form
+func newCorrReport(symbols []string, period, interval string, matrix *analysis.CorrelationMatrix) corrReport {
+	report := corrReport{
+		Period:       period,
+		Interval:     interval,
+		Symbols:      symbols,
+		Matrix:       make([][]*float64, len(symbols)),
+		Observations: matrix.Observations,
+	}
+	for i := range symbols {
+		report.Matrix[i] = make([]*float64, len(symbols))
+		for j := range symbols {
+			if r := matrix.Values[i][j]; !math.IsNaN(r) {
+				report.Matrix[i][j] = &r
+			}
+		}
+	}
+	return report
+}
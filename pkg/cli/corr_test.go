@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// fakeCandles serves fixed candles per symbol
+type fakeCandles map[string][]*models.Candle
+
+func (f fakeCandles) GetAggregatedCandles(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*models.Candle, error) {
+	return f[symbol], nil
+}
+
+func hourlyCloses(closes ...float64) []*models.Candle {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]*models.Candle, len(closes))
+	for i, c := range closes {
+		candles[i] = &models.Candle{Timestamp: start.Add(time.Duration(i) * time.Hour), ClosePrice: strconv.FormatFloat(c, 'f', -1, 64)}
+	}
+	return candles
+}
+
+func TestLoadCorrelations(t *testing.T) {
+	store := fakeCandles{
+		"BTCUSDT": hourlyCloses(100, 110, 99, 120, 108, 130),
+		"ETHUSDT": hourlyCloses(10, 11, 9.9, 12, 10.8, 13), // Same returns as BTCUSDT
+		"SOLUSDT": nil,
+	}
+	symbols := []string{"BTCUSDT", "ETHUSDT", "SOLUSDT"}
+
+	matrix, err := loadCorrelations(context.Background(), store, symbols, time.Time{}, time.Now(), "1h", time.Hour, minCorrReturns)
+	if err != nil {
+		t.Fatalf("loadCorrelations() error = %v", err)
+	}
+	if r := matrix.Values[0][1]; r < 0.999999 {
+		t.Errorf("corr(BTCUSDT, ETHUSDT) = %v, want 1", r)
+	}
+
+	var table strings.Builder
+	printCorrTable(&table, symbols, matrix)
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 5 || strings.Count(lines[4], "n/a") != 3 || strings.Count(lines[2], "1.000") != 2 {
+		t.Errorf("Unexpected table:\n%s", table.String())
+	}
+
+	var out strings.Builder
+	if err := writeCorrCSV(&out, symbols, matrix); err != nil {
+		t.Fatalf("writeCorrCSV() error = %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "symbol,BTCUSDT,ETHUSDT,SOLUSDT\n") || !strings.HasSuffix(out.String(), "SOLUSDT,,,\n") {
+		t.Errorf("Unexpected CSV:\n%s", out.String())
+	}
+
+	encoded, err := json.Marshal(newCorrReport(symbols, "7d", "1h", matrix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(encoded), `[null,null,null]`) || !strings.Contains(string(encoded), `"observations":[[5,5,0]`) {
+		t.Errorf("Unexpected JSON: %s", encoded)
+	}
+
+	// One symbol with data is not enough
+	if _, err := loadCorrelations(context.Background(), store, []string{"BTCUSDT", "SOLUSDT"}, time.Time{}, time.Now(), "1h", time.Hour, minCorrReturns); err == nil {
+		t.Error("Expected an error with data for only one symbol")
+	}
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/pkg/processor"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func newDLQCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dlq",
+		Short: "Inspect and retry dead-lettered trades",
+		Long: `Inspect and retry trades that failed storage or aggregation and were moved
+to the dead letter queue.
+Example: binance-cli dlq list --limit 20`,
+	}
+
+	cmd.AddCommand(newDLQListCmd(), newDLQRetryCmd())
+	return cmd
+}
+
+func newDLQListCmd() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the oldest dead-lettered trades",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+				svc := processor.NewService(configFromContext(cmd.Context()), store, nil)
+				ctx := cmd.Context()
+
+				depth, err := svc.DLQDepth(ctx)
+				if err != nil {
+					return err
+				}
+				entries, err := svc.ListDLQ(ctx, limit)
+				if err != nil {
+					return err
+				}
+				printDLQEntries(entries, depth)
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "l", 20, "Maximum number of entries to show (0 for all)")
+	return cmd
+}
+
+func newDLQRetryCmd() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "retry",
+		Short: "Retry the oldest dead-lettered trades",
+		Long: `Retry dead-lettered trades through storage and aggregation. Completed candles
+are written to PostgreSQL; trades that fail again return to the queue.
+Example: binance-cli dlq retry --limit 100`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+				postgresStore, err := newPostgresStore(cmd.Context())
+				if err != nil {
+					return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+				}
+				defer postgresStore.Close()
+
+				aggregator := storage.NewTradeAggregator(store, postgresStore)
+				svc := processor.NewService(configFromContext(cmd.Context()), store, aggregator)
+				ctx := cmd.Context()
+
+				retried, err := svc.DrainDLQ(ctx, limit)
+				if flushErr := aggregator.Flush(ctx); flushErr != nil && err == nil {
+					err = fmt.Errorf("failed to flush candles: %w", flushErr)
+				}
+				if err != nil {
+					return err
+				}
+
+				depth, err := svc.DLQDepth(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Retried %d trades successfully, %d remain in the dead letter queue\n", retried, depth)
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "l", 100, "Maximum number of entries to retry")
+	return cmd
+}
+
+// printDLQEntries prints dead-lettered trades as a table
+func printDLQEntries(entries []processor.DLQEntry, depth int64) {
+	if len(entries) == 0 {
+		fmt.Println("Dead letter queue is empty")
+		return
+	}
+
+	fmt.Printf("%-20s %-10s %14s %14s %8s  %s\n", "Failed At", "Symbol", "Price", "Quantity", "Attempts", "Error")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, entry := range entries {
+		var symbol, price, quantity string
+		if entry.Event != nil {
+			symbol, price, quantity = entry.Event.Data.Symbol, entry.Event.Data.Price, entry.Event.Data.Quantity
+		}
+		fmt.Printf("%-20s %-10s %14s %14s %8d  %s\n",
+			entry.FailedAt.Local().Format(time.DateTime),
+			symbol, price, quantity, entry.Attempts, entry.Error)
+	}
+	fmt.Printf("\nShowing %d of %d dead-lettered trades\n", len(entries), depth)
+}
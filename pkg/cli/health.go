@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/processor"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func newHealthCmd() *cobra.Command {
+	var (
+		perSymbol   bool
+		connections bool
+		reset       bool
+		addr        string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "health",
+		Short: "Check the health of the streamer",
+		Long: `Check Redis connectivity and, with --per-symbol, show which symbols generate
+the most processing load using the streamer's debug server. With
+--connections, show which WebSocket connection carries each symbol group, as
+reported to Redis by the running ingestion processes.
+Example: binance-cli health --per-symbol
+         binance-cli health --connections`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), 10*time.Second)
+			defer cancel()
+
+			cfg := configFromContext(cmd.Context())
+			redisStore, err := storage.NewRedisStore(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to connect to Redis: %w", err)
+			}
+			defer redisStore.Close()
+
+			start := time.Now()
+			if err := redisStore.GetRedisClient().Ping(ctx).Err(); err != nil {
+				return fmt.Errorf("redis ping failed: %w", err)
+			}
+			fmt.Printf("Redis: OK (%s)\n", time.Since(start).Round(time.Microsecond))
+
+			if connections {
+				groups, err := redisStore.GetStreamGroups(ctx, time.Now())
+				if err != nil {
+					return err
+				}
+				printStreamGroups(cmd.OutOrStdout(), groups, time.Now())
+			}
+			if !perSymbol {
+				return nil
+			}
+
+			stats, err := fetchSymbolStats(ctx, addr, reset)
+			if err != nil {
+				return err
+			}
+			printSymbolStats(stats)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&perSymbol, "per-symbol", false, "Show per-symbol processing statistics")
+	cmd.Flags().BoolVar(&connections, "connections", false, "Show the symbol groups of each WebSocket connection")
+	cmd.Flags().BoolVar(&reset, "reset", false, "Reset per-symbol counters after reading them")
+	cmd.Flags().StringVar(&addr, "addr", "http://localhost:2112", "Address of the streamer's debug server")
+
+	return cmd
+}
+
+// fetchSymbolStats reads per-symbol processing counters from the debug server
+func fetchSymbolStats(ctx context.Context, addr string, reset bool) ([]processor.SymbolStats, error) {
+	endpoint := strings.TrimSuffix(addr, "/") + "/debug/symbols"
+	if reset {
+		endpoint += "?" + url.Values{"reset": {"true"}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid debug server address: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach debug server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("debug server returned %s", resp.Status)
+	}
+
+	var stats []processor.SymbolStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to decode symbol stats: %w", err)
+	}
+	return stats, nil
+}
+
+// printSymbolStats renders per-symbol counters, busiest symbol first
+func printSymbolStats(stats []processor.SymbolStats) {
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-12s %-12s %-14s %-10s %-20s\n", "Symbol", "Trades", "Bytes", "Errors", "Last Trade")
+	fmt.Println(strings.Repeat("-", 80))
+
+	for _, s := range stats {
+		lastTrade := "-"
+		if !s.LastTradeTime.IsZero() {
+			lastTrade = fmt.Sprintf("%s ago", time.Since(s.LastTradeTime).Round(time.Second))
+		}
+		fmt.Printf("%-12s %-12d %-14d %-10d %-20s\n",
+			s.Symbol, s.TradesProcessed, s.Bytes, s.StoreErrors, lastTrade)
+	}
+
+	if len(stats) == 0 {
+		fmt.Println("No trades processed yet")
+	}
+}
+
+// maxGroupSymbolsShown is how many symbols of a group printStreamGroups lists
+const maxGroupSymbolsShown = 5
+
+// printStreamGroups renders the symbol group of each connection, ordered by
+// group
+func printStreamGroups(out io.Writer, groups []*models.StreamGroup, now time.Time) {
+	fmt.Fprintln(out, strings.Repeat("-", 100))
+	fmt.Fprintf(out, "%-20s %-10s %-12s %-10s %-20s %s\n", "Group", "Connected", "Reconnects", "Heartbeat", "Process", "Symbols")
+	fmt.Fprintln(out, strings.Repeat("-", 100))
+
+	for _, g := range groups {
+		connected := "-"
+		if !g.ConnectedAt.IsZero() {
+			connected = formatAgo(now.Sub(g.ConnectedAt))
+		}
+		symbols := strings.Join(g.Symbols[:min(len(g.Symbols), maxGroupSymbolsShown)], ",")
+		if more := len(g.Symbols) - maxGroupSymbolsShown; more > 0 {
+			symbols += fmt.Sprintf(" +%d more", more)
+		}
+		fmt.Fprintf(out, "%-20s %-10s %-12d %-10s %-20s %s\n",
+			g.Group, connected, g.Reconnects, formatAgo(now.Sub(g.Heartbeat)), g.Process, symbols)
+	}
+
+	if len(groups) == 0 {
+		fmt.Fprintln(out, "No streaming connections reported")
+	}
+}
+
+// formatAgo renders an age in seconds as "12s ago"
+func formatAgo(age time.Duration) string {
+	return fmt.Sprintf("%s ago", max(0, age).Round(time.Second))
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestPrintStreamGroups(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	groups := []*models.StreamGroup{{
+		Group:       "btcusdt-7",
+		Symbols:     []string{"BTCUSDT", "ETHUSDT", "BNBUSDT", "SOLUSDT", "XRPUSDT", "ADAUSDT", "DOGEUSDT"},
+		Process:     "host:42",
+		ConnectedAt: now.Add(-90 * time.Second),
+		Reconnects:  3,
+		Heartbeat:   now.Add(-5 * time.Second),
+	}}
+
+	var out bytes.Buffer
+	printStreamGroups(&out, groups, now)
+	for _, want := range []string{"btcusdt-7", "1m30s ago", "5s ago", "host:42", "BTCUSDT,ETHUSDT,BNBUSDT,SOLUSDT,XRPUSDT +2 more"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	printStreamGroups(&out, nil, now)
+	if !strings.Contains(out.String(), "No streaming connections reported") {
+		t.Errorf("Expected a note without groups, got:\n%s", out.String())
+	}
+}
@@ -3,38 +3,175 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
+	"binance-redis-streamer/internal/models"
 	"binance-redis-streamer/pkg/storage"
+	"binance-redis-streamer/pkg/timeutil"
 )
 
+// maxHistoryPeriod bounds --period of history
+const maxHistoryPeriod = 365 * 24 * time.Hour
+
+const (
+	ansiGreen = "\033[32m"
+	ansiRed   = "\033[31m"
+	ansiReset = "\033[0m"
+)
+
+// candleDelta holds the candle-over-candle percentage changes of a candle;
+// a nil field means there is no previous value to compare against
+type candleDelta struct {
+	Close  *float64
+	Volume *float64
+	Trades *float64
+}
+
+// computeCandleDeltas returns the change of each candle relative to the one before it
+func computeCandleDeltas(candles []*models.Candle) []candleDelta {
+	deltas := make([]candleDelta, len(candles))
+	for i := 1; i < len(candles); i++ {
+		prev, cur := candles[i-1], candles[i]
+
+		prevClose, _ := strconv.ParseFloat(prev.ClosePrice, 64)
+		curClose, _ := strconv.ParseFloat(cur.ClosePrice, 64)
+		prevVolume, _ := strconv.ParseFloat(prev.Volume, 64)
+		curVolume, _ := strconv.ParseFloat(cur.Volume, 64)
+
+		deltas[i] = candleDelta{
+			Close:  percentChange(prevClose, curClose),
+			Volume: percentChange(prevVolume, curVolume),
+			Trades: percentChange(float64(prev.TradeCount), float64(cur.TradeCount)),
+		}
+	}
+	return deltas
+}
+
+// percentChange returns (current - previous) / previous * 100, or nil when previous is zero
+func percentChange(previous, current float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := (current - previous) / previous * 100
+	return &change
+}
+
+// formatDelta renders a delta padded to width, colored by sign when color is set
+func formatDelta(delta *float64, width int, color bool) string {
+	if delta == nil {
+		return fmt.Sprintf("%-*s", width, "-")
+	}
+
+	text := fmt.Sprintf("%-*s", width, fmt.Sprintf("%+.2f%%", *delta))
+	switch {
+	case !color || *delta == 0:
+		return text
+	case *delta > 0:
+		return ansiGreen + text + ansiReset
+	default:
+		return ansiRed + text + ansiReset
+	}
+}
+
+// csvDelta renders a delta as a plain CSV value
+func csvDelta(delta *float64) string {
+	if delta == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*delta, 'f', 2, 64)
+}
+
+// liveRanger merges live trades with stored candles, e.g. *storage.RedisStore
+type liveRanger interface {
+	GetRangeSince(ctx context.Context, older storage.RangeQuerier, symbol string, start time.Time) (*models.Candle, error)
+}
+
+// currentBucket returns the start of the candle of step holding now, with
+// buckets aligned to boundary as in GetAggregatedCandlesAligned
+func currentBucket(now time.Time, step, boundary time.Duration) time.Time {
+	stepSeconds := int64(step / time.Second)
+	offset := int64(boundary / time.Second)
+	shifted := now.Unix() + offset
+	bucket := shifted - shifted%stepSeconds
+	if shifted%stepSeconds < 0 {
+		bucket -= stepSeconds
+	}
+	return time.Unix(bucket-offset, 0).In(now.Location())
+}
+
+// mergeCurrentCandle makes the candle starting at bucketStart, the one still
+// in progress, reflect every trade up to now: the minutes already stored in
+// older and the trades since in live. It replaces the trailing candle when
+// it is that bucket and appends one otherwise.
+func mergeCurrentCandle(ctx context.Context, live liveRanger, older storage.RangeQuerier, symbol string, candles []*models.Candle, bucketStart time.Time) ([]*models.Candle, error) {
+	current, err := live.GetRangeSince(ctx, older, symbol, bucketStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the current candle: %w", err)
+	}
+	if current == nil {
+		return candles, nil
+	}
+	current.Timestamp = bucketStart
+
+	if n := len(candles); n > 0 && !candles[n-1].Timestamp.Before(bucketStart) {
+		candles[n-1] = current
+		return candles, nil
+	}
+	return append(candles, current), nil
+}
+
 func newHistoryCmd() *cobra.Command {
 	var (
 		period   string
 		interval string
 		limit    int
 		format   string
+		delta    bool
+		boundary string
+		current  bool
+		gaps     bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "history [symbol]",
 		Short: "View historical trade data",
 		Long: `View historical trade data for a symbol with custom time intervals.
-Example: binance-cli history BTCUSDT --period 24h --interval 5m`,
+Use --delta to add candle-over-candle percentage changes in close price, volume and trade count.
+Stored candles end with the last minute flushed to PostgreSQL; --include-current
+completes the last candle with the live trades in Redis, so it reflects right now.
+--day-boundary sets where days, and so daily candles, begin: UTC by default,
+or an offset such as UTC+8.
+--gaps lists the periods within --period without stored minute candles instead,
+e.g. while the streamer was down.
+Example: binance-cli history BTCUSDT --period 24h --interval 5m --delta
+         binance-cli history BTCUSDT --period 6h --interval 1h --include-current
+         binance-cli history BTCUSDT --period 30d --interval 1d --day-boundary UTC+8
+         binance-cli history --gaps BTCUSDT --period 7d`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			symbol := strings.ToUpper(args[0])
+			symbol, err := resolveSymbol(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
 
 			// Parse time period
-			duration, err := parseDuration(period)
+			duration, err := timeutil.ParseDuration(period, maxHistoryPeriod)
+			if err != nil {
+				return fmt.Errorf("invalid period: %w", err)
+			}
+			offset, err := timeutil.ParseDayBoundary(boundary)
 			if err != nil {
-				return fmt.Errorf("invalid period format: %w", err)
+				return err
 			}
 
-			postgresStore, err := storage.NewPostgresStore()
+			postgresStore, err := newPostgresStore(cmd.Context())
 			if err != nil {
 				return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 			}
@@ -43,46 +180,107 @@ Example: binance-cli history BTCUSDT --period 24h --interval 5m`,
 			end := time.Now()
 			start := end.Add(-duration)
 
-			candles, err := postgresStore.GetAggregatedCandles(context.Background(), symbol, start, end, interval)
+			if gaps {
+				found, err := postgresStore.GetGapAnalysis(cmd.Context(), symbol, start, end)
+				if err != nil {
+					return fmt.Errorf("failed to analyse gaps: %w", err)
+				}
+				return printGaps(cmd.OutOrStdout(), symbol, period, found, format)
+			}
+
+			candles, err := postgresStore.GetAggregatedCandlesAligned(cmd.Context(), symbol, start, end, interval, offset)
 			if err != nil {
 				return fmt.Errorf("failed to get historical data: %w", err)
 			}
 
+			if current {
+				step, err := timeutil.ParseDuration(interval, 0)
+				if err != nil {
+					return fmt.Errorf("invalid interval: %w", err)
+				}
+				bucketStart := currentBucket(end, step, offset)
+				if bucketStart.Before(start) {
+					bucketStart = start
+				}
+				err = withRedisStore(cmd.Context(), func(redisStore *storage.RedisStore) error {
+					candles, err = mergeCurrentCandle(cmd.Context(), redisStore, postgresStore, symbol, candles, bucketStart)
+					return err
+				})
+				if err != nil {
+					return err
+				}
+			}
+
 			if len(candles) == 0 {
 				return fmt.Errorf("no data found for %s in the specified period", symbol)
 			}
+			// Show times at the boundary's offset, so daily candles start at 00:00
+			if offset != 0 {
+				zone := time.FixedZone(timeutil.FormatDayBoundary(offset), int(offset/time.Second))
+				for _, candle := range candles {
+					candle.Timestamp = candle.Timestamp.In(zone)
+				}
+			}
+
+			// Deltas are computed before limiting so the first shown row still has a previous candle
+			deltas := computeCandleDeltas(candles)
 
 			// Limit the number of results if specified
 			if limit > 0 && limit < len(candles) {
 				candles = candles[len(candles)-limit:]
+				deltas = deltas[len(deltas)-limit:]
 			}
 
 			// Print header
-			fmt.Printf("Historical data for %s (%s intervals)\n", strings.ToUpper(symbol), interval)
+			if current {
+				fmt.Printf("Historical data for %s (%s intervals, last in progress)\n", strings.ToUpper(symbol), interval)
+			} else {
+				fmt.Printf("Historical data for %s (%s intervals)\n", strings.ToUpper(symbol), interval)
+			}
 			fmt.Println(strings.Repeat("-", 100))
 
 			switch format {
 			case "table":
-				fmt.Printf("%-20s %-12s %-12s %-12s %-12s %-15s %-10s\n",
+				color := term.IsTerminal(int(os.Stdout.Fd()))
+				precisions := connectPricePrecisions(cmd.Context())
+
+				fmt.Printf("%-20s %-12s %-12s %-12s %-12s %-15s %-10s",
 					"Time", "Open", "High", "Low", "Close", "Volume", "Trades")
+				if delta {
+					fmt.Printf(" %-10s %-10s %-10s", "Δclose%", "Δvolume%", "Δtrades%")
+				}
+				fmt.Println()
 				fmt.Println(strings.Repeat("-", 100))
 
-				for _, candle := range candles {
-					fmt.Printf("%-20s %-12s %-12s %-12s %-12s %-15s %-10d\n",
+				for i, candle := range candles {
+					fmt.Printf("%-20s %-12s %-12s %-12s %-12s %-15s %-10d",
 						candle.Timestamp.Format("2006-01-02 15:04:05"),
-						candle.OpenPrice,
-						candle.HighPrice,
-						candle.LowPrice,
-						candle.ClosePrice,
+						precisions.formatPriceString(symbol, candle.OpenPrice),
+						precisions.formatPriceString(symbol, candle.HighPrice),
+						precisions.formatPriceString(symbol, candle.LowPrice),
+						precisions.formatPriceString(symbol, candle.ClosePrice),
 						candle.Volume,
 						candle.TradeCount,
 					)
+					if delta {
+						fmt.Printf(" %s %s %s",
+							formatDelta(deltas[i].Close, 10, color),
+							formatDelta(deltas[i].Volume, 10, color),
+							formatDelta(deltas[i].Trades, 10, color),
+						)
+					}
+					fmt.Println()
 				}
 
 			case "csv":
-				fmt.Println("timestamp,open,high,low,close,volume,trades")
-				for _, candle := range candles {
-					fmt.Printf("%s,%s,%s,%s,%s,%s,%d\n",
+				header := "timestamp,open,high,low,close,volume,trades"
+				if delta {
+					header += ",delta_close_pct,delta_volume_pct,delta_trades_pct"
+				}
+				fmt.Println(header)
+
+				for i, candle := range candles {
+					fmt.Printf("%s,%s,%s,%s,%s,%s,%d",
 						candle.Timestamp.Format("2006-01-02 15:04:05"),
 						candle.OpenPrice,
 						candle.HighPrice,
@@ -91,6 +289,10 @@ Example: binance-cli history BTCUSDT --period 24h --interval 5m`,
 						candle.Volume,
 						candle.TradeCount,
 					)
+					if delta {
+						fmt.Printf(",%s,%s,%s", csvDelta(deltas[i].Close), csvDelta(deltas[i].Volume), csvDelta(deltas[i].Trades))
+					}
+					fmt.Println()
 				}
 
 			default:
@@ -101,10 +303,48 @@ Example: binance-cli history BTCUSDT --period 24h --interval 5m`,
 		},
 	}
 
-	cmd.Flags().StringVarP(&period, "period", "p", "24h", "Time period (e.g., 1h, 24h, 7d)")
+	cmd.Flags().StringVarP(&period, "period", "p", "24h", "Time period (e.g., 1h, 24h, 7d, 2w, 3mo)")
 	cmd.Flags().StringVarP(&interval, "interval", "i", "1m", "Time interval (e.g., 1m, 5m, 1h)")
 	cmd.Flags().IntVarP(&limit, "limit", "l", 0, "Limit the number of results (0 for all)")
 	cmd.Flags().StringVarP(&format, "format", "f", "table", "Output format (table or csv)")
+	cmd.Flags().BoolVar(&delta, "delta", false, "Show candle-over-candle changes in close, volume and trades")
+	cmd.Flags().StringVar(&boundary, "day-boundary", "UTC", "Where days begin: UTC or an offset such as UTC+8 or UTC-5")
+	cmd.Flags().BoolVar(&current, "include-current", false, "Complete the last candle with live trades from Redis")
+	cmd.Flags().BoolVar(&gaps, "gaps", false, "List periods without stored candles instead of the candles")
 
 	return cmd
 }
+
+// printGaps lists the gaps in a symbol's candles over period as a table or
+// CSV, with their total duration in the table
+func printGaps(out io.Writer, symbol, period string, gaps []storage.TimeGap, format string) error {
+	switch format {
+	case "table":
+		fmt.Fprintf(out, "Data gaps for %s (last %s)\n", strings.ToUpper(symbol), period)
+		fmt.Fprintln(out, strings.Repeat("-", 60))
+		if len(gaps) == 0 {
+			fmt.Fprintln(out, "No gaps found")
+			return nil
+		}
+		fmt.Fprintf(out, "%-20s %-20s %s\n", "Start", "End", "Duration")
+		var total time.Duration
+		for _, gap := range gaps {
+			fmt.Fprintf(out, "%-20s %-20s %s\n",
+				gap.Start.Local().Format(time.DateTime), gap.End.Local().Format(time.DateTime), gap.Duration)
+			total += gap.Duration
+		}
+		fmt.Fprintln(out, strings.Repeat("-", 60))
+		fmt.Fprintf(out, "%d gaps, %s missing\n", len(gaps), total)
+
+	case "csv":
+		fmt.Fprintln(out, "start,end,duration_seconds")
+		for _, gap := range gaps {
+			fmt.Fprintf(out, "%s,%s,%d\n",
+				gap.Start.UTC().Format(time.RFC3339), gap.End.UTC().Format(time.RFC3339), int64(gap.Duration/time.Second))
+		}
+
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func TestComputeCandleDeltas(t *testing.T) {
+	candles := []*models.Candle{
+		{ClosePrice: "100", Volume: "10", TradeCount: 4},
+		{ClosePrice: "110", Volume: "5", TradeCount: 4},
+		{ClosePrice: "99", Volume: "0", TradeCount: 2},
+		{ClosePrice: "99", Volume: "7", TradeCount: 3},
+	}
+
+	deltas := computeCandleDeltas(candles)
+	if len(deltas) != len(candles) {
+		t.Fatalf("Expected %d deltas, got %d", len(candles), len(deltas))
+	}
+
+	first := deltas[0]
+	if first.Close != nil || first.Volume != nil || first.Trades != nil {
+		t.Errorf("Expected no deltas for the first candle, got %+v", first)
+	}
+
+	assertDelta(t, "close[1]", deltas[1].Close, 10)
+	assertDelta(t, "volume[1]", deltas[1].Volume, -50)
+	assertDelta(t, "trades[1]", deltas[1].Trades, 0)
+	assertDelta(t, "close[2]", deltas[2].Close, -10)
+	assertDelta(t, "trades[2]", deltas[2].Trades, -50)
+
+	// A previous volume of zero has no meaningful percentage change
+	if deltas[3].Volume != nil {
+		t.Errorf("Expected no volume delta after a zero-volume candle, got %v", *deltas[3].Volume)
+	}
+}
+
+func TestFormatDelta(t *testing.T) {
+	up, down := 5.0, -2.5
+
+	if got := formatDelta(nil, 8, true); got != "-       " {
+		t.Errorf("formatDelta(nil) = %q", got)
+	}
+	if got := formatDelta(&up, 8, false); got != "+5.00%  " {
+		t.Errorf("formatDelta(+5, no color) = %q", got)
+	}
+	if got := formatDelta(&up, 8, true); !strings.HasPrefix(got, ansiGreen) || !strings.HasSuffix(got, ansiReset) {
+		t.Errorf("Expected positive delta in green, got %q", got)
+	}
+	if got := formatDelta(&down, 8, true); !strings.HasPrefix(got, ansiRed) {
+		t.Errorf("Expected negative delta in red, got %q", got)
+	}
+}
+
+func assertDelta(t *testing.T, name string, got *float64, want float64) {
+	t.Helper()
+	if got == nil {
+		t.Errorf("%s: expected %v, got none", name, want)
+		return
+	}
+	if math.Abs(*got-want) > 1e-9 {
+		t.Errorf("%s: expected %v, got %v", name, want, *got)
+	}
+}
+
+// minuteCandles is an in-memory storage.RangeQuerier over minute candles
+type minuteCandles []*models.Candle
+
+func (m minuteCandles) GetRange(ctx context.Context, symbol string, start, end time.Time) (*models.Candle, error) {
+	var merged *models.Candle
+	for _, candle := range m {
+		if candle.Timestamp.Before(start) || !candle.Timestamp.Before(end) {
+			continue
+		}
+		if merged == nil {
+			merged = models.NewCandle(candle.Timestamp)
+		}
+		c := *candle
+		merged.MergeWith(&c)
+	}
+	return merged, nil
+}
+
+func TestCurrentBucket(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		step, boundary time.Duration
+		want           time.Time
+	}{
+		{time.Minute, 0, time.Date(2024, 1, 1, 10, 17, 0, 0, time.UTC)},
+		{5 * time.Minute, 0, time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{time.Hour, 0, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{24 * time.Hour, 0, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Days at UTC+8 begin at 16:00 UTC, at UTC-5 at 05:00 UTC
+		{24 * time.Hour, 8 * time.Hour, time.Date(2023, 12, 31, 16, 0, 0, 0, time.UTC)},
+		{24 * time.Hour, -5 * time.Hour, time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := currentBucket(now, tt.step, tt.boundary); !got.Equal(tt.want) {
+			t.Errorf("currentBucket(%v, %v) = %v, want %v", tt.step, tt.boundary, got, tt.want)
+		}
+	}
+}
+
+func TestMergeCurrentCandle(t *testing.T) {
+	store, _, _ := newMiniredisStore(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	bucketStart := now.Truncate(time.Minute).Add(-5 * time.Minute)
+	minute := func(i int) time.Time { return bucketStart.Add(time.Duration(i) * time.Minute) }
+
+	// PostgreSQL has the bucket's first three minutes
+	stored := minuteCandles{
+		{Timestamp: minute(0), OpenPrice: "100", HighPrice: "104", LowPrice: "99", ClosePrice: "103", Volume: "2", TradeCount: 4},
+		{Timestamp: minute(1), OpenPrice: "103", HighPrice: "103", LowPrice: "98", ClosePrice: "98", Volume: "1", TradeCount: 2},
+		{Timestamp: minute(2), OpenPrice: "98", HighPrice: "101", LowPrice: "97", ClosePrice: "101", Volume: "3", TradeCount: 3},
+	}
+	// Redis holds trades from within the third minute on, which is partial
+	// there, up to now
+	for _, trade := range []*models.Trade{
+		{Symbol: "BTCUSDT", Price: "101", Quantity: "1", TradeID: 1, Time: minute(2).Add(30 * time.Second)},
+		{Symbol: "BTCUSDT", Price: "106", Quantity: "0.5", TradeID: 2, Time: now.Add(-2 * time.Second)},
+		{Symbol: "BTCUSDT", Price: "105", Quantity: "1.5", TradeID: 3, Time: now.Add(-time.Second)},
+	} {
+		if err := store.StoreTrade(ctx, trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The aggregated candles: a complete bucket and the stored part of the
+	// current one
+	previous := &models.Candle{Timestamp: bucketStart.Add(-time.Hour), OpenPrice: "90", HighPrice: "100", LowPrice: "90", ClosePrice: "100", Volume: "10", TradeCount: 20}
+	partial := &models.Candle{Timestamp: bucketStart, OpenPrice: "100", HighPrice: "104", LowPrice: "97", ClosePrice: "101", Volume: "6", TradeCount: 9}
+
+	candles, err := mergeCurrentCandle(ctx, store, stored, "BTCUSDT", []*models.Candle{previous, partial}, bucketStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 2 || candles[0] != previous {
+		t.Fatalf("Expected the previous candle and the merged one, got %d candles", len(candles))
+	}
+	want := models.Candle{Timestamp: bucketStart, OpenPrice: "100", HighPrice: "106", LowPrice: "97", ClosePrice: "105", Volume: "8", TradeCount: 11}
+	if got := *candles[1]; !got.Timestamp.Equal(want.Timestamp) || got.OpenPrice != want.OpenPrice || got.HighPrice != want.HighPrice ||
+		got.LowPrice != want.LowPrice || got.ClosePrice != want.ClosePrice || got.Volume != want.Volume || got.TradeCount != want.TradeCount {
+		t.Errorf("Merged candle = %+v, want %+v", got, want)
+	}
+
+	// Without the bucket among the stored candles, it is appended
+	candles, err = mergeCurrentCandle(ctx, store, stored, "BTCUSDT", []*models.Candle{previous}, bucketStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 2 || candles[1].ClosePrice != "105" || candles[1].TradeCount != 11 {
+		t.Errorf("Expected the current candle appended, got %+v", candles)
+	}
+}
+
+func TestPrintGaps(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 3, 0, 0, time.UTC)
+	gaps := []storage.TimeGap{
+		{Start: start, End: start.Add(2 * time.Minute), Duration: 2 * time.Minute},
+		{Start: start.Add(time.Hour), End: start.Add(time.Hour + 23*time.Minute), Duration: 23 * time.Minute},
+	}
+
+	var out bytes.Buffer
+	if err := printGaps(&out, "btcusdt", "7d", gaps, "table"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Data gaps for BTCUSDT (last 7d)", "2m0s", "23m0s", "2 gaps, 25m0s missing"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := printGaps(&out, "btcusdt", "7d", gaps, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	want := "start,end,duration_seconds\n2024-01-01T10:03:00Z,2024-01-01T10:05:00Z,120\n2024-01-01T11:03:00Z,2024-01-01T11:26:00Z,1380\n"
+	if out.String() != want {
+		t.Errorf("CSV gaps = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := printGaps(&out, "btcusdt", "7d", nil, "table"); err != nil || !strings.Contains(out.String(), "No gaps found") {
+		t.Errorf("Expected no gaps reported, got %q (%v)", out.String(), err)
+	}
+	if err := printGaps(&out, "btcusdt", "7d", gaps, "json"); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
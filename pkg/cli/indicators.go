@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/pkg/analysis"
+	"binance-redis-streamer/pkg/timeutil"
+)
+
+// Bounds of the indicators --period and --interval
+const (
+	maxIndicatorsPeriod   = 365 * 24 * time.Hour
+	maxIndicatorsInterval = 7 * 24 * time.Hour
+)
+
+// indicatorColumn is one printed indicator series, aligned with the candles
+type indicatorColumn struct {
+	name   string
+	values []float64
+}
+
+func newIndicatorsCmd() *cobra.Command {
+	var (
+		period    string
+		interval  string
+		smaPeriod int
+		emaPeriod int
+		rsiPeriod int
+		macd      bool
+		bbPeriod  int
+		bbStddev  float64
+		limit     int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "indicators [symbol]",
+		Short: "Compute technical indicators from candles",
+		Long: `Compute technical indicators (SMA, EMA, RSI, MACD and Bollinger Bands) from
+candle close prices and print them next to each candle. Extra candles before the
+period are loaded so indicators are warmed up from its first row; set a period
+to 0 to leave that indicator out.
+Example: binance-cli indicators BTCUSDT --period 7d --interval 1h --sma 20 --ema 12 --rsi 14`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			symbol, err := resolveSymbol(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			duration, err := timeutil.ParseDuration(period, maxIndicatorsPeriod)
+			if err != nil {
+				return fmt.Errorf("invalid period: %w", err)
+			}
+			step, err := timeutil.ParseDuration(interval, maxIndicatorsInterval)
+			if err != nil {
+				return fmt.Errorf("invalid interval: %w", err)
+			}
+
+			postgresStore, err := newPostgresStore(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+			}
+			defer postgresStore.Close()
+
+			lookback := indicatorLookback(smaPeriod, emaPeriod, rsiPeriod, macd, bbPeriod)
+
+			end := time.Now()
+			start := end.Add(-duration)
+			warmupStart := start.Add(-time.Duration(lookback) * step)
+
+			candles, err := postgresStore.GetAggregatedCandles(cmd.Context(), symbol, warmupStart, end, interval)
+			if err != nil {
+				return fmt.Errorf("failed to get historical data: %w", err)
+			}
+			if len(candles) == 0 {
+				return fmt.Errorf("no data found for %s in the specified period", symbol)
+			}
+
+			closes := make([]float64, len(candles))
+			for i, candle := range candles {
+				closes[i], err = strconv.ParseFloat(candle.ClosePrice, 64)
+				if err != nil {
+					return fmt.Errorf("invalid close price %q: %w", candle.ClosePrice, err)
+				}
+			}
+
+			columns, err := computeIndicators(closes, smaPeriod, emaPeriod, rsiPeriod, macd, bbPeriod, bbStddev)
+			if err != nil {
+				return err
+			}
+
+			// Skip the warm-up candles, then apply the row limit
+			first := 0
+			for first < len(candles) && candles[first].Timestamp.Before(start) {
+				first++
+			}
+			if limit > 0 && len(candles)-first > limit {
+				first = len(candles) - limit
+			}
+
+			fmt.Printf("Indicators for %s (%s intervals, last %s)\n", symbol, interval, period)
+			fmt.Println(strings.Repeat("-", 100))
+
+			fmt.Printf("%-20s %-12s", "Time", "Close")
+			for _, column := range columns {
+				fmt.Printf(" %-12s", column.name)
+			}
+			fmt.Println()
+			fmt.Println(strings.Repeat("-", 100))
+
+			for i := first; i < len(candles); i++ {
+				fmt.Printf("%-20s %-12s", candles[i].Timestamp.Format("2006-01-02 15:04:05"), candles[i].ClosePrice)
+				for _, column := range columns {
+					fmt.Printf(" %-12s", formatIndicator(column.values[i]))
+				}
+				fmt.Println()
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&period, "period", "p", "7d", "Time period (e.g., 24h, 7d, 2w, 3mo)")
+	cmd.Flags().StringVarP(&interval, "interval", "i", "1h", "Candle interval (e.g., 5m, 1h)")
+	cmd.Flags().IntVar(&smaPeriod, "sma", 20, "SMA period (0 to disable)")
+	cmd.Flags().IntVar(&emaPeriod, "ema", 12, "EMA period (0 to disable)")
+	cmd.Flags().IntVar(&rsiPeriod, "rsi", 14, "RSI period (0 to disable)")
+	cmd.Flags().BoolVar(&macd, "macd", true, "Show MACD (12, 26, 9)")
+	cmd.Flags().IntVar(&bbPeriod, "bb", 20, "Bollinger Bands period (0 to disable)")
+	cmd.Flags().Float64Var(&bbStddev, "bb-stddev", 2, "Bollinger Bands width in standard deviations")
+	cmd.Flags().IntVarP(&limit, "limit", "l", 0, "Limit the number of rows shown (0 for all)")
+
+	return cmd
+}
+
+// indicatorLookback returns how many candles the enabled indicators need
+// before they produce their first value
+func indicatorLookback(smaPeriod, emaPeriod, rsiPeriod int, macd bool, bbPeriod int) int {
+	lookback := smaPeriod
+	for _, n := range []int{emaPeriod, rsiPeriod + 1, bbPeriod} {
+		if n > lookback {
+			lookback = n
+		}
+	}
+	if macd && lookback < 26+9-1 {
+		lookback = 26 + 9 - 1
+	}
+	return lookback
+}
+
+// computeIndicators returns the enabled indicator columns for closes
+func computeIndicators(closes []float64, smaPeriod, emaPeriod, rsiPeriod int, macd bool, bbPeriod int, bbStddev float64) ([]indicatorColumn, error) {
+	var columns []indicatorColumn
+
+	if smaPeriod > 0 {
+		values, err := analysis.SMA(closes, smaPeriod)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, indicatorColumn{fmt.Sprintf("SMA(%d)", smaPeriod), values})
+	}
+	if emaPeriod > 0 {
+		values, err := analysis.EMA(closes, emaPeriod)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, indicatorColumn{fmt.Sprintf("EMA(%d)", emaPeriod), values})
+	}
+	if rsiPeriod > 0 {
+		values, err := analysis.RSI(closes, rsiPeriod)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, indicatorColumn{fmt.Sprintf("RSI(%d)", rsiPeriod), values})
+	}
+	if macd {
+		series, err := analysis.MACDSeries(closes, 12, 26, 9)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns,
+			indicatorColumn{"MACD", series.Line},
+			indicatorColumn{"Signal", series.Signal},
+			indicatorColumn{"Hist", series.Histogram},
+		)
+	}
+	if bbPeriod > 0 {
+		bands, err := analysis.Bollinger(closes, bbPeriod, bbStddev)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns,
+			indicatorColumn{"BB Upper", bands.Upper},
+			indicatorColumn{"BB Lower", bands.Lower},
+		)
+	}
+
+	return columns, nil
+}
+
+// formatIndicator renders an indicator value, with "-" during warm-up
+func formatIndicator(value float64) string {
+	if math.IsNaN(value) {
+		return "-"
+	}
+	return strconv.FormatFloat(value, 'f', 4, 64)
+}
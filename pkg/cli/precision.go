@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"context"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/storage"
+)
+
+// maxPriceDecimals bounds the decimals of prices without a known precision
+const maxPriceDecimals = 8
+
+// pricePrecisions holds the precisions of symbols, by upper-case symbol, as
+// cached in Redis by the streamer from exchangeInfo
+type pricePrecisions map[string]models.Precision
+
+// loadPricePrecisions reads the cached precisions. Without them prices are
+// formatted by magnitude, so failures are only logged in debug mode.
+func loadPricePrecisions(ctx context.Context, store *storage.RedisStore) pricePrecisions {
+	precisions, err := store.GetSymbolPrecisions(ctx)
+	if err != nil && configFromContext(ctx).Debug {
+		log.Printf("Symbol precisions unavailable: %v", err)
+	}
+	return precisions
+}
+
+// connectPricePrecisions is loadPricePrecisions for commands without a
+// Redis connection of their own
+func connectPricePrecisions(ctx context.Context) pricePrecisions {
+	var precisions pricePrecisions
+	err := withRedisStore(ctx, func(store *storage.RedisStore) error {
+		precisions = loadPricePrecisions(ctx, store)
+		return nil
+	})
+	if err != nil && configFromContext(ctx).Debug {
+		log.Printf("Symbol precisions unavailable: %v", err)
+	}
+	return precisions
+}
+
+// lookup returns the precision of symbol, or nil when it is not known
+func (p pricePrecisions) lookup(symbol string) *models.Precision {
+	if precision, ok := p[strings.ToUpper(symbol)]; ok {
+		return &precision
+	}
+	return nil
+}
+
+// priceDecimals returns the decimals of symbol's tick size, or -1 when it is
+// not known
+func (p pricePrecisions) priceDecimals(symbol string) int {
+	return precisionDecimals(p.lookup(symbol))
+}
+
+// precisionDecimals returns the price decimals of precision, or -1 when it
+// is nil
+func precisionDecimals(precision *models.Precision) int {
+	if precision == nil {
+		return -1
+	}
+	return precision.Price
+}
+
+// formatPrice formats a price of symbol with the decimals of its tick size
+func (p pricePrecisions) formatPrice(symbol string, price float64) string {
+	return formatPriceDecimals(price, p.priceDecimals(symbol))
+}
+
+// formatPriceString reformats a price of symbol stored as a decimal string,
+// such as "0.00001234000000", with the decimals of its tick size. Prices of
+// symbols without a known precision, and those that do not parse, are
+// returned as they are.
+func (p pricePrecisions) formatPriceString(symbol, price string) string {
+	decimals := p.priceDecimals(symbol)
+	if decimals < 0 {
+		return price
+	}
+	value, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return price
+	}
+	return formatPriceDecimals(value, decimals)
+}
+
+// formatPriceDecimals formats price with decimals, or when decimals is
+// negative with enough of them to show four significant digits of prices
+// below 1, so that low-priced symbols do not show as 0.00
+func formatPriceDecimals(price float64, decimals int) string {
+	if math.IsNaN(price) || math.IsInf(price, 0) {
+		return "-"
+	}
+	if decimals < 0 {
+		decimals = 2
+		if abs := math.Abs(price); abs > 0 && abs < 1 {
+			decimals = min(maxPriceDecimals, 3-int(math.Floor(math.Log10(abs))))
+		}
+	}
+	return strconv.FormatFloat(price, 'f', decimals, 64)
+}
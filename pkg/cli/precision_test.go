@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestPricePrecisions(t *testing.T) {
+	precisions := pricePrecisions{
+		"BTCUSDT":  {Price: 2, Quantity: 5},
+		"XRPUSDT":  {Price: 4, Quantity: 0},
+		"SHIBUSDT": {Price: 8, Quantity: 0},
+	}
+
+	tests := []struct {
+		symbol string
+		price  float64
+		stored string
+		want   string
+	}{
+		{"BTCUSDT", 97123.456, "97123.45600000", "97123.46"},
+		{"xrpusdt", 0.51234, "0.51230000", "0.5123"},
+		{"SHIBUSDT", 0.0000123456, "0.00001235000000", "0.00001235"},
+	}
+	for _, tt := range tests {
+		if got := precisions.formatPrice(tt.symbol, tt.price); got != tt.want {
+			t.Errorf("formatPrice(%s, %v) = %s, want %s", tt.symbol, tt.price, got, tt.want)
+		}
+		if got := precisions.formatPriceString(tt.symbol, tt.stored); got != tt.want {
+			t.Errorf("formatPriceString(%s, %s) = %s, want %s", tt.symbol, tt.stored, got, tt.want)
+		}
+	}
+
+	// Without a cached precision floats are formatted by magnitude and
+	// strings left alone
+	for price, want := range map[float64]string{97123.456: "97123.46", 0.51234: "0.5123", 0.0000123456: "0.00001235", 0: "0.00"} {
+		if got := precisions.formatPrice("NEWUSDT", price); got != want {
+			t.Errorf("formatPrice(NEWUSDT, %v) = %s, want %s", price, got, want)
+		}
+	}
+	if got := precisions.formatPriceString("NEWUSDT", "0.00001234000000"); got != "0.00001234000000" {
+		t.Errorf("Expected an unknown symbol's price kept, got %s", got)
+	}
+	if got := precisions.formatPriceString("BTCUSDT", "n/a"); got != "n/a" {
+		t.Errorf("Expected an unparsable price kept, got %s", got)
+	}
+}
+
+func TestSymbolMetricsFormatPrice(t *testing.T) {
+	m := &symbolMetrics{precision: &models.Precision{Price: 8}}
+	if got := m.formatPrice(0.0000123456); got != "0.00001235" {
+		t.Errorf("formatPrice() = %s, want 0.00001235", got)
+	}
+	// Before the precision is known, low prices still show their digits
+	if got := (&symbolMetrics{}).formatPrice(0.0000123456); got != "0.00001235" {
+		t.Errorf("formatPrice() without precision = %s, want 0.00001235", got)
+	}
+}
+
+func TestChartPagePriceFormat(t *testing.T) {
+	var page strings.Builder
+	if err := renderChart(&page, chartPage{Symbol: "SHIBUSDT", Period: "1d", PriceFormat: newChartPriceFormat(8)}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(page.String(), `{"type":"price","precision":8,"minMove":1e-8}`) {
+		t.Errorf("Expected the price format in the page:\n%s", page.String())
+	}
+
+	page.Reset()
+	if err := renderChart(&page, chartPage{Symbol: "NEWUSDT", Period: "1d", PriceFormat: newChartPriceFormat(-1)}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(page.String(), "const symbolPriceFormat =  null ;") {
+		t.Error("Expected no price format for an unknown precision")
+	}
+}
+
+func TestConnectPricePrecisions(t *testing.T) {
+	store, _, cfg := newMiniredisStore(t)
+	ctx := context.WithValue(context.Background(), configKey{}, cfg)
+	if err := store.StoreSymbolPrecisions(ctx, map[string]models.Precision{"SHIBUSDT": {Price: 8}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := connectPricePrecisions(ctx).priceDecimals("shibusdt"); got != 8 {
+		t.Errorf("Expected 8 decimals from Redis, got %d", got)
+	}
+}
@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/analysis"
+	"binance-redis-streamer/pkg/storage"
+	"binance-redis-streamer/pkg/timeutil"
+)
+
+const profileBarWidth = 50
+
+// maxProfilePeriod bounds --period of profile, which may load raw trades
+const maxProfilePeriod = 90 * 24 * time.Hour
+
+// Where a profile's volume came from
+const (
+	profileSourceTrades  = "trades"
+	profileSourceCandles = "candles"
+)
+
+func newProfileCmd() *cobra.Command {
+	var (
+		period string
+		bins   int
+		format string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "profile [symbol]",
+		Short: "View the volume profile (volume by price)",
+		Long: `View how traded volume is distributed across price levels, highlighting
+the point of control (the highest-volume price) and the value area (the levels
+around it holding 70% of the volume).
+Periods within the Redis retention window use raw trades; longer periods use
+1m candles from PostgreSQL, spreading each candle's volume evenly across its
+high-low range.
+Example: binance-cli profile BTCUSDT --period 24h --bins 50
+         binance-cli profile BTCUSDT --period 30d --format csv > profile.csv`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch format {
+			case "table", "csv", "json":
+			default:
+				return fmt.Errorf("unsupported format: %s", format)
+			}
+
+			symbol, err := resolveSymbol(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			duration, err := timeutil.ParseDuration(period, maxProfilePeriod)
+			if err != nil {
+				return fmt.Errorf("invalid period: %w", err)
+			}
+
+			end := time.Now()
+			start := end.Add(-duration)
+
+			profile, source, err := loadProfile(cmd.Context(), symbol, start, end, duration, bins)
+			if err != nil {
+				return err
+			}
+
+			switch format {
+			case "csv":
+				return writeProfileCSV(os.Stdout, profile)
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(newProfileDataset(symbol, period, source, profile))
+			default:
+				printProfile(symbol, period, source, profile)
+				return nil
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&period, "period", "p", "24h", "Time period (e.g., 1h, 24h, 7d, 2w, 3mo)")
+	cmd.Flags().IntVarP(&bins, "bins", "b", 50, "Number of price bins")
+	cmd.Flags().IntVar(&bins, "buckets", 50, "Number of price bins")
+	cmd.Flags().MarkDeprecated("buckets", "use --bins instead")
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "Output format (table, csv, or json)")
+
+	return cmd
+}
+
+// loadProfile profiles raw trades from Redis when its history covers the
+// whole period, and falls back to PostgreSQL 1m candles otherwise. It also
+// returns which of the two the profile was computed from.
+func loadProfile(ctx context.Context, symbol string, start, end time.Time, duration time.Duration, bins int) (*analysis.Profile, string, error) {
+	cfg := configFromContext(ctx)
+
+	if duration <= cfg.Redis.RetentionPeriod {
+		profile, ok, err := loadTradeProfile(ctx, symbol, start, end, bins)
+		if err != nil {
+			return nil, "", err
+		}
+		if ok {
+			return profile, profileSourceTrades, nil
+		}
+	}
+
+	postgresStore, err := newPostgresStore(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer postgresStore.Close()
+
+	candles, err := postgresStore.GetAggregatedCandles(ctx, symbol, start, end, "1m")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get historical data: %w", err)
+	}
+	if len(candles) == 0 {
+		return nil, "", fmt.Errorf("no data found for %s in the specified period", symbol)
+	}
+
+	profile, err := analysis.CandleVolumeProfile(candles, bins)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to compute volume profile: %w", err)
+	}
+	return profile, profileSourceCandles, nil
+}
+
+// profileDataset is the JSON form of a volume profile, printed by
+// --format json and sent to the chart
+type profileDataset struct {
+	Symbol         string         `json:"symbol"`
+	Period         string         `json:"period,omitempty"`
+	Source         string         `json:"source"`
+	PointOfControl float64        `json:"point_of_control"`
+	ValueAreaLow   float64        `json:"value_area_low"`
+	ValueAreaHigh  float64        `json:"value_area_high"`
+	TotalVolume    float64        `json:"total_volume"`
+	Levels         []profileLevel `json:"levels"` // Lowest price first
+}
+
+// profileLevel is one price level of a profileDataset
+type profileLevel struct {
+	Low       float64 `json:"low"`
+	High      float64 `json:"high"`
+	Volume    float64 `json:"volume"`
+	ValueArea bool    `json:"value_area"`
+}
+
+// newProfileDataset converts profile to its JSON form
+func newProfileDataset(symbol, period, source string, profile *analysis.Profile) *profileDataset {
+	dataset := &profileDataset{
+		Symbol:         symbol,
+		Period:         period,
+		Source:         source,
+		PointOfControl: profile.PointOfControl,
+		ValueAreaLow:   profile.ValueAreaLow,
+		ValueAreaHigh:  profile.ValueAreaHigh,
+		TotalVolume:    profile.TotalVolume,
+		Levels:         make([]profileLevel, len(profile.Levels)),
+	}
+	for i, level := range profile.Levels {
+		dataset.Levels[i] = profileLevel{Low: level.Low, High: level.High, Volume: level.Volume, ValueArea: profile.InValueArea(i)}
+	}
+	return dataset
+}
+
+// writeProfileCSV writes one row per level, lowest price first, flagging the
+// point of control and the value area
+func writeProfileCSV(w io.Writer, profile *analysis.Profile) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"low", "high", "volume", "poc", "value_area"})
+	for i, level := range profile.Levels {
+		cw.Write([]string{
+			strconv.FormatFloat(level.Low, 'f', -1, 64),
+			strconv.FormatFloat(level.High, 'f', -1, 64),
+			strconv.FormatFloat(level.Volume, 'f', -1, 64),
+			strconv.FormatBool(i == profile.POCIndex),
+			strconv.FormatBool(profile.InValueArea(i)),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// printProfile renders the profile as a horizontal bar table, highest price
+// first, with value area levels drawn solid and the rest shaded
+func printProfile(symbol, period, source string, profile *analysis.Profile) {
+	maxVolume := profile.Levels[profile.POCIndex].Volume
+
+	fmt.Printf("Volume profile for %s (last %s, from %s)\n", symbol, period, source)
+	fmt.Printf("Point of control: %.8f  Value area: %.8f - %.8f  Total volume: %.8f\n",
+		profile.PointOfControl, profile.ValueAreaLow, profile.ValueAreaHigh, profile.TotalVolume)
+	if source == profileSourceCandles {
+		fmt.Println("Approximated from 1m candles: each candle's volume is spread across its high-low range")
+	}
+	fmt.Println(strings.Repeat("-", 100))
+	fmt.Printf("%-27s %-15s %s\n", "Price", "Volume", "")
+	fmt.Println(strings.Repeat("-", 100))
+
+	for i := len(profile.Levels) - 1; i >= 0; i-- {
+		level := profile.Levels[i]
+
+		width := 0
+		if maxVolume > 0 {
+			width = int(math.Round(level.Volume / maxVolume * profileBarWidth))
+		}
+
+		bar := "░"
+		if profile.InValueArea(i) {
+			bar = "█"
+		}
+
+		marker := ""
+		switch {
+		case i == profile.POCIndex:
+			marker = " ◀ POC"
+		case i == profile.VAHIndex:
+			marker = " ◀ VAH"
+		case i == profile.VALIndex:
+			marker = " ◀ VAL"
+		}
+
+		fmt.Printf("%12.4f - %-12.4f %-15.4f %s%s\n",
+			level.Low, level.High, level.Volume, strings.Repeat(bar, width), marker)
+	}
+}
+
+// loadTradeProfile profiles the raw trades Redis holds for the period. It
+// reports false when the history was trimmed past start, since its trades
+// would then only cover the end of the period.
+func loadTradeProfile(ctx context.Context, symbol string, start, end time.Time, bins int) (*analysis.Profile, bool, error) {
+	redisStore, err := storage.NewRedisStore(configFromContext(ctx))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	defer redisStore.Close()
+
+	covered, err := redisStore.HistoryCovers(ctx, symbol, start)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get trade history: %w", err)
+	}
+	if !covered {
+		return nil, false, nil
+	}
+
+	var trades []*models.Trade
+	err = redisStore.ScanTradeHistory(ctx, symbol, start, end, func(event models.AggTradeEvent) error {
+		trades = append(trades, event.ToTrade())
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get trade history: %w", err)
+	}
+	if len(trades) == 0 {
+		return nil, false, fmt.Errorf("no data found for %s in the specified period", symbol)
+	}
+
+	profile, err := analysis.VolumeProfile(trades, bins)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to compute volume profile: %w", err)
+	}
+	return profile, true, nil
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/analysis"
+)
+
+func testProfile(t *testing.T) *analysis.Profile {
+	t.Helper()
+	trades := []*models.Trade{
+		{Price: "100", Quantity: "1"},
+		{Price: "130", Quantity: "1"},
+		{Price: "160", Quantity: "9"},
+		{Price: "200", Quantity: "1"},
+	}
+	profile, err := analysis.VolumeProfile(trades, 4)
+	if err != nil {
+		t.Fatalf("VolumeProfile() error = %v", err)
+	}
+	return profile
+}
+
+func TestWriteProfileCSV(t *testing.T) {
+	var out strings.Builder
+	if err := writeProfileCSV(&out, testProfile(t)); err != nil {
+		t.Fatalf("writeProfileCSV() error = %v", err)
+	}
+
+	want := `low,high,volume,poc,value_area
+100,125,1,false,false
+125,150,1,false,false
+150,175,9,true,true
+175,200,1,false,false
+`
+	if out.String() != want {
+		t.Errorf("writeProfileCSV() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestChartDataProfile(t *testing.T) {
+	data := chartData{Series: models.CandleSeries{Symbol: "BTCUSDT"}}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if strings.Contains(string(encoded), `"profile"`) {
+		t.Errorf("Expected no profile field without a profile, got %s", encoded)
+	}
+
+	data.Profile = newProfileDataset("BTCUSDT", "24h", profileSourceCandles, testProfile(t))
+	encoded, err = json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var decoded struct {
+		Profile profileDataset `json:"profile"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	profile := decoded.Profile
+	if profile.Source != profileSourceCandles || profile.PointOfControl != 162.5 || len(profile.Levels) != 4 {
+		t.Errorf("Unexpected profile %+v", profile)
+	}
+	if profile.ValueAreaLow != 150 || profile.ValueAreaHigh != 175 || !profile.Levels[2].ValueArea {
+		t.Errorf("Value area = %v-%v, want 150-175", profile.ValueAreaLow, profile.ValueAreaHigh)
+	}
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/pkg/storage"
+	"binance-redis-streamer/pkg/timeutil"
+)
+
+// maxRebuildPeriod bounds --period of rebuild-candles
+const maxRebuildPeriod = 30 * 24 * time.Hour
+
+func newRebuildCandlesCmd() *cobra.Command {
+	var (
+		symbol string
+		period string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rebuild-candles",
+		Short: "Backfill PostgreSQL candles from Redis trade history",
+		Long: `Recompute the minute candles of a symbol from the raw trades kept in Redis
+and store them in PostgreSQL, filling gaps left while the aggregator was down.
+Rebuilt candles are kept apart from the aggregator's, and are only replaced
+when a rebuild covers more trades, so running the command again is a no-op.
+Example: binance-cli rebuild-candles --symbol BTCUSDT --period 24h`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if symbol == "" {
+				return fmt.Errorf("--symbol is required")
+			}
+			duration, err := timeutil.ParseDuration(period, maxRebuildPeriod)
+			if err != nil {
+				return err
+			}
+			resolved, err := resolveSymbol(cmd.Context(), symbol)
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			end := time.Now()
+			start := end.Add(-duration)
+
+			var (
+				candles []*storage.RebuiltCandle
+				trades  int
+			)
+			err = withRedisStore(ctx, func(store *storage.RedisStore) error {
+				candles, trades, err = store.RebuildCandles(ctx, resolved, start, end)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if len(candles) == 0 {
+				fmt.Printf("No trades found for %s in the last %s\n", resolved, period)
+				return nil
+			}
+
+			pg, err := newPostgresStore(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+			}
+			defer pg.Close()
+
+			written := 0
+			for _, candle := range candles {
+				ok, err := pg.StoreRebuiltCandle(ctx, resolved, candle)
+				if err != nil {
+					return err
+				}
+				if ok {
+					written++
+				}
+			}
+
+			fmt.Printf("Rebuilt %d candles of %s from %d trades: %d written, %d already up to date\n",
+				len(candles), resolved, trades, written, len(candles)-written)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&symbol, "symbol", "", "Symbol to rebuild (e.g. BTCUSDT)")
+	cmd.Flags().StringVarP(&period, "period", "p", "24h", "How far back to rebuild (e.g., 1h, 24h, 7d)")
+	return cmd
+}
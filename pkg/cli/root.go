@@ -1,17 +1,79 @@
 package cli
 
 import (
+	"context"
+	"os"
+
 	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/exchange"
+	"binance-redis-streamer/pkg/storage"
+)
+
+// Global flags shared by all commands
+var (
+	exchangeName = exchange.DefaultName // Exchange whose data the commands read
+	configFile   string                 // Optional YAML config file
+	redisURL     string
+	postgresURL  string
+	debugMode    bool
+	offlineMode  bool // Skip exchange lookups such as symbol validation
 )
 
+// configKey stores the command configuration in the command context
+type configKey struct{}
+
 func NewRootCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "binance-cli",
 		Short: "Binance trade data CLI interface",
 		Long: `A command line interface for interacting with Binance trade data.
 Provides real-time data viewing, historical data analysis, and visualization capabilities.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.DefaultConfig()
+			if configFile != "" {
+				var err error
+				if cfg, err = config.LoadFile(configFile); err != nil {
+					return err
+				}
+			}
+			// A config file names its own exchange unless --exchange is given
+			if configFile == "" || cmd.Flags().Changed("exchange") {
+				cfg.SetExchange(exchangeName)
+			}
+			cfg.Debug = cfg.Debug || debugMode
+
+			// Precedence: --redis-url, then CUSTOM_REDIS_URL, then REDIS_URL
+			if url := os.Getenv("CUSTOM_REDIS_URL"); url != "" {
+				cfg.Redis.URL = url
+			}
+			if redisURL != "" {
+				cfg.Redis.URL = redisURL
+			}
+			// PostgreSQL stores read their URL from the environment
+			if postgresURL != "" {
+				if err := os.Setenv("DATABASE_URL", postgresURL); err != nil {
+					return err
+				}
+			}
+
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			cmd.SetContext(context.WithValue(ctx, configKey{}, cfg))
+			return nil
+		},
 	}
 
+	cmd.PersistentFlags().StringVar(&exchangeName, "exchange", exchange.DefaultName, "Exchange whose data to read")
+	cmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to a YAML config file (environment variables and flags override it)")
+	cmd.PersistentFlags().StringVar(&redisURL, "redis-url", "", "Redis URL (overrides CUSTOM_REDIS_URL and REDIS_URL)")
+	cmd.PersistentFlags().StringVar(&postgresURL, "postgres-url", "", "PostgreSQL URL (overrides DATABASE_URL)")
+	cmd.PersistentFlags().BoolVarP(&debugMode, "debug", "d", false, "Enable debug logging")
+	cmd.PersistentFlags().BoolVar(&offlineMode, "offline", false, "Validate symbols against Redis only, without querying the exchange")
+
 	// Add subcommands
 	cmd.AddCommand(
 		newWatchCmd(),
@@ -19,7 +81,45 @@ Provides real-time data viewing, historical data analysis, and visualization cap
 		newChartCmd(),
 		newHistoryCmd(),
 		newSymbolsCmd(),
+		newProfileCmd(),
+		newHealthCmd(),
+		newDLQCmd(),
+		newTapeCmd(),
+		newIndicatorsCmd(),
+		newConfigCmd(),
+		newAnomaliesCmd(),
+		newSummaryCmd(),
+		newRebuildCandlesCmd(),
+		newCorrCmd(),
+		newAlertCmd(),
+		newUptimeCmd(),
+		newBusTailCmd(),
+		newSnapshotCmd(),
 	)
 
 	return cmd
 }
+
+// configFromContext returns the configuration set up by the root command,
+// or the default configuration for the selected exchange outside a command
+func configFromContext(ctx context.Context) *config.Config {
+	if cfg, ok := ctx.Value(configKey{}).(*config.Config); ok {
+		return cfg
+	}
+	cfg := config.DefaultConfig()
+	cfg.SetExchange(exchangeName)
+	return cfg
+}
+
+// newPostgresStore connects to PostgreSQL scoped to the selected exchange
+func newPostgresStore(ctx context.Context) (*storage.PostgresStore, error) {
+	cfg := configFromContext(ctx)
+
+	store, err := storage.NewPostgresStore()
+	if err != nil {
+		return nil, err
+	}
+	store.SetExchange(cfg.Exchange)
+	store.SetDebug(cfg.Debug)
+	return store, nil
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+// runWithConfig executes the root command with args against a probe
+// subcommand and returns the configuration it received
+func runWithConfig(t *testing.T, args ...string) *config.Config {
+	t.Helper()
+
+	var got *config.Config
+	root := NewRootCmd()
+	root.AddCommand(&cobra.Command{
+		Use: "probe",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			got = configFromContext(cmd.Context())
+			return nil
+		},
+	})
+	root.SetArgs(append([]string{"probe"}, args...))
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	return got
+}
+
+func TestRootGlobalFlags(t *testing.T) {
+	t.Setenv("CUSTOM_REDIS_URL", "redis://custom:6379")
+	t.Setenv("DATABASE_URL", "postgres://env/db")
+
+	cfg := runWithConfig(t,
+		"--redis-url", "redis://flag:6379/2",
+		"--postgres-url", "postgres://flag/db",
+		"--exchange", "fake",
+		"--debug",
+	)
+
+	if cfg.Redis.URL != "redis://flag:6379/2" {
+		t.Errorf("Expected --redis-url to win, got %s", cfg.Redis.URL)
+	}
+	if got := os.Getenv("DATABASE_URL"); got != "postgres://flag/db" {
+		t.Errorf("Expected DATABASE_URL from --postgres-url, got %s", got)
+	}
+	if !cfg.Debug {
+		t.Error("Expected --debug to enable debug mode")
+	}
+	if cfg.Exchange != "fake" || cfg.Redis.KeyPrefix != "fake:" {
+		t.Errorf("Expected fake exchange config, got %s with prefix %s", cfg.Exchange, cfg.Redis.KeyPrefix)
+	}
+}
+
+func TestRootDefaultsFromEnvironment(t *testing.T) {
+	t.Setenv("CUSTOM_REDIS_URL", "redis://custom:6379")
+	t.Setenv("DATABASE_URL", "postgres://env/db")
+
+	cfg := runWithConfig(t)
+
+	if cfg.Redis.URL != "redis://custom:6379" {
+		t.Errorf("Expected CUSTOM_REDIS_URL without --redis-url, got %s", cfg.Redis.URL)
+	}
+	if got := os.Getenv("DATABASE_URL"); got != "postgres://env/db" {
+		t.Errorf("Expected DATABASE_URL to be left alone, got %s", got)
+	}
+	if cfg.Debug {
+		t.Error("Expected debug mode off by default")
+	}
+}
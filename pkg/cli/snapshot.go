@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/pkg/storage"
+)
+
+func newSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save and restore the Redis state",
+		Long: `Save the Redis keys of the streamer (latest trades, trade history, candles,
+alerts, the dead letter queue, ...) to a gzip-compressed file, and restore them
+after losing Redis. Keys are found with SCAN, so saving does not block Redis,
+but trades written meanwhile may or may not be included.
+Example: binance-cli snapshot save --out state.json.gz
+         binance-cli snapshot restore --in state.json.gz`,
+	}
+
+	cmd.AddCommand(newSnapshotSaveCmd(), newSnapshotRestoreCmd())
+	return cmd
+}
+
+func newSnapshotSaveCmd() *cobra.Command {
+	var out, filter string
+
+	cmd := &cobra.Command{
+		Use:   "save",
+		Short: "Save the Redis state to a file",
+		Long: `Save every string, hash, list, set and sorted set whose key starts with
+--filter (the configured key prefix by default), with its time to live, to
+--out. An existing file is replaced once the snapshot is complete.
+Example: binance-cli snapshot save --out state.json.gz --filter binance:trade:`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("filter") {
+				filter = configFromContext(cmd.Context()).Redis.KeyPrefix
+			}
+			return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+				// Write next to out and rename, so a failed save leaves the
+				// previous snapshot in place
+				tmp, err := os.CreateTemp(filepath.Dir(out), filepath.Base(out)+".*.tmp")
+				if err != nil {
+					return fmt.Errorf("failed to create snapshot: %w", err)
+				}
+				defer os.Remove(tmp.Name())
+
+				stats, err := store.SaveSnapshot(cmd.Context(), tmp, filter)
+				if err != nil {
+					tmp.Close()
+					return err
+				}
+				info, err := tmp.Stat()
+				if err == nil {
+					err = tmp.Close()
+				}
+				if err == nil {
+					err = os.Rename(tmp.Name(), out)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to write snapshot: %w", err)
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "Saved %s to %s (%s, %s uncompressed)\n",
+					formatSnapshotKeys(stats), out, formatSnapshotSize(info.Size()), formatSnapshotSize(stats.Bytes))
+				if stats.Skipped > 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "Skipped %d keys of other types or deleted while saving\n", stats.Skipped)
+				}
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&out, "out", "o", "", "File to write the snapshot to")
+	cmd.Flags().StringVar(&filter, "filter", "", "Only save keys starting with this prefix (default the configured key prefix)")
+	cmd.MarkFlagRequired("out")
+	return cmd
+}
+
+func newSnapshotRestoreCmd() *cobra.Command {
+	var in, filter string
+
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore the Redis state from a file",
+		Long: `Restore the keys of a snapshot whose names start with --filter (the
+configured key prefix by default). Keys of the same name are replaced, each
+in one transaction, and get the time to live they had left when saved. Other
+keys are left alone. Stop the streamer first, or restored keys may be
+overwritten by live trades.
+Example: binance-cli snapshot restore --in state.json.gz`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("filter") {
+				filter = configFromContext(cmd.Context()).Redis.KeyPrefix
+			}
+			f, err := os.Open(in)
+			if err != nil {
+				return fmt.Errorf("failed to open snapshot: %w", err)
+			}
+			defer f.Close()
+			info, err := f.Stat()
+			if err != nil {
+				return fmt.Errorf("failed to open snapshot: %w", err)
+			}
+
+			return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+				stats, err := store.RestoreSnapshot(cmd.Context(), f, filter)
+				if err != nil {
+					if stats != nil && stats.Total() > 0 {
+						fmt.Fprintf(cmd.OutOrStdout(), "Restored %s before failing\n", formatSnapshotKeys(stats))
+					}
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "Restored %s from %s (%s, %s uncompressed)\n",
+					formatSnapshotKeys(stats), in, formatSnapshotSize(info.Size()), formatSnapshotSize(stats.Bytes))
+				if stats.Skipped > 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "Skipped %d keys not starting with %q\n", stats.Skipped, filter)
+				}
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&in, "in", "i", "", "Snapshot file to restore")
+	cmd.Flags().StringVar(&filter, "filter", "", "Only restore keys starting with this prefix (default the configured key prefix)")
+	cmd.MarkFlagRequired("in")
+	return cmd
+}
+
+// formatSnapshotKeys describes the keys of a snapshot, as a total followed
+// by the count of each type
+func formatSnapshotKeys(stats *storage.SnapshotStats) string {
+	types := make([]string, 0, len(stats.Keys))
+	for typ := range stats.Keys {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	counts := make([]string, len(types))
+	for i, typ := range types {
+		counts[i] = fmt.Sprintf("%d %s", stats.Keys[typ], typ)
+	}
+	if len(counts) == 0 {
+		return "0 keys"
+	}
+	return fmt.Sprintf("%d keys: %s", stats.Total(), strings.Join(counts, ", "))
+}
+
+// formatSnapshotSize renders a size in bytes with a binary unit
+func formatSnapshotSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGT"[exp])
+}
@@ -1,58 +1,162 @@
 package cli
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	_ "github.com/lib/pq" // PostgreSQL driver
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
-	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/analysis"
 	"binance-redis-streamer/pkg/storage"
+	"binance-redis-streamer/pkg/timeutil"
 )
 
+// maxStatsPeriod is the longest --period stats accepts, and the furthest
+// back --compare-period can shift the compared window
+const maxStatsPeriod = 30 * 24 * time.Hour
+
+// statsATRPeriod is the number of minute candles the stats ATR averages over
+const statsATRPeriod = 14
+
+// statsDelta holds the percentage changes of a period's stats against a
+// reference period; a nil field means there is nothing to compare against
+type statsDelta struct {
+	Open   *float64
+	Volume *float64
+	Trades *float64
+}
+
+// compareStats returns the change of current against reference, which may
+// be nil when the reference period has no data
+func compareStats(reference, current *storage.TradeStats) statsDelta {
+	if reference == nil || current == nil {
+		return statsDelta{}
+	}
+
+	refOpen, _ := strconv.ParseFloat(reference.OpenPrice, 64)
+	curOpen, _ := strconv.ParseFloat(current.OpenPrice, 64)
+	refVolume, _ := strconv.ParseFloat(reference.TotalVolume, 64)
+	curVolume, _ := strconv.ParseFloat(current.TotalVolume, 64)
+
+	return statsDelta{
+		Open:   percentChange(refOpen, curOpen),
+		Volume: percentChange(refVolume, curVolume),
+		Trades: percentChange(float64(reference.TotalTrades), float64(current.TotalTrades)),
+	}
+}
+
+// periodChange returns the change from the open to the close of stats
+func periodChange(stats *storage.TradeStats) *float64 {
+	open, _ := strconv.ParseFloat(stats.OpenPrice, 64)
+	closePrice, _ := strconv.ParseFloat(stats.ClosePrice, 64)
+	return percentChange(open, closePrice)
+}
+
+// candleATR returns the latest average true range of candles, or nil when
+// there are too few candles for one
+func candleATR(candles []*models.Candle) *float64 {
+	highs := make([]float64, len(candles))
+	lows := make([]float64, len(candles))
+	closes := make([]float64, len(candles))
+	for i, candle := range candles {
+		highs[i], _ = strconv.ParseFloat(candle.HighPrice, 64)
+		lows[i], _ = strconv.ParseFloat(candle.LowPrice, 64)
+		closes[i], _ = strconv.ParseFloat(candle.ClosePrice, 64)
+	}
+
+	atr, err := analysis.ATR(highs, lows, closes, statsATRPeriod)
+	if err != nil || len(atr) == 0 || math.IsNaN(atr[len(atr)-1]) {
+		return nil
+	}
+	return &atr[len(atr)-1]
+}
+
+// latestIndicators renders the latest value of each indicator over candles,
+// "-" when there are too few candles for one
+func latestIndicators(candles []*models.Candle, indicators []analysis.Indicator) []string {
+	out := make([]string, len(indicators))
+	for i, ind := range indicators {
+		out[i] = "-"
+		if values, err := ind.Compute(candles); err == nil && len(values) > 0 {
+			out[i] = formatIndicator(values[len(values)-1])
+		}
+	}
+	return out
+}
+
 func newStatsCmd() *cobra.Command {
 	var period string
+	var comparePeriod string
 	var symbols []string
-	var debug bool
+	var indicatorSpec string
+	var heatmap bool
 
 	cmd := &cobra.Command{
 		Use:   "stats [symbols...]",
 		Short: "View trade statistics",
 		Long: `View trade statistics for specified symbols.
-Example: binance-cli stats --period 1h BTCUSDT ETHUSDT`,
+With --compare-period, each row also shows the change of the open, volume and
+trade count against the same-length period that far earlier: --period 1h
+--compare-period 1h compares with the hour before, --compare-period 24h with
+the same hour yesterday.
+Change is the move from the period's open to its close; ATR is the average
+true range of its last 14 minute candles. Spread is the average ask - bid
+over the period, at most the last 2 hours, when book tickers are streamed.
+--indicators adds the latest RSI, ATR or rolling z-score of the close over
+the period's minute candles, e.g. --indicators rsi:14,atr:14,zscore:20.
+--heatmap shows the Pearson correlations of the symbols' minute returns over
+the period instead, colored from red (below -0.7) through gray (near 0) to
+green (above 0.7); pairs sharing fewer than 30 minutes are N/A.
+Example: binance-cli stats --period 1h BTCUSDT ETHUSDT
+         binance-cli stats --period 4h --indicators rsi:14,zscore:60 BTCUSDT
+         binance-cli stats --period 24h --heatmap BTCUSDT ETHUSDT SOLUSDT`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) > 0 {
-				symbols = args
-			}
-
 			// Parse time period
-			duration, err := parseDuration(period)
+			duration, err := timeutil.ParseDuration(period, maxStatsPeriod)
 			if err != nil {
-				return fmt.Errorf("invalid period format: %w", err)
+				return fmt.Errorf("invalid period: %w", err)
+			}
+			indicators, err := analysis.ParseIndicators(indicatorSpec)
+			if err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				if symbols, err = resolveSymbols(cmd.Context(), args); err != nil {
+					return err
+				}
+			}
+			var shift time.Duration
+			if comparePeriod != "" {
+				shift, err = timeutil.ParseDuration(comparePeriod, maxStatsPeriod)
+				if err != nil {
+					return fmt.Errorf("invalid compare period: %w", err)
+				}
 			}
 
-			cfg := config.DefaultConfig()
+			cfg := configFromContext(cmd.Context())
+			debug := cfg.Debug
 			redisStore, err := storage.NewRedisStore(cfg)
 			if err != nil {
 				return fmt.Errorf("failed to connect to Redis: %w", err)
 			}
 			defer redisStore.Close()
 
-			postgresStore, err := storage.NewPostgresStore()
+			postgresStore, err := newPostgresStore(cmd.Context())
 			if err != nil {
 				return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 			}
 			defer postgresStore.Close()
 
-			// Set debug mode
-			postgresStore.SetDebug(debug)
-
-			ctx := context.Background()
+			ctx := cmd.Context()
 
 			// If no symbols provided, get all available symbols
 			if len(symbols) == 0 {
@@ -76,15 +180,43 @@ Example: binance-cli stats --period 1h BTCUSDT ETHUSDT`,
 				log.Printf("Symbols to query: %v", symbols)
 			}
 
-			fmt.Printf("Statistics for the last %s\n", period)
-			fmt.Println(strings.Repeat("-", 100))
-			fmt.Printf("%-10s %-12s %-12s %-12s %-12s %-15s %-10s\n",
-				"Symbol", "Open", "High", "Low", "Close", "Volume", "Trades")
-			fmt.Println(strings.Repeat("-", 100))
+			color := term.IsTerminal(int(os.Stdout.Fd()))
+			if heatmap {
+				if len(symbols) < 2 {
+					return fmt.Errorf("--heatmap needs at least two symbols, got %d", len(symbols))
+				}
+				matrix, err := loadCorrelations(ctx, postgresStore, symbols, start, end, heatmapInterval, heatmapStep, minHeatmapReturns)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Correlation of %s log returns (last %s)\n", heatmapInterval, period)
+				printCorrHeatmap(os.Stdout, symbols, matrix, color)
+				return nil
+			}
+
+			width := 165 + 13*len(indicators)
+			if shift > 0 {
+				width += 33
+				fmt.Printf("Statistics for the last %s, compared with the same period %s earlier\n", period, comparePeriod)
+			} else {
+				fmt.Printf("Statistics for the last %s\n", period)
+			}
+			fmt.Println(strings.Repeat("-", width))
+			fmt.Printf("%-10s %-12s %-12s %-12s %-12s %-9s %-12s %-8s %-10s %-15s %-10s %-12s %-10s",
+				"Symbol", "Open", "High", "Low", "Close", "Change", "ATR", "Range", "Spread", "Volume", "Trades", "Avg Size", "Trades/min")
+			for _, ind := range indicators {
+				fmt.Printf(" %-12s", ind)
+			}
+			if shift > 0 {
+				fmt.Printf(" %-10s %-10s %-10s", "Δopen%", "Δvolume%", "Δtrades%")
+			}
+			fmt.Println()
+			fmt.Println(strings.Repeat("-", width))
 
+			precisions := loadPricePrecisions(ctx, redisStore)
 			noDataFound := true
 			for _, symbol := range symbols {
-				candles, err := postgresStore.GetHistoricalCandles(ctx, symbol, start, end)
+				stats, err := redisStore.CachedTradeStats(ctx, postgresStore, symbol, start, end)
 				if err != nil {
 					if debug {
 						log.Printf("Error getting data for %s: %v", symbol, err)
@@ -92,7 +224,7 @@ Example: binance-cli stats --period 1h BTCUSDT ETHUSDT`,
 					continue
 				}
 
-				if len(candles) == 0 {
+				if stats == nil {
 					if debug {
 						log.Printf("No data found for %s in the specified period", symbol)
 					}
@@ -101,40 +233,83 @@ Example: binance-cli stats --period 1h BTCUSDT ETHUSDT`,
 
 				noDataFound = false
 
-				// Calculate aggregated statistics
-				first := candles[0]
-				last := candles[len(candles)-1]
-				high := first.HighPrice
-				low := first.LowPrice
-				volume := 0.0
-				trades := int64(0)
-
-				for _, candle := range candles {
-					if candle.HighPrice > high {
-						high = candle.HighPrice
+				volume, _ := strconv.ParseFloat(stats.TotalVolume, 64)
+				if debug {
+					log.Printf("Aggregated stats for %s: high=%s, low=%s, volume=%.2f, trades=%d",
+						symbol, stats.HighPrice, stats.LowPrice, volume, stats.TotalTrades)
+				}
+
+				// The range includes recent minutes not yet written as candles
+				priceRange := "-"
+				if rolling, err := redisStore.GetRollingRange(ctx, postgresStore, symbol, duration); err != nil {
+					if debug {
+						log.Printf("Error getting range for %s: %v", symbol, err)
 					}
-					if candle.LowPrice < low {
-						low = candle.LowPrice
+				} else if rolling != nil {
+					high, _ := strconv.ParseFloat(rolling.HighPrice, 64)
+					low, _ := strconv.ParseFloat(rolling.LowPrice, 64)
+					if low > 0 {
+						priceRange = fmt.Sprintf("%.2f%%", (high-low)/low*100)
 					}
-					v, _ := strconv.ParseFloat(candle.Volume, 64)
-					volume += v
-					trades += candle.TradeCount
 				}
 
-				if debug {
-					log.Printf("Aggregated stats for %s: high=%s, low=%s, volume=%.2f, trades=%d",
-						symbol, high, low, volume, trades)
+				// The ATR only needs the last statsATRPeriod true ranges;
+				// --indicators need the whole period
+				atr := "-"
+				var candles []*models.Candle
+				if len(indicators) > 0 {
+					candles, err = postgresStore.GetHistoricalCandles(ctx, symbol, start, end)
+				} else {
+					candles, err = postgresStore.GetLatestCandles(ctx, symbol, start, end, statsATRPeriod+1)
+				}
+				if err != nil {
+					if debug {
+						log.Printf("Error getting candles for %s: %v", symbol, err)
+					}
+				} else if value := candleATR(candles[max(0, len(candles)-statsATRPeriod-1):]); value != nil {
+					atr = fmt.Sprintf("%.6g", *value)
+				}
+
+				spread := "-"
+				if book, err := redisStore.GetSpreadStats(ctx, symbol, duration); err != nil {
+					if debug && !errors.Is(err, storage.ErrNotFound) {
+						log.Printf("Error getting spread for %s: %v", symbol, err)
+					}
+				} else {
+					spread = formatSpread(book.AvgSpread)
 				}
 
-				fmt.Printf("%-10s %-12s %-12s %-12s %-12s %-15.2f %-10d\n",
+				fmt.Printf("%-10s %-12s %-12s %-12s %-12s %s %-12s %-8s %-10s %-15.2f %-10d %-12.6f %-10.1f",
 					symbol,
-					first.OpenPrice,
-					high,
-					low,
-					last.ClosePrice,
+					precisions.formatPriceString(symbol, stats.OpenPrice),
+					precisions.formatPriceString(symbol, stats.HighPrice),
+					precisions.formatPriceString(symbol, stats.LowPrice),
+					precisions.formatPriceString(symbol, stats.ClosePrice),
+					formatDelta(periodChange(stats), 9, color),
+					atr,
+					priceRange,
+					spread,
 					volume,
-					trades,
+					stats.TotalTrades,
+					stats.AvgTradeSize,
+					stats.TradeIntensity,
 				)
+				for _, value := range latestIndicators(candles, indicators) {
+					fmt.Printf(" %-12s", value)
+				}
+				if shift > 0 {
+					reference, err := redisStore.CachedTradeStats(ctx, postgresStore, symbol, start.Add(-shift), end.Add(-shift))
+					if err != nil && debug {
+						log.Printf("Error getting reference data for %s: %v", symbol, err)
+					}
+					delta := compareStats(reference, stats)
+					fmt.Printf(" %s %s %s",
+						formatDelta(delta.Open, 10, color),
+						formatDelta(delta.Volume, 10, color),
+						formatDelta(delta.Trades, 10, color),
+					)
+				}
+				fmt.Println()
 			}
 
 			if noDataFound {
@@ -145,20 +320,11 @@ Example: binance-cli stats --period 1h BTCUSDT ETHUSDT`,
 		},
 	}
 
-	cmd.Flags().StringVarP(&period, "period", "p", "1h", "Time period (e.g., 1h, 24h, 7d)")
-	cmd.Flags().BoolVarP(&debug, "debug", "d", false, "Enable debug logging")
+	cmd.Flags().StringVarP(&period, "period", "p", "1h", "Time period (e.g., 1h, 24h, 7d, 2w, 3mo)")
+	cmd.Flags().StringVar(&comparePeriod, "compare-period", "", "Compare with the period this far earlier (e.g., 1h, 24h, 7d)")
+	cmd.Flags().StringVar(&indicatorSpec, "indicators", "", "Comma-separated indicator columns: rsi:N, atr:N, zscore:N (e.g. rsi:14,atr:14)")
+	cmd.Flags().BoolVar(&heatmap, "heatmap", false, "Show a colored correlation matrix of the symbols' minute returns instead")
+	cmd.MarkFlagsMutuallyExclusive("heatmap", "compare-period")
+	cmd.MarkFlagsMutuallyExclusive("heatmap", "indicators")
 	return cmd
 }
-
-func parseDuration(period string) (time.Duration, error) {
-	// Convert common formats to Go duration format
-	period = strings.ToLower(period)
-	if strings.HasSuffix(period, "d") {
-		days, err := strconv.Atoi(strings.TrimSuffix(period, "d"))
-		if err != nil {
-			return 0, err
-		}
-		return time.Duration(days) * 24 * time.Hour, nil
-	}
-	return time.ParseDuration(period)
-}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"binance-redis-streamer/pkg/analysis"
+)
+
+// The stats --heatmap correlates minute returns, and needs at least
+// minHeatmapReturns shared returns for a pair
+const (
+	heatmapInterval   = "1m"
+	heatmapStep       = time.Minute
+	minHeatmapReturns = 30
+)
+
+// heatmapShades are the ANSI 256 colors of correlations, from the strongest
+// negative to the strongest positive: red below -0.7, gray near 0 and green
+// above 0.7
+var heatmapShades = []struct {
+	below float64 // Shade correlations below this bound
+	color int
+}{
+	{-0.7, 196},
+	{-0.4, 167},
+	{-0.1, 138},
+	{0.1, 245},
+	{0.4, 108},
+	{0.7, 77},
+	{math.Inf(1), 46},
+}
+
+// heatmapColor returns the ANSI 256 color of correlation r
+func heatmapColor(r float64) int {
+	for _, shade := range heatmapShades {
+		if r < shade.below {
+			return shade.color
+		}
+	}
+	return heatmapShades[len(heatmapShades)-1].color
+}
+
+// formatHeatmapCell renders correlation r padded to width, colored when color
+// is set; pairs without a correlation are N/A
+func formatHeatmapCell(r float64, width int, color bool) string {
+	if math.IsNaN(r) {
+		return fmt.Sprintf("%*s", width, "N/A")
+	}
+	cell := fmt.Sprintf("%*.2f", width, r)
+	if !color {
+		return cell
+	}
+	return fmt.Sprintf("\033[38;5;%dm%s\033[0m", heatmapColor(r), cell)
+}
+
+// printCorrHeatmap prints the matrix with a row and a column per symbol, each
+// correlation colored on a gradient from red through gray to green
+func printCorrHeatmap(w io.Writer, symbols []string, matrix *analysis.CorrelationMatrix, color bool) {
+	width := 8
+	for _, symbol := range symbols {
+		width = max(width, len(symbol)+1)
+	}
+
+	fmt.Fprintf(w, "%-*s", width, "")
+	for _, symbol := range symbols {
+		fmt.Fprintf(w, " %*s", width, symbol)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, strings.Repeat("-", (width+1)*(len(symbols)+1)))
+
+	for i, symbol := range symbols {
+		fmt.Fprintf(w, "%-*s", width, symbol)
+		for j := range symbols {
+			fmt.Fprintf(w, " %s", formatHeatmapCell(matrix.Values[i][j], width, color))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if color {
+		fmt.Fprintf(w, "\nScale: %s %s %s %s %s\n",
+			formatHeatmapCell(-1, 5, true), formatHeatmapCell(-0.5, 5, true), formatHeatmapCell(0, 5, true),
+			formatHeatmapCell(0.5, 5, true), formatHeatmapCell(1, 5, true))
+	}
+	fmt.Fprintf(w, "N/A: fewer than %d overlapping minutes\n", minHeatmapReturns)
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/analysis"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func TestCompareStats(t *testing.T) {
+	reference := &storage.TradeStats{OpenPrice: "100", TotalVolume: "200", TotalTrades: 40}
+	current := &storage.TradeStats{OpenPrice: "110", TotalVolume: "150", TotalTrades: 40}
+
+	delta := compareStats(reference, current)
+	assertDelta(t, "open", delta.Open, 10)
+	assertDelta(t, "volume", delta.Volume, -25)
+	assertDelta(t, "trades", delta.Trades, 0)
+
+	// A reference period without data or volume has nothing to compare against
+	if delta := compareStats(nil, current); delta.Open != nil || delta.Volume != nil || delta.Trades != nil {
+		t.Errorf("Expected no deltas without reference stats, got %+v", delta)
+	}
+	empty := &storage.TradeStats{OpenPrice: "100", TotalVolume: "0"}
+	if delta := compareStats(empty, current); delta.Volume != nil || delta.Trades != nil {
+		t.Errorf("Expected no volume or trade deltas against zero, got %+v", delta)
+	}
+}
+
+func TestPeriodChange(t *testing.T) {
+	assertDelta(t, "rise", periodChange(&storage.TradeStats{OpenPrice: "200", ClosePrice: "250"}), 25)
+	assertDelta(t, "fall", periodChange(&storage.TradeStats{OpenPrice: "0.5", ClosePrice: "0.4"}), -20)
+	if change := periodChange(&storage.TradeStats{OpenPrice: "0", ClosePrice: "1"}); change != nil {
+		t.Errorf("Expected no change from a zero open, got %v", *change)
+	}
+}
+
+func TestCandleATR(t *testing.T) {
+	// Candles swinging 2 around a steady close of 100 have a true range of 2,
+	// until a gap up to 110 makes the last one 11
+	candles := make([]*models.Candle, statsATRPeriod+1)
+	for i := range candles {
+		candles[i] = &models.Candle{HighPrice: "101", LowPrice: "99", ClosePrice: "100"}
+	}
+	candles[statsATRPeriod] = &models.Candle{HighPrice: "111", LowPrice: "109", ClosePrice: "110"}
+
+	got := candleATR(candles)
+	want := (2*float64(statsATRPeriod-1) + 11) / statsATRPeriod
+	if got == nil {
+		t.Fatal("Expected an ATR, got nil")
+	}
+	if math.Abs(*got-want) > 1e-9 {
+		t.Errorf("Expected ATR %v, got %v", want, *got)
+	}
+
+	if got := candleATR(candles[:statsATRPeriod-1]); got != nil {
+		t.Errorf("Expected no ATR from %d candles, got %v", statsATRPeriod-1, *got)
+	}
+}
+
+func TestLatestIndicators(t *testing.T) {
+	// Closes rising from 100 to 104 only gain, and end sqrt(2) deviations
+	// above their mean of 102
+	candles := make([]*models.Candle, 5)
+	for i := range candles {
+		price := strconv.Itoa(100 + i)
+		candles[i] = &models.Candle{HighPrice: price, LowPrice: price, ClosePrice: price}
+	}
+	indicators, err := analysis.ParseIndicators("rsi:3,zscore:5,atr:10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := latestIndicators(candles, indicators)
+	want := []string{"100.0000", "1.4142", "-"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s = %s, want %s", indicators[i], got[i], want[i])
+		}
+	}
+
+	if got := latestIndicators(nil, indicators); got[0] != "-" {
+		t.Errorf("Expected no RSI without candles, got %s", got[0])
+	}
+}
+
+// minuteCloses returns a minute candle per close, starting at offset minutes
+func minuteCloses(offset int, closes ...float64) []*models.Candle {
+	start := time.Date(2024, 1, 1, 0, offset, 0, 0, time.UTC)
+	candles := make([]*models.Candle, len(closes))
+	for i, c := range closes {
+		candles[i] = &models.Candle{Timestamp: start.Add(time.Duration(i) * time.Minute), ClosePrice: strconv.FormatFloat(c, 'f', -1, 64)}
+	}
+	return candles
+}
+
+func TestHeatmapColor(t *testing.T) {
+	tests := []struct {
+		r    float64
+		want int
+	}{
+		{-1, 196}, {-0.75, 196}, {-0.5, 167}, {-0.2, 138}, {0, 245}, {0.05, 245}, {0.2, 108}, {0.5, 77}, {0.7, 46}, {1, 46},
+	}
+	for _, tt := range tests {
+		if got := heatmapColor(tt.r); got != tt.want {
+			t.Errorf("heatmapColor(%v) = %d, want %d", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestStatsHeatmap(t *testing.T) {
+	// BTCUSDT and ETHUSDT move together for 40 minutes, XRPUSDT against
+	// them, and SOLUSDT only overlaps them for 20 minutes
+	var btc, eth, xrp []float64
+	for i := 0; i <= 40; i++ {
+		move := 1 + 0.01*float64(i%3-1) + 0.002*float64(i%7)
+		btc = append(btc, 100*move)
+		eth = append(eth, 10*move)
+		xrp = append(xrp, 1/move)
+	}
+	store := fakeCandles{
+		"BTCUSDT": minuteCloses(0, btc...),
+		"ETHUSDT": minuteCloses(0, eth...),
+		"XRPUSDT": minuteCloses(0, xrp...),
+		"SOLUSDT": minuteCloses(20, btc...),
+	}
+	symbols := []string{"BTCUSDT", "ETHUSDT", "XRPUSDT", "SOLUSDT"}
+
+	matrix, err := loadCorrelations(context.Background(), store, symbols, time.Time{}, time.Now(), heatmapInterval, heatmapStep, minHeatmapReturns)
+	if err != nil {
+		t.Fatalf("loadCorrelations() error = %v", err)
+	}
+
+	var plain strings.Builder
+	printCorrHeatmap(&plain, symbols, matrix, false)
+	lines := strings.Split(plain.String(), "\n")
+	if strings.Contains(plain.String(), "\033") {
+		t.Errorf("Expected no colors when not on a terminal:\n%s", plain.String())
+	}
+	if !strings.Contains(lines[2], " 1.00 ") || !strings.Contains(lines[2], "-1.00") || strings.Count(lines[2], "N/A") != 1 {
+		t.Errorf("Unexpected BTCUSDT row: %q", lines[2])
+	}
+	if strings.Count(lines[5], "N/A") != 3 {
+		t.Errorf("Expected SOLUSDT N/A with the symbols it overlaps for 20 minutes: %q", lines[5])
+	}
+
+	var colored strings.Builder
+	printCorrHeatmap(&colored, symbols, matrix, true)
+	if !strings.Contains(colored.String(), "\033[38;5;46m") || !strings.Contains(colored.String(), "\033[38;5;196m") {
+		t.Errorf("Expected green and red cells:\n%s", colored.String())
+	}
+}
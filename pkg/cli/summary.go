@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"binance-redis-streamer/pkg/storage"
+)
+
+// maxSummaryDays bounds --days of summary
+const maxSummaryDays = 366
+
+// summaryRow is a daily candle with its change and the volume accumulated
+// since the first day shown
+type summaryRow struct {
+	Daily            *storage.DailyCandle
+	Change           *float64 // From the previous day's close, or the first day's open
+	CumulativeVolume float64
+}
+
+// summaryRows computes the change and cumulative volume of days, oldest first
+func summaryRows(days []*storage.DailyCandle) []summaryRow {
+	rows := make([]summaryRow, 0, len(days))
+	var cumulative float64
+	for i, day := range days {
+		reference, _ := strconv.ParseFloat(day.Candle.OpenPrice, 64)
+		if i > 0 {
+			reference, _ = strconv.ParseFloat(days[i-1].Candle.ClosePrice, 64)
+		}
+		closePrice, _ := strconv.ParseFloat(day.Candle.ClosePrice, 64)
+		volume, _ := strconv.ParseFloat(day.Candle.Volume, 64)
+		cumulative += volume
+
+		rows = append(rows, summaryRow{
+			Daily:            day,
+			Change:           percentChange(reference, closePrice),
+			CumulativeVolume: cumulative,
+		})
+	}
+	return rows
+}
+
+func newSummaryCmd() *cobra.Command {
+	var (
+		days   int
+		rollup bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "summary [symbol]",
+		Short: "Show daily OHLCV of a symbol",
+		Long: `Show one row per UTC day with open, high, low, close, the change from the
+previous day's close and the volume accumulated over the days shown. Daily
+candles are rolled up from minute candles by the streamer every
+postgres.rollup_interval; the current day is flagged as in progress.
+With --rollup the days are recomputed from minute candles first, e.g. for days
+before the rollup job ran.
+Example: binance-cli summary BTCUSDT --days 30`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if days <= 0 || days > maxSummaryDays {
+				return fmt.Errorf("days must be between 1 and %d", maxSummaryDays)
+			}
+			symbol, err := resolveSymbol(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			store, err := newPostgresStore(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+			}
+			defer store.Close()
+
+			ctx := cmd.Context()
+			end := time.Now().UTC()
+			start := end.AddDate(0, 0, -(days - 1))
+			if rollup {
+				for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+					if _, err := store.RollupDay(ctx, symbol, day); err != nil {
+						return err
+					}
+				}
+			}
+
+			daily, err := store.GetDailyCandles(ctx, symbol, start, end)
+			if err != nil {
+				return err
+			}
+			if len(daily) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "No daily candles for %s in the last %d days (try --rollup)\n", symbol, days)
+				return nil
+			}
+			printSummary(cmd.OutOrStdout(), symbol, summaryRows(daily), term.IsTerminal(int(os.Stdout.Fd())))
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&days, "days", 30, "Number of UTC days to show, including today")
+	cmd.Flags().BoolVar(&rollup, "rollup", false, "Recompute the days from minute candles before showing them")
+	return cmd
+}
+
+// printSummary prints daily rows as a table, flagging days in progress
+func printSummary(out io.Writer, symbol string, rows []summaryRow, color bool) {
+	fmt.Fprintf(out, "Daily summary for %s (UTC)\n\n", symbol)
+	fmt.Fprintf(out, "%-11s %14s %14s %14s %14s %-9s %16s %18s %10s\n",
+		"Day", "Open", "High", "Low", "Close", "Change", "Volume", "Cum. Volume", "Trades")
+	fmt.Fprintln(out, strings.Repeat("-", 130))
+
+	partial := false
+	for _, row := range rows {
+		c := row.Daily.Candle
+		day := c.Timestamp.Format(time.DateOnly)
+		if !row.Daily.Complete {
+			day += "*"
+			partial = true
+		}
+		volume, _ := strconv.ParseFloat(c.Volume, 64)
+		fmt.Fprintf(out, "%-11s %14s %14s %14s %14s %s %16s %18s %10d\n",
+			day, c.OpenPrice, c.HighPrice, c.LowPrice, c.ClosePrice,
+			formatDelta(row.Change, 9, color), formatVolume(volume), formatVolume(row.CumulativeVolume), c.TradeCount)
+	}
+
+	if partial {
+		fmt.Fprintln(out, "\n* day in progress")
+	}
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func dailyCandle(day time.Time, open, closePrice, volume string, complete bool) *storage.DailyCandle {
+	return &storage.DailyCandle{
+		Candle: models.Candle{
+			Timestamp:  day,
+			OpenPrice:  open,
+			HighPrice:  closePrice,
+			LowPrice:   open,
+			ClosePrice: closePrice,
+			Volume:     volume,
+			TradeCount: 10,
+		},
+		Minutes:  1440,
+		Complete: complete,
+	}
+}
+
+func TestSummaryRows(t *testing.T) {
+	day := time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC)
+	days := []*storage.DailyCandle{
+		dailyCandle(day, "100", "110", "5", true),
+		dailyCandle(day.AddDate(0, 0, 1), "111", "99", "10", true),
+		dailyCandle(day.AddDate(0, 0, 2), "99", "99", "2.5", false),
+	}
+
+	rows := summaryRows(days)
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(rows))
+	}
+	// The first day changes from its open, later days from the previous close
+	assertDelta(t, "first day", rows[0].Change, 10)
+	assertDelta(t, "second day", rows[1].Change, -10)
+	assertDelta(t, "third day", rows[2].Change, 0)
+
+	for i, want := range []float64{5, 15, 17.5} {
+		if rows[i].CumulativeVolume != want {
+			t.Errorf("Row %d cumulative volume = %v, want %v", i, rows[i].CumulativeVolume, want)
+		}
+	}
+
+	var out bytes.Buffer
+	printSummary(&out, "BTCUSDT", rows, false)
+	if !strings.Contains(out.String(), "2024-12-26*") || !strings.Contains(out.String(), "* day in progress") {
+		t.Errorf("Expected the day in progress to be flagged, got:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "2024-12-25*") {
+		t.Errorf("Expected complete days to be unflagged, got:\n%s", out.String())
+	}
+}
@@ -2,14 +2,20 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"binance-redis-streamer/pkg/binance"
 	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/exchange"
 	"binance-redis-streamer/pkg/storage"
+	"binance-redis-streamer/pkg/symbolutil"
 )
 
 func newSymbolsCmd() *cobra.Command {
@@ -21,7 +27,7 @@ func newSymbolsCmd() *cobra.Command {
 		Long: `List all available trading pairs that are being tracked.
 Example: binance-cli symbols --format table`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cfg := config.DefaultConfig()
+			cfg := configFromContext(cmd.Context())
 			store, err := storage.NewRedisStore(cfg)
 			if err != nil {
 				return fmt.Errorf("failed to connect to Redis: %w", err)
@@ -30,7 +36,7 @@ Example: binance-cli symbols --format table`,
 
 			// Get all symbols
 			symbolsKey := fmt.Sprintf("%ssymbols", cfg.Redis.KeyPrefix)
-			symbols, err := store.GetRedisClient().SMembers(context.Background(), symbolsKey).Result()
+			symbols, err := store.GetRedisClient().SMembers(cmd.Context(), symbolsKey).Result()
 			if err != nil {
 				return fmt.Errorf("failed to get symbols: %w", err)
 			}
@@ -42,36 +48,19 @@ Example: binance-cli symbols --format table`,
 			// Sort symbols for consistent output
 			sort.Strings(symbols)
 
-			// Get latest trades for all symbols
-			trades := make(map[string]struct {
-				Price     string
-				Volume24h string
-			})
-
-			for _, symbol := range symbols {
-				trade, err := store.GetLatestTrade(context.Background(), symbol)
-				if err != nil {
-					continue
-				}
-				if trade == nil {
-					continue
-				}
-
-				// Get 24h volume from Redis
-				volumeKey := fmt.Sprintf("%s%s:volume:24h", cfg.Redis.KeyPrefix, symbol)
-				volume, _ := store.GetRedisClient().Get(context.Background(), volumeKey).Result()
+			if cfg.Binance.UseTestnet && format == "table" {
+				printTestnetBanner()
+			}
 
-				trades[symbol] = struct {
-					Price     string
-					Volume24h string
-				}{
-					Price:     trade.Price,
-					Volume24h: volume,
-				}
+			// Get latest trades for all symbols
+			trades, err := latestQuotes(cmd.Context(), store, cfg.Redis.KeyPrefix, symbols)
+			if err != nil {
+				return err
 			}
 
 			switch format {
 			case "table":
+				precisions := loadPricePrecisions(cmd.Context(), store)
 				fmt.Printf("%-10s %-15s %-15s\n", "Symbol", "Price", "24h Volume")
 				fmt.Println(strings.Repeat("-", 42))
 
@@ -79,7 +68,7 @@ Example: binance-cli symbols --format table`,
 					if trade, ok := trades[symbol]; ok {
 						fmt.Printf("%-10s %-15s %-15s\n",
 							strings.ToUpper(symbol),
-							trade.Price,
+							precisions.formatPriceString(symbol, trade.Price),
 							trade.Volume24h,
 						)
 					}
@@ -117,5 +106,321 @@ Example: binance-cli symbols --format table`,
 	}
 
 	cmd.Flags().StringVarP(&format, "format", "f", "table", "Output format (table, simple, or json)")
+	cmd.AddCommand(newSymbolAddCmd(), newSymbolRemoveCmd(), newPriorityCmd(), newStreamTypeCmd())
+	return cmd
+}
+
+func newSymbolAddCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add [symbols...]",
+		Short: "Start streaming symbols without restarting the streamer",
+		Long: `Ask the running streamer to start streaming symbols. It checks them against
+Binance exchangeInfo and adds each to the connection carrying the fewest
+symbols, which reconnects with the new stream list. Added symbols survive
+symbol rediscovery but not a restart; use symbols priority add to keep them.
+Example: binance-cli symbols add SOLUSDT`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Symbols need not be tracked yet, only traded
+			symbols, err := validateSymbols(args, knownSymbols(cmd.Context(), false))
+			if err != nil {
+				return err
+			}
+			return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+				if err := store.RequestSymbolAdd(cmd.Context(), symbols...); err != nil {
+					return err
+				}
+				fmt.Printf("Requested streaming of %s\n", strings.Join(symbols, ", "))
+				return nil
+			})
+		},
+	}
+}
+
+func newSymbolRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove [symbols...]",
+		Short: "Stop streaming symbols without restarting the streamer",
+		Long: `Ask the running streamer to stop streaming symbols. Their connection
+reconnects with the remaining symbols; stored trades and candles are kept.
+Removed symbols stay unstreamed across symbol rediscovery until added again
+or the streamer restarts.
+Example: binance-cli symbols remove SOLUSDT`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			symbols, err := validateSymbols(args, nil)
+			if err != nil {
+				return err
+			}
+			return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+				if err := store.RequestSymbolRemove(cmd.Context(), symbols...); err != nil {
+					return err
+				}
+				fmt.Printf("Requested removal of %s\n", strings.Join(symbols, ", "))
+				return nil
+			})
+		},
+	}
+}
+
+func newPriorityCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "priority",
+		Short: "Manage priority symbols",
+		Long: `Manage the priority symbols stored in Redis. Priority symbols are merged with
+the configured main symbols, bypass volume filtering and are picked up by the
+streamer on its next symbol refresh.
+Example: binance-cli symbols priority add BTCUSDT`,
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "add [symbols...]",
+			Short: "Add priority symbols",
+			Args:  cobra.MinimumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				// Priority symbols need not be tracked yet, only traded
+				symbols, err := validateSymbols(args, knownSymbols(cmd.Context(), false))
+				if err != nil {
+					return err
+				}
+				return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+					if err := store.AddPrioritySymbols(cmd.Context(), symbols...); err != nil {
+						return err
+					}
+					fmt.Printf("Added priority symbols: %s\n", strings.Join(symbols, ", "))
+					return nil
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "remove [symbols...]",
+			Short: "Remove priority symbols",
+			Args:  cobra.MinimumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				symbols, err := validateSymbols(args, nil)
+				if err != nil {
+					return err
+				}
+				return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+					if err := store.RemovePrioritySymbols(cmd.Context(), symbols...); err != nil {
+						return err
+					}
+					fmt.Printf("Removed priority symbols: %s\n", strings.Join(symbols, ", "))
+					return nil
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "list",
+			Short: "List priority symbols",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+					symbols, err := store.GetPrioritySymbols(cmd.Context())
+					if err != nil {
+						return err
+					}
+					if len(symbols) == 0 {
+						fmt.Println("No priority symbols set")
+						return nil
+					}
+					for _, symbol := range symbols {
+						fmt.Println(symbol)
+					}
+					return nil
+				})
+			},
+		},
+	)
+
 	return cmd
 }
+
+func newStreamTypeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stream-type",
+		Short: "Manage per-symbol stream types",
+		Long: fmt.Sprintf(`Manage which Binance stream each symbol is streamed with: %s (every
+trade), %s (trades aggregated by price and taker) or %s (24h statistics
+every second, without trades). Symbols without an override use
+binance.default_stream_type. The streamer picks up changes the next time it
+connects the symbol's stream.
+Example: binance-cli symbols stream-type set BTCUSDT aggTrade`,
+			config.StreamTypeTrade, config.StreamTypeAggTrade, config.StreamTypeMiniTicker),
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "set [symbol] [type]",
+			Short: "Set the stream type of a symbol",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				streamType, err := parseStreamType(args[1])
+				if err != nil {
+					return err
+				}
+				symbols, err := validateSymbols(args[:1], knownSymbols(cmd.Context(), false))
+				if err != nil {
+					return err
+				}
+				return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+					if err := store.SetStreamType(cmd.Context(), symbols[0], streamType); err != nil {
+						return err
+					}
+					fmt.Printf("%s streams %s\n", symbols[0], streamType)
+					return nil
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "get [symbol]",
+			Short: "Show the stream type of a symbol",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				symbols, err := validateSymbols(args, nil)
+				if err != nil {
+					return err
+				}
+				return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+					streamType, err := store.GetStreamType(cmd.Context(), symbols[0])
+					if err != nil {
+						return err
+					}
+					if streamType == "" {
+						fmt.Printf("%s streams %s (default)\n", symbols[0], configFromContext(cmd.Context()).Binance.DefaultStreamType)
+						return nil
+					}
+					fmt.Printf("%s streams %s\n", symbols[0], streamType)
+					return nil
+				})
+			},
+		},
+	)
+
+	return cmd
+}
+
+// parseStreamType matches a stream type case-insensitively, so aggtrade
+// names aggTrade
+func parseStreamType(arg string) (string, error) {
+	for _, streamType := range []string{config.StreamTypeTrade, config.StreamTypeAggTrade, config.StreamTypeMiniTicker} {
+		if strings.EqualFold(arg, streamType) {
+			return streamType, nil
+		}
+	}
+	return "", fmt.Errorf("invalid stream type %q: must be %s, %s or %s",
+		arg, config.StreamTypeTrade, config.StreamTypeAggTrade, config.StreamTypeMiniTicker)
+}
+
+// symbolQuote is the latest price and 24h volume of a symbol
+type symbolQuote struct {
+	Price     string
+	Volume24h string
+}
+
+// latestQuotes returns the quote of each of symbols that has trades. Symbols
+// without trades are left out; a Redis outage fails the whole lookup.
+func latestQuotes(ctx context.Context, store *storage.RedisStore, keyPrefix string, symbols []string) (map[string]symbolQuote, error) {
+	quotes := make(map[string]symbolQuote, len(symbols))
+	for _, symbol := range symbols {
+		trade, err := store.GetLatestTrade(ctx, symbol)
+		if errors.Is(err, storage.ErrUnavailable) {
+			return nil, err
+		}
+		if err != nil {
+			if !errors.Is(err, storage.ErrNotFound) {
+				log.Printf("Warning: skipping %s: %v", symbol, err)
+			}
+			continue
+		}
+
+		// Get 24h volume from Redis
+		volumeKey := fmt.Sprintf("%s%s:volume:24h", keyPrefix, symbol)
+		volume, _ := store.GetRedisClient().Get(ctx, volumeKey).Result()
+
+		quotes[symbol] = symbolQuote{Price: trade.Price, Volume24h: volume}
+	}
+	return quotes, nil
+}
+
+// withRedisStore connects to Redis for the duration of fn
+func withRedisStore(ctx context.Context, fn func(store *storage.RedisStore) error) error {
+	store, err := storage.NewRedisStore(configFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	defer store.Close()
+
+	return fn(store)
+}
+
+// symbolLookupTimeout bounds each lookup of known symbols
+const symbolLookupTimeout = 10 * time.Second
+
+// resolveSymbols normalizes symbol arguments and checks them against the
+// symbols tracked in Redis and, unless --offline is set, the symbols Binance
+// trades. Unknown symbols return a *symbolutil.UnknownSymbolError with near
+// matches; when no source can be reached the arguments are only normalized.
+func resolveSymbols(ctx context.Context, args []string) ([]string, error) {
+	return validateSymbols(args, knownSymbols(ctx, true))
+}
+
+// resolveSymbol is resolveSymbols for a single argument
+func resolveSymbol(ctx context.Context, arg string) (string, error) {
+	symbols, err := resolveSymbols(ctx, []string{arg})
+	if err != nil {
+		return "", err
+	}
+	return symbols[0], nil
+}
+
+// validateSymbols normalizes args and, when known is not empty, checks them
+// against it
+func validateSymbols(args, known []string) ([]string, error) {
+	symbols := make([]string, len(args))
+	for i, arg := range args {
+		var err error
+		if len(known) == 0 {
+			symbols[i], err = symbolutil.Normalize(arg)
+		} else {
+			symbols[i], err = symbolutil.Validate(arg, known)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return symbols, nil
+}
+
+// knownSymbols returns the symbols tracked in Redis, when tracked is set, and
+// unless --offline is set those Binance trades. Unreachable sources are
+// skipped.
+func knownSymbols(ctx context.Context, tracked bool) []string {
+	cfg := configFromContext(ctx)
+	var known []string
+
+	if tracked {
+		err := withRedisStore(ctx, func(store *storage.RedisStore) error {
+			lookupCtx, cancel := context.WithTimeout(ctx, symbolLookupTimeout)
+			defer cancel()
+			symbols, err := store.GetRedisClient().SMembers(lookupCtx, fmt.Sprintf("%ssymbols", cfg.Redis.KeyPrefix)).Result()
+			known = append(known, symbols...)
+			return err
+		})
+		if err != nil && cfg.Debug {
+			log.Printf("Tracked symbols unavailable for validation: %v", err)
+		}
+	}
+
+	if !offlineMode && cfg.Exchange == exchange.DefaultName {
+		lookupCtx, cancel := context.WithTimeout(ctx, symbolLookupTimeout)
+		defer cancel()
+		symbols, err := binance.NewClient(cfg, nil).ListSymbols(lookupCtx)
+		if err != nil && cfg.Debug {
+			log.Printf("Exchange symbols unavailable for validation: %v", err)
+		}
+		known = append(known, symbols...)
+	}
+	return known
+}
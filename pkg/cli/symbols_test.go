@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/exchange"
+	"binance-redis-streamer/pkg/storage"
+	"binance-redis-streamer/pkg/symbolutil"
+)
+
+// newMiniredisStore returns a store backed by a fresh miniredis
+func newMiniredisStore(t *testing.T) (*storage.RedisStore, *miniredis.Miniredis, *config.Config) {
+	t.Helper()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		store.Close()
+		mr.Close()
+	})
+	return store, mr, cfg
+}
+
+func TestLatestQuotes_SkipsSymbolsWithoutTrades(t *testing.T) {
+	store, _, cfg := newMiniredisStore(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	if err := store.StoreTrade(ctx, &models.Trade{Symbol: "BTCUSDT", Price: "50000", Quantity: "1", Time: now, EventTime: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	quotes, err := latestQuotes(ctx, store, cfg.Redis.KeyPrefix, []string{"BTCUSDT", "ETHUSDT"})
+	if err != nil {
+		t.Fatalf("A symbol without trades should not fail the listing: %v", err)
+	}
+	if len(quotes) != 1 || quotes["BTCUSDT"].Price != "50000" {
+		t.Errorf("Expected only BTCUSDT to be quoted, got %+v", quotes)
+	}
+}
+
+func TestLatestQuotes_RedisDown(t *testing.T) {
+	store, mr, cfg := newMiniredisStore(t)
+	mr.Close()
+
+	if _, err := latestQuotes(context.Background(), store, cfg.Redis.KeyPrefix, []string{"BTCUSDT"}); !errors.Is(err, storage.ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable with Redis down, got %v", err)
+	}
+}
+
+func TestResolveSymbols_Offline(t *testing.T) {
+	store, _, cfg := newMiniredisStore(t)
+	ctx := context.WithValue(context.Background(), configKey{}, cfg)
+
+	offlineMode = true
+	defer func() { offlineMode = false }()
+
+	// Without tracked symbols there is nothing to check against
+	if symbols, err := resolveSymbols(ctx, []string{"btc/usdt", "eth-usdt"}); err != nil || symbols[0] != "BTCUSDT" || symbols[1] != "ETHUSDT" {
+		t.Errorf("Expected normalized symbols, got %v (%v)", symbols, err)
+	}
+
+	now := time.Now()
+	if err := store.StoreTrade(ctx, &models.Trade{Symbol: "BTCUSDT", Price: "50000", Quantity: "1", Time: now, EventTime: now}); err != nil {
+		t.Fatal(err)
+	}
+	if symbol, err := resolveSymbol(ctx, "btcusdt"); err != nil || symbol != "BTCUSDT" {
+		t.Errorf("Expected BTCUSDT, got %q (%v)", symbol, err)
+	}
+
+	_, err := resolveSymbols(ctx, []string{"BTCUSDT", "BTCUDST"})
+	var unknown *symbolutil.UnknownSymbolError
+	if !errors.As(err, &unknown) || len(unknown.Suggestions) != 1 || unknown.Suggestions[0] != "BTCUSDT" {
+		t.Errorf("Expected BTCUDST to be unknown with BTCUSDT suggested, got %v", err)
+	}
+	if _, err := resolveSymbols(ctx, []string{"BTC$"}); !errors.Is(err, exchange.ErrBadSymbol) {
+		t.Errorf("Expected ErrBadSymbol for a malformed symbol, got %v", err)
+	}
+}
+
+func TestParseStreamType(t *testing.T) {
+	for arg, want := range map[string]string{"aggtrade": "aggTrade", "TRADE": "trade", "miniTicker": "miniTicker"} {
+		if got, err := parseStreamType(arg); err != nil || got != want {
+			t.Errorf("parseStreamType(%q) = %q, %v, want %q", arg, got, err, want)
+		}
+	}
+	if _, err := parseStreamType("depth"); err == nil {
+		t.Error("Expected an error for an unsupported stream type")
+	}
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/messaging"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func newTapeCmd() *cobra.Command {
+	var (
+		minSize float64
+		last    int
+		follow  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "tape [symbol]",
+		Short: "Show the time-and-sales tape of a symbol",
+		Long: `Show individual trades of a symbol as a time-and-sales tape: time, price, size
+and taker side. Prints the last N trades from Redis history, then with --follow
+keeps printing trades as they arrive.
+Example: binance-cli tape BTCUSDT --min-size 0.5 --follow`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			symbol, err := resolveSymbol(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			cfg := configFromContext(cmd.Context())
+
+			store, err := storage.NewRedisStore(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to connect to Redis: %w", err)
+			}
+			defer store.Close()
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, os.Interrupt)
+			go func() {
+				<-sigCh
+				cancel()
+			}()
+
+			color := term.IsTerminal(int(os.Stdout.Fd()))
+			out := cmd.OutOrStdout()
+
+			trades, err := loadTape(ctx, store, symbol, cfg.Redis.RetentionPeriod, minSize, last)
+			if err != nil {
+				return err
+			}
+
+			if cfg.Binance.UseTestnet {
+				printTestnetBanner()
+			}
+			fmt.Fprintf(out, "%-12s %14s %14s  %s\n", "Time", "Price", "Size", "Side")
+			fmt.Fprintln(out, strings.Repeat("-", 50))
+			for _, trade := range trades {
+				fmt.Fprintln(out, formatTapeLine(trade, color))
+			}
+
+			if !follow {
+				return nil
+			}
+
+			bus := messaging.NewRedisPubSub(store.GetRedisClient())
+			err = bus.SubscribeSymbol(ctx, symbol, func(env *messaging.Envelope) error {
+				if tapeMatches(env.Payload.Data, symbol, minSize) {
+					fmt.Fprintln(out, formatTapeLine(env.Payload.Data, color))
+				}
+				return nil
+			})
+			if err != nil && ctx.Err() == nil {
+				return fmt.Errorf("failed to follow trades: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Float64Var(&minSize, "min-size", 0, "Only show trades of at least this size")
+	cmd.Flags().IntVar(&last, "last", 20, "Number of recent trades to show before following (0 for none)")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Keep printing trades as they arrive")
+
+	return cmd
+}
+
+// loadTape returns the last trades of symbol within window that are at least
+// minSize, oldest first
+func loadTape(ctx context.Context, store *storage.RedisStore, symbol string, window time.Duration, minSize float64, last int) ([]models.TradeData, error) {
+	if last <= 0 {
+		return nil, nil
+	}
+
+	end := time.Now()
+	events, err := store.GetTradeHistory(ctx, symbol, end.Add(-window), end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trade history: %w", err)
+	}
+
+	// History is newest first; keep the newest matches and flip them
+	trades := make([]models.TradeData, 0, last)
+	for _, event := range events {
+		if len(trades) == last {
+			break
+		}
+		if tapeMatches(event.Data, symbol, minSize) {
+			trades = append(trades, event.Data)
+		}
+	}
+	for i, j := 0, len(trades)-1; i < j; i, j = i+1, j-1 {
+		trades[i], trades[j] = trades[j], trades[i]
+	}
+	return trades, nil
+}
+
+// tapeMatches reports whether a trade belongs on the tape of symbol
+func tapeMatches(trade models.TradeData, symbol string, minSize float64) bool {
+	if !strings.EqualFold(trade.Symbol, symbol) {
+		return false
+	}
+	if minSize <= 0 {
+		return true
+	}
+	size, err := strconv.ParseFloat(trade.Quantity, 64)
+	return err == nil && size >= minSize
+}
+
+// formatTapeLine renders a trade as a tape row, colored by taker side when color is set
+func formatTapeLine(trade models.TradeData, color bool) string {
+	side := "BUY"
+	if trade.IsBuyerMaker {
+		side = "SELL"
+	}
+
+	line := fmt.Sprintf("%-12s %14s %14s  %s",
+		time.UnixMilli(trade.TradeTime).Local().Format("15:04:05.000"),
+		trade.Price, trade.Quantity, side)
+	if !color {
+		return line
+	}
+	if trade.IsBuyerMaker {
+		return ansiRed + line + ansiReset
+	}
+	return ansiGreen + line + ansiReset
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func TestTapeMatches(t *testing.T) {
+	trade := models.TradeData{Symbol: "BTCUSDT", Quantity: "0.5"}
+
+	tests := []struct {
+		name    string
+		symbol  string
+		minSize float64
+		want    bool
+	}{
+		{"no filter", "BTCUSDT", 0, true},
+		{"symbol case-insensitive", "btcusdt", 0, true},
+		{"other symbol", "ETHUSDT", 0, false},
+		{"at min size", "BTCUSDT", 0.5, true},
+		{"below min size", "BTCUSDT", 0.6, false},
+	}
+	for _, tt := range tests {
+		if got := tapeMatches(trade, tt.symbol, tt.minSize); got != tt.want {
+			t.Errorf("%s: tapeMatches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if tapeMatches(models.TradeData{Symbol: "BTCUSDT", Quantity: "bad"}, "BTCUSDT", 0.1) {
+		t.Error("Expected unparseable size to be filtered out")
+	}
+}
+
+func TestLoadTape_LastN(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	sizes := []string{"0.1", "2.0", "0.3", "1.5", "3.0", "0.2"}
+	for i, size := range sizes {
+		err := store.StoreTrade(ctx, &models.Trade{
+			Symbol:       "BTCUSDT",
+			Price:        "50000",
+			Quantity:     size,
+			TradeID:      int64(i + 1),
+			Time:         base.Add(time.Duration(i) * time.Second),
+			EventTime:    base.Add(time.Duration(i) * time.Second),
+			IsBuyerMaker: i%2 == 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trades, err := loadTape(ctx, store, "BTCUSDT", time.Hour, 1.0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 || trades[0].TradeID != 4 || trades[1].TradeID != 5 {
+		t.Fatalf("Expected trades [4 5] oldest first, got %+v", trades)
+	}
+	if !trades[0].IsBuyerMaker || trades[1].IsBuyerMaker {
+		t.Errorf("Expected taker side to survive history round trip, got %+v", trades)
+	}
+
+	all, err := loadTape(ctx, store, "BTCUSDT", time.Hour, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(sizes) || all[0].TradeID != 1 {
+		t.Errorf("Expected all %d trades oldest first, got %d", len(sizes), len(all))
+	}
+
+	line := formatTapeLine(trades[0], false)
+	if !strings.Contains(line, "1.5") || !strings.HasSuffix(line, "SELL") {
+		t.Errorf("Unexpected tape line %q", line)
+	}
+	if colored := formatTapeLine(trades[1], true); !strings.HasPrefix(colored, ansiGreen) {
+		t.Errorf("Expected buy to be green, got %q", colored)
+	}
+}
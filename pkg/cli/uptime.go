@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/storage"
+	"binance-redis-streamer/pkg/timeutil"
+)
+
+// maxUptimePeriod bounds the uptime --period
+const maxUptimePeriod = 365 * 24 * time.Hour
+
+// connectionEventLoader reads the connection event log
+type connectionEventLoader interface {
+	GetConnectionEvents(ctx context.Context) ([]*models.ConnectionEvent, error)
+}
+
+func newUptimeCmd() *cobra.Command {
+	var (
+		period string
+		limit  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "uptime",
+		Short: "Show stream downtime from the connection event log",
+		Long: `Summarize the stream connects and disconnects the streamer logged to Redis
+over a period: the share of time every connection was up, the total downtime,
+the number of outages and the longest one, followed by the most recent
+outages. An outage lasts from a connection dropping until it delivers
+messages again; outages of different connections that overlap count once
+toward the downtime. Outages still open are marked with a +.
+Example: binance-cli uptime --period 7d`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			window, err := timeutil.ParseDuration(period, maxUptimePeriod)
+			if err != nil {
+				return fmt.Errorf("invalid period: %w", err)
+			}
+
+			return withRedisStore(cmd.Context(), func(store *storage.RedisStore) error {
+				report, covered, err := loadUptime(cmd.Context(), store, window, time.Now())
+				if err != nil {
+					return err
+				}
+				printUptime(cmd.OutOrStdout(), report, covered, limit)
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&period, "period", "p", "7d", "Period to summarize (e.g., 24h, 7d, 30d)")
+	cmd.Flags().IntVarP(&limit, "limit", "l", 20, "Maximum number of outages to list (0 for all)")
+	return cmd
+}
+
+// loadUptime summarizes the connection events of the period before now. It
+// also returns the time of the oldest logged event when that falls inside
+// the period, as older events may have been trimmed from the log.
+func loadUptime(ctx context.Context, store connectionEventLoader, period time.Duration, now time.Time) (*storage.UptimeReport, time.Time, error) {
+	events, err := store.GetConnectionEvents(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	start := now.Add(-period)
+	var covered time.Time
+	if len(events) > 0 && events[0].Time.After(start) {
+		covered = events[0].Time
+	}
+	return storage.SummarizeUptime(events, start, now), covered, nil
+}
+
+// printUptime prints the summary of report and up to limit of its most
+// recent outages, newest first
+func printUptime(out io.Writer, report *storage.UptimeReport, covered time.Time, limit int) {
+	fmt.Fprintf(out, "Period:          %s - %s\n",
+		report.Start.Local().Format(time.DateTime), report.End.Local().Format(time.DateTime))
+	if !covered.IsZero() {
+		fmt.Fprintf(out, "Logged since:    %s\n", covered.Local().Format(time.DateTime))
+	}
+	fmt.Fprintf(out, "Uptime:          %.3f%%\n", report.Uptime()*100)
+	fmt.Fprintf(out, "Downtime:        %s\n", report.Downtime.Round(time.Second))
+	fmt.Fprintf(out, "Outages:         %d\n", len(report.Outages))
+	if len(report.Outages) == 0 {
+		return
+	}
+	longest := report.Longest
+	fmt.Fprintf(out, "Longest outage:  %s (%s at %s)\n",
+		longest.Duration().Round(time.Second), longest.Group, longest.Start.Local().Format(time.DateTime))
+
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "%-20s %-12s %-16s %s\n", "Start", "Duration", "Group", "Error")
+	fmt.Fprintln(out, strings.Repeat("-", 80))
+	for i := len(report.Outages) - 1; i >= 0; i-- {
+		if limit > 0 && len(report.Outages)-i > limit {
+			break
+		}
+		outage := report.Outages[i]
+		duration := outage.Duration().Round(time.Second).String()
+		if outage.Open {
+			duration += "+"
+		}
+		fmt.Fprintf(out, "%-20s %-12s %-16s %s\n",
+			outage.Start.Local().Format(time.DateTime), duration, outage.Group, outage.Error)
+	}
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestLoadUptime(t *testing.T) {
+	store, _, _ := newMiniredisStore(t)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	seed := []models.ConnectionEvent{
+		{Kind: models.ConnectionUp, Group: "btcusdt-2", Time: now.Add(-6 * 24 * time.Hour)},
+		{Kind: models.ConnectionDown, Group: "btcusdt-2", Time: now.Add(-48 * time.Hour), Error: "i/o timeout"},
+		{Kind: models.ConnectionUp, Group: "btcusdt-2", Time: now.Add(-48*time.Hour + 5*time.Minute)},
+		{Kind: models.ConnectionDown, Group: "btcusdt-2", Time: now.Add(-time.Hour), Error: "connection reset by peer"},
+		{Kind: models.ConnectionUp, Group: "btcusdt-2", Time: now.Add(-time.Hour + 30*time.Second)},
+		{Kind: models.ConnectionDown, Group: "ethusdt-2", Time: now.Add(-10 * time.Minute), Error: "EOF"},
+	}
+	for i := range seed {
+		if err := store.RecordConnectionEvent(ctx, &seed[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, covered, err := loadUptime(ctx, store, 7*24*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !covered.Equal(seed[0].Time) {
+		t.Errorf("Expected the log to cover the period from %v, got %v", seed[0].Time, covered)
+	}
+	if want := 5*time.Minute + 30*time.Second + 10*time.Minute; report.Downtime != want {
+		t.Errorf("Downtime = %v, want %v", report.Downtime, want)
+	}
+	if len(report.Outages) != 3 || report.Longest.Duration() != 10*time.Minute || report.Longest.Group != "ethusdt-2" {
+		t.Errorf("Unexpected outages %+v, longest %+v", report.Outages, report.Longest)
+	}
+
+	var buf bytes.Buffer
+	printUptime(&buf, report, covered, 2)
+	out := buf.String()
+	for _, want := range []string{"Downtime:        15m30s", "Outages:         3", "Longest outage:  10m0s (ethusdt-2", "10m0s+", "connection reset by peer"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "i/o timeout") {
+		t.Errorf("Expected only the 2 most recent outages:\n%s", out)
+	}
+
+	// Over the last day, the outage two days ago is out of the period
+	report, covered, err = loadUptime(ctx, store, 24*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !covered.IsZero() || len(report.Outages) != 2 || report.Downtime != 10*time.Minute+30*time.Second {
+		t.Errorf("Unexpected report for the last day: %+v, covered from %v", report, covered)
+	}
+}
@@ -2,7 +2,9 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
@@ -13,23 +15,27 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
+	"binance-redis-streamer/internal/models"
 	"binance-redis-streamer/pkg/config"
 	"binance-redis-streamer/pkg/storage"
+	"binance-redis-streamer/pkg/timeutil"
 )
 
 type symbolMetrics struct {
 	lastPrice     float64
 	prevPrice     float64
-	high24h       float64
-	low24h        float64
+	rangeHigh     float64 // Highest price over the range window
+	rangeLow      float64 // Lowest price over the range window
 	vwap          float64
 	lastTradeTime time.Time
 	tradesPerMin  float64
 	initialized   bool
+	precision     *models.Precision // Tick size decimals; nil formats prices by magnitude
 
 	// Price action metrics
-	priceRange    float64 // 24h range as percentage
+	priceRange    float64 // Window range as percentage
 	rangePosition float64 // Where in the range current price sits (0-100%)
 	volatility    float64 // Standard deviation of returns
 	vwapDev       float64 // Deviation from VWAP as percentage
@@ -42,7 +48,9 @@ type symbolMetrics struct {
 	tradeAccel   float64 // Trade frequency acceleration
 
 	// Market microstructure
-	orderImbalance float64 // Buy volume - Sell volume / Total volume
+	orderImbalance float64 // Top-of-book imbalance, or Buy volume - Sell volume / Total volume without book tickers
+	avgSpread      float64 // Average ask - bid, when book tickers are stored
+	hasSpread      bool
 	marketImpact   float64 // Price movement per unit of volume
 
 	// Historical data for calculations
@@ -51,39 +59,69 @@ type symbolMetrics struct {
 	recentTrades  []float64
 }
 
+// maxWatchWindow bounds --window of watch
+const maxWatchWindow = 365 * 24 * time.Hour
+
+// rangeWindow is the rolling window watch shows the price range over
+type rangeWindow struct {
+	label    string
+	duration time.Duration
+	older    storage.RangeQuerier // Candles beyond the Redis history; nil without PostgreSQL
+}
+
 func newWatchCmd() *cobra.Command {
 	var interval int
 	var symbols []string
-	var debug bool
+	var push bool
+	var enableNotifications bool
+	var window string
+	var tape bool
+	var tapeDepth int
+	var filterPattern, excludePattern string
+	var filter symbolFilter
 
 	cmd := &cobra.Command{
 		Use:   "watch [symbols...]",
 		Short: "Watch real-time trade data",
 		Long: `Watch real-time trade data for specified symbols.
-Example: binance-cli watch BTCUSDT ETHUSDT`,
+With --push (the default) the display only refreshes when new trades arrive,
+using Redis keyspace notifications; if the server does not send them it polls
+instead. watch only changes the server's configuration with
+--enable-notifications, which sets notify-keyspace-events KEA; otherwise
+enable them in redis.conf or at the Redis provider.
+Keys: p pauses and resumes, s cycles the sort order, f filters symbols by a
+substring, + and - change the interval and q quits.
+The price range covers --window: recent minutes come from Redis and older
+ones from PostgreSQL candles when a database is reachable.
+With --tape the last --tape-depth trades of the shown symbols are listed
+below them, newest at the bottom, shortened to fit the terminal.
+--filter and --exclude-filter select the symbols stored in Redis by regular
+expression; symbols given as arguments are always shown.
+Example: binance-cli watch BTCUSDT ETHUSDT --window 4h
+         binance-cli watch BTCUSDT ETHUSDT --tape --tape-depth 30
+         binance-cli watch --filter '^BTC' --exclude-filter 'UP|DOWN'`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			filter, err = newSymbolFilter(filterPattern, excludePattern)
+			return err
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) > 0 {
-				symbols = args
+			duration, err := timeutil.ParseDuration(window, maxWatchWindow)
+			if err != nil {
+				return fmt.Errorf("invalid window: %w", err)
 			}
-
-			cfg := config.DefaultConfig()
-			cfg.Debug = debug
-
-			// First check for custom Redis URL (highest priority for local development)
-			if redisURL := os.Getenv("CUSTOM_REDIS_URL"); redisURL != "" {
-				if debug {
-					log.Printf("Using custom Redis URL from CUSTOM_REDIS_URL")
-				}
-				cfg.Redis.URL = redisURL
-			} else if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
-				// Then check for Heroku Redis URL
-				if debug {
-					log.Printf("Using Heroku Redis URL from REDIS_URL")
+			if tape && tapeDepth <= 0 {
+				return fmt.Errorf("--tape-depth must be positive")
+			}
+			if len(args) > 0 {
+				if symbols, err = resolveSymbols(cmd.Context(), args); err != nil {
+					return err
 				}
-				cfg.Redis.URL = redisURL
-			} else if debug {
-				log.Printf("Warning: Neither CUSTOM_REDIS_URL nor REDIS_URL set, using default: %s", cfg.Redis.URL)
 			}
+			rw := &rangeWindow{label: window, duration: duration}
+
+			cfg := configFromContext(cmd.Context())
+			debug := cfg.Debug
 
 			store, err := storage.NewRedisStore(cfg)
 			if err != nil {
@@ -91,8 +129,18 @@ Example: binance-cli watch BTCUSDT ETHUSDT`,
 			}
 			defer store.Close()
 
+			// Without PostgreSQL the range only reaches as far back as Redis
+			if postgresStore, err := newPostgresStore(cmd.Context()); err != nil {
+				if debug {
+					log.Printf("PostgreSQL unavailable, ranges use Redis only: %v", err)
+				}
+			} else {
+				defer postgresStore.Close()
+				rw.older = postgresStore
+			}
+
 			// Setup signal handling
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithCancel(cmd.Context())
 			defer cancel()
 
 			sigCh := make(chan os.Signal, 1)
@@ -102,18 +150,20 @@ Example: binance-cli watch BTCUSDT ETHUSDT`,
 				cancel()
 			}()
 
-			// If no symbols provided, get all available symbols
-			if len(symbols) == 0 {
+			// Without symbols, or with a filter to pick them by, get all
+			// available symbols
+			var available []string
+			if len(symbols) == 0 || filter.active() {
 				symbolsKey := fmt.Sprintf("%ssymbols", cfg.Redis.KeyPrefix)
 				if debug {
 					log.Printf("Looking for symbols in Redis key: %s", symbolsKey)
 				}
-				symbols, err = store.GetRedisClient().SMembers(ctx, symbolsKey).Result()
+				available, err = store.GetRedisClient().SMembers(ctx, symbolsKey).Result()
 				if err != nil {
 					return fmt.Errorf("failed to get symbols: %w", err)
 				}
 				if debug {
-					log.Printf("Found symbols: %v", symbols)
+					log.Printf("Found symbols: %v", available)
 				}
 			}
 
@@ -121,53 +171,190 @@ Example: binance-cli watch BTCUSDT ETHUSDT`,
 			for i := range symbols {
 				symbols[i] = strings.ToUpper(symbols[i])
 			}
+			for i := range available {
+				available[i] = strings.ToUpper(available[i])
+			}
+			symbols = filter.selectSymbols(symbols, available)
 
 			if len(symbols) == 0 {
 				return fmt.Errorf("no symbols found to watch")
 			}
 
 			// Initialize metrics for each symbol
+			precisions := loadPricePrecisions(ctx, store)
 			metrics := make(map[string]*symbolMetrics)
 			for _, symbol := range symbols {
-				metrics[symbol] = &symbolMetrics{}
+				metrics[symbol] = &symbolMetrics{precision: precisions.lookup(symbol)}
 			}
 
 			// Clear screen and hide cursor
 			fmt.Print("\033[2J\033[H\033[?25l")
 			defer fmt.Print("\033[?25h") // Show cursor on exit
 
-			ticker := time.NewTicker(time.Duration(interval) * time.Second)
+			// Keyboard controls redirect stdout, so size the screen by the
+			// terminal's own descriptor
+			screen := int(os.Stdout.Fd())
+			color := term.IsTerminal(screen)
+
+			state := &watchState{interval: time.Duration(interval) * time.Second}
+			keys := make(chan byte)
+			if restore, err := startKeyboard(ctx, keys); err != nil {
+				if debug {
+					log.Printf("Keyboard controls unavailable: %v", err)
+				}
+			} else {
+				defer restore()
+			}
+
+			// In push mode ticks only refresh once trades have arrived
+			var updates <-chan struct{}
+			if push {
+				if enableNotifications {
+					if err := store.EnableKeyspaceNotifications(ctx); err != nil {
+						log.Printf("Warning: %v", err)
+					}
+				}
+				updates, err = watchSymbolUpdates(ctx, store, symbols)
+				if err != nil && debug {
+					log.Printf("Push updates unavailable, polling instead: %v", err)
+				}
+			}
+			changed := true
+
+			render := func() {
+				fmt.Print("\033[H") // Move cursor to top
+				out := &lineCounter{w: os.Stdout}
+				printHeader(out, cfg.Binance.UseTestnet)
+
+				// Fetch every symbol's latest trade in one round trip
+				fetchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+				latest, err := store.GetLatestTrades(fetchCtx, symbols)
+				cancel()
+				if err != nil {
+					// An outage is shown on screen; it is not the same as a quiet symbol
+					if errors.Is(err, storage.ErrUnavailable) || errors.Is(err, storage.ErrCorruptData) {
+						fmt.Fprintf(out, "\033[K\033[1;31m%v, retrying...\033[0m\n", err)
+					} else if debug {
+						log.Printf("Error getting latest trades: %v", err)
+					}
+				} else {
+					for _, symbol := range state.visibleSymbols(symbols, metrics) {
+						if err := updateAndDisplayMetrics(ctx, out, store, rw, symbol, latest[symbol], metrics[symbol], cfg); err != nil {
+							if debug && !errors.Is(err, storage.ErrNotFound) {
+								log.Printf("Error updating metrics for %s: %v", symbol, err)
+							}
+						}
+					}
+				}
+
+				if tape {
+					height := 0
+					if _, h, err := term.GetSize(screen); err == nil {
+						height = h
+					}
+					if rows := tapeRows(tapeDepth, height, out.lines); rows > 0 {
+						fetchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+						trades, err := loadWatchTape(fetchCtx, store, state.visibleSymbols(symbols, metrics), cfg.Redis.RetentionPeriod, rows)
+						cancel()
+						if err != nil {
+							if debug {
+								log.Printf("Error getting tape: %v", err)
+							}
+						} else {
+							printWatchTape(out, trades, color)
+						}
+					}
+				}
+
+				// Remember where the status bar goes, for redraws while paused
+				fmt.Print("\0337")
+				printStatusBar(state)
+			}
+
+			ticker := time.NewTicker(state.interval)
 			defer ticker.Stop()
 
 			for {
 				select {
 				case <-ctx.Done():
 					return nil
+				case key := <-keys:
+					switch state.handleKey(key) {
+					case keyQuit:
+						return nil
+					case keyNone:
+						continue
+					case keyInterval:
+						ticker.Reset(state.interval)
+					}
+					if state.paused {
+						printStatusBar(state)
+					} else {
+						render()
+					}
+				case <-updates:
+					changed = true
 				case <-ticker.C:
-					fmt.Print("\033[H") // Move cursor to top
-					printHeader()
-
-					for _, symbol := range symbols {
-						if err := updateAndDisplayMetrics(ctx, store, symbol, metrics[symbol], cfg); err != nil {
-							if debug {
-								log.Printf("Error updating metrics for %s: %v", symbol, err)
-							}
-							continue
-						}
+					if state.paused || (updates != nil && !changed) {
+						continue
 					}
+					changed = false
+					render()
 				}
 			}
 		},
 	}
 
 	cmd.Flags().IntVarP(&interval, "interval", "i", 1, "Update interval in seconds")
-	cmd.Flags().BoolVarP(&debug, "debug", "d", false, "Enable debug logging")
+	cmd.Flags().BoolVar(&push, "push", true, "Refresh only on trade updates via Redis keyspace notifications")
+	cmd.Flags().BoolVar(&enableNotifications, "enable-notifications", false, "Turn on keyspace notifications on the Redis server (CONFIG SET notify-keyspace-events KEA) for --push")
+	cmd.Flags().StringVarP(&window, "window", "w", "24h", "Price range window (e.g., 1h, 4h, 7d)")
+	cmd.Flags().BoolVar(&tape, "tape", false, "Show the latest trades of the watched symbols below them")
+	cmd.Flags().IntVar(&tapeDepth, "tape-depth", 20, "Number of trades on the tape")
+	cmd.Flags().StringVar(&filterPattern, "filter", "", "Only show stored symbols matching this regular expression (e.g. ^BTC, USDT$)")
+	cmd.Flags().StringVar(&excludePattern, "exclude-filter", "", "Hide stored symbols matching this regular expression")
 	return cmd
 }
 
-func printHeader() {
-	fmt.Println("Press Ctrl+C to exit")
-	fmt.Println()
+// watchSymbolUpdates checks that keyspace notifications are on and returns a
+// channel signalled whenever the latest trade of any of symbols changes
+func watchSymbolUpdates(ctx context.Context, store *storage.RedisStore, symbols []string) (<-chan struct{}, error) {
+	if err := store.CheckKeyspaceNotifications(ctx); err != nil {
+		return nil, err
+	}
+
+	updates := make(chan struct{}, 1)
+	for _, symbol := range symbols {
+		trades, err := store.WatchTradeUpdates(ctx, symbol)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			for range trades {
+				select {
+				case updates <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+	return updates, nil
+}
+
+// testnetBanner makes it obvious that data comes from the Binance testnet
+const testnetBanner = "\033[1;33m*** TESTNET — data from testnet.binance.vision, not production ***\033[0m"
+
+func printHeader(out io.Writer, testnet bool) {
+	if testnet {
+		fmt.Fprintln(out, testnetBanner)
+	}
+	fmt.Fprintln(out, "Press Ctrl+C to exit")
+	fmt.Fprintln(out)
+}
+
+// printTestnetBanner prints testnetBanner
+func printTestnetBanner() {
+	fmt.Println(testnetBanner)
 }
 
 func formatFloat(f float64, decimals int) string {
@@ -178,6 +365,12 @@ func formatFloat(f float64, decimals int) string {
 	return fmt.Sprintf(format, f)
 }
 
+// formatSpread formats a price spread with 6 significant digits, since
+// spreads range from whole dollars to fractions of a satoshi
+func formatSpread(spread float64) string {
+	return fmt.Sprintf("%.6g", spread)
+}
+
 // formatVolume formats volume with K/M/B suffixes
 func formatVolume(volume float64) string {
 	if volume >= 1_000_000_000 {
@@ -192,50 +385,45 @@ func formatVolume(volume float64) string {
 	return fmt.Sprintf("%.2f", volume)
 }
 
-func updateAndDisplayMetrics(ctx context.Context, store *storage.RedisStore, symbol string, m *symbolMetrics, cfg *config.Config) error {
+// updateAndDisplayMetrics refreshes and prints symbol's row to out from its
+// pre-fetched latest trade, which is nil when the symbol has no fresh trade
+func updateAndDisplayMetrics(ctx context.Context, out io.Writer, store *storage.RedisStore, rw *rangeWindow, symbol string, trade *models.Trade, m *symbolMetrics, cfg *config.Config) error {
 	// Create a context with timeout for Redis operations
 	timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	// Get latest trade
-	trade, err := store.GetLatestTrade(timeoutCtx, symbol)
-	if err != nil {
-		if cfg.Debug {
-			log.Printf("Error getting latest trade for %s: %v", symbol, err)
-		}
-		return fmt.Errorf("no trade data: %w", err)
-	}
 	if trade == nil {
 		if cfg.Debug {
-			log.Printf("No latest trade found for %s in Redis", symbol)
+			log.Printf("No fresh trade found for %s in Redis", symbol)
 		}
-		return fmt.Errorf("no trade data available for %s", symbol)
+		printStaleRow(out, symbol, m)
+		return fmt.Errorf("no trade data available for %s: %w", symbol, storage.ErrNotFound)
 	}
 
 	// Update basic metrics from latest trade
 	price, _ := strconv.ParseFloat(trade.Price, 64)
 	if !m.initialized {
 		m.prevPrice = price
-		m.high24h = price
-		m.low24h = price
 		m.initialized = true
 	} else {
 		m.prevPrice = m.lastPrice
-		// Update high/low only if we don't have history
-		if m.high24h == 0 {
-			m.high24h = price
-		} else if price > m.high24h {
-			m.high24h = price
-		}
-		if m.low24h == 0 {
-			m.low24h = price
-		} else if price < m.low24h {
-			m.low24h = price
-		}
 	}
 	m.lastPrice = price
 	m.lastTradeTime = trade.Time
 
+	// Range over the window from Redis minutes and older candles, falling
+	// back to the latest price when there is none
+	m.rangeHigh, m.rangeLow = price, price
+	rolling, err := store.GetRollingRange(timeoutCtx, rw.older, symbol, rw.duration)
+	if err != nil {
+		if cfg.Debug {
+			log.Printf("Failed to get %s range for %s: %v", rw.label, symbol, err)
+		}
+	} else if rolling != nil {
+		m.rangeHigh, _ = strconv.ParseFloat(rolling.HighPrice, 64)
+		m.rangeLow, _ = strconv.ParseFloat(rolling.LowPrice, 64)
+	}
+
 	// Try to get recent history (last 15 minutes for display)
 	end := time.Now()
 	start := end.Add(-15 * time.Minute)
@@ -288,14 +476,6 @@ func updateAndDisplayMetrics(ctx context.Context, store *storage.RedisStore, sym
 		} else {
 			buyVol += quoteVolume
 		}
-
-		// Update high/low prices
-		if p > m.high24h {
-			m.high24h = p
-		}
-		if p < m.low24h || m.low24h == 0 {
-			m.low24h = p
-		}
 	}
 
 	// Calculate metrics with available data
@@ -306,55 +486,92 @@ func updateAndDisplayMetrics(ctx context.Context, store *storage.RedisStore, sym
 		m.tradesPerMin = float64(tradeCount) / 15 // trades per minute over 15 minutes
 	}
 
+	// The order book's imbalance beats guessing sides from IsBuyerMaker
+	m.hasSpread = false
+	if spread, err := store.GetSpreadStats(timeoutCtx, symbol, 15*time.Minute); err != nil {
+		if cfg.Debug && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Failed to get spread for %s: %v", symbol, err)
+		}
+	} else {
+		m.orderImbalance = spread.AvgImbalance
+		m.avgSpread = spread.AvgSpread
+		m.hasSpread = true
+	}
+
 	// If we don't have running volume, use recent volume
 	if totalVolume == 0 {
 		totalVolume = recentVolume
 	}
 
 	// Display metrics
-	fmt.Printf("─── %s %s%s %s ───\n",
+	fmt.Fprintf(out, "─── %s %s%s %s ───\n",
 		symbol,
-		formatFloat(m.lastPrice, 2),
+		m.formatPrice(m.lastPrice),
 		formatPriceChange(((m.lastPrice-m.prevPrice)/m.prevPrice)*100),
 		m.lastTradeTime.Format("15:04:05"))
 
 	vwap := "-"
 	if totalQuantity > 0 {
-		vwap = formatFloat(volumePrice/totalQuantity, 2) // VWAP = Σ(price * quantity) / Σ(quantity)
+		vwap = m.formatPrice(volumePrice / totalQuantity) // VWAP = Σ(price * quantity) / Σ(quantity)
 	}
 
-	fmt.Printf("Range: %s - %s    VWAP: %s\n",
-		formatFloat(m.low24h, 2),
-		formatFloat(m.high24h, 2),
+	fmt.Fprintf(out, "Range (%s): %s - %s    VWAP: %s\n",
+		rw.label,
+		m.formatPrice(m.rangeLow),
+		m.formatPrice(m.rangeHigh),
 		vwap)
 
-	fmt.Println()
+	fmt.Fprintln(out)
 
-	fmt.Printf("Volume (2h):      %s USDT\n", formatVolume(totalVolume))
+	fmt.Fprintf(out, "Volume (2h):      %s USDT\n", formatVolume(totalVolume))
 	buyPercent := 0.0
 	if recentVolume > 0 {
 		buyPercent = (buyVol / recentVolume) * 100
 	}
-	fmt.Printf("Buy Volume:       %.1f%%\n", buyPercent)
-	fmt.Printf("Avg Trade Size:   %s USDT\n", formatVolume(m.avgTradeSize))
-	fmt.Printf("Trades/min:       %.1f\n", m.tradesPerMin)
+	fmt.Fprintf(out, "Buy Volume:       %.1f%%\n", buyPercent)
+	fmt.Fprintf(out, "Avg Trade Size:   %s USDT\n", formatVolume(m.avgTradeSize))
+	fmt.Fprintf(out, "Trades/min:       %.1f\n", m.tradesPerMin)
 
-	fmt.Println()
+	fmt.Fprintln(out)
 
-	if m.high24h > m.low24h {
-		m.priceRange = ((m.high24h - m.low24h) / m.low24h) * 100
-		m.rangePosition = ((m.lastPrice - m.low24h) / (m.high24h - m.low24h)) * 100
+	if m.rangeHigh > m.rangeLow {
+		m.priceRange = ((m.rangeHigh - m.rangeLow) / m.rangeLow) * 100
+		m.rangePosition = ((m.lastPrice - m.rangeLow) / (m.rangeHigh - m.rangeLow)) * 100
 	}
 
-	fmt.Printf("Price Range:      %.2f%%\n", m.priceRange)
-	fmt.Printf("Range Position:   %.1f%%\n", m.rangePosition)
-	fmt.Printf("Order Imbalance:  %.1f%%\n", m.orderImbalance*100)
+	fmt.Fprintf(out, "Price Range:      %.2f%%\n", m.priceRange)
+	fmt.Fprintf(out, "Range Position:   %.1f%%\n", m.rangePosition)
+	if m.hasSpread {
+		fmt.Fprintf(out, "Order Imbalance:  %.1f%% (book)\n", m.orderImbalance*100)
+		fmt.Fprintf(out, "Avg Spread:       %s\n", formatSpread(m.avgSpread))
+	} else {
+		fmt.Fprintf(out, "Order Imbalance:  %.1f%%\n", m.orderImbalance*100)
+	}
 
-	fmt.Printf("%s\n\n", strings.Repeat("─", 50))
+	fmt.Fprintf(out, "%s\n\n", strings.Repeat("─", 50))
 
 	return nil
 }
 
+// printStaleRow marks a symbol without a fresh trade, keeping the last price
+// seen while watching
+func printStaleRow(out io.Writer, symbol string, m *symbolMetrics) {
+	if !m.initialized {
+		fmt.Fprintf(out, "\033[K─── %s [STALE] no recent trades ───\n\n", symbol)
+		return
+	}
+	fmt.Fprintf(out, "\033[K─── %s [STALE] %s last trade %s ───\n\n",
+		symbol,
+		m.formatPrice(m.lastPrice),
+		m.lastTradeTime.Format("15:04:05"))
+}
+
+// formatPrice formats a price of the symbol with the decimals of its tick
+// size
+func (m *symbolMetrics) formatPrice(price float64) string {
+	return formatPriceDecimals(price, precisionDecimals(m.precision))
+}
+
 // formatPriceChange formats the price change with color and direction
 func formatPriceChange(change float64) string {
 	if change > 0 {
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// symbolFilter selects the symbols watch shows by --filter and
+// --exclude-filter. A nil pattern matches every symbol.
+type symbolFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// newSymbolFilter compiles the --filter and --exclude-filter patterns; empty
+// ones are not set
+func newSymbolFilter(include, exclude string) (symbolFilter, error) {
+	var f symbolFilter
+	var err error
+	if include != "" {
+		if f.include, err = regexp.Compile(include); err != nil {
+			return symbolFilter{}, fmt.Errorf("invalid --filter %q: %w", include, err)
+		}
+	}
+	if exclude != "" {
+		if f.exclude, err = regexp.Compile(exclude); err != nil {
+			return symbolFilter{}, fmt.Errorf("invalid --exclude-filter %q: %w", exclude, err)
+		}
+	}
+	return f, nil
+}
+
+// active reports whether either pattern is set
+func (f symbolFilter) active() bool {
+	return f.include != nil || f.exclude != nil
+}
+
+// matches reports whether symbol passes both patterns
+func (f symbolFilter) matches(symbol string) bool {
+	if f.include != nil && !f.include.MatchString(symbol) {
+		return false
+	}
+	return f.exclude == nil || !f.exclude.MatchString(symbol)
+}
+
+// selectSymbols returns the explicit symbols, which are shown whatever the
+// filter, followed by the available ones that match it
+func (f symbolFilter) selectSymbols(explicit, available []string) []string {
+	selected := append([]string(nil), explicit...)
+	seen := make(map[string]bool, len(explicit))
+	for _, symbol := range explicit {
+		seen[symbol] = true
+	}
+	for _, symbol := range available {
+		if !seen[symbol] && f.matches(symbol) {
+			seen[symbol] = true
+			selected = append(selected, symbol)
+		}
+	}
+	return selected
+}
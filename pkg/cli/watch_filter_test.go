@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSymbolFilter_SelectSymbols(t *testing.T) {
+	available := []string{"BTCUSDT", "ETHUSDT", "BTCEUR", "BTCUPUSDT", "ETHBTC"}
+	tests := []struct {
+		name             string
+		include, exclude string
+		explicit         []string
+		want             []string
+	}{
+		{"no filter", "", "", nil, available},
+		{"prefix", "^BTC", "", nil, []string{"BTCUSDT", "BTCEUR", "BTCUPUSDT"}},
+		{"suffix", "USDT$", "", nil, []string{"BTCUSDT", "ETHUSDT", "BTCUPUSDT"}},
+		{"exclude", "^BTC", "UP", nil, []string{"BTCUSDT", "BTCEUR"}},
+		{"exclude only", "", "BTC$", nil, []string{"BTCUSDT", "ETHUSDT", "BTCEUR", "BTCUPUSDT"}},
+		{"explicit always shown", "^BTC", "EUR", []string{"ETHBTC", "BTCEUR"}, []string{"ETHBTC", "BTCEUR", "BTCUSDT", "BTCUPUSDT"}},
+		{"nothing matches", "^XRP", "", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newSymbolFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.selectSymbols(tt.explicit, available); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectSymbols = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchCmd_InvalidFilter(t *testing.T) {
+	for _, flag := range []string{"--filter", "--exclude-filter"} {
+		root := NewRootCmd()
+		root.SetArgs([]string{"watch", flag, "BTC("})
+		root.SilenceUsage = true
+		root.SilenceErrors = true
+		err := root.Execute()
+		if err == nil || !strings.Contains(err.Error(), "invalid "+flag) {
+			t.Errorf("Expected an invalid %s error, got %v", flag, err)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Bounds of the interval +/- adjust in watch
+const (
+	minWatchInterval = time.Second
+	maxWatchInterval = time.Minute
+)
+
+// watchSort is the row order of watch
+type watchSort int
+
+const (
+	sortSymbol    watchSort = iota // As given, or as listed in Redis
+	sortChange                     // Largest price move since the last refresh first
+	sortTrades                     // Most trades per minute first
+	sortImbalance                  // Most lopsided order flow first
+	watchSortCount
+)
+
+func (s watchSort) String() string {
+	return [...]string{"symbol", "change", "trades/min", "imbalance"}[s]
+}
+
+// keyAction is what the watch loop does after a keystroke
+type keyAction int
+
+const (
+	keyNone     keyAction = iota // Nothing visible changed
+	keyRedraw                    // Rows or the status bar changed
+	keyInterval                  // The refresh interval changed
+	keyQuit
+)
+
+// Control keys read in raw mode
+const (
+	keyCtrlC     = 3
+	keyBackspace = 8
+	keyEnter     = '\r'
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// watchState is what the watch keyboard controls change
+type watchState struct {
+	interval  time.Duration
+	paused    bool
+	sort      watchSort
+	filter    string // Case-insensitive substring of the symbols shown
+	filtering bool   // Keystrokes edit the filter until Enter or Escape
+}
+
+// handleKey applies a keystroke to the state: p pauses and resumes, s cycles
+// the sort, + and - change the interval, f edits the filter and q or Ctrl+C
+// quits
+func (st *watchState) handleKey(key byte) keyAction {
+	if key == keyCtrlC {
+		return keyQuit
+	}
+
+	if st.filtering {
+		switch {
+		case key == keyEnter || key == '\n':
+			st.filtering = false
+		case key == keyEscape:
+			st.filter = ""
+			st.filtering = false
+		case key == keyBackspace || key == keyDelete:
+			if len(st.filter) > 0 {
+				st.filter = st.filter[:len(st.filter)-1]
+			}
+		case key > ' ' && key < keyDelete:
+			st.filter += string(key)
+		default:
+			return keyNone
+		}
+		return keyRedraw
+	}
+
+	switch key {
+	case 'q', 'Q':
+		return keyQuit
+	case 'p', 'P':
+		st.paused = !st.paused
+	case 's', 'S':
+		st.sort = (st.sort + 1) % watchSortCount
+	case 'f', 'F':
+		st.filtering = true
+	case '+', '=':
+		if st.interval >= maxWatchInterval {
+			return keyNone
+		}
+		st.interval += time.Second
+		return keyInterval
+	case '-', '_':
+		if st.interval <= minWatchInterval {
+			return keyNone
+		}
+		st.interval -= time.Second
+		return keyInterval
+	default:
+		return keyNone
+	}
+	return keyRedraw
+}
+
+// visibleSymbols returns the symbols matching the filter, in sort order
+func (st *watchState) visibleSymbols(symbols []string, metrics map[string]*symbolMetrics) []string {
+	filter := strings.ToUpper(st.filter)
+	visible := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		if strings.Contains(symbol, filter) {
+			visible = append(visible, symbol)
+		}
+	}
+
+	key := func(symbol string) float64 {
+		m := metrics[symbol]
+		switch st.sort {
+		case sortChange:
+			if m.prevPrice == 0 {
+				return 0
+			}
+			return math.Abs((m.lastPrice - m.prevPrice) / m.prevPrice)
+		case sortTrades:
+			return m.tradesPerMin
+		case sortImbalance:
+			return math.Abs(m.orderImbalance)
+		}
+		return 0
+	}
+	if st.sort != sortSymbol {
+		sort.SliceStable(visible, func(i, j int) bool { return key(visible[i]) > key(visible[j]) })
+	}
+	return visible
+}
+
+// statusBar returns the status line shown below the rows
+func (st *watchState) statusBar() string {
+	filter := st.filter
+	if st.filtering {
+		filter += "_"
+	}
+	if filter == "" {
+		filter = "none"
+	}
+
+	status := fmt.Sprintf(" Interval: %s | Sort: %s | Filter: %s ", st.interval, st.sort, filter)
+	if st.paused {
+		status += "[PAUSED] "
+	}
+	hint := " p pause  s sort  f filter  +/- interval  q quit"
+	if st.filtering {
+		hint = " type to filter  Enter done  Esc clear"
+	}
+	return "\033[7m" + status + "\033[0m" + hint
+}
+
+// printStatusBar draws the status bar where the last frame left it
+func printStatusBar(st *watchState) {
+	fmt.Printf("\0338%s\033[J", st.statusBar())
+}
+
+// startKeyboard puts stdin in raw mode and sends its keystrokes to keys. It
+// returns a function restoring the terminal, or an error when stdin is not
+// a terminal. Raw mode also stops the terminal from turning "\n" into
+// "\r\n", so stdout and the log are passed through crlfWriter meanwhile.
+func startKeyboard(ctx context.Context, keys chan<- byte) (func(), error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("stdin is not a terminal")
+	}
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set raw mode: %w", err)
+	}
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		term.Restore(fd, oldState)
+		return nil, fmt.Errorf("failed to redirect stdout: %w", err)
+	}
+	os.Stdout = w
+	log.SetOutput(crlfWriter{os.Stderr})
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		io.Copy(crlfWriter{stdout}, r)
+	}()
+
+	// The read blocks until a key is pressed, so this outlives watch; it
+	// only matters until the process exits
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(buf); err != nil {
+				return
+			}
+			select {
+			case keys <- buf[0]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		w.Close()
+		<-copied
+		os.Stdout = stdout
+		log.SetOutput(os.Stderr)
+		term.Restore(fd, oldState)
+	}, nil
+}
+
+// crlfWriter writes "\r\n" for each "\n", as a cooked terminal would
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (c crlfWriter) Write(p []byte) (int, error) {
+	if _, err := c.w.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
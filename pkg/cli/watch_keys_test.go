@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchState_HandleKey(t *testing.T) {
+	st := &watchState{interval: time.Second}
+
+	if action := st.handleKey('p'); action != keyRedraw || !st.paused {
+		t.Errorf("Expected p to pause, got %v with %+v", action, st)
+	}
+	if st.handleKey('p'); st.paused {
+		t.Error("Expected a second p to resume")
+	}
+
+	for _, want := range []watchSort{sortChange, sortTrades, sortImbalance, sortSymbol} {
+		if st.handleKey('s'); st.sort != want {
+			t.Errorf("Expected sort %s, got %s", want, st.sort)
+		}
+	}
+
+	if action := st.handleKey('-'); action != keyNone || st.interval != time.Second {
+		t.Errorf("Expected the interval to stay at its minimum, got %v and %s", action, st.interval)
+	}
+	if action := st.handleKey('+'); action != keyInterval || st.interval != 2*time.Second {
+		t.Errorf("Expected + to lengthen the interval, got %v and %s", action, st.interval)
+	}
+	if action := st.handleKey('-'); action != keyInterval || st.interval != time.Second {
+		t.Errorf("Expected - to shorten the interval, got %v and %s", action, st.interval)
+	}
+
+	// While filtering, letters edit the filter instead of acting as commands
+	for _, key := range []byte("fbtq") {
+		st.handleKey(key)
+	}
+	if st.handleKey(keyDelete); st.filter != "bt" || !st.filtering {
+		t.Errorf("Expected filter \"bt\" being edited, got %+v", st)
+	}
+	if st.handleKey(keyEnter); st.filter != "bt" || st.filtering {
+		t.Errorf("Expected Enter to keep the filter, got %+v", st)
+	}
+	if st.handleKey('f'); st.handleKey(keyEscape) != keyRedraw || st.filter != "" {
+		t.Errorf("Expected Escape to clear the filter, got %+v", st)
+	}
+
+	if st.handleKey('x') != keyNone {
+		t.Error("Expected unbound keys to be ignored")
+	}
+	if st.handleKey('q') != keyQuit || st.handleKey(keyCtrlC) != keyQuit {
+		t.Error("Expected q and Ctrl+C to quit")
+	}
+}
+
+func TestWatchState_VisibleSymbols(t *testing.T) {
+	symbols := []string{"BTCUSDT", "ETHUSDT", "ETHBTC", "SOLUSDT"}
+	metrics := map[string]*symbolMetrics{
+		"BTCUSDT": {lastPrice: 101, prevPrice: 100, tradesPerMin: 50, orderImbalance: 0.1},
+		"ETHUSDT": {lastPrice: 95, prevPrice: 100, tradesPerMin: 80, orderImbalance: -0.6},
+		"ETHBTC":  {lastPrice: 1, prevPrice: 1, tradesPerMin: 5, orderImbalance: 0.3},
+		"SOLUSDT": {}, // No trades yet
+	}
+
+	tests := []struct {
+		state watchState
+		want  []string
+	}{
+		{watchState{}, symbols},
+		{watchState{sort: sortChange}, []string{"ETHUSDT", "BTCUSDT", "ETHBTC", "SOLUSDT"}},
+		{watchState{sort: sortTrades}, []string{"ETHUSDT", "BTCUSDT", "ETHBTC", "SOLUSDT"}},
+		{watchState{sort: sortImbalance}, []string{"ETHUSDT", "ETHBTC", "BTCUSDT", "SOLUSDT"}},
+		{watchState{filter: "eth"}, []string{"ETHUSDT", "ETHBTC"}},
+		{watchState{filter: "btc", sort: sortTrades}, []string{"BTCUSDT", "ETHBTC"}},
+		{watchState{filter: "doge"}, []string{}},
+	}
+	for _, tt := range tests {
+		if got := tt.state.visibleSymbols(symbols, metrics); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("visibleSymbols(%+v) = %v, want %v", tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestWatchState_StatusBar(t *testing.T) {
+	st := &watchState{interval: 2 * time.Second, sort: sortTrades, filter: "btc", paused: true}
+	bar := st.statusBar()
+	for _, want := range []string{"Interval: 2s", "Sort: trades/min", "Filter: btc", "[PAUSED]"} {
+		if !strings.Contains(bar, want) {
+			t.Errorf("Expected %q in the status bar, got %q", want, bar)
+		}
+	}
+
+	st = &watchState{interval: time.Second, filtering: true}
+	if bar := st.statusBar(); strings.Contains(bar, "[PAUSED]") || !strings.Contains(bar, "Filter: _") {
+		t.Errorf("Expected an empty filter being edited, got %q", bar)
+	}
+}
+
+func TestCRLFWriter(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := crlfWriter{&buf}.Write([]byte("a\nb\n"))
+	if err != nil || n != 4 || buf.String() != "a\r\nb\r\n" {
+		t.Errorf("Expected 4 bytes written as %q, got %d %q (%v)", "a\r\nb\r\n", n, buf.String(), err)
+	}
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// tapeReserveLines are the screen lines around the tape rows: its title, its
+// rule and the status bar
+const tapeReserveLines = 3
+
+// tradePager pages through the trade history of a symbol
+type tradePager interface {
+	GetTradeHistoryPage(ctx context.Context, symbol string, start, end time.Time, offset, limit int64) ([]models.AggTradeEvent, int64, error)
+}
+
+// lineCounter counts the lines written through it
+type lineCounter struct {
+	w     io.Writer
+	lines int
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	c.lines += bytes.Count(p, []byte("\n"))
+	return c.w.Write(p)
+}
+
+// tapeRows returns how many tape rows fit below used lines on a screen of
+// height lines, at most depth. A height of 0, when it is not known, always
+// fits depth rows.
+func tapeRows(depth, height, used int) int {
+	if height <= 0 {
+		return depth
+	}
+	return max(0, min(depth, height-used-tapeReserveLines))
+}
+
+// loadWatchTape returns the last depth trades of symbols within window,
+// oldest first, reading the last page of each symbol's history
+func loadWatchTape(ctx context.Context, store tradePager, symbols []string, window time.Duration, depth int) ([]models.TradeData, error) {
+	if depth <= 0 {
+		return nil, nil
+	}
+
+	end := time.Now()
+	start := end.Add(-window)
+	var trades []models.TradeData
+	for _, symbol := range symbols {
+		// The first page only counts the trades, so the second can start
+		// depth trades before the end
+		_, total, err := store.GetTradeHistoryPage(ctx, symbol, start, end, 0, 1)
+		if err != nil {
+			return nil, err
+		}
+		page, _, err := store.GetTradeHistoryPage(ctx, symbol, start, end, max(0, total-int64(depth)), int64(depth))
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range page {
+			trades = append(trades, event.Data)
+		}
+	}
+
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].TradeTime < trades[j].TradeTime })
+	if len(trades) > depth {
+		trades = trades[len(trades)-depth:]
+	}
+	return trades, nil
+}
+
+// printWatchTape prints the tape panel with its newest trade at the bottom,
+// so trades scroll upward as they arrive
+func printWatchTape(out io.Writer, trades []models.TradeData, color bool) {
+	fmt.Fprintf(out, "\033[K─── Tape (last %d trades) ───\n", len(trades))
+	for _, trade := range trades {
+		fmt.Fprintf(out, "\033[K%s\n", formatWatchTapeLine(trade, color))
+	}
+	fmt.Fprintf(out, "\033[K%s\n", strings.Repeat("─", 50))
+}
+
+// formatWatchTapeLine renders a trade as a watch tape row, colored by taker
+// side when color is set
+func formatWatchTapeLine(trade models.TradeData, color bool) string {
+	side := "BUY"
+	if trade.IsBuyerMaker {
+		side = "SELL"
+	}
+
+	line := fmt.Sprintf("%-12s %-10s %-4s %14s %14s",
+		time.UnixMilli(trade.TradeTime).Local().Format("15:04:05.000"),
+		trade.Symbol, side, trade.Price, trade.Quantity)
+	if !color {
+		return line
+	}
+	if trade.IsBuyerMaker {
+		return ansiRed + line + ansiReset
+	}
+	return ansiGreen + line + ansiReset
+}
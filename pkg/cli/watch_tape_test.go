@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestTapeRows(t *testing.T) {
+	tests := []struct {
+		depth, height, used int
+		want                int
+	}{
+		{depth: 20, height: 0, used: 100, want: 20}, // Unknown height
+		{depth: 20, height: 50, used: 10, want: 20},
+		{depth: 20, height: 30, used: 17, want: 10},
+		{depth: 20, height: 30, used: 27, want: 0},
+		{depth: 20, height: 30, used: 40, want: 0},
+	}
+	for _, tt := range tests {
+		if got := tapeRows(tt.depth, tt.height, tt.used); got != tt.want {
+			t.Errorf("tapeRows(%d, %d, %d) = %d, want %d", tt.depth, tt.height, tt.used, got, tt.want)
+		}
+	}
+}
+
+func TestLoadWatchTape(t *testing.T) {
+	store, _, _ := newMiniredisStore(t)
+	ctx := context.Background()
+
+	// Trades alternate between the symbols, one a second
+	base := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	for i := 0; i < 10; i++ {
+		symbol := "BTCUSDT"
+		if i%2 == 1 {
+			symbol = "ETHUSDT"
+		}
+		err := store.StoreTrade(ctx, &models.Trade{
+			Symbol:       symbol,
+			Price:        "100",
+			Quantity:     "1",
+			TradeID:      int64(i + 1),
+			Time:         base.Add(time.Duration(i) * time.Second),
+			EventTime:    base.Add(time.Duration(i) * time.Second),
+			IsBuyerMaker: i%3 == 0,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trades, err := loadWatchTape(ctx, store, []string{"BTCUSDT", "ETHUSDT"}, time.Hour, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 3 || trades[0].TradeID != 8 || trades[1].TradeID != 9 || trades[2].TradeID != 10 {
+		t.Fatalf("Expected trades [8 9 10] oldest first, got %+v", trades)
+	}
+
+	// Only the shown symbols are on the tape
+	trades, err = loadWatchTape(ctx, store, []string{"BTCUSDT"}, time.Hour, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 5 || trades[4].TradeID != 9 {
+		t.Fatalf("Expected the 5 BTCUSDT trades, got %+v", trades)
+	}
+}
+
+func TestPrintWatchTape(t *testing.T) {
+	trades := []models.TradeData{
+		{Symbol: "BTCUSDT", Price: "50000.5", Quantity: "0.25", TradeTime: time.Now().UnixMilli()},
+		{Symbol: "ETHUSDT", Price: "3000", Quantity: "2", TradeTime: time.Now().UnixMilli(), IsBuyerMaker: true},
+	}
+
+	var buf bytes.Buffer
+	out := &lineCounter{w: &buf}
+	printWatchTape(out, trades, true)
+	if out.lines != 4 {
+		t.Errorf("Expected a title, 2 trades and a rule, counted %d lines", out.lines)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if !strings.Contains(lines[1], ansiGreen) || !strings.Contains(lines[1], "BTCUSDT    BUY         50000.5           0.25") {
+		t.Errorf("Unexpected buy line %q", lines[1])
+	}
+	if !strings.Contains(lines[2], ansiRed) || !strings.Contains(lines[2], "ETHUSDT    SELL") {
+		t.Errorf("Unexpected sell line %q", lines[2])
+	}
+
+	if line := formatWatchTapeLine(trades[0], false); strings.Contains(line, "\033") {
+		t.Errorf("Expected no color codes without color, got %q", line)
+	}
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func TestWatchRefresh_SymbolWithoutTrades(t *testing.T) {
+	store, _, cfg := newMiniredisStore(t)
+	ctx := context.Background()
+
+	// A refresh fetches every symbol at once; one without trades has no entry
+	latest, err := store.GetLatestTrades(ctx, []string{"ETHUSDT"})
+	if err != nil {
+		t.Fatalf("A symbol without trades should not fail the refresh: %v", err)
+	}
+	if latest["ETHUSDT"] != nil {
+		t.Fatalf("Expected no trade for ETHUSDT, got %+v", latest["ETHUSDT"])
+	}
+
+	rw := &rangeWindow{label: "24h", duration: 24 * time.Hour}
+	err = updateAndDisplayMetrics(ctx, io.Discard, store, rw, "ETHUSDT", latest["ETHUSDT"], &symbolMetrics{}, cfg)
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a symbol without trades, got %v", err)
+	}
+}
+
+func TestWatchRefresh_StaleTrade(t *testing.T) {
+	store, _, cfg := newMiniredisStore(t)
+	ctx := context.Background()
+
+	old := time.Now().Add(-cfg.Redis.MaxLatestTradeAge - time.Minute)
+	if err := store.StoreTrade(ctx, &models.Trade{Symbol: "BTCUSDT", Price: "50000", Quantity: "1", Time: old, EventTime: old}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stale trade is left out of the refresh and its row marked
+	latest, err := store.GetLatestTrades(ctx, []string{"BTCUSDT"})
+	if err != nil || latest["BTCUSDT"] != nil {
+		t.Fatalf("Expected no fresh trade for BTCUSDT, got %+v (%v)", latest["BTCUSDT"], err)
+	}
+
+	m := &symbolMetrics{lastPrice: 50000, lastTradeTime: old, initialized: true}
+	rw := &rangeWindow{label: "24h", duration: 24 * time.Hour}
+	var out bytes.Buffer
+	err = updateAndDisplayMetrics(ctx, &out, store, rw, "BTCUSDT", latest["BTCUSDT"], m, cfg)
+	output := out.String()
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a stale symbol, got %v", err)
+	}
+	if !strings.Contains(output, "BTCUSDT [STALE] 50000.00") {
+		t.Errorf("Expected the row to be marked stale with the last price, got %q", output)
+	}
+}
+
+func TestWatchRefresh_BookImbalance(t *testing.T) {
+	store, _, cfg := newMiniredisStore(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	trade := &models.Trade{Symbol: "BTCUSDT", Price: "50000", Quantity: "1", Time: now, EventTime: now}
+	if err := store.StoreTrade(ctx, trade); err != nil {
+		t.Fatal(err)
+	}
+	rw := &rangeWindow{label: "24h", duration: 24 * time.Hour}
+
+	var out bytes.Buffer
+	if err := updateAndDisplayMetrics(ctx, &out, store, rw, "BTCUSDT", trade, &symbolMetrics{}, cfg); err != nil {
+		t.Errorf("Refresh failed: %v", err)
+	}
+	output := out.String()
+	if strings.Contains(output, "(book)") || strings.Contains(output, "Avg Spread") {
+		t.Errorf("Expected the trade-side imbalance without book tickers, got %q", output)
+	}
+
+	// Bids 1, asks 3: imbalance -50%
+	ticker := &models.BookTicker{Symbol: "BTCUSDT", BidPrice: "49999.5", BidQty: "1", AskPrice: "50000", AskQty: "3"}
+	if err := store.StoreBookTicker(ctx, ticker, now); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := updateAndDisplayMetrics(ctx, &out, store, rw, "BTCUSDT", trade, &symbolMetrics{}, cfg); err != nil {
+		t.Errorf("Refresh failed: %v", err)
+	}
+	output = out.String()
+	if !strings.Contains(output, "Order Imbalance:  -50.0% (book)") || !strings.Contains(output, "Avg Spread:       0.5") {
+		t.Errorf("Expected the book imbalance and spread, got %q", output)
+	}
+}
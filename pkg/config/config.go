@@ -2,73 +2,397 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
 // Config represents the application configuration
 type Config struct {
-	Redis     RedisConfig
-	Binance   BinanceConfig
-	WebSocket WebSocketConfig
-	Debug     bool
+	Exchange  string          `mapstructure:"exchange"` // Venue to ingest from; namespaces all stored data
+	Redis     RedisConfig     `mapstructure:"redis"`
+	Binance   BinanceConfig   `mapstructure:"binance"`
+	WebSocket WebSocketConfig `mapstructure:"websocket"`
+	Ingestion IngestionConfig `mapstructure:"ingestion"`
+	Processor ProcessorConfig `mapstructure:"processor"`
+	Cache     CacheConfig     `mapstructure:"cache"`
+	Breaker   BreakerConfig   `mapstructure:"breaker"`
+	Postgres  PostgresConfig  `mapstructure:"postgres"`
+	API       APIConfig       `mapstructure:"api"`
+	Debug     bool            `mapstructure:"debug"`
+	DebugAddr string          `mapstructure:"debug_addr"` // Listen address of the debug HTTP server (empty disables it)
 }
 
 // RedisConfig holds Redis-specific configuration
 type RedisConfig struct {
-	URL             string
-	RetentionPeriod time.Duration
-	CleanupInterval time.Duration
-	KeyPrefix       string
+	URL             string        `mapstructure:"url"`
+	RetentionPeriod time.Duration `mapstructure:"retention_period"`
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+	KeyPrefix       string        `mapstructure:"key_prefix"`
 	// New fields for optimization
-	UseCompression  bool
-	MaxTradesPerKey int // Limit number of trades stored per symbol
+	UseCompression  bool `mapstructure:"use_compression"`    // Unused; raw trades are compressed with CompressionCodec
+	MaxTradesPerKey int  `mapstructure:"max_trades_per_key"` // Limit number of trades stored per symbol
+	// Latest trades older than this are treated as missing, e.g. for
+	// delisted symbols (0 disables the check)
+	MaxLatestTradeAge time.Duration `mapstructure:"max_latest_trade_age"`
+	// Sentinel settings (used instead of URL when SentinelAddrs is set)
+	SentinelAddrs      []string `mapstructure:"sentinel_addrs"`       // Sentinel host:port addresses
+	SentinelMasterName string   `mapstructure:"sentinel_master_name"` // Name of the monitored master
+	SentinelPassword   string   `mapstructure:"sentinel_password"`    // Password for authenticated sentinels
+	// Treat the URL as a seed node of a Redis Cluster
+	Cluster bool `mapstructure:"cluster"`
+	// Publish trades on a channel per symbol (trades.BTCUSDT) instead of the
+	// shared trades channel
+	PerSymbolChannels bool `mapstructure:"per_symbol_channels"`
+	// Trades published together as one message per channel (1 publishes
+	// each on its own), and the longest a trade waits for its batch to fill
+	PublishBatchSize  int           `mapstructure:"publish_batch_size"`
+	PublishBatchDelay time.Duration `mapstructure:"publish_batch_delay"`
+	// Trades stored per second with history before StoreTrade coalesces,
+	// keeping only the latest trade (0 disables), and the burst allowed
+	// above it (0 for one second's worth)
+	MaxWritesPerSec float64 `mapstructure:"max_writes_per_sec"`
+	WriteBurst      int     `mapstructure:"write_burst"`
+	// Rejected payloads kept for inspection; the oldest are trimmed beyond
+	// this length
+	QuarantineMaxLen int64 `mapstructure:"quarantine_max_len"`
+	// Codec compressing raw trades in the history: "none", "gzip" or
+	// "zstd". Entries are read whatever codec wrote them.
+	CompressionCodec string `mapstructure:"compression_codec"`
 }
 
 // BinanceConfig holds Binance-specific configuration
 type BinanceConfig struct {
-	BaseURL           string
-	MaxStreamsPerConn int
-	HistorySize       int64
+	BaseURL           string `mapstructure:"base_url"`
+	StreamURL         string `mapstructure:"stream_url"`           // WebSocket host overriding the environment's, e.g. a mock server
+	MaxStreamsPerConn int    `mapstructure:"max_streams_per_conn"` // Streams per WebSocket connection, capped at MaxBinanceStreamsPerConn (0 for the cap)
+	HistorySize       int64  `mapstructure:"history_size"`
 	// New fields for symbol filtering
-	MainSymbols    []string // Priority symbols to track (e.g., ["BTCUSDT", "ETHUSDT"])
-	MaxSymbols     int      // Maximum number of symbols to track (0 for unlimited)
-	MinDailyVolume float64  // Minimum 24h volume to track a symbol (0 for unlimited)
+	MainSymbols []string `mapstructure:"main_symbols"` // Priority symbols to track (e.g., ["BTCUSDT", "ETHUSDT"])
+	// Stream main symbols whatever their volume, exchange status or
+	// MaxSymbols; when false they are filtered like discovered pairs
+	MainSymbolsAlwaysOn bool     `mapstructure:"main_symbols_always_on"`
+	MaxSymbols          int      `mapstructure:"max_symbols"`      // Maximum number of symbols to track (0 for unlimited)
+	MinDailyVolume      float64  `mapstructure:"min_daily_volume"` // Minimum 24h volume to track a symbol (0 for unlimited)
+	QuoteAssets         []string `mapstructure:"quote_assets"`     // Quote assets of discovered pairs (e.g., ["USDT"])
+	// Only discover spot-tradable pairs, skipping margin-only pairs and
+	// leveraged tokens
+	SpotOnly bool `mapstructure:"spot_only"`
+	// How often to re-run symbol discovery and adjust subscriptions (0 disables)
+	SymbolRefreshInterval time.Duration `mapstructure:"symbol_refresh_interval"`
+	// Use the spot testnet (testnet.binance.vision) and the futures testnet
+	// (binancefuture.com) instead of production endpoints
+	UseTestnet bool `mapstructure:"use_testnet"`
+	// Group symbols into connections by 24h volume so each carries a similar
+	// message load, instead of in discovery order
+	BalanceGroupsByVolume bool `mapstructure:"balance_groups_by_volume"`
+	// Also stream each symbol's best bid and ask (@bookTicker) and keep
+	// per-minute spread and imbalance series; doubles the streams per symbol
+	BookTicker bool `mapstructure:"book_ticker"`
+	// API key for the user-data stream of the account's orders and balances (empty disables it)
+	APIKey string `mapstructure:"api_key"`
+	// Proxies for REST calls and stream connections, as http://host:port or
+	// socks5://host:port. Streams use HTTPProxy unless WSProxy is set; when
+	// both are empty HTTPS_PROXY, HTTP_PROXY and NO_PROXY apply.
+	HTTPProxy string `mapstructure:"http_proxy"`
+	WSProxy   string `mapstructure:"ws_proxy"`
+	// Where the symbols to stream come from: "api" discovers them through the
+	// exchange with the filters above, "static" streams StaticSymbols and
+	// "file" the symbols listed in SymbolsFile, reloaded when it changes
+	SymbolSource  string   `mapstructure:"symbol_source"`
+	StaticSymbols []string `mapstructure:"static_symbols"`
+	SymbolsFile   string   `mapstructure:"symbols_file"`
+	// Stream each symbol's "trade", "aggTrade" or "miniTicker" stream unless
+	// overridden per symbol in Redis
+	DefaultStreamType string `mapstructure:"default_stream_type"`
+	// After a reconnect, backfill the trades each symbol missed through the
+	// REST historicalTrades or aggTrades endpoint, matching its stream
+	AutoRecoverGaps bool `mapstructure:"auto_recover_gaps"`
+}
+
+// Symbol sources selected with BinanceConfig.SymbolSource
+const (
+	SymbolSourceAPI    = "api"
+	SymbolSourceStatic = "static"
+	SymbolSourceFile   = "file"
+)
+
+// Stream types selected with BinanceConfig.DefaultStreamType and per-symbol
+// overrides
+const (
+	StreamTypeTrade      = "trade"
+	StreamTypeAggTrade   = "aggTrade"
+	StreamTypeMiniTicker = "miniTicker"
+)
+
+// IsStreamType reports whether streamType is one of the supported stream types
+func IsStreamType(streamType string) bool {
+	switch streamType {
+	case StreamTypeTrade, StreamTypeAggTrade, StreamTypeMiniTicker:
+		return true
+	}
+	return false
+}
+
+// Compression codecs selected with RedisConfig.CompressionCodec
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// MaxBinanceStreamsPerConn is Binance's limit on streams per WebSocket connection
+const MaxBinanceStreamsPerConn = 1024
+
+// StreamsPerConn returns MaxStreamsPerConn clamped to the Binance limit
+func (b BinanceConfig) StreamsPerConn() int {
+	if b.MaxStreamsPerConn <= 0 || b.MaxStreamsPerConn > MaxBinanceStreamsPerConn {
+		return MaxBinanceStreamsPerConn
+	}
+	return b.MaxStreamsPerConn
+}
+
+// StreamsPerSymbol returns the streams subscribed per symbol: its trades,
+// and its book ticker when BookTicker is set
+func (b BinanceConfig) StreamsPerSymbol() int {
+	if b.BookTicker {
+		return 2
+	}
+	return 1
+}
+
+// SymbolsPerConn returns how many symbols fit in one connection's
+// StreamsPerConn streams
+func (b BinanceConfig) SymbolsPerConn() int {
+	return b.StreamsPerConn() / b.StreamsPerSymbol()
 }
 
 // WebSocketConfig holds WebSocket-specific configuration
 type WebSocketConfig struct {
-	ReconnectDelay time.Duration
-	PingInterval   time.Duration
+	ReconnectDelay time.Duration `mapstructure:"reconnect_delay"`
+	PingInterval   time.Duration `mapstructure:"ping_interval"`
+}
+
+// IngestionConfig holds ingestion-specific configuration
+type IngestionConfig struct {
+	RecordDir          string        `mapstructure:"record_dir"`            // Directory for raw message archives (empty disables recording)
+	RecordMaxFileSize  int64         `mapstructure:"record_max_file_size"`  // Rotate an archive after this many uncompressed bytes
+	RecordMaxFileAge   time.Duration `mapstructure:"record_max_file_age"`   // Rotate an archive after it has been open this long
+	RecordMaxTotalSize int64         `mapstructure:"record_max_total_size"` // Prune the oldest archives once the directory exceeds this size
+	// Watchdog settings: rebuild all connections when no message arrives for WatchdogSilence
+	WatchdogSilence     time.Duration `mapstructure:"watchdog_silence"`      // Silence threshold (0 disables the watchdog)
+	WatchdogMaxRestarts int           `mapstructure:"watchdog_max_restarts"` // Consecutive full restarts before giving up
+	WatchdogActiveFrom  int           `mapstructure:"watchdog_active_from"`  // First UTC hour (0-23) in which silence is unexpected
+	WatchdogActiveTo    int           `mapstructure:"watchdog_active_to"`    // UTC hour (1-24) at which the active window ends
+	// Publish queue between WebSocket reads and the message bus
+	PublishQueueSize   int    `mapstructure:"publish_queue_size"`   // Buffered trades (0 publishes synchronously)
+	PublishQueuePolicy string `mapstructure:"publish_queue_policy"` // "block" slows reads when full, "shed" or "drop_oldest" drop trades
+	PublishWorkers     int    `mapstructure:"publish_workers"`      // Goroutines publishing queued trades; each symbol sticks to one
+	// Decode trade messages with a minimal scanner and pooled events instead
+	// of encoding/json; unusual messages still go through encoding/json
+	FastDecode bool `mapstructure:"fast_decode"`
+	// Connection event log for uptime auditing
+	ConnectionEventsMax int64 `mapstructure:"connection_events_max"` // Oldest connects and disconnects are trimmed beyond this length
+	// How often the symbol group assignments in Redis are refreshed; entries
+	// of a process that stops refreshing expire after three intervals
+	GroupHeartbeat time.Duration `mapstructure:"group_heartbeat"`
+	// Leader election between replicas: only the holder of the Redis lease
+	// streams, and a replica takes over once the lease lapses for LeaseTTL
+	LeaderElection bool          `mapstructure:"leader_election"`
+	LeaseTTL       time.Duration `mapstructure:"lease_ttl"`
+}
+
+// Publish queue policies
+const (
+	PublishPolicyBlock      = "block"
+	PublishPolicyShed       = "shed"        // Drop the incoming trade
+	PublishPolicyDropOldest = "drop_oldest" // Drop the longest-queued trade to make room
+)
+
+// ProcessorConfig holds trade processing configuration
+type ProcessorConfig struct {
+	DLQMaxLen         int64 `mapstructure:"dlq_max_len"`         // Oldest dead-lettered trades are trimmed beyond this length
+	DLQAlertThreshold int64 `mapstructure:"dlq_alert_threshold"` // Warn when the dead letter queue grows past this depth
+
+	Anomalies AnomalyConfig `mapstructure:"anomalies"` // Outsized trade and price jump detection
+}
+
+// AnomalyConfig holds the thresholds of each class of trade anomaly the
+// processor detects
+type AnomalyConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Outsized trades: notional more than SizeSigmas standard deviations
+	// above the average of the symbol's last SizeWindow trades, once
+	// SizeMinSamples trades were seen
+	SizeSigmas     float64 `mapstructure:"size_sigmas"`
+	SizeWindow     int     `mapstructure:"size_window"`
+	SizeMinSamples int     `mapstructure:"size_min_samples"`
+	// Price jumps: a move of more than PriceJumpPercent from the highest or
+	// lowest price within PriceJumpWindow
+	PriceJumpPercent float64       `mapstructure:"price_jump_percent"`
+	PriceJumpWindow  time.Duration `mapstructure:"price_jump_window"`
+	MaxEvents        int64         `mapstructure:"max_events"` // Oldest events are trimmed from the review list beyond this length
+}
+
+// CacheConfig holds the in-process read cache configuration
+type CacheConfig struct {
+	TTL        time.Duration `mapstructure:"ttl"`         // How long latest prices and 24h volumes are served from memory
+	MaxEntries int           `mapstructure:"max_entries"` // Least recently used entries are evicted beyond this count
+}
+
+// BreakerConfig holds the circuit breaker settings for exchange connections
+// and PostgreSQL candle writes
+type BreakerConfig struct {
+	MaxFailures int           `mapstructure:"max_failures"` // Consecutive failures that open the circuit
+	Window      time.Duration `mapstructure:"window"`       // Failures further apart than this start a new count
+	Cooldown    time.Duration `mapstructure:"cooldown"`     // How long the circuit stays open before a probe
+}
+
+// PostgresConfig holds the PostgreSQL candle retention settings
+type PostgresConfig struct {
+	CandleRetention time.Duration `mapstructure:"candle_retention"` // Candles older than this are pruned (0 keeps them forever)
+	PruneInterval   time.Duration `mapstructure:"prune_interval"`   // How often old candles are pruned
+	PruneBatchSize  int           `mapstructure:"prune_batch_size"` // Rows deleted per statement, keeping locks short
+	CompressAfter   time.Duration `mapstructure:"compress_after"`   // TimescaleDB compresses candle chunks older than this (0 disables)
+	RollupInterval  time.Duration `mapstructure:"rollup_interval"`  // How often daily candles are rolled up (0 disables)
+
+	// Names this streamer's live candle rows. Replicas taking over from each
+	// other share the default; only streamers aggregating the same trades
+	// at once need names of their own.
+	CandleWriter string `mapstructure:"candle_writer"`
+
+	// Candles the aggregator keeps in memory before writing out the oldest,
+	// complete or not, e.g. while PostgreSQL is down (0 disables the cap)
+	MaxBufferedCandles int `mapstructure:"max_buffered_candles"`
+}
+
+// APIConfig holds the read API service (ordersvc) settings
+type APIConfig struct {
+	Addr string `mapstructure:"addr"` // Listen address of the REST/WebSocket API
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
+	exchange := getEnvOrDefault("EXCHANGE", "binance")
 	return &Config{
+		Exchange: exchange,
 		Redis: RedisConfig{
 			URL:             getEnvOrDefault("REDIS_URL", "redis://localhost:6379"),
 			RetentionPeriod: 24 * time.Hour,
 			CleanupInterval: 5 * time.Minute,
-			KeyPrefix:       "binance:",
+			KeyPrefix:       exchange + ":",
 			MaxTradesPerKey: 500,
 			UseCompression:  true,
+
+			MaxLatestTradeAge: 5 * time.Minute,
+
+			SentinelAddrs:      splitEnvList("REDIS_SENTINEL_ADDRS"),
+			SentinelMasterName: getEnvOrDefault("REDIS_SENTINEL_MASTER", "mymaster"),
+			SentinelPassword:   os.Getenv("REDIS_SENTINEL_PASSWORD"),
+			Cluster:            os.Getenv("REDIS_CLUSTER") == "true",
+			PerSymbolChannels:  os.Getenv("REDIS_PER_SYMBOL_CHANNELS") == "true",
+			PublishBatchSize:   1,
+			PublishBatchDelay:  5 * time.Millisecond,
+
+			QuarantineMaxLen: 1000,
+			CompressionCodec: getEnvOrDefault("COMPRESSION_CODEC", CompressionNone),
 		},
 		Binance: BinanceConfig{
-			BaseURL:           "https://api.binance.com",
-			MaxSymbols:        5,
-			MaxStreamsPerConn: 1000,
-			MinDailyVolume:    10000000,
-			MainSymbols:       []string{"BTCUSDT", "ETHUSDT"},
-			HistorySize:       100,
+			BaseURL:             "https://api.binance.com",
+			MaxSymbols:          5,
+			MaxStreamsPerConn:   1000,
+			MinDailyVolume:      10000000,
+			MainSymbols:         []string{"BTCUSDT", "ETHUSDT"},
+			MainSymbolsAlwaysOn: os.Getenv("MAIN_SYMBOLS_ALWAYS_ON") != "false",
+			QuoteAssets:         defaultList(splitEnvList("QUOTE_ASSETS"), "USDT"),
+			HistorySize:         100,
+
+			SymbolRefreshInterval: time.Hour,
+			UseTestnet:            os.Getenv("BINANCE_TESTNET") == "true",
+			BalanceGroupsByVolume: os.Getenv("BALANCE_GROUPS_BY_VOLUME") == "true",
+			BookTicker:            os.Getenv("BINANCE_BOOK_TICKER") == "true",
+			SpotOnly:              os.Getenv("BINANCE_SPOT_ONLY") == "true",
+			APIKey:                os.Getenv("BINANCE_API_KEY"),
+			HTTPProxy:             os.Getenv("BINANCE_HTTP_PROXY"),
+			WSProxy:               os.Getenv("BINANCE_WS_PROXY"),
+			SymbolSource:          getEnvOrDefault("SYMBOL_SOURCE", SymbolSourceAPI),
+			StaticSymbols:         splitEnvList("STATIC_SYMBOLS"),
+			SymbolsFile:           os.Getenv("SYMBOLS_FILE"),
+			DefaultStreamType:     getEnvOrDefault("BINANCE_STREAM_TYPE", StreamTypeTrade),
+			AutoRecoverGaps:       os.Getenv("BINANCE_AUTO_RECOVER_GAPS") == "true",
 		},
 		WebSocket: WebSocketConfig{
 			PingInterval:   time.Minute,
 			ReconnectDelay: 5 * time.Second,
 		},
-		Debug: false,
+		Ingestion: IngestionConfig{
+			RecordDir:          os.Getenv("RECORD_DIR"),
+			RecordMaxFileSize:  256 << 20,
+			RecordMaxFileAge:   time.Hour,
+			RecordMaxTotalSize: 10 << 30,
+
+			WatchdogSilence:     2 * time.Minute,
+			WatchdogMaxRestarts: 3,
+			WatchdogActiveFrom:  0,
+			WatchdogActiveTo:    24,
+
+			PublishQueueSize:   10000,
+			PublishQueuePolicy: getEnvOrDefault("PUBLISH_QUEUE_POLICY", PublishPolicyBlock),
+			PublishWorkers:     4,
+
+			FastDecode:          os.Getenv("FAST_DECODE") == "true",
+			ConnectionEventsMax: 10000,
+			GroupHeartbeat:      15 * time.Second,
+			LeaderElection:      os.Getenv("LEADER_ELECTION") == "true",
+			LeaseTTL:            15 * time.Second,
+		},
+		Processor: ProcessorConfig{
+			DLQMaxLen:         10000,
+			DLQAlertThreshold: 100,
+			Anomalies: AnomalyConfig{
+				Enabled:          os.Getenv("ANOMALY_DETECTION") == "true",
+				SizeSigmas:       4,
+				SizeWindow:       500,
+				SizeMinSamples:   50,
+				PriceJumpPercent: 1,
+				PriceJumpWindow:  time.Minute,
+				MaxEvents:        1000,
+			},
+		},
+		Cache: CacheConfig{
+			TTL:        time.Second,
+			MaxEntries: 1000,
+		},
+		Breaker: BreakerConfig{
+			MaxFailures: 5,
+			Window:      time.Minute,
+			Cooldown:    30 * time.Second,
+		},
+		Postgres: PostgresConfig{
+			CandleRetention: 0, // Pruning is opt-in
+			PruneInterval:   time.Hour,
+			PruneBatchSize:  10000,
+			RollupInterval:  time.Hour,
+			CandleWriter:    getEnvOrDefault("CANDLE_WRITER", ""),
+
+			MaxBufferedCandles: 100000,
+		},
+		API: APIConfig{
+			// Heroku assigns web dynos their port through PORT
+			Addr: getEnvOrDefault("API_ADDR", ":"+getEnvOrDefault("PORT", "8080")),
+		},
+		Debug:     false,
+		DebugAddr: getEnvOrDefault("DEBUG_ADDR", ":2112"),
 	}
 }
 
+// SetExchange switches the venue and namespaces Redis keys under it
+func (c *Config) SetExchange(name string) {
+	c.Exchange = name
+	c.Redis.KeyPrefix = name + ":"
+}
+
 // getEnvOrDefault returns environment variable value or default if not set
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -77,8 +401,82 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// splitEnvList returns the comma-separated values of an environment variable
+func splitEnvList(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// defaultList returns items, or defaults when items is empty
+func defaultList(items []string, defaults ...string) []string {
+	if len(items) == 0 {
+		return defaults
+	}
+	return items
+}
+
+// ParseProxyURL parses a proxy URL such as http://proxy:3128 or
+// socks5://proxy:1080
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme must be http, https or socks5", raw)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", raw)
+	}
+	return u, nil
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
+	if c.Exchange == "" {
+		return fmt.Errorf("exchange must be set")
+	}
+	for _, proxy := range []string{c.Binance.HTTPProxy, c.Binance.WSProxy} {
+		if proxy == "" {
+			continue
+		}
+		if _, err := ParseProxyURL(proxy); err != nil {
+			return err
+		}
+	}
+	switch c.Binance.SymbolSource {
+	case SymbolSourceAPI:
+	case SymbolSourceStatic:
+		if len(c.Binance.StaticSymbols) == 0 {
+			return fmt.Errorf("static symbol source needs at least one static symbol")
+		}
+	case SymbolSourceFile:
+		if c.Binance.SymbolsFile == "" {
+			return fmt.Errorf("file symbol source needs a symbols file")
+		}
+	default:
+		return fmt.Errorf("symbol source must be %q, %q or %q", SymbolSourceAPI, SymbolSourceStatic, SymbolSourceFile)
+	}
+	if !IsStreamType(c.Binance.DefaultStreamType) {
+		return fmt.Errorf("default stream type must be %q, %q or %q", StreamTypeTrade, StreamTypeAggTrade, StreamTypeMiniTicker)
+	}
+	switch c.Redis.CompressionCodec {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return fmt.Errorf("compression codec must be %q, %q or %q", CompressionNone, CompressionGzip, CompressionZstd)
+	}
 	if c.Redis.RetentionPeriod <= 0 {
 		return fmt.Errorf("retention period must be positive")
 	}
@@ -88,5 +486,96 @@ func (c *Config) Validate() error {
 	if c.Redis.MaxTradesPerKey < 0 {
 		return fmt.Errorf("max trades per key must be non-negative")
 	}
+	if c.Redis.MaxWritesPerSec < 0 || c.Redis.WriteBurst < 0 {
+		return fmt.Errorf("redis write limit and burst must be non-negative")
+	}
+	if c.Redis.MaxLatestTradeAge < 0 {
+		return fmt.Errorf("max latest trade age must be non-negative")
+	}
+	if c.Redis.QuarantineMaxLen <= 0 {
+		return fmt.Errorf("quarantine max len must be positive")
+	}
+	if c.Redis.PublishBatchSize < 1 {
+		return fmt.Errorf("publish batch size must be at least 1")
+	}
+	if c.Redis.PublishBatchSize > 1 && c.Redis.PublishBatchDelay <= 0 {
+		return fmt.Errorf("publish batch delay must be positive when batching")
+	}
+	if c.Binance.MaxStreamsPerConn < 0 {
+		return fmt.Errorf("max streams per connection must be non-negative")
+	}
+	if len(c.Binance.QuoteAssets) == 0 {
+		return fmt.Errorf("at least one quote asset must be set")
+	}
+	if c.Binance.SymbolRefreshInterval < 0 {
+		return fmt.Errorf("symbol refresh interval must be non-negative")
+	}
+	if c.Ingestion.RecordDir != "" {
+		if c.Ingestion.RecordMaxFileSize <= 0 || c.Ingestion.RecordMaxFileAge <= 0 {
+			return fmt.Errorf("record file size and age limits must be positive")
+		}
+		if c.Ingestion.RecordMaxTotalSize < c.Ingestion.RecordMaxFileSize {
+			return fmt.Errorf("record disk budget must be at least the max file size")
+		}
+	}
+	if c.Ingestion.WatchdogSilence < 0 || c.Ingestion.WatchdogMaxRestarts < 0 {
+		return fmt.Errorf("watchdog silence and max restarts must be non-negative")
+	}
+	if c.Ingestion.WatchdogActiveFrom < 0 || c.Ingestion.WatchdogActiveFrom > 23 ||
+		c.Ingestion.WatchdogActiveTo < 1 || c.Ingestion.WatchdogActiveTo > 24 {
+		return fmt.Errorf("watchdog active hours must be within 0-24")
+	}
+	if c.Ingestion.PublishQueueSize < 0 {
+		return fmt.Errorf("publish queue size must be non-negative")
+	}
+	switch c.Ingestion.PublishQueuePolicy {
+	case PublishPolicyBlock, PublishPolicyShed, PublishPolicyDropOldest:
+	default:
+		return fmt.Errorf("publish queue policy must be %q, %q or %q", PublishPolicyBlock, PublishPolicyShed, PublishPolicyDropOldest)
+	}
+	if c.Ingestion.PublishQueueSize > 0 && c.Ingestion.PublishWorkers <= 0 {
+		return fmt.Errorf("publish workers must be positive when the publish queue is enabled")
+	}
+	if c.Ingestion.ConnectionEventsMax <= 0 {
+		return fmt.Errorf("connection events max must be positive")
+	}
+	if c.Ingestion.GroupHeartbeat <= 0 {
+		return fmt.Errorf("group heartbeat must be positive")
+	}
+	if c.Ingestion.LeaderElection && c.Ingestion.LeaseTTL <= 0 {
+		return fmt.Errorf("lease TTL must be positive when leader election is enabled")
+	}
+	if c.Processor.DLQMaxLen <= 0 {
+		return fmt.Errorf("dead letter queue max length must be positive")
+	}
+	if a := c.Processor.Anomalies; a.Enabled {
+		if a.SizeSigmas <= 0 || a.SizeWindow <= 1 || a.SizeMinSamples <= 1 || a.SizeMinSamples > a.SizeWindow {
+			return fmt.Errorf("anomaly size sigmas must be positive and min samples between 2 and the size window")
+		}
+		if a.PriceJumpPercent <= 0 || a.PriceJumpWindow <= 0 || a.MaxEvents <= 0 {
+			return fmt.Errorf("anomaly price jump percent and window, and max events, must be positive")
+		}
+	}
+	if c.Cache.TTL < 0 || c.Cache.MaxEntries <= 0 {
+		return fmt.Errorf("cache TTL must be non-negative and max entries positive")
+	}
+	if c.Breaker.MaxFailures <= 0 || c.Breaker.Window <= 0 || c.Breaker.Cooldown <= 0 {
+		return fmt.Errorf("circuit breaker failures, window and cooldown must be positive")
+	}
+	if c.Postgres.CandleRetention < 0 || c.Postgres.CompressAfter < 0 || c.Postgres.RollupInterval < 0 {
+		return fmt.Errorf("candle retention, compression delay and rollup interval must be non-negative")
+	}
+	if c.Postgres.MaxBufferedCandles < 0 {
+		return fmt.Errorf("max buffered candles must be non-negative")
+	}
+	if c.Postgres.CandleRetention > 0 && (c.Postgres.PruneInterval <= 0 || c.Postgres.PruneBatchSize <= 0) {
+		return fmt.Errorf("prune interval and batch size must be positive when candle retention is set")
+	}
+	if c.Redis.Cluster && len(c.Redis.SentinelAddrs) > 0 {
+		return fmt.Errorf("redis cluster and sentinel modes are mutually exclusive")
+	}
+	if len(c.Redis.SentinelAddrs) > 0 && c.Redis.SentinelMasterName == "" {
+		return fmt.Errorf("sentinel master name is required when sentinel addresses are set")
+	}
 	return nil
 }
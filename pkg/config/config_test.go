@@ -90,6 +90,111 @@ func TestConfigValidation(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "invalid cache max entries",
+			modifyConfig: func(c *Config) {
+				c.Cache.MaxEntries = 0
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid prune batch size",
+			modifyConfig: func(c *Config) {
+				c.Postgres.CandleRetention = 90 * 24 * time.Hour
+				c.Postgres.PruneBatchSize = 0
+			},
+			expectError: true,
+		},
+		{
+			name: "negative max streams per connection",
+			modifyConfig: func(c *Config) {
+				c.Binance.MaxStreamsPerConn = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "anomaly min samples above window",
+			modifyConfig: func(c *Config) {
+				c.Processor.Anomalies.Enabled = true
+				c.Processor.Anomalies.SizeMinSamples = c.Processor.Anomalies.SizeWindow + 1
+			},
+			expectError: true,
+		},
+		{
+			name: "socks5 proxy",
+			modifyConfig: func(c *Config) {
+				c.Binance.HTTPProxy = "socks5://proxy:1080"
+			},
+			expectError: false,
+		},
+		{
+			name: "unsupported proxy scheme",
+			modifyConfig: func(c *Config) {
+				c.Binance.WSProxy = "ftp://proxy:21"
+			},
+			expectError: true,
+		},
+		{
+			name: "static symbol source",
+			modifyConfig: func(c *Config) {
+				c.Binance.SymbolSource = SymbolSourceStatic
+				c.Binance.StaticSymbols = []string{"BTCUSDT"}
+			},
+			expectError: false,
+		},
+		{
+			name: "static symbol source without symbols",
+			modifyConfig: func(c *Config) {
+				c.Binance.SymbolSource = SymbolSourceStatic
+				c.Binance.StaticSymbols = nil
+			},
+			expectError: true,
+		},
+		{
+			name: "file symbol source without a file",
+			modifyConfig: func(c *Config) {
+				c.Binance.SymbolSource = SymbolSourceFile
+				c.Binance.SymbolsFile = ""
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown symbol source",
+			modifyConfig: func(c *Config) {
+				c.Binance.SymbolSource = "dns"
+			},
+			expectError: true,
+		},
+		{
+			name: "aggTrade streams",
+			modifyConfig: func(c *Config) {
+				c.Binance.DefaultStreamType = StreamTypeAggTrade
+			},
+			expectError: false,
+		},
+		{
+			name: "unknown stream type",
+			modifyConfig: func(c *Config) {
+				c.Binance.DefaultStreamType = "depth"
+			},
+			expectError: true,
+		},
+		{
+			name: "publish batching without a delay",
+			modifyConfig: func(c *Config) {
+				c.Redis.PublishBatchSize = 100
+				c.Redis.PublishBatchDelay = 0
+			},
+			expectError: true,
+		},
+		{
+			name: "retention disabled",
+			modifyConfig: func(c *Config) {
+				c.Postgres.CandleRetention = 0
+				c.Postgres.PruneBatchSize = 0
+			},
+			expectError: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -107,3 +212,33 @@ func TestConfigValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestSentinelConfigFromEnv(t *testing.T) {
+	t.Setenv("REDIS_SENTINEL_ADDRS", "sentinel-1:26379, sentinel-2:26379,")
+	t.Setenv("REDIS_SENTINEL_MASTER", "orders")
+	t.Setenv("REDIS_SENTINEL_PASSWORD", "secret")
+
+	cfg := DefaultConfig()
+
+	expected := []string{"sentinel-1:26379", "sentinel-2:26379"}
+	if len(cfg.Redis.SentinelAddrs) != len(expected) {
+		t.Fatalf("Expected SentinelAddrs %v, got %v", expected, cfg.Redis.SentinelAddrs)
+	}
+	for i, addr := range expected {
+		if cfg.Redis.SentinelAddrs[i] != addr {
+			t.Errorf("Expected sentinel %s at position %d, got %s", addr, i, cfg.Redis.SentinelAddrs[i])
+		}
+	}
+
+	if cfg.Redis.SentinelMasterName != "orders" {
+		t.Errorf("Expected SentinelMasterName to be 'orders', got %v", cfg.Redis.SentinelMasterName)
+	}
+	if cfg.Redis.SentinelPassword != "secret" {
+		t.Errorf("Expected SentinelPassword to be 'secret', got %v", cfg.Redis.SentinelPassword)
+	}
+
+	cfg.Redis.SentinelMasterName = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected validation error for sentinel addresses without master name")
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// envBindings maps config keys to the environment variables that override them
+var envBindings = map[string]string{
+	"exchange":                       "EXCHANGE",
+	"redis.url":                      "REDIS_URL",
+	"redis.sentinel_addrs":           "REDIS_SENTINEL_ADDRS",
+	"redis.sentinel_master_name":     "REDIS_SENTINEL_MASTER",
+	"redis.sentinel_password":        "REDIS_SENTINEL_PASSWORD",
+	"redis.cluster":                  "REDIS_CLUSTER",
+	"binance.use_testnet":            "BINANCE_TESTNET",
+	"binance.api_key":                "BINANCE_API_KEY",
+	"binance.quote_assets":           "QUOTE_ASSETS",
+	"ingestion.record_dir":           "RECORD_DIR",
+	"ingestion.publish_queue_policy": "PUBLISH_QUEUE_POLICY",
+	"debug_addr":                     "DEBUG_ADDR",
+	"api.addr":                       "API_ADDR",
+}
+
+// LoadFile returns the default configuration overlaid with the values of a
+// YAML config file. Environment variables still take precedence over the file.
+func LoadFile(path string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	for key, env := range envBindings {
+		if err := v.BindEnv(key, env); err != nil {
+			return nil, fmt.Errorf("failed to bind %s: %w", env, err)
+		}
+	}
+
+	// Keys missing from both the file and the environment keep their defaults
+	err := v.Unmarshal(cfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	// Namespace keys under the configured exchange unless a prefix is explicit
+	if v.IsSet("exchange") && !v.IsSet("redis.key_prefix") {
+		cfg.SetExchange(cfg.Exchange)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "streamer.yaml")
+	yaml := `
+redis:
+  url: redis://file-host:6379/1
+  retention_period: 2h
+  key_prefix: "file:"
+  sentinel_addrs: [sentinel-a:26379, sentinel-b:26379]
+binance:
+  max_symbols: 12
+  main_symbols: [BTCUSDT, SOLUSDT]
+  min_daily_volume: 2500000
+  symbol_refresh_interval: 15m
+websocket:
+  reconnect_delay: 3s
+  ping_interval: 45s
+ingestion:
+  watchdog_max_restarts: 7
+debug: true
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("REDIS_URL", "")
+	t.Setenv("DEBUG_ADDR", "")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+
+	if cfg.Redis.URL != "redis://file-host:6379/1" {
+		t.Errorf("Redis.URL = %q", cfg.Redis.URL)
+	}
+	if cfg.Redis.RetentionPeriod != 2*time.Hour || cfg.Redis.KeyPrefix != "file:" {
+		t.Errorf("Redis section not populated: %+v", cfg.Redis)
+	}
+	if strings.Join(cfg.Redis.SentinelAddrs, ",") != "sentinel-a:26379,sentinel-b:26379" {
+		t.Errorf("Redis.SentinelAddrs = %v", cfg.Redis.SentinelAddrs)
+	}
+	if cfg.Binance.MaxSymbols != 12 || cfg.Binance.MinDailyVolume != 2500000 ||
+		cfg.Binance.SymbolRefreshInterval != 15*time.Minute {
+		t.Errorf("Binance section not populated: %+v", cfg.Binance)
+	}
+	if strings.Join(cfg.Binance.MainSymbols, ",") != "BTCUSDT,SOLUSDT" {
+		t.Errorf("Binance.MainSymbols = %v", cfg.Binance.MainSymbols)
+	}
+	if cfg.WebSocket.ReconnectDelay != 3*time.Second || cfg.WebSocket.PingInterval != 45*time.Second {
+		t.Errorf("WebSocket section not populated: %+v", cfg.WebSocket)
+	}
+	if cfg.Ingestion.WatchdogMaxRestarts != 7 || !cfg.Debug {
+		t.Errorf("Ingestion/Debug not populated: %+v debug=%v", cfg.Ingestion, cfg.Debug)
+	}
+
+	// Keys absent from the file keep their defaults
+	if cfg.Binance.MaxStreamsPerConn != 1000 || cfg.Redis.CleanupInterval != 5*time.Minute {
+		t.Errorf("Expected defaults for unset keys, got %+v %+v", cfg.Binance, cfg.Redis)
+	}
+
+	// Environment variables override the file
+	t.Setenv("REDIS_URL", "redis://env-host:6379/0")
+	t.Setenv("REDIS_SENTINEL_ADDRS", "env-sentinel:26379")
+	cfg, err = LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.Redis.URL != "redis://env-host:6379/0" {
+		t.Errorf("Expected env to override Redis.URL, got %q", cfg.Redis.URL)
+	}
+	if strings.Join(cfg.Redis.SentinelAddrs, ",") != "env-sentinel:26379" {
+		t.Errorf("Expected env to override Redis.SentinelAddrs, got %v", cfg.Redis.SentinelAddrs)
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for a missing config file")
+	}
+}
@@ -0,0 +1,175 @@
+// Package coordination lets replicas of a process agree on which of them
+// does work that must not run twice.
+package coordination
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// leading is 1 while this process holds the lease
+var leading = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "binance_coordination_leader",
+	Help: "1 while this process holds the leader lease, 0 otherwise",
+})
+
+// acquireScript takes the lease when it is free and extends it when it is
+// already held by the caller, in one step so a lapsed lease is never
+// extended for someone else
+var acquireScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+if not owner then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseScript deletes the lease if it is still held by the caller
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Elector holds a leader lease in a Redis key shared by replicas: the key
+// names its holder and expires ttl after the last renewal, so another
+// replica takes over once the leader stops renewing it.
+type Elector struct {
+	client redis.UniversalClient
+	key    string
+	id     string
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewElector returns an elector competing for the lease in key as id, which
+// must be unique among the replicas
+func NewElector(client redis.UniversalClient, key, id string, ttl time.Duration) *Elector {
+	return &Elector{client: client, key: key, id: id, ttl: ttl, now: time.Now}
+}
+
+// RenewInterval is how often Run should be ticked: a third of the TTL, so a
+// renewal can fail once and still be retried before the lease lapses
+func (e *Elector) RenewInterval() time.Duration {
+	return e.ttl / 3
+}
+
+// acquire takes or renews the lease and reports whether e holds it
+func (e *Elector) acquire(ctx context.Context) (bool, error) {
+	held, err := acquireScript.Run(ctx, e.client, []string{e.key}, e.id, e.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire leader lease: %w", err)
+	}
+	return held == 1, nil
+}
+
+// release gives the lease up if e still holds it
+func (e *Elector) release(ctx context.Context) error {
+	if err := releaseScript.Run(ctx, e.client, []string{e.key}, e.id).Err(); err != nil {
+		return fmt.Errorf("failed to release leader lease: %w", err)
+	}
+	return nil
+}
+
+// leadership is an elector's state between rounds
+type leadership struct {
+	cancel  context.CancelFunc // Stops lead; nil while following
+	done    chan error         // Receives lead's result
+	renewed time.Time          // When the last successful renewal was sent
+}
+
+// leading reports whether lead is running
+func (l *leadership) leading() bool {
+	return l.done != nil
+}
+
+// start runs lead in a goroutine under a context of its own
+func (l *leadership) start(ctx context.Context, lead func(ctx context.Context) error) {
+	leadCtx, cancel := context.WithCancel(ctx)
+	l.cancel, l.done = cancel, make(chan error, 1)
+	go func() { l.done <- lead(leadCtx) }()
+	leading.Set(1)
+}
+
+// stepDown stops lead and waits for it to return
+func (l *leadership) stepDown() {
+	l.cancel()
+	<-l.done
+	l.cancel, l.done = nil, nil
+	leading.Set(0)
+}
+
+// Run competes for the lease on start and on each tick until ctx is
+// cancelled, running lead while e holds it. lead's context is cancelled when
+// the lease is lost to another replica, or when renewals fail for so long
+// that it would lapse before the next tick; Run waits for lead to return and
+// competes again. The lease is released when ctx is cancelled, so a follower
+// need not wait for it to expire. If lead returns on its own, Run releases
+// the lease and returns its error.
+func (e *Elector) Run(ctx context.Context, ticks <-chan time.Time, lead func(ctx context.Context) error) error {
+	var l leadership
+	defer func() {
+		if l.leading() {
+			l.stepDown()
+		}
+		if err := e.release(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}()
+
+	for {
+		if err := e.round(ctx, &l, lead); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-l.done:
+			l.cancel()
+			l.cancel, l.done = nil, nil
+			leading.Set(0)
+			return err
+		case <-ticks:
+		}
+	}
+}
+
+// round takes or renews the lease once, starting lead when it is won and
+// stopping it when it is lost. It only fails once ctx is cancelled.
+func (e *Elector) round(ctx context.Context, l *leadership, lead func(ctx context.Context) error) error {
+	sent := e.now()
+	held, err := e.acquire(ctx)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return ctx.Err()
+	case err != nil:
+		log.Printf("Warning: %v", err)
+		if l.leading() && !sent.Add(e.RenewInterval()).Before(l.renewed.Add(e.ttl)) {
+			log.Printf("Leader lease %s may lapse before it can be renewed, stepping down", e.key)
+			l.stepDown()
+		}
+	case held && !l.leading():
+		log.Printf("Acquired leader lease %s as %s", e.key, e.id)
+		l.renewed = sent
+		l.start(ctx, lead)
+	case held:
+		l.renewed = sent
+	case l.leading():
+		log.Printf("Lost leader lease %s to another replica, stepping down", e.key)
+		l.stepDown()
+	}
+	return nil
+}
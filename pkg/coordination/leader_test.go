@@ -0,0 +1,192 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	testKey = "test:ingestion:leader"
+	testTTL = 15 * time.Second
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+// replica drives an elector round by round on a clock the test moves
+type replica struct {
+	elector *Elector
+	state   leadership
+	now     time.Time
+}
+
+func newReplica(client redis.UniversalClient, id string, now time.Time) *replica {
+	r := &replica{elector: NewElector(client, testKey, id, testTTL), now: now}
+	r.elector.now = func() time.Time { return r.now }
+	return r
+}
+
+// round runs one election round, leading until told to step down
+func (r *replica) round(t *testing.T) {
+	t.Helper()
+	err := r.elector.round(context.Background(), &r.state, func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// advance moves the replica's clock and Redis time forward by d
+func (r *replica) advance(mr *miniredis.Miniredis, d time.Duration) {
+	r.now = r.now.Add(d)
+	mr.FastForward(d)
+}
+
+// stop steps down if leading, as Run does on return
+func (r *replica) stop() {
+	if r.state.leading() {
+		r.state.stepDown()
+	}
+}
+
+func (r *replica) expectLeading(t *testing.T, want bool) {
+	t.Helper()
+	if r.state.leading() != want {
+		t.Fatalf("%s leading = %v, want %v", r.elector.id, r.state.leading(), want)
+	}
+}
+
+func TestElector_FollowerTakesOverExpiredLease(t *testing.T) {
+	mr, client := newTestRedis(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a, b := newReplica(client, "a", start), newReplica(client, "b", start)
+	defer a.stop()
+	defer b.stop()
+
+	a.round(t)
+	a.expectLeading(t, true)
+	b.round(t)
+	b.expectLeading(t, false)
+
+	// Renewals keep the lease from expiring
+	a.advance(mr, testTTL/2)
+	a.round(t)
+	if got := mr.TTL(testKey); got != testTTL {
+		t.Errorf("Expected a renewal to reset the lease to %s, got %s", testTTL, got)
+	}
+
+	// a hangs without renewing: b takes over once the lease expires
+	mr.FastForward(testTTL - time.Second)
+	b.round(t)
+	b.expectLeading(t, false)
+	mr.FastForward(time.Second)
+	b.round(t)
+	b.expectLeading(t, true)
+	if got, _ := mr.Get(testKey); got != "b" {
+		t.Errorf("Expected the lease held by b, got %q", got)
+	}
+
+	// a steps down as soon as it sees the lease taken, and b keeps it
+	a.round(t)
+	a.expectLeading(t, false)
+	b.round(t)
+	b.expectLeading(t, true)
+}
+
+func TestElector_StepsDownWhenRenewalsFail(t *testing.T) {
+	mr, client := newTestRedis(t)
+	a := newReplica(client, "a", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	defer a.stop()
+
+	a.round(t)
+	a.expectLeading(t, true)
+
+	// One failed renewal leaves time for another before the lease lapses
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	a.advance(mr, a.elector.RenewInterval())
+	a.round(t)
+	a.expectLeading(t, true)
+
+	// The next renewal would come too late, so a stops leading before a
+	// follower could take over
+	a.advance(mr, a.elector.RenewInterval())
+	a.round(t)
+	a.expectLeading(t, false)
+
+	// Redis recovers with the lease still held by a
+	mr.SetError("")
+	a.round(t)
+	a.expectLeading(t, true)
+}
+
+func TestElector_RunReleasesLeaseOnShutdown(t *testing.T) {
+	mr, client := newTestRedis(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leading := make(chan struct{})
+	stopped := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- NewElector(client, testKey, "a", testTTL).Run(ctx, nil, func(ctx context.Context) error {
+			close(leading)
+			<-ctx.Done()
+			close(stopped)
+			return nil
+		})
+	}()
+
+	<-leading
+	if got, _ := mr.Get(testKey); got != "a" {
+		t.Errorf("Expected the lease held by a, got %q", got)
+	}
+	cancel()
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Run to return context.Canceled, got %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("Expected Run to wait for lead to stop")
+	}
+	// A follower need not wait for the lease to expire
+	if mr.Exists(testKey) {
+		t.Error("Expected the lease released on shutdown")
+	}
+}
+
+func TestElector_RunReturnsWhenLeadFails(t *testing.T) {
+	mr, client := newTestRedis(t)
+	failure := errors.New("watchdog gave up")
+
+	err := NewElector(client, testKey, "a", testTTL).Run(context.Background(), nil, func(ctx context.Context) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the lead error, got %v", err)
+	}
+	if mr.Exists(testKey) {
+		t.Error("Expected the lease released after lead failed")
+	}
+}
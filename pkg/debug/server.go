@@ -0,0 +1,75 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Server exposes operational endpoints (runtime stats, connection state) over HTTP
+type Server struct {
+	srv *http.Server
+	mux *http.ServeMux
+}
+
+// NewServer creates a debug server listening on addr
+func NewServer(addr string) *Server {
+	mux := http.NewServeMux()
+	return &Server{
+		mux: mux,
+		srv: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// Handle registers a handler for the given path
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Handler returns the server's request router
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Start serves requests until ctx is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := s.srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down debug server: %v", err)
+		}
+	}()
+
+	log.Printf("Debug server listening on %s", s.srv.Addr)
+	if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("debug server failed: %w", err)
+	}
+	return nil
+}
+
+// JSON returns a handler that encodes the result of fn as JSON
+func JSON(fn func(r *http.Request) (interface{}, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, err := fn(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Printf("Failed to encode debug response: %v", err)
+		}
+	})
+}
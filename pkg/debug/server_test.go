@@ -0,0 +1,38 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJSONHandler(t *testing.T) {
+	server := NewServer(":0")
+	server.Handle("/debug/value", JSON(func(r *http.Request) (interface{}, error) {
+		return map[string]string{"reset": r.URL.Query().Get("reset")}, nil
+	}))
+	server.Handle("/debug/fail", JSON(func(*http.Request) (interface{}, error) {
+		return nil, fmt.Errorf("boom")
+	}))
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/value?reset=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", ct)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["reset"] != "true" {
+		t.Errorf("Unexpected body %q (err=%v)", rec.Body.String(), err)
+	}
+
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/fail", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 on handler error, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// DefaultName is the exchange used when none is configured
+const DefaultName = "binance"
+
+// Errors returned by clients, wrapped with details; branch on them with
+// errors.Is
+var (
+	// ErrRateLimited means the exchange refused a request for exceeding its
+	// request limits
+	ErrRateLimited = errors.New("rate limited by exchange")
+	// ErrBadSymbol means a symbol is malformed or unknown to the exchange
+	ErrBadSymbol = errors.New("bad symbol")
+)
+
+// MessageHandler receives each raw message read from a trade stream. Frames
+// the client handles itself, such as tickers, are passed as a nil message,
+// so the connection still shows as alive without a trade to parse.
+type MessageHandler func(message []byte) error
+
+// Client is implemented by every venue trades can be ingested from
+type Client interface {
+	// Name returns the exchange identifier used to namespace stored data
+	Name() string
+	// GetSymbols returns the lowercased symbols to stream
+	GetSymbols(ctx context.Context) ([]string, error)
+	// StreamTrades streams trades for symbols over a single connection,
+	// passing every raw message to handler until ctx is cancelled or the
+	// connection fails
+	StreamTrades(ctx context.Context, symbols []string, handler MessageHandler) error
+	// ParseTrade converts a raw stream message into a normalized trade event
+	ParseTrade(message []byte) (*models.AggTradeEvent, error)
+}
+
+// MaxStreamURLLength is the longest stream URL connections are opened with.
+// Longer URLs are rejected by exchanges and proxies, so symbol groups are
+// split until their URL fits.
+const MaxStreamURLLength = 8000
+
+// StreamURLBuilder is optionally implemented by clients whose streams are
+// addressed by a single URL
+type StreamURLBuilder interface {
+	BuildStreamURL(symbols []string) string
+}
+
+// VolumeReporter is optionally implemented by clients that know the 24h
+// volume of the symbols GetSymbols returned
+type VolumeReporter interface {
+	// SymbolVolumes returns the 24h quote volume of each lowercased symbol
+	// seen by the last GetSymbols call, or nil when none was fetched
+	SymbolVolumes() map[string]float64
+}
+
+// SymbolLister is optionally implemented by clients that can list every
+// symbol the exchange trades, streamed or not
+type SymbolLister interface {
+	// ListSymbols returns the upper-case symbols currently trading
+	ListSymbols(ctx context.Context) ([]string, error)
+}
+
+// GapRecoverer is optionally implemented by clients that can backfill the
+// trades a dropped connection missed
+type GapRecoverer interface {
+	// RecoverGap passes the trades of symbol after gapStart and up to
+	// gapEnd to handle, oldest first, as events of the symbol's stream
+	RecoverGap(ctx context.Context, symbol string, gapStart, gapEnd time.Time, handle func(*models.AggTradeEvent) error) error
+}
@@ -0,0 +1,133 @@
+// Package fake provides an in-memory exchange used by tests and the
+// integration harness to exercise ingestion without a live venue.
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/exchange"
+)
+
+// Name is the exchange identifier of the fake venue
+const Name = "fake"
+
+// Trade is the wire format of a fake trade message
+type Trade struct {
+	Symbol   string `json:"symbol"`
+	Price    string `json:"price"`
+	Quantity string `json:"qty"`
+	ID       int64  `json:"id"`
+	Time     int64  `json:"time"` // Unix milliseconds
+	Side     string `json:"side"` // "buy" or "sell" (taker side)
+}
+
+// Exchange is an exchange.Client whose symbols and trades are set up front.
+// StreamTrades replays the queued trades of the requested symbols and then
+// blocks until its context is cancelled.
+type Exchange struct {
+	mu      sync.Mutex
+	symbols []string
+	trades  []Trade
+	streams int
+}
+
+var _ exchange.Client = (*Exchange)(nil)
+
+// New creates a fake exchange listing the given symbols
+func New(symbols ...string) *Exchange {
+	return &Exchange{symbols: symbols}
+}
+
+// AddTrades queues trades to be replayed by the next StreamTrades calls
+func (e *Exchange) AddTrades(trades ...Trade) {
+	e.mu.Lock()
+	e.trades = append(e.trades, trades...)
+	e.mu.Unlock()
+}
+
+// Streams returns how many times StreamTrades has been called
+func (e *Exchange) Streams() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.streams
+}
+
+// Name returns the exchange identifier
+func (e *Exchange) Name() string {
+	return Name
+}
+
+// GetSymbols returns the configured symbols, lowercased
+func (e *Exchange) GetSymbols(ctx context.Context) ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	symbols := make([]string, len(e.symbols))
+	for i, symbol := range e.symbols {
+		symbols[i] = strings.ToLower(symbol)
+	}
+	return symbols, nil
+}
+
+// StreamTrades passes queued trades of symbols to handler, then waits for ctx
+func (e *Exchange) StreamTrades(ctx context.Context, symbols []string, handler exchange.MessageHandler) error {
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[strings.ToUpper(symbol)] = true
+	}
+
+	e.mu.Lock()
+	e.streams++
+	var pending []Trade
+	for _, trade := range e.trades {
+		if wanted[strings.ToUpper(trade.Symbol)] {
+			pending = append(pending, trade)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, trade := range pending {
+		message, err := json.Marshal(trade)
+		if err != nil {
+			return fmt.Errorf("failed to encode fake trade: %w", err)
+		}
+		if err := handler(message); err != nil {
+			return err
+		}
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// ParseTrade converts a fake trade message into a normalized trade event
+func (e *Exchange) ParseTrade(message []byte) (*models.AggTradeEvent, error) {
+	var trade Trade
+	if err := json.Unmarshal(message, &trade); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fake trade: %w", err)
+	}
+	if trade.Symbol == "" {
+		return nil, fmt.Errorf("fake trade has no symbol")
+	}
+
+	symbol := strings.ToUpper(trade.Symbol)
+	return &models.AggTradeEvent{
+		Stream: strings.ToLower(symbol) + "@trade",
+		Data: models.TradeData{
+			EventType:    models.EventTypeTrade,
+			EventTime:    trade.Time,
+			Symbol:       symbol,
+			TradeID:      trade.ID,
+			Price:        trade.Price,
+			Quantity:     trade.Quantity,
+			TradeTime:    trade.Time,
+			IsBuyerMaker: trade.Side == "sell",
+		},
+		Raw: message,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExchange_StreamTradesReplaysRequestedSymbols(t *testing.T) {
+	ex := New("BTCUSDT", "ETHUSDT")
+	ex.AddTrades(
+		Trade{Symbol: "BTCUSDT", Price: "50000", Quantity: "1", ID: 1, Time: 1000, Side: "buy"},
+		Trade{Symbol: "ETHUSDT", Price: "3000", Quantity: "2", ID: 2, Time: 2000, Side: "sell"},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var messages [][]byte
+	done := make(chan error, 1)
+	go func() {
+		done <- ex.StreamTrades(ctx, []string{"btcusdt"}, func(message []byte) error {
+			messages = append(messages, message)
+			cancel()
+			return nil
+		})
+	}()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message for btcusdt, got %d", len(messages))
+	}
+
+	event, err := ex.ParseTrade(messages[0])
+	if err != nil {
+		t.Fatalf("ParseTrade failed: %v", err)
+	}
+	if event.Data.Symbol != "BTCUSDT" || event.Data.Price != "50000" || event.Data.TradeID != 1 {
+		t.Errorf("Unexpected event data: %+v", event.Data)
+	}
+	if event.Data.IsBuyerMaker {
+		t.Error("Expected buy-side taker to not be buyer maker")
+	}
+	if ex.Streams() != 1 {
+		t.Errorf("Expected 1 stream, got %d", ex.Streams())
+	}
+}
+
+func TestExchange_GetSymbolsLowercases(t *testing.T) {
+	symbols, err := New("BTCUSDT").GetSymbols(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(symbols) != 1 || symbols[0] != "btcusdt" {
+		t.Errorf("Expected [btcusdt], got %v", symbols)
+	}
+}
+
+func TestExchange_ParseTradeRejectsInvalid(t *testing.T) {
+	ex := New()
+	if _, err := ex.ParseTrade([]byte(`not json`)); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+	if _, err := ex.ParseTrade([]byte(`{"price":"1"}`)); err == nil {
+		t.Error("Expected error for missing symbol")
+	}
+}
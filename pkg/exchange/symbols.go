@@ -0,0 +1,201 @@
+package exchange
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+// SymbolSource provides the symbols to stream
+type SymbolSource interface {
+	// Symbols returns the lowercased symbols to stream
+	Symbols(ctx context.Context) ([]string, error)
+}
+
+// SymbolWatcher is optionally implemented by symbol sources that know when
+// their symbols change
+type SymbolWatcher interface {
+	// Watch calls onChange each time the symbols change, until ctx is
+	// cancelled
+	Watch(ctx context.Context, onChange func()) error
+}
+
+// NewSymbolSource returns the symbol source selected by cfg.SymbolSource,
+// discovering symbols through client by default
+func NewSymbolSource(cfg config.BinanceConfig, client Client) (SymbolSource, error) {
+	switch cfg.SymbolSource {
+	case "", config.SymbolSourceAPI:
+		return ClientSymbols{Client: client}, nil
+	case config.SymbolSourceStatic:
+		return NewStaticSymbolSource(cfg.StaticSymbols)
+	case config.SymbolSourceFile:
+		return NewFileSymbolSource(cfg.SymbolsFile)
+	default:
+		return nil, fmt.Errorf("unknown symbol source %q", cfg.SymbolSource)
+	}
+}
+
+// ClientSymbols discovers symbols through the exchange API
+type ClientSymbols struct {
+	Client Client
+}
+
+// Symbols returns the client's GetSymbols
+func (s ClientSymbols) Symbols(ctx context.Context) ([]string, error) {
+	return s.Client.GetSymbols(ctx)
+}
+
+// StaticSymbolSource streams a fixed list of symbols
+type StaticSymbolSource struct {
+	symbols []string
+}
+
+// NewStaticSymbolSource returns a source of symbols, lowercased and without
+// duplicates
+func NewStaticSymbolSource(symbols []string) (*StaticSymbolSource, error) {
+	normalized := normalizeSymbols(symbols)
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("no static symbols configured")
+	}
+	return &StaticSymbolSource{symbols: normalized}, nil
+}
+
+// Symbols returns the configured symbols
+func (s *StaticSymbolSource) Symbols(ctx context.Context) ([]string, error) {
+	return append([]string(nil), s.symbols...), nil
+}
+
+// FileSymbolSource streams the symbols listed in a file: separated by
+// newlines, commas or spaces, with # starting a comment. Watch reloads the
+// file when it changes; a file that cannot be read or lists no symbols
+// keeps the previous list.
+type FileSymbolSource struct {
+	path string
+
+	mu      sync.RWMutex
+	symbols []string
+}
+
+// NewFileSymbolSource loads the symbols listed in path
+func NewFileSymbolSource(path string) (*FileSymbolSource, error) {
+	s := &FileSymbolSource{path: filepath.Clean(path)}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Symbols returns the symbols of the last successful load
+func (s *FileSymbolSource) Symbols(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.symbols...), nil
+}
+
+// Reload re-reads the file and reports whether its symbols changed
+func (s *FileSymbolSource) Reload() (bool, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open symbols file: %w", err)
+	}
+	defer f.Close()
+
+	symbols, err := parseSymbolList(f)
+	if err != nil {
+		return false, fmt.Errorf("failed to read symbols file %s: %w", s.path, err)
+	}
+	if len(symbols) == 0 {
+		return false, fmt.Errorf("symbols file %s lists no symbols", s.path)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := strings.Join(symbols, ",") != strings.Join(s.symbols, ",")
+	s.symbols = symbols
+	return changed, nil
+}
+
+// Watch reloads the file whenever it is written or replaced and calls
+// onChange when its symbols changed. The file's directory is watched, so
+// editors that save by renaming a new file over the old one are seen too.
+func (s *FileSymbolSource) Watch(ctx context.Context, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch symbols file: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(s.path)); err != nil {
+		return fmt.Errorf("failed to watch symbols file: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != s.path || !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			changed, err := s.Reload()
+			if err != nil {
+				log.Printf("Warning: keeping previous symbols: %v", err)
+				continue
+			}
+			if changed {
+				onChange()
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("Warning: symbols file watch error: %v", err)
+		}
+	}
+}
+
+// parseSymbolList reads symbols separated by newlines, commas or spaces,
+// ignoring # comments
+func parseSymbolList(r io.Reader) ([]string, error) {
+	var symbols []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		symbols = append(symbols, strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return normalizeSymbols(symbols), nil
+}
+
+// normalizeSymbols lowercases symbols and drops blanks and duplicates,
+// keeping the first occurrence
+func normalizeSymbols(symbols []string) []string {
+	seen := make(map[string]bool, len(symbols))
+	out := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		symbol = strings.ToLower(strings.TrimSpace(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		out = append(out, symbol)
+	}
+	return out
+}
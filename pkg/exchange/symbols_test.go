@@ -0,0 +1,169 @@
+package exchange
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+func writeSymbolsFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseSymbolList(t *testing.T) {
+	input := "# pinned pairs\nBTCUSDT\nethusdt, SOLUSDT  # majors\n\nbtcusdt\tXRPUSDT\r\n"
+	got, err := parseSymbolList(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"btcusdt", "ethusdt", "solusdt", "xrpusdt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSymbolList() = %v, want %v", got, want)
+	}
+}
+
+func TestNewSymbolSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "symbols.txt")
+	writeSymbolsFile(t, path, "BTCUSDT\nETHUSDT\n")
+
+	tests := []struct {
+		name    string
+		cfg     config.BinanceConfig
+		want    []string
+		wantErr bool
+	}{
+		{"static", config.BinanceConfig{SymbolSource: config.SymbolSourceStatic, StaticSymbols: []string{"BTCUSDT", "btcusdt", "ETHUSDT"}}, []string{"btcusdt", "ethusdt"}, false},
+		{"file", config.BinanceConfig{SymbolSource: config.SymbolSourceFile, SymbolsFile: path}, []string{"btcusdt", "ethusdt"}, false},
+		{"empty static list", config.BinanceConfig{SymbolSource: config.SymbolSourceStatic}, nil, true},
+		{"missing file", config.BinanceConfig{SymbolSource: config.SymbolSourceFile, SymbolsFile: path + ".missing"}, nil, true},
+		{"unknown source", config.BinanceConfig{SymbolSource: "dns"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := NewSymbolSource(tt.cfg, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewSymbolSource() error = %v", err)
+			}
+			got, err := source.Symbols(context.Background())
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Symbols() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	if source, err := NewSymbolSource(config.BinanceConfig{SymbolSource: config.SymbolSourceAPI}, nil); err != nil {
+		t.Errorf("NewSymbolSource(api) error = %v", err)
+	} else if _, ok := source.(ClientSymbols); !ok {
+		t.Errorf("Expected the API source to use the client, got %T", source)
+	}
+}
+
+func TestFileSymbolSource_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "symbols.txt")
+	writeSymbolsFile(t, path, "BTCUSDT\n")
+
+	source, err := NewFileSymbolSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := source.Reload(); err != nil || changed {
+		t.Errorf("Reload() of an unchanged file = %v, %v, want false", changed, err)
+	}
+
+	writeSymbolsFile(t, path, "BTCUSDT\nETHUSDT\n")
+	if changed, err := source.Reload(); err != nil || !changed {
+		t.Errorf("Reload() of a changed file = %v, %v, want true", changed, err)
+	}
+
+	// A file emptied mid-write keeps the previous symbols
+	writeSymbolsFile(t, path, "# nothing yet\n")
+	if _, err := source.Reload(); err == nil {
+		t.Error("Expected an error for a file without symbols")
+	}
+	got, _ := source.Symbols(context.Background())
+	if want := []string{"btcusdt", "ethusdt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Symbols() = %v, want %v", got, want)
+	}
+}
+
+func TestFileSymbolSource_Watch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "symbols.txt")
+	writeSymbolsFile(t, path, "BTCUSDT\n")
+
+	source, err := NewFileSymbolSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan struct{}, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- source.Watch(ctx, func() { changes <- struct{}{} })
+	}()
+
+	waitFor := func(want []string) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for {
+			select {
+			case <-changes:
+				got, _ := source.Symbols(context.Background())
+				if reflect.DeepEqual(got, want) {
+					return
+				}
+			case <-deadline:
+				got, _ := source.Symbols(context.Background())
+				t.Fatalf("Timed out waiting for %v, have %v", want, got)
+			}
+		}
+	}
+
+	// The watch starts asynchronously, so keep writing until it is seen
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				os.WriteFile(path, []byte("BTCUSDT\nETHUSDT\n"), 0o644)
+			}
+		}
+	}()
+	waitFor([]string{"btcusdt", "ethusdt"})
+	close(stop)
+
+	// Editors that save by renaming a new file over the old one are seen too
+	tmp := filepath.Join(dir, "symbols.txt.tmp")
+	writeSymbolsFile(t, tmp, "SOLUSDT\n")
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor([]string{"solusdt"})
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch() error = %v", err)
+	}
+}
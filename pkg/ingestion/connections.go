@@ -0,0 +1,204 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/exchange"
+	"binance-redis-streamer/pkg/metrics"
+)
+
+// connectionRateWindow is the window, in seconds, that
+// ConnectionMetrics.MessagesPerSecond averages over
+const connectionRateWindow = 60
+
+// ConnectionMetrics is the traffic of one symbol group's WebSocket
+// connection, counted across its reconnects
+type ConnectionMetrics struct {
+	Group             string    `json:"group"`
+	URL               string    `json:"url,omitempty"` // Set when the exchange builds stream URLs
+	MessagesReceived  int64     `json:"messages_received"`
+	BytesReceived     int64     `json:"bytes_received"`
+	ErrorCount        int64     `json:"error_count"` // Stream failures and rejected messages
+	LastMessageTime   time.Time `json:"last_message_time"`
+	MessagesPerSecond float64   `json:"messages_per_second"` // Over the last connectionRateWindow seconds
+}
+
+// connectionEventRecorder persists connection events
+type connectionEventRecorder interface {
+	RecordConnectionEvent(ctx context.Context, event *models.ConnectionEvent) error
+}
+
+// recordConnectionEvent logs a connect or disconnect of group, with the
+// error that dropped the connection. Failing to record it only warns.
+func (s *Service) recordConnectionEvent(ctx context.Context, group, kind string, cause error) {
+	if s.events == nil {
+		return
+	}
+	event := &models.ConnectionEvent{Kind: kind, Group: group, Time: s.now()}
+	if cause != nil {
+		event.Error = cause.Error()
+	}
+	if err := s.events.RecordConnectionEvent(ctx, event); err != nil {
+		log.Printf("Warning: failed to record %s event for %s: %v", kind, group, err)
+	}
+}
+
+// connectionTracker records the traffic of one connection
+type connectionTracker struct {
+	id       string // Unique across processes and regroups
+	mu       sync.Mutex
+	metrics  ConnectionMetrics
+	rate     messageRate
+	messages prometheus.Counter
+
+	// The group's assignment, reported to Redis
+	symbols     []string
+	connectedAt time.Time
+	connections int
+}
+
+// connect records a new connection of the group established at now
+func (t *connectionTracker) connect(now time.Time) {
+	t.mu.Lock()
+	t.connectedAt = now
+	t.connections++
+	t.mu.Unlock()
+}
+
+// streamGroup returns the group's assignment as of now
+func (t *connectionTracker) streamGroup(process string, now time.Time) *models.StreamGroup {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &models.StreamGroup{
+		Group:       t.metrics.Group,
+		Connection:  t.id,
+		Symbols:     t.symbols,
+		Process:     process,
+		ConnectedAt: t.connectedAt,
+		Reconnects:  max(0, t.connections-1),
+		Heartbeat:   now,
+	}
+}
+
+// message records a received message of size bytes
+func (t *connectionTracker) message(size int, now time.Time) {
+	t.messages.Inc()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.metrics.MessagesReceived++
+	t.metrics.BytesReceived += int64(size)
+	t.metrics.LastMessageTime = now
+	t.rate.add(now)
+}
+
+// failure records a stream failure or a message that could not be handled
+func (t *connectionTracker) failure() {
+	t.mu.Lock()
+	t.metrics.ErrorCount++
+	t.mu.Unlock()
+}
+
+// snapshot returns the connection's metrics as of now
+func (t *connectionTracker) snapshot(now time.Time) ConnectionMetrics {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m := t.metrics
+	m.MessagesPerSecond = t.rate.perSecond(now)
+	return m
+}
+
+// messageRate is a ring buffer of per-second message counts covering the
+// last connectionRateWindow seconds
+type messageRate struct {
+	buckets [connectionRateWindow]struct {
+		second int64 // Unix second the count belongs to
+		count  int64
+	}
+}
+
+// add counts a message received at now, reusing the bucket of a second that
+// has left the window
+func (r *messageRate) add(now time.Time) {
+	second := now.Unix()
+	bucket := &r.buckets[second%connectionRateWindow]
+	if bucket.second != second {
+		bucket.second = second
+		bucket.count = 0
+	}
+	bucket.count++
+}
+
+// perSecond returns the average message rate over the window ending at now
+func (r *messageRate) perSecond(now time.Time) float64 {
+	second := now.Unix()
+	var total int64
+	for _, bucket := range r.buckets {
+		if bucket.second > second-connectionRateWindow && bucket.second <= second {
+			total += bucket.count
+		}
+	}
+	return float64(total) / connectionRateWindow
+}
+
+// trackConnection starts tracking the connection of a symbol group under a
+// new connection ID. A regroup can start a group before the connection it
+// replaces, under the same name, has stopped, so neither is keyed by name.
+func (s *Service) trackConnection(symbols []string) *connectionTracker {
+	group := recordGroupName(symbols)
+	tracker := &connectionTracker{
+		id:      fmt.Sprintf("%s/%d", s.process, s.nextConnectionID.Add(1)),
+		metrics: ConnectionMetrics{Group: group},
+		symbols: symbols,
+	}
+	if builder, ok := s.client.(exchange.StreamURLBuilder); ok {
+		tracker.metrics.URL = builder.BuildStreamURL(symbols)
+	}
+
+	s.connectionMu.Lock()
+	if s.groupConnections == nil {
+		s.groupConnections = make(map[string]int)
+	}
+	s.groupConnections[group]++
+	tracker.messages = metrics.IngestionMessages.WithLabelValues(group)
+	s.connectionMu.Unlock()
+
+	s.connections.Store(tracker.id, tracker)
+	return tracker
+}
+
+// untrackConnection stops tracking a connection once its group stops. The
+// group's message counter goes with its last connection.
+func (s *Service) untrackConnection(tracker *connectionTracker) {
+	s.connections.Delete(tracker.id)
+
+	group := tracker.metrics.Group
+	s.connectionMu.Lock()
+	defer s.connectionMu.Unlock()
+	s.groupConnections[group]--
+	if s.groupConnections[group] == 0 {
+		delete(s.groupConnections, group)
+		metrics.IngestionMessages.DeleteLabelValues(group)
+	}
+}
+
+// Connections returns the metrics of each streaming connection, ordered by
+// group
+func (s *Service) Connections() []ConnectionMetrics {
+	now := s.now()
+	connections := make([]ConnectionMetrics, 0)
+	s.connections.Range(func(_, value interface{}) bool {
+		connections = append(connections, value.(*connectionTracker).snapshot(now))
+		return true
+	})
+	sort.Slice(connections, func(i, j int) bool { return connections[i].Group < connections[j].Group })
+	return connections
+}
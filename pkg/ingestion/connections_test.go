@@ -0,0 +1,194 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/exchange"
+	"binance-redis-streamer/pkg/exchange/fake"
+	"binance-redis-streamer/pkg/metrics"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func TestMessageRate(t *testing.T) {
+	var rate messageRate
+	start := time.Unix(1_700_000_000, 0)
+
+	// 120 messages spread over the first 30 seconds
+	for i := 0; i < 120; i++ {
+		rate.add(start.Add(time.Duration(i) * 250 * time.Millisecond))
+	}
+	if got := rate.perSecond(start.Add(30 * time.Second)); got != 2 {
+		t.Errorf("Expected 2 messages per second over the window, got %v", got)
+	}
+
+	// Seconds that left the window no longer count, even once their buckets are reused
+	rate.add(start.Add(65 * time.Second))
+	if got := rate.perSecond(start.Add(65 * time.Second)); got != 97.0/60 {
+		t.Errorf("Expected the first 6 seconds to drop out, got %v", got)
+	}
+	if got := rate.perSecond(start.Add(10 * time.Minute)); got != 0 {
+		t.Errorf("Expected no rate after a long silence, got %v", got)
+	}
+}
+
+func TestConnectionMetrics_Load(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.SetExchange(fake.Name)
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.Binance.MaxStreamsPerConn = 1
+	cfg.Binance.SymbolRefreshInterval = 0
+	cfg.Ingestion.WatchdogSilence = 0
+
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// One connection per symbol, each delivering its own burst of trades
+	const perSymbol = 1000
+	symbols := []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "XRPUSDT"}
+	ex := fake.New(symbols...)
+	wantBytes := make(map[string]int64)
+	for i, symbol := range symbols {
+		for n := 0; n < perSymbol; n++ {
+			trade := fake.Trade{Symbol: symbol, Price: "100", Quantity: "1", ID: int64(n + 1), Time: int64(1000 + n)}
+			ex.AddTrades(trade)
+			data, _ := json.Marshal(trade)
+			wantBytes[recordGroupName([]string{symbols[i]})] += int64(len(data))
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	svc := NewService(cfg, ex, store)
+	go svc.Start(ctx)
+
+	var connections []ConnectionMetrics
+	for {
+		connections = svc.Connections()
+		var total int64
+		for _, c := range connections {
+			total += c.MessagesReceived
+		}
+		if len(connections) == len(symbols) && total == perSymbol*int64(len(symbols)) {
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("Timed out waiting for %d messages, got %+v", perSymbol*len(symbols), connections)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, c := range connections {
+		if c.MessagesReceived != perSymbol || c.BytesReceived != wantBytes[c.Group] || c.ErrorCount != 0 {
+			t.Errorf("Unexpected counters for %s: %+v", c.Group, c)
+		}
+		if c.LastMessageTime.IsZero() || c.MessagesPerSecond <= 0 {
+			t.Errorf("Expected a last message time and rate for %s: %+v", c.Group, c)
+		}
+		if got := testutil.ToFloat64(metrics.IngestionMessages.WithLabelValues(c.Group)); got != perSymbol {
+			t.Errorf("Expected the %s counter at %d, got %v", c.Group, perSymbol, got)
+		}
+	}
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(svc.Connections()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected connections to be untracked after shutdown, got %+v", svc.Connections())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// droppingClient delivers a message on every stream and drops the first
+// drops of them
+type droppingClient struct {
+	*fake.Exchange
+	drops   int
+	streams int
+}
+
+func (c *droppingClient) StreamTrades(ctx context.Context, symbols []string, handler exchange.MessageHandler) error {
+	c.streams++
+	handler([]byte(`{}`))
+	if c.streams <= c.drops {
+		return errors.New("connection reset by peer")
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestProcessSymbolGroup_RecordsConnectionEvents(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.WebSocket.ReconnectDelay = time.Millisecond
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	client := &droppingClient{Exchange: fake.New(), drops: 2}
+	svc := NewService(cfg, client, store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.processSymbolGroup(ctx, []string{"BTCUSDT"})
+	}()
+
+	want := []string{models.ConnectionUp, models.ConnectionDown, models.ConnectionUp, models.ConnectionDown, models.ConnectionUp}
+	var events []*models.ConnectionEvent
+	deadline := time.Now().Add(5 * time.Second)
+	for len(events) < len(want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d connection events, got %d", len(want), len(events))
+		}
+		time.Sleep(5 * time.Millisecond)
+		if events, err = store.GetConnectionEvents(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Shutting down is not an outage, but a stop
+	cancel()
+	<-done
+	if events, err = store.GetConnectionEvents(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, models.ConnectionStopped)
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events after shutdown, got %d", len(want), len(events))
+	}
+	for i, event := range events {
+		if event.Kind != want[i] || event.Group != "btcusdt-1" {
+			t.Errorf("Event %d = %+v, want %s for btcusdt-1", i, event, want[i])
+		}
+		if (event.Kind == models.ConnectionDown) != (event.Error == "connection reset by peer") {
+			t.Errorf("Event %d has error %q", i, event.Error)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ingestion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/exchange/fake"
+	"binance-redis-streamer/pkg/messaging"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func TestService_StreamsFromFakeExchange(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.SetExchange(fake.Name)
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.Binance.MaxStreamsPerConn = 2
+	cfg.Binance.SymbolRefreshInterval = 0
+	cfg.Ingestion.WatchdogSilence = 0
+
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ex := fake.New("BTCUSDT", "ETHUSDT", "SOLUSDT")
+	ex.AddTrades(
+		fake.Trade{Symbol: "BTCUSDT", Price: "50000", Quantity: "1", ID: 1, Time: 1000},
+		fake.Trade{Symbol: "SOLUSDT", Price: "150", Quantity: "3", ID: 2, Time: 2000},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan *models.AggTradeEvent, 2)
+	bus := messaging.NewRedisPubSub(store.GetRedisClient())
+	go bus.SubscribeAll(ctx, func(env *messaging.Envelope) error {
+		if env.Exchange != fake.Name || env.Source != messaging.SourceLive {
+			t.Errorf("Unexpected envelope metadata: %+v", env)
+		}
+		received <- env.Payload
+		return nil
+	})
+	// Give the subscription time to register before trades are published
+	time.Sleep(100 * time.Millisecond)
+
+	svc := NewService(cfg, ex, store)
+	go svc.Start(ctx)
+
+	got := make(map[string]string)
+	for len(got) < 2 {
+		select {
+		case trade := <-received:
+			got[trade.Data.Symbol] = trade.Data.Price
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for trades, got %v", got)
+		}
+	}
+
+	if got["BTCUSDT"] != "50000" || got["SOLUSDT"] != "150" {
+		t.Errorf("Unexpected trades: %v", got)
+	}
+	if ex.Streams() != 2 {
+		t.Errorf("Expected 2 connections for 3 symbols, got %d", ex.Streams())
+	}
+}
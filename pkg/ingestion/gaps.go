@@ -0,0 +1,55 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/exchange"
+	"binance-redis-streamer/pkg/messaging"
+)
+
+// latestTradeReader reads the latest stored trade of a symbol
+type latestTradeReader interface {
+	GetLatestTrade(ctx context.Context, symbol string) (*models.Trade, error)
+}
+
+// recoverGaps backfills the trades symbols missed between their connection
+// dropping and resuming, when the client can, publishing them on the bus
+// as backfilled trades so they are stored and aggregated into candles like
+// streamed ones. Each symbol's gap starts at its latest stored trade, or
+// when the connection dropped if that trade is missing, stale or already
+// from the new connection. Failures only warn: the stream itself is
+// unaffected.
+func (s *Service) recoverGaps(ctx context.Context, symbols []string, dropped, resumed time.Time) {
+	recoverer, ok := s.client.(exchange.GapRecoverer)
+	if !ok || !s.config.Binance.AutoRecoverGaps {
+		return
+	}
+
+	backfillCtx := messaging.WithSource(ctx, messaging.SourceBackfill)
+	publish := func(event *models.AggTradeEvent) error {
+		if err := s.messageBus.Publish(backfillCtx, event); err != nil {
+			return fmt.Errorf("failed to publish recovered trade: %w", err)
+		}
+		return nil
+	}
+
+	for _, symbol := range symbols {
+		gapStart := dropped
+		if s.latest != nil {
+			// A trade after the drop came from the new connection
+			if trade, err := s.latest.GetLatestTrade(ctx, symbol); err == nil && trade.Time.Before(dropped) {
+				gapStart = trade.Time
+			}
+		}
+		if err := recoverer.RecoverGap(ctx, symbol, gapStart, resumed, publish); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Warning: failed to recover the trade gap of %s: %v", symbol, err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package ingestion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/exchange/fake"
+	"binance-redis-streamer/pkg/messaging"
+	"binance-redis-streamer/pkg/storage"
+)
+
+// gap is a RecoverGap call
+type gap struct {
+	symbol     string
+	start, end time.Time
+}
+
+// recoveringClient drops connections like droppingClient, records the gaps
+// it is asked to recover and recovers one trade at the end of each
+type recoveringClient struct {
+	droppingClient
+	gaps chan gap
+}
+
+func (c *recoveringClient) RecoverGap(ctx context.Context, symbol string, gapStart, gapEnd time.Time, handle func(*models.AggTradeEvent) error) error {
+	c.gaps <- gap{symbol, gapStart, gapEnd}
+	return handle(&models.AggTradeEvent{Data: models.TradeData{
+		EventType: models.EventTypeTrade,
+		Symbol:    symbol,
+		TradeID:   1,
+		TradeTime: gapEnd.UnixMilli(),
+	}})
+}
+
+// latestTrade returns trade as the latest trade of every symbol, or
+// ErrNotFound when it is nil
+type latestTrade struct {
+	trade *models.Trade
+}
+
+func (l latestTrade) GetLatestTrade(ctx context.Context, symbol string) (*models.Trade, error) {
+	if l.trade == nil {
+		return nil, storage.ErrNotFound
+	}
+	return l.trade, nil
+}
+
+func TestProcessSymbolGroup_RecoversGapsOnReconnect(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.WebSocket.ReconnectDelay = time.Millisecond
+	cfg.Binance.AutoRecoverGaps = true
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	lastTrade := time.Now().Add(-time.Minute)
+	for _, tt := range []struct {
+		name   string
+		latest *models.Trade
+	}{
+		{"from the latest trade", &models.Trade{Symbol: "BTCUSDT", Time: lastTrade}},
+		{"from the drop without a latest trade", nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &recoveringClient{droppingClient: droppingClient{Exchange: fake.New(), drops: 1}, gaps: make(chan gap, 2)}
+			svc := NewService(cfg, client, store)
+			svc.latest = latestTrade{tt.latest}
+
+			// Recovered trades are published as backfilled ones
+			ctx, cancel := context.WithCancel(context.Background())
+			recovered := make(chan *messaging.Envelope, 2)
+			subscribed := make(chan struct{})
+			go func() {
+				pubsub := store.GetRedisClient().PSubscribe(ctx, "trades*")
+				defer pubsub.Close()
+				if _, err := pubsub.Receive(ctx); err == nil {
+					close(subscribed)
+				}
+				for msg := range pubsub.Channel() {
+					envs, _ := messaging.DecodeEnvelopes([]byte(msg.Payload))
+					for _, env := range envs {
+						recovered <- env
+					}
+				}
+			}()
+			<-subscribed
+
+			started := time.Now()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				svc.processSymbolGroup(ctx, []string{"BTCUSDT", "ETHUSDT"})
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			for _, symbol := range []string{"BTCUSDT", "ETHUSDT"} {
+				var g gap
+				select {
+				case g = <-client.gaps:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("Timed out waiting for the gap of %s", symbol)
+				}
+				if g.symbol != symbol || g.end.Before(g.start) {
+					t.Errorf("Unexpected gap %+v", g)
+				}
+				if tt.latest != nil && !g.start.Equal(lastTrade) {
+					t.Errorf("Expected the gap of %s to start at the latest trade, got %v", symbol, g.start)
+				}
+				if tt.latest == nil && g.start.Before(started) {
+					t.Errorf("Expected the gap of %s to start at the drop, got %v", symbol, g.start)
+				}
+			}
+			for i := 0; i < 2; i++ {
+				select {
+				case env := <-recovered:
+					if env.Source != messaging.SourceBackfill || env.Payload.Data.TradeID != 1 {
+						t.Errorf("Expected a backfilled trade, got %s trade %d", env.Source, env.Payload.Data.TradeID)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("Timed out waiting for the recovered trades")
+				}
+			}
+			if client.streams != 2 {
+				t.Errorf("Expected one reconnect, got %d streams", client.streams)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/coordination"
+	"binance-redis-streamer/pkg/exchange"
+	"binance-redis-streamer/pkg/exchange/fake"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func TestService_OnlyLeaderStreams(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.Ingestion.LeaderElection = true
+	cfg.Ingestion.LeaseTTL = 300 * time.Millisecond
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	source, err := exchange.NewStaticSymbolSource([]string{"BTCUSDT", "ETHUSDT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two replicas, named apart as separate processes would be
+	// and each running a leader task, e.g. the trade processor
+	replica := func(id string) (*Service, *streamRecorder, *atomic.Int32) {
+		svc := NewService(cfg, fake.New(), store)
+		svc.leader = coordination.NewElector(store.GetRedisClient(), cfg.Redis.KeyPrefix+"ingestion:leader", id, cfg.Ingestion.LeaseTTL)
+		svc.SetSymbolSource(source)
+		recorder := &streamRecorder{active: make(map[string]bool)}
+		svc.streamGroup = recorder.stream
+		tasks := &atomic.Int32{}
+		svc.AddLeaderTask(func(ctx context.Context) error {
+			tasks.Add(1)
+			defer tasks.Add(-1)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		return svc, recorder, tasks
+	}
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s", what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	leader, leaderStreams, leaderTasks := replica("a")
+	leaderCtx, stopLeader := context.WithCancel(context.Background())
+	defer stopLeader()
+	leaderDone := make(chan error, 1)
+	go func() { leaderDone <- leader.Start(leaderCtx) }()
+	waitFor("the first replica to stream", func() bool { return leaderStreams.count() > 0 })
+	waitFor("the leader task to start", func() bool { return leaderTasks.Load() == 1 })
+
+	follower, followerStreams, followerTasks := replica("b")
+	followerCtx, stopFollower := context.WithCancel(context.Background())
+	followerDone := make(chan error, 1)
+	go func() { followerDone <- follower.Start(followerCtx) }()
+
+	// The follower keeps competing for the lease without streaming
+	time.Sleep(2 * cfg.Ingestion.LeaseTTL)
+	if got := followerStreams.count(); got != 0 {
+		t.Fatalf("Expected the follower not to stream, got %d connections", got)
+	}
+	if got := followerTasks.Load(); got != 0 {
+		t.Fatalf("Expected the follower to run no leader tasks, got %d", got)
+	}
+
+	// The leader shuts down and the follower takes over
+	stopLeader()
+	if err := <-leaderDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("Start() = %v, want context.Canceled", err)
+	}
+	if got := leaderStreams.count(); got != 0 {
+		t.Errorf("Expected the old leader's connections closed, got %d", got)
+	}
+	if got := leaderTasks.Load(); got != 0 {
+		t.Errorf("Expected the old leader's tasks stopped, got %d", got)
+	}
+	waitFor("the follower to take over", func() bool { return followerStreams.count() > 0 })
+	waitFor("the follower to run the leader task", func() bool { return followerTasks.Load() == 1 })
+	assertSymbols(t, follower.ActiveSymbols(), "btcusdt", "ethusdt")
+
+	stopFollower()
+	<-followerDone
+}
@@ -0,0 +1,159 @@
+package ingestion
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/metrics"
+)
+
+// publishQueue decouples WebSocket read loops from message bus publishing.
+// When the bus slows down the queue fills up and, depending on the policy,
+// either blocks readers (so the exchange buffers on its side) or drops trades.
+// A pool of workers publishes in parallel; the queue is sharded by symbol so
+// each symbol's trades are published in order by a single worker.
+type publishQueue struct {
+	shards  []chan *models.AggTradeEvent
+	policy  string
+	dropped atomic.Int64
+	publish func(ctx context.Context, trade *models.AggTradeEvent) error
+}
+
+// newPublishQueue creates a queue holding up to size trades, split evenly
+// across workers
+func newPublishQueue(size, workers int, policy string, publish func(ctx context.Context, trade *models.AggTradeEvent) error) *publishQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	shardSize := (size + workers - 1) / workers
+
+	q := &publishQueue{
+		shards:  make([]chan *models.AggTradeEvent, workers),
+		policy:  policy,
+		publish: publish,
+	}
+	for i := range q.shards {
+		q.shards[i] = make(chan *models.AggTradeEvent, shardSize)
+	}
+	return q
+}
+
+// shardFor returns the shard whose worker publishes symbol's trades
+func (q *publishQueue) shardFor(symbol string) chan *models.AggTradeEvent {
+	if len(q.shards) == 1 {
+		return q.shards[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(symbol))
+	return q.shards[h.Sum32()%uint32(len(q.shards))]
+}
+
+// enqueue adds a trade to the queue. With the block policy it waits for room
+// or for ctx to be cancelled; with the shed policy a full queue drops the
+// trade, and with drop_oldest it drops the longest-queued trade instead.
+func (q *publishQueue) enqueue(ctx context.Context, trade *models.AggTradeEvent) error {
+	shard := q.shardFor(trade.Data.Symbol)
+
+	switch q.policy {
+	case config.PublishPolicyShed:
+		select {
+		case shard <- trade:
+		default:
+			q.drop()
+		}
+	case config.PublishPolicyDropOldest:
+		for queued := false; !queued; {
+			select {
+			case shard <- trade:
+				queued = true
+			default:
+				// Make room; the worker may have taken the oldest already
+				select {
+				case <-shard:
+					q.drop()
+				default:
+				}
+			}
+		}
+	default:
+		select {
+		case shard <- trade:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	metrics.PublishQueueDepth.Set(float64(q.depth()))
+	return nil
+}
+
+// drop counts a trade lost to a full queue
+func (q *publishQueue) drop() {
+	q.dropped.Add(1)
+	metrics.PublishQueueDropped.Inc()
+}
+
+// run publishes queued trades with one worker per shard until ctx is
+// cancelled, then publishes the trades still queued
+func (q *publishQueue) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, shard := range q.shards {
+		wg.Add(1)
+		go func(shard chan *models.AggTradeEvent) {
+			defer wg.Done()
+			q.work(ctx, shard)
+		}(shard)
+	}
+	wg.Wait()
+}
+
+// work publishes the trades of one shard in order. Cancelling ctx stops
+// it, but not a publish under way, which would lose the trade.
+func (q *publishQueue) work(ctx context.Context, shard chan *models.AggTradeEvent) {
+	publishCtx := context.WithoutCancel(ctx)
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case trade := <-shard:
+			metrics.PublishQueueDepth.Set(float64(q.depth()))
+			// The trade may be recycled once published
+			symbol := trade.Data.Symbol
+			if err := q.publish(publishCtx, trade); err != nil {
+				log.Printf("Failed to publish trade for %s: %v", symbol, err)
+			}
+		}
+	}
+	q.drain(publishCtx, shard)
+}
+
+// drain publishes the trades left in shard once the queue stops, counting
+// those that cannot be published as dropped
+func (q *publishQueue) drain(ctx context.Context, shard chan *models.AggTradeEvent) {
+	for {
+		select {
+		case trade := <-shard:
+			symbol := trade.Data.Symbol
+			if err := q.publish(ctx, trade); err != nil {
+				log.Printf("Failed to publish queued trade for %s on shutdown: %v", symbol, err)
+				q.drop()
+			}
+		default:
+			metrics.PublishQueueDepth.Set(float64(q.depth()))
+			return
+		}
+	}
+}
+
+// depth returns the number of queued trades
+func (q *publishQueue) depth() int {
+	depth := 0
+	for _, shard := range q.shards {
+		depth += len(shard)
+	}
+	return depth
+}
@@ -0,0 +1,271 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/metrics"
+)
+
+// slowBus counts published trades, sleeping per trade to simulate a slow Redis
+type slowBus struct {
+	delay     time.Duration
+	published atomic.Int64
+}
+
+func (b *slowBus) publish(ctx context.Context, trade *models.AggTradeEvent) error {
+	time.Sleep(b.delay)
+	b.published.Add(1)
+	return nil
+}
+
+func (b *slowBus) waitFor(t *testing.T, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for b.published.Load() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d published trades, got %d", want, b.published.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// produce enqueues total trades from several concurrent readers and returns
+// how long the readers were held up
+func produce(t *testing.T, ctx context.Context, q *publishQueue, readers, total int) time.Duration {
+	t.Helper()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < total/readers; i++ {
+				if err := q.enqueue(ctx, &models.AggTradeEvent{}); err != nil {
+					t.Errorf("enqueue failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return time.Since(start)
+}
+
+func TestPublishQueue_BlockPolicyAppliesBackpressure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := &slowBus{delay: 200 * time.Microsecond}
+	q := newPublishQueue(10, 1, config.PublishPolicyBlock, bus.publish)
+	go q.run(ctx)
+
+	const total = 1000
+	elapsed := produce(t, ctx, q, 4, total)
+	bus.waitFor(t, total)
+
+	if q.dropped.Load() != 0 {
+		t.Errorf("Expected no drops with the block policy, got %d", q.dropped.Load())
+	}
+	// Readers can only run ahead of the bus by the queue size
+	if min := time.Duration(total-10) * bus.delay; elapsed < min {
+		t.Errorf("Expected readers to be slowed to the bus rate (>= %s), took %s", min, elapsed)
+	}
+}
+
+func TestPublishQueue_BlockPolicyUnblocksOnCancel(t *testing.T) {
+	q := newPublishQueue(1, 1, config.PublishPolicyBlock, (&slowBus{}).publish)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := q.enqueue(ctx, &models.AggTradeEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- q.enqueue(ctx, &models.AggTradeEvent{}) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Expected enqueue on a full queue to block, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestPublishQueue_ShedPolicyDropsWithoutBlocking(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := &slowBus{delay: time.Millisecond}
+	q := newPublishQueue(10, 1, config.PublishPolicyShed, bus.publish)
+
+	// Nothing drains the queue yet, so exactly the overflow is shed
+	produce(t, ctx, q, 1, 100)
+	if q.dropped.Load() != 90 || q.depth() != 10 {
+		t.Fatalf("Expected 90 drops and 10 queued, got %d drops and %d queued", q.dropped.Load(), q.depth())
+	}
+
+	go q.run(ctx)
+	bus.waitFor(t, 10)
+
+	// Under sustained load every trade is either published or counted as dropped
+	const total = 1000
+	elapsed := produce(t, ctx, q, 4, total)
+	if max := time.Duration(total) * bus.delay / 2; elapsed > max {
+		t.Errorf("Expected readers to run ahead of the slow bus (< %s), took %s", max, elapsed)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for q.depth() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(5 * bus.delay)
+	if got := bus.published.Load() + q.dropped.Load(); got != total+100 {
+		t.Errorf("Expected published + dropped = %d, got %d", total+100, got)
+	}
+	if q.dropped.Load() <= 90 {
+		t.Errorf("Expected the slow bus to cause more drops, got %d", q.dropped.Load())
+	}
+}
+
+func TestPublishQueue_DropOldestPolicyKeepsNewest(t *testing.T) {
+	q := newPublishQueue(3, 1, config.PublishPolicyDropOldest, (&slowBus{}).publish)
+
+	for id := int64(1); id <= 10; id++ {
+		if err := q.enqueue(context.Background(), &models.AggTradeEvent{Data: models.TradeData{TradeID: id}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if q.dropped.Load() != 7 || q.depth() != 3 {
+		t.Fatalf("Expected 7 drops and 3 queued, got %d drops and %d queued", q.dropped.Load(), q.depth())
+	}
+	for _, want := range []int64{8, 9, 10} {
+		if got := (<-q.shards[0]).Data.TradeID; got != want {
+			t.Errorf("Expected trade %d to be kept, got %d", want, got)
+		}
+	}
+}
+
+// orderedBus records the trade IDs it publishes per symbol, slowly
+type orderedBus struct {
+	delay time.Duration
+	mu    sync.Mutex
+	ids   map[string][]int64
+	total int
+}
+
+func (b *orderedBus) publish(ctx context.Context, trade *models.AggTradeEvent) error {
+	time.Sleep(b.delay)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ids[trade.Data.Symbol] = append(b.ids[trade.Data.Symbol], trade.Data.TradeID)
+	b.total++
+	return nil
+}
+
+func (b *orderedBus) published() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.total
+}
+
+func TestPublishQueue_WorkersBufferSlowBusWithoutStallingReads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := &orderedBus{delay: 2 * time.Millisecond, ids: make(map[string][]int64)}
+	q := newPublishQueue(1000, 4, config.PublishPolicyBlock, bus.publish)
+	go q.run(ctx)
+
+	// The reader enqueues far faster than the bus publishes
+	symbols := []string{"BTCUSDT", "ETHUSDT", "BNBUSDT", "SOLUSDT", "XRPUSDT", "ADAUSDT", "DOGEUSDT", "DOTUSDT"}
+	const total = 400
+	start := time.Now()
+	for i := 0; i < total; i++ {
+		trade := &models.AggTradeEvent{Data: models.TradeData{Symbol: symbols[i%len(symbols)], TradeID: int64(i)}}
+		if err := q.enqueue(ctx, trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > total*bus.delay/4 {
+		t.Errorf("Expected reads not to wait for the bus, took %s", elapsed)
+	}
+	if q.depth() == 0 {
+		t.Error("Expected trades to be buffered behind the slow bus")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.published() < total {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out with %d of %d trades published", bus.published(), total)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if q.dropped.Load() != 0 {
+		t.Errorf("Expected no drops, got %d", q.dropped.Load())
+	}
+
+	// Workers run in parallel, but each symbol's trades keep their order
+	for symbol, ids := range bus.ids {
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Fatalf("Trades of %s published out of order: %v", symbol, ids)
+			}
+		}
+	}
+}
+
+func TestPublishQueue_DrainsOnCancel(t *testing.T) {
+	var published atomic.Int64
+	publish := func(ctx context.Context, trade *models.AggTradeEvent) error {
+		if trade.Data.Symbol == "BADUSDT" {
+			return errors.New("bus unavailable")
+		}
+		published.Add(1)
+		return nil
+	}
+	q := newPublishQueue(100, 4, config.PublishPolicyBlock, publish)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 50; i++ {
+		symbol := fmt.Sprintf("SYM%dUSDT", i)
+		if i%10 == 0 {
+			symbol = "BADUSDT"
+		}
+		if err := q.enqueue(ctx, &models.AggTradeEvent{Data: models.TradeData{Symbol: symbol}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Stopped before the workers took anything, the queue still publishes
+	// what it holds
+	cancel()
+	before := testutil.ToFloat64(metrics.PublishQueueDropped)
+	q.run(ctx)
+
+	if got := published.Load(); got != 45 {
+		t.Errorf("Expected the 45 publishable trades published, got %d", got)
+	}
+	if got := q.dropped.Load(); got != 5 {
+		t.Errorf("Expected the 5 unpublishable trades counted as dropped, got %d", got)
+	}
+	if got := testutil.ToFloat64(metrics.PublishQueueDropped) - before; got != 5 {
+		t.Errorf("Expected binance_publish_queue_dropped_total up by 5, got %v", got)
+	}
+	if depth := q.depth(); depth != 0 {
+		t.Errorf("Expected an empty queue, got %d trades", depth)
+	}
+}
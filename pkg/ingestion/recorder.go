@@ -0,0 +1,254 @@
+package ingestion
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+const recordFileSuffix = ".ndjson.gz"
+
+// Recorder archives raw WebSocket messages to gzip-compressed ndjson files,
+// partitioned by hour and symbol group. Files are rotated by size and age and
+// the oldest archives are pruned once the directory exceeds its disk budget.
+type Recorder struct {
+	dir          string
+	maxFileSize  int64
+	maxFileAge   time.Duration
+	maxTotalSize int64
+	now          func() time.Time
+
+	mu    sync.Mutex
+	files map[string]*recordFile
+	seq   int
+}
+
+// recordFile is an open archive for one symbol group
+type recordFile struct {
+	path   string
+	hour   string
+	file   *os.File
+	gz     *gzip.Writer
+	size   int64
+	opened time.Time
+}
+
+// recordLine is a single archived message
+type recordLine struct {
+	ReceivedAt int64           `json:"ts"`
+	Message    json.RawMessage `json:"msg"`
+}
+
+// NewRecorder creates a recorder writing to cfg.RecordDir
+func NewRecorder(cfg config.IngestionConfig) (*Recorder, error) {
+	if err := os.MkdirAll(cfg.RecordDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create record directory: %w", err)
+	}
+
+	return &Recorder{
+		dir:          cfg.RecordDir,
+		maxFileSize:  cfg.RecordMaxFileSize,
+		maxFileAge:   cfg.RecordMaxFileAge,
+		maxTotalSize: cfg.RecordMaxTotalSize,
+		now:          time.Now,
+		files:        make(map[string]*recordFile),
+	}, nil
+}
+
+// Record appends a raw message to the archive of the given symbol group
+func (r *Recorder) Record(group string, message []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now().UTC()
+	hour := now.Format("2006010215")
+
+	f := r.files[group]
+	if f != nil && (f.hour != hour || f.size >= r.maxFileSize || now.Sub(f.opened) >= r.maxFileAge) {
+		if err := r.rotate(group); err != nil {
+			return err
+		}
+		f = nil
+	}
+	if f == nil {
+		var err error
+		if f, err = r.open(group, hour, now); err != nil {
+			return err
+		}
+	}
+
+	line, err := json.Marshal(recordLine{ReceivedAt: now.UnixMilli(), Message: message})
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	line = append(line, '\n')
+
+	n, err := f.gz.Write(line)
+	f.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+
+	return nil
+}
+
+// open creates a new archive file; callers must hold mu
+func (r *Recorder) open(group, hour string, now time.Time) (*recordFile, error) {
+	hourDir := filepath.Join(r.dir, hour)
+	if err := os.MkdirAll(hourDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create hour directory: %w", err)
+	}
+
+	r.seq++
+	name := fmt.Sprintf("%s-%s-%04d%s", group, now.Format("150405.000"), r.seq, recordFileSuffix)
+	path := filepath.Join(hourDir, name)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create record file: %w", err)
+	}
+
+	f := &recordFile{
+		path:   path,
+		hour:   hour,
+		file:   file,
+		gz:     gzip.NewWriter(file),
+		opened: now,
+	}
+	r.files[group] = f
+	return f, nil
+}
+
+// rotate closes the current archive of a group and prunes old archives;
+// callers must hold mu
+func (r *Recorder) rotate(group string) error {
+	f := r.files[group]
+	delete(r.files, group)
+
+	if err := f.close(); err != nil {
+		return err
+	}
+	return r.prune()
+}
+
+// close flushes and closes the archive
+func (f *recordFile) close() error {
+	if err := f.gz.Close(); err != nil {
+		f.file.Close()
+		return fmt.Errorf("failed to flush record file: %w", err)
+	}
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close record file: %w", err)
+	}
+	return nil
+}
+
+// prune deletes the oldest closed archives until the directory fits the disk
+// budget; callers must hold mu
+func (r *Recorder) prune() error {
+	type archive struct {
+		path    string
+		hour    string
+		size    int64
+		modTime time.Time
+	}
+
+	open := make(map[string]bool, len(r.files))
+	for _, f := range r.files {
+		open[f.path] = true
+	}
+
+	var archives []archive
+	var total int64
+	err := filepath.Walk(r.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, recordFileSuffix) {
+			return nil
+		}
+		total += info.Size()
+		if !open[path] {
+			archives = append(archives, archive{
+				path:    path,
+				hour:    filepath.Base(filepath.Dir(path)),
+				size:    info.Size(),
+				modTime: info.ModTime(),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan record directory: %w", err)
+	}
+
+	// Oldest first: by hour partition, then by last write
+	sort.Slice(archives, func(i, j int) bool {
+		a, b := archives[i], archives[j]
+		if a.hour != b.hour {
+			return a.hour < b.hour
+		}
+		if !a.modTime.Equal(b.modTime) {
+			return a.modTime.Before(b.modTime)
+		}
+		return a.path < b.path
+	})
+
+	for _, a := range archives {
+		if total <= r.maxTotalSize {
+			break
+		}
+		if err := os.Remove(a.path); err != nil {
+			return fmt.Errorf("failed to prune %s: %w", a.path, err)
+		}
+		total -= a.size
+
+		// Drop the hour directory once it is empty
+		_ = os.Remove(filepath.Dir(a.path))
+	}
+
+	return nil
+}
+
+// CloseGroup flushes and closes the open archive of a symbol group, if any.
+// Groups are named by their symbols, so a rebalanced group would otherwise
+// leave its archive open and unreadable until the service stops.
+func (r *Recorder) CloseGroup(group string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.files[group] == nil {
+		return nil
+	}
+	return r.rotate(group)
+}
+
+// Close flushes and closes all open archives
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var firstErr error
+	for group := range r.files {
+		if err := r.rotate(group); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// recordGroupName returns a stable, readable archive name for a symbol group
+func recordGroupName(symbols []string) string {
+	if len(symbols) == 0 {
+		return "empty"
+	}
+	return fmt.Sprintf("%s-%d", strings.ToLower(symbols[0]), len(symbols))
+}
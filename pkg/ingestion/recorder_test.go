@@ -0,0 +1,243 @@
+package ingestion
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+var testMessage = []byte(`{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","p":"50000.00","q":"1.5"}}`)
+
+func setupTestRecorder(t *testing.T, cfg config.IngestionConfig) (*Recorder, *time.Time) {
+	cfg.RecordDir = t.TempDir()
+	recorder, err := NewRecorder(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	now := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	recorder.now = func() time.Time { return now }
+	return recorder, &now
+}
+
+func listArchives(t *testing.T, dir string) []string {
+	t.Helper()
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(path, recordFileSuffix) {
+			rel, _ := filepath.Rel(dir, path)
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func readArchive(t *testing.T, path string) []recordLine {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gz.Close()
+
+	var lines []recordLine
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var line recordLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Invalid ndjson line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestRecorder_RotatesBySize(t *testing.T) {
+	recorder, _ := setupTestRecorder(t, config.IngestionConfig{
+		RecordMaxFileSize:  100, // one message per file
+		RecordMaxFileAge:   time.Hour,
+		RecordMaxTotalSize: 1 << 20,
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := recorder.Record("btcusdt-2", testMessage); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	archives := listArchives(t, recorder.dir)
+	if len(archives) != 3 {
+		t.Fatalf("Expected 3 archives after size rotation, got %v", archives)
+	}
+	for _, archive := range archives {
+		if !strings.HasPrefix(archive, "2024122610"+string(filepath.Separator)+"btcusdt-2-") {
+			t.Errorf("Archive %s not partitioned by hour and group", archive)
+		}
+
+		lines := readArchive(t, filepath.Join(recorder.dir, archive))
+		if len(lines) != 1 || string(lines[0].Message) != string(testMessage) {
+			t.Errorf("Archive %s has unexpected content: %+v", archive, lines)
+		}
+	}
+}
+
+func TestRecorder_RotatesByAgeAndHour(t *testing.T) {
+	recorder, now := setupTestRecorder(t, config.IngestionConfig{
+		RecordMaxFileSize:  1 << 20,
+		RecordMaxFileAge:   10 * time.Minute,
+		RecordMaxTotalSize: 1 << 20,
+	})
+
+	record := func() {
+		t.Helper()
+		if err := recorder.Record("ethusdt-1", testMessage); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	record()
+	*now = now.Add(5 * time.Minute)
+	record() // same file, still young
+	*now = now.Add(6 * time.Minute)
+	record() // file is older than 10 minutes
+	*now = now.Add(50 * time.Minute)
+	record() // next hour partition
+
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	archives := listArchives(t, recorder.dir)
+	if len(archives) != 3 {
+		t.Fatalf("Expected 3 archives, got %v", archives)
+	}
+	if !strings.HasPrefix(archives[2], "2024122611") {
+		t.Errorf("Expected last archive in the 11:00 partition, got %s", archives[2])
+	}
+
+	total := 0
+	for _, archive := range archives {
+		total += len(readArchive(t, filepath.Join(recorder.dir, archive)))
+	}
+	if total != 4 {
+		t.Errorf("Expected 4 archived messages, got %d", total)
+	}
+}
+
+func TestRecorder_PrunesOldestBeyondBudget(t *testing.T) {
+	recorder, now := setupTestRecorder(t, config.IngestionConfig{
+		RecordMaxFileSize:  1,
+		RecordMaxFileAge:   time.Hour,
+		RecordMaxTotalSize: 300, // room for only a couple of compressed archives
+	})
+
+	for i := 0; i < 10; i++ {
+		if err := recorder.Record("btcusdt-2", testMessage); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+		*now = now.Add(10 * time.Minute)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var total int64
+	archives := listArchives(t, recorder.dir)
+	for _, archive := range archives {
+		info, err := os.Stat(filepath.Join(recorder.dir, archive))
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += info.Size()
+	}
+
+	if total > 300 {
+		t.Errorf("Expected archives within 300 bytes, got %d bytes in %v", total, archives)
+	}
+	if len(archives) == 0 || len(archives) >= 10 {
+		t.Fatalf("Expected pruning to keep some but not all archives, got %v", archives)
+	}
+
+	// The newest partition must survive; the oldest must be gone
+	if !strings.HasPrefix(archives[len(archives)-1], "2024122611") {
+		t.Errorf("Expected newest archive to be kept, got %v", archives)
+	}
+	if strings.HasPrefix(archives[0], "2024122611") {
+		if _, err := os.Stat(filepath.Join(recorder.dir, "2024122610")); err == nil {
+			t.Error("Expected empty hour directory to be removed")
+		}
+	}
+}
+
+func TestRecorder_CloseGroupFinalizesOnlyThatGroup(t *testing.T) {
+	recorder, _ := setupTestRecorder(t, config.IngestionConfig{
+		RecordMaxFileSize:  1 << 20,
+		RecordMaxFileAge:   time.Hour,
+		RecordMaxTotalSize: 1 << 20,
+	})
+
+	for _, group := range []string{"btcusdt-2", "ethusdt-3"} {
+		if err := recorder.Record(group, testMessage); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	if err := recorder.CloseGroup("btcusdt-2"); err != nil {
+		t.Fatalf("CloseGroup failed: %v", err)
+	}
+	if err := recorder.CloseGroup("unknown-1"); err != nil {
+		t.Errorf("CloseGroup of a group without an archive failed: %v", err)
+	}
+
+	if _, open := recorder.files["btcusdt-2"]; open {
+		t.Error("Closed group still has an open archive")
+	}
+	if _, open := recorder.files["ethusdt-3"]; !open {
+		t.Error("Other group's archive was closed")
+	}
+
+	// The closed archive is complete and readable while the service runs
+	archives := listArchives(t, recorder.dir)
+	var closed string
+	for _, archive := range archives {
+		if strings.Contains(archive, "btcusdt-2-") {
+			closed = archive
+		}
+	}
+	if closed == "" {
+		t.Fatalf("No archive for the closed group in %v", archives)
+	}
+	lines := readArchive(t, filepath.Join(recorder.dir, closed))
+	if len(lines) != 1 || string(lines[0].Message) != string(testMessage) {
+		t.Errorf("Closed archive has unexpected content: %+v", lines)
+	}
+
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+}
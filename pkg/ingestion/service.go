@@ -2,203 +2,702 @@ package ingestion
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/gorilla/websocket"
-
 	"binance-redis-streamer/internal/models"
-	"binance-redis-streamer/pkg/binance"
+	"binance-redis-streamer/pkg/breaker"
 	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/coordination"
+	"binance-redis-streamer/pkg/exchange"
 	"binance-redis-streamer/pkg/messaging"
 	"binance-redis-streamer/pkg/storage"
 )
 
-// Service handles the ingestion of trade data from Binance
+// Service handles the ingestion of trade data from an exchange
 type Service struct {
 	config     *config.Config
-	client     *binance.Client
+	client     exchange.Client
+	symbols    exchange.SymbolSource // Symbols to stream, discovered through client by default
 	messageBus messaging.MessageBus
-	mu         sync.RWMutex
-	wsConns    map[string]*websocket.Conn
+
+	// Active symbol groups, each streamed by its own goroutine
+	groupMu     sync.Mutex
+	groups      map[int]*symbolGroup
+	nextGroupID int
+	groupWg     sync.WaitGroup
+
+	// streamGroup streams a single symbol group until its context is cancelled
+	streamGroup func(ctx context.Context, symbols []string) error
+
+	// recorder archives raw messages to disk when recording is enabled
+	recorder *Recorder
+
+	// queue buffers parsed trades for publishing; nil publishes synchronously
+	queue *publishQueue
+
+	// streamBreaker stops reconnect attempts across all groups during outages
+	streamBreaker *breaker.Breaker
+
+	// connections holds a *connectionTracker per streaming connection, by
+	// connection ID
+	connections      sync.Map
+	nextConnectionID atomic.Int64
+
+	// groupConnections counts the tracked connections of each group, which
+	// share its metrics.IngestionMessages series
+	connectionMu     sync.Mutex
+	groupConnections map[string]int
+
+	// events logs connects and disconnects for uptime auditing; nil skips them
+	events connectionEventRecorder
+
+	// quarantine keeps messages rejected before publishing; nil only drops them
+	quarantine payloadQuarantiner
+
+	// latest starts the trade gaps recovered after reconnects
+	latest latestTradeReader
+
+	// assignments reports which connection carries each symbol group, as
+	// process; nil skips it
+	assignments streamGroupStore
+	process     string
+
+	// control receives symbols added and removed at runtime; nil ignores
+	// them. Symbols in addedSymbols and removedSymbols, guarded by groupMu,
+	// override discovery; they are loaded from control when ingest starts.
+	control        symbolControlStore
+	addedSymbols   map[string]bool
+	removedSymbols map[string]bool
+
+	// leader gates streaming on holding the replicas' leader lease; nil
+	// always streams
+	leader *coordination.Elector
+
+	// leaderTasks run alongside streaming, and so only on the leader
+	leaderTasks []func(ctx context.Context) error
+
+	// lastMessage is the receive time of the latest message across all groups (Unix nanoseconds)
+	lastMessage atomic.Int64
+	now         func() time.Time
+}
+
+// symbolGroup is a set of symbols sharing one WebSocket connection
+type symbolGroup struct {
+	symbols []string
+	cancel  context.CancelFunc
 }
 
 // NewService creates a new ingestion service
-func NewService(cfg *config.Config, client *binance.Client, store *storage.RedisStore) *Service {
-	return &Service{
+func NewService(cfg *config.Config, client exchange.Client, store *storage.RedisStore) *Service {
+	bus := messaging.NewRedisPubSub(store.GetRedisClient())
+	bus.SetExchange(client.Name())
+	bus.SetPerSymbolChannels(cfg.Redis.PerSymbolChannels)
+	bus.SetBatching(messaging.RedisPubSubConfig{
+		BatchSize:  cfg.Redis.PublishBatchSize,
+		BatchDelay: cfg.Redis.PublishBatchDelay,
+	})
+
+	s := &Service{
 		config:     cfg,
 		client:     client,
-		messageBus: messaging.NewRedisPubSub(store.GetRedisClient()),
-		wsConns:    make(map[string]*websocket.Conn),
+		symbols:    exchange.ClientSymbols{Client: client},
+		messageBus: bus,
+		events:     store,
+		quarantine: store,
+		latest:     store,
+		process:    processName(),
+		groups:     make(map[int]*symbolGroup),
+		now:        time.Now,
+
+		assignments: store,
+
+		control:        store,
+		addedSymbols:   make(map[string]bool),
+		removedSymbols: make(map[string]bool),
+
+		streamBreaker: breaker.New(client.Name()+"-stream", cfg.Breaker),
+	}
+	s.streamGroup = s.processSymbolGroup
+
+	if cfg.Binance.MaxStreamsPerConn > config.MaxBinanceStreamsPerConn {
+		log.Printf("Warning: max streams per connection %d exceeds the Binance limit, using %d",
+			cfg.Binance.MaxStreamsPerConn, config.MaxBinanceStreamsPerConn)
 	}
+
+	if size := cfg.Ingestion.PublishQueueSize; size > 0 {
+		s.queue = newPublishQueue(size, cfg.Ingestion.PublishWorkers, cfg.Ingestion.PublishQueuePolicy, s.publish)
+	}
+
+	if cfg.Ingestion.LeaderElection {
+		s.leader = coordination.NewElector(store.GetRedisClient(), cfg.Redis.KeyPrefix+"ingestion:leader", s.process, cfg.Ingestion.LeaseTTL)
+	}
+
+	if cfg.Ingestion.RecordDir != "" {
+		recorder, err := NewRecorder(cfg.Ingestion)
+		if err != nil {
+			log.Printf("Warning: raw message recording disabled: %v", err)
+		} else {
+			s.recorder = recorder
+		}
+	}
+
+	return s
+}
+
+// SetSymbolSource replaces where the symbols to stream come from; call it
+// before Start
+func (s *Service) SetSymbolSource(source exchange.SymbolSource) {
+	s.symbols = source
+}
+
+// busDrainDelay is how long leader tasks keep running after the last
+// batched trades are published, so subscribers among them receive those
+const busDrainDelay = 500 * time.Millisecond
+
+// AddLeaderTask runs task whenever the service streams, i.e. while it holds
+// the leader lease with leader election, so work such as processing the
+// streamed trades never runs on two replicas at once. task's context is
+// cancelled once streaming stopped and the trades it published were
+// flushed; an error returned before that stops ingestion. Call it before
+// Start.
+func (s *Service) AddLeaderTask(task func(ctx context.Context) error) {
+	s.leaderTasks = append(s.leaderTasks, task)
 }
 
-// Start starts the ingestion service
+// Start starts the ingestion service. With leader election it only streams
+// while holding the leader lease, and waits as a follower otherwise.
 func (s *Service) Start(ctx context.Context) error {
-	symbols, err := s.client.GetSymbols(ctx)
+	if s.leader == nil {
+		return s.ingest(ctx)
+	}
+
+	ticker := time.NewTicker(s.leader.RenewInterval())
+	defer ticker.Stop()
+	return s.leader.Run(ctx, ticker.C, s.ingest)
+}
+
+// ingest streams all symbols until ctx is cancelled or the watchdog gives
+// up, and returns once every connection is closed
+func (s *Service) ingest(ctx context.Context) error {
+	symbols, err := s.symbols.Symbols(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get symbols: %w", err)
 	}
+	// Outages still open were left by a process that stopped without
+	// recording it, e.g. one that crashed
+	s.recordConnectionEvent(ctx, "", models.StreamingStarted, nil)
 
-	// Create symbol groups for parallel processing
-	symbolGroups := s.createSymbolGroups(symbols)
+	// Leader tasks outlive streaming so they see its last trades
+	taskCtx, cancelTasks := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelTasks()
+	var taskWg sync.WaitGroup
+	taskErr := make(chan error, len(s.leaderTasks))
+	for _, task := range s.leaderTasks {
+		taskWg.Add(1)
+		go func() {
+			defer taskWg.Done()
+			if err := task(taskCtx); err != nil && taskCtx.Err() == nil {
+				taskErr <- err
+			}
+		}()
+	}
+	defer func() {
+		cancelTasks()
+		taskWg.Wait()
+	}()
 
-	// Start processing each group
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(symbolGroups))
+	// Groups run under their own context so a watchdog failure can stop them
+	groupCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	for _, group := range symbolGroups {
-		wg.Add(1)
-		go func(symbols []string) {
-			defer wg.Done()
-			if err := s.processSymbolGroup(ctx, symbols); err != nil {
-				select {
-				case errChan <- err:
-				default:
-				}
-			}
-		}(group)
+	// The queue stops after the groups, so it publishes every trade they
+	// queued before ingest returns
+	queueCtx, stopQueue := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopQueue()
+	queueDone := make(chan struct{})
+	if s.queue != nil {
+		go func() {
+			defer close(queueDone)
+			s.queue.run(queueCtx)
+		}()
+	} else {
+		close(queueDone)
 	}
 
-	// Wait for error or context cancellation
-	go func() {
-		wg.Wait()
-		close(errChan)
-	}()
+	s.markMessage()
+	if s.control != nil {
+		if err := s.loadSymbolOverrides(ctx); err != nil {
+			log.Printf("Warning: symbols added or removed at runtime are not applied: %v", err)
+		}
+	}
+	s.applySymbols(groupCtx, s.withSymbolOverrides(symbols))
+
+	// Background loops count as groups so nothing changes the groups once
+	// ingest returns
+	background := func(run func()) {
+		s.groupWg.Add(1)
+		go func() {
+			defer s.groupWg.Done()
+			run()
+		}()
+	}
+	if interval := s.config.Binance.SymbolRefreshInterval; interval > 0 {
+		background(func() { s.rediscoverSymbols(groupCtx, interval) })
+	}
+	if watcher, ok := s.symbols.(exchange.SymbolWatcher); ok {
+		background(func() { s.watchSymbols(groupCtx, watcher) })
+	}
+	if s.control != nil {
+		background(func() { s.runSymbolControl(groupCtx) })
+	}
+
+	heartbeat := time.NewTicker(s.config.Ingestion.GroupHeartbeat)
+	defer heartbeat.Stop()
+	background(func() { s.runGroupHeartbeat(groupCtx, heartbeat.C) })
+
+	watchdogErr := make(chan error, 1)
+	if silence := s.config.Ingestion.WatchdogSilence; silence > 0 {
+		ticker := time.NewTicker(silence / 4)
+		defer ticker.Stop()
+		background(func() { watchdogErr <- s.runWatchdog(groupCtx, ticker.C) })
+	}
 
 	select {
-	case err := <-errChan:
-		if err != nil {
-			return fmt.Errorf("streaming error: %w", err)
-		}
 	case <-ctx.Done():
-		return ctx.Err()
+		err = ctx.Err()
+	case err = <-watchdogErr:
+	case err = <-taskErr:
+	}
+
+	cancel()
+	s.groupWg.Wait()
+	stopQueue()
+	<-queueDone
+
+	// Publish what is still batched before the leader tasks stop
+	if len(s.leaderTasks) > 0 {
+		s.flushBus()
+		time.Sleep(busDrainDelay)
+	}
+
+	// Start afresh should streaming resume, as after regaining leadership
+	s.groupMu.Lock()
+	s.groups = make(map[int]*symbolGroup)
+	s.groupMu.Unlock()
+	return err
+}
+
+// rediscoverSymbols periodically re-runs symbol discovery so new listings are
+// picked up and delisted symbols are dropped without a restart
+func (s *Service) rediscoverSymbols(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.refreshSymbols(ctx)
+			switch {
+			case errors.Is(err, exchange.ErrRateLimited):
+				log.Printf("Symbol rediscovery rate limited, keeping current symbols until the next run: %v", err)
+			case err != nil:
+				log.Printf("Symbol rediscovery failed: %v", err)
+			}
+		}
+	}
+}
+
+// watchSymbols updates subscriptions as soon as a watched symbol source
+// changes, rather than at the next rediscovery
+func (s *Service) watchSymbols(ctx context.Context, watcher exchange.SymbolWatcher) {
+	err := watcher.Watch(ctx, func() {
+		if err := s.refreshSymbols(ctx); err != nil {
+			log.Printf("Symbol reload failed: %v", err)
+		}
+	})
+	if err != nil {
+		log.Printf("Warning: symbol changes will only be picked up by rediscovery: %v", err)
+	}
+}
+
+// refreshSymbols fetches the current symbol set and updates subscriptions
+func (s *Service) refreshSymbols(ctx context.Context) error {
+	symbols, err := s.symbols.Symbols(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get symbols: %w", err)
 	}
 
+	added, removed := s.applySymbols(ctx, s.withSymbolOverrides(symbols))
+	if len(added) > 0 || len(removed) > 0 {
+		log.Printf("Symbol rediscovery: added %v, removed %v", added, removed)
+	}
 	return nil
 }
 
-// createSymbolGroups splits symbols into groups based on MaxStreamsPerConn
+// applySymbols diffs the desired symbols against the active set, closing
+// connections that carry delisted symbols and opening connections for new
+// ones. Symbols sharing a connection with a removed symbol are regrouped.
+func (s *Service) applySymbols(ctx context.Context, symbols []string) (added, removed []string) {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	desired := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		desired[symbol] = true
+	}
+
+	active := make(map[string]bool)
+	var pending []string
+	for id, group := range s.groups {
+		keep := true
+		for _, symbol := range group.symbols {
+			active[symbol] = true
+			if !desired[symbol] {
+				removed = append(removed, symbol)
+				keep = false
+			}
+		}
+		if keep {
+			continue
+		}
+
+		// Tear down the connection and regroup its surviving symbols
+		group.cancel()
+		delete(s.groups, id)
+		for _, symbol := range group.symbols {
+			if desired[symbol] {
+				pending = append(pending, symbol)
+			}
+		}
+	}
+
+	for _, symbol := range symbols {
+		if !active[symbol] {
+			added = append(added, symbol)
+			pending = append(pending, symbol)
+		}
+	}
+
+	for _, group := range s.createSymbolGroups(pending) {
+		s.startGroup(ctx, group)
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// startGroup starts streaming a symbol group; callers must hold groupMu
+func (s *Service) startGroup(ctx context.Context, symbols []string) {
+	groupCtx, cancel := context.WithCancel(ctx)
+	id := s.nextGroupID
+	s.nextGroupID++
+	s.groups[id] = &symbolGroup{symbols: symbols, cancel: cancel}
+
+	s.groupWg.Add(1)
+	go func() {
+		defer s.groupWg.Done()
+		if err := s.streamGroup(groupCtx, symbols); err != nil && groupCtx.Err() == nil {
+			log.Printf("Streaming error for symbols %v: %v", symbols, err)
+		}
+	}()
+}
+
+// ActiveSymbols returns the sorted list of symbols currently being streamed
+func (s *Service) ActiveSymbols() []string {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	var symbols []string
+	for _, group := range s.groups {
+		symbols = append(symbols, group.symbols...)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// createSymbolGroups splits symbols into groups of MaxStreamsPerConn, clamped
+// to the Binance per-connection stream limit. Groups whose stream URL would
+// be too long are split further.
 func (s *Service) createSymbolGroups(symbols []string) [][]string {
-	// Split symbols into groups of maxSymbolsPerConnection
 	symbolCount := len(symbols)
-	groupSize := s.config.Binance.MaxStreamsPerConn
+	groupSize := s.config.Binance.SymbolsPerConn()
 	groupCount := (symbolCount + groupSize - 1) / groupSize // Ceiling division
 
 	groups := make([][]string, 0, groupCount)
 
-	for i := 0; i < symbolCount; i += groupSize {
-		end := i + groupSize
-		if end > symbolCount {
-			end = symbolCount
+	if volumes := s.symbolVolumes(); len(volumes) > 0 {
+		groups = balanceGroups(symbols, volumes, groupCount, groupSize)
+	} else {
+		for i := 0; i < symbolCount; i += groupSize {
+			end := i + groupSize
+			if end > symbolCount {
+				end = symbolCount
+			}
+			groups = append(groups, symbols[i:end])
 		}
-		groups = append(groups, symbols[i:end])
 	}
-	return groups
+
+	builder, ok := s.client.(exchange.StreamURLBuilder)
+	if !ok {
+		return groups
+	}
+	fitted := make([][]string, 0, len(groups))
+	for _, group := range groups {
+		fitted = appendFittingGroups(fitted, builder, group)
+	}
+	return fitted
 }
 
-// processSymbolGroup handles WebSocket connection for a group of symbols
-func (s *Service) processSymbolGroup(ctx context.Context, symbols []string) error {
-	url := s.client.BuildStreamURL(symbols)
+// symbolVolumes returns the 24h volumes to balance groups by, or nil when
+// balancing is off or the exchange does not report volumes
+func (s *Service) symbolVolumes() map[string]float64 {
+	if !s.config.Binance.BalanceGroupsByVolume {
+		return nil
+	}
+	reporter, ok := s.client.(exchange.VolumeReporter)
+	if !ok {
+		return nil
+	}
+	return reporter.SymbolVolumes()
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			if err := s.connectAndStream(ctx, url, symbols); err != nil {
-				log.Printf("Stream error for symbols %v: %v, reconnecting...", symbols, err)
-				time.Sleep(s.config.WebSocket.ReconnectDelay)
+// balanceGroups splits symbols into groupCount groups of at most groupSize,
+// assigning them busiest first to the group with the least volume so far.
+// Symbols without volume data count as zero.
+func balanceGroups(symbols []string, volumes map[string]float64, groupCount, groupSize int) [][]string {
+	volume := func(symbol string) float64 { return volumes[strings.ToLower(symbol)] }
+
+	sorted := append([]string(nil), symbols...)
+	sort.SliceStable(sorted, func(i, j int) bool { return volume(sorted[i]) > volume(sorted[j]) })
+
+	groups := make([][]string, groupCount)
+	loads := make([]float64, groupCount)
+	for _, symbol := range sorted {
+		best := -1
+		for i := range groups {
+			if len(groups[i]) >= groupSize {
 				continue
 			}
+			if best < 0 || loads[i] < loads[best] ||
+				(loads[i] == loads[best] && len(groups[i]) < len(groups[best])) {
+				best = i
+			}
 		}
+		groups[best] = append(groups[best], symbol)
+		loads[best] += volume(symbol)
 	}
+	return groups
 }
 
-// connectAndStream establishes WebSocket connection and processes messages
-func (s *Service) connectAndStream(ctx context.Context, url string, symbols []string) error {
-	wsConn, _, err := websocket.DefaultDialer.Dial(url, nil)
-	if err != nil {
-		return fmt.Errorf("websocket dial error: %w", err)
+// appendFittingGroups appends group to groups, halving it until each part's
+// stream URL fits MaxStreamURLLength. A single symbol that does not fit is
+// kept as is; StreamTrades then reports the error.
+func appendFittingGroups(groups [][]string, builder exchange.StreamURLBuilder, group []string) [][]string {
+	if len(group) <= 1 || len(builder.BuildStreamURL(group)) <= exchange.MaxStreamURLLength {
+		return append(groups, group)
 	}
-	defer wsConn.Close()
-
-	// Store connection
-	connKey := fmt.Sprintf("%v", symbols)
-	s.mu.Lock()
-	s.wsConns[connKey] = wsConn
-	s.mu.Unlock()
-
-	// Remove connection on exit
-	defer func() {
-		s.mu.Lock()
-		delete(s.wsConns, connKey)
-		s.mu.Unlock()
-	}()
+	half := len(group) / 2
+	groups = appendFittingGroups(groups, builder, group[:half])
+	return appendFittingGroups(groups, builder, group[half:])
+}
 
-	// Set up ping handler
-	go s.handlePing(ctx, wsConn)
+// processSymbolGroup streams a group of symbols, reconnecting after failures.
+// Reconnects go through the stream circuit breaker: once it opens, groups
+// wait out its cool-down and a single connection probes the exchange.
+func (s *Service) processSymbolGroup(ctx context.Context, symbols []string) error {
+	group := recordGroupName(symbols)
+	handler := s.messageHandler(ctx, group)
+	tracker := s.trackConnection(symbols)
+	// Untracked first, so the heartbeat does not save the group again
+	defer s.removeStreamGroup(ctx, tracker)
+	defer s.untrackConnection(tracker)
+	// Ends the group's outage, if any, as it will not reconnect under this
+	// name; recorded even once ctx is cancelled
+	defer s.recordConnectionEvent(context.WithoutCancel(ctx), group, models.ConnectionStopped, nil)
+	defer s.closeRecordGroup(group)
+	s.saveStreamGroup(ctx, tracker)
 
-	// Process messages
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			_, message, err := wsConn.ReadMessage()
-			if err != nil {
-				return fmt.Errorf("websocket read error: %w", err)
+	// When the last connection dropped, zero until one has
+	var dropped time.Time
+	for ctx.Err() == nil {
+		if err := s.streamBreaker.Allow(); err != nil {
+			wait := s.streamBreaker.RetryAfter()
+			if wait <= 0 {
+				// Another group is probing; check back after the usual delay
+				wait = s.config.WebSocket.ReconnectDelay
 			}
+			if !sleepContext(ctx, wait) {
+				break
+			}
+			continue
+		}
 
-			if err := s.processMessage(ctx, message); err != nil {
-				log.Printf("Failed to process message: %v", err)
+		// A connection counts as healthy once it delivers a message
+		connected := false
+		err := s.client.StreamTrades(ctx, symbols, func(message []byte) error {
+			if !connected {
+				connected = true
+				s.streamBreaker.Success()
+				s.recordConnectionEvent(ctx, group, models.ConnectionUp, nil)
+				tracker.connect(s.now())
+				s.saveStreamGroup(ctx, tracker)
+				if !dropped.IsZero() {
+					// Backfill in the background so the stream keeps reading
+					go s.recoverGaps(ctx, symbols, dropped, s.now())
+				}
 			}
+			tracker.message(len(message), s.now())
+			if err := handler(message); err != nil {
+				tracker.failure()
+				return err
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			if !connected {
+				// Stopped, e.g. by a regroup, before the attempt had an
+				// outcome; free the probe it may hold for other groups
+				s.streamBreaker.Cancel()
+			}
+			break
+		}
+		if !connected {
+			s.streamBreaker.Failure()
+		} else {
+			dropped = s.now()
+			s.recordConnectionEvent(ctx, group, models.ConnectionDown, err)
+		}
+		if err != nil {
+			tracker.failure()
+		}
+		if errors.Is(err, exchange.ErrBadSymbol) {
+			// Reconnecting cannot fix the symbol list
+			return fmt.Errorf("stopped streaming: %w", err)
+		}
+		if err != nil {
+			log.Printf("Stream error for symbols %v: %v, reconnecting...", symbols, err)
+		}
+		if !sleepContext(ctx, s.config.WebSocket.ReconnectDelay) {
+			break
 		}
 	}
+	return ctx.Err()
 }
 
-// handlePing sends periodic ping messages to keep the connection alive
-func (s *Service) handlePing(ctx context.Context, conn *websocket.Conn) {
-	ticker := time.NewTicker(s.config.WebSocket.PingInterval)
-	defer ticker.Stop()
+// sleepContext waits for d and reports whether ctx is still active
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				log.Printf("Failed to send ping: %v", err)
-				return
+// closeRecordGroup finalizes the archive of a symbol group whose stream stopped
+func (s *Service) closeRecordGroup(group string) {
+	if s.recorder == nil {
+		return
+	}
+	if err := s.recorder.CloseGroup(group); err != nil {
+		log.Printf("Failed to close record group %s: %v", group, err)
+	}
+}
+
+// messageHandler returns the handler for raw messages of one symbol group
+func (s *Service) messageHandler(ctx context.Context, recordGroup string) exchange.MessageHandler {
+	return func(message []byte) error {
+		s.markMessage()
+		if message == nil {
+			// A frame the client handled itself, e.g. a ticker
+			return nil
+		}
+
+		// Archive the raw frame independently of Redis storage
+		if s.recorder != nil {
+			if err := s.recorder.Record(recordGroup, message); err != nil {
+				log.Printf("Failed to record message: %v", err)
 			}
 		}
+
+		return s.processMessage(ctx, message)
 	}
 }
 
-// processMessage processes a WebSocket message and publishes it to Redis
+// payloadQuarantiner keeps rejected messages for inspection
+type payloadQuarantiner interface {
+	QuarantinePayload(ctx context.Context, payload []byte, cause error) error
+}
+
+// processMessage normalizes a raw message and queues it for the message bus.
+// Messages that do not decode, lack a symbol or trade time, or whose price or
+// quantity is not a number are quarantined instead.
 func (s *Service) processMessage(ctx context.Context, message []byte) error {
-	var event models.AggTradeEvent
-	if err := event.UnmarshalJSON(message); err != nil {
-		return fmt.Errorf("failed to unmarshal message: %w", err)
+	event, err := s.client.ParseTrade(message)
+	if err != nil {
+		s.quarantineMessage(ctx, message, err)
+		return err
+	}
+	// Parse price and quantity once for every consumer of the bus
+	if err = event.Validate(); err == nil {
+		err = event.Data.ParseNumeric()
+	}
+	if err != nil {
+		models.ReleaseAggTradeEvent(event)
+		s.quarantineMessage(ctx, message, err)
+		return fmt.Errorf("rejected message: %w", err)
 	}
 
-	// Publish to message bus
-	if err := s.messageBus.Publish(ctx, &event); err != nil {
-		return fmt.Errorf("failed to publish message: %w", err)
+	if s.queue != nil {
+		return s.queue.enqueue(ctx, event)
 	}
+	return s.publish(ctx, event)
+}
+
+// quarantineMessage keeps a rejected message. Failing to keep it only warns.
+func (s *Service) quarantineMessage(ctx context.Context, message []byte, cause error) {
+	if s.quarantine == nil {
+		return
+	}
+	if err := s.quarantine.QuarantinePayload(ctx, message, cause); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
 
+// publish sends a trade to the message bus, after which the event goes back
+// to the pool ParseTrade may have taken it from
+func (s *Service) publish(ctx context.Context, event *models.AggTradeEvent) error {
+	defer models.ReleaseAggTradeEvent(event)
+	if err := s.messageBus.Publish(ctx, event); err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
 	return nil
 }
 
-// Stop gracefully stops all WebSocket connections
-func (s *Service) Stop() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// flushBus publishes the trades still batched
+func (s *Service) flushBus() {
+	if err := s.messageBus.Close(); err != nil {
+		log.Printf("Failed to publish batched trades: %v", err)
+	}
+}
 
-	for _, conn := range s.wsConns {
-		conn.Close()
+// Stop releases resources, publishing trades still batched; connections
+// close when the Start context is cancelled
+func (s *Service) Stop() {
+	s.flushBus()
+	if s.recorder != nil {
+		if err := s.recorder.Close(); err != nil {
+			log.Printf("Failed to close recorder: %v", err)
+		}
 	}
-	s.wsConns = make(map[string]*websocket.Conn)
 }
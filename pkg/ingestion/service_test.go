@@ -0,0 +1,460 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/binance"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/exchange"
+	"binance-redis-streamer/pkg/exchange/fake"
+	"binance-redis-streamer/pkg/storage"
+)
+
+// mockExchange serves an exchangeInfo response whose symbol list can be
+// swapped between calls
+type mockExchange struct {
+	mu      sync.Mutex
+	symbols []string
+	down    bool // Answer every request with 503
+}
+
+func (m *mockExchange) setDown(down bool) {
+	m.mu.Lock()
+	m.down = down
+	m.mu.Unlock()
+}
+
+func (m *mockExchange) setSymbols(symbols ...string) {
+	m.mu.Lock()
+	m.symbols = symbols
+	m.mu.Unlock()
+}
+
+func (m *mockExchange) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.down {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	entries := make([]string, 0, len(m.symbols))
+	for _, symbol := range m.symbols {
+		entries = append(entries, fmt.Sprintf(`{"symbol":%q,"status":"TRADING"}`, symbol))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"symbols":[%s]}`, strings.Join(entries, ","))
+}
+
+func setupTestService(t *testing.T, exchange *mockExchange) (*Service, func()) {
+	server := httptest.NewServer(exchange)
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.Binance.BaseURL = server.URL
+	cfg.Binance.MainSymbols = nil
+	cfg.Binance.MaxSymbols = 10
+	cfg.Binance.MinDailyVolume = 0
+	cfg.Binance.MaxStreamsPerConn = 2
+	cfg.Binance.SymbolRefreshInterval = 0
+
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		mr.Close()
+		server.Close()
+		t.Fatal(err)
+	}
+
+	svc := NewService(cfg, binance.NewClient(cfg, store), store)
+
+	return svc, func() {
+		store.Close()
+		mr.Close()
+		server.Close()
+	}
+}
+
+// streamRecorder replaces real WebSocket streaming and tracks live groups
+type streamRecorder struct {
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+func (r *streamRecorder) stream(ctx context.Context, symbols []string) error {
+	key := strings.Join(symbols, ",")
+	r.mu.Lock()
+	r.active[key] = true
+	r.mu.Unlock()
+
+	<-ctx.Done()
+
+	r.mu.Lock()
+	delete(r.active, key)
+	r.mu.Unlock()
+	return ctx.Err()
+}
+
+func (r *streamRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.active)
+}
+
+func TestService_RefreshSymbols(t *testing.T) {
+	exchange := &mockExchange{}
+	exchange.setSymbols("BTCUSDT", "ETHUSDT", "BNBUSDT")
+
+	svc, cleanup := setupTestService(t, exchange)
+	defer cleanup()
+
+	recorder := &streamRecorder{active: make(map[string]bool)}
+	svc.streamGroup = recorder.stream
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := svc.refreshSymbols(ctx); err != nil {
+		t.Fatalf("Initial discovery failed: %v", err)
+	}
+	assertSymbols(t, svc.ActiveSymbols(), "bnbusdt", "btcusdt", "ethusdt")
+
+	// A new listing appears and one symbol is delisted
+	exchange.setSymbols("BTCUSDT", "ETHUSDT", "SOLUSDT")
+	if err := svc.refreshSymbols(ctx); err != nil {
+		t.Fatalf("Rediscovery failed: %v", err)
+	}
+	assertSymbols(t, svc.ActiveSymbols(), "btcusdt", "ethusdt", "solusdt")
+
+	// Groups are capped at MaxStreamsPerConn, so three symbols need two connections
+	deadline := time.Now().Add(time.Second)
+	for recorder.count() != 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := recorder.count(); got != 2 {
+		t.Errorf("Expected 2 live connections, got %d", got)
+	}
+
+	// An unchanged symbol set must not touch existing connections
+	added, removed := svc.applySymbols(ctx, []string{"btcusdt", "ethusdt", "solusdt"})
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("Expected no changes, got added=%v removed=%v", added, removed)
+	}
+
+	cancel()
+	svc.groupWg.Wait()
+	if got := recorder.count(); got != 0 {
+		t.Errorf("Expected all connections closed after cancel, got %d", got)
+	}
+}
+
+func TestService_FileSymbolSource(t *testing.T) {
+	// The exchange lists other symbols; the file pins the set
+	venue := &mockExchange{}
+	venue.setSymbols("BTCUSDT", "ETHUSDT", "BNBUSDT")
+
+	svc, cleanup := setupTestService(t, venue)
+	defer cleanup()
+
+	recorder := &streamRecorder{active: make(map[string]bool)}
+	svc.streamGroup = recorder.stream
+
+	path := filepath.Join(t.TempDir(), "symbols.txt")
+	if err := os.WriteFile(path, []byte("SOLUSDT\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	source, err := exchange.NewFileSymbolSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.SetSymbolSource(source)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- svc.Start(ctx) }()
+
+	waitForSymbols := func(want ...string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for strings.Join(svc.ActiveSymbols(), ",") != strings.Join(want, ",") && time.Now().Before(deadline) {
+			time.Sleep(20 * time.Millisecond)
+		}
+		assertSymbols(t, svc.ActiveSymbols(), want...)
+	}
+	waitForSymbols("solusdt")
+
+	// Editing the file resubscribes without waiting for rediscovery; the
+	// watch starts asynchronously, so keep writing until it is seen
+	deadline := time.Now().Add(5 * time.Second)
+	for strings.Join(svc.ActiveSymbols(), ",") != "ethusdt,solusdt" && time.Now().Before(deadline) {
+		os.WriteFile(path, []byte("SOLUSDT\nETHUSDT\n"), 0o644)
+		time.Sleep(50 * time.Millisecond)
+	}
+	assertSymbols(t, svc.ActiveSymbols(), "ethusdt", "solusdt")
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Start() = %v, want context.Canceled", err)
+	}
+}
+
+func assertSymbols(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected active symbols %v, got %v", want, got)
+	}
+}
+
+func TestCreateSymbolGroups_StreamLimit(t *testing.T) {
+	symbols := func(n int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = fmt.Sprintf("SYM%dUSDT", i)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name       string
+		perConn    int
+		symbols    int
+		wantGroups []int
+	}{
+		{"exactly the limit", config.MaxBinanceStreamsPerConn, 1024, []int{1024}},
+		{"one over the limit", config.MaxBinanceStreamsPerConn, 1025, []int{1024, 1}},
+		{"configured above the limit", 2000, 1025, []int{1024, 1}},
+		{"zero uses the limit", 0, 1024, []int{1024}},
+		{"below the limit", 1000, 1025, []int{1000, 25}},
+		{"no symbols", 1000, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Binance.MaxStreamsPerConn = tt.perConn
+			svc := &Service{config: cfg}
+
+			groups := svc.createSymbolGroups(symbols(tt.symbols))
+			if len(groups) != len(tt.wantGroups) {
+				t.Fatalf("Expected %d groups, got %d", len(tt.wantGroups), len(groups))
+			}
+			for i, group := range groups {
+				if len(group) != tt.wantGroups[i] {
+					t.Errorf("Group %d: expected %d symbols, got %d", i, tt.wantGroups[i], len(group))
+				}
+			}
+		})
+	}
+}
+
+func TestCreateSymbolGroups_URLLength(t *testing.T) {
+	// Long names push the combined stream URL past the length limit well
+	// before the per-connection stream limit
+	symbols := make([]string, 1000)
+	for i := range symbols {
+		symbols[i] = fmt.Sprintf("%sUSDT%04d", strings.Repeat("LONGNAME", 4), i)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Binance.MaxStreamsPerConn = config.MaxBinanceStreamsPerConn
+	client := binance.NewTestClient(cfg, nil)
+	svc := &Service{config: cfg, client: client}
+
+	groups := svc.createSymbolGroups(symbols)
+	if len(groups) < 2 {
+		t.Fatalf("Expected the group to be split, got %d groups", len(groups))
+	}
+
+	total := 0
+	for i, group := range groups {
+		if n := len(client.BuildStreamURL(group)); n > exchange.MaxStreamURLLength {
+			t.Errorf("Group %d: URL is %d bytes, over the %d byte limit", i, n, exchange.MaxStreamURLLength)
+		}
+		for j, symbol := range group {
+			if symbol != symbols[total+j] {
+				t.Fatalf("Group %d: symbols reordered at %d", i, j)
+			}
+		}
+		total += len(group)
+	}
+	if total != len(symbols) {
+		t.Errorf("Expected %d symbols across groups, got %d", len(symbols), total)
+	}
+
+	// A symbol too long for any URL keeps its own group
+	huge := strings.Repeat("X", exchange.MaxStreamURLLength)
+	groups = svc.createSymbolGroups([]string{"BTCUSDT", huge})
+	if len(groups) != 2 || len(groups[1]) != 1 || groups[1][0] != huge {
+		t.Errorf("Expected the oversized symbol alone in the last group, got %d groups", len(groups))
+	}
+}
+
+// volumeClient reports fixed 24h volumes for its symbols
+type volumeClient struct {
+	*fake.Exchange
+	volumes map[string]float64
+}
+
+func (c *volumeClient) SymbolVolumes() map[string]float64 {
+	return c.volumes
+}
+
+func TestCreateSymbolGroups_BalancedByVolume(t *testing.T) {
+	// Discovery order puts the two busiest pairs in the same group
+	symbols := []string{"btcusdt", "ethusdt", "solusdt", "xrpusdt", "dogeusdt", "adausdt", "linkusdt", "dotusdt"}
+	volumes := map[string]float64{
+		"btcusdt": 900, "ethusdt": 800, "solusdt": 50, "xrpusdt": 40,
+		"dogeusdt": 30, "adausdt": 20, "linkusdt": 10, // dotusdt has no volume data
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Binance.MaxStreamsPerConn = 2
+	svc := &Service{config: cfg, client: &volumeClient{Exchange: fake.New(symbols...), volumes: volumes}}
+
+	maxLoad := func(groups [][]string) float64 {
+		max := 0.0
+		for _, group := range groups {
+			load := 0.0
+			for _, symbol := range group {
+				load += volumes[symbol]
+			}
+			if load > max {
+				max = load
+			}
+		}
+		return max
+	}
+
+	naive := svc.createSymbolGroups(symbols)
+	cfg.Binance.BalanceGroupsByVolume = true
+	balanced := svc.createSymbolGroups(symbols)
+
+	if len(balanced) != len(naive) {
+		t.Fatalf("Expected %d groups, got %d", len(naive), len(balanced))
+	}
+	seen := make(map[string]bool)
+	for i, group := range balanced {
+		if len(group) > cfg.Binance.StreamsPerConn() {
+			t.Errorf("Group %d: %d symbols, over the limit of %d", i, len(group), cfg.Binance.StreamsPerConn())
+		}
+		for _, symbol := range group {
+			seen[symbol] = true
+		}
+	}
+	if len(seen) != len(symbols) {
+		t.Errorf("Expected all %d symbols across groups, got %d", len(symbols), len(seen))
+	}
+	if maxLoad(balanced) >= maxLoad(naive) {
+		t.Errorf("Expected a lower max group volume than %v, got %v", maxLoad(naive), maxLoad(balanced))
+	}
+
+	// Without volume data the discovery order is kept
+	svc.client = fake.New(symbols...)
+	if groups := svc.createSymbolGroups(symbols); maxLoad(groups) != maxLoad(naive) {
+		t.Errorf("Expected naive groups without volume data, got %v", groups)
+	}
+}
+
+// badSymbolClient fails every stream with ErrBadSymbol
+type badSymbolClient struct {
+	*fake.Exchange
+	streams int
+}
+
+func (c *badSymbolClient) StreamTrades(ctx context.Context, symbols []string, handler exchange.MessageHandler) error {
+	c.streams++
+	return fmt.Errorf("%w: %q", exchange.ErrBadSymbol, symbols[0])
+}
+
+func TestProcessSymbolGroup_StopsOnBadSymbol(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.WebSocket.ReconnectDelay = time.Millisecond
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	client := &badSymbolClient{Exchange: fake.New()}
+	svc := NewService(cfg, client, store)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = svc.processSymbolGroup(ctx, []string{"btc/usdt"})
+	if !errors.Is(err, exchange.ErrBadSymbol) {
+		t.Errorf("Expected ErrBadSymbol, got %v", err)
+	}
+	if client.streams != 1 {
+		t.Errorf("Expected no reconnects after a bad symbol, got %d streams", client.streams)
+	}
+}
+
+// payloadRecorder records quarantined messages
+type payloadRecorder struct {
+	causes []error
+}
+
+func (r *payloadRecorder) QuarantinePayload(ctx context.Context, payload []byte, cause error) error {
+	r.causes = append(r.causes, cause)
+	return nil
+}
+
+func TestProcessMessage_QuarantinesRejectedMessages(t *testing.T) {
+	svc, cleanup := setupTestService(t, &mockExchange{})
+	defer cleanup()
+	quarantine := &payloadRecorder{}
+	svc.quarantine = quarantine
+
+	ctx := context.Background()
+	rejected := []struct {
+		message string
+		wantErr error
+	}{
+		{`{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","t":1,"p":"50000.00","q":"1.5"}}`, models.ErrMissingTradeTime},
+		{`{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","t":1,"p":"abc","q":"1.5","T":1672515782136}}`, models.ErrInvalidNumber},
+		{`{"stream":"btcusdt@trade","data":{"e":"trade","t":1,"p":"1","q":"1.5","T":1672515782136}}`, models.ErrMissingSymbol},
+		{`{"stream":"btcusdt@trade","data":`, nil},
+	}
+	for _, tt := range rejected {
+		err := svc.processMessage(ctx, []byte(tt.message))
+		if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+			t.Errorf("processMessage(%s) error = %v, want %v", tt.message, err, tt.wantErr)
+		}
+	}
+	if len(quarantine.causes) != len(rejected) {
+		t.Fatalf("Expected %d quarantined messages, got %d", len(rejected), len(quarantine.causes))
+	}
+
+	valid := `{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","t":1,"p":"50000.00","q":"1.5","T":1672515782136}}`
+	if err := svc.processMessage(ctx, []byte(valid)); err != nil {
+		t.Fatalf("Expected a valid trade to be published, got %v", err)
+	}
+	if len(quarantine.causes) != len(rejected) {
+		t.Error("Expected a valid trade to not be quarantined")
+	}
+}
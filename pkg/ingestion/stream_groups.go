@@ -0,0 +1,70 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// streamGroupStore persists the assignments of symbol groups to connections
+type streamGroupStore interface {
+	SaveStreamGroup(ctx context.Context, group *models.StreamGroup, ttl time.Duration) error
+	RemoveStreamGroup(ctx context.Context, connection string) error
+}
+
+// processName identifies this process in stream group assignments
+func processName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// streamGroupTTL is how long an assignment stays valid without a heartbeat
+func (s *Service) streamGroupTTL() time.Duration {
+	return 3 * s.config.Ingestion.GroupHeartbeat
+}
+
+// saveStreamGroup reports the assignment of a tracked group. Failing to
+// report it only warns.
+func (s *Service) saveStreamGroup(ctx context.Context, tracker *connectionTracker) {
+	if s.assignments == nil {
+		return
+	}
+	group := tracker.streamGroup(s.process, s.now())
+	if err := s.assignments.SaveStreamGroup(ctx, group, s.streamGroupTTL()); err != nil {
+		log.Printf("Warning: failed to save stream group %s: %v", group.Group, err)
+	}
+}
+
+// removeStreamGroup deletes the assignment of a tracked connection that
+// stopped, even once ctx is cancelled on shutdown
+func (s *Service) removeStreamGroup(ctx context.Context, tracker *connectionTracker) {
+	if s.assignments == nil {
+		return
+	}
+	if err := s.assignments.RemoveStreamGroup(context.WithoutCancel(ctx), tracker.id); err != nil {
+		log.Printf("Warning: failed to remove stream group %s: %v", tracker.metrics.Group, err)
+	}
+}
+
+// runGroupHeartbeat refreshes the assignment of every tracked group on each
+// tick until ctx is cancelled
+func (s *Service) runGroupHeartbeat(ctx context.Context, ticks <-chan time.Time) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			s.connections.Range(func(_, value interface{}) bool {
+				s.saveStreamGroup(ctx, value.(*connectionTracker))
+				return true
+			})
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package ingestion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/exchange/fake"
+	"binance-redis-streamer/pkg/metrics"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func TestProcessSymbolGroup_ReportsStreamGroup(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.WebSocket.ReconnectDelay = time.Millisecond
+	cfg.Ingestion.GroupHeartbeat = 10 * time.Second
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	client := &droppingClient{Exchange: fake.New(), drops: 2}
+	svc := NewService(cfg, client, store)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := make(chan time.Time)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.processSymbolGroup(ctx, []string{"BTCUSDT", "ETHUSDT"})
+	}()
+	go svc.runGroupHeartbeat(ctx, ticks)
+
+	key := storage.StreamGroupsKey(cfg.Redis.KeyPrefix)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		groups, err := store.GetStreamGroups(context.Background(), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(groups) == 1 && groups[0].Reconnects == 2 {
+			g := groups[0]
+			if g.Group != "btcusdt-2" || len(g.Symbols) != 2 || g.Process != svc.process || !g.ConnectedAt.Equal(now) {
+				t.Errorf("Unexpected stream group %+v", g)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the group's third connection, got %+v", groups)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := mr.TTL(key); got != 30*time.Second {
+		t.Errorf("Expected the groups to expire in 30s, got %s", got)
+	}
+
+	// A heartbeat refreshes the assignment and its expiry
+	mr.FastForward(20 * time.Second)
+	now = now.Add(20 * time.Second)
+	ticks <- now
+	ticks <- now // Returns once the first tick is handled
+	if got := mr.TTL(key); got != 30*time.Second {
+		t.Errorf("Expected a heartbeat to reset the expiry to 30s, got %s", got)
+	}
+	groups, err := store.GetStreamGroups(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || !groups[0].Heartbeat.Equal(now) {
+		t.Errorf("Expected the heartbeat at %s, got %+v", now, groups)
+	}
+
+	// Stopping removes the assignment
+	cancel()
+	<-done
+	if groups, err = store.GetStreamGroups(context.Background(), now); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("Expected no stream groups after stopping, got %+v", groups)
+	}
+}
+
+func TestUntrackConnection_KeepsRegroupedConnection(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	svc := NewService(cfg, fake.New(), store)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// A regroup starts the new connection of a group before the old one,
+	// under the same name, has stopped
+	symbols := []string{"BTCUSDT", "ETHUSDT"}
+	old := svc.trackConnection(symbols)
+	svc.saveStreamGroup(ctx, old)
+	current := svc.trackConnection(symbols)
+	svc.saveStreamGroup(ctx, current)
+	current.message(10, now)
+	svc.untrackConnection(old)
+	svc.removeStreamGroup(ctx, old)
+
+	if got := svc.Connections(); len(got) != 1 || got[0].MessagesReceived != 1 {
+		t.Errorf("Expected the new connection still tracked, got %+v", got)
+	}
+	group := recordGroupName(symbols)
+	if got := testutil.ToFloat64(metrics.IngestionMessages.WithLabelValues(group)); got != 1 {
+		t.Errorf("Expected the %s counter kept at 1, got %v", group, got)
+	}
+	groups, err := store.GetStreamGroups(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Connection != current.id {
+		t.Errorf("Expected only the new connection's assignment, got %+v", groups)
+	}
+
+	series := testutil.CollectAndCount(metrics.IngestionMessages)
+	svc.untrackConnection(current)
+	if got := testutil.CollectAndCount(metrics.IngestionMessages); got != series-1 {
+		t.Errorf("Expected the %s counter removed with its last connection, got %d series, was %d", group, got, series)
+	}
+}
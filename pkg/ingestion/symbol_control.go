@@ -0,0 +1,246 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"binance-redis-streamer/pkg/exchange"
+	"binance-redis-streamer/pkg/storage"
+)
+
+// symbolControlPollTimeout bounds each wait for a symbol request, and so how
+// long shutdown waits on the control loop
+const symbolControlPollTimeout = time.Second
+
+// errSymbolRejected marks symbol requests that can never be applied, such
+// as adding a symbol the exchange does not trade; others are retried
+var errSymbolRejected = errors.New("symbol request rejected")
+
+// symbolControlStore receives the symbols operators add and remove at
+// runtime, e.g. with binance-cli symbols add, and keeps them across
+// restarts and leader changes
+type symbolControlStore interface {
+	PopSymbolControl(ctx context.Context, timeout time.Duration) (*storage.SymbolControl, error)
+	RequeueSymbolControl(ctx context.Context, request *storage.SymbolControl) error
+	AddTrackedSymbol(ctx context.Context, symbol string) error
+	RemoveTrackedSymbol(ctx context.Context, symbol string) error
+	SymbolOverrides(ctx context.Context) (map[string]string, error)
+}
+
+// runSymbolControl applies symbol requests as they arrive until ctx is
+// cancelled
+func (s *Service) runSymbolControl(ctx context.Context) {
+	for ctx.Err() == nil {
+		request, err := s.control.PopSymbolControl(ctx, symbolControlPollTimeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Warning: %v", err)
+				sleepContext(ctx, symbolControlPollTimeout)
+			}
+			continue
+		}
+		if request == nil {
+			continue
+		}
+
+		switch request.Action {
+		case storage.SymbolControlAdd:
+			err = s.addSymbol(ctx, request.Symbol)
+		case storage.SymbolControlRemove:
+			err = s.removeSymbol(ctx, request.Symbol)
+		default:
+			err = fmt.Errorf("%w: unknown action %q", errSymbolRejected, request.Action)
+		}
+		if err == nil {
+			continue
+		}
+		action := strings.TrimSuffix(request.Action, "_symbol")
+		if errors.Is(err, errSymbolRejected) {
+			log.Printf("Ignoring request to %s %s: %v", action, request.Symbol, err)
+			continue
+		}
+
+		// The request stays queued, also when shutdown interrupted it, so
+		// this or the next leader applies it once exchangeInfo or Redis are
+		// back
+		log.Printf("Retrying request to %s %s: %v", action, request.Symbol, err)
+		if err := s.control.RequeueSymbolControl(context.WithoutCancel(ctx), request); err != nil {
+			log.Printf("Warning: dropped request to %s %s: %v", action, request.Symbol, err)
+		}
+		sleepContext(ctx, symbolControlPollTimeout)
+	}
+}
+
+// loadSymbolOverrides replaces the symbols added and removed at runtime with
+// those stored, which a previous leader or run may have changed
+func (s *Service) loadSymbolOverrides(ctx context.Context) error {
+	overrides, err := s.control.SymbolOverrides(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+	s.addedSymbols = make(map[string]bool)
+	s.removedSymbols = make(map[string]bool)
+	for symbol, action := range overrides {
+		switch action {
+		case storage.SymbolControlAdd:
+			s.addedSymbols[strings.ToLower(symbol)] = true
+		case storage.SymbolControlRemove:
+			s.removedSymbols[strings.ToLower(symbol)] = true
+		}
+	}
+	return nil
+}
+
+// addSymbol starts streaming symbol after checking the exchange trades it.
+// It stays streamed across symbol rediscovery until removed.
+func (s *Service) addSymbol(ctx context.Context, symbol string) error {
+	if err := s.checkTrading(ctx, symbol); err != nil {
+		return err
+	}
+	if err := s.control.AddTrackedSymbol(ctx, symbol); err != nil {
+		return err
+	}
+
+	symbol = strings.ToLower(symbol)
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+	delete(s.removedSymbols, symbol)
+	s.addedSymbols[symbol] = true
+	if s.routeSymbol(ctx, symbol) {
+		log.Printf("Added symbol %s", strings.ToUpper(symbol))
+	}
+	return nil
+}
+
+// removeSymbol stops streaming symbol; it stays unstreamed across symbol
+// rediscovery until added again
+func (s *Service) removeSymbol(ctx context.Context, symbol string) error {
+	if err := s.control.RemoveTrackedSymbol(ctx, symbol); err != nil {
+		return err
+	}
+
+	symbol = strings.ToLower(symbol)
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+	delete(s.addedSymbols, symbol)
+	s.removedSymbols[symbol] = true
+	if s.unrouteSymbol(ctx, symbol) {
+		log.Printf("Removed symbol %s", strings.ToUpper(symbol))
+	}
+	return nil
+}
+
+// checkTrading reports an error unless the exchange lists symbol as trading.
+// Clients that cannot list their symbols accept any symbol.
+func (s *Service) checkTrading(ctx context.Context, symbol string) error {
+	lister, ok := s.client.(exchange.SymbolLister)
+	if !ok {
+		return nil
+	}
+	symbols, err := lister.ListSymbols(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check symbol: %w", err)
+	}
+	for _, listed := range symbols {
+		if strings.EqualFold(listed, symbol) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not trading on %s", errSymbolRejected, strings.ToUpper(symbol), s.client.Name())
+}
+
+// withSymbolOverrides applies the symbols added and removed at runtime to
+// discovered symbols
+func (s *Service) withSymbolOverrides(symbols []string) []string {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	seen := make(map[string]bool, len(symbols)+len(s.addedSymbols))
+	merged := make([]string, 0, len(symbols)+len(s.addedSymbols))
+	for _, symbol := range symbols {
+		if !s.removedSymbols[symbol] && !seen[symbol] {
+			seen[symbol] = true
+			merged = append(merged, symbol)
+		}
+	}
+	for symbol := range s.addedSymbols {
+		if !seen[symbol] {
+			merged = append(merged, symbol)
+		}
+	}
+	return merged
+}
+
+// routeSymbol adds symbol to the connection carrying the fewest symbols that
+// has room for it, or opens a connection when all are full, and reports
+// whether it was not streamed yet. The chosen connection reconnects with the
+// new stream list; callers must hold groupMu.
+func (s *Service) routeSymbol(ctx context.Context, symbol string) bool {
+	best := -1
+	for id, group := range s.groups {
+		for _, streamed := range group.symbols {
+			if streamed == symbol {
+				return false
+			}
+		}
+		if !s.fitsGroup(append(group.symbols[:len(group.symbols):len(group.symbols)], symbol)) {
+			continue
+		}
+		if best < 0 || len(group.symbols) < len(s.groups[best].symbols) ||
+			(len(group.symbols) == len(s.groups[best].symbols) && id < best) {
+			best = id
+		}
+	}
+
+	if best < 0 {
+		s.startGroup(ctx, []string{symbol})
+		return true
+	}
+	group := s.groups[best]
+	group.cancel()
+	delete(s.groups, best)
+	s.startGroup(ctx, append(append([]string(nil), group.symbols...), symbol))
+	return true
+}
+
+// unrouteSymbol drops symbol from the connection carrying it, which
+// reconnects with the remaining symbols, and reports whether it was
+// streamed; callers must hold groupMu
+func (s *Service) unrouteSymbol(ctx context.Context, symbol string) bool {
+	for id, group := range s.groups {
+		remaining := make([]string, 0, len(group.symbols))
+		for _, streamed := range group.symbols {
+			if streamed != symbol {
+				remaining = append(remaining, streamed)
+			}
+		}
+		if len(remaining) == len(group.symbols) {
+			continue
+		}
+
+		group.cancel()
+		delete(s.groups, id)
+		if len(remaining) > 0 {
+			s.startGroup(ctx, remaining)
+		}
+		return true
+	}
+	return false
+}
+
+// fitsGroup reports whether symbols can share one connection, within the
+// streams per connection and the stream URL length
+func (s *Service) fitsGroup(symbols []string) bool {
+	if len(symbols) > s.config.Binance.SymbolsPerConn() {
+		return false
+	}
+	builder, ok := s.client.(exchange.StreamURLBuilder)
+	return !ok || len(builder.BuildStreamURL(symbols)) <= exchange.MaxStreamURLLength
+}
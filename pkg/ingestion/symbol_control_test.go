@@ -0,0 +1,171 @@
+package ingestion
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/pkg/exchange"
+	"binance-redis-streamer/pkg/storage"
+)
+
+// groupKeys returns the symbols of each connection, sorted
+func groupKeys(svc *Service) []string {
+	svc.groupMu.Lock()
+	defer svc.groupMu.Unlock()
+
+	var keys []string
+	for _, group := range svc.groups {
+		symbols := append([]string(nil), group.symbols...)
+		sort.Strings(symbols)
+		keys = append(keys, strings.Join(symbols, ","))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func assertGroups(t *testing.T, svc *Service, want ...string) {
+	t.Helper()
+	if got := groupKeys(svc); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected connections %v, got %v", want, got)
+	}
+}
+
+func TestService_SymbolControl(t *testing.T) {
+	venue := &mockExchange{}
+	venue.setSymbols("BTCUSDT", "ETHUSDT", "BNBUSDT", "SOLUSDT", "XRPUSDT")
+
+	svc, cleanup := setupTestService(t, venue)
+	defer cleanup()
+
+	recorder := &streamRecorder{active: make(map[string]bool)}
+	svc.streamGroup = recorder.stream
+	source, err := exchange.NewStaticSymbolSource([]string{"BTCUSDT", "ETHUSDT", "BNBUSDT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.SetSymbolSource(source)
+	store := svc.control.(*storage.RedisStore)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		svc.groupWg.Wait()
+	}()
+
+	if err := svc.refreshSymbols(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertGroups(t, svc, "bnbusdt", "btcusdt,ethusdt")
+
+	// A new symbol joins the connection with the fewest symbols
+	if err := svc.addSymbol(ctx, "SOLUSDT"); err != nil {
+		t.Fatalf("addSymbol failed: %v", err)
+	}
+	assertGroups(t, svc, "bnbusdt,solusdt", "btcusdt,ethusdt")
+
+	// Once every connection is full it gets one of its own
+	if err := svc.addSymbol(ctx, "XRPUSDT"); err != nil {
+		t.Fatalf("addSymbol failed: %v", err)
+	}
+	assertGroups(t, svc, "bnbusdt,solusdt", "btcusdt,ethusdt", "xrpusdt")
+
+	if err := svc.addSymbol(ctx, "DOGEUSDT"); err == nil {
+		t.Error("Expected a symbol the exchange does not trade rejected")
+	}
+
+	if err := svc.removeSymbol(ctx, "ETHUSDT"); err != nil {
+		t.Fatalf("removeSymbol failed: %v", err)
+	}
+	assertGroups(t, svc, "bnbusdt,solusdt", "btcusdt", "xrpusdt")
+
+	members, err := store.GetRedisClient().SMembers(ctx, "binance:symbols").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(members)
+	if strings.Join(members, ",") != "SOLUSDT,XRPUSDT" {
+		t.Errorf("Expected SOLUSDT and XRPUSDT tracked, got %v", members)
+	}
+
+	// Rediscovery keeps the runtime changes
+	if err := svc.refreshSymbols(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertSymbols(t, svc.ActiveSymbols(), "bnbusdt", "btcusdt", "solusdt", "xrpusdt")
+
+	// Requests from the CLI arrive through the control lists
+	go svc.runSymbolControl(ctx)
+	if err := store.RequestSymbolRemove(ctx, "XRPUSDT"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for strings.Join(svc.ActiveSymbols(), ",") != "bnbusdt,btcusdt,solusdt" && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	assertSymbols(t, svc.ActiveSymbols(), "bnbusdt", "btcusdt", "solusdt")
+
+	// Replaced connections are closed
+	deadline = time.Now().Add(time.Second)
+	for recorder.count() != 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := recorder.count(); got != 2 {
+		t.Errorf("Expected 2 live connections, got %d", got)
+	}
+}
+
+func TestService_SymbolControlRetriesAndPersists(t *testing.T) {
+	venue := &mockExchange{}
+	venue.setSymbols("BTCUSDT", "ETHUSDT", "SOLUSDT")
+
+	svc, cleanup := setupTestService(t, venue)
+	defer cleanup()
+
+	recorder := &streamRecorder{active: make(map[string]bool)}
+	svc.streamGroup = recorder.stream
+	source, err := exchange.NewStaticSymbolSource([]string{"BTCUSDT", "ETHUSDT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.SetSymbolSource(source)
+	store := svc.control.(*storage.RedisStore)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		svc.groupWg.Wait()
+	}()
+	if err := svc.refreshSymbols(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A request that cannot be checked against exchangeInfo is kept until
+	// it can
+	venue.setDown(true)
+	go svc.runSymbolControl(ctx)
+	if err := store.RequestSymbolAdd(ctx, "SOLUSDT"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	assertSymbols(t, svc.ActiveSymbols(), "btcusdt", "ethusdt")
+
+	venue.setDown(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for strings.Join(svc.ActiveSymbols(), ",") != "btcusdt,ethusdt,solusdt" && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	assertSymbols(t, svc.ActiveSymbols(), "btcusdt", "ethusdt", "solusdt")
+	if err := svc.removeSymbol(ctx, "ETHUSDT"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another replica taking over applies the same changes to discovery
+	follower := NewService(svc.config, svc.client, store)
+	if err := follower.loadSymbolOverrides(ctx); err != nil {
+		t.Fatalf("loadSymbolOverrides failed: %v", err)
+	}
+	assertSymbols(t, follower.withSymbolOverrides([]string{"btcusdt", "ethusdt"}), "btcusdt", "solusdt")
+}
@@ -0,0 +1,104 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrWatchdogExhausted is returned by Start when connections stay silent after
+// the maximum number of full restarts
+var ErrWatchdogExhausted = errors.New("no messages received after maximum watchdog restarts")
+
+// watchdogState tracks restarts across watchdog checks
+type watchdogState struct {
+	restarts    int
+	lastRestart time.Time
+}
+
+// markMessage records that a message was received on any connection
+func (s *Service) markMessage() {
+	s.lastMessage.Store(s.now().UnixNano())
+}
+
+// runWatchdog checks for silence on every tick until ctx is cancelled or the
+// restart budget is exhausted
+func (s *Service) runWatchdog(ctx context.Context, ticks <-chan time.Time) error {
+	var state watchdogState
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticks:
+			if err := s.checkWatchdog(ctx, &state); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkWatchdog rebuilds all connections when no message has arrived within
+// the silence threshold during active hours
+func (s *Service) checkWatchdog(ctx context.Context, state *watchdogState) error {
+	cfg := s.config.Ingestion
+	now := s.now()
+	last := time.Unix(0, s.lastMessage.Load())
+
+	// Traffic since the last restart means the restart worked
+	if last.After(state.lastRestart) {
+		state.restarts = 0
+	}
+
+	if !withinActiveHours(now, cfg.WatchdogActiveFrom, cfg.WatchdogActiveTo) {
+		return nil
+	}
+
+	silentSince := last
+	if state.lastRestart.After(silentSince) {
+		silentSince = state.lastRestart
+	}
+	silence := now.Sub(silentSince)
+	if silence < cfg.WatchdogSilence {
+		return nil
+	}
+
+	if state.restarts >= cfg.WatchdogMaxRestarts {
+		return fmt.Errorf("%w (silent for %s, %d restarts)", ErrWatchdogExhausted, silence.Round(time.Second), state.restarts)
+	}
+
+	state.restarts++
+	state.lastRestart = now
+	log.Printf("Watchdog: no messages for %s, restarting all connections (attempt %d/%d)",
+		silence.Round(time.Second), state.restarts, cfg.WatchdogMaxRestarts)
+	s.restartGroups(ctx)
+	return nil
+}
+
+// withinActiveHours reports whether t falls in the UTC hour window [from, to);
+// windows with from > to wrap around midnight
+func withinActiveHours(t time.Time, from, to int) bool {
+	hour := t.UTC().Hour()
+	if from <= to {
+		return hour >= from && hour < to
+	}
+	return hour >= from || hour < to
+}
+
+// restartGroups tears down every connection and rebuilds the same symbol groups
+func (s *Service) restartGroups(ctx context.Context) {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	var symbols []string
+	for id, group := range s.groups {
+		group.cancel()
+		delete(s.groups, id)
+		symbols = append(symbols, group.symbols...)
+	}
+
+	for _, group := range s.createSymbolGroups(symbols) {
+		s.startGroup(ctx, group)
+	}
+}
@@ -0,0 +1,228 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+
+	"binance-redis-streamer/pkg/binance"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/storage"
+)
+
+// connectionCounter replaces real streaming, counting connection attempts
+type connectionCounter struct {
+	mu    sync.Mutex
+	dials int
+}
+
+func (c *connectionCounter) stream(ctx context.Context, _ []string) error {
+	c.mu.Lock()
+	c.dials++
+	c.mu.Unlock()
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (c *connectionCounter) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dials
+}
+
+func TestWatchdog_RestartsSilentConnections(t *testing.T) {
+	svc, cleanup := setupTestService(t, &mockExchange{})
+	defer cleanup()
+
+	svc.config.Ingestion.WatchdogSilence = time.Minute
+	svc.config.Ingestion.WatchdogMaxRestarts = 2
+
+	now := time.Date(2024, 12, 26, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	conns := &connectionCounter{}
+	svc.streamGroup = conns.stream
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		svc.groupWg.Wait()
+	}()
+
+	svc.markMessage()
+	svc.applySymbols(ctx, []string{"btcusdt", "ethusdt", "solusdt"}) // two groups
+	waitForDials(t, conns, 2)
+
+	var state watchdogState
+	check := func(advance time.Duration) error {
+		t.Helper()
+		now = now.Add(advance)
+		return svc.checkWatchdog(ctx, &state)
+	}
+
+	// Quiet, but below the threshold
+	if err := check(30 * time.Second); err != nil || conns.count() != 2 {
+		t.Fatalf("Expected no restart before the threshold (err=%v, dials=%d)", err, conns.count())
+	}
+
+	// Silence exceeds the threshold: all groups are rebuilt
+	if err := check(31 * time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitForDials(t, conns, 4)
+	assertSymbols(t, svc.ActiveSymbols(), "btcusdt", "ethusdt", "solusdt")
+
+	// Messages flow again after the restart, resetting the restart budget
+	now = now.Add(10 * time.Second)
+	svc.markMessage()
+	if err := check(10 * time.Second); err != nil || state.restarts != 0 {
+		t.Fatalf("Expected restart budget to reset (err=%v, restarts=%d)", err, state.restarts)
+	}
+
+	// Silence persists through every allowed restart, then the watchdog gives up
+	for i := 0; i < 2; i++ {
+		if err := check(time.Minute); err != nil {
+			t.Fatalf("Restart %d failed: %v", i+1, err)
+		}
+	}
+	waitForDials(t, conns, 8)
+
+	err := check(time.Minute)
+	if !errors.Is(err, ErrWatchdogExhausted) {
+		t.Fatalf("Expected ErrWatchdogExhausted, got %v", err)
+	}
+}
+
+func TestWatchdog_IgnoresSilenceOutsideActiveHours(t *testing.T) {
+	svc, cleanup := setupTestService(t, &mockExchange{})
+	defer cleanup()
+
+	svc.config.Ingestion.WatchdogSilence = time.Minute
+	svc.config.Ingestion.WatchdogMaxRestarts = 0
+	svc.config.Ingestion.WatchdogActiveFrom = 22 // 22:00-06:00 UTC, wrapping midnight
+	svc.config.Ingestion.WatchdogActiveTo = 6
+
+	now := time.Date(2024, 12, 26, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	svc.markMessage()
+
+	var state watchdogState
+	now = now.Add(time.Hour)
+	if err := svc.checkWatchdog(context.Background(), &state); err != nil {
+		t.Fatalf("Expected silence outside active hours to be ignored, got %v", err)
+	}
+
+	now = time.Date(2024, 12, 26, 23, 0, 0, 0, time.UTC)
+	if err := svc.checkWatchdog(context.Background(), &state); !errors.Is(err, ErrWatchdogExhausted) {
+		t.Fatalf("Expected silence inside active hours to trip the watchdog, got %v", err)
+	}
+}
+
+func TestService_StartFailsWhenWatchdogExhausted(t *testing.T) {
+	exchange := &mockExchange{}
+	exchange.setSymbols("BTCUSDT")
+
+	svc, cleanup := setupTestService(t, exchange)
+	defer cleanup()
+
+	svc.config.Ingestion.WatchdogSilence = 20 * time.Millisecond
+	svc.config.Ingestion.WatchdogMaxRestarts = 1
+
+	conns := &connectionCounter{}
+	svc.streamGroup = conns.stream
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- svc.Start(context.Background()) }()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrWatchdogExhausted) {
+			t.Fatalf("Expected ErrWatchdogExhausted, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after the watchdog gave up")
+	}
+
+	if got := conns.count(); got != 2 {
+		t.Errorf("Expected initial connection plus one restart, got %d dials", got)
+	}
+}
+
+func TestService_WatchdogSeesTickerOnlyConnections(t *testing.T) {
+	// A miniTicker-only group delivers no trades, only tickers
+	var dials atomic.Int32
+	streams := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		dials.Add(1)
+		for {
+			frame := `{"stream":"btcusdt@miniTicker","data":{"e":"24hrMiniTicker","E":1,"s":"BTCUSDT","c":"100","o":"99","h":"101","l":"98","v":"10","q":"1000"}}`
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer streams.Close()
+
+	exchange := &mockExchange{}
+	exchange.setSymbols("BTCUSDT")
+	rest := httptest.NewServer(exchange)
+	defer rest.Close()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.Binance.BaseURL = rest.URL
+	cfg.Binance.StreamURL = "ws" + strings.TrimPrefix(streams.URL, "http")
+	cfg.Binance.DefaultStreamType = config.StreamTypeMiniTicker
+	cfg.Binance.MainSymbols = nil
+	cfg.Binance.MinDailyVolume = 0
+	cfg.Binance.SymbolRefreshInterval = 0
+	cfg.Ingestion.WatchdogSilence = 200 * time.Millisecond
+	cfg.Ingestion.WatchdogMaxRestarts = 1
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	svc := NewService(cfg, binance.NewClient(cfg, store), store)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := svc.Start(ctx); errors.Is(err, ErrWatchdogExhausted) {
+		t.Fatalf("Expected streaming until cancelled, got %v", err)
+	}
+	if got := dials.Load(); got != 1 {
+		t.Errorf("Expected the ticker connection kept without restarts, got %d dials", got)
+	}
+}
+
+func waitForDials(t *testing.T, conns *connectionCounter, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for conns.count() < want && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := conns.count(); got != want {
+		t.Fatalf("Expected %d connection attempts, got %d", want, got)
+	}
+}
@@ -0,0 +1,123 @@
+package messaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// EnvelopeVersion is the schema version of published payloads. Bump it
+// whenever models.TradeData changes shape so consumers can tell formats apart.
+const EnvelopeVersion = 2
+
+// Source identifies how a trade reached the bus
+type Source string
+
+// Trade sources
+const (
+	SourceLive     Source = "live"
+	SourceBackfill Source = "backfill"
+	SourceReplay   Source = "replay"
+)
+
+// Envelope wraps a trade published on the bus with its provenance
+type Envelope struct {
+	Version    int                   `json:"version"`
+	Exchange   string                `json:"exchange"`
+	IngestedAt time.Time             `json:"ingested_at"`
+	Source     Source                `json:"source"`
+	Payload    *models.AggTradeEvent `json:"payload"`
+}
+
+type sourceKey struct{}
+
+// WithSource marks trades published with ctx as coming from source
+func WithSource(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFrom returns the source set on ctx, defaulting to live
+func SourceFrom(ctx context.Context) Source {
+	if source, ok := ctx.Value(sourceKey{}).(Source); ok {
+		return source
+	}
+	return SourceLive
+}
+
+// newEnvelope wraps trade for publishing
+func newEnvelope(ctx context.Context, exchange string, trade *models.AggTradeEvent, now time.Time) *Envelope {
+	return &Envelope{
+		Version:    EnvelopeVersion,
+		Exchange:   exchange,
+		IngestedAt: now.UTC(),
+		Source:     SourceFrom(ctx),
+		Payload:    trade,
+	}
+}
+
+// DecodeEnvelope parses a bus message. Bare AggTradeEvent payloads published
+// before envelopes were introduced are accepted and reported as version 0
+// live trades. The payload's Raw field is set to its original JSON.
+func DecodeEnvelope(data []byte) (*Envelope, error) {
+	var probe struct {
+		Version int             `json:"version"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+
+	if probe.Version == 0 || len(probe.Payload) == 0 {
+		var trade models.AggTradeEvent
+		if err := json.Unmarshal(data, &trade); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal legacy trade: %w", err)
+		}
+		trade.Raw = data
+		return &Envelope{Source: SourceLive, Payload: &trade}, nil
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal envelope: %w", err)
+	}
+	if env.Payload == nil {
+		return nil, fmt.Errorf("envelope has no payload")
+	}
+	env.Payload.Raw = probe.Payload
+	return &env, nil
+}
+
+// DecodeEnvelopes parses a bus message holding one trade, as DecodeEnvelope
+// does, or a JSON array of them published as a batch, in publishing order.
+// Messages of a batch that fail to decode are skipped, so the rest are still
+// returned along with an error naming the skipped ones.
+func DecodeEnvelopes(data []byte) ([]*Envelope, error) {
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) == 0 || trimmed[0] != '[' {
+		env, err := DecodeEnvelope(data)
+		if err != nil {
+			return nil, err
+		}
+		return []*Envelope{env}, nil
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal batch: %w", err)
+	}
+	envs := make([]*Envelope, 0, len(batch))
+	var errs []error
+	for i, message := range batch {
+		env, err := DecodeEnvelope(message)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("batch message %d: %w", i, err))
+			continue
+		}
+		envs = append(envs, env)
+	}
+	return envs, errors.Join(errs...)
+}
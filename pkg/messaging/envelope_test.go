@@ -0,0 +1,222 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"binance-redis-streamer/internal/models"
+)
+
+var testTrade = &models.AggTradeEvent{
+	Stream: "btcusdt@trade",
+	Data: models.TradeData{
+		EventType: models.EventTypeTrade,
+		Symbol:    "BTCUSDT",
+		TradeID:   42,
+		Price:     "50000.00",
+		Quantity:  "0.5",
+		TradeTime: 1735207200000,
+	},
+}
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	now := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	ctx := WithSource(context.Background(), SourceReplay)
+
+	data, err := json.Marshal(newEnvelope(ctx, "binance", testTrade, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"version":2`, `"exchange":"binance"`, `"source":"replay"`, `"ingested_at":"2024-12-26T10:00:00Z"`, `"payload":{`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("Expected %s in %s", field, data)
+		}
+	}
+
+	env, err := DecodeEnvelope(data)
+	if err != nil {
+		t.Fatalf("DecodeEnvelope failed: %v", err)
+	}
+	if env.Version != EnvelopeVersion || env.Exchange != "binance" || env.Source != SourceReplay || !env.IngestedAt.Equal(now) {
+		t.Errorf("Unexpected envelope metadata: %+v", env)
+	}
+	if env.Payload.Data.TradeID != 42 || env.Payload.Data.Price != "50000.00" {
+		t.Errorf("Unexpected payload: %+v", env.Payload.Data)
+	}
+	if !strings.HasPrefix(string(env.Payload.Raw), `{"stream":"btcusdt@trade"`) {
+		t.Errorf("Expected Raw to hold the payload JSON, got %s", env.Payload.Raw)
+	}
+}
+
+func TestDecodeEnvelope_LegacyPayload(t *testing.T) {
+	legacy, err := json.Marshal(testTrade)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := DecodeEnvelope(legacy)
+	if err != nil {
+		t.Fatalf("DecodeEnvelope failed: %v", err)
+	}
+	if env.Version != 0 || env.Source != SourceLive {
+		t.Errorf("Expected legacy payload as a version 0 live envelope, got %+v", env)
+	}
+	if env.Payload.Data.Symbol != "BTCUSDT" || env.Payload.Data.TradeID != 42 {
+		t.Errorf("Unexpected payload: %+v", env.Payload.Data)
+	}
+
+	if _, err := DecodeEnvelope([]byte(`not json`)); err == nil {
+		t.Error("Expected error for invalid message")
+	}
+	if _, err := DecodeEnvelope([]byte(`{"version":1,"payload":null}`)); err == nil {
+		t.Error("Expected error for envelope without payload")
+	}
+}
+
+// TestEnvelopeVersion_TracksTradeData fails when TradeData changes shape;
+// bump EnvelopeVersion and update the expected fields together
+func TestEnvelopeVersion_TracksTradeData(t *testing.T) {
+	expected := map[int][]string{
+		1: {"e", "E", "s", "t", "a", "p", "q", "b", "-", "T", "m", "M"},
+		2: {"e", "E", "s", "t", "a", "p", "q", "b", "-", "T", "m", "M", "num"},
+	}
+
+	var fields []string
+	typ := reflect.TypeOf(models.TradeData{})
+	for i := 0; i < typ.NumField(); i++ {
+		fields = append(fields, strings.Split(typ.Field(i).Tag.Get("json"), ",")[0])
+	}
+
+	if !reflect.DeepEqual(fields, expected[EnvelopeVersion]) {
+		t.Errorf("TradeData fields %v do not match envelope version %d; bump EnvelopeVersion", fields, EnvelopeVersion)
+	}
+}
+
+func TestRedisPubSub_PublishesEnvelopes(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	bus := NewRedisPubSub(client)
+	bus.SetExchange("fake")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan *Envelope, 2)
+	go bus.SubscribeAll(ctx, func(env *Envelope) error {
+		received <- env
+		return nil
+	})
+	time.Sleep(100 * time.Millisecond)
+
+	if err := bus.Publish(WithSource(ctx, SourceBackfill), testTrade); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	// Bare payloads from publishers that predate envelopes are still delivered
+	legacy, _ := json.Marshal(testTrade)
+	if err := client.Publish(ctx, tradeChannel, legacy).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []struct {
+		version int
+		source  Source
+	}{{EnvelopeVersion, SourceBackfill}, {0, SourceLive}} {
+		select {
+		case env := <-received:
+			if env.Version != want.version || env.Source != want.source || env.Payload.Data.TradeID != 42 {
+				t.Errorf("Unexpected envelope: %+v", env)
+			}
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for message")
+		}
+	}
+}
+
+func TestRedisPubSub_PerSymbolChannels(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subscribe := func(symbol string) <-chan *Envelope {
+		received := make(chan *Envelope, 10)
+		go NewRedisPubSub(client).SubscribeSymbol(ctx, symbol, func(env *Envelope) error {
+			received <- env
+			return nil
+		})
+		return received
+	}
+	btc, all := subscribe("btcusdt"), subscribe("")
+	raw := client.Subscribe(ctx, "trades.BTCUSDT")
+	defer raw.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	eth := *testTrade
+	eth.Data.Symbol = "ETHUSDT"
+	eth.Data.TradeID = 43
+
+	bus := NewRedisPubSub(client)
+	bus.SetPerSymbolChannels(true)
+	if err := bus.Publish(ctx, testTrade); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Publish(ctx, &eth); err != nil {
+		t.Fatal(err)
+	}
+	// A publisher on the shared channel still reaches symbol subscribers
+	bus.SetPerSymbolChannels(false)
+	if err := bus.Publish(ctx, &eth); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Publish(ctx, testTrade); err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(ch <-chan *Envelope) int64 {
+		t.Helper()
+		select {
+		case env := <-ch:
+			return env.Payload.Data.TradeID
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for message")
+			return 0
+		}
+	}
+	if got := []int64{next(btc), next(btc)}; got[0] != 42 || got[1] != 42 {
+		t.Errorf("Expected only BTCUSDT trades on the symbol subscription, got %v", got)
+	}
+	if got := []int64{next(all), next(all), next(all), next(all)}; got[0] != 42 || got[1] != 43 || got[2] != 43 || got[3] != 42 {
+		t.Errorf("Expected every trade on the subscription to all symbols, got %v", got)
+	}
+
+	msg, err := raw.ReceiveMessage(ctx)
+	if err != nil || msg.Channel != "trades.BTCUSDT" {
+		t.Errorf("Expected the trade on trades.BTCUSDT, got %+v (%v)", msg, err)
+	}
+	select {
+	case env := <-btc:
+		t.Errorf("Unexpected trade for the symbol subscription: %+v", env.Payload.Data)
+	default:
+	}
+}
@@ -8,10 +8,15 @@ import (
 
 // MessageBus defines the interface for message passing
 type MessageBus interface {
-	// Publish publishes a trade event
+	// Publish publishes a trade event wrapped in an Envelope; the source is
+	// taken from ctx (see WithSource)
 	Publish(ctx context.Context, trade *models.AggTradeEvent) error
-	// Subscribe subscribes to trade events
-	Subscribe(ctx context.Context, handler func(trade *models.AggTradeEvent) error) error
+	// SubscribeAll subscribes to the trade events of every symbol, unwrapping
+	// legacy bare payloads into version 0 envelopes
+	SubscribeAll(ctx context.Context, handler func(env *Envelope) error) error
+	// SubscribeSymbol is SubscribeAll for the trades of one symbol; an empty
+	// symbol subscribes to all of them
+	SubscribeSymbol(ctx context.Context, symbol string, handler func(env *Envelope) error) error
 	// Close closes the message bus connection
 	Close() error
 }
@@ -1,49 +1,205 @@
 package messaging
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"binance-redis-streamer/internal/models"
 )
 
+// envelopesSkipped counts the received trades dropped because they failed
+// to decode
+var envelopesSkipped = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "binance_bus_envelopes_skipped_total",
+	Help: "Received bus messages skipped because they failed to decode.",
+})
+
+// tradeChannel carries the trades of every symbol unless per-symbol channels
+// are enabled, which publish to tradeChannel + "." + SYMBOL instead
 const tradeChannel = "trades"
 
+// AnomalyChannel carries the JSON anomaly events flagged by the processor,
+// apart from the trades so trade subscribers never see them
+const AnomalyChannel = "anomalies"
+
+// RedisPubSubConfig sets how RedisPubSub batches the trades it publishes
+type RedisPubSubConfig struct {
+	// Trades buffered per channel before they are published together as one
+	// JSON array; 1 publishes each trade on its own
+	BatchSize int
+	// Longest a buffered trade waits for its batch to fill
+	BatchDelay time.Duration
+}
+
 // RedisPubSub implements MessageBus using Redis Pub/Sub
 type RedisPubSub struct {
-	client *redis.Client
+	client    redis.UniversalClient
+	exchange  string // Exchange recorded in published envelopes
+	perSymbol bool   // Publish to trades.<SYMBOL> rather than trades
+	now       func() time.Time
+
+	batching RedisPubSubConfig
+	batchMu  sync.Mutex
+	batches  map[string]*pendingBatch // By channel
+}
+
+// pendingBatch holds the trades waiting to be published on one channel.
+// Its lock is held while the batch is published, so batches of a channel
+// go out in order.
+type pendingBatch struct {
+	mu       sync.Mutex
+	messages [][]byte
+	timer    *time.Timer // Flushes the batch after BatchDelay; nil when empty
 }
 
 // NewRedisPubSub creates a new Redis Pub/Sub message bus
-func NewRedisPubSub(client *redis.Client) *RedisPubSub {
+func NewRedisPubSub(client redis.UniversalClient) *RedisPubSub {
 	return &RedisPubSub{
-		client: client,
+		client:   client,
+		exchange: "binance",
+		now:      time.Now,
+		batching: RedisPubSubConfig{BatchSize: 1},
+		batches:  make(map[string]*pendingBatch),
 	}
 }
 
-// Publish publishes a trade event to Redis
+// SetExchange sets the exchange recorded in published envelopes
+func (r *RedisPubSub) SetExchange(name string) {
+	r.exchange = name
+}
+
+// SetPerSymbolChannels publishes each trade to the trades.<SYMBOL> channel
+// of its symbol, e.g. trades.BTCUSDT, instead of the shared trades channel.
+// Subscribers receive both, so publishers can switch without them.
+func (r *RedisPubSub) SetPerSymbolChannels(enabled bool) {
+	r.perSymbol = enabled
+}
+
+// SetBatching buffers up to cfg.BatchSize trades per channel, for at most
+// cfg.BatchDelay, and publishes them as one message holding a JSON array of
+// envelopes, in publishing order. Subscribers decode both forms. Call it
+// before publishing; Close publishes what is still buffered.
+func (r *RedisPubSub) SetBatching(cfg RedisPubSubConfig) {
+	r.batching = cfg
+}
+
+// symbolChannel returns the channel of symbol's trades
+func symbolChannel(symbol string) string {
+	return tradeChannel + "." + strings.ToUpper(symbol)
+}
+
+// Publish publishes a trade event to Redis. With batching, the trade is
+// buffered and only errors publishing a batch filled by it are returned;
+// those of batches flushed after BatchDelay are logged.
 func (r *RedisPubSub) Publish(ctx context.Context, trade *models.AggTradeEvent) error {
-	data, err := json.Marshal(trade)
+	data, err := json.Marshal(newEnvelope(ctx, r.exchange, trade, r.now()))
 	if err != nil {
 		return fmt.Errorf("failed to marshal trade: %w", err)
 	}
 
-	if err := r.client.Publish(ctx, tradeChannel, data).Err(); err != nil {
-		return fmt.Errorf("failed to publish trade: %w", err)
+	channel := tradeChannel
+	if r.perSymbol {
+		channel = symbolChannel(trade.Data.Symbol)
+	}
+	if r.batching.BatchSize <= 1 {
+		if err := r.client.Publish(ctx, channel, data).Err(); err != nil {
+			return fmt.Errorf("failed to publish trade: %w", err)
+		}
+		return nil
 	}
 
+	b := r.pendingBatch(channel)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = append(b.messages, data)
+	if len(b.messages) >= r.batching.BatchSize {
+		return r.flush(ctx, channel, b)
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(r.batching.BatchDelay, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if err := r.flush(context.Background(), channel, b); err != nil {
+				log.Printf("Error flushing trade batch on %s: %v", channel, err)
+			}
+		})
+	}
 	return nil
 }
 
-// Subscribe subscribes to trade events
-func (r *RedisPubSub) Subscribe(ctx context.Context, handler func(trade *models.AggTradeEvent) error) error {
-	pubsub := r.client.Subscribe(ctx, tradeChannel)
+// pendingBatch returns the batch of channel
+func (r *RedisPubSub) pendingBatch(channel string) *pendingBatch {
+	r.batchMu.Lock()
+	defer r.batchMu.Unlock()
+	b, ok := r.batches[channel]
+	if !ok {
+		b = &pendingBatch{}
+		r.batches[channel] = b
+	}
+	return b
+}
+
+// flush publishes the trades buffered in b, a lone trade as a plain
+// envelope; callers must hold b.mu
+func (r *RedisPubSub) flush(ctx context.Context, channel string, b *pendingBatch) error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.messages) == 0 {
+		return nil
+	}
+
+	payload := b.messages[0]
+	if len(b.messages) > 1 {
+		payload = make([]byte, 0, len(b.messages)*(len(payload)+1)+1)
+		payload = append(payload, '[')
+		payload = append(payload, bytes.Join(b.messages, []byte{','})...)
+		payload = append(payload, ']')
+	}
+	n := len(b.messages)
+	b.messages = b.messages[:0]
+
+	if err := r.client.Publish(ctx, channel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish %d trades: %w", n, err)
+	}
+	return nil
+}
+
+// SubscribeAll subscribes to the trades of every symbol, from the shared
+// channel and every per-symbol channel
+func (r *RedisPubSub) SubscribeAll(ctx context.Context, handler func(env *Envelope) error) error {
+	pubsub := r.client.PSubscribe(ctx, tradeChannel, tradeChannel+".*")
+	defer pubsub.Close()
+	return receive(ctx, pubsub, "", handler)
+}
+
+// SubscribeSymbol subscribes to the trades of symbol, from its own channel
+// and, filtered by symbol, the shared one
+func (r *RedisPubSub) SubscribeSymbol(ctx context.Context, symbol string, handler func(env *Envelope) error) error {
+	if symbol == "" {
+		return r.SubscribeAll(ctx, handler)
+	}
+	pubsub := r.client.Subscribe(ctx, tradeChannel, symbolChannel(symbol))
 	defer pubsub.Close()
+	return receive(ctx, pubsub, strings.ToUpper(symbol), handler)
+}
 
+// receive passes the trades arriving on pubsub to handler until ctx is
+// cancelled. When symbol is set, trades of other symbols on the shared
+// channel are dropped.
+func receive(ctx context.Context, pubsub *redis.PubSub, symbol string, handler func(env *Envelope) error) error {
 	ch := pubsub.Channel()
 	for {
 		select {
@@ -54,20 +210,42 @@ func (r *RedisPubSub) Subscribe(ctx context.Context, handler func(trade *models.
 				continue
 			}
 
-			var trade models.AggTradeEvent
-			if err := json.Unmarshal([]byte(msg.Payload), &trade); err != nil {
-				log.Printf("Failed to unmarshal trade: %v", err)
-				continue
+			envs, err := DecodeEnvelopes([]byte(msg.Payload))
+			if err != nil {
+				log.Printf("Skipping undecodable trades: %v", err)
+				envelopesSkipped.Add(float64(countErrors(err)))
 			}
-
-			if err := handler(&trade); err != nil {
-				log.Printf("Failed to handle trade: %v", err)
+			for _, env := range envs {
+				if symbol != "" && !strings.EqualFold(env.Payload.Data.Symbol, symbol) {
+					continue
+				}
+				if err := handler(env); err != nil {
+					log.Printf("Failed to handle trade: %v", err)
+				}
 			}
 		}
 	}
 }
 
-// Close closes the Redis Pub/Sub connection
+// countErrors returns how many errors err joins, or 1
+func countErrors(err error) int {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return len(joined.Unwrap())
+	}
+	return 1
+}
+
+// Close publishes the trades still buffered for batching. The Redis client
+// is left open for its owner to close.
 func (r *RedisPubSub) Close() error {
-	return nil
+	r.batchMu.Lock()
+	defer r.batchMu.Unlock()
+
+	var errs []error
+	for channel, b := range r.batches {
+		b.mu.Lock()
+		errs = append(errs, r.flush(context.Background(), channel, b))
+		b.mu.Unlock()
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,197 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// newTestBus returns a bus batching as set on a fresh miniredis
+func newTestBus(t testing.TB, batching RedisPubSubConfig) (*RedisPubSub, *redis.Client) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	bus := NewRedisPubSub(client)
+	bus.SetBatching(batching)
+	return bus, client
+}
+
+// subscribeRaw subscribes to the messages of the shared trades channel as
+// published
+func subscribeRaw(t *testing.T, client *redis.Client) *redis.PubSub {
+	t.Helper()
+	raw := client.Subscribe(context.Background(), tradeChannel)
+	t.Cleanup(func() { raw.Close() })
+	if _, err := raw.Receive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// tradeWithID returns testTrade with its trade ID set to id
+func tradeWithID(id int64) *models.AggTradeEvent {
+	trade := *testTrade
+	trade.Data.TradeID = id
+	return &trade
+}
+
+// nextTradeIDs decodes the next message on raw and returns its trade IDs
+func nextTradeIDs(t *testing.T, raw *redis.PubSub) (ids []int64, batched bool) {
+	t.Helper()
+	select {
+	case msg := <-raw.Channel():
+		envs, err := DecodeEnvelopes([]byte(msg.Payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, env := range envs {
+			ids = append(ids, env.Payload.Data.TradeID)
+		}
+		return ids, strings.HasPrefix(msg.Payload, "[")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for message")
+		return nil, false
+	}
+}
+
+func TestRedisPubSub_BatchesTrades(t *testing.T) {
+	bus, client := newTestBus(t, RedisPubSubConfig{BatchSize: 3, BatchDelay: time.Hour})
+	raw := subscribeRaw(t, client)
+	ctx := context.Background()
+
+	for id := int64(1); id <= 4; id++ {
+		if err := bus.Publish(ctx, tradeWithID(id)); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	if ids, batched := nextTradeIDs(t, raw); !batched || fmt.Sprint(ids) != "[1 2 3]" {
+		t.Errorf("Expected trades 1 to 3 in one array, got %v (batched %v)", ids, batched)
+	}
+
+	// Close publishes the rest, a lone trade as a plain envelope
+	if err := bus.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if ids, batched := nextTradeIDs(t, raw); batched || fmt.Sprint(ids) != "[4]" {
+		t.Errorf("Expected trade 4 on its own, got %v (batched %v)", ids, batched)
+	}
+}
+
+func TestRedisPubSub_FlushesBatchAfterDelay(t *testing.T) {
+	bus, client := newTestBus(t, RedisPubSubConfig{BatchSize: 100, BatchDelay: 20 * time.Millisecond})
+	raw := subscribeRaw(t, client)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan int64, 10)
+	go bus.SubscribeAll(ctx, func(env *Envelope) error {
+		received <- env.Payload.Data.TradeID
+		return nil
+	})
+	time.Sleep(100 * time.Millisecond)
+
+	for id := int64(1); id <= 3; id++ {
+		if err := bus.Publish(ctx, tradeWithID(id)); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	if ids, batched := nextTradeIDs(t, raw); !batched || fmt.Sprint(ids) != "[1 2 3]" {
+		t.Errorf("Expected the partial batch published after the delay, got %v (batched %v)", ids, batched)
+	}
+
+	// Subscribers unpack the batch in order
+	for want := int64(1); want <= 3; want++ {
+		select {
+		case id := <-received:
+			if id != want {
+				t.Errorf("Received trade %d, want %d", id, want)
+			}
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for trade")
+		}
+	}
+}
+
+func TestDecodeEnvelopes_RejectsBadBatch(t *testing.T) {
+	for _, data := range []string{`[`, `[{"version":2,"payload":null}]`, `[1]`} {
+		if _, err := DecodeEnvelopes([]byte(data)); err == nil {
+			t.Errorf("Expected an error decoding %s", data)
+		}
+	}
+}
+
+func TestDecodeEnvelopes_SkipsBadMessages(t *testing.T) {
+	good, err := json.Marshal(newEnvelope(context.Background(), "binance", &models.AggTradeEvent{Data: models.TradeData{Symbol: "BTCUSDT", TradeID: 1}}, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Sprintf(`[%s,1,{"version":2,"payload":null},%s]`, good, good)
+
+	envs, err := DecodeEnvelopes([]byte(data))
+	if err == nil {
+		t.Error("Expected an error naming the skipped messages")
+	}
+	if got := countErrors(err); got != 2 {
+		t.Errorf("Expected 2 skipped messages, got %d", got)
+	}
+	if len(envs) != 2 {
+		t.Fatalf("Expected the 2 good trades decoded, got %d", len(envs))
+	}
+}
+
+// BenchmarkRedisPubSub_Publish compares publishing trades one by one with
+// batches of 10 and 100, reporting throughput and the mean delay from
+// Publish to delivery, which batching trades for
+func BenchmarkRedisPubSub_Publish(b *testing.B) {
+	for _, size := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("BatchSize%d", size), func(b *testing.B) {
+			bus, _ := newTestBus(b, RedisPubSubConfig{BatchSize: size, BatchDelay: 5 * time.Millisecond})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var received, latency atomic.Int64
+			done := make(chan struct{})
+			go bus.SubscribeAll(ctx, func(env *Envelope) error {
+				latency.Add(int64(time.Since(env.IngestedAt)))
+				if received.Add(1) == int64(b.N) {
+					close(done)
+				}
+				return nil
+			})
+			time.Sleep(100 * time.Millisecond)
+
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if err := bus.Publish(ctx, tradeWithID(int64(i))); err != nil {
+					b.Fatal(err)
+				}
+			}
+			select {
+			case <-done:
+			case <-time.After(time.Minute):
+				b.Fatalf("Received %d of %d trades", received.Load(), b.N)
+			}
+			elapsed := time.Since(start)
+			b.StopTimer()
+
+			b.ReportMetric(float64(b.N)/elapsed.Seconds(), "trades/s")
+			b.ReportMetric(float64(latency.Load())/float64(b.N)/float64(time.Millisecond), "ms-latency")
+		})
+	}
+}
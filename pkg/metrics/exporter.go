@@ -2,7 +2,6 @@ package metrics
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"time"
@@ -11,22 +10,24 @@ import (
 
 	"binance-redis-streamer/internal/models"
 	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/processor"
 )
 
 // Metrics represents collected metrics
 type Metrics struct {
-	Prices map[string]string // Symbol -> Price mapping
+	Prices   map[string]string // Symbol -> Price mapping
+	DLQDepth int64             // Trades waiting in the dead letter queue
 }
 
 // MetricsExporter handles metrics collection and export
 type MetricsExporter struct {
 	config *config.Config
-	client *redis.Client
+	client redis.UniversalClient
 	stopCh chan struct{}
 }
 
 // NewMetricsExporter creates a new metrics exporter
-func NewMetricsExporter(cfg *config.Config, client *redis.Client) *MetricsExporter {
+func NewMetricsExporter(cfg *config.Config, client redis.UniversalClient) *MetricsExporter {
 	return &MetricsExporter{
 		config: cfg,
 		client: client,
@@ -86,8 +87,8 @@ func (e *MetricsExporter) CollectMetrics(ctx context.Context) (*Metrics, error)
 			continue
 		}
 
-		var trade models.Trade
-		if err := json.Unmarshal([]byte(data), &trade); err != nil {
+		trade, err := models.DecodeTrade([]byte(data))
+		if err != nil {
 			log.Printf("Error unmarshaling trade data for %s: %v", symbol, err)
 			continue
 		}
@@ -95,6 +96,12 @@ func (e *MetricsExporter) CollectMetrics(ctx context.Context) (*Metrics, error)
 		metrics.Prices[symbol] = trade.Price
 	}
 
+	depth, err := e.client.LLen(ctx, processor.DLQKey(e.config.Redis.KeyPrefix)).Result()
+	if err != nil {
+		log.Printf("Error getting dead letter queue depth: %v", err)
+	}
+	metrics.DLQDepth = depth
+
 	return metrics, nil
 }
 
@@ -103,4 +110,14 @@ func (e *MetricsExporter) exportMetrics(metrics *Metrics) {
 	for symbol, price := range metrics.Prices {
 		log.Printf("Price for %s: %s", symbol, price)
 	}
+
+	threshold := e.config.Processor.DLQAlertThreshold
+	dlqDepthGauge.Set(float64(metrics.DLQDepth))
+	dlqAlertThresholdGauge.Set(float64(threshold))
+	if threshold > 0 && metrics.DLQDepth > threshold {
+		dlqAlertGauge.Set(1)
+		log.Printf("Warning: dead letter queue depth %d exceeds threshold %d", metrics.DLQDepth, threshold)
+	} else {
+		dlqAlertGauge.Set(0)
+	}
 }
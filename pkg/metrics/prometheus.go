@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus gauges, served on the debug server at /metrics
+var (
+	dlqDepthGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "binance_dlq_depth",
+		Help: "Number of trades waiting in the dead letter queue.",
+	})
+	dlqAlertThresholdGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "binance_dlq_alert_threshold",
+		Help: "Dead letter queue depth above which an alert should fire.",
+	})
+	dlqAlertGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "binance_dlq_alert",
+		Help: "1 while the dead letter queue depth exceeds its alert threshold.",
+	})
+)
+
+// Publish queue metrics, updated by the ingestion service
+var (
+	PublishQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "binance_publish_queue_depth",
+		Help: "Trades waiting in the ingestion publish queue.",
+	})
+	PublishQueueDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "binance_publish_queue_dropped_total",
+		Help: "Trades dropped because the publish queue was full (shed and drop_oldest policies) or could not be published on shutdown.",
+	})
+)
+
+// IngestionMessages counts the messages received by each symbol group's
+// connection; rate() over it gives messages per second
+var IngestionMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "binance_ingestion_messages_per_second",
+	Help: "Messages received per symbol group connection; take rate() for messages per second.",
+}, []string{"group"})
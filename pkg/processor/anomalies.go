@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/messaging"
+)
+
+// AnomalyKey returns the Redis list holding recent anomaly events, newest first
+func AnomalyKey(prefix string) string {
+	return prefix + "anomalies"
+}
+
+// detectAnomalies runs a trade through the anomaly detector, if enabled,
+// and records every event it flags
+func (s *Service) detectAnomalies(ctx context.Context, trade *models.Trade) {
+	if s.detector == nil {
+		return
+	}
+	for _, event := range s.detector.Observe(trade) {
+		if s.config.Debug {
+			log.Printf("Detected %s for %s at %v", event.Kind, event.Symbol, event.Price)
+		}
+		if err := s.recordAnomaly(ctx, event); err != nil {
+			log.Printf("Warning: dropping %s for %s: %v", event.Kind, event.Symbol, err)
+		}
+	}
+}
+
+// recordAnomaly pushes an event onto the review list, trimming the oldest
+// beyond the configured maximum, and publishes it on the anomaly channel
+func (s *Service) recordAnomaly(ctx context.Context, event *models.AnomalyEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal anomaly: %w", err)
+	}
+
+	key := AnomalyKey(s.config.Redis.KeyPrefix)
+	pipe := s.redisStore.GetRedisClient().TxPipeline()
+	pipe.LPush(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, s.config.Processor.Anomalies.MaxEvents-1)
+	pipe.Publish(ctx, messaging.AnomalyChannel, data)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record anomaly: %w", err)
+	}
+	return nil
+}
+
+// ListAnomalies returns up to limit of the most recent anomaly events,
+// newest first, optionally only those of symbol; a limit of 0 returns every
+// stored event
+func (s *Service) ListAnomalies(ctx context.Context, symbol string, limit int) ([]*models.AnomalyEvent, error) {
+	items, err := s.redisStore.GetRedisClient().LRange(ctx, AnomalyKey(s.config.Redis.KeyPrefix), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read anomalies: %w", err)
+	}
+
+	var events []*models.AnomalyEvent
+	for _, item := range items {
+		var event models.AnomalyEvent
+		if err := json.Unmarshal([]byte(item), &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal anomaly: %w", err)
+		}
+		if symbol != "" && !strings.EqualFold(event.Symbol, symbol) {
+			continue
+		}
+		events = append(events, &event)
+		if limit > 0 && len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
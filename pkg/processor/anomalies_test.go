@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/analysis"
+	"binance-redis-streamer/pkg/messaging"
+)
+
+func TestService_RecordAndListAnomalies(t *testing.T) {
+	svc := setupDLQService(t)
+	svc.config.Processor.Anomalies.MaxEvents = 3
+	ctx := context.Background()
+
+	sub := svc.redisStore.GetRedisClient().Subscribe(ctx, messaging.AnomalyChannel)
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	symbols := []string{"BTCUSDT", "ETHUSDT", "BTCUSDT", "ETHUSDT", "BTCUSDT"}
+	for i, symbol := range symbols {
+		event := &models.AnomalyEvent{Kind: models.AnomalyPriceJump, Symbol: symbol, TradeID: int64(i + 1)}
+		if err := svc.recordAnomaly(ctx, event); err != nil {
+			t.Fatalf("recordAnomaly() error = %v", err)
+		}
+	}
+
+	msg, err := sub.ReceiveMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Payload == "" {
+		t.Error("Expected the event to be published")
+	}
+
+	// Only the 3 newest are kept, newest first
+	events, err := svc.ListAnomalies(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("ListAnomalies() error = %v", err)
+	}
+	if len(events) != 3 || events[0].TradeID != 5 || events[2].TradeID != 3 {
+		t.Fatalf("Expected trades 5, 4, 3, got %+v", events)
+	}
+
+	events, err = svc.ListAnomalies(ctx, "btcusdt", 1)
+	if err != nil {
+		t.Fatalf("ListAnomalies() error = %v", err)
+	}
+	if len(events) != 1 || events[0].TradeID != 5 {
+		t.Errorf("Expected trade 5 of BTCUSDT, got %+v", events)
+	}
+}
+
+func TestService_HandleTradeDetectsAnomalies(t *testing.T) {
+	svc := setupDLQService(t)
+	svc.process = func(ctx context.Context, trade *models.AggTradeEvent) error { return nil }
+
+	cfg := svc.config.Processor.Anomalies
+	cfg.PriceJumpWindow = time.Hour
+	svc.detector = analysis.NewAnomalyDetector(cfg)
+
+	first := testTrade(1)
+	jump := testTrade(2)
+	jump.Data.Price = "51000.00"
+	for _, trade := range []*models.AggTradeEvent{first, jump} {
+		if err := svc.handleTrade(context.Background(), trade); err != nil {
+			t.Fatalf("handleTrade() error = %v", err)
+		}
+	}
+
+	events, err := svc.ListAnomalies(context.Background(), "BTCUSDT", 0)
+	if err != nil {
+		t.Fatalf("ListAnomalies() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != models.AnomalyPriceJump || events[0].TradeID != 2 {
+		t.Errorf("Expected a price jump at trade 2, got %+v", events)
+	}
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+const (
+	// aggregateBatchSize is the most trades handed to the aggregator at once
+	aggregateBatchSize = 50
+	// aggregateBatchWait is how long a trade may wait for its batch to fill
+	aggregateBatchWait = 100 * time.Millisecond
+)
+
+// tradeBatcher buffers trades and hands them to flush once maxSize trades
+// are pending or the oldest has waited maxWait, whichever comes first
+type tradeBatcher struct {
+	mu      sync.Mutex
+	pending []*models.Trade
+	timer   *time.Timer
+	maxSize int
+	maxWait time.Duration
+	flush   func(ctx context.Context, trades []*models.Trade)
+}
+
+// newTradeBatcher creates a batcher calling flush with each batch
+func newTradeBatcher(maxSize int, maxWait time.Duration, flush func(ctx context.Context, trades []*models.Trade)) *tradeBatcher {
+	return &tradeBatcher{maxSize: maxSize, maxWait: maxWait, flush: flush}
+}
+
+// add queues a trade, flushing the batch when it is full
+func (b *tradeBatcher) add(trade *models.Trade) {
+	b.mu.Lock()
+	b.pending = append(b.pending, trade)
+	if len(b.pending) < b.maxSize {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.maxWait, b.flushPending)
+		}
+		b.mu.Unlock()
+		return
+	}
+	batch := b.take()
+	b.mu.Unlock()
+
+	b.flush(context.Background(), batch)
+}
+
+// flushPending hands over whatever is pending
+func (b *tradeBatcher) flushPending() {
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+
+	if len(batch) > 0 {
+		b.flush(context.Background(), batch)
+	}
+}
+
+// take removes and returns the pending trades; callers must hold mu
+func (b *tradeBatcher) take() []*models.Trade {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch := b.pending
+	b.pending = nil
+	return batch
+}
@@ -0,0 +1,79 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// batchRecorder collects the batches a batcher flushes
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]*models.Trade
+	flushed chan struct{}
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{flushed: make(chan struct{}, 10)}
+}
+
+func (r *batchRecorder) flush(ctx context.Context, trades []*models.Trade) {
+	r.mu.Lock()
+	r.batches = append(r.batches, trades)
+	r.mu.Unlock()
+	r.flushed <- struct{}{}
+}
+
+func (r *batchRecorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sizes := make([]int, len(r.batches))
+	for i, batch := range r.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+func TestTradeBatcher_FlushesFullBatches(t *testing.T) {
+	rec := newBatchRecorder()
+	b := newTradeBatcher(3, time.Hour, rec.flush)
+
+	for id := int64(1); id <= 7; id++ {
+		b.add(&models.Trade{Symbol: "BTCUSDT", TradeID: id})
+	}
+	if sizes := rec.sizes(); len(sizes) != 2 || sizes[0] != 3 || sizes[1] != 3 {
+		t.Fatalf("Expected two full batches of 3, got %v", sizes)
+	}
+
+	// The remainder is handed over on demand, e.g. at shutdown
+	b.flushPending()
+	sizes := rec.sizes()
+	if len(sizes) != 3 || sizes[2] != 1 || rec.batches[2][0].TradeID != 7 {
+		t.Errorf("Expected the last trade in a final batch, got %v", sizes)
+	}
+
+	b.flushPending()
+	if got := len(rec.sizes()); got != 3 {
+		t.Errorf("Expected no empty batch, got %d batches", got)
+	}
+}
+
+func TestTradeBatcher_FlushesAfterWait(t *testing.T) {
+	rec := newBatchRecorder()
+	b := newTradeBatcher(50, 20*time.Millisecond, rec.flush)
+
+	b.add(&models.Trade{Symbol: "BTCUSDT", TradeID: 1})
+	b.add(&models.Trade{Symbol: "ETHUSDT", TradeID: 2})
+
+	select {
+	case <-rec.flushed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a partial batch to be flushed after the wait")
+	}
+	if sizes := rec.sizes(); len(sizes) != 1 || sizes[0] != 2 {
+		t.Errorf("Expected one batch of 2, got %v", sizes)
+	}
+}
@@ -0,0 +1,178 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// Storing steps of a trade, which dead-lettered trades retry individually
+const (
+	stepStore    = "store"     // Latest trade, history and running volume
+	stepStoreRaw = "store_raw" // Original stream message
+)
+
+// storeSteps are the steps every trade goes through before aggregation
+var storeSteps = []string{stepStore, stepStoreRaw}
+
+// stepError is the failure of one processing step
+type stepError struct {
+	step string
+	err  error
+}
+
+func (e *stepError) Error() string { return e.err.Error() }
+func (e *stepError) Unwrap() error { return e.err }
+
+// failedSteps returns the steps err reports as failed, or nil when it names
+// none
+func failedSteps(err error) []string {
+	var steps []string
+	var walk func(err error)
+	walk = func(err error) {
+		var stepErr *stepError
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, err := range e.Unwrap() {
+				walk(err)
+			}
+		default:
+			if errors.As(err, &stepErr) {
+				steps = append(steps, stepErr.step)
+			}
+		}
+	}
+	walk(err)
+	return steps
+}
+
+// DLQEntry is a trade that failed processing, as stored in the dead letter queue
+type DLQEntry struct {
+	Event *models.AggTradeEvent `json:"event"`
+	Raw   []byte                `json:"raw,omitempty"` // Original stream message
+	// Steps that failed and are retried. The trade was already aggregated,
+	// so retries only store it; entries without steps retry every storing
+	// step.
+	Steps    []string  `json:"steps,omitempty"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+	Attempts int       `json:"attempts"`
+}
+
+// DLQKey returns the Redis list holding dead-lettered trades
+func DLQKey(prefix string) string {
+	return prefix + "dlq:trades"
+}
+
+// deadLetter appends a failed trade to the dead letter queue, trimming the
+// oldest entries beyond the configured maximum length
+func (s *Service) deadLetter(ctx context.Context, entry DLQEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	key := DLQKey(s.config.Redis.KeyPrefix)
+	pipe := s.redisStore.GetRedisClient().TxPipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.LTrim(ctx, key, -s.config.Processor.DLQMaxLen, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to push dead letter: %w", err)
+	}
+	return nil
+}
+
+// ListDLQ returns up to limit of the oldest dead-lettered trades without
+// removing them; a limit of 0 returns the whole queue
+func (s *Service) ListDLQ(ctx context.Context, limit int) ([]DLQEntry, error) {
+	stop := int64(limit) - 1
+	if limit <= 0 {
+		stop = -1
+	}
+
+	items, err := s.redisStore.GetRedisClient().LRange(ctx, DLQKey(s.config.Redis.KeyPrefix), 0, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter queue: %w", err)
+	}
+
+	entries := make([]DLQEntry, 0, len(items))
+	for _, item := range items {
+		var entry DLQEntry
+		if err := json.Unmarshal([]byte(item), &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// DLQDepth returns the number of trades in the dead letter queue
+func (s *Service) DLQDepth(ctx context.Context) (int64, error) {
+	depth, err := s.redisStore.GetRedisClient().LLen(ctx, DLQKey(s.config.Redis.KeyPrefix)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read dead letter queue depth: %w", err)
+	}
+	return depth, nil
+}
+
+// DrainDLQ retries the failed steps of up to limit of the oldest
+// dead-lettered trades and returns how many succeeded. Trades that fail
+// again are pushed back to the end of the queue with the steps still
+// failing and are not retried again in the same drain.
+func (s *Service) DrainDLQ(ctx context.Context, limit int) (int, error) {
+	client := s.redisStore.GetRedisClient()
+	key := DLQKey(s.config.Redis.KeyPrefix)
+
+	depth, err := s.DLQDepth(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if int64(limit) > depth {
+		limit = int(depth)
+	}
+
+	succeeded := 0
+	for i := 0; i < limit; i++ {
+		item, err := client.LPop(ctx, key).Result()
+		if err == redis.Nil {
+			break
+		}
+		if err != nil {
+			return succeeded, fmt.Errorf("failed to pop dead letter: %w", err)
+		}
+
+		var entry DLQEntry
+		if err := json.Unmarshal([]byte(item), &entry); err != nil || entry.Event == nil {
+			// Unreadable entries can never succeed; drop them rather than loop
+			continue
+		}
+		entry.Event.Raw = entry.Raw
+
+		steps := entry.Steps
+		if len(steps) == 0 {
+			steps = storeSteps
+		}
+		if err := s.replay(ctx, entry.Event, steps); err != nil {
+			if failed := failedSteps(err); len(failed) > 0 {
+				steps = failed
+			}
+			entry.Steps = steps
+			entry.Error = err.Error()
+			entry.FailedAt = s.now()
+			entry.Attempts++
+			if err := s.deadLetter(ctx, entry); err != nil {
+				return succeeded, err
+			}
+			continue
+		}
+		succeeded++
+	}
+
+	return succeeded, nil
+}
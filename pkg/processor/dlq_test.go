@@ -0,0 +1,208 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/storage"
+)
+
+func setupDLQService(t *testing.T) *Service {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mr.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.Redis.URL = "redis://" + mr.Addr()
+	cfg.Processor.DLQMaxLen = 3
+
+	store, err := storage.NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	svc := NewService(cfg, store, nil)
+	svc.now = func() time.Time { return time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC) }
+	return svc
+}
+
+func testTrade(id int64) *models.AggTradeEvent {
+	return &models.AggTradeEvent{
+		Stream: "btcusdt@trade",
+		Data: models.TradeData{
+			EventType: models.EventTypeTrade,
+			Symbol:    "BTCUSDT",
+			TradeID:   id,
+			Price:     "50000.00",
+			Quantity:  "0.1",
+			TradeTime: 1735207200000,
+		},
+		Raw: []byte(fmt.Sprintf(`{"t":%d}`, id)),
+	}
+}
+
+func TestService_FailedTradesAreDeadLettered(t *testing.T) {
+	svc := setupDLQService(t)
+	svc.process = func(ctx context.Context, trade *models.AggTradeEvent) error {
+		return fmt.Errorf("redis unavailable")
+	}
+
+	for id := int64(1); id <= 5; id++ {
+		if err := svc.handleTrade(context.Background(), testTrade(id)); err != nil {
+			t.Fatalf("handleTrade failed: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	depth, err := svc.DLQDepth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth != 3 {
+		t.Fatalf("Expected the queue capped at 3 entries, got %d", depth)
+	}
+
+	entries, err := svc.ListDLQ(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Event.Data.TradeID != 3 {
+		t.Errorf("Expected oldest entries to be trimmed, first entry is trade %d", entries[0].Event.Data.TradeID)
+	}
+	if entries[0].Error != "redis unavailable" || entries[0].Attempts != 1 || string(entries[0].Raw) != `{"t":3}` {
+		t.Errorf("Unexpected dead letter: %+v", entries[0])
+	}
+}
+
+func TestService_DrainDLQ(t *testing.T) {
+	svc := setupDLQService(t)
+	ctx := context.Background()
+
+	svc.process = func(ctx context.Context, trade *models.AggTradeEvent) error {
+		return fmt.Errorf("postgres down")
+	}
+	for id := int64(1); id <= 3; id++ {
+		svc.handleTrade(context.Background(), testTrade(id))
+	}
+
+	// Trade 2 keeps failing; the others succeed on retry
+	var retried []int64
+	svc.replay = func(ctx context.Context, trade *models.AggTradeEvent, steps []string) error {
+		retried = append(retried, trade.Data.TradeID)
+		if string(trade.Raw) != fmt.Sprintf(`{"t":%d}`, trade.Data.TradeID) {
+			t.Errorf("Raw message not restored for trade %d", trade.Data.TradeID)
+		}
+		if trade.Data.TradeID == 2 {
+			return fmt.Errorf("still failing")
+		}
+		return nil
+	}
+
+	succeeded, err := svc.DrainDLQ(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if succeeded != 1 || len(retried) != 2 {
+		t.Fatalf("Expected 1 of 2 retries to succeed, got %d of %v", succeeded, retried)
+	}
+
+	entries, err := svc.ListDLQ(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Event.Data.TradeID != 3 || entries[1].Event.Data.TradeID != 2 {
+		t.Fatalf("Expected [3 2] to remain queued, got %+v", entries)
+	}
+	if entries[1].Error != "still failing" || entries[1].Attempts != 2 {
+		t.Errorf("Expected requeued entry to carry the new error, got %+v", entries[1])
+	}
+
+	// Draining more than the queue holds retries each entry once
+	retried = nil
+	succeeded, err = svc.DrainDLQ(ctx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if succeeded != 1 || len(retried) != 2 {
+		t.Errorf("Expected 2 retries with 1 success on second drain, got %d of %v", succeeded, retried)
+	}
+}
+
+func TestService_InvalidTradeTimesAreNotDeadLettered(t *testing.T) {
+	svc := setupDLQService(t)
+	svc.process = func(ctx context.Context, trade *models.AggTradeEvent) error {
+		return fmt.Errorf("failed to store raw trade: %w", storage.ErrInvalidTradeTime)
+	}
+
+	if err := svc.handleTrade(context.Background(), testTrade(1)); err != nil {
+		t.Fatalf("handleTrade failed: %v", err)
+	}
+
+	depth, err := svc.DLQDepth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth != 0 {
+		t.Errorf("Expected trades with invalid times to be dropped, got DLQ depth %d", depth)
+	}
+}
+
+func TestService_DrainDLQ_RetriesOnlyFailedSteps(t *testing.T) {
+	svc := setupDLQService(t)
+	ctx := context.Background()
+
+	var aggregated int
+	svc.batcher = newTradeBatcher(1, time.Hour, func(ctx context.Context, trades []*models.Trade) {
+		aggregated += len(trades)
+	})
+
+	// Storing the raw message fails; the trade itself is stored and aggregated
+	svc.process = func(ctx context.Context, trade *models.AggTradeEvent) error {
+		svc.batcher.add(trade.ToTrade())
+		return errors.Join(&stepError{step: stepStoreRaw, err: fmt.Errorf("failed to store raw trade: timeout")})
+	}
+	trade := testTrade(1)
+	trade.Data.TradeTime = time.Now().UnixMilli()
+	trade.Raw = []byte(fmt.Sprintf(`{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","t":1,"p":"50000.00","q":"0.1","T":%d}}`, trade.Data.TradeTime))
+	svc.handleTrade(context.Background(), trade)
+
+	entries, err := svc.ListDLQ(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || len(entries[0].Steps) != 1 || entries[0].Steps[0] != stepStoreRaw {
+		t.Fatalf("Expected the failed step recorded, got %+v", entries)
+	}
+
+	succeeded, err := svc.DrainDLQ(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if succeeded != 1 {
+		t.Fatalf("Expected the retry to succeed, got %d", succeeded)
+	}
+	if aggregated != 1 {
+		t.Errorf("Expected the trade aggregated once, got %d", aggregated)
+	}
+	stored, err := svc.redisStore.GetRedisClient().ZCard(ctx, "binance:trade:BTCUSDT:history").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 1 {
+		t.Error("Expected the raw message stored by the retry")
+	}
+	if _, err := svc.redisStore.GetLatestTrade(ctx, "BTCUSDT"); err == nil {
+		t.Error("Expected the retry to skip the trade store step that had succeeded")
+	}
+}
@@ -2,12 +2,14 @@ package processor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 
 	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/analysis"
 	"binance-redis-streamer/pkg/config"
 	"binance-redis-streamer/pkg/messaging"
 	"binance-redis-streamer/pkg/storage"
@@ -22,6 +24,15 @@ type Service struct {
 	workerPool chan struct{}
 	stopCh     chan struct{}
 	wg         sync.WaitGroup
+	stats      symbolStatsRegistry
+	batcher    *tradeBatcher             // Feeds trades to the aggregator in batches
+	detector   *analysis.AnomalyDetector // Nil unless anomaly detection is enabled
+
+	// process stores and aggregates a trade; failures are dead-lettered
+	process func(ctx context.Context, trade *models.AggTradeEvent) error
+	// replay reruns the failed steps of a dead-lettered trade
+	replay func(ctx context.Context, trade *models.AggTradeEvent, steps []string) error
+	now    func() time.Time
 }
 
 // NewService creates a new processor service
@@ -30,20 +41,28 @@ func NewService(
 	store *storage.RedisStore,
 	aggregator *storage.TradeAggregator,
 ) *Service {
-	return &Service{
+	s := &Service{
 		config:     cfg,
 		messageBus: messaging.NewRedisPubSub(store.GetRedisClient()),
 		redisStore: store,
 		aggregator: aggregator,
 		workerPool: make(chan struct{}, 100), // Limit concurrent processing
 		stopCh:     make(chan struct{}),
+		now:        time.Now,
 	}
+	s.process = s.processTrade
+	s.replay = s.replaySteps
+	s.batcher = newTradeBatcher(aggregateBatchSize, aggregateBatchWait, s.aggregate)
+	if cfg.Processor.Anomalies.Enabled {
+		s.detector = analysis.NewAnomalyDetector(cfg.Processor.Anomalies)
+	}
+	return s
 }
 
 // Start starts the processor service
 func (s *Service) Start(ctx context.Context) error {
 	// Subscribe to trade events
-	if err := s.messageBus.Subscribe(ctx, s.handleTrade); err != nil {
+	if err := s.messageBus.SubscribeAll(ctx, s.handleEnvelope); err != nil {
 		return fmt.Errorf("failed to subscribe to trades: %w", err)
 	}
 
@@ -52,8 +71,19 @@ func (s *Service) Start(ctx context.Context) error {
 	return ctx.Err()
 }
 
-// handleTrade processes a single trade event
-func (s *Service) handleTrade(trade *models.AggTradeEvent) error {
+// handleEnvelope unwraps a bus message and processes its trade
+func (s *Service) handleEnvelope(env *messaging.Envelope) error {
+	if env.Payload == nil {
+		return fmt.Errorf("message has no trade payload")
+	}
+	if s.config.Debug && env.Source != messaging.SourceLive {
+		log.Printf("Processing %s trade from %s (envelope v%d)", env.Source, env.Exchange, env.Version)
+	}
+	return s.handleTrade(messaging.WithSource(context.Background(), env.Source), env.Payload)
+}
+
+// handleTrade processes a single trade event from the source set on ctx
+func (s *Service) handleTrade(ctx context.Context, trade *models.AggTradeEvent) error {
 	// Acquire worker from pool
 	select {
 	case s.workerPool <- struct{}{}:
@@ -62,12 +92,16 @@ func (s *Service) handleTrade(trade *models.AggTradeEvent) error {
 		return fmt.Errorf("service is stopping")
 	}
 
-	// Check for duplicate trade
+	// Check for duplicate trade. Aggregate trade IDs are numbered apart from
+	// trade IDs.
 	tradeKey := fmt.Sprintf("%s:%d", trade.Data.Symbol, trade.Data.TradeID)
+	if trade.Data.EventType == models.EventTypeAggTrade {
+		tradeKey = fmt.Sprintf("%s:a%d", trade.Data.Symbol, trade.Data.AggregateTradeID)
+	}
 	duplicateKey := fmt.Sprintf("%strade:processed:%s", s.config.Redis.KeyPrefix, tradeKey)
 
 	// Try to set the key with 1-hour expiry
-	isNew, err := s.redisStore.GetRedisClient().SetNX(context.Background(), duplicateKey, "1", time.Hour).Result()
+	isNew, err := s.redisStore.GetRedisClient().SetNX(ctx, duplicateKey, "1", time.Hour).Result()
 	if err != nil {
 		log.Printf("Warning: failed to check for duplicate trade: %v", err)
 	} else if !isNew {
@@ -81,31 +115,112 @@ func (s *Service) handleTrade(trade *models.AggTradeEvent) error {
 	log.Printf("Received trade event for %s: price=%s, quantity=%s",
 		trade.Data.Symbol, trade.Data.Price, trade.Data.Quantity)
 
+	if err := s.process(ctx, trade); errors.Is(err, storage.ErrInvalidTradeTime) {
+		// Retrying cannot fix a bad timestamp, so keep it out of the DLQ
+		log.Printf("Warning: dropping trade for %s: %v", trade.Data.Symbol, err)
+	} else if err != nil {
+		log.Printf("Failed to process trade for %s, moving it to the dead letter queue: %v", trade.Data.Symbol, err)
+		entry := DLQEntry{Event: trade, Raw: trade.Raw, Steps: failedSteps(err), Error: err.Error(), FailedAt: s.now(), Attempts: 1}
+		if err := s.deadLetter(ctx, entry); err != nil {
+			log.Printf("Warning: dropping failed trade for %s: %v", trade.Data.Symbol, err)
+		}
+	}
+
+	// Detect live trades only, so backfilled ones are not flagged late
+	if messaging.SourceFrom(ctx) == messaging.SourceLive {
+		s.detectAnomalies(ctx, trade.ToTrade())
+	}
+
+	return nil
+}
+
+// processTrade stores a trade in Redis and feeds it to the aggregator,
+// returning every step that failed as a *stepError
+func (s *Service) processTrade(ctx context.Context, trade *models.AggTradeEvent) error {
 	// Convert to trade model
 	processedTrade := trade.ToTrade()
 
-	// Store in Redis
-	if err := s.redisStore.StoreTrade(context.Background(), processedTrade); err != nil {
-		log.Printf("Failed to store trade in Redis: %v", err)
+	s.stats.recordTrade(processedTrade.Symbol, len(trade.Raw), processedTrade.Time)
+
+	err := s.runSteps(ctx, trade, processedTrade, storeSteps)
+
+	// Aggregate in batches to take the candle lock less often. Trades are
+	// aggregated even when storing failed, so retries never aggregate again.
+	s.batcher.add(processedTrade)
+
+	return err
+}
+
+// replaySteps reruns steps of a dead-lettered trade. Aggregation is never
+// among them, so candles do not count a retried trade twice.
+func (s *Service) replaySteps(ctx context.Context, trade *models.AggTradeEvent, steps []string) error {
+	return s.runSteps(messaging.WithSource(ctx, messaging.SourceReplay), trade, trade.ToTrade(), steps)
+}
+
+// runSteps runs the storing steps of a trade in order, returning every one
+// that failed as a *stepError. Trades not from the live stream never replace
+// a newer latest trade.
+func (s *Service) runSteps(ctx context.Context, trade *models.AggTradeEvent, processedTrade *models.Trade, steps []string) error {
+	store := s.redisStore.StoreTrade
+	if messaging.SourceFrom(ctx) != messaging.SourceLive {
+		store = s.redisStore.StoreBackfilledTrade
 	}
 
-	// Store raw trade data
-	if err := s.redisStore.StoreRawTrade(context.Background(), processedTrade.Symbol, trade.Raw); err != nil {
-		log.Printf("Failed to store raw trade: %v", err)
+	var errs []error
+	for _, step := range steps {
+		var err error
+		switch step {
+		case stepStore:
+			if err = store(ctx, processedTrade); err != nil {
+				log.Printf("Failed to store trade in Redis: %v", err)
+				err = fmt.Errorf("failed to store trade: %w", err)
+			}
+		case stepStoreRaw:
+			if err = s.redisStore.StoreRawTrade(ctx, processedTrade.Symbol, trade.Raw); err != nil {
+				log.Printf("Failed to store raw trade: %v", err)
+				err = fmt.Errorf("failed to store raw trade: %w", err)
+			}
+		default:
+			err = fmt.Errorf("unknown processing step %q", step)
+		}
+		if err != nil {
+			s.stats.recordStoreError(processedTrade.Symbol)
+			errs = append(errs, &stepError{step: step, err: err})
+		}
 	}
+	return errors.Join(errs...)
+}
 
-	// Process through aggregator
-	if err := s.aggregator.ProcessTrade(context.Background(), processedTrade); err != nil {
-		log.Printf("Failed to process trade through aggregator: %v", err)
-	} else {
-		log.Printf("Successfully processed trade through aggregator for %s", processedTrade.Symbol)
+// aggregate feeds a batch of trades to the aggregator. Aggregation happens
+// after the trades were handled, so failures are logged and counted rather
+// than dead-lettered.
+func (s *Service) aggregate(ctx context.Context, trades []*models.Trade) {
+	if err := s.aggregator.ProcessTrades(ctx, trades); err != nil {
+		log.Printf("Failed to process %d trades through aggregator: %v", len(trades), err)
+		for _, trade := range trades {
+			s.stats.recordStoreError(trade.Symbol)
+		}
+		return
 	}
+	log.Printf("Successfully processed %d trades through aggregator", len(trades))
+}
 
-	return nil
+// Snapshot returns per-symbol processing counters, busiest symbol first.
+// When reset is set, the counters are zeroed as they are read.
+func (s *Service) Snapshot(reset bool) []SymbolStats {
+	return s.stats.snapshot(reset)
 }
 
-// Stop gracefully stops the processor service
+// Stop gracefully stops the processor service, handing trades still
+// waiting for a batch to the aggregator
 func (s *Service) Stop() {
 	close(s.stopCh)
 	s.wg.Wait()
+	s.FlushPending()
+}
+
+// FlushPending hands the trades batched for aggregation to the aggregator,
+// e.g. once the trades stopped arriving
+func (s *Service) FlushPending() {
+	s.batcher.flushPending()
 }
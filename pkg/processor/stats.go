@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SymbolStats is a point-in-time view of the processing counters of a symbol
+type SymbolStats struct {
+	Symbol          string    `json:"symbol"`
+	TradesProcessed int64     `json:"trades_processed"`
+	Bytes           int64     `json:"bytes"`
+	StoreErrors     int64     `json:"store_errors"`
+	LastTradeTime   time.Time `json:"last_trade_time"`
+}
+
+// symbolCounters holds the live counters of a symbol; all fields are updated
+// atomically so the hot path never takes a lock
+type symbolCounters struct {
+	trades      atomic.Int64
+	bytes       atomic.Int64
+	storeErrors atomic.Int64
+	lastTrade   atomic.Int64 // Unix milliseconds
+}
+
+// symbolStatsRegistry tracks per-symbol counters
+type symbolStatsRegistry struct {
+	counters sync.Map // symbol -> *symbolCounters
+}
+
+// get returns the counters of a symbol, creating them on first use
+func (r *symbolStatsRegistry) get(symbol string) *symbolCounters {
+	if c, ok := r.counters.Load(symbol); ok {
+		return c.(*symbolCounters)
+	}
+	c, _ := r.counters.LoadOrStore(symbol, &symbolCounters{})
+	return c.(*symbolCounters)
+}
+
+// recordTrade counts a processed trade
+func (r *symbolStatsRegistry) recordTrade(symbol string, size int, tradeTime time.Time) {
+	c := r.get(symbol)
+	c.trades.Add(1)
+	c.bytes.Add(int64(size))
+
+	// Keep the latest trade time even when trades arrive out of order
+	ms := tradeTime.UnixMilli()
+	for {
+		last := c.lastTrade.Load()
+		if ms <= last || c.lastTrade.CompareAndSwap(last, ms) {
+			break
+		}
+	}
+}
+
+// recordStoreError counts a failed write for a symbol
+func (r *symbolStatsRegistry) recordStoreError(symbol string) {
+	r.get(symbol).storeErrors.Add(1)
+}
+
+// snapshot returns the counters of all symbols, busiest first. When reset is
+// set, the trade, byte and error counters are zeroed as they are read.
+func (r *symbolStatsRegistry) snapshot(reset bool) []SymbolStats {
+	var stats []SymbolStats
+	r.counters.Range(func(key, value interface{}) bool {
+		c := value.(*symbolCounters)
+		s := SymbolStats{Symbol: key.(string)}
+
+		if reset {
+			s.TradesProcessed = c.trades.Swap(0)
+			s.Bytes = c.bytes.Swap(0)
+			s.StoreErrors = c.storeErrors.Swap(0)
+		} else {
+			s.TradesProcessed = c.trades.Load()
+			s.Bytes = c.bytes.Load()
+			s.StoreErrors = c.storeErrors.Load()
+		}
+		if ms := c.lastTrade.Load(); ms > 0 {
+			s.LastTradeTime = time.UnixMilli(ms)
+		}
+
+		stats = append(stats, s)
+		return true
+	})
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].TradesProcessed != stats[j].TradesProcessed {
+			return stats[i].TradesProcessed > stats[j].TradesProcessed
+		}
+		return stats[i].Symbol < stats[j].Symbol
+	})
+	return stats
+}
@@ -0,0 +1,133 @@
+package processor
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSymbolStats_Snapshot(t *testing.T) {
+	var stats symbolStatsRegistry
+	base := time.UnixMilli(1700000000000)
+
+	stats.recordTrade("ETHUSDT", 100, base)
+	stats.recordTrade("BTCUSDT", 120, base.Add(2*time.Second))
+	stats.recordTrade("BTCUSDT", 80, base.Add(time.Second)) // out of order
+	stats.recordStoreError("BTCUSDT")
+
+	snapshot := stats.snapshot(false)
+	if len(snapshot) != 2 {
+		t.Fatalf("Expected 2 symbols, got %d", len(snapshot))
+	}
+
+	btc := snapshot[0]
+	if btc.Symbol != "BTCUSDT" || btc.TradesProcessed != 2 || btc.Bytes != 200 || btc.StoreErrors != 1 {
+		t.Errorf("Unexpected BTCUSDT stats: %+v", btc)
+	}
+	if !btc.LastTradeTime.Equal(base.Add(2 * time.Second)) {
+		t.Errorf("Expected last trade time to ignore out-of-order trades, got %v", btc.LastTradeTime)
+	}
+	if snapshot[1].Symbol != "ETHUSDT" {
+		t.Errorf("Expected busiest symbol first, got %v", snapshot)
+	}
+
+	// Reset-on-read zeroes counters but keeps the last trade time
+	stats.snapshot(true)
+	after := stats.snapshot(false)
+	if after[0].TradesProcessed != 0 || after[0].Bytes != 0 || after[0].StoreErrors != 0 {
+		t.Errorf("Expected counters to be reset, got %+v", after[0])
+	}
+	if after[0].LastTradeTime.IsZero() {
+		t.Error("Expected last trade time to survive a reset")
+	}
+}
+
+func TestSymbolStats_Concurrent(t *testing.T) {
+	var stats symbolStatsRegistry
+	const (
+		writers   = 8
+		perWriter = 1000
+		symbols   = 4
+	)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				symbol := fmt.Sprintf("SYM%d", i%symbols)
+				stats.recordTrade(symbol, 10, time.UnixMilli(int64(w*perWriter+i+1)))
+				if i%10 == 0 {
+					stats.recordStoreError(symbol)
+				}
+			}
+		}(w)
+	}
+
+	// Readers drain counters with reset-on-read while writers are running
+	var (
+		mu           sync.Mutex
+		drained      int64
+		drainedBytes int64
+		drainedErrs  int64
+	)
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for r := 0; r < 2; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, s := range stats.snapshot(true) {
+					mu.Lock()
+					drained += s.TradesProcessed
+					drainedBytes += s.Bytes
+					drainedErrs += s.StoreErrors
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	for _, s := range stats.snapshot(true) {
+		drained += s.TradesProcessed
+		drainedBytes += s.Bytes
+		drainedErrs += s.StoreErrors
+	}
+
+	if want := int64(writers * perWriter); drained != want {
+		t.Errorf("Expected %d trades across snapshots, got %d", want, drained)
+	}
+	if want := int64(writers * perWriter * 10); drainedBytes != want {
+		t.Errorf("Expected %d bytes across snapshots, got %d", want, drainedBytes)
+	}
+	if want := int64(writers * perWriter / 10); drainedErrs != want {
+		t.Errorf("Expected %d store errors across snapshots, got %d", want, drainedErrs)
+	}
+
+	snapshot := stats.snapshot(false)
+	if len(snapshot) != symbols {
+		t.Fatalf("Expected %d symbols, got %d", symbols, len(snapshot))
+	}
+	latest := time.UnixMilli(int64(writers * perWriter))
+	var maxSeen time.Time
+	for _, s := range snapshot {
+		if s.LastTradeTime.After(maxSeen) {
+			maxSeen = s.LastTradeTime
+		}
+	}
+	if !maxSeen.Equal(latest) {
+		t.Errorf("Expected latest trade time %v, got %v", latest, maxSeen)
+	}
+}
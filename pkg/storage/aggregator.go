@@ -2,32 +2,106 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
 	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/breaker"
+	"binance-redis-streamer/pkg/config"
+)
+
+// spillDrainBatch is how many spilled candles each flush reads at a time
+const spillDrainBatch = 100
+
+// candleEvictDivisor sets how far below the cap eviction goes: a tenth of it,
+// so the oldest candles are not searched for on every new candle
+const candleEvictDivisor = 10
+
+var (
+	bufferedCandles = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "binance_aggregator_buffered_candles",
+		Help: "Candles held in memory by the aggregator awaiting a flush",
+	})
+	evictedCandles = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "binance_aggregator_evicted_candles_total",
+		Help: "Candles written out (flushed), parked in Redis (spilled) or lost (dropped) early because the candle buffer was full",
+	}, []string{"result"})
+	dataGaps = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "binance_data_gaps_total",
+		Help: "Gaps between stored minute candles of a symbol in the window of the last migration",
+	}, []string{"symbol"})
 )
 
+// candleWriter stores minute candles
+type candleWriter interface {
+	StoreCandleData(ctx context.Context, symbol string, candle *models.Candle, source CandleSource) error
+}
+
+// candleSpill parks evicted candles that could not be written until
+// PostgreSQL is back
+type candleSpill interface {
+	SpillCandles(ctx context.Context, candles []SpilledCandle) error
+	SpilledCandles(ctx context.Context, n int) ([]SpilledCandle, error)
+	TrimSpilledCandles(ctx context.Context, n int) error
+}
+
 // TradeAggregator handles trade aggregation and storage
 type TradeAggregator struct {
 	redisStore    *RedisStore
 	postgresStore *PostgresStore
+	candleStore   candleWriter     // Where candles are flushed, the PostgreSQL store
+	writeBreaker  *breaker.Breaker // Guards candle writes while PostgreSQL is down; nil writes unguarded
+	spill         candleSpill      // Where evicted candles go while PostgreSQL is down; nil drops them
 	candles       map[string]*models.Candle
 	candleMu      sync.RWMutex
+	maxCandles    int // Buffered candles before the oldest are evicted (0 is unbounded)
 	stopCh        chan struct{}
+	retention     config.PostgresConfig // Candle pruning and daily rollup settings (zero disables each)
 }
 
 // NewTradeAggregator creates a new trade aggregator
 func NewTradeAggregator(redisStore *RedisStore, postgresStore *PostgresStore) *TradeAggregator {
-	return &TradeAggregator{
+	a := &TradeAggregator{
 		redisStore:    redisStore,
 		postgresStore: postgresStore,
+		candleStore:   postgresStore,
 		candles:       make(map[string]*models.Candle),
 		stopCh:        make(chan struct{}),
 	}
+	if redisStore != nil {
+		a.spill = redisStore
+	}
+	return a
+}
+
+// SetMaxBufferedCandles caps the candles held in memory at n (0 removes the
+// cap); it must be called before trades are processed
+func (a *TradeAggregator) SetMaxBufferedCandles(n int) {
+	a.maxCandles = n
+}
+
+// SetWriteBreaker guards candle writes with a circuit breaker, so that while
+// PostgreSQL is down flushes stop after a few failures instead of each
+// trying and failing every candle. Unwritten candles stay buffered, up to the
+// cap of SetMaxBufferedCandles. It must be called before trades are
+// processed.
+func (a *TradeAggregator) SetWriteBreaker(cfg config.BreakerConfig) {
+	a.writeBreaker = breaker.New("postgres-write", cfg)
+}
+
+// SetCandleRetention enables pruning of PostgreSQL candles older than
+// cfg.CandleRetention and the daily rollup every cfg.RollupInterval; it must
+// be called before Start
+func (a *TradeAggregator) SetCandleRetention(cfg config.PostgresConfig) {
+	a.retention = cfg
 }
 
 // Start starts the aggregation process
@@ -41,6 +115,13 @@ func (a *TradeAggregator) Start(ctx context.Context) {
 	// Start historical data migration
 	go a.migrateHistoricalData(ctx)
 
+	if a.retention.CandleRetention > 0 {
+		go a.pruneCandles(ctx)
+	}
+	if a.retention.RollupInterval > 0 {
+		go a.rollupDaily(ctx)
+	}
+
 	// Run the flush loop in the main goroutine
 	for {
 		select {
@@ -59,8 +140,143 @@ func (a *TradeAggregator) Start(ctx context.Context) {
 // ProcessTrade processes a new trade and updates the current candle
 func (a *TradeAggregator) ProcessTrade(ctx context.Context, trade *models.Trade) error {
 	a.candleMu.Lock()
-	defer a.candleMu.Unlock()
+	a.updateCandle(trade)
+	evicted := a.evictOldest()
+	a.candleMu.Unlock()
+
+	a.writeEvicted(ctx, evicted)
+	return nil
+}
+
+// ProcessTrades updates candles from a batch of trades, which may span
+// symbols, taking the candle lock once for the whole batch
+func (a *TradeAggregator) ProcessTrades(ctx context.Context, trades []*models.Trade) error {
+	if len(trades) == 1 {
+		return a.ProcessTrade(ctx, trades[0])
+	}
+
+	a.candleMu.Lock()
+	for _, trade := range trades {
+		a.updateCandle(trade)
+	}
+	evicted := a.evictOldest()
+	a.candleMu.Unlock()
+
+	a.writeEvicted(ctx, evicted)
+	return nil
+}
+
+// evictOldest removes the oldest candles once more than maxCandles are
+// buffered, down to a tenth below the cap, and returns them by key; callers
+// must hold candleMu
+func (a *TradeAggregator) evictOldest() map[string]*models.Candle {
+	defer func() { bufferedCandles.Set(float64(len(a.candles))) }()
+	if a.maxCandles <= 0 || len(a.candles) <= a.maxCandles {
+		return nil
+	}
+
+	keys := make([]string, 0, len(a.candles))
+	for key := range a.candles {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return a.candles[keys[i]].Timestamp.Before(a.candles[keys[j]].Timestamp)
+	})
+
+	n := len(keys) - a.maxCandles + a.maxCandles/candleEvictDivisor
+	evicted := make(map[string]*models.Candle, n)
+	for _, key := range keys[:n] {
+		evicted[key] = a.candles[key]
+		delete(a.candles, key)
+	}
+	return evicted
+}
+
+// writeEvicted writes evicted candles out, complete or not. A candle whose
+// minute gets more trades is written again by a later flush, adding to this
+// one. Once a write fails, as while PostgreSQL is down, the rest are spilled
+// to Redis rather than each waiting on the database, and written by the
+// flushes once it is back. They are only dropped if Redis fails too.
+func (a *TradeAggregator) writeEvicted(ctx context.Context, evicted map[string]*models.Candle) {
+	if len(evicted) == 0 {
+		return
+	}
+
+	var writeErr error
+	var unwritten []SpilledCandle
+	for key, candle := range evicted {
+		symbol := strings.Split(key, ":")[0]
+		if writeErr == nil {
+			if writeErr = a.storeCandle(ctx, symbol, candle, CandleSourceLive); writeErr == nil {
+				continue
+			}
+		}
+		unwritten = append(unwritten, SpilledCandle{Symbol: symbol, Candle: candle})
+	}
+	flushed := len(evicted) - len(unwritten)
+	evictedCandles.WithLabelValues("flushed").Add(float64(flushed))
+
+	if writeErr == nil {
+		log.Printf("[WARNING] Candle buffer over %d candles: flushed the %d oldest early", a.maxCandles, flushed)
+		return
+	}
+	spillErr := errors.New("no spill store")
+	if a.spill != nil {
+		spillErr = a.spill.SpillCandles(ctx, unwritten)
+	}
+	if spillErr != nil {
+		evictedCandles.WithLabelValues("dropped").Add(float64(len(unwritten)))
+		log.Printf("[ERROR] Candle buffer over %d candles: flushed %d of the oldest and dropped %d: %v (spill: %v)",
+			a.maxCandles, flushed, len(unwritten), writeErr, spillErr)
+		return
+	}
+	evictedCandles.WithLabelValues("spilled").Add(float64(len(unwritten)))
+	log.Printf("[WARNING] Candle buffer over %d candles: flushed %d of the oldest and spilled %d to Redis: %v",
+		a.maxCandles, flushed, len(unwritten), writeErr)
+}
+
+// drainSpill writes the spilled candles, oldest first, removing each batch
+// once written
+func (a *TradeAggregator) drainSpill(ctx context.Context) error {
+	if a.spill == nil {
+		return nil
+	}
+	for {
+		spilled, err := a.spill.SpilledCandles(ctx, spillDrainBatch)
+		if err != nil || len(spilled) == 0 {
+			return err
+		}
+
+		written := 0
+		for _, c := range spilled {
+			if err = a.storeCandle(ctx, c.Symbol, c.Candle, CandleSourceLive); err != nil {
+				break
+			}
+			written++
+		}
+		if trimErr := a.spill.TrimSpilledCandles(ctx, written); trimErr != nil {
+			return trimErr
+		}
+		if err != nil {
+			return err
+		}
+		log.Printf("Wrote %d spilled candles", written)
+	}
+}
+
+// storeCandle writes a candle through the write breaker, if any. It returns
+// breaker.ErrOpen without writing while the breaker is open.
+func (a *TradeAggregator) storeCandle(ctx context.Context, symbol string, candle *models.Candle, source CandleSource) error {
+	if a.writeBreaker == nil {
+		return a.candleStore.StoreCandleData(ctx, symbol, candle, source)
+	}
+	return a.writeBreaker.Do(func() error {
+		return a.candleStore.StoreCandleData(ctx, symbol, candle, source)
+	})
+}
 
+// updateCandle adds a trade to its minute candle; callers must hold candleMu
+func (a *TradeAggregator) updateCandle(trade *models.Trade) {
 	// Truncate to minute for candle
 	candleTime := trade.Time.Truncate(time.Minute)
 	key := fmt.Sprintf("%s:%s", trade.Symbol, candleTime.Format(time.RFC3339))
@@ -81,17 +297,41 @@ func (a *TradeAggregator) ProcessTrade(ctx context.Context, trade *models.Trade)
 		trade.Symbol, candleTime.Format(time.RFC3339),
 		candle.OpenPrice, candle.HighPrice, candle.LowPrice, candle.ClosePrice,
 		candle.Volume, candle.TradeCount)
+}
 
-	return nil
+// Flush writes completed candles to PostgreSQL immediately instead of
+// waiting for the next flush tick
+func (a *TradeAggregator) Flush(ctx context.Context) error {
+	return a.flushCandles(ctx)
 }
 
 // flushCandles writes completed candles to PostgreSQL
 func (a *TradeAggregator) flushCandles(ctx context.Context) error {
+	return a.flushBefore(ctx, time.Now().UTC().Truncate(time.Minute))
+}
+
+// FlushAll writes every buffered candle, including those of the current
+// minute, e.g. before another replica takes over the aggregation. Live
+// candles of one writer are merged additively, so the rest of the minute
+// adds up as long as the replica writes under the same
+// postgres.candle_writer, as it does by default.
+func (a *TradeAggregator) FlushAll(ctx context.Context) error {
+	return a.flushBefore(ctx, time.Now().UTC().Truncate(time.Minute).Add(time.Minute))
+}
+
+// flushBefore writes the buffered candles of minutes before currentMinute
+func (a *TradeAggregator) flushBefore(ctx context.Context, currentMinute time.Time) error {
 	a.candleMu.Lock()
 	defer a.candleMu.Unlock()
 
+	// Spilled candles are older, so they go first; while they cannot be
+	// written neither can the buffered ones
+	if err := a.drainSpill(ctx); err != nil {
+		bufferedCandles.Set(float64(len(a.candles)))
+		return fmt.Errorf("failed to write spilled candles: %w", err)
+	}
+
 	log.Printf("[DEBUG] Starting candle flush, current count: %d", len(a.candles))
-	currentMinute := time.Now().UTC().Truncate(time.Minute)
 	flushedCount := 0
 
 	for key, candle := range a.candles {
@@ -103,7 +343,12 @@ func (a *TradeAggregator) flushCandles(ctx context.Context) error {
 				candle.OpenPrice, candle.HighPrice, candle.LowPrice, candle.ClosePrice,
 				candle.Volume, candle.TradeCount)
 
-			if err := a.postgresStore.StoreCandleData(ctx, symbol, candle); err != nil {
+			if err := a.storeCandle(ctx, symbol, candle, CandleSourceLive); errors.Is(err, breaker.ErrOpen) {
+				// Keep the rest buffered for a later flush
+				log.Printf("[WARNING] Candle writes paused by the circuit breaker (retry in %s), keeping %d candles buffered",
+					a.writeBreaker.RetryAfter().Round(time.Second), len(a.candles))
+				break
+			} else if err != nil {
 				log.Printf("[ERROR] Failed to store candle data: %v", err)
 				continue
 			}
@@ -118,6 +363,7 @@ func (a *TradeAggregator) flushCandles(ctx context.Context) error {
 
 	log.Printf("[DEBUG] Flush complete: flushed %d candles, %d remaining in memory",
 		flushedCount, len(a.candles))
+	bufferedCandles.Set(float64(len(a.candles)))
 
 	return nil
 }
@@ -171,24 +417,19 @@ func (a *TradeAggregator) performMigration(ctx context.Context) error {
 		log.Printf("[DEBUG] Found %d historical trades for %s", len(trades), symbol)
 
 		// Group trades by minute
-		candleMap := make(map[time.Time]*models.Candle)
-		for _, trade := range trades {
-			tradeTime := time.UnixMilli(trade.Data.TradeTime).Truncate(time.Minute)
-			if candle, exists := candleMap[tradeTime]; exists {
-				candle.UpdateFromTrade(trade.Data.ToTrade())
-			} else {
-				candle = models.NewCandle(tradeTime)
-				candle.UpdateFromTrade(trade.Data.ToTrade())
-				candleMap[tradeTime] = candle
-			}
-		}
+		candles := models.MinuteCandles(trades)
 
-		log.Printf("[DEBUG] Created %d candles from historical trades for %s", len(candleMap), symbol)
+		log.Printf("[DEBUG] Created %d candles from historical trades for %s", len(candles), symbol)
 
-		// Store candles in PostgreSQL
+		// Store candles in PostgreSQL. While the write breaker is open the
+		// migration stops; the next one reads the same trades again.
 		storedCount := 0
-		for _, candle := range candleMap {
-			if err := a.postgresStore.StoreCandleData(ctx, symbol, candle); err != nil {
+		for _, candle := range candles {
+			err := a.storeCandle(ctx, symbol, candle, CandleSourceMigration)
+			if errors.Is(err, breaker.ErrOpen) {
+				return fmt.Errorf("migration of %s stopped: %w", symbol, err)
+			}
+			if err != nil {
 				log.Printf("[ERROR] Error storing historical candle data for %s: %v", symbol, err)
 				continue
 			}
@@ -196,7 +437,13 @@ func (a *TradeAggregator) performMigration(ctx context.Context) error {
 		}
 
 		log.Printf("[DEBUG] Successfully stored %d/%d historical candles for %s",
-			storedCount, len(candleMap), symbol)
+			storedCount, len(candles), symbol)
+
+		if gaps, err := a.postgresStore.GetGapAnalysis(ctx, symbol, start, end); err != nil {
+			log.Printf("[WARNING] Failed to analyse candle gaps for %s: %v", symbol, err)
+		} else {
+			dataGaps.WithLabelValues(symbol).Set(float64(len(gaps)))
+		}
 
 		// After successful migration, clean up Redis data older than retention period
 		if err := a.redisStore.trimHistory(ctx, fmt.Sprintf("%strade:%s:history",
@@ -208,6 +455,55 @@ func (a *TradeAggregator) performMigration(ctx context.Context) error {
 	return nil
 }
 
+// pruneCandles periodically deletes candles past the retention period
+func (a *TradeAggregator) pruneCandles(ctx context.Context) {
+	ticker := time.NewTicker(a.retention.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.stopCh:
+			return
+		case <-ticker.C:
+			deleted, err := a.postgresStore.PruneOlderThan(ctx, a.retention.CandleRetention, a.retention.PruneBatchSize)
+			if err != nil {
+				log.Printf("Error pruning old candles: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Pruned %d candles older than %s", deleted, a.retention.CandleRetention)
+			}
+		}
+	}
+}
+
+// rollupDaily recomputes yesterday's and today's daily candles at start and
+// every rollup interval. Yesterday is included so its last minutes, flushed
+// after midnight, make it into its complete rollup.
+func (a *TradeAggregator) rollupDaily(ctx context.Context) {
+	ticker := time.NewTicker(a.retention.RollupInterval)
+	defer ticker.Stop()
+
+	for {
+		stored, err := a.postgresStore.RollupSince(ctx, time.Now().Add(-24*time.Hour))
+		if err != nil {
+			log.Printf("Error rolling up daily candles: %v", err)
+		} else if stored > 0 {
+			log.Printf("Rolled up %d daily candles", stored)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // Stop stops the aggregator
 func (a *TradeAggregator) Stop() {
 	close(a.stopCh)
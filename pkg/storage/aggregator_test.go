@@ -2,10 +2,24 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
 	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/breaker"
+	"binance-redis-streamer/pkg/config"
 )
 
 func setupTestAggregator(t *testing.T) (*TradeAggregator, func()) {
@@ -117,3 +131,431 @@ func TestTradeAggregator_FlushCandles(t *testing.T) {
 		t.Errorf("Expected 0 candles after flush, got %d", numCandles)
 	}
 }
+
+func TestTradeAggregator_ProcessTrades(t *testing.T) {
+	// Candles are built in memory, so no stores are needed
+	aggregator := NewTradeAggregator(nil, nil)
+	ctx := context.Background()
+	minute := time.Now().Truncate(time.Minute)
+
+	trades := []*models.Trade{
+		{Symbol: "BTCUSDT", Price: "100", Quantity: "1", Time: minute},
+		{Symbol: "ETHUSDT", Price: "10", Quantity: "2", Time: minute.Add(time.Second)},
+		{Symbol: "BTCUSDT", Price: "120", Quantity: "1", Time: minute.Add(2 * time.Second)},
+		{Symbol: "BTCUSDT", Price: "90", Quantity: "1", Time: minute.Add(time.Minute)},
+	}
+	if err := aggregator.ProcessTrades(ctx, trades); err != nil {
+		t.Fatalf("ProcessTrades failed: %v", err)
+	}
+	// A batch of one takes the per-trade path
+	if err := aggregator.ProcessTrades(ctx, []*models.Trade{{Symbol: "ETHUSDT", Price: "11", Quantity: "1", Time: minute}}); err != nil {
+		t.Fatalf("ProcessTrades failed: %v", err)
+	}
+
+	candle := func(symbol string, at time.Time) *models.Candle {
+		return aggregator.candles[symbol+":"+at.Format(time.RFC3339)]
+	}
+	if c := candle("BTCUSDT", minute); c == nil || c.TradeCount != 2 || c.OpenPrice != "100" || c.HighPrice != "120" {
+		t.Errorf("Unexpected BTCUSDT candle: %+v", c)
+	}
+	if c := candle("BTCUSDT", minute.Add(time.Minute)); c == nil || c.TradeCount != 1 {
+		t.Errorf("Expected the next minute in its own candle, got %+v", c)
+	}
+	if c := candle("ETHUSDT", minute); c == nil || c.TradeCount != 2 || c.ClosePrice != "11" {
+		t.Errorf("Unexpected ETHUSDT candle: %+v", c)
+	}
+}
+
+// bucketKey names a symbol's minute candle as the aggregator keys it
+func bucketKey(symbol string, minute time.Time) string {
+	return symbol + ":" + minute.Format(time.RFC3339)
+}
+
+// sendConcurrently has workers goroutines each process perWorker trades of
+// random symbols in random minutes, and returns the trades sent per bucket
+func sendConcurrently(t *testing.T, aggregator *TradeAggregator, symbols []string, minutes []time.Time, workers, perWorker int) map[string]int64 {
+	t.Helper()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		sent = make(map[string]int64)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			counts := make(map[string]int64)
+			for i := 0; i < perWorker; i++ {
+				symbol := symbols[rng.Intn(len(symbols))]
+				minute := minutes[rng.Intn(len(minutes))]
+				trade := &models.Trade{
+					Symbol:   symbol,
+					Price:    strconv.Itoa(100 + rng.Intn(10)),
+					Quantity: "1",
+					TradeID:  seed*int64(perWorker) + int64(i),
+					Time:     minute.Add(time.Duration(rng.Intn(60)) * time.Second),
+				}
+				if err := aggregator.ProcessTrade(context.Background(), trade); err != nil {
+					t.Errorf("ProcessTrade failed: %v", err)
+					return
+				}
+				counts[bucketKey(symbol, minute)]++
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for key, n := range counts {
+				sent[key] += n
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+	return sent
+}
+
+// Run with -race to check the candle map for unsynchronized access
+func TestTradeAggregator_ConcurrentProcessing(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	// Candles are built in memory, so no stores are needed
+	aggregator := NewTradeAggregator(nil, nil)
+	now := time.Now().Truncate(time.Minute)
+	symbols := []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT", "XRPUSDT"}
+	minutes := []time.Time{now.Add(-2 * time.Minute), now.Add(-time.Minute), now}
+
+	sent := sendConcurrently(t, aggregator, symbols, minutes, 20, 50)
+
+	aggregator.candleMu.RLock()
+	defer aggregator.candleMu.RUnlock()
+
+	var total int64
+	for key, want := range sent {
+		candle, ok := aggregator.candles[key]
+		if !ok {
+			t.Errorf("Missing candle %s with %d trades", key, want)
+			continue
+		}
+		if candle.TradeCount != want {
+			t.Errorf("Candle %s counted %d trades, want %d", key, candle.TradeCount, want)
+		}
+		if volume, _ := strconv.ParseFloat(candle.Volume, 64); volume != float64(want) {
+			t.Errorf("Candle %s volume = %v, want %d", key, volume, want)
+		}
+		total += want
+	}
+	if total != 20*50 {
+		t.Errorf("Sent %d trades, want %d", total, 20*50)
+	}
+	if len(aggregator.candles) != len(sent) {
+		t.Errorf("Aggregator holds %d candles, want %d", len(aggregator.candles), len(sent))
+	}
+}
+
+func TestTradeAggregator_FlushDuringProcessing(t *testing.T) {
+	aggregator, cleanup := setupTestAggregator(t)
+	if aggregator == nil {
+		return
+	}
+	defer cleanup()
+
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	symbols := []string{"FLUSHAUSDT", "FLUSHBUSDT", "FLUSHCUSDT"}
+	// Past minutes are flushed while trades for them still arrive; the
+	// current one stays in memory
+	minutes := []time.Time{now.Add(-3 * time.Minute), now.Add(-2 * time.Minute), now.Add(-time.Minute), now}
+
+	done := make(chan struct{})
+	flushed := make(chan int)
+	go func() {
+		flushes := 0
+		for {
+			select {
+			case <-done:
+				flushed <- flushes
+				return
+			default:
+			}
+			if err := aggregator.flushCandles(ctx); err != nil {
+				t.Errorf("flushCandles failed: %v", err)
+			}
+			flushes++
+		}
+	}()
+
+	sent := sendConcurrently(t, aggregator, symbols, minutes, 20, 50)
+	close(done)
+	if n := <-flushed; n == 0 {
+		t.Fatal("Expected flushes while processing")
+	}
+
+	// Every trade is counted once: in a stored candle, or in memory when
+	// its minute is not complete or it arrived after the last flush
+	for _, symbol := range symbols {
+		stored, err := aggregator.postgresStore.GetHistoricalCandles(ctx, symbol, minutes[0], now.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("GetHistoricalCandles failed: %v", err)
+		}
+		counted := make(map[string]int64)
+		for _, candle := range stored {
+			counted[bucketKey(symbol, candle.Timestamp.UTC())] += candle.TradeCount
+		}
+		aggregator.candleMu.RLock()
+		for _, minute := range minutes {
+			if candle, ok := aggregator.candles[bucketKey(symbol, minute)]; ok {
+				counted[bucketKey(symbol, minute)] += candle.TradeCount
+			}
+		}
+		aggregator.candleMu.RUnlock()
+
+		for _, minute := range minutes {
+			key := bucketKey(symbol, minute)
+			if counted[key] != sent[key] {
+				t.Errorf("Candle %s counted %d trades, want %d", key, counted[key], sent[key])
+			}
+		}
+	}
+}
+
+// candleRecorder records the candles written to it, failing once down
+type candleRecorder struct {
+	down     bool
+	written  []string // Keys of the candles written
+	attempts int      // Writes tried, including failed ones
+	onWrite  func()   // Called on each write when set
+}
+
+func (r *candleRecorder) StoreCandleData(ctx context.Context, symbol string, candle *models.Candle, source CandleSource) error {
+	r.attempts++
+	if r.onWrite != nil {
+		r.onWrite()
+	}
+	if r.down {
+		return errors.New("connection refused")
+	}
+	r.written = append(r.written, bucketKey(symbol, candle.Timestamp))
+	return nil
+}
+
+func TestTradeAggregator_EvictsOldestCandles(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	recorder := &candleRecorder{}
+	aggregator := NewTradeAggregator(nil, nil)
+	aggregator.candleStore = recorder
+	aggregator.SetMaxBufferedCandles(10)
+
+	// One candle a minute; the 11th goes over the cap and evicts down to 9
+	start := time.Now().Truncate(time.Minute).Add(-time.Hour)
+	trade := func(minute int) *models.Trade {
+		return &models.Trade{Symbol: "BTCUSDT", Price: "100", Quantity: "1", Time: start.Add(time.Duration(minute) * time.Minute)}
+	}
+	flushed := testutil.ToFloat64(evictedCandles.WithLabelValues("flushed"))
+	for minute := 0; minute < 11; minute++ {
+		if err := aggregator.ProcessTrade(context.Background(), trade(minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sort.Strings(recorder.written)
+	want := []string{bucketKey("BTCUSDT", start), bucketKey("BTCUSDT", start.Add(time.Minute))}
+	if !reflect.DeepEqual(recorder.written, want) {
+		t.Errorf("Expected the two oldest candles flushed, got %v", recorder.written)
+	}
+	if len(aggregator.candles) != 9 {
+		t.Errorf("Expected 9 candles left, got %d", len(aggregator.candles))
+	}
+	if _, ok := aggregator.candles[want[0]]; ok {
+		t.Error("Expected the oldest candle evicted")
+	}
+	if got := testutil.ToFloat64(evictedCandles.WithLabelValues("flushed")) - flushed; got != 2 {
+		t.Errorf("Expected 2 flushed evictions counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(bufferedCandles); got != 9 {
+		t.Errorf("Expected 9 buffered candles reported, got %v", got)
+	}
+
+	// With the database down the evicted candles are dropped, still bounding
+	// memory
+	recorder.down = true
+	dropped := testutil.ToFloat64(evictedCandles.WithLabelValues("dropped"))
+	batch := []*models.Trade{trade(11), trade(12)}
+	if err := aggregator.ProcessTrades(context.Background(), batch); err != nil {
+		t.Fatal(err)
+	}
+	if len(aggregator.candles) != 9 {
+		t.Errorf("Expected 9 candles left, got %d", len(aggregator.candles))
+	}
+	if got := testutil.ToFloat64(evictedCandles.WithLabelValues("dropped")) - dropped; got != 2 {
+		t.Errorf("Expected 2 dropped evictions counted, got %v", got)
+	}
+	if _, ok := aggregator.candles[bucketKey("BTCUSDT", start.Add(12*time.Minute))]; !ok {
+		t.Error("Expected the newest candle kept")
+	}
+}
+
+func TestTradeAggregator_SpillsEvictedCandles(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	redisStore, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer redisStore.Close()
+
+	recorder := &candleRecorder{down: true}
+	aggregator := NewTradeAggregator(redisStore, nil)
+	aggregator.candleStore = recorder
+	aggregator.SetMaxBufferedCandles(10)
+
+	// With the database down the 2 oldest candles are evicted into Redis
+	start := time.Now().Truncate(time.Minute).Add(-time.Hour)
+	spilled := testutil.ToFloat64(evictedCandles.WithLabelValues("spilled"))
+	for minute := 0; minute < 11; minute++ {
+		trade := &models.Trade{Symbol: "BTCUSDT", Price: "100", Quantity: "1", Time: start.Add(time.Duration(minute) * time.Minute)}
+		if err := aggregator.ProcessTrade(context.Background(), trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := testutil.ToFloat64(evictedCandles.WithLabelValues("spilled")) - spilled; got != 2 {
+		t.Errorf("Expected 2 spilled evictions counted, got %v", got)
+	}
+	if err := aggregator.flushCandles(context.Background()); err == nil {
+		t.Error("Expected the flush to fail while the database is down")
+	}
+	if entries, _ := mr.List("test:candles:spill"); len(entries) != 2 {
+		t.Fatalf("Expected 2 spilled candles, got %d", len(entries))
+	}
+
+	// Once it is back the spilled candles are written first
+	recorder.down = false
+	if err := aggregator.flushCandles(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.written) != 11 {
+		t.Fatalf("Expected all 11 candles written, got %d", len(recorder.written))
+	}
+	sort.Strings(recorder.written[:2])
+	want := []string{bucketKey("BTCUSDT", start), bucketKey("BTCUSDT", start.Add(time.Minute))}
+	if !reflect.DeepEqual(recorder.written[:2], want) {
+		t.Errorf("Expected the spilled candles written first, got %v", recorder.written[:2])
+	}
+	if mr.Exists("test:candles:spill") {
+		t.Error("Expected the spill list emptied")
+	}
+}
+
+func TestTradeAggregator_WriteBreaker(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	const cooldown = 50 * time.Millisecond
+	recorder := &candleRecorder{down: true}
+	aggregator := NewTradeAggregator(nil, nil)
+	aggregator.candleStore = recorder
+	aggregator.SetWriteBreaker(config.BreakerConfig{MaxFailures: 3, Window: time.Minute, Cooldown: cooldown})
+
+	start := time.Now().Truncate(time.Minute).Add(-time.Hour)
+	for minute := 0; minute < 5; minute++ {
+		trade := &models.Trade{Symbol: "BTCUSDT", Price: "100", Quantity: "1", Time: start.Add(time.Duration(minute) * time.Minute)}
+		if err := aggregator.ProcessTrade(context.Background(), trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+	flush := func() {
+		t.Helper()
+		if err := aggregator.Flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(state breaker.State, attempts, buffered int) {
+		t.Helper()
+		if got := aggregator.writeBreaker.State(); got != state {
+			t.Errorf("Expected the breaker %s, got %s", state, got)
+		}
+		if recorder.attempts != attempts {
+			t.Errorf("Expected %d writes tried, got %d", attempts, recorder.attempts)
+		}
+		if len(aggregator.candles) != buffered {
+			t.Errorf("Expected %d candles buffered, got %d", buffered, len(aggregator.candles))
+		}
+	}
+
+	// The third failure opens the breaker and the flush stops there
+	flush()
+	expect(breaker.Open, 3, 5)
+
+	// While open, flushes write nothing and the candles stay buffered
+	flush()
+	expect(breaker.Open, 3, 5)
+
+	// After the cool-down a single probe is tried; its failure reopens
+	var probeStates []breaker.State
+	recorder.onWrite = func() { probeStates = append(probeStates, aggregator.writeBreaker.State()) }
+	time.Sleep(cooldown + 10*time.Millisecond)
+	flush()
+	expect(breaker.Open, 4, 5)
+
+	// Once PostgreSQL is back the probe closes the breaker and the flush
+	// writes everything buffered
+	recorder.down = false
+	time.Sleep(cooldown + 10*time.Millisecond)
+	flush()
+	expect(breaker.Closed, 9, 0)
+	if len(recorder.written) != 5 {
+		t.Errorf("Expected all 5 candles written, got %d", len(recorder.written))
+	}
+	if len(probeStates) < 2 || probeStates[0] != breaker.HalfOpen || probeStates[1] != breaker.HalfOpen {
+		t.Errorf("Expected the probes written half-open, got %v", probeStates)
+	}
+}
+
+// BenchmarkProcessTrades compares per-trade and batched aggregation with
+// concurrent callers spread over 200 symbols
+func BenchmarkProcessTrades(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	now := time.Now()
+	trades := make([]*models.Trade, 1000)
+	for i := range trades {
+		trades[i] = &models.Trade{Symbol: fmt.Sprintf("SYM%dUSDT", i%200), Price: "100", Quantity: "1", Time: now}
+	}
+
+	b.Run("PerTrade", func(b *testing.B) {
+		aggregator := NewTradeAggregator(nil, nil)
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				aggregator.ProcessTrade(context.Background(), trades[i%len(trades)])
+				i++
+			}
+		})
+	})
+
+	b.Run("Batch50", func(b *testing.B) {
+		aggregator := NewTradeAggregator(nil, nil)
+		b.RunParallel(func(pb *testing.PB) {
+			batch := make([]*models.Trade, 0, 50)
+			i := 0
+			for pb.Next() {
+				batch = append(batch, trades[i%len(trades)])
+				i++
+				if len(batch) == cap(batch) {
+					aggregator.ProcessTrades(context.Background(), batch)
+					batch = batch[:0]
+				}
+			}
+			aggregator.ProcessTrades(context.Background(), batch)
+		})
+	})
+}
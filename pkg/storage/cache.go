@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/messaging"
+)
+
+// CachedTradeStore is a TradeStore that serves latest trades and 24h volumes
+// from an in-process read-through cache. Entries are never served more than
+// the configured TTL after they were read, and are dropped early when trades
+// for their symbol are stored through the wrapper or seen on the message bus.
+type CachedTradeStore struct {
+	TradeStore
+
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // Most recently used at the front
+}
+
+// cacheEntry is a cached read result; value is a *models.Trade or a float64
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// Cache key kinds
+const (
+	cacheLatestTrade = "latest:"
+	cacheVolume24h   = "volume:"
+)
+
+// NewCachedTradeStore wraps store with a read cache configured by cfg
+func NewCachedTradeStore(store TradeStore, cfg config.CacheConfig) *CachedTradeStore {
+	return &CachedTradeStore{
+		TradeStore: store,
+		ttl:        cfg.TTL,
+		maxEntries: cfg.MaxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// GetLatestTrade returns the latest trade of a symbol, reading through to the
+// wrapped store when it is not cached or has expired
+func (c *CachedTradeStore) GetLatestTrade(ctx context.Context, symbol string) (*models.Trade, error) {
+	symbol = strings.ToUpper(symbol)
+	key := cacheLatestTrade + symbol
+
+	if value, ok := c.get(key); ok {
+		return copyTrade(value.(*models.Trade)), nil
+	}
+
+	trade, err := c.TradeStore.GetLatestTrade(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, copyTrade(trade))
+	return trade, nil
+}
+
+// Get24hVolume returns the 24h volume of a symbol, reading through to the
+// wrapped store when it is not cached or has expired
+func (c *CachedTradeStore) Get24hVolume(ctx context.Context, symbol string) (float64, error) {
+	symbol = strings.ToUpper(symbol)
+	key := cacheVolume24h + symbol
+
+	if value, ok := c.get(key); ok {
+		return value.(float64), nil
+	}
+
+	volume, err := c.TradeStore.Get24hVolume(ctx, symbol)
+	if err != nil {
+		return 0, err
+	}
+	c.set(key, volume)
+	return volume, nil
+}
+
+// StoreTrade stores a trade and invalidates the cached reads of its symbol
+func (c *CachedTradeStore) StoreTrade(ctx context.Context, trade *models.Trade) error {
+	err := c.TradeStore.StoreTrade(ctx, trade)
+	c.Invalidate(trade.Symbol)
+	return err
+}
+
+// StoreRawTrade stores a raw trade and invalidates the cached reads of its symbol
+func (c *CachedTradeStore) StoreRawTrade(ctx context.Context, symbol string, data []byte) error {
+	err := c.TradeStore.StoreRawTrade(ctx, symbol, data)
+	c.Invalidate(symbol)
+	return err
+}
+
+// Update24hVolume recalculates the 24h volume and invalidates the cached reads of its symbol
+func (c *CachedTradeStore) Update24hVolume(ctx context.Context, symbol string) error {
+	err := c.TradeStore.Update24hVolume(ctx, symbol)
+	c.Invalidate(symbol)
+	return err
+}
+
+// Invalidate drops the cached reads of a symbol
+func (c *CachedTradeStore) Invalidate(symbol string) {
+	symbol = strings.ToUpper(symbol)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range []string{cacheLatestTrade + symbol, cacheVolume24h + symbol} {
+		if elem, ok := c.entries[key]; ok {
+			c.remove(elem)
+		}
+	}
+}
+
+// InvalidateOnTrades drops the cached reads of each symbol as its trades
+// arrive on bus, until ctx is cancelled. Use it when trades are stored by
+// another process, so reads see new prices before the TTL runs out.
+func (c *CachedTradeStore) InvalidateOnTrades(ctx context.Context, bus messaging.MessageBus) error {
+	return bus.SubscribeAll(ctx, func(env *messaging.Envelope) error {
+		c.Invalidate(env.Payload.Data.Symbol)
+		return nil
+	})
+}
+
+// Len returns the number of cached entries, including expired ones not yet evicted
+func (c *CachedTradeStore) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// get returns the unexpired cached value of key
+func (c *CachedTradeStore) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.value, true
+}
+
+// set caches value under key, evicting the least recently used entries
+// beyond the maximum
+func (c *CachedTradeStore) set(key string, value interface{}) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, value: value, expires: c.now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove drops an entry; the caller must hold mu
+func (c *CachedTradeStore) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
+
+// copyTrade returns a copy of trade so callers cannot modify cached values
+func copyTrade(trade *models.Trade) *models.Trade {
+	if trade == nil {
+		return nil
+	}
+	copied := *trade
+	return &copied
+}
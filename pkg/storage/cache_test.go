@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/config"
+	"binance-redis-streamer/pkg/messaging"
+)
+
+// countingStore answers reads with fixed values and counts them
+type countingStore struct {
+	TradeStore
+	mu          sync.Mutex
+	price       string
+	volume      float64
+	tradeReads  atomic.Int64
+	volumeReads atomic.Int64
+}
+
+func (s *countingStore) GetLatestTrade(ctx context.Context, symbol string) (*models.Trade, error) {
+	s.tradeReads.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &models.Trade{Symbol: symbol, Price: s.price}, nil
+}
+
+func (s *countingStore) Get24hVolume(ctx context.Context, symbol string) (float64, error) {
+	s.volumeReads.Add(1)
+	return s.volume, nil
+}
+
+func (s *countingStore) StoreTrade(ctx context.Context, trade *models.Trade) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.price = trade.Price
+	return nil
+}
+
+// newTestCache wraps inner with a cache driven by a controllable clock
+func newTestCache(inner TradeStore, ttl time.Duration, maxEntries int) (*CachedTradeStore, *time.Time) {
+	cache := NewCachedTradeStore(inner, config.CacheConfig{TTL: ttl, MaxEntries: maxEntries})
+	now := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	return cache, &now
+}
+
+func TestCachedTradeStore_StalenessBound(t *testing.T) {
+	inner := &countingStore{price: "100", volume: 5000}
+	cache, now := newTestCache(inner, time.Second, 10)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		trade, err := cache.GetLatestTrade(ctx, "btcusdt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trade.Price != "100" {
+			t.Errorf("Expected price 100, got %s", trade.Price)
+		}
+		if _, err := cache.Get24hVolume(ctx, "BTCUSDT"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.tradeReads.Load() != 1 || inner.volumeReads.Load() != 1 {
+		t.Errorf("Expected 1 read each within the TTL, got %d trade and %d volume reads",
+			inner.tradeReads.Load(), inner.volumeReads.Load())
+	}
+
+	// The price changes behind the cache's back; it may be served until the TTL runs out
+	inner.price = "101"
+
+	*now = now.Add(999 * time.Millisecond)
+	if trade, _ := cache.GetLatestTrade(ctx, "BTCUSDT"); trade.Price != "100" {
+		t.Errorf("Expected cached price 100 just before expiry, got %s", trade.Price)
+	}
+
+	*now = now.Add(time.Millisecond)
+	if trade, _ := cache.GetLatestTrade(ctx, "BTCUSDT"); trade.Price != "101" {
+		t.Errorf("Expected fresh price 101 at expiry, got %s", trade.Price)
+	}
+	if inner.tradeReads.Load() != 2 {
+		t.Errorf("Expected 2 trade reads after expiry, got %d", inner.tradeReads.Load())
+	}
+}
+
+func TestCachedTradeStore_ReturnsCopies(t *testing.T) {
+	cache, _ := newTestCache(&countingStore{price: "100"}, time.Second, 10)
+	ctx := context.Background()
+
+	trade, _ := cache.GetLatestTrade(ctx, "BTCUSDT")
+	trade.Price = "0"
+
+	if again, _ := cache.GetLatestTrade(ctx, "BTCUSDT"); again.Price != "100" {
+		t.Errorf("Expected cached trade to be unaffected by callers, got %s", again.Price)
+	}
+}
+
+func TestCachedTradeStore_InvalidatesOnStore(t *testing.T) {
+	inner := &countingStore{price: "100"}
+	cache, _ := newTestCache(inner, time.Hour, 10)
+	ctx := context.Background()
+
+	if _, err := cache.GetLatestTrade(ctx, "BTCUSDT"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.StoreTrade(ctx, &models.Trade{Symbol: "btcusdt", Price: "102"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if trade, _ := cache.GetLatestTrade(ctx, "BTCUSDT"); trade.Price != "102" {
+		t.Errorf("Expected price 102 after storing a trade, got %s", trade.Price)
+	}
+}
+
+func TestCachedTradeStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	inner := &countingStore{price: "100"}
+	cache, _ := newTestCache(inner, time.Hour, 2)
+	ctx := context.Background()
+
+	cache.GetLatestTrade(ctx, "BTCUSDT")
+	cache.GetLatestTrade(ctx, "ETHUSDT")
+	cache.GetLatestTrade(ctx, "BTCUSDT") // ETHUSDT is now least recently used
+	cache.GetLatestTrade(ctx, "SOLUSDT")
+
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.Len())
+	}
+
+	reads := inner.tradeReads.Load()
+	cache.GetLatestTrade(ctx, "BTCUSDT")
+	if inner.tradeReads.Load() != reads {
+		t.Error("Expected BTCUSDT to stay cached")
+	}
+	cache.GetLatestTrade(ctx, "ETHUSDT")
+	if inner.tradeReads.Load() != reads+1 {
+		t.Error("Expected ETHUSDT to have been evicted")
+	}
+}
+
+func TestCachedTradeStore_ZeroTTLDisablesCaching(t *testing.T) {
+	inner := &countingStore{price: "100"}
+	cache, _ := newTestCache(inner, 0, 10)
+
+	for i := 0; i < 3; i++ {
+		cache.GetLatestTrade(context.Background(), "BTCUSDT")
+	}
+	if inner.tradeReads.Load() != 3 || cache.Len() != 0 {
+		t.Errorf("Expected every read to pass through, got %d reads and %d entries", inner.tradeReads.Load(), cache.Len())
+	}
+}
+
+func TestCachedTradeStore_ConcurrentAccess(t *testing.T) {
+	inner := &countingStore{price: "100"}
+	cache := NewCachedTradeStore(inner, config.CacheConfig{TTL: time.Millisecond, MaxEntries: 3})
+	ctx := context.Background()
+	symbols := []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT"}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				symbol := symbols[(g+i)%len(symbols)]
+				switch i % 5 {
+				case 0:
+					cache.StoreTrade(ctx, &models.Trade{Symbol: symbol, Price: "100"})
+				case 1:
+					cache.Invalidate(symbol)
+				case 2:
+					if _, err := cache.Get24hVolume(ctx, symbol); err != nil {
+						t.Error(err)
+					}
+				default:
+					trade, err := cache.GetLatestTrade(ctx, symbol)
+					if err != nil || trade.Symbol != symbol {
+						t.Errorf("Unexpected trade %+v for %s: %v", trade, symbol, err)
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if cache.Len() > 3 {
+		t.Errorf("Expected at most 3 entries, got %d", cache.Len())
+	}
+}
+
+func TestCachedTradeStore_InvalidateOnTrades(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	bus := messaging.NewRedisPubSub(client)
+
+	inner := &countingStore{price: "100"}
+	cache, _ := newTestCache(inner, time.Hour, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cache.InvalidateOnTrades(ctx, bus)
+
+	if _, err := cache.GetLatestTrade(ctx, "BTCUSDT"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Publish until the subscriber is up and has dropped the entry
+	deadline := time.Now().Add(2 * time.Second)
+	for cache.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected cache entry to be invalidated by a published trade")
+		}
+		trade := &models.AggTradeEvent{Data: models.TradeData{Symbol: "BTCUSDT", Price: "101"}}
+		if err := bus.Publish(ctx, trade); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
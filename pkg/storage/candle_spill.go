@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// SpilledCandle is a live candle parked in Redis because it could not be
+// written to PostgreSQL when evicted from the aggregator's buffer
+type SpilledCandle struct {
+	Symbol string
+	Candle *models.Candle
+}
+
+// candleSpillKey returns the list of spilled candles, oldest first
+func (s *RedisStore) candleSpillKey() string {
+	return fmt.Sprintf("%scandles:spill", s.config.Redis.KeyPrefix)
+}
+
+// SpillCandles appends candles to the spill list
+func (s *RedisStore) SpillCandles(ctx context.Context, candles []SpilledCandle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(candles))
+	for i, candle := range candles {
+		data, err := json.Marshal(candle)
+		if err != nil {
+			return fmt.Errorf("failed to marshal spilled candle: %w", err)
+		}
+		values[i] = data
+	}
+	if err := s.client.RPush(ctx, s.candleSpillKey(), values...).Err(); err != nil {
+		return fmt.Errorf("failed to spill candles: %w", err)
+	}
+	return nil
+}
+
+// SpilledCandles returns up to n of the oldest spilled candles without
+// removing them; see TrimSpilledCandles
+func (s *RedisStore) SpilledCandles(ctx context.Context, n int) ([]SpilledCandle, error) {
+	entries, err := s.client.LRange(ctx, s.candleSpillKey(), 0, int64(n)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spilled candles: %w", err)
+	}
+	candles := make([]SpilledCandle, 0, len(entries))
+	for _, entry := range entries {
+		var candle SpilledCandle
+		if err := json.Unmarshal([]byte(entry), &candle); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal spilled candle: %w", err)
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// TrimSpilledCandles removes the n oldest spilled candles once written
+func (s *RedisStore) TrimSpilledCandles(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if err := s.client.LTrim(ctx, s.candleSpillKey(), int64(n), -1).Err(); err != nil {
+		return fmt.Errorf("failed to trim spilled candles: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+// Magic bytes starting gzip and zstd data; plain JSON entries start with {
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// gzipWriters recycles gzip writers, which are costly to allocate per entry
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// The zstd encoder and decoder are shared: EncodeAll and DecodeAll are safe
+// for concurrent use. Entries are single small frames, so they skip the
+// checksum and decode within a small window.
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func initZstd() {
+	var err error
+	if zstdEncoder, err = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderCRC(false)); err != nil {
+		panic(fmt.Sprintf("zstd encoder: %v", err))
+	}
+	if zstdDecoder, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxWindow(1<<20)); err != nil {
+		panic(fmt.Sprintf("zstd decoder: %v", err))
+	}
+}
+
+// compressEntry compresses a history entry with codec, one of the
+// config.Compression codecs; an empty codec leaves it as is
+func compressEntry(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case "", config.CompressionNone:
+		return data, nil
+	case config.CompressionGzip:
+		var buf bytes.Buffer
+		w := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(w)
+		w.Reset(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to gzip trade: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("failed to gzip trade: %w", err)
+		}
+		return buf.Bytes(), nil
+	case config.CompressionZstd:
+		zstdOnce.Do(initZstd)
+		return zstdEncoder.EncodeAll(data, make([]byte, 0, len(data))), nil
+	default:
+		return nil, fmt.Errorf("unknown compression codec %q", codec)
+	}
+}
+
+// decompressEntry returns a history entry decompressed with the codec its
+// magic bytes name; entries without one are returned as they are
+func decompressEntry(entry []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(entry, zstdMagic):
+		zstdOnce.Do(initZstd)
+		data, err := zstdDecoder.DecodeAll(entry, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress zstd trade: %w", err)
+		}
+		return data, nil
+	case bytes.HasPrefix(entry, gzipMagic):
+		reader, err := gzip.NewReader(bytes.NewReader(entry))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress trade: %w", err)
+		}
+		return data, nil
+	default:
+		return entry, nil
+	}
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/pkg/config"
+)
+
+// sampleRawTrades returns n raw trade messages shaped like the stream's,
+// trading at now
+func sampleRawTrades(n int, now time.Time) [][]byte {
+	trades := make([][]byte, n)
+	for i := range trades {
+		trades[i] = []byte(fmt.Sprintf(`{"stream":"btcusdt@trade","data":{"e":"trade","E":%d,"s":"BTCUSDT","t":%d,"p":"%.2f","q":"%.5f","b":%d,"a":%d,"T":%d,"m":%t,"M":true}}`,
+			now.UnixMilli(), 3000000000+i, 67000+float64(i%500)/100, 0.001+float64(i%97)/1000,
+			28000000000+i*2, 28000000001+i*2, now.UnixMilli()-int64(n-i), i%3 == 0))
+	}
+	return trades
+}
+
+var codecs = []string{config.CompressionNone, config.CompressionGzip, config.CompressionZstd}
+
+func TestCompressEntry_RoundTrip(t *testing.T) {
+	for _, codec := range codecs {
+		for _, data := range sampleRawTrades(20, time.Now()) {
+			compressed, err := compressEntry(codec, data)
+			if err != nil {
+				t.Fatalf("%s: %v", codec, err)
+			}
+			if codec != config.CompressionNone && bytes.Equal(compressed, data) {
+				t.Fatalf("%s: expected the entry compressed", codec)
+			}
+			got, err := decompressEntry(compressed)
+			if err != nil {
+				t.Fatalf("%s: %v", codec, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("%s round trip = %s, want %s", codec, got, data)
+			}
+		}
+	}
+
+	if _, err := compressEntry("lz4", []byte("{}")); err == nil {
+		t.Error("Expected an unknown codec to fail")
+	}
+	if _, err := decompressEntry(append(append([]byte{}, zstdMagic...), "corrupt"...)); err == nil {
+		t.Error("Expected a corrupt zstd entry to fail")
+	}
+}
+
+func TestRedisStore_StoreRawTradeCompression(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	// Entries of every codec are read back together, as after the codec is
+	// switched on a running history
+	ctx := context.Background()
+	now := time.Now()
+	trades := sampleRawTrades(len(codecs), now)
+	for i, codec := range codecs {
+		store.config.Redis.CompressionCodec = codec
+		if err := store.StoreRawTrade(ctx, "BTCUSDT", trades[i]); err != nil {
+			t.Fatalf("%s: %v", codec, err)
+		}
+	}
+
+	members, err := mr.ZMembers("test:trade:BTCUSDT:history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gzipped, zstded int
+	for _, member := range members {
+		switch {
+		case bytes.HasPrefix([]byte(member), gzipMagic):
+			gzipped++
+		case bytes.HasPrefix([]byte(member), zstdMagic):
+			zstded++
+		}
+	}
+	if gzipped != 1 || zstded != 1 {
+		t.Errorf("Expected one gzip and one zstd entry, got %d and %d", gzipped, zstded)
+	}
+
+	history, err := store.GetTradeHistory(ctx, "BTCUSDT", now.Add(-time.Minute), now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != len(codecs) {
+		t.Fatalf("Expected %d trades, got %d", len(codecs), len(history))
+	}
+	for _, event := range history {
+		if event.Data.Symbol != "BTCUSDT" || event.Data.Price == "" {
+			t.Errorf("Unexpected trade %+v", event.Data)
+		}
+	}
+}
+
+// BenchmarkCompressEntry compares the codecs on a sample of raw trades,
+// reporting the compressed size as a ratio of the JSON
+func BenchmarkCompressEntry(b *testing.B) {
+	trades := sampleRawTrades(1000, time.Now())
+	var raw int
+	for _, data := range trades {
+		raw += len(data)
+	}
+
+	for _, codec := range codecs {
+		b.Run(codec, func(b *testing.B) {
+			var compressed int
+			for _, data := range trades {
+				entry, err := compressEntry(codec, data)
+				if err != nil {
+					b.Fatal(err)
+				}
+				compressed += len(entry)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := compressEntry(codec, trades[i%len(trades)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(compressed)/float64(raw), "ratio")
+		})
+	}
+}
+
+// BenchmarkDecompressEntry measures reading a raw trade back per codec
+func BenchmarkDecompressEntry(b *testing.B) {
+	trades := sampleRawTrades(1000, time.Now())
+	for _, codec := range codecs {
+		entries := make([][]byte, len(trades))
+		for i, data := range trades {
+			var err error
+			if entries[i], err = compressEntry(codec, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.Run(codec, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := decompressEntry(entries[i%len(entries)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// ConnectionEventKey returns the Redis list holding stream connection
+// events, newest first
+func ConnectionEventKey(prefix string) string {
+	return prefix + "events:connection"
+}
+
+// RecordConnectionEvent pushes a connection event onto the event log,
+// trimming the oldest beyond the configured maximum
+func (s *RedisStore) RecordConnectionEvent(ctx context.Context, event *models.ConnectionEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal connection event: %w", err)
+	}
+
+	key := ConnectionEventKey(s.config.Redis.KeyPrefix)
+	pipe := s.client.TxPipeline()
+	pipe.LPush(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, s.config.Ingestion.ConnectionEventsMax-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record connection event: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// GetConnectionEvents returns the stored connection events, oldest first
+func (s *RedisStore) GetConnectionEvents(ctx context.Context) ([]*models.ConnectionEvent, error) {
+	items, err := s.client.LRange(ctx, ConnectionEventKey(s.config.Redis.KeyPrefix), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connection events: %w: %w", ErrUnavailable, err)
+	}
+
+	events := make([]*models.ConnectionEvent, 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		var event models.ConnectionEvent
+		if err := json.Unmarshal([]byte(items[i]), &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal connection event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+// Outage is a span during which a symbol group was disconnected
+type Outage struct {
+	Group string
+	Start time.Time
+	End   time.Time
+	Error string // Why the connection dropped
+	Open  bool   // Still disconnected at the end of the period
+}
+
+// Duration returns how long the outage lasted
+func (o Outage) Duration() time.Duration {
+	return o.End.Sub(o.Start)
+}
+
+// UptimeReport summarizes the connection events of a period
+type UptimeReport struct {
+	Start    time.Time
+	End      time.Time
+	Outages  []Outage      // Clipped to the period, oldest first
+	Downtime time.Duration // Time at least one group was disconnected
+	Longest  Outage
+}
+
+// Uptime returns the share of the period without downtime, from 0 to 1
+func (r *UptimeReport) Uptime() float64 {
+	period := r.End.Sub(r.Start)
+	if period <= 0 {
+		return 1
+	}
+	return 1 - float64(r.Downtime)/float64(period)
+}
+
+// SummarizeUptime computes the outages of each group between start and end
+// from events, oldest first. An outage runs from a group's disconnect to its
+// next connect, or until the group is stopped or streaming starts afresh, as
+// after a crash; one that began before start counts from start, and one
+// still open lasts until end. Overlapping outages of different groups count
+// once toward the downtime.
+func SummarizeUptime(events []*models.ConnectionEvent, start, end time.Time) *UptimeReport {
+	report := &UptimeReport{Start: start, End: end}
+
+	down := make(map[string]*models.ConnectionEvent)
+	addOutage := func(from *models.ConnectionEvent, to time.Time, open bool) {
+		outage := Outage{Group: from.Group, Start: from.Time, End: to, Error: from.Error, Open: open}
+		if outage.Start.Before(start) {
+			outage.Start = start
+		}
+		if outage.End.After(end) {
+			outage.End, outage.Open = end, true
+		}
+		if outage.End.After(outage.Start) {
+			report.Outages = append(report.Outages, outage)
+		}
+	}
+
+	for _, event := range events {
+		if event.Time.After(end) {
+			break
+		}
+		switch event.Kind {
+		case models.ConnectionDown:
+			// A repeated disconnect keeps the outage that is already open
+			if down[event.Group] == nil {
+				down[event.Group] = event
+			}
+		case models.ConnectionUp, models.ConnectionStopped:
+			if from := down[event.Group]; from != nil {
+				addOutage(from, event.Time, false)
+				delete(down, event.Group)
+			}
+		case models.StreamingStarted:
+			for group, from := range down {
+				addOutage(from, event.Time, false)
+				delete(down, group)
+			}
+		}
+	}
+	for _, from := range down {
+		addOutage(from, end, true)
+	}
+
+	sort.Slice(report.Outages, func(i, j int) bool {
+		return report.Outages[i].Start.Before(report.Outages[j].Start)
+	})
+
+	// Merge the outages, which are sorted by start, into the downtime
+	var spanStart, spanEnd time.Time
+	for i, outage := range report.Outages {
+		if outage.Duration() > report.Longest.Duration() {
+			report.Longest = outage
+		}
+		if i > 0 && !outage.Start.After(spanEnd) {
+			if outage.End.After(spanEnd) {
+				spanEnd = outage.End
+			}
+			continue
+		}
+		report.Downtime += spanEnd.Sub(spanStart)
+		spanStart, spanEnd = outage.Start, outage.End
+	}
+	report.Downtime += spanEnd.Sub(spanStart)
+	return report
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestRedisStore_ConnectionEvents(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+	store.config.Ingestion.ConnectionEventsMax = 3
+
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		kind := models.ConnectionUp
+		if i%2 == 1 {
+			kind = models.ConnectionDown
+		}
+		event := &models.ConnectionEvent{Kind: kind, Group: "btcusdt-1", Time: base.Add(time.Duration(i) * time.Minute)}
+		if err := store.RecordConnectionEvent(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := store.GetConnectionEvents(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected the log trimmed to 3 events, got %d", len(events))
+	}
+	for i, event := range events {
+		if want := base.Add(time.Duration(i+2) * time.Minute); !event.Time.Equal(want) {
+			t.Errorf("Event %d at %v, want %v (oldest first)", i, event.Time, want)
+		}
+	}
+	if !mr.Exists("test:events:connection") {
+		t.Error("Expected the events in the test:events:connection list")
+	}
+}
+
+func TestSummarizeUptime(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	event := func(kind, group string, d time.Duration) *models.ConnectionEvent {
+		return &models.ConnectionEvent{Kind: kind, Group: group, Time: at(d)}
+	}
+	up, down := models.ConnectionUp, models.ConnectionDown
+
+	events := []*models.ConnectionEvent{
+		// Down since before the period: counts from its start
+		event(down, "a", -time.Hour),
+		event(up, "a", 10*time.Minute),
+		// Two groups down at overlapping times count once
+		event(down, "a", 2*time.Hour),
+		event(down, "b", 2*time.Hour+30*time.Minute),
+		event(down, "a", 2*time.Hour+45*time.Minute), // Already down
+		event(up, "a", 3*time.Hour),
+		event(up, "b", 4*time.Hour),
+		// Still down at the end of the period
+		event(down, "b", 23*time.Hour+50*time.Minute),
+		// After the period
+		event(up, "b", 25*time.Hour),
+	}
+	report := SummarizeUptime(events, start, end)
+
+	if len(report.Outages) != 4 {
+		t.Fatalf("Expected 4 outages, got %+v", report.Outages)
+	}
+	if want := 10*time.Minute + 2*time.Hour + 10*time.Minute; report.Downtime != want {
+		t.Errorf("Downtime = %v, want %v", report.Downtime, want)
+	}
+	if report.Longest.Group != "b" || report.Longest.Duration() != 90*time.Minute {
+		t.Errorf("Longest = %+v, want b's 90 minute outage", report.Longest)
+	}
+	if last := report.Outages[3]; !last.Open || !last.End.Equal(end) {
+		t.Errorf("Expected the last outage open until the end, got %+v", last)
+	}
+	if got, want := report.Uptime(), 1-float64(report.Downtime)/float64(24*time.Hour); got != want {
+		t.Errorf("Uptime() = %v, want %v", got, want)
+	}
+
+	// Outages entirely before the period are left out
+	report = SummarizeUptime(events[:2], at(time.Hour), end)
+	if len(report.Outages) != 0 || report.Downtime != 0 || report.Uptime() != 1 {
+		t.Errorf("Expected no downtime, got %+v", report)
+	}
+	// A group stopped while down, e.g. regrouped under a new name, and
+	// groups left down by a crashed process end their outages
+	events = []*models.ConnectionEvent{
+		event(down, "a", time.Hour),
+		event(models.ConnectionStopped, "a", time.Hour+10*time.Minute),
+		event(up, "a-b", time.Hour+10*time.Minute),
+		event(down, "a-b", 2*time.Hour),
+		event(down, "c", 2*time.Hour),
+		event(models.StreamingStarted, "", 2*time.Hour+20*time.Minute),
+		event(up, "a-b-c", 2*time.Hour+21*time.Minute),
+	}
+	report = SummarizeUptime(events, start, end)
+	if len(report.Outages) != 3 {
+		t.Fatalf("Expected 3 outages, got %+v", report.Outages)
+	}
+	for _, outage := range report.Outages {
+		if outage.Open {
+			t.Errorf("Expected every outage ended, got %+v", outage)
+		}
+	}
+	if want := 30 * time.Minute; report.Downtime != want {
+		t.Errorf("Downtime = %v, want %v", report.Downtime, want)
+	}
+}
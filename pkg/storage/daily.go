@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// DailyCandle is one UTC day of a symbol rolled up from its minute candles
+type DailyCandle struct {
+	Candle   models.Candle // Timestamp is midnight UTC of the day
+	Minutes  int           // Minute candles rolled up
+	Complete bool          // False while the day is still in progress
+}
+
+// utcDay returns midnight UTC of the day t falls on
+func utcDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// rollupDay merges the minute candles of day into one daily candle, or
+// returns nil when none of them have trades. The day is complete once now is
+// past its end.
+func rollupDay(day time.Time, candles []*models.Candle, now time.Time) *DailyCandle {
+	// MergeWith takes the close from the later of two candles, so merge in
+	// time order
+	sorted := append([]*models.Candle(nil), candles...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	day = utcDay(day)
+	merged := models.NewCandle(day)
+	minutes := 0
+	for _, candle := range sorted {
+		if candle.TradeCount == 0 {
+			continue
+		}
+		merged.MergeWith(candle)
+		minutes++
+	}
+	if minutes == 0 {
+		return nil
+	}
+
+	merged.Timestamp = day
+	return &DailyCandle{
+		Candle:   *merged,
+		Minutes:  minutes,
+		Complete: !now.Before(day.Add(24 * time.Hour)),
+	}
+}
+
+// RollupDay recomputes the daily candle of symbol for the UTC day of day
+// from its minute candles and stores it, replacing any earlier rollup. Days
+// without minute candles, e.g. because they were pruned, keep their stored
+// rollup; the returned candle is then nil.
+func (s *PostgresStore) RollupDay(ctx context.Context, symbol string, day time.Time) (*DailyCandle, error) {
+	symbol = strings.ToUpper(symbol)
+	start := utcDay(day)
+	// Timestamps are stored with microsecond precision and the query's
+	// range is inclusive
+	end := start.Add(24*time.Hour - time.Microsecond)
+
+	var candles []*models.Candle
+	err := s.StreamHistoricalCandles(ctx, symbol, start, end, func(candle *models.Candle) error {
+		candles = append(candles, candle)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	daily := rollupDay(start, candles, time.Now())
+	if daily == nil {
+		return nil, nil
+	}
+
+	c := daily.Candle
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO trade_candles_daily (
+			exchange, symbol, day, open_price, high_price, low_price,
+			close_price, volume, trade_count, minutes, complete, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+		ON CONFLICT (exchange, symbol, day) DO UPDATE SET
+			open_price = EXCLUDED.open_price,
+			high_price = EXCLUDED.high_price,
+			low_price = EXCLUDED.low_price,
+			close_price = EXCLUDED.close_price,
+			volume = EXCLUDED.volume,
+			trade_count = EXCLUDED.trade_count,
+			minutes = EXCLUDED.minutes,
+			complete = EXCLUDED.complete,
+			updated_at = NOW()`,
+		s.exchange, symbol, start, c.OpenPrice, c.HighPrice, c.LowPrice,
+		c.ClosePrice, c.Volume, c.TradeCount, daily.Minutes, daily.Complete,
+	); err != nil {
+		return nil, fmt.Errorf("failed to store daily candle: %w", err)
+	}
+	return daily, nil
+}
+
+// RollupSince recomputes the daily candles of every symbol with minute
+// candles since since, for each UTC day from since's through today, and
+// returns how many were stored
+func (s *PostgresStore) RollupSince(ctx context.Context, since time.Time) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT symbol FROM trade_candles
+		WHERE exchange = $1 AND timestamp >= $2`,
+		s.exchange, utcDay(since),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list symbols with candles: %w", err)
+	}
+	var symbols []string
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan symbol: %w", err)
+		}
+		symbols = append(symbols, symbol)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list symbols with candles: %w", err)
+	}
+
+	stored := 0
+	today := utcDay(time.Now())
+	for _, symbol := range symbols {
+		for day := utcDay(since); !day.After(today); day = day.Add(24 * time.Hour) {
+			daily, err := s.RollupDay(ctx, symbol, day)
+			if err != nil {
+				return stored, err
+			}
+			if daily != nil {
+				stored++
+			}
+		}
+	}
+
+	if s.debug {
+		log.Printf("[DEBUG] Rolled up %d daily candles for %d symbols", stored, len(symbols))
+	}
+	return stored, nil
+}
+
+// GetDailyCandles returns the stored daily candles of symbol for the UTC
+// days from start's through end's, oldest first
+func (s *PostgresStore) GetDailyCandles(ctx context.Context, symbol string, start, end time.Time) ([]*DailyCandle, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT day, open_price, high_price, low_price, close_price,
+			volume, trade_count, minutes, complete
+		FROM trade_candles_daily
+		WHERE exchange = $1 AND symbol = $2 AND day BETWEEN $3 AND $4
+		ORDER BY day ASC`,
+		s.exchange, strings.ToUpper(symbol), utcDay(start), utcDay(end),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily candles: %w", err)
+	}
+	defer rows.Close()
+
+	var days []*DailyCandle
+	for rows.Next() {
+		daily := &DailyCandle{}
+		c := &daily.Candle
+		if err := rows.Scan(
+			&c.Timestamp, &c.OpenPrice, &c.HighPrice, &c.LowPrice, &c.ClosePrice,
+			&c.Volume, &c.TradeCount, &daily.Minutes, &daily.Complete,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan daily candle: %w", err)
+		}
+		c.Timestamp = utcDay(c.Timestamp)
+		days = append(days, daily)
+	}
+	return days, rows.Err()
+}
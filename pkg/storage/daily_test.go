@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// seededMinuteCandles returns a deterministic random walk of minute candles
+// over day, shuffled so the rollup cannot rely on their order
+func seededMinuteCandles(seed int64, day time.Time, minutes int) []*models.Candle {
+	rng := rand.New(rand.NewSource(seed))
+	candles := make([]*models.Candle, minutes)
+	price := 100.0
+	for i := range candles {
+		open := price
+		price += rng.Float64()*2 - 1
+		high := math.Max(open, price) + rng.Float64()
+		low := math.Min(open, price) - rng.Float64()
+		candles[i] = &models.Candle{
+			Timestamp:  day.Add(time.Duration(i) * time.Minute),
+			OpenPrice:  strconv.FormatFloat(open, 'f', 4, 64),
+			HighPrice:  strconv.FormatFloat(high, 'f', 4, 64),
+			LowPrice:   strconv.FormatFloat(low, 'f', 4, 64),
+			ClosePrice: strconv.FormatFloat(price, 'f', 4, 64),
+			Volume:     strconv.FormatFloat(rng.Float64()*10, 'f', 4, 64),
+			TradeCount: int64(1 + rng.Intn(50)),
+		}
+	}
+	return candles
+}
+
+func TestRollupDay(t *testing.T) {
+	day := time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC)
+	for seed := int64(1); seed <= 5; seed++ {
+		candles := seededMinuteCandles(seed, day, 1440)
+
+		high, low := math.Inf(-1), math.Inf(1)
+		var volume float64
+		var trades int64
+		for _, c := range candles {
+			h, _ := strconv.ParseFloat(c.HighPrice, 64)
+			l, _ := strconv.ParseFloat(c.LowPrice, 64)
+			v, _ := strconv.ParseFloat(c.Volume, 64)
+			high, low = math.Max(high, h), math.Min(low, l)
+			volume += v
+			trades += c.TradeCount
+		}
+		first, last := candles[0], candles[len(candles)-1]
+
+		shuffled := append([]*models.Candle(nil), candles...)
+		rand.New(rand.NewSource(seed)).Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+
+		daily := rollupDay(day.Add(13*time.Hour), shuffled, day.Add(48*time.Hour))
+		if daily == nil {
+			t.Fatalf("seed %d: rollupDay() = nil", seed)
+		}
+		c := daily.Candle
+		if !c.Timestamp.Equal(day) {
+			t.Errorf("seed %d: Timestamp = %v, want %v", seed, c.Timestamp, day)
+		}
+		if c.OpenPrice != first.OpenPrice || c.ClosePrice != last.ClosePrice {
+			t.Errorf("seed %d: open/close = %s/%s, want %s/%s", seed, c.OpenPrice, c.ClosePrice, first.OpenPrice, last.ClosePrice)
+		}
+		gotHigh, _ := strconv.ParseFloat(c.HighPrice, 64)
+		gotLow, _ := strconv.ParseFloat(c.LowPrice, 64)
+		if gotHigh != high || gotLow != low {
+			t.Errorf("seed %d: high/low = %v/%v, want %v/%v", seed, gotHigh, gotLow, high, low)
+		}
+		gotVolume, _ := strconv.ParseFloat(c.Volume, 64)
+		if math.Abs(gotVolume-volume) > 1e-6 {
+			t.Errorf("seed %d: volume = %v, want %v", seed, gotVolume, volume)
+		}
+		if c.TradeCount != trades || daily.Minutes != 1440 || !daily.Complete {
+			t.Errorf("seed %d: trades = %d, minutes = %d, complete = %v, want %d, 1440, true",
+				seed, c.TradeCount, daily.Minutes, daily.Complete, trades)
+		}
+	}
+}
+
+func TestRollupDay_PartialDay(t *testing.T) {
+	day := time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC)
+	candles := seededMinuteCandles(9, day, 600)
+	candles = append(candles, &models.Candle{Timestamp: day.Add(601 * time.Minute), Volume: "0"})
+
+	daily := rollupDay(day, candles, day.Add(10*time.Hour+5*time.Minute))
+	if daily == nil {
+		t.Fatal("rollupDay() = nil")
+	}
+	if daily.Complete {
+		t.Error("Expected a day in progress to be incomplete")
+	}
+	if daily.Minutes != 600 {
+		t.Errorf("Minutes = %d, want 600 (empty candles skipped)", daily.Minutes)
+	}
+
+	if daily := rollupDay(day, nil, day); daily != nil {
+		t.Errorf("rollupDay() without candles = %+v, want nil", daily)
+	}
+}
+
+func TestPostgresStore_RollupDay(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+	defer store.db.Exec("DELETE FROM trade_candles_daily")
+
+	ctx := context.Background()
+	day := time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC)
+	candles := seededMinuteCandles(3, day, 120)
+	for _, candle := range candles[:60] {
+		if err := store.StoreCandleData(ctx, "BTCUSDT", candle, CandleSourceLive); err != nil {
+			t.Fatalf("StoreCandleData() error = %v", err)
+		}
+	}
+
+	if _, err := store.RollupDay(ctx, "BTCUSDT", day); err != nil {
+		t.Fatalf("RollupDay() error = %v", err)
+	}
+
+	// Recomputing after more minutes arrive overwrites the day
+	for _, candle := range candles[60:] {
+		if err := store.StoreCandleData(ctx, "BTCUSDT", candle, CandleSourceLive); err != nil {
+			t.Fatalf("StoreCandleData() error = %v", err)
+		}
+	}
+	if _, err := store.RollupDay(ctx, "BTCUSDT", day.Add(12*time.Hour)); err != nil {
+		t.Fatalf("RollupDay() error = %v", err)
+	}
+
+	days, err := store.GetDailyCandles(ctx, "BTCUSDT", day, day)
+	if err != nil {
+		t.Fatalf("GetDailyCandles() error = %v", err)
+	}
+	if len(days) != 1 {
+		t.Fatalf("Expected 1 daily candle, got %d", len(days))
+	}
+	if days[0].Minutes != 120 || days[0].Candle.ClosePrice != candles[119].ClosePrice || !days[0].Complete {
+		t.Errorf("Got %d minutes closing at %s, want 120 closing at %s", days[0].Minutes, days[0].Candle.ClosePrice, candles[119].ClosePrice)
+	}
+}
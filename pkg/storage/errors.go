@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by stores, wrapped with details; branch on them with
+// errors.Is
+var (
+	// ErrNotFound means the store holds no data for the request, e.g. a
+	// symbol without trades
+	ErrNotFound = errors.New("not found")
+	// ErrUnavailable means the backing store could not be reached or failed
+	// to answer
+	ErrUnavailable = errors.New("storage unavailable")
+	// ErrCorruptData means a stored record could not be decoded
+	ErrCorruptData = errors.New("corrupt data")
+	// ErrStale means the stored data is too old to be current, e.g. the
+	// latest trade of a delisted symbol. It also matches ErrNotFound.
+	ErrStale = fmt.Errorf("stale data: %w", ErrNotFound)
+)
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations failed: %v", err)
+	}
+	if len(migrations) < 2 {
+		t.Fatalf("Expected at least 2 migrations, got %d", len(migrations))
+	}
+
+	for i, m := range migrations {
+		if strings.TrimSpace(m.sql) == "" {
+			t.Errorf("Migration %s is empty", m.version)
+		}
+		if i > 0 && migrations[i-1].version >= m.version {
+			t.Errorf("Migrations out of order: %s before %s", migrations[i-1].version, m.version)
+		}
+	}
+
+	if migrations[0].version != "001_create_trade_candles" {
+		t.Errorf("Expected the initial schema first, got %s", migrations[0].version)
+	}
+
+	// Databases without TimescaleDB must still migrate
+	for _, m := range migrations {
+		if strings.Contains(m.sql, "create_hypertable") && !strings.Contains(m.sql, "pg_extension") {
+			t.Errorf("Migration %s creates a hypertable without checking for TimescaleDB", m.version)
+		}
+	}
+
+	// Compressed hypertables reject primary key changes
+	for _, m := range migrations {
+		if strings.Contains(m.sql, "ADD PRIMARY KEY") && m.version > "003_enable_timescale" &&
+			!strings.Contains(m.sql, "decompress_chunk") {
+			t.Errorf("Migration %s changes the primary key without decompressing candles", m.version)
+		}
+	}
+}
+
+func TestMigrate_WaitsForTheMigrationLock(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	// Another process is migrating
+	ctx := context.Background()
+	conn, err := store.db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- migrate(ctx, store.db) }()
+	select {
+	case err := <-done:
+		t.Fatalf("Expected migrate to wait for the lock, returned %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLock); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("migrate failed after the lock was released: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for migrate once the lock was released")
+	}
+}
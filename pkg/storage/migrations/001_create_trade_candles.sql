@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS trade_candles (
+	symbol TEXT NOT NULL,
+	timestamp TIMESTAMPTZ NOT NULL,
+	open_price NUMERIC NOT NULL,
+	high_price NUMERIC NOT NULL,
+	low_price NUMERIC NOT NULL,
+	close_price NUMERIC NOT NULL,
+	volume NUMERIC NOT NULL,
+	trade_count BIGINT NOT NULL,
+	PRIMARY KEY (symbol, timestamp)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trade_candles_time
+	ON trade_candles(timestamp);
@@ -0,0 +1,7 @@
+-- Key candles by exchange so several venues can share the table.
+-- Existing rows were all ingested from Binance.
+ALTER TABLE trade_candles
+	ADD COLUMN IF NOT EXISTS exchange TEXT NOT NULL DEFAULT 'binance';
+
+ALTER TABLE trade_candles DROP CONSTRAINT IF EXISTS trade_candles_pkey;
+ALTER TABLE trade_candles ADD PRIMARY KEY (exchange, symbol, timestamp);
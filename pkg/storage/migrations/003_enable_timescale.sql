@@ -0,0 +1,11 @@
+-- Partition candles by time when TimescaleDB is installed. Without the
+-- extension this is a no-op; createTables converts the table later if
+-- TimescaleDB is installed after this migration ran.
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb') THEN
+		PERFORM create_hypertable('trade_candles', 'timestamp',
+			migrate_data => TRUE, if_not_exists => TRUE);
+	END IF;
+END
+$$;
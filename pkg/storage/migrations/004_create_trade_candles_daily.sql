@@ -0,0 +1,17 @@
+-- One candle per symbol and UTC day, rolled up from trade_candles. Rows
+-- outlive the minute candles they were computed from once those are pruned.
+CREATE TABLE IF NOT EXISTS trade_candles_daily (
+	exchange TEXT NOT NULL,
+	symbol TEXT NOT NULL,
+	day DATE NOT NULL,
+	open_price NUMERIC NOT NULL,
+	high_price NUMERIC NOT NULL,
+	low_price NUMERIC NOT NULL,
+	close_price NUMERIC NOT NULL,
+	volume NUMERIC NOT NULL,
+	trade_count BIGINT NOT NULL,
+	minutes INTEGER NOT NULL,
+	complete BOOLEAN NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (exchange, symbol, day)
+);
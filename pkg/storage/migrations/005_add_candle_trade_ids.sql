@@ -0,0 +1,4 @@
+-- Range of trade IDs a candle was rebuilt from, so rebuilding from the same
+-- trades again is a no-op. NULL for candles written by the aggregator.
+ALTER TABLE trade_candles ADD COLUMN IF NOT EXISTS first_trade_id BIGINT;
+ALTER TABLE trade_candles ADD COLUMN IF NOT EXISTS last_trade_id BIGINT;
@@ -0,0 +1,53 @@
+-- Key candles by the writer that produced them, so the aggregator's live
+-- flushes, its historical migration and rebuild-candles each keep their own
+-- row per minute instead of adding to each other's volume. Existing rows
+-- with a trade ID range were written by rebuild-candles.
+--
+-- Compressed chunks reject the primary key change and are slow to update,
+-- so with TimescaleDB compression enabled the chunks are decompressed and
+-- compression is turned off around the change, then turned back on. The
+-- compression policy is added again when the streamer starts.
+DO $$
+DECLARE
+	compressed BOOLEAN := FALSE;
+BEGIN
+	IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb') THEN
+		SELECT COALESCE(bool_or(compression_enabled), FALSE) INTO compressed
+		FROM timescaledb_information.hypertables
+		WHERE hypertable_name = 'trade_candles';
+	END IF;
+	IF compressed THEN
+		PERFORM remove_compression_policy('trade_candles', if_exists => TRUE);
+		PERFORM decompress_chunk(chunk, if_compressed => TRUE)
+		FROM show_chunks('trade_candles') AS chunk;
+		ALTER TABLE trade_candles SET (timescaledb.compress = FALSE);
+	END IF;
+
+	ALTER TABLE trade_candles
+		ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'live';
+	UPDATE trade_candles SET source = 'backfill' WHERE first_trade_id IS NOT NULL;
+
+	ALTER TABLE trade_candles DROP CONSTRAINT IF EXISTS trade_candles_pkey;
+	ALTER TABLE trade_candles ADD PRIMARY KEY (exchange, symbol, timestamp, source);
+
+	IF compressed THEN
+		ALTER TABLE trade_candles SET (
+			timescaledb.compress,
+			timescaledb.compress_segmentby = 'exchange, symbol',
+			timescaledb.compress_orderby = 'timestamp DESC'
+		);
+	END IF;
+END
+$$;
+
+-- One candle per minute: the one built from the most trades, as every
+-- writer can miss trades but none counts one twice. Ties go to backfill,
+-- then migration, then live. Filters on exchange, symbol and timestamp are
+-- pushed below DISTINCT ON, so range queries still use the primary key.
+CREATE OR REPLACE VIEW trade_candles_reconciled AS
+SELECT DISTINCT ON (exchange, symbol, timestamp)
+	exchange, symbol, timestamp, open_price, high_price, low_price,
+	close_price, volume, trade_count, source, first_trade_id, last_trade_id
+FROM trade_candles
+ORDER BY exchange, symbol, timestamp, trade_count DESC,
+	CASE source WHEN 'backfill' THEN 0 WHEN 'migration' THEN 1 ELSE 2 END;
@@ -0,0 +1,36 @@
+-- Key live candles by the streamer that flushed them too, so two streamers
+-- aggregating the same trades, e.g. during a rolling deploy, each keep their
+-- own row instead of adding to one. trade_candles_reconciled picks the row
+-- built from the most trades. Migrated and rebuilt candles are written with
+-- an empty writer. Compression is handled as in 006_add_candle_source.
+DO $$
+DECLARE
+	compressed BOOLEAN := FALSE;
+BEGIN
+	IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb') THEN
+		SELECT COALESCE(bool_or(compression_enabled), FALSE) INTO compressed
+		FROM timescaledb_information.hypertables
+		WHERE hypertable_name = 'trade_candles';
+	END IF;
+	IF compressed THEN
+		PERFORM remove_compression_policy('trade_candles', if_exists => TRUE);
+		PERFORM decompress_chunk(chunk, if_compressed => TRUE)
+		FROM show_chunks('trade_candles') AS chunk;
+		ALTER TABLE trade_candles SET (timescaledb.compress = FALSE);
+	END IF;
+
+	ALTER TABLE trade_candles
+		ADD COLUMN IF NOT EXISTS writer TEXT NOT NULL DEFAULT '';
+
+	ALTER TABLE trade_candles DROP CONSTRAINT IF EXISTS trade_candles_pkey;
+	ALTER TABLE trade_candles ADD PRIMARY KEY (exchange, symbol, timestamp, source, writer);
+
+	IF compressed THEN
+		ALTER TABLE trade_candles SET (
+			timescaledb.compress,
+			timescaledb.compress_segmentby = 'exchange, symbol',
+			timescaledb.compress_orderby = 'timestamp DESC'
+		);
+	END IF;
+END
+$$;
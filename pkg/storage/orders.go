@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// StoreOrderUpdate saves the latest state of an order, replacing earlier
+// updates of the same order
+func (s *RedisStore) StoreOrderUpdate(ctx context.Context, update *models.OrderUpdate) error {
+	data, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("failed to marshal order update: %w", err)
+	}
+
+	key := fmt.Sprintf("%sorders:%s", s.config.Redis.KeyPrefix, strings.ToUpper(update.Symbol))
+	if err := s.client.HSet(ctx, key, strconv.FormatInt(update.OrderID, 10), data).Err(); err != nil {
+		return fmt.Errorf("failed to store order update: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// GetOrders returns the latest state of each order of symbol, oldest order
+// first
+func (s *RedisStore) GetOrders(ctx context.Context, symbol string) ([]*models.OrderUpdate, error) {
+	key := fmt.Sprintf("%sorders:%s", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
+	values, err := s.client.HVals(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w: %w", ErrUnavailable, err)
+	}
+
+	orders := make([]*models.OrderUpdate, 0, len(values))
+	for _, value := range values {
+		var order models.OrderUpdate
+		if err := json.Unmarshal([]byte(value), &order); err != nil {
+			return nil, fmt.Errorf("failed to decode order: %w: %w", ErrCorruptData, err)
+		}
+		orders = append(orders, &order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrderID < orders[j].OrderID })
+	return orders, nil
+}
+
+// StoreAccountPosition saves the balances of an account update, keyed by
+// asset. Assets missing from the update keep their last balance.
+func (s *RedisStore) StoreAccountPosition(ctx context.Context, position *models.AccountPosition) error {
+	if len(position.Balances) == 0 {
+		return nil
+	}
+
+	fields := make([]interface{}, 0, 2*len(position.Balances))
+	for _, balance := range position.Balances {
+		data, err := json.Marshal(balance)
+		if err != nil {
+			return fmt.Errorf("failed to marshal balance: %w", err)
+		}
+		fields = append(fields, strings.ToUpper(balance.Asset), data)
+	}
+
+	key := fmt.Sprintf("%saccount:balances", s.config.Redis.KeyPrefix)
+	if err := s.client.HSet(ctx, key, fields...).Err(); err != nil {
+		return fmt.Errorf("failed to store balances: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// GetBalances returns the last stored balance of each asset
+func (s *RedisStore) GetBalances(ctx context.Context) (map[string]models.Balance, error) {
+	key := fmt.Sprintf("%saccount:balances", s.config.Redis.KeyPrefix)
+	values, err := s.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w: %w", ErrUnavailable, err)
+	}
+
+	balances := make(map[string]models.Balance, len(values))
+	for asset, value := range values {
+		var balance models.Balance
+		if err := json.Unmarshal([]byte(value), &balance); err != nil {
+			return nil, fmt.Errorf("failed to decode balance of %s: %w: %w", asset, ErrCorruptData, err)
+		}
+		balances[asset] = balance
+	}
+	return balances, nil
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestRedisStore_OrderUpdates(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	updates := []*models.OrderUpdate{
+		{Symbol: "ethbtc", OrderID: 9, Status: "NEW"},
+		{Symbol: "ETHBTC", OrderID: 4, Status: "NEW"},
+		{Symbol: "ETHBTC", OrderID: 9, Status: "FILLED", FilledQuantity: "1"},
+	}
+	for _, update := range updates {
+		if err := store.StoreOrderUpdate(ctx, update); err != nil {
+			t.Fatalf("StoreOrderUpdate failed: %v", err)
+		}
+	}
+
+	// Later updates replace earlier ones of the same order
+	orders, err := store.GetOrders(ctx, "ethbtc")
+	if err != nil {
+		t.Fatalf("GetOrders failed: %v", err)
+	}
+	if len(orders) != 2 || orders[0].OrderID != 4 || orders[1].OrderID != 9 || orders[1].Status != "FILLED" {
+		t.Errorf("Expected orders 4 and a filled 9, got %+v", orders)
+	}
+}
+
+func TestRedisStore_AccountPositions(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	first := &models.AccountPosition{Balances: []models.Balance{
+		{Asset: "BTC", Free: "1", Locked: "0"},
+		{Asset: "USDT", Free: "500", Locked: "100"},
+	}}
+	second := &models.AccountPosition{Balances: []models.Balance{{Asset: "BTC", Free: "0.5", Locked: "0.5"}}}
+	for _, position := range []*models.AccountPosition{first, second} {
+		if err := store.StoreAccountPosition(ctx, position); err != nil {
+			t.Fatalf("StoreAccountPosition failed: %v", err)
+		}
+	}
+
+	// Assets missing from an update keep their balance
+	balances, err := store.GetBalances(ctx)
+	if err != nil {
+		t.Fatalf("GetBalances failed: %v", err)
+	}
+	if len(balances) != 2 || balances["BTC"].Free != "0.5" || balances["USDT"].Locked != "100" {
+		t.Errorf("Unexpected balances: %+v", balances)
+	}
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// poolStatsInterval is how often PostgresStore publishes its connection
+// pool stats
+const poolStatsInterval = 10 * time.Second
+
+// PostgreSQL connection pool metrics, from sql.DBStats. A rising wait count
+// means writers queue for connections, e.g. during batch flushes.
+var (
+	postgresOpenConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "binance_postgres_open_connections",
+		Help: "Open PostgreSQL connections, in use and idle.",
+	})
+	postgresInUseConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "binance_postgres_in_use_connections",
+		Help: "PostgreSQL connections currently in use.",
+	})
+	postgresIdleConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "binance_postgres_idle_connections",
+		Help: "Idle PostgreSQL connections.",
+	})
+	postgresWaitCount = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "binance_postgres_waits_total",
+		Help: "Total connections waited for because the pool was at its limit.",
+	})
+	postgresWaitDuration = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "binance_postgres_wait_seconds_total",
+		Help: "Total time spent waiting for a PostgreSQL connection.",
+	})
+)
+
+// postgresCandleWriteSeconds is the latency of StoreCandleData's upsert
+var postgresCandleWriteSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "binance_postgres_candle_write_seconds",
+	Help:    "Latency of candle writes to PostgreSQL.",
+	Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14), // 0.5ms to ~4s
+})
+
+// publishPoolStats sets the pool gauges from stats and adds the waits since
+// last, the stats published before, to the wait counters
+func publishPoolStats(stats, last sql.DBStats) {
+	postgresOpenConnections.Set(float64(stats.OpenConnections))
+	postgresInUseConnections.Set(float64(stats.InUse))
+	postgresIdleConnections.Set(float64(stats.Idle))
+	postgresWaitCount.Add(float64(stats.WaitCount - last.WaitCount))
+	postgresWaitDuration.Add((stats.WaitDuration - last.WaitDuration).Seconds())
+}
+
+// startPoolStats publishes the pool stats every interval until Close
+func (s *PostgresStore) startPoolStats(interval time.Duration) {
+	s.stopStats = make(chan struct{})
+	s.statsDone = make(chan struct{})
+
+	go func() {
+		defer close(s.statsDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last sql.DBStats
+		publish := func() {
+			stats := s.db.Stats()
+			publishPoolStats(stats, last)
+			last = stats
+		}
+
+		publish()
+		for {
+			select {
+			case <-s.stopStats:
+				return
+			case <-ticker.C:
+				publish()
+			}
+		}
+	}()
+}
+
+// stopPoolStats stops the collector started by startPoolStats, if any
+func (s *PostgresStore) stopPoolStats() {
+	if s.stopStats == nil {
+		return
+	}
+	s.closeOnce.Do(func() { close(s.stopStats) })
+	<-s.statsDone
+}
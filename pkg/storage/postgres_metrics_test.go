@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// blockingConnector connects to a fake database whose Exec blocks until
+// release is closed, so tests can hold pool connections without PostgreSQL
+type blockingConnector struct {
+	release chan struct{}
+}
+
+func (c *blockingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &blockingConn{release: c.release}, nil
+}
+
+func (c *blockingConnector) Driver() driver.Driver {
+	return nil
+}
+
+type blockingConn struct {
+	release chan struct{}
+}
+
+func (c *blockingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	select {
+	case <-c.release:
+		return driver.RowsAffected(1), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *blockingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *blockingConn) Close() error { return nil }
+
+func (c *blockingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestPostgresStore_PoolStats(t *testing.T) {
+	release := make(chan struct{})
+	db := sql.OpenDB(&blockingConnector{release: release})
+	db.SetMaxOpenConns(1)
+
+	store := &PostgresStore{db: db, exchange: "binance"}
+	store.startPoolStats(5 * time.Millisecond)
+	defer store.Close()
+
+	writesBefore := candleWrites(t)
+	waitsBefore := testutil.ToFloat64(postgresWaitCount)
+	waitedBefore := testutil.ToFloat64(postgresWaitDuration)
+	candle := &models.Candle{Timestamp: time.Now(), OpenPrice: "1", HighPrice: "1", LowPrice: "1", ClosePrice: "1", Volume: "1", TradeCount: 1}
+
+	// Two writers share one connection: the first holds it, the second waits
+	var writes sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		writes.Add(1)
+		go func() {
+			defer writes.Done()
+			if err := store.StoreCandleData(context.Background(), "BTCUSDT", candle, CandleSourceLive); err != nil {
+				t.Errorf("Failed to store candle: %v", err)
+			}
+		}()
+	}
+
+	waitForGauge(t, "in-use connections", func() bool { return testutil.ToFloat64(postgresInUseConnections) == 1 })
+	waitForGauge(t, "wait count", func() bool { return testutil.ToFloat64(postgresWaitCount) >= waitsBefore+1 })
+	if open := testutil.ToFloat64(postgresOpenConnections); open != 1 {
+		t.Errorf("Expected 1 open connection, got %v", open)
+	}
+
+	close(release)
+	writes.Wait()
+
+	waitForGauge(t, "idle connections", func() bool { return testutil.ToFloat64(postgresInUseConnections) == 0 })
+	waitForGauge(t, "wait duration", func() bool { return testutil.ToFloat64(postgresWaitDuration) > waitedBefore })
+	if writes := candleWrites(t) - writesBefore; writes != 2 {
+		t.Errorf("Expected 2 timed candle writes, got %d", writes)
+	}
+}
+
+// candleWrites returns the number of candle writes timed so far
+func candleWrites(t *testing.T) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := postgresCandleWriteSeconds.Write(&m); err != nil {
+		t.Fatalf("Failed to read the candle write histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+// waitForGauge polls cond until it holds or a second passes
+func waitForGauge(t *testing.T, name string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", name)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
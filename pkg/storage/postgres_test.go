@@ -4,15 +4,17 @@ import (
 	"context"
 	"os"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	"binance-redis-streamer/internal/models"
+	"binance-redis-streamer/pkg/timeutil"
 
 	_ "github.com/lib/pq"
 )
 
-func setupTestPostgres(t *testing.T) (*PostgresStore, func()) {
+func setupTestPostgres(t testing.TB) (*PostgresStore, func()) {
 	// Use test database URL from environment
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
@@ -73,7 +75,7 @@ func TestPostgresStore_StoreCandleData(t *testing.T) {
 	}
 
 	// Test storing new candle
-	err := store.StoreCandleData(ctx, "BTCUSDT", candle)
+	err := store.StoreCandleData(ctx, "BTCUSDT", candle, CandleSourceLive)
 	if err != nil {
 		t.Fatalf("Failed to store candle data: %v", err)
 	}
@@ -89,7 +91,7 @@ func TestPostgresStore_StoreCandleData(t *testing.T) {
 		TradeCount: 150,
 	}
 
-	err = store.StoreCandleData(ctx, "BTCUSDT", updatedCandle)
+	err = store.StoreCandleData(ctx, "BTCUSDT", updatedCandle, CandleSourceLive)
 	if err != nil {
 		t.Fatalf("Failed to update candle data: %v", err)
 	}
@@ -132,3 +134,459 @@ func TestPostgresStore_StoreCandleData(t *testing.T) {
 		t.Errorf("Expected trade count 250, got %d", result.tradeCount)
 	}
 }
+
+func TestPostgresStore_CandleSourcesConverge(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	symbol := "SOURCEUSDT"
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	candle := func(minute int, volume string, trades int64) *models.Candle {
+		return &models.Candle{
+			Timestamp:  base.Add(time.Duration(minute) * time.Minute),
+			OpenPrice:  "100",
+			HighPrice:  "110",
+			LowPrice:   "90",
+			ClosePrice: "105",
+			Volume:     volume,
+			TradeCount: trades,
+		}
+	}
+
+	// Each minute has 10 trades. The aggregator flushes minutes in two parts
+	// but lost the second part of minute 2, while the migration rewrites
+	// full candles of minutes 0 and 1, twice, at the same time.
+	type write struct {
+		candle *models.Candle
+		source CandleSource
+	}
+	writes := []write{
+		{candle(0, "6", 6), CandleSourceLive}, {candle(0, "4", 4), CandleSourceLive},
+		{candle(1, "6", 6), CandleSourceLive}, {candle(1, "4", 4), CandleSourceLive},
+		{candle(2, "6", 6), CandleSourceLive},
+	}
+	for i := 0; i < 2; i++ {
+		writes = append(writes, write{candle(0, "10", 10), CandleSourceMigration}, write{candle(1, "10", 10), CandleSourceMigration})
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(writes))
+	for _, w := range writes {
+		wg.Add(1)
+		go func(w write) {
+			defer wg.Done()
+			errs <- store.StoreCandleData(ctx, symbol, w.candle, w.source)
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("StoreCandleData() error = %v", err)
+		}
+	}
+
+	// A later migration after Redis history was trimmed sees fewer trades
+	if err := store.StoreCandleData(ctx, symbol, candle(0, "5", 5), CandleSourceMigration); err != nil {
+		t.Fatalf("StoreCandleData() error = %v", err)
+	}
+
+	candles, err := store.GetHistoricalCandles(ctx, symbol, base, base.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("GetHistoricalCandles() error = %v", err)
+	}
+	if len(candles) != 3 {
+		t.Fatalf("Expected one candle per minute, got %d", len(candles))
+	}
+	for i, want := range []int64{10, 10, 6} {
+		volume, err := strconv.ParseFloat(candles[i].Volume, 64)
+		if err != nil {
+			t.Fatalf("Failed to parse volume: %v", err)
+		}
+		if candles[i].TradeCount != want || volume != float64(want) {
+			t.Errorf("Minute %d: %d trades, volume %s, want %d of each", i, candles[i].TradeCount, candles[i].Volume, want)
+		}
+	}
+
+	total, err := store.GetRange(ctx, symbol, base, base.Add(3*time.Minute))
+	if err != nil {
+		t.Fatalf("GetRange() error = %v", err)
+	}
+	if total.TradeCount != 26 {
+		t.Errorf("Expected 26 trades over the range, got %d", total.TradeCount)
+	}
+}
+
+func TestPostgresStore_LiveWritersConverge(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	symbol := "WRITERUSDT"
+	minute := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	candle := func(volume string, trades int64) *models.Candle {
+		return &models.Candle{
+			Timestamp:  minute,
+			OpenPrice:  "100",
+			HighPrice:  "110",
+			LowPrice:   "90",
+			ClosePrice: "105",
+			Volume:     volume,
+			TradeCount: trades,
+		}
+	}
+
+	// Two streamers aggregate the same 10 trades, one flushing them in two
+	// parts
+	store.SetWriter("streamer-a")
+	for _, part := range []*models.Candle{candle("6", 6), candle("4", 4)} {
+		if err := store.StoreCandleData(ctx, symbol, part, CandleSourceLive); err != nil {
+			t.Fatalf("StoreCandleData() error = %v", err)
+		}
+	}
+	store.SetWriter("streamer-b")
+	if err := store.StoreCandleData(ctx, symbol, candle("10", 10), CandleSourceLive); err != nil {
+		t.Fatalf("StoreCandleData() error = %v", err)
+	}
+
+	candles, err := store.GetHistoricalCandles(ctx, symbol, minute, minute)
+	if err != nil {
+		t.Fatalf("GetHistoricalCandles() error = %v", err)
+	}
+	if len(candles) != 1 {
+		t.Fatalf("Expected one candle, got %d", len(candles))
+	}
+	volume, err := strconv.ParseFloat(candles[0].Volume, 64)
+	if err != nil {
+		t.Fatalf("Failed to parse volume: %v", err)
+	}
+	if candles[0].TradeCount != 10 || volume != 10 {
+		t.Errorf("Got %d trades, volume %s, want 10 of each", candles[0].TradeCount, candles[0].Volume)
+	}
+}
+
+func TestPostgresStore_LiveHandoverAddsUp(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	symbol := "HANDOVERUSDT"
+	minute := time.Now().Add(-time.Hour).Truncate(time.Minute)
+
+	// The leader flushes its part of the minute on stepping down, and the
+	// replica taking over, under the same default writer, the rest
+	for _, part := range []*models.Candle{
+		{Timestamp: minute, OpenPrice: "100", HighPrice: "110", LowPrice: "95", ClosePrice: "105", Volume: "6", TradeCount: 6},
+		{Timestamp: minute, OpenPrice: "105", HighPrice: "108", LowPrice: "90", ClosePrice: "101", Volume: "4", TradeCount: 4},
+	} {
+		if err := store.StoreCandleData(ctx, symbol, part, CandleSourceLive); err != nil {
+			t.Fatalf("StoreCandleData() error = %v", err)
+		}
+	}
+
+	candles, err := store.GetHistoricalCandles(ctx, symbol, minute, minute)
+	if err != nil {
+		t.Fatalf("GetHistoricalCandles() error = %v", err)
+	}
+	if len(candles) != 1 {
+		t.Fatalf("Expected one candle, got %d", len(candles))
+	}
+	volume, err := strconv.ParseFloat(candles[0].Volume, 64)
+	if err != nil {
+		t.Fatalf("Failed to parse volume: %v", err)
+	}
+	if candles[0].TradeCount != 10 || volume != 10 {
+		t.Errorf("Got %d trades, volume %s, want the two parts' 10 of each", candles[0].TradeCount, candles[0].Volume)
+	}
+}
+
+func TestPostgresStore_GetLatestCandles(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	symbol := "LATESTUSDT"
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	for i := 0; i < 5; i++ {
+		price := strconv.Itoa(100 + i)
+		candle := &models.Candle{Timestamp: base.Add(time.Duration(i) * time.Minute), OpenPrice: price, HighPrice: price, LowPrice: price, ClosePrice: price, Volume: "1", TradeCount: 1}
+		if err := store.StoreCandleData(ctx, symbol, candle, CandleSourceLive); err != nil {
+			t.Fatalf("StoreCandleData() error = %v", err)
+		}
+	}
+
+	candles, err := store.GetLatestCandles(ctx, symbol, base, base.Add(10*time.Minute), 3)
+	if err != nil {
+		t.Fatalf("GetLatestCandles() error = %v", err)
+	}
+	if len(candles) != 3 {
+		t.Fatalf("Expected 3 candles, got %d", len(candles))
+	}
+	for i, candle := range candles {
+		if want := base.Add(time.Duration(i+2) * time.Minute); !candle.Timestamp.Equal(want) {
+			t.Errorf("Candle %d at %s, want %s", i, candle.Timestamp, want)
+		}
+	}
+}
+
+func TestPostgresStore_PruneOlderThan(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+
+	candleAt := func(ts time.Time) *models.Candle {
+		return &models.Candle{
+			Timestamp:  ts,
+			OpenPrice:  "50000.00",
+			HighPrice:  "50000.00",
+			LowPrice:   "50000.00",
+			ClosePrice: "50000.00",
+			Volume:     "1",
+			TradeCount: 1,
+		}
+	}
+
+	// Five old candles, pruned two at a time, and two recent ones
+	for i := 0; i < 5; i++ {
+		if err := store.StoreCandleData(ctx, "BTCUSDT", candleAt(now.Add(-48*time.Hour-time.Duration(i)*time.Minute)), CandleSourceLive); err != nil {
+			t.Fatalf("Failed to store old candle: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := store.StoreCandleData(ctx, "BTCUSDT", candleAt(now.Add(-time.Duration(i)*time.Minute)), CandleSourceLive); err != nil {
+			t.Fatalf("Failed to store recent candle: %v", err)
+		}
+	}
+
+	deleted, err := store.PruneOlderThan(ctx, 24*time.Hour, 2)
+	if err != nil {
+		t.Fatalf("Failed to prune candles: %v", err)
+	}
+	if deleted != 5 {
+		t.Errorf("Expected 5 candles pruned, got %d", deleted)
+	}
+
+	candles, err := store.GetHistoricalCandles(ctx, "BTCUSDT", now.Add(-72*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Failed to get candles: %v", err)
+	}
+	if len(candles) != 2 {
+		t.Fatalf("Expected 2 recent candles to remain, got %d", len(candles))
+	}
+	for _, candle := range candles {
+		if candle.Timestamp.Before(now.Add(-24 * time.Hour)) {
+			t.Errorf("Expected only recent candles, found one at %s", candle.Timestamp)
+		}
+	}
+}
+
+func TestPostgresStore_GetGapAnalysis(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	start := time.Now().UTC().Truncate(time.Minute).Add(-time.Hour)
+
+	// Minutes 0-2, 5 and 6, 30: gaps of minutes 3-4 and 7-29
+	for _, minute := range []int{0, 1, 2, 5, 6, 30} {
+		candle := &models.Candle{
+			Timestamp:  start.Add(time.Duration(minute) * time.Minute),
+			OpenPrice:  "50000.00",
+			HighPrice:  "50000.00",
+			LowPrice:   "50000.00",
+			ClosePrice: "50000.00",
+			Volume:     "1",
+			TradeCount: 1,
+		}
+		if err := store.StoreCandleData(ctx, "BTCUSDT", candle, CandleSourceLive); err != nil {
+			t.Fatalf("Failed to store candle: %v", err)
+		}
+	}
+
+	gaps, err := store.GetGapAnalysis(ctx, "BTCUSDT", start.Add(-time.Hour), start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to analyse gaps: %v", err)
+	}
+	want := []TimeGap{
+		{Start: start.Add(3 * time.Minute), End: start.Add(5 * time.Minute), Duration: 2 * time.Minute},
+		{Start: start.Add(7 * time.Minute), End: start.Add(30 * time.Minute), Duration: 23 * time.Minute},
+	}
+	if len(gaps) != len(want) {
+		t.Fatalf("Expected %d gaps, got %+v", len(want), gaps)
+	}
+	for i := range want {
+		if !gaps[i].Start.Equal(want[i].Start) || !gaps[i].End.Equal(want[i].End) || gaps[i].Duration != want[i].Duration {
+			t.Errorf("Gap %d = %+v, want %+v", i, gaps[i], want[i])
+		}
+	}
+}
+
+func TestPostgresStore_EnableTimescaleCompression(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	timescale, err := store.IsTimescaleDB(ctx)
+	if err != nil {
+		t.Fatalf("IsTimescaleDB() error = %v", err)
+	}
+
+	err = store.EnableTimescaleCompression(ctx, 7*24*time.Hour)
+	if timescale && err != nil {
+		t.Errorf("EnableTimescaleCompression() error = %v", err)
+	}
+	if !timescale && err == nil {
+		t.Error("Expected an error without TimescaleDB")
+	}
+	if err := store.EnableTimescaleCompression(ctx, 0); err == nil {
+		t.Error("Expected an error for a zero delay")
+	}
+}
+
+// BenchmarkGetHistoricalCandles30Days reads a 30-day range of minute
+// candles. Run it against plain PostgreSQL and TimescaleDB to compare:
+//
+//	TEST_DATABASE_URL=... go test ./pkg/storage -run '^$' -bench 30Days
+func BenchmarkGetHistoricalCandles30Days(b *testing.B) {
+	store, cleanup := setupTestPostgres(b)
+	defer cleanup()
+	store.SetDebug(false)
+
+	ctx := context.Background()
+	end := time.Now().UTC().Truncate(time.Minute)
+	start := end.Add(-30 * 24 * time.Hour)
+
+	// 90 days of candles for 3 symbols, so the range is a slice of the table
+	if _, err := store.db.ExecContext(ctx, `
+		INSERT INTO trade_candles (symbol, timestamp, open_price, high_price, low_price,
+			close_price, volume, trade_count, exchange)
+		SELECT s, ts, 50000, 50010, 49990, 50005, 1.5, 10, $2
+		FROM unnest(ARRAY['BTCUSDT', 'ETHUSDT', 'BNBUSDT']) AS s,
+			generate_series($1::timestamptz - interval '90 days', $1::timestamptz, interval '1 minute') AS ts
+		ON CONFLICT DO NOTHING`,
+		end, store.exchange,
+	); err != nil {
+		b.Fatalf("Failed to seed candles: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		candles, err := store.GetHistoricalCandles(ctx, "BTCUSDT", start, end)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(candles) < 30*24*60 {
+			b.Fatalf("Expected 30 days of candles, got %d", len(candles))
+		}
+	}
+}
+
+func TestBucketSeconds(t *testing.T) {
+	tests := []struct {
+		interval string
+		want     int64
+		wantErr  bool
+	}{
+		{interval: "1m", want: 60},
+		{interval: "5m", want: 300},
+		{interval: "4h", want: 4 * 3600},
+		{interval: "1d", want: 86400},
+		{interval: "1w", want: 7 * 86400},
+		{interval: "30s", wantErr: true},
+		{interval: "1mo", wantErr: true},
+		{interval: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := bucketSeconds(tt.interval)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("bucketSeconds(%q) = %d, want an error", tt.interval, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("bucketSeconds(%q) = %d, %v, want %d", tt.interval, got, err, tt.want)
+		}
+	}
+}
+
+func TestPostgresStore_GetAggregatedCandlesAligned(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	// A candle every six hours over two UTC days, each priced by its index
+	ctx := context.Background()
+	symbol := "BOUNDARYUSDT"
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 8; i++ {
+		price := strconv.Itoa(100 + i)
+		candle := &models.Candle{
+			Timestamp:  base.Add(time.Duration(i) * 6 * time.Hour),
+			OpenPrice:  price,
+			HighPrice:  price,
+			LowPrice:   price,
+			ClosePrice: price,
+			Volume:     "1",
+			TradeCount: 1,
+		}
+		if err := store.StoreCandleData(ctx, symbol, candle, CandleSourceLive); err != nil {
+			t.Fatalf("Failed to store candle: %v", err)
+		}
+	}
+
+	type bucket struct {
+		start       time.Time
+		open, close string
+		trades      int64
+	}
+	tests := []struct {
+		boundary string
+		want     []bucket
+	}{
+		{boundary: "UTC", want: []bucket{
+			{base, "100", "103", 4},
+			{base.Add(24 * time.Hour), "104", "107", 4},
+		}},
+		// Days begin at 16:00 UTC the day before
+		{boundary: "UTC+8", want: []bucket{
+			{base.Add(-8 * time.Hour), "100", "102", 3},
+			{base.Add(16 * time.Hour), "103", "106", 4},
+			{base.Add(40 * time.Hour), "107", "107", 1},
+		}},
+		// Days begin at 05:00 UTC
+		{boundary: "UTC-5", want: []bucket{
+			{base.Add(-19 * time.Hour), "100", "100", 1},
+			{base.Add(5 * time.Hour), "101", "104", 4},
+			{base.Add(29 * time.Hour), "105", "107", 3},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.boundary, func(t *testing.T) {
+			offset, err := timeutil.ParseDayBoundary(tt.boundary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			candles, err := store.GetAggregatedCandlesAligned(ctx, symbol, base, base.Add(48*time.Hour-time.Minute), "1d", offset)
+			if err != nil {
+				t.Fatalf("GetAggregatedCandlesAligned() error = %v", err)
+			}
+			if len(candles) != len(tt.want) {
+				t.Fatalf("Got %d daily candles, want %d", len(candles), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := candles[i]
+				if !got.Timestamp.Equal(want.start) {
+					t.Errorf("Candle %d starts at %v, want %v", i, got.Timestamp.UTC(), want.start)
+				}
+				if got.OpenPrice != want.open || got.ClosePrice != want.close || got.TradeCount != want.trades {
+					t.Errorf("Candle %d = open %s close %s trades %d, want open %s close %s trades %d",
+						i, got.OpenPrice, got.ClosePrice, got.TradeCount, want.open, want.close, want.trades)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// symbolPrecisionsKey is the hash of the price and quantity decimals of each
+// symbol, by upper-case symbol
+func (s *RedisStore) symbolPrecisionsKey() string {
+	return fmt.Sprintf("%ssymbols:precision", s.config.Redis.KeyPrefix)
+}
+
+// StoreSymbolPrecisions caches the precisions of symbols, replacing those
+// already cached for the same symbols
+func (s *RedisStore) StoreSymbolPrecisions(ctx context.Context, precisions map[string]models.Precision) error {
+	if len(precisions) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(precisions))
+	for symbol, precision := range precisions {
+		data, err := json.Marshal(precision)
+		if err != nil {
+			return fmt.Errorf("failed to marshal precision of %s: %w", symbol, err)
+		}
+		fields[strings.ToUpper(symbol)] = data
+	}
+	if err := s.client.HSet(ctx, s.symbolPrecisionsKey(), fields).Err(); err != nil {
+		return fmt.Errorf("failed to store symbol precisions: %w", err)
+	}
+	return nil
+}
+
+// GetSymbolPrecisions returns the cached precisions by upper-case symbol.
+// Entries that fail to decode are logged and skipped.
+func (s *RedisStore) GetSymbolPrecisions(ctx context.Context) (map[string]models.Precision, error) {
+	fields, err := s.client.HGetAll(ctx, s.symbolPrecisionsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get symbol precisions: %w", err)
+	}
+
+	precisions := make(map[string]models.Precision, len(fields))
+	for symbol, data := range fields {
+		var precision models.Precision
+		if err := json.Unmarshal([]byte(data), &precision); err != nil {
+			log.Printf("Warning: skipping precision of %s: %v", symbol, err)
+			continue
+		}
+		precisions[symbol] = precision
+	}
+	return precisions, nil
+}
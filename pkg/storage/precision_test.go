@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestRedisStore_SymbolPrecisions(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.StoreSymbolPrecisions(ctx, map[string]models.Precision{
+		"btcusdt":  {Price: 2, Quantity: 5},
+		"SHIBUSDT": {Price: 4, Quantity: 0},
+	}); err != nil {
+		t.Fatalf("StoreSymbolPrecisions failed: %v", err)
+	}
+	// A later refresh replaces the precisions it lists and keeps the others
+	if err := store.StoreSymbolPrecisions(ctx, map[string]models.Precision{"SHIBUSDT": {Price: 8, Quantity: 0}}); err != nil {
+		t.Fatalf("StoreSymbolPrecisions failed: %v", err)
+	}
+	mr.HSet("test:symbols:precision", "BADUSDT", "not json")
+
+	got, err := store.GetSymbolPrecisions(ctx)
+	if err != nil {
+		t.Fatalf("GetSymbolPrecisions failed: %v", err)
+	}
+	want := map[string]models.Precision{"BTCUSDT": {Price: 2, Quantity: 5}, "SHIBUSDT": {Price: 8, Quantity: 0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSymbolPrecisions() = %v, want %v", got, want)
+	}
+}
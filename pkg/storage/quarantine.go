@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// maxQuarantinedPayload is how much of a rejected payload is kept
+const maxQuarantinedPayload = 4096
+
+// rejectedPayloads counts payloads rejected before storage by reason
+var rejectedPayloads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "binance_rejected_payloads_total",
+	Help: "Payloads rejected before storage, by reason",
+}, []string{"reason"})
+
+// QuarantineKey returns the Redis list holding rejected payloads, newest
+// first
+func QuarantineKey(prefix string) string {
+	return prefix + "quarantine:payloads"
+}
+
+// RejectReason returns the metric label of why a payload was rejected with
+// err
+func RejectReason(err error) string {
+	switch {
+	case errors.Is(err, models.ErrMissingSymbol):
+		return "missing_symbol"
+	case errors.Is(err, models.ErrMissingTradeTime):
+		return "missing_trade_time"
+	case errors.Is(err, models.ErrInvalidNumber):
+		return "invalid_number"
+	case errors.Is(err, ErrInvalidTradeTime):
+		return "invalid_trade_time"
+	default:
+		return "malformed"
+	}
+}
+
+// QuarantinePayload counts a payload rejected with cause and pushes it onto
+// the quarantine list, trimming the oldest beyond the configured maximum
+func (s *RedisStore) QuarantinePayload(ctx context.Context, payload []byte, cause error) error {
+	reason := RejectReason(cause)
+	rejectedPayloads.WithLabelValues(reason).Inc()
+
+	if len(payload) > maxQuarantinedPayload {
+		payload = payload[:maxQuarantinedPayload]
+	}
+	data, err := json.Marshal(&models.QuarantinedPayload{
+		Reason:  reason,
+		Error:   cause.Error(),
+		Payload: string(payload),
+		Time:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantined payload: %w", err)
+	}
+
+	key := QuarantineKey(s.config.Redis.KeyPrefix)
+	pipe := s.client.TxPipeline()
+	pipe.LPush(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, s.config.Redis.QuarantineMaxLen-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to quarantine payload: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// GetQuarantinedPayloads returns the quarantined payloads, newest first
+func (s *RedisStore) GetQuarantinedPayloads(ctx context.Context) ([]*models.QuarantinedPayload, error) {
+	items, err := s.client.LRange(ctx, QuarantineKey(s.config.Redis.KeyPrefix), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantined payloads: %w: %w", ErrUnavailable, err)
+	}
+
+	payloads := make([]*models.QuarantinedPayload, 0, len(items))
+	for _, item := range items {
+		var payload models.QuarantinedPayload
+		if err := json.Unmarshal([]byte(item), &payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal quarantined payload: %w: %w", ErrCorruptData, err)
+		}
+		payloads = append(payloads, &payload)
+	}
+	return payloads, nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestRedisStore_QuarantinePayload(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+	store.config.Redis.QuarantineMaxLen = 2
+
+	ctx := context.Background()
+	rejected := testutil.ToFloat64(rejectedPayloads.WithLabelValues("missing_symbol"))
+	for i := 0; i < 3; i++ {
+		cause := fmt.Errorf("%w: s is %q", models.ErrMissingSymbol, "")
+		if err := store.QuarantinePayload(ctx, []byte(fmt.Sprintf(`{"n":%d}`, i)), cause); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.QuarantinePayload(ctx, []byte(strings.Repeat("x", 2*maxQuarantinedPayload)), fmt.Errorf("bad frame")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := testutil.ToFloat64(rejectedPayloads.WithLabelValues("missing_symbol")) - rejected; got != 3 {
+		t.Errorf("Expected 3 rejections counted, got %v", got)
+	}
+	payloads, err := store.GetQuarantinedPayloads(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 2 {
+		t.Fatalf("Expected the quarantine trimmed to 2 payloads, got %d", len(payloads))
+	}
+	if p := payloads[0]; p.Reason != "malformed" || len(p.Payload) != maxQuarantinedPayload {
+		t.Errorf("Expected the newest payload malformed and truncated, got %s with %d bytes", p.Reason, len(p.Payload))
+	}
+	if p := payloads[1]; p.Reason != "missing_symbol" || p.Payload != `{"n":2}` || p.Error == "" {
+		t.Errorf("Unexpected quarantined payload %+v", p)
+	}
+	if !mr.Exists("test:quarantine:payloads") {
+		t.Error("Expected the payloads in the test:quarantine:payloads list")
+	}
+}
+
+func TestRejectReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: %w: no data.T", ErrInvalidTradeTime, models.ErrMissingTradeTime), "missing_trade_time"},
+		{fmt.Errorf("%w: too far ahead", ErrInvalidTradeTime), "invalid_trade_time"},
+		{fmt.Errorf("%w: price %q", models.ErrInvalidNumber, "abc"), "invalid_number"},
+		{models.ErrMissingSymbol, "missing_symbol"},
+		{fmt.Errorf("unexpected end of JSON input"), "malformed"},
+	}
+	for _, tt := range tests {
+		if got := RejectReason(tt.err); got != tt.want {
+			t.Errorf("RejectReason(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
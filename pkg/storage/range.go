@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// RangeQuerier merges stored minute candles, e.g. *PostgresStore
+type RangeQuerier interface {
+	GetRange(ctx context.Context, symbol string, start, end time.Time) (*models.Candle, error)
+}
+
+// GetRange merges the candles of symbol whose minute starts in [start, end)
+// into one candle stamped with the first of them. It returns nil when there
+// are no candles in the range.
+func (s *PostgresStore) GetRange(ctx context.Context, symbol string, start, end time.Time) (*models.Candle, error) {
+	var (
+		first                               sql.NullTime
+		open, closePrice, high, low, volume sql.NullString
+		trades                              sql.NullInt64
+	)
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			MIN(timestamp),
+			(array_agg(open_price ORDER BY timestamp ASC))[1],
+			(array_agg(close_price ORDER BY timestamp DESC))[1],
+			MAX(high_price),
+			MIN(low_price),
+			SUM(volume),
+			SUM(trade_count)
+		FROM trade_candles_reconciled
+		WHERE symbol = $1 AND timestamp >= $2 AND timestamp < $3 AND exchange = $4`,
+		strings.ToUpper(symbol), start, end, s.exchange,
+	).Scan(&first, &open, &closePrice, &high, &low, &volume, &trades)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price range: %w", err)
+	}
+
+	if !first.Valid {
+		return nil, nil
+	}
+
+	return &models.Candle{
+		Timestamp:  first.Time,
+		OpenPrice:  open.String,
+		HighPrice:  high.String,
+		LowPrice:   low.String,
+		ClosePrice: closePrice.String,
+		Volume:     volume.String,
+		TradeCount: trades.Int64,
+	}, nil
+}
+
+// GetRollingRange returns the open, high, low and close of symbol over the
+// window ending now as one candle, or nil when there were no trades in it.
+// Recent minutes come from the trade history in Redis; minutes Redis no
+// longer holds come from older, which may be nil to use Redis alone.
+func (s *RedisStore) GetRollingRange(ctx context.Context, older RangeQuerier, symbol string, window time.Duration) (*models.Candle, error) {
+	end := time.Now()
+	return s.rollingRange(ctx, older, symbol, end.Add(-window), end)
+}
+
+// GetRangeSince returns the open, high, low and close of symbol from start
+// until now as one candle, merging recent Redis trades with older candles
+// as GetRollingRange does
+func (s *RedisStore) GetRangeSince(ctx context.Context, older RangeQuerier, symbol string, start time.Time) (*models.Candle, error) {
+	return s.rollingRange(ctx, older, symbol, start, time.Now())
+}
+
+// rollingRange merges Redis trades and older candles between start and end.
+// Redis history is trimmed by age and count, so its oldest minute may be
+// partial: unless Redis still holds trades from before start, it covers the
+// whole minutes after its oldest trade and older covers those before, so no
+// minute is counted twice.
+func (s *RedisStore) rollingRange(ctx context.Context, older RangeQuerier, symbol string, start, end time.Time) (*models.Candle, error) {
+	symbol = strings.ToUpper(symbol)
+
+	// Newest first
+	history, err := s.GetTradeHistory(ctx, symbol, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	recentStart := start
+	if older != nil {
+		complete, err := s.historyCovers(ctx, symbol, start, len(history))
+		if err != nil {
+			return nil, err
+		}
+		if !complete {
+			recentStart = end
+			if len(history) > 0 {
+				oldest := time.UnixMilli(history[len(history)-1].Data.TradeTime)
+				recentStart = oldest.Truncate(time.Minute).Add(time.Minute)
+			}
+		}
+	}
+
+	var result *models.Candle
+	if recentStart.After(start) {
+		result, err = older.GetRange(ctx, symbol, start, recentStart)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var recent *models.Candle
+	for i := len(history) - 1; i >= 0; i-- {
+		trade := history[i].ToTrade()
+		if trade.Time.Before(recentStart) {
+			continue
+		}
+		if recent == nil {
+			recent = models.NewCandle(trade.Time.Truncate(time.Minute))
+		}
+		recent.UpdateFromTrade(trade)
+	}
+
+	switch {
+	case result == nil:
+		return recent, nil
+	case recent != nil:
+		result.MergeWith(recent)
+	}
+	return result, nil
+}
+
+// historyCovers reports whether the Redis history of symbol holds every trade
+// since start: it still has an older trade, so nothing after start was
+// trimmed, and returned was below the history read limit
+func (s *RedisStore) historyCovers(ctx context.Context, symbol string, start time.Time, returned int) (bool, error) {
+	if returned >= tradeHistoryLimit {
+		return false, nil
+	}
+
+	key := fmt.Sprintf("%strade:%s:history", s.config.Redis.KeyPrefix, symbol)
+	oldest, err := s.client.ZRangeWithScores(ctx, key, 0, 0).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to read oldest trade: %w", err)
+	}
+	return len(oldest) > 0 && int64(oldest[0].Score) < start.UnixMilli(), nil
+}
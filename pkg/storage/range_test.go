@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// fakeRangeQuerier returns a fixed candle and records the range asked for
+type fakeRangeQuerier struct {
+	candle     *models.Candle
+	calls      int
+	start, end time.Time
+}
+
+func (q *fakeRangeQuerier) GetRange(ctx context.Context, symbol string, start, end time.Time) (*models.Candle, error) {
+	q.calls++
+	q.start, q.end = start, end
+	return q.candle, nil
+}
+
+// storeTrades stores BTCUSDT trades at the given prices and times
+func storeTrades(t *testing.T, store *RedisStore, prices []string, times []time.Time) {
+	t.Helper()
+	for i, price := range prices {
+		trade := &models.Trade{Symbol: "BTCUSDT", Price: price, Quantity: "1", TradeID: int64(i + 1), Time: times[i], EventTime: times[i]}
+		if err := store.StoreTrade(context.Background(), trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func assertRange(t *testing.T, got *models.Candle, open, high, low, closePrice string, trades int64) {
+	t.Helper()
+	if got == nil {
+		t.Fatal("Expected a range, got nil")
+	}
+	if got.OpenPrice != open || got.HighPrice != high || got.LowPrice != low || got.ClosePrice != closePrice || got.TradeCount != trades {
+		t.Errorf("Expected O/H/L/C %s/%s/%s/%s over %d trades, got %s/%s/%s/%s over %d",
+			open, high, low, closePrice, trades,
+			got.OpenPrice, got.HighPrice, got.LowPrice, got.ClosePrice, got.TradeCount)
+	}
+}
+
+func TestRedisStore_RollingRangeSpansBoundary(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	// Redis was trimmed mid-minute: its oldest trade is 30s into base, so
+	// that minute must come from the older candles only
+	end := time.Now().Truncate(time.Minute)
+	base := end.Add(-10 * time.Minute)
+	storeTrades(t, store,
+		[]string{"999", "130", "80"},
+		[]time.Time{base.Add(30 * time.Second), base.Add(70 * time.Second), base.Add(2 * time.Minute)})
+
+	older := &fakeRangeQuerier{candle: &models.Candle{
+		Timestamp: base.Add(-time.Hour), OpenPrice: "100", HighPrice: "150", LowPrice: "90", ClosePrice: "120", Volume: "5", TradeCount: 5,
+	}}
+	start := end.Add(-2 * time.Hour)
+
+	got, err := store.rollingRange(context.Background(), older, "btcusdt", start, end)
+	if err != nil {
+		t.Fatalf("rollingRange failed: %v", err)
+	}
+	if older.calls != 1 || !older.start.Equal(start) || !older.end.Equal(base.Add(time.Minute)) {
+		t.Errorf("Expected older candles for [%s, %s), got %d calls for [%s, %s)",
+			start, base.Add(time.Minute), older.calls, older.start, older.end)
+	}
+	// The partial minute's 999 trade is not counted on top of its candle
+	assertRange(t, got, "100", "150", "80", "80", 7)
+	if volume, _ := strconv.ParseFloat(got.Volume, 64); volume != 7 {
+		t.Errorf("Expected volume 7, got %s", got.Volume)
+	}
+	if !got.Timestamp.Equal(base.Add(-time.Hour)) {
+		t.Errorf("Expected the range to start with the older candle, got %s", got.Timestamp)
+	}
+}
+
+func TestRedisStore_RollingRangeWithinRedis(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	end := time.Now().Truncate(time.Minute)
+	storeTrades(t, store,
+		[]string{"50", "100", "110", "95"},
+		[]time.Time{end.Add(-3 * time.Hour), end.Add(-50 * time.Minute), end.Add(-20 * time.Minute), end.Add(-time.Minute)})
+
+	// Redis still holds a trade from before the window, so it has all of it
+	older := &fakeRangeQuerier{candle: &models.Candle{OpenPrice: "1", HighPrice: "1", LowPrice: "1", ClosePrice: "1", TradeCount: 1}}
+	got, err := store.rollingRange(context.Background(), older, "BTCUSDT", end.Add(-time.Hour), end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if older.calls != 0 {
+		t.Errorf("Expected no older query, got %d", older.calls)
+	}
+	assertRange(t, got, "100", "110", "95", "95", 3)
+
+	// Without older candles Redis is used as far as it goes
+	got, err = store.rollingRange(context.Background(), nil, "BTCUSDT", end.Add(-4*time.Hour), end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRange(t, got, "50", "110", "50", "95", 4)
+}
+
+func TestRedisStore_RollingRangeWithoutRedisHistory(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	end := time.Now()
+	start := end.Add(-7 * 24 * time.Hour)
+
+	got, err := store.rollingRange(context.Background(), nil, "BTCUSDT", start, end)
+	if err != nil || got != nil {
+		t.Errorf("Expected no range without any data, got %+v (%v)", got, err)
+	}
+
+	older := &fakeRangeQuerier{candle: &models.Candle{OpenPrice: "10", HighPrice: "12", LowPrice: "9", ClosePrice: "11", TradeCount: 40}}
+	got, err = store.rollingRange(context.Background(), older, "BTCUSDT", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !older.start.Equal(start) || !older.end.Equal(end) {
+		t.Errorf("Expected the whole window from older candles, got [%s, %s)", older.start, older.end)
+	}
+	assertRange(t, got, "10", "12", "9", "11", 40)
+}
+
+func TestPostgresStore_GetRange(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	candles := []*models.Candle{
+		{Timestamp: base, OpenPrice: "100", HighPrice: "110", LowPrice: "95", ClosePrice: "105", Volume: "10", TradeCount: 5},
+		{Timestamp: base.Add(time.Minute), OpenPrice: "105", HighPrice: "120", LowPrice: "90", ClosePrice: "115", Volume: "30", TradeCount: 15},
+		{Timestamp: base.Add(2 * time.Minute), OpenPrice: "115", HighPrice: "200", LowPrice: "50", ClosePrice: "150", Volume: "1", TradeCount: 1},
+	}
+	for _, candle := range candles {
+		if err := store.StoreCandleData(ctx, "BTCUSDT", candle, CandleSourceLive); err != nil {
+			t.Fatalf("Failed to store candle: %v", err)
+		}
+	}
+
+	// The end minute is excluded
+	got, err := store.GetRange(ctx, "btcusdt", base, base.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("GetRange failed: %v", err)
+	}
+	if got == nil {
+		t.Fatal("Expected a range, got nil")
+	}
+	for name, check := range map[string]struct {
+		got  string
+		want float64
+	}{
+		"open": {got.OpenPrice, 100}, "high": {got.HighPrice, 120}, "low": {got.LowPrice, 90},
+		"close": {got.ClosePrice, 115}, "volume": {got.Volume, 40},
+	} {
+		if v, err := strconv.ParseFloat(check.got, 64); err != nil || v != check.want {
+			t.Errorf("Expected %s %.2f, got %s", name, check.want, check.got)
+		}
+	}
+	if got.TradeCount != 20 || !got.Timestamp.Equal(base) {
+		t.Errorf("Unexpected range: %+v", got)
+	}
+
+	empty, err := store.GetRange(ctx, "ETHUSDT", base, base.Add(time.Hour))
+	if err != nil || empty != nil {
+		t.Errorf("Expected nil range for a symbol without candles, got %+v, %v", empty, err)
+	}
+}
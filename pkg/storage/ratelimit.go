@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// redisTradesCoalesced counts trades StoreTrade wrote only as the latest
+// trade, skipping their history entry, because writes exceeded the limit
+var redisTradesCoalesced = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "binance_redis_trades_coalesced_total",
+	Help: "Trades stored only as the latest trade, without history, because Redis writes were rate limited.",
+})
+
+// tokenBucket admits up to rate events per second with bursts of up to burst
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// allow takes a token if one is available, without waiting for one
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// coalescedVolume holds the notional of coalesced trades per symbol until
+// the next full write adds it to the running volume
+type coalescedVolume struct {
+	mu      sync.Mutex
+	pending map[string]float64
+}
+
+func (v *coalescedVolume) add(symbol string, volume float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.pending == nil {
+		v.pending = make(map[string]float64)
+	}
+	v.pending[symbol] += volume
+}
+
+// take returns and clears symbol's pending volume
+func (v *coalescedVolume) take(symbol string) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	volume := v.pending[symbol]
+	delete(v.pending, symbol)
+	return volume
+}
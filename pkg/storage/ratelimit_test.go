@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	b := newTokenBucket(10, 5)
+	b.now = func() time.Time { return now }
+
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		if b.allow() {
+			allowed++
+		}
+	}
+	if allowed != 5 {
+		t.Errorf("Expected the burst of 5 to be allowed, got %d", allowed)
+	}
+
+	// 10/s refills one token every 100ms
+	now = now.Add(350 * time.Millisecond)
+	allowed = 0
+	for i := 0; i < 20; i++ {
+		if b.allow() {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("Expected 3 tokens after 350ms, got %d", allowed)
+	}
+
+	// Idle time never refills beyond the burst
+	now = now.Add(time.Hour)
+	allowed = 0
+	for i := 0; i < 20; i++ {
+		if b.allow() {
+			allowed++
+		}
+	}
+	if allowed != 5 {
+		t.Errorf("Expected the burst of 5 after idling, got %d", allowed)
+	}
+}
+
+func TestRedisStore_StoreTradeCoalescesAboveLimit(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	now := time.Now()
+	store.writeLimit = newTokenBucket(100, 3)
+	store.writeLimit.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	before := testutil.ToFloat64(redisTradesCoalesced)
+	for i := 1; i <= 10; i++ {
+		trade := &models.Trade{
+			Symbol:   "BTCUSDT",
+			Price:    fmt.Sprintf("%d", 50000+i),
+			Quantity: "1",
+			TradeID:  int64(i),
+			Time:     now.Add(time.Duration(i) * time.Millisecond),
+		}
+		if err := store.StoreTrade(ctx, trade); err != nil {
+			t.Fatalf("StoreTrade() error = %v", err)
+		}
+	}
+
+	if coalesced := testutil.ToFloat64(redisTradesCoalesced) - before; coalesced != 7 {
+		t.Errorf("Expected 7 coalesced trades, got %v", coalesced)
+	}
+	if n := store.client.ZCard(ctx, "test:trade:BTCUSDT:history").Val(); n != 3 {
+		t.Errorf("Expected 3 history entries, got %d", n)
+	}
+
+	latest, err := store.GetLatestTrade(ctx, "BTCUSDT")
+	if err != nil {
+		t.Fatalf("GetLatestTrade() error = %v", err)
+	}
+	if latest.TradeID != 10 || latest.Price != "50010" {
+		t.Errorf("Expected the latest trade 10 at 50010, got %d at %s", latest.TradeID, latest.Price)
+	}
+
+	// The next admitted write carries the coalesced volume
+	now = now.Add(time.Second)
+	last := &models.Trade{Symbol: "BTCUSDT", Price: "50011", Quantity: "1", TradeID: 11, Time: now}
+	if err := store.StoreTrade(ctx, last); err != nil {
+		t.Fatalf("StoreTrade() error = %v", err)
+	}
+	volume, err := store.client.Get(ctx, "test:BTCUSDT:volume:running").Float64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := 0.0
+	for i := 1; i <= 11; i++ {
+		want += float64(50000 + i)
+	}
+	if volume != want {
+		t.Errorf("Running volume = %v, want %v", volume, want)
+	}
+}
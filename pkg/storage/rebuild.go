@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// RebuiltCandle is a minute candle recomputed from the Redis trade history,
+// with the range of trade IDs it was built from
+type RebuiltCandle struct {
+	Candle       *models.Candle
+	FirstTradeID int64
+	LastTradeID  int64
+}
+
+// RebuildCandles recomputes the minute candles of symbol between start and
+// end from the Redis trade history, grouping trades as the aggregator's
+// historical migration does. It returns the candles, oldest first, and the
+// number of trades read. When the history was trimmed past start, the
+// rebuild starts at the first whole minute after its oldest trade instead,
+// as earlier minutes would be rebuilt from only part of their trades.
+func (s *RedisStore) RebuildCandles(ctx context.Context, symbol string, start, end time.Time) ([]*RebuiltCandle, int, error) {
+	covered, err := s.HistoryCovers(ctx, symbol, start)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !covered {
+		oldest, err := s.HistoryStart(ctx, symbol)
+		if err != nil {
+			return nil, 0, err
+		}
+		start = oldest.Truncate(time.Minute).Add(time.Minute)
+	}
+
+	byMinute := make(map[time.Time][]models.AggTradeEvent)
+	trades := 0
+	err = s.ScanTradeHistory(ctx, symbol, start, end, func(event models.AggTradeEvent) error {
+		minute := time.UnixMilli(event.Data.TradeTime).Truncate(time.Minute)
+		byMinute[minute] = append(byMinute[minute], event)
+		trades++
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	candles := make([]*RebuiltCandle, 0, len(byMinute))
+	for _, events := range byMinute {
+		rebuilt := &RebuiltCandle{
+			Candle:       models.MinuteCandles(events)[0],
+			FirstTradeID: events[0].Data.TradeID,
+			LastTradeID:  events[0].Data.TradeID,
+		}
+		for _, event := range events[1:] {
+			rebuilt.FirstTradeID = min(rebuilt.FirstTradeID, event.Data.TradeID)
+			rebuilt.LastTradeID = max(rebuilt.LastTradeID, event.Data.TradeID)
+		}
+		candles = append(candles, rebuilt)
+	}
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Candle.Timestamp.Before(candles[j].Candle.Timestamp)
+	})
+	return candles, trades, nil
+}
+
+// StoreRebuiltCandle writes a rebuilt candle as the backfill candle of its
+// minute unless the stored one already covers its trades: one rebuilt from
+// the same or a wider trade ID range. Rebuilding from the same history twice
+// therefore changes nothing. Candles of other sources are left alone; reads
+// keep whichever of them was built from the most trades. It reports whether
+// the candle was written.
+func (s *PostgresStore) StoreRebuiltCandle(ctx context.Context, symbol string, rebuilt *RebuiltCandle) (bool, error) {
+	c := rebuilt.Candle
+	timestamp := c.Timestamp.UTC()
+	if timestamp.IsZero() {
+		return false, fmt.Errorf("invalid timestamp: zero value")
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO trade_candles (
+			symbol, timestamp, open_price, high_price, low_price,
+			close_price, volume, trade_count, exchange, source, first_trade_id, last_trade_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (exchange, symbol, timestamp, source, writer) DO UPDATE SET
+			open_price = EXCLUDED.open_price,
+			high_price = EXCLUDED.high_price,
+			low_price = EXCLUDED.low_price,
+			close_price = EXCLUDED.close_price,
+			volume = EXCLUDED.volume,
+			trade_count = EXCLUDED.trade_count,
+			first_trade_id = EXCLUDED.first_trade_id,
+			last_trade_id = EXCLUDED.last_trade_id
+		WHERE EXCLUDED.first_trade_id < trade_candles.first_trade_id
+			OR EXCLUDED.last_trade_id > trade_candles.last_trade_id`,
+		strings.ToUpper(symbol), timestamp, c.OpenPrice, c.HighPrice, c.LowPrice,
+		c.ClosePrice, c.Volume, c.TradeCount, s.exchange, CandleSourceBackfill,
+		rebuilt.FirstTradeID, rebuilt.LastTradeID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to store rebuilt candle: %w", err)
+	}
+	written, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to store rebuilt candle: %w", err)
+	}
+	return written > 0, nil
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// seedTrades stores n random trades of symbol spread over minutes minutes
+// from base, with trade IDs counting up from firstID, and returns them
+func seedTrades(t *testing.T, store *RedisStore, symbol string, base time.Time, minutes, n int, firstID int64) []models.AggTradeEvent {
+	t.Helper()
+	rng := rand.New(rand.NewSource(firstID))
+	events := make([]models.AggTradeEvent, 0, n)
+	for i := 0; i < n; i++ {
+		tradeTime := base.Add(time.Duration(rng.Int63n(int64(minutes) * int64(time.Minute)))).Truncate(time.Millisecond)
+		trade := &models.Trade{
+			Symbol:    symbol,
+			Price:     strconv.FormatFloat(100+rng.Float64()*10, 'f', 4, 64),
+			Quantity:  strconv.FormatFloat(0.1+rng.Float64(), 'f', 4, 64),
+			TradeID:   firstID + int64(i),
+			Time:      tradeTime,
+			EventTime: tradeTime,
+		}
+		if err := store.StoreTrade(context.Background(), trade); err != nil {
+			t.Fatalf("Failed to store trade: %v", err)
+		}
+		events = append(events, models.AggTradeEvent{Data: models.TradeData{
+			Symbol:    symbol,
+			Price:     trade.Price,
+			Quantity:  trade.Quantity,
+			TradeID:   trade.TradeID,
+			TradeTime: tradeTime.UnixMilli(),
+			EventTime: tradeTime.UnixMilli(),
+		}})
+	}
+	return events
+}
+
+func TestRedisStore_RebuildCandles(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	// More trades than one history page, so the rebuild has to page
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	events := seedTrades(t, store, "BTCUSDT", base, 10, historyPageSize*2+500, 1000)
+
+	ctx := context.Background()
+	rebuilt, trades, err := store.RebuildCandles(ctx, "BTCUSDT", base, base.Add(10*time.Minute))
+	if err != nil {
+		t.Fatalf("RebuildCandles() error = %v", err)
+	}
+	if trades != len(events) {
+		t.Errorf("Read %d trades, want %d", trades, len(events))
+	}
+
+	want := models.MinuteCandles(events)
+	if len(rebuilt) != len(want) {
+		t.Fatalf("Rebuilt %d candles, want %d", len(rebuilt), len(want))
+	}
+	for i, candle := range rebuilt {
+		if fmt.Sprint(*candle.Candle) != fmt.Sprint(*want[i]) {
+			t.Errorf("Candle %d = %+v, want %+v", i, *candle.Candle, *want[i])
+		}
+
+		first, last := int64(-1), int64(-1)
+		for _, event := range events {
+			if !time.UnixMilli(event.Data.TradeTime).Truncate(time.Minute).Equal(candle.Candle.Timestamp) {
+				continue
+			}
+			if first < 0 || event.Data.TradeID < first {
+				first = event.Data.TradeID
+			}
+			if event.Data.TradeID > last {
+				last = event.Data.TradeID
+			}
+		}
+		if candle.FirstTradeID != first || candle.LastTradeID != last {
+			t.Errorf("Candle %d trade IDs = %d-%d, want %d-%d", i, candle.FirstTradeID, candle.LastTradeID, first, last)
+		}
+	}
+}
+
+func TestRedisStore_RebuildCandlesEmpty(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	now := time.Now()
+	rebuilt, trades, err := store.RebuildCandles(context.Background(), "BTCUSDT", now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("RebuildCandles() error = %v", err)
+	}
+	if len(rebuilt) != 0 || trades != 0 {
+		t.Errorf("Expected nothing to rebuild, got %d candles from %d trades", len(rebuilt), trades)
+	}
+}
+
+func TestRedisStore_RebuildCandlesTrimmedHistory(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+	store.config.Redis.MaxTradesPerKey = 100
+
+	// Trimming keeps the last 100 of 300 trades, starting within a minute
+	// whose earlier trades are gone
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	seedTrades(t, store, "BTCUSDT", base, 10, 300, 1)
+	oldest, err := store.HistoryStart(context.Background(), "BTCUSDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rebuilt, _, err := store.RebuildCandles(context.Background(), "BTCUSDT", base, base.Add(10*time.Minute))
+	if err != nil {
+		t.Fatalf("RebuildCandles() error = %v", err)
+	}
+	if len(rebuilt) == 0 {
+		t.Fatal("Expected the minutes after the oldest trade to be rebuilt")
+	}
+	for _, candle := range rebuilt {
+		if !candle.Candle.Timestamp.After(oldest) {
+			t.Errorf("Rebuilt candle %s, which the history only holds part of (it starts at %s)", candle.Candle.Timestamp, oldest)
+		}
+	}
+}
+
+func TestPostgresStore_StoreRebuiltCandle(t *testing.T) {
+	pg, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	redisStore, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer redisStore.Close()
+
+	ctx := context.Background()
+	symbol := "REBUILDUSDT"
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	end := base.Add(5 * time.Minute)
+
+	// The aggregator stored a partial first minute before going down
+	events := seedTrades(t, redisStore, symbol, base, 5, 200, 1)
+	partial := models.MinuteCandles(events)[0]
+	partial.TradeCount = 1
+	if err := pg.StoreCandleData(ctx, symbol, partial, CandleSourceLive); err != nil {
+		t.Fatalf("Failed to store partial candle: %v", err)
+	}
+
+	rebuilt, _, err := redisStore.RebuildCandles(ctx, symbol, base, end)
+	if err != nil {
+		t.Fatalf("RebuildCandles() error = %v", err)
+	}
+	for _, candle := range rebuilt {
+		written, err := pg.StoreRebuiltCandle(ctx, symbol, candle)
+		if err != nil {
+			t.Fatalf("StoreRebuiltCandle() error = %v", err)
+		}
+		if !written {
+			t.Errorf("Expected candle %v to be written", candle.Candle.Timestamp)
+		}
+	}
+
+	stored, err := pg.GetHistoricalCandles(ctx, symbol, base, end)
+	if err != nil {
+		t.Fatalf("GetHistoricalCandles() error = %v", err)
+	}
+	if len(stored) != len(rebuilt) {
+		t.Fatalf("Stored %d candles, want %d", len(stored), len(rebuilt))
+	}
+	for i, candle := range stored {
+		if candle.TradeCount != rebuilt[i].Candle.TradeCount {
+			t.Errorf("Candle %d trade count = %d, want %d", i, candle.TradeCount, rebuilt[i].Candle.TradeCount)
+		}
+	}
+
+	// Rebuilding from the same trades again changes nothing
+	for _, candle := range rebuilt {
+		written, err := pg.StoreRebuiltCandle(ctx, symbol, candle)
+		if err != nil {
+			t.Fatalf("StoreRebuiltCandle() error = %v", err)
+		}
+		if written {
+			t.Errorf("Expected candle %v to be skipped on the second run", candle.Candle.Timestamp)
+		}
+	}
+
+	// Later trades of the last minute extend its range and replace it
+	last := rebuilt[len(rebuilt)-1]
+	extended := *last
+	extended.LastTradeID++
+	if written, err := pg.StoreRebuiltCandle(ctx, symbol, &extended); err != nil || !written {
+		t.Errorf("StoreRebuiltCandle(extended) = %v, %v, want true", written, err)
+	}
+}
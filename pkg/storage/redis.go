@@ -1,18 +1,23 @@
 package storage
 
 import (
-	"compress/gzip"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
+	"math"
+	"math/rand"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 
+	"binance-redis-streamer/internal/jsoncodec"
 	"binance-redis-streamer/internal/models"
 	"binance-redis-streamer/pkg/config"
 )
@@ -22,27 +27,115 @@ type TradeStore interface {
 	StoreTrade(ctx context.Context, trade *models.Trade) error
 	StoreRawTrade(ctx context.Context, symbol string, data []byte) error
 	GetTradeHistory(ctx context.Context, symbol string, start, end time.Time) ([]models.AggTradeEvent, error)
+	// CountTradesInRange counts the trades between start and end, beyond the
+	// cap of GetTradeHistory
+	CountTradesInRange(ctx context.Context, symbol string, start, end time.Time) (int64, error)
+	// HistoryCovers reports whether the history still holds every trade
+	// since start, or was trimmed past it
+	HistoryCovers(ctx context.Context, symbol string, start time.Time) (bool, error)
+	// GetLatestTrade returns ErrNotFound when symbol has no trades yet
 	GetLatestTrade(ctx context.Context, symbol string) (*models.Trade, error)
-	GetRedisClient() *redis.Client
+	GetRedisClient() redis.UniversalClient
 	Close() error
 	Update24hVolume(ctx context.Context, symbol string) error
+	Get24hVolume(ctx context.Context, symbol string) (float64, error)
+	GetPrioritySymbols(ctx context.Context) ([]string, error)
 }
 
+const (
+	// volumeRefreshInterval limits background 24h volume refreshes per
+	// symbol; up to volumeRefreshJitter is added per refresh so symbols that
+	// started together drift apart
+	volumeRefreshInterval = time.Minute
+	volumeRefreshJitter   = 30 * time.Second
+	// volumeTTL is how long a stored 24h volume lives, plus up to
+	// volumeTTLJitter so the volumes of all symbols do not expire together
+	volumeTTL       = 5 * time.Minute
+	volumeTTLJitter = time.Minute
+	// maxVolumeScans caps the 24h history scans of Update24hVolume running
+	// at once across all symbols
+	maxVolumeScans = 4
+	// closeTimeout bounds how long Close waits for background work
+	closeTimeout = 5 * time.Second
+	// maxTradeTimeSkew is how far a raw trade's timestamp may be from now
+	maxTradeTimeSkew = 24 * time.Hour
+)
+
+// ErrInvalidTradeTime is returned for raw trades whose timestamp does not
+// parse, is missing or is too far from the current time to be stored in the
+// history. Missing ones also match models.ErrMissingTradeTime.
+var ErrInvalidTradeTime = errors.New("invalid trade time")
+
 // RedisStore handles Redis storage operations
 type RedisStore struct {
-	client *redis.Client
+	client redis.UniversalClient // *redis.Client, or *redis.ClusterClient in cluster mode
+	db     int                   // Logical database, for keyspace notification channels
 	config *config.Config
+
+	// Background work (24h volume refreshes) started by StoreTrade
+	bgMu      sync.Mutex
+	bgWg      sync.WaitGroup
+	bgCtx     context.Context
+	bgCancel  context.CancelFunc
+	closed    bool
+	volumeRun sync.Map // symbol -> time.Time the next volume refresh is due
+
+	// volumeScans holds a token per running 24h volume scan
+	volumeScans chan struct{}
+	// volumeHistory reads the trades a 24h volume is computed from
+	volumeHistory func(ctx context.Context, symbol string, start, end time.Time) ([]models.AggTradeEvent, error)
+
+	// StoreTrade write limit; nil when unlimited
+	writeLimit *tokenBucket
+	coalesced  coalescedVolume
 }
 
 // NewRedisStore creates a new Redis store
 func NewRedisStore(cfg *config.Config) (*RedisStore, error) {
+	client, db, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	if cfg.Debug {
+		log.Printf("Successfully connected to Redis at %s", cfg.Redis.URL)
+	}
+
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	store := &RedisStore{
+		client:   client,
+		db:       db,
+		config:   cfg,
+		bgCtx:    bgCtx,
+		bgCancel: bgCancel,
+
+		volumeScans: make(chan struct{}, maxVolumeScans),
+	}
+	store.volumeHistory = store.GetTradeHistory
+	if cfg.Redis.MaxWritesPerSec > 0 {
+		burst := cfg.Redis.WriteBurst
+		if burst <= 0 {
+			burst = int(math.Ceil(cfg.Redis.MaxWritesPerSec))
+		}
+		store.writeLimit = newTokenBucket(cfg.Redis.MaxWritesPerSec, burst)
+	}
+	return store, nil
+}
+
+// newRedisClient creates a single-node client, a failover client when
+// sentinel addresses are configured, or a cluster client in cluster mode.
+// It also returns the logical database in use.
+func newRedisClient(cfg *config.Config) (redis.UniversalClient, int, error) {
 	if cfg.Debug {
 		log.Printf("Attempting to connect to Redis at URL: %s", cfg.Redis.URL)
 	}
 
 	opt, err := redis.ParseURL(cfg.Redis.URL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+		return nil, 0, fmt.Errorf("failed to parse Redis URL: %w", err)
 	}
 	if cfg.Debug {
 		log.Printf("Parsed Redis options: addr=%s db=%d", opt.Addr, opt.DB)
@@ -56,49 +149,180 @@ func NewRedisStore(cfg *config.Config) (*RedisStore, error) {
 		opt.TLSConfig.InsecureSkipVerify = true
 	}
 
-	client := redis.NewClient(opt)
-	if err := client.Ping(context.Background()).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	if cfg.Redis.Cluster {
+		// Clusters only have database 0; the URL's host is a seed node and the
+		// remaining nodes are discovered from it
+		if opt.DB != 0 {
+			return nil, 0, fmt.Errorf("redis cluster does not support database %d", opt.DB)
+		}
+		if cfg.Debug {
+			log.Printf("Using Redis Cluster seeded from %s", opt.Addr)
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     []string{opt.Addr},
+			Username:  opt.Username,
+			Password:  opt.Password,
+			TLSConfig: opt.TLSConfig,
+		}), 0, nil
+	}
+
+	if len(cfg.Redis.SentinelAddrs) == 0 {
+		return redis.NewClient(opt), opt.DB, nil
 	}
+
 	if cfg.Debug {
-		log.Printf("Successfully connected to Redis at %s", cfg.Redis.URL)
+		log.Printf("Using Redis Sentinel master %q via %v", cfg.Redis.SentinelMasterName, cfg.Redis.SentinelAddrs)
 	}
 
-	return &RedisStore{
-		client: client,
-		config: cfg,
-	}, nil
+	// Credentials, database and TLS settings still come from the URL
+	return redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:       cfg.Redis.SentinelMasterName,
+		SentinelAddrs:    cfg.Redis.SentinelAddrs,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		Username:         opt.Username,
+		Password:         opt.Password,
+		DB:               opt.DB,
+		TLSConfig:        opt.TLSConfig,
+	}), opt.DB, nil
 }
 
 // GetRedisClient returns the underlying Redis client
-func (s *RedisStore) GetRedisClient() *redis.Client {
+func (s *RedisStore) GetRedisClient() redis.UniversalClient {
 	return s.client
 }
 
-// Close closes the Redis connection
+// Close waits for background work to finish, up to a timeout, then closes
+// the Redis connection
 func (s *RedisStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	return s.CloseContext(ctx)
+}
+
+// CloseContext stops accepting background work and waits for outstanding
+// operations until ctx is done, cancelling any that remain, then closes the
+// Redis connection
+func (s *RedisStore) CloseContext(ctx context.Context) error {
+	s.bgMu.Lock()
+	s.closed = true
+	s.bgMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.bgWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("Warning: cancelling background Redis operations on close: %v", ctx.Err())
+		s.bgCancel()
+		<-done
+	}
+	s.bgCancel()
+
 	return s.client.Close()
 }
 
+// goBackground runs fn in a tracked goroutine unless the store is closing
+func (s *RedisStore) goBackground(fn func(ctx context.Context)) bool {
+	s.bgMu.Lock()
+	defer s.bgMu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.bgWg.Add(1)
+	go func() {
+		defer s.bgWg.Done()
+		fn(s.bgCtx)
+	}()
+	return true
+}
+
+// jitter returns a random duration in [0, max)
+func jitter(max time.Duration) time.Duration {
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// refreshVolumeAsync recalculates the 24h volume of a symbol in the
+// background, at most once per volumeRefreshInterval plus jitter
+func (s *RedisStore) refreshVolumeAsync(symbol string) {
+	now := time.Now()
+	if due, ok := s.volumeRun.Load(symbol); ok && now.Before(due.(time.Time)) {
+		return
+	}
+	s.volumeRun.Store(symbol, now.Add(volumeRefreshInterval+jitter(volumeRefreshJitter)))
+
+	s.goBackground(func(ctx context.Context) {
+		if err := s.Update24hVolume(ctx, symbol); err != nil {
+			log.Printf("Warning: failed to update 24h volume for %s: %v", symbol, err)
+		}
+	})
+}
+
 // StoreTrade stores a trade in Redis
 func (s *RedisStore) StoreTrade(ctx context.Context, trade *models.Trade) error {
-	// Add symbol to tracked symbols set
+	return s.storeTrade(ctx, trade, false)
+}
+
+// StoreBackfilledTrade stores a trade that arrives after newer ones, such as
+// one recovered from a stream gap or a dead-letter retry, like StoreTrade,
+// except that it only becomes the latest trade when that is older or missing
+func (s *RedisStore) StoreBackfilledTrade(ctx context.Context, trade *models.Trade) error {
+	return s.storeTrade(ctx, trade, true)
+}
+
+// storeTrade stores trade, keeping a newer latest trade when backfilled
+func (s *RedisStore) storeTrade(ctx context.Context, trade *models.Trade, backfilled bool) error {
+	// Add symbol to tracked symbols set and today's HyperLogLog in one
+	// round trip
 	symbolsKey := fmt.Sprintf("%ssymbols", s.config.Redis.KeyPrefix)
-	if err := s.client.SAdd(ctx, symbolsKey, strings.ToUpper(trade.Symbol)).Err(); err != nil {
+	now := time.Now()
+	pipe := s.client.Pipeline()
+	tracked := pipe.SAdd(ctx, symbolsKey, strings.ToUpper(trade.Symbol))
+	added := s.queueSymbolHLL(ctx, pipe, trade.Symbol, now)
+	// Each command's error is checked on its own below
+	pipe.Exec(ctx)
+	if err := tracked.Err(); err != nil {
 		return fmt.Errorf("failed to add symbol to set: %w", err)
 	}
+	if err := s.noteSymbolHLL(ctx, added, now); err != nil {
+		log.Printf("Warning: %v", err)
+	}
 
 	// Store latest trade
 	latestKey := fmt.Sprintf("%strade:%s:latest", s.config.Redis.KeyPrefix, strings.ToUpper(trade.Symbol))
-	data, err := json.Marshal(trade)
+	data, err := models.EncodeTrade(trade)
 	if err != nil {
 		return fmt.Errorf("failed to marshal trade: %w", err)
 	}
 
-	if err := s.client.Set(ctx, latestKey, data, s.config.Redis.RetentionPeriod).Err(); err != nil {
+	if backfilled {
+		err = s.setLatestIfNewer(ctx, latestKey, trade, data)
+	} else {
+		err = s.client.Set(ctx, latestKey, data, s.config.Redis.RetentionPeriod).Err()
+	}
+	if err != nil {
 		return fmt.Errorf("failed to store latest trade: %w", err)
 	}
 
+	// Unparsable fields count as zero
+	numbers, _ := trade.Numbers()
+	tradeVolume := numbers.Notional()
+
+	// Over the write limit, keep the latest price but skip the history and
+	// volume writes rather than block the caller. The skipped volume is
+	// added by the symbol's next full write.
+	if s.writeLimit != nil && !s.writeLimit.allow() {
+		s.coalesced.add(strings.ToUpper(trade.Symbol), tradeVolume)
+		redisTradesCoalesced.Inc()
+		return nil
+	}
+	tradeVolume += s.coalesced.take(strings.ToUpper(trade.Symbol))
+
 	// Store in history
 	historyKey := fmt.Sprintf("%strade:%s:history", s.config.Redis.KeyPrefix, strings.ToUpper(trade.Symbol))
 
@@ -106,17 +330,19 @@ func (s *RedisStore) StoreTrade(ctx context.Context, trade *models.Trade) error
 	event := models.AggTradeEvent{
 		Stream: fmt.Sprintf("%s@trade", strings.ToLower(trade.Symbol)),
 		Data: models.TradeData{
-			EventType: "trade",
+			EventType: models.EventTypeTrade,
 			EventTime: trade.EventTime.UnixMilli(),
 			Symbol:    trade.Symbol,
 			TradeID:   trade.TradeID,
 			Price:     trade.Price,
 			Quantity:  trade.Quantity,
 			TradeTime: trade.Time.UnixMilli(),
+
+			IsBuyerMaker: trade.IsBuyerMaker,
 		},
 	}
 
-	eventData, err := json.Marshal(event)
+	eventData, err := jsoncodec.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal trade event: %w", err)
 	}
@@ -138,9 +364,6 @@ func (s *RedisStore) StoreTrade(ctx context.Context, trade *models.Trade) error
 
 	// Update running volume in Redis
 	volumeKey := fmt.Sprintf("%s%s:volume:running", s.config.Redis.KeyPrefix, strings.ToUpper(trade.Symbol))
-	price, _ := strconv.ParseFloat(trade.Price, 64)
-	quantity, _ := strconv.ParseFloat(trade.Quantity, 64)
-	tradeVolume := price * quantity
 
 	// Check if we need to reset the volume (every 2 hours)
 	resetKey := fmt.Sprintf("%s%s:volume:reset_time", s.config.Redis.KeyPrefix, strings.ToUpper(trade.Symbol))
@@ -160,6 +383,8 @@ func (s *RedisStore) StoreTrade(ctx context.Context, trade *models.Trade) error
 		}
 	}
 
+	s.refreshVolumeAsync(strings.ToUpper(trade.Symbol))
+
 	return nil
 }
 
@@ -172,27 +397,36 @@ func (s *RedisStore) StoreRawTrade(ctx context.Context, symbol string, data []by
 		log.Printf("Storing raw trade data for %s: %s", symbol, string(data))
 	}
 
-	// Parse event to get timestamp for score
-	var event struct {
-		Data struct {
-			TradeTime int64 `json:"T"`
-		} `json:"data"`
+	// The timestamp is the sorted set score: a zero or negative one would sit
+	// at the start of the history and a far-future one would never be trimmed
+	tradeTime, err := rawTradeTime(data)
+	if err == nil {
+		err = validateTradeTime(tradeTime, time.Now())
+	}
+	if err != nil {
+		log.Printf("Warning: rejecting raw trade for %s: %v", symbol, err)
+		if qErr := s.QuarantinePayload(ctx, data, err); qErr != nil {
+			log.Printf("Warning: %v", qErr)
+		}
+		return err
 	}
-	if err := json.Unmarshal(data, &event); err != nil {
-		return fmt.Errorf("failed to parse trade time: %w", err)
+
+	member, err := compressEntry(s.config.Redis.CompressionCodec, data)
+	if err != nil {
+		return err
 	}
 
 	// Add to sorted set with score as timestamp in milliseconds
 	if err := s.client.ZAdd(ctx, historyKey, &redis.Z{
-		Score:  float64(event.Data.TradeTime), // TradeTime is already in milliseconds
-		Member: data,
+		Score:  float64(tradeTime), // TradeTime is already in milliseconds
+		Member: member,
 	}).Err(); err != nil {
 		return fmt.Errorf("failed to store trade history: %w", err)
 	}
 
 	if s.config.Debug {
 		// Debug: Print stored trade data
-		log.Printf("Successfully stored trade data for %s with timestamp %d", symbol, event.Data.TradeTime)
+		log.Printf("Successfully stored trade data for %s with timestamp %d", symbol, tradeTime)
 	}
 
 	// Trim old trades
@@ -205,6 +439,38 @@ func (s *RedisStore) StoreRawTrade(ctx context.Context, symbol string, data []by
 	return nil
 }
 
+// rawTradeTime extracts the trade time of a raw trade message. Messages
+// that do not parse or lack data.T are rejected with ErrInvalidTradeTime.
+func rawTradeTime(data []byte) (int64, error) {
+	var event struct {
+		Data struct {
+			TradeTime *int64 `json:"T"`
+			TradeID   int64  `json:"t"` // Keeps "t" from matching "T" case-insensitively
+		} `json:"data"`
+	}
+	if err := jsoncodec.Unmarshal(data, &event); err != nil {
+		return 0, fmt.Errorf("%w: failed to parse trade time: %w", ErrInvalidTradeTime, err)
+	}
+	if event.Data.TradeTime == nil {
+		return 0, fmt.Errorf("%w: %w: no data.T", ErrInvalidTradeTime, models.ErrMissingTradeTime)
+	}
+	return *event.Data.TradeTime, nil
+}
+
+// validateTradeTime checks that a trade time in Unix milliseconds lies within
+// maxTradeTimeSkew of now
+func validateTradeTime(tradeTimeMs int64, now time.Time) error {
+	if tradeTimeMs <= 0 {
+		return fmt.Errorf("%w: %w: %d", ErrInvalidTradeTime, models.ErrMissingTradeTime, tradeTimeMs)
+	}
+
+	tradeTime := time.UnixMilli(tradeTimeMs).UTC()
+	if skew := tradeTime.Sub(now.UTC()); skew > maxTradeTimeSkew || skew < -maxTradeTimeSkew {
+		return fmt.Errorf("%w: %s is more than %s from now", ErrInvalidTradeTime, tradeTime.Format(time.RFC3339), maxTradeTimeSkew)
+	}
+	return nil
+}
+
 // trimHistory removes old trades from history
 func (s *RedisStore) trimHistory(ctx context.Context, key string) error {
 	// Remove trades older than retention period (convert to milliseconds)
@@ -224,26 +490,253 @@ func (s *RedisStore) trimHistory(ctx context.Context, key string) error {
 	return nil
 }
 
-// GetLatestTrade gets the latest trade for a symbol
+// setLatestIfNewer stores data as the latest trade at key unless the stored
+// one is newer than trade. A concurrent write to key wins, as it comes from
+// the live stream.
+func (s *RedisStore) setLatestIfNewer(ctx context.Context, key string, trade *models.Trade, data []byte) error {
+	err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, key).Bytes()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if err == nil {
+			if stored, err := models.DecodeTrade(current); err == nil && stored.Time.After(trade.Time) {
+				return nil
+			}
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, s.config.Redis.RetentionPeriod)
+			return nil
+		})
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil
+	}
+	return err
+}
+
+// GetLatestTrade gets the latest trade for a symbol. It returns ErrNotFound
+// when the symbol has no trade, and ErrStale when the trade is older than
+// MaxLatestTradeAge.
 func (s *RedisStore) GetLatestTrade(ctx context.Context, symbol string) (*models.Trade, error) {
 	key := fmt.Sprintf("%strade:%s:latest", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
 	data, err := s.client.Get(ctx, key).Result()
 	if err == redis.Nil {
-		return nil, nil
+		return nil, fmt.Errorf("no latest trade for %s: %w", strings.ToUpper(symbol), ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest trade: %w: %w", ErrUnavailable, err)
+	}
+
+	trade, err := models.DecodeTrade([]byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode trade data: %w: %w", ErrCorruptData, err)
+	}
+
+	if age, stale := s.tradeAge(trade); stale {
+		log.Printf("Warning: latest trade for %s is %s old, treating it as missing", strings.ToUpper(symbol), age)
+		return nil, fmt.Errorf("latest trade for %s is %s old: %w", strings.ToUpper(symbol), age, ErrStale)
+	}
+	return trade, nil
+}
+
+// tradeAge returns how old trade is, rounded to the second, and whether that
+// exceeds MaxLatestTradeAge
+func (s *RedisStore) tradeAge(trade *models.Trade) (time.Duration, bool) {
+	age := time.Since(trade.Time)
+	maxAge := s.config.Redis.MaxLatestTradeAge
+	return age.Round(time.Second), maxAge > 0 && age > maxAge
+}
+
+// GetLatestTrades fetches the latest trade of each symbol in one round trip,
+// keyed by upper-case symbol. Symbols without a trade, or whose trade is
+// older than MaxLatestTradeAge, are left out.
+func (s *RedisStore) GetLatestTrades(ctx context.Context, symbols []string) (map[string]*models.Trade, error) {
+	trades := make(map[string]*models.Trade, len(symbols))
+	if len(symbols) == 0 {
+		return trades, nil
+	}
+
+	keys := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		keys[i] = fmt.Sprintf("%strade:%s:latest", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
+	}
+
+	values := make([]interface{}, len(keys))
+	if s.config.Redis.Cluster {
+		// MGET cannot span hash slots; a pipeline of GETs is split per node
+		pipe := s.client.Pipeline()
+		cmds := make([]*redis.StringCmd, len(keys))
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("failed to get latest trades: %w: %w", ErrUnavailable, err)
+		}
+		for i, cmd := range cmds {
+			if data, err := cmd.Result(); err == nil {
+				values[i] = data
+			}
+		}
+	} else {
+		var err error
+		values, err = s.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest trades: %w: %w", ErrUnavailable, err)
+		}
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		trade, err := models.DecodeTrade([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode trade data for %s: %w: %w", symbols[i], ErrCorruptData, err)
+		}
+		if _, stale := s.tradeAge(trade); stale {
+			continue
+		}
+		trades[strings.ToUpper(symbols[i])] = trade
+	}
+
+	return trades, nil
+}
+
+// EnableKeyspaceNotifications turns on the Redis keyspace notifications
+// WatchTradeUpdates relies on, setting notify-keyspace-events to KEA on
+// every master. It changes the server's configuration, so callers only do
+// so when asked to; managed Redis services often disallow CONFIG, and need
+// the setting changed through the provider instead.
+func (s *RedisStore) EnableKeyspaceNotifications(ctx context.Context) error {
+	enable := func(ctx context.Context, client redis.UniversalClient) error {
+		return client.ConfigSet(ctx, "notify-keyspace-events", "KEA").Err()
+	}
+
+	// Cluster nodes only notify about their own keys, so configure every master
+	var err error
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return enable(ctx, client)
+		})
+	} else {
+		err = enable(ctx, s.client)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get latest trade: %w", err)
+		return fmt.Errorf("failed to enable keyspace notifications: %w", err)
 	}
+	return nil
+}
 
-	var trade models.Trade
-	if err := json.Unmarshal([]byte(data), &trade); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal trade data: %w", err)
+// CheckKeyspaceNotifications reports an error unless Redis sends the
+// keyspace notifications WatchTradeUpdates relies on. It only reads the
+// server's notify-keyspace-events setting: turning it on is left to the
+// operator, through redis.conf or the provider of a managed Redis, or to
+// EnableKeyspaceNotifications.
+func (s *RedisStore) CheckKeyspaceNotifications(ctx context.Context) error {
+	check := func(ctx context.Context, client redis.UniversalClient) error {
+		setting, err := client.ConfigGet(ctx, "notify-keyspace-events").Result()
+		if err != nil {
+			return fmt.Errorf("failed to read notify-keyspace-events: %w", err)
+		}
+		var flags string
+		if len(setting) == 2 {
+			flags, _ = setting[1].(string)
+		}
+		if !notifiesStringKeyspace(flags) {
+			return fmt.Errorf("keyspace notifications are off (notify-keyspace-events %q); set it to include K$ or KA", flags)
+		}
+		return nil
 	}
 
-	return &trade, nil
+	// Cluster nodes only notify about their own keys, so check every master
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return check(ctx, client)
+		})
+	}
+	return check(ctx, s.client)
 }
 
-// GetTradeHistory gets historical trades for a symbol within a time range
+// notifiesStringKeyspace reports whether notify-keyspace-events flags send
+// keyspace events for string commands such as SET
+func notifiesStringKeyspace(flags string) bool {
+	return strings.Contains(flags, "K") && strings.ContainsAny(flags, "A$")
+}
+
+// WatchTradeUpdates sends the latest trade of a symbol each time it is
+// updated, using keyspace notifications on its latest trade key. A slow
+// receiver only gets the most recent trade. The channel is closed when ctx
+// is cancelled or the subscription fails.
+func (s *RedisStore) WatchTradeUpdates(ctx context.Context, symbol string) (<-chan *models.Trade, error) {
+	symbol = strings.ToUpper(symbol)
+	latestKey := fmt.Sprintf("%strade:%s:latest", s.config.Redis.KeyPrefix, symbol)
+	channel := fmt.Sprintf("__keyspace@%d__:%s", s.db, latestKey)
+
+	// Keyspace notifications are node-local: in a cluster, listen on the
+	// master that owns the key
+	subscriber := s.client
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		master, err := cluster.MasterForKey(ctx, latestKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find cluster node for %s: %w", latestKey, err)
+		}
+		subscriber = master
+	}
+
+	pubsub := subscriber.Subscribe(ctx, channel)
+	// Wait for the subscription so a failure is reported to the caller
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to trade updates: %w", err)
+	}
+
+	updates := make(chan *models.Trade, 1)
+	go func() {
+		defer close(updates)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				if msg.Payload != "set" {
+					continue
+				}
+
+				trade, err := s.GetLatestTrade(ctx, symbol)
+				if err != nil {
+					if !errors.Is(err, ErrNotFound) && ctx.Err() == nil {
+						log.Printf("Warning: failed to read updated trade for %s: %v", symbol, err)
+					}
+					continue
+				}
+
+				// Replace an unread trade rather than fall behind
+				select {
+				case <-updates:
+				default:
+				}
+				updates <- trade
+			}
+		}
+	}()
+
+	return updates, nil
+}
+
+// tradeHistoryLimit caps how many trades GetTradeHistory returns
+const tradeHistoryLimit = 1000
+
+// GetTradeHistory gets historical trades for a symbol within a time range,
+// most recent first
 func (s *RedisStore) GetTradeHistory(ctx context.Context, symbol string, start, end time.Time) ([]models.AggTradeEvent, error) {
 	key := fmt.Sprintf("%strade:%s:history", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
 
@@ -256,16 +749,16 @@ func (s *RedisStore) GetTradeHistory(ctx context.Context, symbol string, start,
 			symbol, start.Format(time.RFC3339), end.Format(time.RFC3339), key)
 	}
 
-	// Get most recent trades first, limited to 1000 trades
+	// Get most recent trades first, limited to tradeHistoryLimit trades
 	trades, err := s.client.ZRevRangeByScore(ctx, key, &redis.ZRangeBy{
 		Min:    fmt.Sprintf("%d", startMs),
 		Max:    fmt.Sprintf("%d", endMs),
-		Count:  1000,
+		Count:  tradeHistoryLimit,
 		Offset: 0,
 	}).Result()
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get trade history: %w", err)
+		return nil, fmt.Errorf("failed to get trade history: %w: %w", ErrUnavailable, err)
 	}
 
 	if s.config.Debug {
@@ -276,30 +769,10 @@ func (s *RedisStore) GetTradeHistory(ctx context.Context, symbol string, start,
 	seenTrades := make(map[int64]bool)
 
 	for _, trade := range trades {
-		// Check if data is compressed
-		if len(trade) > 2 && trade[0] == 0x1f && trade[1] == 0x8b {
-			reader, err := gzip.NewReader(strings.NewReader(trade))
-			if err != nil {
-				if s.config.Debug {
-					log.Printf("Failed to create gzip reader for trade: %v", err)
-				}
-				continue
-			}
-			decompressed, err := io.ReadAll(reader)
-			reader.Close()
-			if err != nil {
-				if s.config.Debug {
-					log.Printf("Failed to decompress trade data: %v", err)
-				}
-				continue
-			}
-			trade = string(decompressed)
-		}
-
-		var event models.AggTradeEvent
-		if err := json.Unmarshal([]byte(trade), &event); err != nil {
+		event, err := decodeHistoryEntry(trade)
+		if err != nil {
 			if s.config.Debug {
-				log.Printf("Failed to unmarshal trade data: %v", err)
+				log.Printf("Failed to decode trade data: %v", err)
 			}
 			continue
 		}
@@ -318,10 +791,287 @@ func (s *RedisStore) GetTradeHistory(ctx context.Context, symbol string, start,
 	return events, nil
 }
 
-// Update24hVolume calculates and stores the 24-hour volume for a symbol
+// decodeHistoryEntry decodes a trade history member, which may be gzip or
+// zstd compressed
+func decodeHistoryEntry(entry string) (models.AggTradeEvent, error) {
+	var event models.AggTradeEvent
+	data, err := decompressEntry([]byte(entry))
+	if err != nil {
+		return event, err
+	}
+	if err := jsoncodec.Unmarshal(data, &event); err != nil {
+		return event, fmt.Errorf("failed to unmarshal trade: %w", err)
+	}
+	return event, nil
+}
+
+// historyPageSize is how many trades ScanTradeHistory reads per request
+const historyPageSize = 1000
+
+// ScanTradeHistory calls fn with every trade of symbol between start and
+// end, oldest first, reading the history in pages. Unlike GetTradeHistory it
+// is not capped; undecodable entries and repeated trade IDs are skipped. An
+// error from fn stops the scan and is returned.
+func (s *RedisStore) ScanTradeHistory(ctx context.Context, symbol string, start, end time.Time, fn func(models.AggTradeEvent) error) error {
+	key := fmt.Sprintf("%strade:%s:history", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
+	seen := make(map[int64]bool)
+
+	for offset := int64(0); ; offset += historyPageSize {
+		page, err := s.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+			Min:    fmt.Sprintf("%d", start.UnixMilli()),
+			Max:    fmt.Sprintf("%d", end.UnixMilli()),
+			Offset: offset,
+			Count:  historyPageSize,
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan trade history: %w: %w", ErrUnavailable, err)
+		}
+
+		for _, entry := range page {
+			event, err := decodeHistoryEntry(entry)
+			if err != nil || seen[event.Data.TradeID] {
+				continue
+			}
+			seen[event.Data.TradeID] = true
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		if len(page) < historyPageSize {
+			return nil
+		}
+	}
+}
+
+// ZRangeByScoreWithCount returns the members of the sorted set key scored
+// between min and max together with their count, read in one pipeline
+func (s *RedisStore) ZRangeByScoreWithCount(ctx context.Context, key, min, max string) ([]string, int64, error) {
+	return s.zRangeByScoreWithCount(ctx, key, &redis.ZRangeBy{Min: min, Max: max})
+}
+
+// zRangeByScoreWithCount returns the members of key in by, limited by its
+// offset and count, and the count of all members in its score range
+func (s *RedisStore) zRangeByScoreWithCount(ctx context.Context, key string, by *redis.ZRangeBy) ([]string, int64, error) {
+	pipe := s.client.Pipeline()
+	members := pipe.ZRangeByScore(ctx, key, by)
+	count := pipe.ZCount(ctx, key, by.Min, by.Max)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, 0, err
+	}
+	return members.Val(), count.Val(), nil
+}
+
+// GetTradeHistoryPage returns up to limit trades of symbol between start and
+// end, oldest first, skipping the first offset, and the number of trades in
+// the whole range
+func (s *RedisStore) GetTradeHistoryPage(ctx context.Context, symbol string, start, end time.Time, offset, limit int64) ([]models.AggTradeEvent, int64, error) {
+	key := fmt.Sprintf("%strade:%s:history", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
+	entries, total, err := s.zRangeByScoreWithCount(ctx, key, &redis.ZRangeBy{
+		Min:    fmt.Sprintf("%d", start.UnixMilli()),
+		Max:    fmt.Sprintf("%d", end.UnixMilli()),
+		Offset: offset,
+		Count:  limit,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get trade history page: %w: %w", ErrUnavailable, err)
+	}
+
+	events := make([]models.AggTradeEvent, 0, len(entries))
+	for _, entry := range entries {
+		event, err := decodeHistoryEntry(entry)
+		if err != nil {
+			if s.config.Debug {
+				log.Printf("Failed to decode trade data: %v", err)
+			}
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, total, nil
+}
+
+// CountTradesInRange returns how many trades of symbol between start and end
+// the history holds
+func (s *RedisStore) CountTradesInRange(ctx context.Context, symbol string, start, end time.Time) (int64, error) {
+	key := fmt.Sprintf("%strade:%s:history", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
+	count, err := s.client.ZCount(ctx, key, fmt.Sprintf("%d", start.UnixMilli()), fmt.Sprintf("%d", end.UnixMilli())).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count trades: %w: %w", ErrUnavailable, err)
+	}
+	return count, nil
+}
+
+// HistoryStart returns the time of the oldest trade of symbol the history
+// still holds. The history is trimmed by count as well as by age, so a
+// period that fits the retention window may still start before it.
+func (s *RedisStore) HistoryStart(ctx context.Context, symbol string) (time.Time, error) {
+	key := fmt.Sprintf("%strade:%s:history", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
+	oldest, err := s.client.ZRangeWithScores(ctx, key, 0, 0).Result()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get history start: %w: %w", ErrUnavailable, err)
+	}
+	if len(oldest) == 0 {
+		return time.Time{}, ErrNotFound
+	}
+	return time.UnixMilli(int64(oldest[0].Score)), nil
+}
+
+// HistoryCovers reports whether the history of symbol still holds every
+// trade since start. Only trimming to redis.max_trades_per_key can drop
+// trades within the retention period, so a history below that size covers
+// any period; a full one only covers periods from its oldest trade on.
+func (s *RedisStore) HistoryCovers(ctx context.Context, symbol string, start time.Time) (bool, error) {
+	if s.config.Redis.MaxTradesPerKey <= 0 {
+		return true, nil
+	}
+	key := fmt.Sprintf("%strade:%s:history", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
+	size, err := s.client.ZCard(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to get history size: %w: %w", ErrUnavailable, err)
+	}
+	if size < int64(s.config.Redis.MaxTradesPerKey) {
+		return true, nil
+	}
+	oldest, err := s.HistoryStart(ctx, symbol)
+	if err != nil {
+		return false, err
+	}
+	return !oldest.After(start), nil
+}
+
+// prioritySymbolsKey returns the key of the set of operator-managed priority symbols
+func (s *RedisStore) prioritySymbolsKey() string {
+	return fmt.Sprintf("%spriority:symbols", s.config.Redis.KeyPrefix)
+}
+
+// GetPrioritySymbols returns the sorted priority symbols managed at runtime
+func (s *RedisStore) GetPrioritySymbols(ctx context.Context) ([]string, error) {
+	symbols, err := s.client.SMembers(ctx, s.prioritySymbolsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get priority symbols: %w: %w", ErrUnavailable, err)
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+// AddPrioritySymbols adds symbols to the priority set
+func (s *RedisStore) AddPrioritySymbols(ctx context.Context, symbols ...string) error {
+	if err := s.client.SAdd(ctx, s.prioritySymbolsKey(), upperSymbols(symbols)...).Err(); err != nil {
+		return fmt.Errorf("failed to add priority symbols: %w", err)
+	}
+	return nil
+}
+
+// RemovePrioritySymbols removes symbols from the priority set
+func (s *RedisStore) RemovePrioritySymbols(ctx context.Context, symbols ...string) error {
+	if err := s.client.SRem(ctx, s.prioritySymbolsKey(), upperSymbols(symbols)...).Err(); err != nil {
+		return fmt.Errorf("failed to remove priority symbols: %w", err)
+	}
+	return nil
+}
+
+// upperSymbols converts symbols to upper case set members
+func upperSymbols(symbols []string) []interface{} {
+	members := make([]interface{}, len(symbols))
+	for i, symbol := range symbols {
+		members[i] = strings.ToUpper(symbol)
+	}
+	return members
+}
+
+// symbolHLLTTL keeps each day's unique symbol counter through the next day
+const symbolHLLTTL = 48 * time.Hour
+
+// uniqueSymbolsToday is the estimated number of symbols traded today (UTC)
+var uniqueSymbolsToday = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "binance_unique_symbols_today",
+	Help: "Estimated number of distinct symbols with trades today (UTC).",
+})
+
+// symbolHLLKey returns the key of the HyperLogLog of symbols traded on the
+// UTC day of date
+func (s *RedisStore) symbolHLLKey(date time.Time) string {
+	return fmt.Sprintf("%shll:symbols:%s", s.config.Redis.KeyPrefix, date.UTC().Format(time.DateOnly))
+}
+
+// AddToSymbolHLL counts symbol as traded today (UTC) in the day's
+// HyperLogLog, and updates binance_unique_symbols_today when the estimate
+// changes
+func (s *RedisStore) AddToSymbolHLL(ctx context.Context, symbol string) error {
+	now := time.Now()
+	pipe := s.client.Pipeline()
+	added := s.queueSymbolHLL(ctx, pipe, symbol, now)
+	pipe.Exec(ctx)
+	return s.noteSymbolHLL(ctx, added, now)
+}
+
+// queueSymbolHLL queues adding symbol to the HyperLogLog of now's day on pipe
+func (s *RedisStore) queueSymbolHLL(ctx context.Context, pipe redis.Pipeliner, symbol string, now time.Time) *redis.IntCmd {
+	key := s.symbolHLLKey(now)
+	added := pipe.PFAdd(ctx, key, strings.ToUpper(symbol))
+	pipe.Expire(ctx, key, symbolHLLTTL)
+	return added
+}
+
+// noteSymbolHLL checks the result of a queued queueSymbolHLL, and updates
+// binance_unique_symbols_today when it changed the estimate
+func (s *RedisStore) noteSymbolHLL(ctx context.Context, added *redis.IntCmd, now time.Time) error {
+	if err := added.Err(); err != nil {
+		return fmt.Errorf("failed to add symbol to HyperLogLog: %w: %w", ErrUnavailable, err)
+	}
+	if added.Val() == 1 {
+		count, err := s.EstimateUniqueSymbols(ctx, now)
+		if err != nil {
+			return err
+		}
+		uniqueSymbolsToday.Set(float64(count))
+	}
+	return nil
+}
+
+// EstimateUniqueSymbols returns the estimated number of distinct symbols
+// traded on the UTC day of date: exact for small counts, within about 1%
+// beyond
+func (s *RedisStore) EstimateUniqueSymbols(ctx context.Context, date time.Time) (int64, error) {
+	count, err := s.client.PFCount(ctx, s.symbolHLLKey(date)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unique symbols: %w: %w", ErrUnavailable, err)
+	}
+	return count, nil
+}
+
+// Get24hVolume returns the stored 24-hour quote volume of a symbol, or 0 when
+// it has not been calculated yet
+func (s *RedisStore) Get24hVolume(ctx context.Context, symbol string) (float64, error) {
+	volumeKey := fmt.Sprintf("%s%s:volume:24h", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
+	data, err := s.client.Get(ctx, volumeKey).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get 24h volume: %w: %w", ErrUnavailable, err)
+	}
+
+	volume, err := strconv.ParseFloat(data, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid 24h volume %q: %w: %w", data, ErrCorruptData, err)
+	}
+	return volume, nil
+}
+
+// Update24hVolume calculates and stores the 24-hour volume for a symbol. At
+// most maxVolumeScans run at once; others wait for a slot or for ctx.
 func (s *RedisStore) Update24hVolume(ctx context.Context, symbol string) error {
 	volumeKey := fmt.Sprintf("%s%s:volume:24h", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
 
+	// Wait for a scan slot before taking the lock, so waiting never holds it
+	select {
+	case s.volumeScans <- struct{}{}:
+		defer func() { <-s.volumeScans }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
 	// Use Redis lock to prevent concurrent updates
 	lockKey := fmt.Sprintf("%s%s:volume:lock", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
 	locked, err := s.client.SetNX(ctx, lockKey, "1", 30*time.Second).Result()
@@ -346,7 +1096,7 @@ func (s *RedisStore) Update24hVolume(ctx context.Context, symbol string) error {
 	end := time.Now()
 	start := end.Add(-24 * time.Hour)
 
-	trades, err := s.GetTradeHistory(ctx, symbol, start, end)
+	trades, err := s.volumeHistory(ctx, symbol, start, end)
 	if err != nil {
 		return fmt.Errorf("failed to get trade history: %w", err)
 	}
@@ -365,8 +1115,8 @@ func (s *RedisStore) Update24hVolume(ctx context.Context, symbol string) error {
 		totalVolume += quantity * price
 	}
 
-	// Store the volume with 5-minute expiry
-	err = s.client.Set(ctx, volumeKey, fmt.Sprintf("%.2f", totalVolume), 5*time.Minute).Err()
+	// Store the volume with a jittered expiry
+	err = s.client.Set(ctx, volumeKey, fmt.Sprintf("%.2f", totalVolume), volumeTTL+jitter(volumeTTLJitter)).Err()
 	if err != nil {
 		return fmt.Errorf("failed to store 24h volume: %w", err)
 	}
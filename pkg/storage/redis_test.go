@@ -1,7 +1,14 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,6 +16,9 @@ import (
 	"binance-redis-streamer/pkg/config"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func setupTestRedis() (*RedisStore, *miniredis.Miniredis, error) {
@@ -35,6 +45,381 @@ func setupTestRedis() (*RedisStore, *miniredis.Miniredis, error) {
 	return store, mr, nil
 }
 
+// setupTestSentinel starts a miniredis instance that answers the SENTINEL
+// commands used by the failover client, pointing at the given master
+func setupTestSentinel(master *miniredis.Miniredis, masterName, password string) (*miniredis.Miniredis, error) {
+	sentinel, err := miniredis.Run()
+	if err != nil {
+		return nil, err
+	}
+	if password != "" {
+		sentinel.RequireAuth(password)
+	}
+
+	err = sentinel.Server().Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		if len(args) < 2 || args[1] != masterName {
+			c.WriteNull()
+			return
+		}
+		switch strings.ToLower(args[0]) {
+		case "get-master-addr-by-name":
+			c.WriteStrings([]string{master.Host(), master.Port()})
+		case "sentinels":
+			c.WriteLen(0)
+		default:
+			c.WriteError("ERR unknown sentinel subcommand")
+		}
+	})
+	if err != nil {
+		sentinel.Close()
+		return nil, err
+	}
+
+	return sentinel, nil
+}
+
+func TestNewRedisStore_Sentinel(t *testing.T) {
+	master, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+
+	sentinel, err := setupTestSentinel(master, "mymaster", "sentinel-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sentinel.Close()
+
+	cfg := &config.Config{
+		Redis: config.RedisConfig{
+			URL:                "redis://localhost:0",
+			RetentionPeriod:    24 * time.Hour,
+			CleanupInterval:    1 * time.Hour,
+			KeyPrefix:          "test:",
+			SentinelAddrs:      []string{sentinel.Addr()},
+			SentinelMasterName: "mymaster",
+			SentinelPassword:   "sentinel-secret",
+		},
+	}
+
+	store, err := NewRedisStore(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sentinel-backed store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	trade := &models.Trade{
+		Symbol:    "BTCUSDT",
+		Price:     "50000.00",
+		Quantity:  "1.5",
+		TradeID:   1,
+		Time:      now,
+		EventTime: now,
+	}
+	if err := store.StoreTrade(context.Background(), trade); err != nil {
+		t.Fatalf("Failed to store trade through sentinel: %v", err)
+	}
+
+	// The write must have landed on the master the sentinel pointed at
+	if !master.Exists("test:trade:BTCUSDT:latest") {
+		t.Error("Expected latest trade key on the sentinel master")
+	}
+
+	// Wrong sentinel password must fail to resolve the master
+	cfg.Redis.SentinelPassword = "wrong"
+	if _, err := NewRedisStore(cfg); err == nil {
+		t.Error("Expected error with wrong sentinel password")
+	}
+}
+
+func TestNewRedisStore_Cluster(t *testing.T) {
+	// miniredis answers CLUSTER SLOTS as a single node owning every slot
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	cfg := &config.Config{
+		Redis: config.RedisConfig{
+			URL:             "redis://" + mr.Addr(),
+			RetentionPeriod: 24 * time.Hour,
+			CleanupInterval: 1 * time.Hour,
+			KeyPrefix:       "test:",
+			Cluster:         true,
+		},
+	}
+
+	store, err := NewRedisStore(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create cluster-backed store: %v", err)
+	}
+	defer store.Close()
+
+	if _, ok := store.GetRedisClient().(*redis.ClusterClient); !ok {
+		t.Fatalf("Expected a cluster client, got %T", store.GetRedisClient())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	now := time.Now()
+	trade := &models.Trade{Symbol: "BTCUSDT", Price: "50000.00", Quantity: "1.5", TradeID: 1, Time: now, EventTime: now}
+	if err := store.StoreTrade(ctx, trade); err != nil {
+		t.Fatalf("Failed to store trade through cluster client: %v", err)
+	}
+
+	latest, err := store.GetLatestTrade(ctx, "BTCUSDT")
+	if err != nil || latest == nil || latest.Price != "50000.00" {
+		t.Fatalf("Expected latest trade through cluster client, got %+v (%v)", latest, err)
+	}
+	history, err := store.GetTradeHistory(ctx, "BTCUSDT", now.Add(-time.Minute), now.Add(time.Minute))
+	if err != nil || len(history) != 1 {
+		t.Fatalf("Expected 1 trade in history, got %d (%v)", len(history), err)
+	}
+
+	// Keyspace notifications are subscribed on the node owning the key
+	updates, err := store.WatchTradeUpdates(ctx, "BTCUSDT")
+	if err != nil {
+		t.Fatalf("Failed to watch trade updates: %v", err)
+	}
+	mr.Publish("__keyspace@0__:test:trade:BTCUSDT:latest", "set")
+	select {
+	case <-updates:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a trade update through the cluster node")
+	}
+	cancel()
+	for range updates {
+	}
+
+	// Clusters have no logical databases
+	cfg.Redis.URL = "redis://" + mr.Addr() + "/1"
+	if _, err := NewRedisStore(cfg); err == nil {
+		t.Error("Expected error for a non-zero database in cluster mode")
+	}
+}
+
+// clusterSlot returns the Redis Cluster hash slot of key: the CRC16 of its
+// first non-empty {hash tag}, or else of the whole key, modulo 16384
+func clusterSlot(key string) int {
+	if start := strings.Index(key, "{"); start >= 0 {
+		if end := strings.Index(key[start+1:], "}"); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc % 16384)
+}
+
+// commandKeys returns the keys of cmd, for the commands the store sends
+func commandKeys(cmd redis.Cmder) []string {
+	args := cmd.Args()
+	var keys []interface{}
+	switch strings.ToLower(cmd.Name()) {
+	case "multi", "exec", "discard", "ping":
+	case "del", "unlink", "exists", "mget", "watch", "pfcount", "pfmerge", "sunion", "sinter":
+		keys = args[1:]
+	case "mset":
+		for i := 1; i < len(args); i += 2 {
+			keys = append(keys, args[i])
+		}
+	case "eval", "evalsha":
+		if n, ok := args[2].(int); ok {
+			keys = args[3 : 3+n]
+		}
+	default:
+		if len(args) > 1 {
+			keys = args[1:2]
+		}
+	}
+	out := make([]string, len(keys))
+	for i, key := range keys {
+		out[i] = fmt.Sprint(key)
+	}
+	return out
+}
+
+// slotRecorder is a client hook recording the keys of every MULTI
+// transaction and multi-key command, which Redis Cluster rejects unless
+// their keys share a slot
+type slotRecorder struct {
+	mu     sync.Mutex
+	groups [][]string
+}
+
+func (r *slotRecorder) record(keys []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.groups = append(r.groups, keys)
+}
+
+func (r *slotRecorder) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if keys := commandKeys(cmd); len(keys) > 1 {
+		r.record(keys)
+	}
+	return ctx, nil
+}
+
+func (r *slotRecorder) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (r *slotRecorder) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	// Plain pipelines are split by node by the cluster client; only
+	// transactions have to stay in one slot
+	transaction := len(cmds) > 0 && strings.EqualFold(cmds[0].Name(), "multi")
+	var keys []string
+	for _, cmd := range cmds {
+		cmdKeys := commandKeys(cmd)
+		if !transaction && len(cmdKeys) > 1 {
+			r.record(cmdKeys)
+		}
+		keys = append(keys, cmdKeys...)
+	}
+	if transaction {
+		r.record(keys)
+	}
+	return ctx, nil
+}
+
+func (r *slotRecorder) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestClusterSlot(t *testing.T) {
+	// Slots as reported by CLUSTER KEYSLOT
+	for key, want := range map[string]int{"foo": 12182, "bar": 5061, "{user1000}.following": 3443, "{user1000}.followers": 3443} {
+		if got := clusterSlot(key); got != want {
+			t.Errorf("clusterSlot(%q) = %d, want %d", key, got, want)
+		}
+	}
+}
+
+// TestClusterSafeOperations runs the store's transactions and multi-key
+// commands as in cluster mode and checks that each keeps its keys in one
+// hash slot, as Redis Cluster requires
+func TestClusterSafeOperations(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+	store.config.Redis.Cluster = true
+	recorder := &slotRecorder{}
+	store.client.AddHook(recorder)
+
+	ctx := context.Background()
+	now := time.Now()
+	for i, symbol := range []string{"BTCUSDT", "ETHUSDT"} {
+		trade := &models.Trade{Symbol: symbol, Price: "100", Quantity: "1", TradeID: int64(i + 1), Time: now, EventTime: now}
+		if err := store.StoreTrade(ctx, trade); err != nil {
+			t.Fatal(err)
+		}
+		backfilled := &models.Trade{Symbol: symbol, Price: "99", Quantity: "1", TradeID: int64(i + 10), Time: now.Add(-time.Minute), EventTime: now.Add(-time.Minute)}
+		if err := store.StoreBackfilledTrade(ctx, backfilled); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Update24hVolume(ctx, symbol); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.GetLatestTrades(ctx, []string{"BTCUSDT", "ETHUSDT"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordConnectionEvent(ctx, &models.ConnectionEvent{Kind: models.ConnectionUp, Group: "group-0", Time: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.QuarantinePayload(ctx, []byte("{"), errors.New("bad payload")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveStreamGroup(ctx, &models.StreamGroup{Group: "group-0", Connection: "a:1/1", Heartbeat: now}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	var snapshot bytes.Buffer
+	if _, err := store.SaveSnapshot(ctx, &snapshot, "test:"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.RestoreSnapshot(ctx, &snapshot, "test:"); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.groups) == 0 {
+		t.Fatal("Expected transactions to be recorded")
+	}
+	for _, keys := range recorder.groups {
+		for _, key := range keys[1:] {
+			if clusterSlot(key) != clusterSlot(keys[0]) {
+				t.Errorf("Keys %q and %q of one transaction or command hash to different cluster slots", keys[0], key)
+			}
+		}
+	}
+}
+
+func TestRedisStore_StoreBackfilledTrade(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	trade := func(id int64, at time.Time) *models.Trade {
+		return &models.Trade{Symbol: "BTCUSDT", Price: "50000.00", Quantity: "1", TradeID: id, Time: at, EventTime: at}
+	}
+
+	// Without a latest trade a backfilled one becomes it
+	if err := store.StoreBackfilledTrade(ctx, trade(1, now.Add(-2*time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreTrade(ctx, trade(3, now)); err != nil {
+		t.Fatal(err)
+	}
+
+	// An older one is stored in the history but leaves the latest alone
+	if err := store.StoreBackfilledTrade(ctx, trade(2, now.Add(-time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := store.GetLatestTrade(ctx, "BTCUSDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.TradeID != 3 {
+		t.Errorf("Expected trade 3 to stay the latest, got %d", latest.TradeID)
+	}
+	history, err := store.GetTradeHistory(ctx, "BTCUSDT", now.Add(-time.Hour), now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Errorf("Expected 3 trades in the history, got %d", len(history))
+	}
+
+	// A newer one replaces it
+	if err := store.StoreBackfilledTrade(ctx, trade(4, now.Add(time.Second))); err != nil {
+		t.Fatal(err)
+	}
+	if latest, err = store.GetLatestTrade(ctx, "BTCUSDT"); err != nil || latest.TradeID != 4 {
+		t.Errorf("Expected trade 4 to become the latest, got %+v (%v)", latest, err)
+	}
+}
+
 func TestRedisStore_StoreTrade(t *testing.T) {
 	store, mr, err := setupTestRedis()
 	if err != nil {
@@ -116,7 +501,8 @@ func BenchmarkStoreRawTrade(b *testing.B) {
 	defer mr.Close()
 	defer store.Close()
 
-	rawData := []byte(`{"stream":"btcusdt@aggTrade","data":{"e":"aggTrade","E":1625232862,"s":"BTCUSDT","p":"50000.00","q":"1.5","T":1625232862,"t":12345,"m":true}}`)
+	now := time.Now().UnixMilli()
+	rawData := []byte(fmt.Sprintf(`{"stream":"btcusdt@aggTrade","data":{"e":"aggTrade","E":%d,"s":"BTCUSDT","p":"50000.00","q":"1.5","T":%d,"t":12345,"m":true}}`, now, now))
 
 	ctx := context.Background()
 	b.ResetTimer()
@@ -130,36 +516,250 @@ func BenchmarkStoreRawTrade(b *testing.B) {
 	})
 }
 
-func BenchmarkGetTradeHistory(b *testing.B) {
+func TestRedisStore_GetLatestTrades(t *testing.T) {
+	for _, cluster := range []bool{false, true} {
+		t.Run(fmt.Sprintf("cluster=%v", cluster), func(t *testing.T) {
+			mr, err := miniredis.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer mr.Close()
+
+			cfg := config.DefaultConfig()
+			cfg.Redis.URL = "redis://" + mr.Addr()
+			cfg.Redis.KeyPrefix = "test:"
+			cfg.Redis.Cluster = cluster
+			store, err := NewRedisStore(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+
+			ctx := context.Background()
+			now := time.Now()
+			for _, trade := range []*models.Trade{
+				{Symbol: "BTCUSDT", Price: "50000.00", Quantity: "1", TradeID: 1, Time: now, EventTime: now},
+				{Symbol: "ETHUSDT", Price: "3000.00", Quantity: "2", TradeID: 2, Time: now, EventTime: now},
+			} {
+				if err := store.StoreTrade(ctx, trade); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Symbols without a trade are omitted rather than failing the batch
+			trades, err := store.GetLatestTrades(ctx, []string{"btcusdt", "SOLUSDT", "ETHUSDT"})
+			if err != nil {
+				t.Fatalf("Failed to get latest trades: %v", err)
+			}
+			if len(trades) != 2 || trades["BTCUSDT"].Price != "50000.00" || trades["ETHUSDT"].Price != "3000.00" {
+				t.Errorf("Expected BTCUSDT and ETHUSDT trades, got %+v", trades)
+			}
+			if _, ok := trades["SOLUSDT"]; ok {
+				t.Error("Expected SOLUSDT to be omitted")
+			}
+
+			if trades, err := store.GetLatestTrades(ctx, nil); err != nil || len(trades) != 0 {
+				t.Errorf("Expected an empty result for no symbols, got %v (%v)", trades, err)
+			}
+		})
+	}
+}
+
+func TestRedisStore_ReadsLegacyAndVersionedTrades(t *testing.T) {
 	store, mr, err := setupTestRedis()
 	if err != nil {
-		b.Fatal(err)
+		t.Fatal(err)
 	}
 	defer mr.Close()
 	defer store.Close()
 
-	// Populate some test data
-	ctx := context.Background()
+	// A record written before trades carried a schema version, and the
+	// current format
+	mr.Set("test:trade:BTCUSDT:latest", `{"Symbol":"BTCUSDT","Price":"50000.00","Quantity":"1.5","TradeID":7,"Time":"2024-01-01T00:00:00Z","EventTime":"2024-01-01T00:00:00Z","IsBuyerMaker":true}`)
 	now := time.Now()
-	trade := &models.Trade{
-		Symbol:    "BTCUSDT",
-		Price:     "50000.00",
-		Quantity:  "1.5",
-		TradeID:   12345,
-		Time:      now,
-		EventTime: now,
+	if err := store.StoreTrade(context.Background(), &models.Trade{Symbol: "ETHUSDT", Price: "3000.00", Quantity: "2", TradeID: 8, Time: now, EventTime: now}); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := mr.Get("test:trade:ETHUSDT:latest"); !strings.Contains(stored, fmt.Sprintf(`"version":%d`, models.TradeSchemaVersion)) {
+		t.Errorf("Expected stored trade to carry the schema version, got %s", stored)
 	}
 
-	for i := 0; i < 100; i++ {
+	ctx := context.Background()
+	legacy, err := store.GetLatestTrade(ctx, "BTCUSDT")
+	if err != nil || legacy == nil || legacy.Price != "50000.00" || legacy.TradeID != 7 || !legacy.IsBuyerMaker {
+		t.Errorf("Expected the legacy trade to decode, got %+v (%v)", legacy, err)
+	}
+	versioned, err := store.GetLatestTrade(ctx, "ETHUSDT")
+	if err != nil || versioned == nil || versioned.Price != "3000.00" || versioned.TradeID != 8 {
+		t.Errorf("Expected the versioned trade to decode, got %+v (%v)", versioned, err)
+	}
+
+	trades, err := store.GetLatestTrades(ctx, []string{"BTCUSDT", "ETHUSDT"})
+	if err != nil || len(trades) != 2 {
+		t.Errorf("Expected both trades from the batch read, got %v (%v)", trades, err)
+	}
+
+	// Records from a newer schema are rejected rather than misread
+	mr.Set("test:trade:SOLUSDT:latest", `{"version":99,"Symbol":"SOLUSDT"}`)
+	if _, err := store.GetLatestTrade(ctx, "SOLUSDT"); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("Expected an unknown schema version to fail with ErrCorruptData, got %v", err)
+	}
+}
+
+func TestRedisStore_ReadErrors(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if trade, err := store.GetLatestTrade(ctx, "BTCUSDT"); !errors.Is(err, ErrNotFound) || trade != nil {
+		t.Errorf("Expected ErrNotFound for a symbol without trades, got %+v (%v)", trade, err)
+	}
+
+	mr.Set("test:trade:BTCUSDT:latest", "not json")
+	if _, err := store.GetLatestTrade(ctx, "BTCUSDT"); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("Expected ErrCorruptData for an undecodable trade, got %v", err)
+	}
+	if _, err := store.GetLatestTrades(ctx, []string{"BTCUSDT"}); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("Expected ErrCorruptData from the batch read, got %v", err)
+	}
+	mr.Set("test:BTCUSDT:volume:24h", "lots")
+	if _, err := store.Get24hVolume(ctx, "BTCUSDT"); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("Expected ErrCorruptData for an invalid volume, got %v", err)
+	}
+
+	// With Redis gone every read reports it as unavailable, not as missing
+	mr.Close()
+	if _, err := store.GetLatestTrade(ctx, "BTCUSDT"); !errors.Is(err, ErrUnavailable) || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrUnavailable from GetLatestTrade, got %v", err)
+	}
+	if _, err := store.GetLatestTrades(ctx, []string{"BTCUSDT"}); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable from GetLatestTrades, got %v", err)
+	}
+	if _, err := store.GetTradeHistory(ctx, "BTCUSDT", time.Now().Add(-time.Hour), time.Now()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable from GetTradeHistory, got %v", err)
+	}
+	if _, err := store.Get24hVolume(ctx, "BTCUSDT"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable from Get24hVolume, got %v", err)
+	}
+}
+
+func TestRedisStore_StaleLatestTrade(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+	store.config.Redis.MaxLatestTradeAge = 5 * time.Minute
+
+	ctx := context.Background()
+	now := time.Now()
+	fresh := &models.Trade{Symbol: "BTCUSDT", Price: "50000", Quantity: "1", Time: now.Add(-time.Minute), EventTime: now}
+	// A delisted symbol whose last trade is still stored
+	stale := &models.Trade{Symbol: "LUNAUSDT", Price: "0.0001", Quantity: "1", Time: now.Add(-2 * time.Hour), EventTime: now.Add(-2 * time.Hour)}
+	for _, trade := range []*models.Trade{fresh, stale} {
 		if err := store.StoreTrade(ctx, trade); err != nil {
-			b.Fatal(err)
+			t.Fatal(err)
 		}
 	}
 
-	b.ResetTimer()
+	if trade, err := store.GetLatestTrade(ctx, "BTCUSDT"); err != nil || trade.Price != "50000" {
+		t.Errorf("Expected the fresh trade, got %+v (%v)", trade, err)
+	}
+	trade, err := store.GetLatestTrade(ctx, "LUNAUSDT")
+	if !errors.Is(err, ErrStale) || !errors.Is(err, ErrNotFound) || trade != nil {
+		t.Errorf("Expected ErrStale matching ErrNotFound, got %+v (%v)", trade, err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "2h0m0s old") {
+		t.Errorf("Expected the error to name the trade's age, got %v", err)
+	}
 
-	end := time.Now()
-	start := end.Add(-24 * time.Hour)
+	trades, err := store.GetLatestTrades(ctx, []string{"BTCUSDT", "LUNAUSDT"})
+	if err != nil || len(trades) != 1 || trades["BTCUSDT"] == nil {
+		t.Errorf("Expected only the fresh trade from the batch read, got %v (%v)", trades, err)
+	}
+
+	// Zero disables the check
+	store.config.Redis.MaxLatestTradeAge = 0
+	if trade, err := store.GetLatestTrade(ctx, "LUNAUSDT"); err != nil || trade.Price != "0.0001" {
+		t.Errorf("Expected the old trade with the check disabled, got %+v (%v)", trade, err)
+	}
+}
+
+// BenchmarkGetLatestTrades compares one batched fetch of 100 symbols with
+// fetching them one at a time
+func BenchmarkGetLatestTrades(b *testing.B) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	symbols := make([]string, 100)
+	for i := range symbols {
+		symbols[i] = fmt.Sprintf("SYM%dUSDT", i)
+		trade := &models.Trade{Symbol: symbols[i], Price: "1.00", Quantity: "1", TradeID: int64(i), Time: now, EventTime: now}
+		if err := store.StoreTrade(ctx, trade); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+
+	b.Run("Batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := store.GetLatestTrades(ctx, symbols); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, symbol := range symbols {
+				if _, err := store.GetLatestTrade(ctx, symbol); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkGetTradeHistory(b *testing.B) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	// Populate some test data
+	ctx := context.Background()
+	now := time.Now()
+	trade := &models.Trade{
+		Symbol:    "BTCUSDT",
+		Price:     "50000.00",
+		Quantity:  "1.5",
+		TradeID:   12345,
+		Time:      now,
+		EventTime: now,
+	}
+
+	for i := 0; i < 100; i++ {
+		if err := store.StoreTrade(ctx, trade); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+
+	end := time.Now()
+	start := end.Add(-24 * time.Hour)
 
 	b.Run("GetTradeHistory", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
@@ -170,3 +770,597 @@ func BenchmarkGetTradeHistory(b *testing.B) {
 		}
 	})
 }
+
+func TestRedisStore_CloseWaitsForBackgroundWork(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatalf("Failed to setup test Redis: %v", err)
+	}
+	defer mr.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	trade := &models.Trade{
+		Symbol:   "BTCUSDT",
+		TradeID:  1,
+		Price:    "50000.00",
+		Quantity: "1.0",
+		Time:     time.Now(),
+	}
+	if err := store.StoreTrade(context.Background(), trade); err != nil {
+		t.Fatalf("StoreTrade failed: %v", err)
+	}
+
+	// A slow volume update still in flight when Close is called
+	updateErr := make(chan error, 1)
+	store.goBackground(func(ctx context.Context) {
+		time.Sleep(50 * time.Millisecond)
+		updateErr <- store.Update24hVolume(ctx, "ETHUSDT")
+	})
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if err := <-updateErr; err != nil {
+		t.Errorf("Background update failed: %v", err)
+	}
+	if strings.Contains(logs.String(), "closed") {
+		t.Errorf("Expected no closed-connection errors, got logs:\n%s", logs.String())
+	}
+	if !mr.Exists("test:BTCUSDT:volume:24h") {
+		t.Error("Expected the background 24h volume update to complete before Close")
+	}
+
+	// Work submitted after Close is rejected
+	if store.goBackground(func(context.Context) {}) {
+		t.Error("Expected background work to be rejected after Close")
+	}
+}
+
+func TestRedisStore_CloseContextCancelsStuckWork(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatalf("Failed to setup test Redis: %v", err)
+	}
+	defer mr.Close()
+
+	store.goBackground(func(ctx context.Context) {
+		<-ctx.Done()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := store.CloseContext(ctx); err != nil {
+		t.Fatalf("CloseContext failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CloseContext took %v, expected it to give up after its deadline", elapsed)
+	}
+}
+
+func TestRedisStore_PrioritySymbols(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatalf("Failed to setup test Redis: %v", err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.AddPrioritySymbols(ctx, "ethusdt", "BTCUSDT", "ETHUSDT"); err != nil {
+		t.Fatalf("AddPrioritySymbols failed: %v", err)
+	}
+
+	symbols, err := store.GetPrioritySymbols(ctx)
+	if err != nil {
+		t.Fatalf("GetPrioritySymbols failed: %v", err)
+	}
+	if strings.Join(symbols, ",") != "BTCUSDT,ETHUSDT" {
+		t.Errorf("Expected [BTCUSDT ETHUSDT], got %v", symbols)
+	}
+	if ok, _ := mr.SIsMember("test:priority:symbols", "BTCUSDT"); !ok {
+		t.Error("Expected symbols in the test:priority:symbols set")
+	}
+
+	if err := store.RemovePrioritySymbols(ctx, "btcusdt"); err != nil {
+		t.Fatalf("RemovePrioritySymbols failed: %v", err)
+	}
+	symbols, _ = store.GetPrioritySymbols(ctx)
+	if strings.Join(symbols, ",") != "ETHUSDT" {
+		t.Errorf("Expected [ETHUSDT] after removal, got %v", symbols)
+	}
+
+	// With Redis gone the read reports it as unavailable
+	mr.Close()
+	if _, err := store.GetPrioritySymbols(ctx); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable with Redis down, got %v", err)
+	}
+}
+
+func TestRedisStore_SymbolHLL(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatalf("Failed to setup test Redis: %v", err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 50; i++ {
+		// Each symbol twice, in both cases
+		for _, symbol := range []string{fmt.Sprintf("SYM%dUSDT", i), fmt.Sprintf("sym%dusdt", i)} {
+			if err := store.AddToSymbolHLL(ctx, symbol); err != nil {
+				t.Fatalf("AddToSymbolHLL failed: %v", err)
+			}
+		}
+	}
+
+	now := time.Now()
+	count, err := store.EstimateUniqueSymbols(ctx, now)
+	if err != nil {
+		t.Fatalf("EstimateUniqueSymbols failed: %v", err)
+	}
+	if count != 50 {
+		t.Errorf("Expected 50 unique symbols, got %d", count)
+	}
+	if got := testutil.ToFloat64(uniqueSymbolsToday); got != 50 {
+		t.Errorf("Expected binance_unique_symbols_today at 50, got %v", got)
+	}
+
+	key := "test:hll:symbols:" + now.UTC().Format(time.DateOnly)
+	if ttl := mr.TTL(key); ttl != symbolHLLTTL {
+		t.Errorf("Expected %s to expire in %v, got %v", key, symbolHLLTTL, ttl)
+	}
+	if count, err := store.EstimateUniqueSymbols(ctx, now.AddDate(0, 0, -1)); err != nil || count != 0 {
+		t.Errorf("Expected no symbols yesterday, got %d, %v", count, err)
+	}
+}
+
+// roundTripRecorder is a client hook recording the command names sent in
+// each round trip, and every command's arguments
+type roundTripRecorder struct {
+	mu    sync.Mutex
+	trips [][]string
+	args  []string
+}
+
+func (r *roundTripRecorder) record(cmds []redis.Cmder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		names[i] = cmd.Name()
+		r.args = append(r.args, strings.TrimSpace(fmt.Sprintln(cmd.Args()...)))
+	}
+	r.trips = append(r.trips, names)
+}
+
+func (r *roundTripRecorder) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	r.record([]redis.Cmder{cmd})
+	return ctx, nil
+}
+
+func (r *roundTripRecorder) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (r *roundTripRecorder) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	r.record(cmds)
+	return ctx, nil
+}
+
+func (r *roundTripRecorder) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestRedisStore_StoreTradeCountsSymbolInPipeline(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatalf("Failed to setup test Redis: %v", err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	recorder := &roundTripRecorder{}
+	store.client.AddHook(recorder)
+
+	ctx := context.Background()
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		trade := &models.Trade{Symbol: "BTCUSDT", Price: "100", Quantity: "1", TradeID: int64(i + 1), Time: now, EventTime: now}
+		if err := store.StoreTrade(ctx, trade); err != nil {
+			t.Fatalf("StoreTrade failed: %v", err)
+		}
+	}
+
+	if count, err := store.EstimateUniqueSymbols(ctx, now); err != nil || count != 1 {
+		t.Errorf("Expected 1 unique symbol, got %d, %v", count, err)
+	}
+	for _, trip := range recorder.trips {
+		for _, name := range trip {
+			if name == "pfadd" && !strings.Contains(strings.Join(trip, " "), "sadd") {
+				t.Errorf("Expected PFADD to be pipelined with SADD, got round trip %v", trip)
+			}
+		}
+	}
+}
+
+func TestRedisStore_StoreRawTradeRejectsInvalidTimestamps(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	year2100 := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	for _, tradeTime := range []int64{0, -1, year2100} {
+		raw := []byte(fmt.Sprintf(`{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","p":"1","q":"1","T":%d,"t":1}}`, tradeTime))
+		if err := store.StoreRawTrade(ctx, "BTCUSDT", raw); !errors.Is(err, ErrInvalidTradeTime) {
+			t.Errorf("Expected ErrInvalidTradeTime for T=%d, got %v", tradeTime, err)
+		}
+	}
+
+	if mr.Exists("test:trade:BTCUSDT:history") {
+		t.Error("Expected rejected trades to not be stored")
+	}
+
+	valid := []byte(fmt.Sprintf(`{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","p":"1","q":"1","T":%d,"t":2}}`, time.Now().UnixMilli()))
+	if err := store.StoreRawTrade(ctx, "BTCUSDT", valid); err != nil {
+		t.Fatalf("Expected current trade to be stored, got %v", err)
+	}
+}
+
+func TestRedisStore_StoreRawTradeQuarantinesMissingTradeTime(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	for _, raw := range []string{
+		`{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","p":"1","q":"1","t":1}}`,
+		`{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","p":"1","q":"1","T":null,"t":1}}`,
+	} {
+		err := store.StoreRawTrade(ctx, "BTCUSDT", []byte(raw))
+		if !errors.Is(err, ErrInvalidTradeTime) || !errors.Is(err, models.ErrMissingTradeTime) {
+			t.Errorf("Expected a missing trade time for %s, got %v", raw, err)
+		}
+	}
+	if mr.Exists("test:trade:BTCUSDT:history") {
+		t.Error("Expected trades without a time to not be stored")
+	}
+
+	payloads, err := store.GetQuarantinedPayloads(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 2 || payloads[0].Reason != "missing_trade_time" {
+		t.Errorf("Expected both trades quarantined as missing_trade_time, got %+v", payloads)
+	}
+}
+
+func FuzzRawTradeTime(f *testing.F) {
+	f.Add([]byte(`{"stream":"btcusdt@trade","data":{"s":"BTCUSDT","T":1672515782136,"t":1}}`))
+	f.Add([]byte(`{"data":{"t":1}}`))
+	f.Add([]byte(`{"data":{"T":null}}`))
+	f.Add([]byte(`{"data":{"T":"1"}}`))
+	f.Add([]byte(`{"data":{"T":1e3}}`))
+	f.Add([]byte(`{"data":null}`))
+	f.Add([]byte(`[]`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tradeTime, err := rawTradeTime(data)
+		if err == nil {
+			err = validateTradeTime(tradeTime, time.Now())
+		}
+		if err != nil {
+			if !errors.Is(err, ErrInvalidTradeTime) {
+				t.Errorf("Expected ErrInvalidTradeTime for %q, got %v", data, err)
+			}
+			return
+		}
+		if tradeTime <= 0 {
+			t.Errorf("Accepted trade time %d of %q", tradeTime, data)
+		}
+	})
+}
+
+func TestValidateTradeTime(t *testing.T) {
+	now := time.Date(2024, 12, 26, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		tradeTime time.Time
+		valid     bool
+	}{
+		{"now", now, true},
+		{"just under a day old", now.Add(-23 * time.Hour), true},
+		{"just under a day ahead", now.Add(23 * time.Hour), true},
+		{"two days old", now.Add(-48 * time.Hour), false},
+		{"two days ahead", now.Add(48 * time.Hour), false},
+		{"non-UTC zone", now.In(time.FixedZone("UTC+9", 9*3600)), true},
+	}
+	for _, tt := range tests {
+		err := validateTradeTime(tt.tradeTime.UnixMilli(), now)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: validateTradeTime error = %v, want valid=%v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestRedisStore_WatchTradeUpdates(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, err := store.WatchTradeUpdates(ctx, "btcusdt")
+	if err != nil {
+		t.Fatalf("Failed to watch trade updates: %v", err)
+	}
+
+	// miniredis does not emit keyspace notifications, so publish them by hand
+	channel := "__keyspace@0__:test:trade:BTCUSDT:latest"
+	mr.Set("test:trade:BTCUSDT:latest", `{"symbol":"BTCUSDT","price":"50000.00"}`)
+	mr.Publish(channel, "expire") // Only writes are forwarded
+	mr.Publish(channel, "set")
+
+	select {
+	case trade := <-updates:
+		if trade.Price != "50000.00" {
+			t.Errorf("Expected price 50000.00, got %s", trade.Price)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a trade update")
+	}
+
+	select {
+	case trade := <-updates:
+		t.Errorf("Expected a single update, got %+v", trade)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Error("Expected channel to be closed after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected channel to close after cancel")
+	}
+}
+
+func TestRedisStore_TradeHistoryPageAndCount(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 0; i < 25; i++ {
+		tradeTime := base.Add(time.Duration(i) * time.Second)
+		trade := &models.Trade{Symbol: "BTCUSDT", Price: "100", Quantity: "1", TradeID: int64(i + 1), Time: tradeTime, EventTime: tradeTime}
+		if err := store.StoreTrade(ctx, trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The third page of ten holds the last five trades, oldest first
+	page, total, err := store.GetTradeHistoryPage(ctx, "btcusdt", base, base.Add(time.Minute), 20, 10)
+	if err != nil {
+		t.Fatalf("GetTradeHistoryPage() error = %v", err)
+	}
+	if total != 25 || len(page) != 5 || page[0].Data.TradeID != 21 || page[4].Data.TradeID != 25 {
+		t.Errorf("Expected trades 21-25 of 25, got %d trades of %d", len(page), total)
+	}
+
+	count, err := store.CountTradesInRange(ctx, "BTCUSDT", base.Add(5*time.Second), base.Add(9*time.Second))
+	if err != nil || count != 5 {
+		t.Errorf("CountTradesInRange() = %d, %v, want 5", count, err)
+	}
+
+	members, total, err := store.ZRangeByScoreWithCount(ctx, "test:trade:BTCUSDT:history",
+		fmt.Sprintf("%d", base.UnixMilli()), fmt.Sprintf("%d", base.Add(2*time.Second).UnixMilli()))
+	if err != nil || len(members) != 3 || total != 3 {
+		t.Errorf("ZRangeByScoreWithCount() = %d members, %d, %v, want 3 and 3", len(members), total, err)
+	}
+
+	// A missing key is an empty range, not an error
+	members, total, err = store.ZRangeByScoreWithCount(ctx, "test:missing", "-inf", "+inf")
+	if err != nil || len(members) != 0 || total != 0 {
+		t.Errorf("ZRangeByScoreWithCount(missing) = %v, %d, %v", members, total, err)
+	}
+
+	mr.Close()
+	if _, err := store.CountTradesInRange(ctx, "BTCUSDT", base, base.Add(time.Minute)); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable with Redis down, got %v", err)
+	}
+}
+
+func TestRedisStore_HistoryCovers(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+	store.config.Redis.MaxTradesPerKey = 10
+
+	ctx := context.Background()
+	if _, err := store.HistoryStart(ctx, "BTCUSDT"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an empty history, got %v", err)
+	}
+
+	// Trimming by count drops the first five trades although they are well
+	// within the retention period
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 0; i < 15; i++ {
+		tradeTime := base.Add(time.Duration(i) * time.Second)
+		trade := &models.Trade{Symbol: "BTCUSDT", Price: "100", Quantity: "1", TradeID: int64(i + 1), Time: tradeTime, EventTime: tradeTime}
+		if err := store.StoreTrade(ctx, trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldest, err := store.HistoryStart(ctx, "btcusdt")
+	if err != nil || !oldest.Equal(base.Add(5*time.Second)) {
+		t.Errorf("HistoryStart() = %v, %v, want %v", oldest, err, base.Add(5*time.Second))
+	}
+	if covered, err := store.HistoryCovers(ctx, "BTCUSDT", base); err != nil || covered {
+		t.Errorf("HistoryCovers(trimmed start) = %v, %v, want false", covered, err)
+	}
+	if covered, err := store.HistoryCovers(ctx, "BTCUSDT", base.Add(5*time.Second)); err != nil || !covered {
+		t.Errorf("HistoryCovers(oldest trade) = %v, %v, want true", covered, err)
+	}
+
+	// A history below the cap was never trimmed, whenever its oldest trade
+	eth := &models.Trade{Symbol: "ETHUSDT", Price: "10", Quantity: "1", TradeID: 1, Time: base.Add(time.Minute), EventTime: base.Add(time.Minute)}
+	if err := store.StoreTrade(ctx, eth); err != nil {
+		t.Fatal(err)
+	}
+	for _, symbol := range []string{"ETHUSDT", "SOLUSDT"} {
+		if covered, err := store.HistoryCovers(ctx, symbol, base); err != nil || !covered {
+			t.Errorf("HistoryCovers(%s) = %v, %v, want true", symbol, covered, err)
+		}
+	}
+}
+
+func TestRedisStore_Update24hVolumeBoundsConcurrentScans(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	// Scans block until released, recording how many run at once
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	release := make(chan struct{})
+	store.volumeHistory = func(ctx context.Context, symbol string, start, end time.Time) ([]models.AggTradeEvent, error) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return []models.AggTradeEvent{{Data: models.TradeData{Price: "10", Quantity: "2"}}}, nil
+	}
+
+	const symbols = maxVolumeScans * 3
+	ctx := context.Background()
+	errs := make(chan error, symbols)
+	for i := 0; i < symbols; i++ {
+		go func(symbol string) {
+			errs <- store.Update24hVolume(ctx, symbol)
+		}(fmt.Sprintf("SYM%dUSDT", i))
+	}
+
+	// Let every update reach the semaphore before releasing any scan
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		running := inFlight
+		mu.Unlock()
+		if running == maxVolumeScans || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < symbols; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Update24hVolume() error = %v", err)
+		}
+	}
+	if peak != maxVolumeScans {
+		t.Errorf("Expected at most %d concurrent scans, peaked at %d", maxVolumeScans, peak)
+	}
+
+	// Every symbol got its volume, expiring at a jittered time
+	for i := 0; i < symbols; i++ {
+		key := fmt.Sprintf("test:SYM%dUSDT:volume:24h", i)
+		if got, _ := mr.Get(key); got != "20.00" {
+			t.Errorf("%s = %q, want 20.00", key, got)
+		}
+		if ttl := mr.TTL(key); ttl < volumeTTL || ttl >= volumeTTL+volumeTTLJitter {
+			t.Errorf("%s TTL = %v, want within [%v, %v)", key, ttl, volumeTTL, volumeTTL+volumeTTLJitter)
+		}
+	}
+}
+
+func TestRedisStore_Update24hVolumeWaitHonorsContext(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	// All scan slots are taken
+	for i := 0; i < maxVolumeScans; i++ {
+		store.volumeScans <- struct{}{}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := store.Update24hVolume(ctx, "BTCUSDT"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait for a scan slot to end with the context, got %v", err)
+	}
+}
+
+func TestRedisStore_EnableKeyspaceNotifications(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+	recorder := &roundTripRecorder{}
+	store.client.AddHook(recorder)
+
+	// miniredis has no CONFIG, so the command fails after it is sent
+	err = store.EnableKeyspaceNotifications(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to enable keyspace notifications") {
+		t.Errorf("Expected the CONFIG SET failure reported, got %v", err)
+	}
+	if len(recorder.args) != 1 || recorder.args[0] != "config set notify-keyspace-events KEA" {
+		t.Errorf("Expected CONFIG SET notify-keyspace-events KEA, got %v", recorder.args)
+	}
+}
+
+func TestNotifiesStringKeyspace(t *testing.T) {
+	tests := map[string]bool{
+		"":     false,
+		"KEA":  true,
+		"K$":   true,
+		"AK":   true,
+		"E$":   false, // Keyevent notifications only
+		"Kgx":  false, // No string commands
+		"KElx": false,
+	}
+	for flags, want := range tests {
+		if got := notifiesStringKeyspace(flags); got != want {
+			t.Errorf("notifiesStringKeyspace(%q) = %v, want %v", flags, got, want)
+		}
+	}
+}
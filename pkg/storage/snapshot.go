@@ -0,0 +1,318 @@
+package storage
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SnapshotVersion is the format version written by SaveSnapshot
+const SnapshotVersion = 1
+
+// snapshotScanCount is the SCAN batch size of SaveSnapshot
+const snapshotScanCount = 500
+
+// Redis types a snapshot holds, as reported by TYPE
+const (
+	snapshotString = "string"
+	snapshotHash   = "hash"
+	snapshotList   = "list"
+	snapshotSet    = "set"
+	snapshotZSet   = "zset"
+)
+
+// snapshotHeader is the first record of a snapshot
+type snapshotHeader struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Prefix    string    `json:"prefix"`
+}
+
+// SnapshotKey is a Redis key as saved in a snapshot. Values are kept as
+// bytes, since trade history members may be compressed.
+type SnapshotKey struct {
+	Key  string        `json:"key"`
+	Type string        `json:"type"`
+	TTL  time.Duration `json:"ttl,omitempty"` // Time left to live when saved; 0 for none
+
+	Value   []byte            `json:"value,omitempty"`   // String
+	Fields  map[string][]byte `json:"fields,omitempty"`  // Hash
+	Members [][]byte          `json:"members,omitempty"` // List in order, set or sorted set
+	Scores  []float64         `json:"scores,omitempty"`  // Sorted set scores, by member
+}
+
+// SnapshotStats summarizes a saved or restored snapshot
+type SnapshotStats struct {
+	Keys    map[string]int // Keys by type
+	Skipped int            // Keys of other types, outside the prefix or gone before being read
+	Bytes   int64          // Size of the uncompressed snapshot
+}
+
+// Total returns the number of keys saved or restored
+func (s *SnapshotStats) Total() int {
+	total := 0
+	for _, n := range s.Keys {
+		total += n
+	}
+	return total
+}
+
+// byteCounter counts the bytes passing through it
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// SaveSnapshot writes every string, hash, list, set and sorted set whose key
+// starts with prefix to w, with its time to live, as gzip-compressed JSON
+// lines: a header, then one key per line. Keys are found with SCAN, so the
+// snapshot is not a point-in-time copy: keys written meanwhile may or may
+// not be included.
+func (s *RedisStore) SaveSnapshot(ctx context.Context, w io.Writer, prefix string) (*SnapshotStats, error) {
+	keys, err := s.scanKeys(ctx, prefix+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &SnapshotStats{Keys: make(map[string]int)}
+	var size byteCounter
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(io.MultiWriter(zw, &size))
+	if err := enc.Encode(snapshotHeader{Version: SnapshotVersion, CreatedAt: time.Now().UTC(), Prefix: prefix}); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	for _, key := range keys {
+		entry, err := s.readSnapshotKey(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			stats.Skipped++
+			continue
+		}
+		if err := enc.Encode(entry); err != nil {
+			return nil, fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		stats.Keys[entry.Type]++
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	stats.Bytes = size.n
+	return stats, nil
+}
+
+// scanKeys returns the keys matching pattern, sorted. SCAN is node-local, so
+// in a cluster every master is scanned.
+func (s *RedisStore) scanKeys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	scan := func(ctx context.Context, client redis.UniversalClient) ([]string, error) {
+		var found []string
+		iter := client.Scan(ctx, 0, pattern, snapshotScanCount).Iterator()
+		for iter.Next(ctx) {
+			found = append(found, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return nil, fmt.Errorf("failed to scan keys: %w", err)
+		}
+		return found, nil
+	}
+
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		var mu sync.Mutex
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			found, err := scan(ctx, node)
+			mu.Lock()
+			keys = append(keys, found...)
+			mu.Unlock()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if keys, err = scan(ctx, s.client); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// readSnapshotKey reads key with the command of its type. It returns nil
+// for keys of other types and keys deleted since the scan.
+func (s *RedisStore) readSnapshotKey(ctx context.Context, key string) (*SnapshotKey, error) {
+	var typ *redis.StatusCmd
+	var ttl *redis.DurationCmd
+	if _, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		typ = pipe.Type(ctx, key)
+		ttl = pipe.PTTL(ctx, key)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read type of %s: %w", key, err)
+	}
+
+	entry := &SnapshotKey{Key: key, Type: typ.Val()}
+	// PTTL reports no expiry and missing keys as negative durations
+	if ttl.Val() > 0 {
+		entry.TTL = ttl.Val()
+	}
+
+	var err error
+	switch entry.Type {
+	case snapshotString:
+		entry.Value, err = s.client.Get(ctx, key).Bytes()
+	case snapshotHash:
+		var fields map[string]string
+		if fields, err = s.client.HGetAll(ctx, key).Result(); err == nil {
+			entry.Fields = make(map[string][]byte, len(fields))
+			for field, value := range fields {
+				entry.Fields[field] = []byte(value)
+			}
+		}
+	case snapshotList:
+		var members []string
+		members, err = s.client.LRange(ctx, key, 0, -1).Result()
+		entry.Members = toBytes(members)
+	case snapshotSet:
+		var members []string
+		members, err = s.client.SMembers(ctx, key).Result()
+		sort.Strings(members)
+		entry.Members = toBytes(members)
+	case snapshotZSet:
+		var members []redis.Z
+		if members, err = s.client.ZRangeWithScores(ctx, key, 0, -1).Result(); err == nil {
+			for _, z := range members {
+				entry.Members = append(entry.Members, []byte(z.Member.(string)))
+				entry.Scores = append(entry.Scores, z.Score)
+			}
+		}
+	default:
+		return nil, nil
+	}
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return entry, nil
+}
+
+// toBytes converts Redis replies to byte slices
+func toBytes(values []string) [][]byte {
+	out := make([][]byte, len(values))
+	for i, value := range values {
+		out[i] = []byte(value)
+	}
+	return out
+}
+
+// RestoreSnapshot re-creates the keys of a snapshot written by SaveSnapshot
+// whose names start with prefix, replacing keys of the same name and
+// setting the time to live they had left when saved. Other keys are left
+// alone.
+func (s *RedisStore) RestoreSnapshot(ctx context.Context, r io.Reader, prefix string) (*SnapshotStats, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer zr.Close()
+
+	var size byteCounter
+	dec := json.NewDecoder(io.TeeReader(zr, &size))
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot header: %w", err)
+	}
+	if header.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+
+	stats := &SnapshotStats{Keys: make(map[string]int)}
+	for {
+		var entry SnapshotKey
+		if err := dec.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return stats, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		if !strings.HasPrefix(entry.Key, prefix) {
+			stats.Skipped++
+			continue
+		}
+		if err := s.restoreSnapshotKey(ctx, &entry); err != nil {
+			return stats, err
+		}
+		stats.Keys[entry.Type]++
+	}
+	stats.Bytes = size.n
+	return stats, nil
+}
+
+// restoreSnapshotKey replaces entry's key with its saved value in one
+// transaction
+func (s *RedisStore) restoreSnapshotKey(ctx context.Context, entry *SnapshotKey) error {
+	if entry.Type == snapshotZSet && len(entry.Scores) != len(entry.Members) {
+		return fmt.Errorf("snapshot of %s has %d scores for %d members", entry.Key, len(entry.Scores), len(entry.Members))
+	}
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, entry.Key)
+		switch entry.Type {
+		case snapshotString:
+			pipe.Set(ctx, entry.Key, entry.Value, 0)
+		case snapshotHash:
+			fields := make(map[string]interface{}, len(entry.Fields))
+			for field, value := range entry.Fields {
+				fields[field] = value
+			}
+			pipe.HSet(ctx, entry.Key, fields)
+		case snapshotList:
+			pipe.RPush(ctx, entry.Key, toArgs(entry.Members)...)
+		case snapshotSet:
+			pipe.SAdd(ctx, entry.Key, toArgs(entry.Members)...)
+		case snapshotZSet:
+			members := make([]*redis.Z, len(entry.Members))
+			for i, member := range entry.Members {
+				members[i] = &redis.Z{Score: entry.Scores[i], Member: member}
+			}
+			pipe.ZAdd(ctx, entry.Key, members...)
+		default:
+			return fmt.Errorf("snapshot of %s has unsupported type %q", entry.Key, entry.Type)
+		}
+		if entry.TTL > 0 {
+			pipe.PExpire(ctx, entry.Key, entry.TTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", entry.Key, err)
+	}
+	return nil
+}
+
+// toArgs converts values to command arguments
+func toArgs(values [][]byte) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return args
+}
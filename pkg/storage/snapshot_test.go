@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// dumpKeyspace renders every key of mr with its type, value and TTL
+func dumpKeyspace(t *testing.T, mr *miniredis.Miniredis) map[string]string {
+	t.Helper()
+	dump := make(map[string]string)
+	for _, key := range mr.Keys() {
+		var value interface{}
+		var err error
+		switch typ := mr.Type(key); typ {
+		case "string":
+			value, err = mr.Get(key)
+		case "hash":
+			var names []string
+			names, err = mr.HKeys(key)
+			fields := make(map[string]string)
+			for _, field := range names {
+				fields[field] = mr.HGet(key, field)
+			}
+			value = fields
+		case "list":
+			value, err = mr.List(key)
+		case "set":
+			var members []string
+			members, err = mr.Members(key)
+			sort.Strings(members)
+			value = members
+		case "zset":
+			value, err = mr.SortedSet(key)
+		default:
+			t.Fatalf("Unexpected type %s of %s", typ, key)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		dump[key] = fmt.Sprintf("%s %q ttl=%s", mr.Type(key), value, mr.TTL(key))
+	}
+	return dump
+}
+
+func TestRedisStore_SnapshotRoundTrip(t *testing.T) {
+	source, sourceMR, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sourceMR.Close()
+	defer source.Close()
+	target, targetMR, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer targetMR.Close()
+	defer target.Close()
+
+	ctx := context.Background()
+	binary := string([]byte{0x1f, 0x8b, 0x00, 0xff, '\n'})
+	sourceClient := source.GetRedisClient()
+	if _, err := sourceClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "test:trade:BTCUSDT:latest", `{"p":"42000.5"}`, time.Hour)
+		pipe.Set(ctx, "test:blob", binary, 0)
+		pipe.HSet(ctx, "test:alerts", "a1", `{"symbol":"BTCUSDT"}`, "a2", binary)
+		pipe.RPush(ctx, "test:dlq", "third", "first", "second")
+		pipe.SAdd(ctx, "test:symbols", "ETHUSDT", "BTCUSDT", binary)
+		pipe.ZAdd(ctx, "test:trade:BTCUSDT:history", &redis.Z{Score: 2, Member: binary}, &redis.Z{Score: 1.5, Member: "older"})
+		pipe.Expire(ctx, "test:trade:BTCUSDT:history", 24*time.Hour)
+		pipe.Set(ctx, "other:key", "not ours", 0)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshot bytes.Buffer
+	stats, err := source.SaveSnapshot(ctx, &snapshot, "test:")
+	if err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	want := map[string]int{"string": 2, "hash": 1, "list": 1, "set": 1, "zset": 1}
+	if !reflect.DeepEqual(stats.Keys, want) {
+		t.Errorf("Saved %v, want %v", stats.Keys, want)
+	}
+	if stats.Bytes <= int64(snapshot.Len()) {
+		t.Errorf("Expected the snapshot compressed, got %d bytes from %d", snapshot.Len(), stats.Bytes)
+	}
+
+	// Restoring replaces keys of the same name and leaves the others alone
+	targetClient := target.GetRedisClient()
+	targetClient.RPush(ctx, "test:dlq", "stale")
+	targetClient.Set(ctx, "test:local", "kept", 0)
+	if _, err := target.RestoreSnapshot(ctx, bytes.NewReader(snapshot.Bytes()), "test:"); err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	if got, _ := targetMR.Get("test:local"); got != "kept" {
+		t.Errorf("Expected a key missing from the snapshot kept, got %q", got)
+	}
+	targetMR.Del("test:local")
+
+	sourceMR.Del("other:key")
+	got, expected := dumpKeyspace(t, targetMR), dumpKeyspace(t, sourceMR)
+	if !reflect.DeepEqual(got, expected) {
+		for key := range expected {
+			if got[key] != expected[key] {
+				t.Errorf("%s restored as %q, want %q", key, got[key], expected[key])
+			}
+		}
+		for key := range got {
+			if _, ok := expected[key]; !ok {
+				t.Errorf("Unexpected key %s restored", key)
+			}
+		}
+	}
+}
+
+func TestRedisStore_RestoreSnapshotFilter(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	mr.Set("test:trade:BTCUSDT:latest", "btc")
+	mr.Set("test:trade:ETHUSDT:latest", "eth")
+	mr.Set("test:alerts", "alerts")
+
+	var snapshot bytes.Buffer
+	if _, err := store.SaveSnapshot(ctx, &snapshot, "test:trade:"); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	mr.FlushAll()
+	stats, err := store.RestoreSnapshot(ctx, bytes.NewReader(snapshot.Bytes()), "test:trade:BTC")
+	if err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	if stats.Total() != 1 || stats.Skipped != 1 {
+		t.Errorf("Expected 1 key restored and 1 skipped, got %d and %d", stats.Total(), stats.Skipped)
+	}
+	if keys := mr.Keys(); !reflect.DeepEqual(keys, []string{"test:trade:BTCUSDT:latest"}) {
+		t.Errorf("Expected only the BTCUSDT trade restored, got %v", keys)
+	}
+
+	if _, err := store.RestoreSnapshot(ctx, bytes.NewReader([]byte("not gzip")), ""); err == nil {
+		t.Error("Expected an error restoring a file that is not a snapshot")
+	}
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// spreadRetention is how long per-minute spread buckets are kept, and so the
+// longest window GetSpreadStats covers
+const spreadRetention = 2 * time.Hour
+
+// Fields of a spread bucket hash
+const (
+	spreadSumField    = "spread_sum"
+	imbalanceSumField = "imbalance_sum"
+	updatesField      = "updates"
+)
+
+// SpreadBucket is one minute of book ticker updates
+type SpreadBucket struct {
+	Minute       time.Time
+	AvgSpread    float64 // Average ask - bid
+	AvgImbalance float64 // Average top-of-book imbalance, -1 (asks only) to 1 (bids only)
+	Updates      int64
+}
+
+// SpreadStats is the spread and imbalance of a symbol's book over a window
+type SpreadStats struct {
+	Symbol       string
+	AvgSpread    float64 // Averaged over all updates in the window
+	AvgImbalance float64
+	Updates      int64
+	Buckets      []SpreadBucket // Minutes with updates, oldest first
+}
+
+// spreadKey returns the key of a symbol's spread bucket for minute
+func (s *RedisStore) spreadKey(symbol string, minute time.Time) string {
+	return fmt.Sprintf("%s%s:spread:%d", s.config.Redis.KeyPrefix, strings.ToUpper(symbol), minute.Unix())
+}
+
+// StoreBookTicker adds a book ticker update received at at to its symbol's
+// spread bucket for that minute
+func (s *RedisStore) StoreBookTicker(ctx context.Context, ticker *models.BookTicker, at time.Time) error {
+	spread, imbalance, err := ticker.TopOfBook()
+	if err != nil {
+		return fmt.Errorf("invalid book ticker for %s: %w", ticker.Symbol, err)
+	}
+
+	key := s.spreadKey(ticker.Symbol, at.Truncate(time.Minute))
+	pipe := s.client.Pipeline()
+	pipe.HIncrByFloat(ctx, key, spreadSumField, spread)
+	pipe.HIncrByFloat(ctx, key, imbalanceSumField, imbalance)
+	pipe.HIncrBy(ctx, key, updatesField, 1)
+	pipe.Expire(ctx, key, spreadRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store book ticker: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// GetSpreadStats returns the spread and imbalance of symbol over the window
+// ending now, which reaches back at most spreadRetention. It returns
+// ErrNotFound when no book ticker updates were stored in the window.
+func (s *RedisStore) GetSpreadStats(ctx context.Context, symbol string, window time.Duration) (*SpreadStats, error) {
+	if window > spreadRetention {
+		window = spreadRetention
+	}
+	end := time.Now().Truncate(time.Minute)
+	start := end.Add(-window).Add(time.Minute)
+	if start.After(end) {
+		start = end
+	}
+
+	pipe := s.client.Pipeline()
+	var minutes []time.Time
+	var cmds []*redis.StringStringMapCmd
+	for minute := start; !minute.After(end); minute = minute.Add(time.Minute) {
+		minutes = append(minutes, minute)
+		cmds = append(cmds, pipe.HGetAll(ctx, s.spreadKey(symbol, minute)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get spread buckets: %w: %w", ErrUnavailable, err)
+	}
+
+	stats := &SpreadStats{Symbol: strings.ToUpper(symbol)}
+	var spreadSum, imbalanceSum float64
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue
+		}
+		bucket, sums, err := parseSpreadBucket(minutes[i], fields)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spread bucket for %s at %s: %w: %w",
+				stats.Symbol, minutes[i].Format(time.RFC3339), ErrCorruptData, err)
+		}
+		if bucket.Updates == 0 {
+			continue
+		}
+		stats.Buckets = append(stats.Buckets, bucket)
+		stats.Updates += bucket.Updates
+		spreadSum += sums[0]
+		imbalanceSum += sums[1]
+	}
+
+	if stats.Updates == 0 {
+		return nil, fmt.Errorf("no spread data for %s in the last %s: %w", stats.Symbol, window, ErrNotFound)
+	}
+	stats.AvgSpread = spreadSum / float64(stats.Updates)
+	stats.AvgImbalance = imbalanceSum / float64(stats.Updates)
+	return stats, nil
+}
+
+// parseSpreadBucket decodes a spread bucket hash, returning the bucket and
+// its spread and imbalance sums
+func parseSpreadBucket(minute time.Time, fields map[string]string) (SpreadBucket, [2]float64, error) {
+	bucket := SpreadBucket{Minute: minute}
+	var sums [2]float64
+
+	updates, err := strconv.ParseInt(fields[updatesField], 10, 64)
+	if err != nil {
+		return bucket, sums, fmt.Errorf("invalid %s: %w", updatesField, err)
+	}
+	for i, field := range []string{spreadSumField, imbalanceSumField} {
+		if sums[i], err = strconv.ParseFloat(fields[field], 64); err != nil {
+			return bucket, sums, fmt.Errorf("invalid %s: %w", field, err)
+		}
+	}
+
+	bucket.Updates = updates
+	if updates > 0 {
+		bucket.AvgSpread = sums[0] / float64(updates)
+		bucket.AvgImbalance = sums[1] / float64(updates)
+	}
+	return bucket, sums, nil
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestRedisStore_SpreadStats(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	updates := []struct {
+		at     time.Time
+		ticker models.BookTicker
+	}{
+		// Spread 1, imbalance (3-1)/4 = 0.5
+		{now.Add(-time.Minute), models.BookTicker{Symbol: "BTCUSDT", BidPrice: "100", BidQty: "3", AskPrice: "101", AskQty: "1"}},
+		// Spread 3, imbalance (1-3)/4 = -0.5
+		{now.Add(-time.Minute), models.BookTicker{Symbol: "BTCUSDT", BidPrice: "100", BidQty: "1", AskPrice: "103", AskQty: "3"}},
+		// Spread 0.5, imbalance 1: only bids quoted
+		{now, models.BookTicker{Symbol: "btcusdt", BidPrice: "100", BidQty: "2", AskPrice: "100.5", AskQty: "0"}},
+		// Outside a 10 minute window
+		{now.Add(-30 * time.Minute), models.BookTicker{Symbol: "BTCUSDT", BidPrice: "100", BidQty: "1", AskPrice: "200", AskQty: "1"}},
+	}
+	for _, u := range updates {
+		ticker := u.ticker
+		if err := store.StoreBookTicker(ctx, &ticker, u.at); err != nil {
+			t.Fatalf("Failed to store book ticker: %v", err)
+		}
+	}
+
+	stats, err := store.GetSpreadStats(ctx, "BTCUSDT", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to get spread stats: %v", err)
+	}
+	if stats.Updates != 3 || len(stats.Buckets) != 2 {
+		t.Fatalf("Expected 3 updates in 2 buckets, got %+v", stats)
+	}
+	approx := func(name string, got, want float64) {
+		t.Helper()
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("Expected %s %v, got %v", name, want, got)
+		}
+	}
+	approx("average spread", stats.AvgSpread, 4.5/3)
+	approx("average imbalance", stats.AvgImbalance, 1.0/3)
+	approx("first bucket spread", stats.Buckets[0].AvgSpread, 2)
+	approx("first bucket imbalance", stats.Buckets[0].AvgImbalance, 0)
+	approx("last bucket spread", stats.Buckets[1].AvgSpread, 0.5)
+	approx("last bucket imbalance", stats.Buckets[1].AvgImbalance, 1)
+	if !stats.Buckets[0].Minute.Before(stats.Buckets[1].Minute) {
+		t.Errorf("Expected buckets oldest first, got %+v", stats.Buckets)
+	}
+
+	// The longer window reaches the older update
+	stats, err = store.GetSpreadStats(ctx, "BTCUSDT", time.Hour)
+	if err != nil || stats.Updates != 4 {
+		t.Errorf("Expected 4 updates in the last hour, got %+v (%v)", stats, err)
+	}
+
+	if _, err := store.GetSpreadStats(ctx, "ETHUSDT", time.Hour); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound without updates, got %v", err)
+	}
+
+	bad := models.BookTicker{Symbol: "BTCUSDT", BidPrice: "x", BidQty: "1", AskPrice: "1", AskQty: "1"}
+	if err := store.StoreBookTicker(ctx, &bad, now); err == nil {
+		t.Error("Expected an error for an invalid bid price")
+	}
+
+	mr.HSet(store.spreadKey("SOLUSDT", now.Truncate(time.Minute)), updatesField, "many")
+	if _, err := store.GetSpreadStats(ctx, "SOLUSDT", time.Minute); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("Expected ErrCorruptData for a malformed bucket, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// tradeStatsCacheTTL is how long computed trade stats are cached in Redis
+const tradeStatsCacheTTL = time.Minute
+
+// TradeStats summarizes the candles of a symbol over a time range
+type TradeStats struct {
+	OpenPrice      string  `json:"open_price"`
+	ClosePrice     string  `json:"close_price"`
+	HighPrice      string  `json:"high_price"`
+	LowPrice       string  `json:"low_price"`
+	TotalVolume    string  `json:"total_volume"`
+	TotalTrades    int64   `json:"total_trades"`
+	AvgTradeSize   float64 `json:"avg_trade_size"`  // Mean trade size across candles
+	TradeIntensity float64 `json:"trade_intensity"` // Trades per minute with data
+}
+
+// TradeStatsQuerier computes trade stats, e.g. *PostgresStore
+type TradeStatsQuerier interface {
+	GetTradeStats(ctx context.Context, symbol string, start, end time.Time) (*TradeStats, error)
+}
+
+// GetTradeStats aggregates the candles of symbol between start and end in
+// SQL. It returns nil when there are no candles in the range.
+func (s *PostgresStore) GetTradeStats(ctx context.Context, symbol string, start, end time.Time) (*TradeStats, error) {
+	var (
+		count                         int64
+		open, closePrice, high, low   sql.NullString
+		volume                        sql.NullString
+		trades                        sql.NullInt64
+		avgTradeSize, tradesPerMinute sql.NullFloat64
+	)
+
+	// array_agg with ORDER BY stands in for FIRST/LAST aggregates, which
+	// stock PostgreSQL lacks
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*),
+			(array_agg(open_price ORDER BY timestamp ASC))[1],
+			(array_agg(close_price ORDER BY timestamp DESC))[1],
+			MAX(high_price),
+			MIN(low_price),
+			SUM(volume::numeric),
+			SUM(trade_count),
+			AVG(volume::numeric / NULLIF(trade_count, 0))::float8,
+			(SUM(trade_count)::numeric / NULLIF(COUNT(*), 0))::float8
+		FROM trade_candles_reconciled
+		WHERE symbol = $1 AND timestamp BETWEEN $2 AND $3 AND exchange = $4`,
+		strings.ToUpper(symbol), start, end, s.exchange,
+	).Scan(&count, &open, &closePrice, &high, &low, &volume, &trades, &avgTradeSize, &tradesPerMinute)
+	if err != nil {
+		if s.debug {
+			log.Printf("[ERROR] Failed to query trade stats: %v", err)
+		}
+		return nil, fmt.Errorf("failed to query trade stats: %w", err)
+	}
+
+	if count == 0 {
+		return nil, nil
+	}
+
+	return &TradeStats{
+		OpenPrice:      open.String,
+		ClosePrice:     closePrice.String,
+		HighPrice:      high.String,
+		LowPrice:       low.String,
+		TotalVolume:    volume.String,
+		TotalTrades:    trades.Int64,
+		AvgTradeSize:   avgTradeSize.Float64,
+		TradeIntensity: tradesPerMinute.Float64,
+	}, nil
+}
+
+// CachedTradeStats returns trade stats for symbol from Redis, computing them
+// with querier on a miss and caching them for a minute. The range is
+// truncated to whole minutes so repeated calls share a cache entry.
+func (s *RedisStore) CachedTradeStats(ctx context.Context, querier TradeStatsQuerier, symbol string, start, end time.Time) (*TradeStats, error) {
+	symbol = strings.ToUpper(symbol)
+	start = start.Truncate(time.Minute)
+	end = end.Truncate(time.Minute)
+	key := fmt.Sprintf("%sstats:%s:%d:%d", s.config.Redis.KeyPrefix, symbol, start.Unix(), end.Unix())
+
+	data, err := s.client.Get(ctx, key).Bytes()
+	if err == nil {
+		var stats TradeStats
+		if err := json.Unmarshal(data, &stats); err == nil {
+			return &stats, nil
+		}
+	} else if err != redis.Nil {
+		log.Printf("Warning: failed to read cached trade stats: %v", err)
+	}
+
+	stats, err := querier.GetTradeStats(ctx, symbol, start, end)
+	if err != nil || stats == nil {
+		return stats, err
+	}
+
+	if data, err := json.Marshal(stats); err == nil {
+		if err := s.client.Set(ctx, key, data, tradeStatsCacheTTL).Err(); err != nil {
+			log.Printf("Warning: failed to cache trade stats: %v", err)
+		}
+	}
+	return stats, nil
+}
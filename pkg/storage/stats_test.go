@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// countingQuerier returns fixed stats and counts how often it is asked
+type countingQuerier struct {
+	stats *TradeStats
+	calls int
+}
+
+func (q *countingQuerier) GetTradeStats(ctx context.Context, symbol string, start, end time.Time) (*TradeStats, error) {
+	q.calls++
+	return q.stats, nil
+}
+
+func TestRedisStore_CachedTradeStats(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	querier := &countingQuerier{stats: &TradeStats{OpenPrice: "100", TotalTrades: 7, TradeIntensity: 3.5}}
+	end := time.Date(2024, 12, 26, 10, 30, 15, 0, time.UTC)
+	start := end.Add(-time.Hour)
+
+	for i := 0; i < 2; i++ {
+		// Calls within the same minute share a cache entry
+		stats, err := store.CachedTradeStats(ctx, querier, "btcusdt", start.Add(time.Duration(i)*time.Second), end.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatalf("CachedTradeStats failed: %v", err)
+		}
+		if stats.OpenPrice != "100" || stats.TotalTrades != 7 || stats.TradeIntensity != 3.5 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	}
+	if querier.calls != 1 {
+		t.Errorf("Expected 1 query with caching, got %d", querier.calls)
+	}
+
+	mr.FastForward(tradeStatsCacheTTL + time.Second)
+	if _, err := store.CachedTradeStats(ctx, querier, "BTCUSDT", start, end); err != nil {
+		t.Fatal(err)
+	}
+	if querier.calls != 2 {
+		t.Errorf("Expected cache to expire after %s, got %d queries", tradeStatsCacheTTL, querier.calls)
+	}
+
+	// Empty ranges are not cached
+	empty := &countingQuerier{}
+	for i := 0; i < 2; i++ {
+		stats, err := store.CachedTradeStats(ctx, empty, "ETHUSDT", start, end)
+		if err != nil || stats != nil {
+			t.Fatalf("Expected no stats, got %+v, %v", stats, err)
+		}
+	}
+	if empty.calls != 2 {
+		t.Errorf("Expected empty results to skip the cache, got %d queries", empty.calls)
+	}
+}
+
+func TestPostgresStore_GetTradeStats(t *testing.T) {
+	store, cleanup := setupTestPostgres(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	candles := []*models.Candle{
+		{Timestamp: base, OpenPrice: "100", HighPrice: "110", LowPrice: "95", ClosePrice: "105", Volume: "10", TradeCount: 5},
+		{Timestamp: base.Add(time.Minute), OpenPrice: "105", HighPrice: "120", LowPrice: "90", ClosePrice: "115", Volume: "30", TradeCount: 15},
+	}
+	for _, candle := range candles {
+		if err := store.StoreCandleData(ctx, "BTCUSDT", candle, CandleSourceLive); err != nil {
+			t.Fatalf("Failed to store candle: %v", err)
+		}
+	}
+
+	stats, err := store.GetTradeStats(ctx, "BTCUSDT", base.Add(-time.Minute), base.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("GetTradeStats failed: %v", err)
+	}
+	if stats == nil {
+		t.Fatal("Expected stats, got nil")
+	}
+
+	assertNumeric := func(name, got string, want float64) {
+		t.Helper()
+		if v, err := strconv.ParseFloat(got, 64); err != nil || v != want {
+			t.Errorf("Expected %s %.2f, got %s", name, want, got)
+		}
+	}
+	assertNumeric("open", stats.OpenPrice, 100)
+	assertNumeric("close", stats.ClosePrice, 115)
+	assertNumeric("high", stats.HighPrice, 120)
+	assertNumeric("low", stats.LowPrice, 90)
+	assertNumeric("volume", stats.TotalVolume, 40)
+	if stats.TotalTrades != 20 || stats.AvgTradeSize != 2 || stats.TradeIntensity != 10 {
+		t.Errorf("Unexpected derived stats: %+v", stats)
+	}
+
+	empty, err := store.GetTradeStats(ctx, "ETHUSDT", base, base.Add(time.Hour))
+	if err != nil || empty != nil {
+		t.Errorf("Expected nil stats for a symbol without candles, got %+v, %v", empty, err)
+	}
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// StreamGroupsKey returns the Redis hash of symbol group assignments, keyed
+// by connection, so a group regrouped under the same name keeps the new
+// connection's entry when the old one is removed
+func StreamGroupsKey(prefix string) string {
+	return prefix + "ingestion:groups"
+}
+
+// SaveStreamGroup writes the assignment of a symbol group, valid for ttl
+// from its heartbeat. The hash expires with its last refresh, so it goes
+// away once no process is left to refresh it.
+func (s *RedisStore) SaveStreamGroup(ctx context.Context, group *models.StreamGroup, ttl time.Duration) error {
+	entry := *group
+	entry.ExpiresAt = group.Heartbeat.Add(ttl)
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream group: %w", err)
+	}
+
+	key := StreamGroupsKey(s.config.Redis.KeyPrefix)
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, key, group.Connection, data)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save stream group: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// RemoveStreamGroup deletes the assignment of a connection that stopped
+// streaming
+func (s *RedisStore) RemoveStreamGroup(ctx context.Context, connection string) error {
+	if err := s.client.HDel(ctx, StreamGroupsKey(s.config.Redis.KeyPrefix), connection).Err(); err != nil {
+		return fmt.Errorf("failed to remove stream group: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// GetStreamGroups returns the group assignments still valid at now, ordered
+// by group. Expired ones, left by processes that stopped without removing
+// them, are deleted.
+func (s *RedisStore) GetStreamGroups(ctx context.Context, now time.Time) ([]*models.StreamGroup, error) {
+	key := StreamGroupsKey(s.config.Redis.KeyPrefix)
+	entries, err := s.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream groups: %w: %w", ErrUnavailable, err)
+	}
+
+	groups := make([]*models.StreamGroup, 0, len(entries))
+	var expired []string
+	for field, data := range entries {
+		var group models.StreamGroup
+		if err := json.Unmarshal([]byte(data), &group); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stream group %s: %w: %w", field, ErrCorruptData, err)
+		}
+		if group.ExpiresAt.Before(now) {
+			expired = append(expired, field)
+			continue
+		}
+		groups = append(groups, &group)
+	}
+	if len(expired) > 0 {
+		if err := s.client.HDel(ctx, key, expired...).Err(); err != nil {
+			return nil, fmt.Errorf("failed to remove expired stream groups: %w: %w", ErrUnavailable, err)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Group < groups[j].Group })
+	return groups, nil
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestRedisStore_StreamGroups(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ttl := 45 * time.Second
+	for _, group := range []*models.StreamGroup{
+		{Group: "ethusdt-1", Connection: "a:1/1", Symbols: []string{"ETHUSDT"}, Process: "a:1", ConnectedAt: now, Heartbeat: now},
+		{Group: "btcusdt-1", Connection: "a:1/2", Symbols: []string{"BTCUSDT", "BNBUSDT"}, Process: "a:1", ConnectedAt: now, Reconnects: 2, Heartbeat: now},
+		// Left by a process that stopped without removing it
+		{Group: "solusdt-1", Connection: "b:2/1", Symbols: []string{"SOLUSDT"}, Process: "b:2", Heartbeat: now.Add(-time.Minute)},
+	} {
+		if err := store.SaveStreamGroup(ctx, group, ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	key := "test:ingestion:groups"
+	if got := mr.TTL(key); got != ttl {
+		t.Errorf("Expected %s to expire in %s, got %s", key, ttl, got)
+	}
+
+	groups, err := store.GetStreamGroups(ctx, now.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[0].Group != "btcusdt-1" || groups[1].Group != "ethusdt-1" {
+		t.Fatalf("Expected btcusdt-1 and ethusdt-1, got %+v", groups)
+	}
+	if g := groups[0]; len(g.Symbols) != 2 || g.Reconnects != 2 || g.Process != "a:1" || !g.ExpiresAt.Equal(now.Add(ttl)) {
+		t.Errorf("Unexpected stream group %+v", g)
+	}
+	if fields, _ := mr.HKeys(key); len(fields) != 2 {
+		t.Errorf("Expected the expired group pruned, got fields %v", fields)
+	}
+
+	if err := store.RemoveStreamGroup(ctx, "a:1/1"); err != nil {
+		t.Fatal(err)
+	}
+	if groups, err = store.GetStreamGroups(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Group != "btcusdt-1" {
+		t.Errorf("Expected only btcusdt-1 after removing ethusdt-1, got %+v", groups)
+	}
+
+	// Without heartbeats the whole hash goes away
+	mr.FastForward(ttl)
+	if mr.Exists(key) {
+		t.Errorf("Expected %s to expire without heartbeats", key)
+	}
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// streamTypesKey returns the key of the hash of per-symbol stream type
+// overrides, keyed by upper-case symbol
+func (s *RedisStore) streamTypesKey() string {
+	return fmt.Sprintf("%sstream:types", s.config.Redis.KeyPrefix)
+}
+
+// GetStreamTypes returns the stream type overrides of all symbols, keyed by
+// upper-case symbol
+func (s *RedisStore) GetStreamTypes(ctx context.Context) (map[string]string, error) {
+	types, err := s.client.HGetAll(ctx, s.streamTypesKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream types: %w: %w", ErrUnavailable, err)
+	}
+	return types, nil
+}
+
+// GetStreamType returns the stream type override of symbol, or "" when it
+// streams the default type
+func (s *RedisStore) GetStreamType(ctx context.Context, symbol string) (string, error) {
+	streamType, err := s.client.HGet(ctx, s.streamTypesKey(), strings.ToUpper(symbol)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get stream type: %w: %w", ErrUnavailable, err)
+	}
+	return streamType, nil
+}
+
+// SetStreamType overrides the stream type of symbol
+func (s *RedisStore) SetStreamType(ctx context.Context, symbol, streamType string) error {
+	if err := s.client.HSet(ctx, s.streamTypesKey(), strings.ToUpper(symbol), streamType).Err(); err != nil {
+		return fmt.Errorf("failed to set stream type: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
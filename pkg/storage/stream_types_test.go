@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"binance-redis-streamer/internal/models"
+)
+
+func TestRedisStore_StreamTypes(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	if streamType, err := store.GetStreamType(ctx, "BTCUSDT"); err != nil || streamType != "" {
+		t.Errorf("GetStreamType() = %q, %v, want no override", streamType, err)
+	}
+
+	if err := store.SetStreamType(ctx, "btcusdt", "aggTrade"); err != nil {
+		t.Fatalf("SetStreamType() error = %v", err)
+	}
+	if err := store.SetStreamType(ctx, "ETHUSDT", "miniTicker"); err != nil {
+		t.Fatalf("SetStreamType() error = %v", err)
+	}
+
+	if streamType, err := store.GetStreamType(ctx, "BTCUSDT"); err != nil || streamType != "aggTrade" {
+		t.Errorf("GetStreamType() = %q, %v, want aggTrade", streamType, err)
+	}
+	types, err := store.GetStreamTypes(ctx)
+	if err != nil {
+		t.Fatalf("GetStreamTypes() error = %v", err)
+	}
+	if len(types) != 2 || types["BTCUSDT"] != "aggTrade" || types["ETHUSDT"] != "miniTicker" {
+		t.Errorf("GetStreamTypes() = %v", types)
+	}
+	if !mr.Exists("test:stream:types") {
+		t.Error("Expected the overrides in the test:stream:types hash")
+	}
+}
+
+func TestRedisStore_MiniTicker(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	if _, err := store.GetMiniTicker(ctx, "ETHUSDT"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetMiniTicker() error = %v, want ErrNotFound", err)
+	}
+
+	ticker := &models.MiniTicker{
+		EventTime:   1700000000000,
+		Symbol:      "ETHUSDT",
+		ClosePrice:  "3010.5",
+		OpenPrice:   "2950.1",
+		HighPrice:   "3050",
+		LowPrice:    "2900",
+		Volume:      "1200.5",
+		QuoteVolume: "3600000",
+	}
+	if err := store.StoreMiniTicker(ctx, ticker); err != nil {
+		t.Fatalf("StoreMiniTicker() error = %v", err)
+	}
+
+	got, err := store.GetMiniTicker(ctx, "ethusdt")
+	if err != nil {
+		t.Fatalf("GetMiniTicker() error = %v", err)
+	}
+	if *got != *ticker {
+		t.Errorf("GetMiniTicker() = %+v, want %+v", *got, *ticker)
+	}
+
+	mr.FastForward(miniTickerTTL)
+	if _, err := store.GetMiniTicker(ctx, "ETHUSDT"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetMiniTicker() after the TTL error = %v, want ErrNotFound", err)
+	}
+}
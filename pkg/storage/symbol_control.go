@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Symbol control actions, which are also the names of their request lists
+const (
+	SymbolControlAdd    = "add_symbol"
+	SymbolControlRemove = "remove_symbol"
+)
+
+// SymbolControl is a request to start or stop streaming a symbol without
+// restarting the streamer
+type SymbolControl struct {
+	Action string // SymbolControlAdd or SymbolControlRemove
+	Symbol string // Upper-case symbol
+}
+
+// symbolControlKey returns the list of pending requests for action
+func (s *RedisStore) symbolControlKey(action string) string {
+	return fmt.Sprintf("%scontrol:%s", s.config.Redis.KeyPrefix, action)
+}
+
+// RequestSymbolAdd asks the streamer to start streaming symbols
+func (s *RedisStore) RequestSymbolAdd(ctx context.Context, symbols ...string) error {
+	if err := s.client.RPush(ctx, s.symbolControlKey(SymbolControlAdd), upperSymbols(symbols)...).Err(); err != nil {
+		return fmt.Errorf("failed to request symbol addition: %w", err)
+	}
+	return nil
+}
+
+// RequestSymbolRemove asks the streamer to stop streaming symbols
+func (s *RedisStore) RequestSymbolRemove(ctx context.Context, symbols ...string) error {
+	if err := s.client.RPush(ctx, s.symbolControlKey(SymbolControlRemove), upperSymbols(symbols)...).Err(); err != nil {
+		return fmt.Errorf("failed to request symbol removal: %w", err)
+	}
+	return nil
+}
+
+// PopSymbolControl waits up to timeout for the next symbol request and
+// returns nil when none arrives. Redis waits at least a second. In cluster
+// mode the lists may live on different nodes, so each is waited on in turn.
+func (s *RedisStore) PopSymbolControl(ctx context.Context, timeout time.Duration) (*SymbolControl, error) {
+	keys := []string{s.symbolControlKey(SymbolControlAdd), s.symbolControlKey(SymbolControlRemove)}
+	if !s.config.Redis.Cluster {
+		return s.popSymbolControl(ctx, timeout, keys...)
+	}
+	for _, key := range keys {
+		request, err := s.popSymbolControl(ctx, timeout, key)
+		if request != nil || err != nil {
+			return request, err
+		}
+	}
+	return nil, nil
+}
+
+// popSymbolControl runs one BLPOP over keys
+func (s *RedisStore) popSymbolControl(ctx context.Context, timeout time.Duration, keys ...string) (*SymbolControl, error) {
+	result, err := s.client.BLPop(ctx, timeout, keys...).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read symbol requests: %w", err)
+	}
+
+	action := strings.TrimPrefix(result[0], s.symbolControlKey(""))
+	return &SymbolControl{Action: action, Symbol: strings.ToUpper(result[1])}, nil
+}
+
+// RequeueSymbolControl puts request back at the head of its list, for a
+// request that could not be applied yet
+func (s *RedisStore) RequeueSymbolControl(ctx context.Context, request *SymbolControl) error {
+	if err := s.client.LPush(ctx, s.symbolControlKey(request.Action), request.Symbol).Err(); err != nil {
+		return fmt.Errorf("failed to requeue symbol request: %w", err)
+	}
+	return nil
+}
+
+// symbolOverridesKey returns the hash of symbols added and removed at
+// runtime, keyed by upper-case symbol with the last action as value
+func (s *RedisStore) symbolOverridesKey() string {
+	return fmt.Sprintf("%scontrol:overrides", s.config.Redis.KeyPrefix)
+}
+
+// AddTrackedSymbol adds symbol to the set of tracked symbols ahead of its
+// first trade, and records it as added so discovery keeps it
+func (s *RedisStore) AddTrackedSymbol(ctx context.Context, symbol string) error {
+	return s.trackSymbol(ctx, symbol, SymbolControlAdd)
+}
+
+// RemoveTrackedSymbol removes symbol from the set of tracked symbols, and
+// records it as removed so discovery drops it; its trades and candles are
+// kept
+func (s *RedisStore) RemoveTrackedSymbol(ctx context.Context, symbol string) error {
+	return s.trackSymbol(ctx, symbol, SymbolControlRemove)
+}
+
+// trackSymbol applies action to the tracked symbols and its override in one
+// round trip
+func (s *RedisStore) trackSymbol(ctx context.Context, symbol, action string) error {
+	symbol = strings.ToUpper(symbol)
+	symbolsKey := fmt.Sprintf("%ssymbols", s.config.Redis.KeyPrefix)
+
+	pipe := s.client.Pipeline()
+	if action == SymbolControlAdd {
+		pipe.SAdd(ctx, symbolsKey, symbol)
+	} else {
+		pipe.SRem(ctx, symbolsKey, symbol)
+	}
+	pipe.HSet(ctx, s.symbolOverridesKey(), symbol, action)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to %s %s: %w", strings.TrimSuffix(action, "_symbol"), symbol, err)
+	}
+	return nil
+}
+
+// SymbolOverrides returns the symbols added and removed at runtime, keyed by
+// upper-case symbol with SymbolControlAdd or SymbolControlRemove as value
+func (s *RedisStore) SymbolOverrides(ctx context.Context) (map[string]string, error) {
+	overrides, err := s.client.HGetAll(ctx, s.symbolOverridesKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read symbol overrides: %w", err)
+	}
+	return overrides, nil
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRedisStore_SymbolControl(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.RequestSymbolAdd(ctx, "solusdt", "XRPUSDT"); err != nil {
+		t.Fatalf("RequestSymbolAdd failed: %v", err)
+	}
+	if err := store.RequestSymbolRemove(ctx, "ETHUSDT"); err != nil {
+		t.Fatalf("RequestSymbolRemove failed: %v", err)
+	}
+	if !mr.Exists("test:control:add_symbol") || !mr.Exists("test:control:remove_symbol") {
+		t.Fatal("Expected the requests in test:control:add_symbol and test:control:remove_symbol")
+	}
+
+	// Additions are listed first and each list pops in request order
+	want := []SymbolControl{
+		{Action: SymbolControlAdd, Symbol: "SOLUSDT"},
+		{Action: SymbolControlAdd, Symbol: "XRPUSDT"},
+		{Action: SymbolControlRemove, Symbol: "ETHUSDT"},
+	}
+	for _, w := range want {
+		got, err := store.PopSymbolControl(ctx, time.Second)
+		if err != nil {
+			t.Fatalf("PopSymbolControl failed: %v", err)
+		}
+		if got == nil || *got != w {
+			t.Errorf("PopSymbolControl() = %v, want %v", got, w)
+		}
+	}
+
+	got, err := store.PopSymbolControl(ctx, time.Second)
+	if err != nil || got != nil {
+		t.Errorf("Expected no request after the timeout, got %v, %v", got, err)
+	}
+}
+
+func TestRedisStore_TrackedSymbols(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.AddTrackedSymbol(ctx, "solusdt"); err != nil {
+		t.Fatalf("AddTrackedSymbol failed: %v", err)
+	}
+	if ok, _ := mr.SIsMember("test:symbols", "SOLUSDT"); !ok {
+		t.Error("Expected SOLUSDT tracked")
+	}
+	if err := store.RemoveTrackedSymbol(ctx, "SOLUSDT"); err != nil {
+		t.Fatalf("RemoveTrackedSymbol failed: %v", err)
+	}
+	if ok, _ := mr.SIsMember("test:symbols", "SOLUSDT"); ok {
+		t.Error("Expected SOLUSDT untracked")
+	}
+	if err := store.AddTrackedSymbol(ctx, "XRPUSDT"); err != nil {
+		t.Fatalf("AddTrackedSymbol failed: %v", err)
+	}
+
+	// The last action on each symbol is kept for other replicas and restarts
+	overrides, err := store.SymbolOverrides(ctx)
+	if err != nil {
+		t.Fatalf("SymbolOverrides failed: %v", err)
+	}
+	if len(overrides) != 2 || overrides["SOLUSDT"] != SymbolControlRemove || overrides["XRPUSDT"] != SymbolControlAdd {
+		t.Errorf("Unexpected overrides %v", overrides)
+	}
+}
+
+func TestRedisStore_RequeueSymbolControl(t *testing.T) {
+	store, mr, err := setupTestRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.RequestSymbolAdd(ctx, "BTCUSDT", "ETHUSDT"); err != nil {
+		t.Fatal(err)
+	}
+	first, err := store.PopSymbolControl(ctx, time.Second)
+	if err != nil || first == nil {
+		t.Fatalf("PopSymbolControl() = %v, %v", first, err)
+	}
+
+	// A requeued request is the next one popped
+	if err := store.RequeueSymbolControl(ctx, first); err != nil {
+		t.Fatalf("RequeueSymbolControl failed: %v", err)
+	}
+	again, err := store.PopSymbolControl(ctx, time.Second)
+	if err != nil || again == nil || *again != *first {
+		t.Errorf("Expected %+v popped again, got %+v, %v", first, again, err)
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"binance-redis-streamer/internal/models"
+)
+
+// miniTickerTTL is how long a symbol's last mini ticker is kept without
+// updates; Binance pushes one every second while the symbol trades
+const miniTickerTTL = 5 * time.Minute
+
+// miniTickerKey returns the key of a symbol's last mini ticker
+func (s *RedisStore) miniTickerKey(symbol string) string {
+	return fmt.Sprintf("%s%s:ticker", s.config.Redis.KeyPrefix, strings.ToUpper(symbol))
+}
+
+// StoreMiniTicker keeps ticker as its symbol's latest 24h statistics
+func (s *RedisStore) StoreMiniTicker(ctx context.Context, ticker *models.MiniTicker) error {
+	key := s.miniTickerKey(ticker.Symbol)
+	pipe := s.client.Pipeline()
+	pipe.HSet(ctx, key,
+		"event_time", ticker.EventTime,
+		"close", ticker.ClosePrice,
+		"open", ticker.OpenPrice,
+		"high", ticker.HighPrice,
+		"low", ticker.LowPrice,
+		"volume", ticker.Volume,
+		"quote_volume", ticker.QuoteVolume,
+	)
+	pipe.Expire(ctx, key, miniTickerTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store mini ticker: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// GetMiniTicker returns the latest mini ticker of symbol, or ErrNotFound when
+// none was stored in the last miniTickerTTL
+func (s *RedisStore) GetMiniTicker(ctx context.Context, symbol string) (*models.MiniTicker, error) {
+	fields, err := s.client.HGetAll(ctx, s.miniTickerKey(symbol)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get mini ticker: %w: %w", ErrUnavailable, err)
+	}
+	if len(fields) == 0 {
+		return nil, ErrNotFound
+	}
+
+	ticker := &models.MiniTicker{
+		Symbol:      strings.ToUpper(symbol),
+		ClosePrice:  fields["close"],
+		OpenPrice:   fields["open"],
+		HighPrice:   fields["high"],
+		LowPrice:    fields["low"],
+		Volume:      fields["volume"],
+		QuoteVolume: fields["quote_volume"],
+	}
+	ticker.EventTime, _ = strconv.ParseInt(fields["event_time"], 10, 64)
+	return ticker, nil
+}
@@ -0,0 +1,183 @@
+// Package symbolutil normalizes and validates the trading pair symbols
+// accepted by CLI commands and API endpoints.
+package symbolutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"binance-redis-streamer/pkg/exchange"
+)
+
+const (
+	// MaxLength is the longest symbol accepted; Binance symbols are at most
+	// 20 characters
+	MaxLength = 20
+	// maxSuggestions is how many near matches an UnknownSymbolError lists
+	maxSuggestions = 3
+	// maxSuggestionDistance is the largest edit distance of a near match.
+	// Pairs such as ETHUSDT and BTCUSDT are only two edits apart.
+	maxSuggestionDistance = 1
+	// minPrefixLength is the shortest input suggested by prefix alone, e.g.
+	// BTC for BTCUSDT
+	minPrefixLength = 3
+)
+
+// separators are stripped from input, so btc/usdt, BTC-USDT and btc_usdt
+// all name BTCUSDT
+const separators = "/-_:. "
+
+// UnknownSymbolError reports a well-formed symbol that is not among the
+// known ones, with the closest known symbols. It matches
+// exchange.ErrBadSymbol with errors.Is.
+type UnknownSymbolError struct {
+	Symbol      string
+	Suggestions []string // Closest known symbols, best first
+}
+
+func (e *UnknownSymbolError) Error() string {
+	msg := fmt.Sprintf("unknown symbol %q", e.Symbol)
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf(" (did you mean %s?)", strings.Join(e.Suggestions, ", "))
+	}
+	return msg
+}
+
+func (e *UnknownSymbolError) Unwrap() error {
+	return exchange.ErrBadSymbol
+}
+
+// Normalize returns input as an exchange symbol: upper-cased and with
+// separators removed. Anything but letters and digits, or a symbol longer
+// than MaxLength, is an error wrapping exchange.ErrBadSymbol.
+func Normalize(input string) (string, error) {
+	symbol := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(separators, r) {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(input)))
+
+	if symbol == "" {
+		return "", fmt.Errorf("%w: empty symbol", exchange.ErrBadSymbol)
+	}
+	if len(symbol) > MaxLength {
+		return "", fmt.Errorf("%w: %q is longer than %d characters", exchange.ErrBadSymbol, input, MaxLength)
+	}
+	for _, r := range symbol {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return "", fmt.Errorf("%w: %q may only contain letters and digits", exchange.ErrBadSymbol, input)
+		}
+	}
+	return symbol, nil
+}
+
+// Validate normalizes input and checks it against known symbols, which may
+// be in any case. A symbol that is not known returns *UnknownSymbolError.
+func Validate(input string, known []string) (string, error) {
+	symbol, err := Normalize(input)
+	if err != nil {
+		return "", err
+	}
+	for _, k := range known {
+		if strings.EqualFold(k, symbol) {
+			return symbol, nil
+		}
+	}
+	return "", &UnknownSymbolError{Symbol: symbol, Suggestions: Suggest(symbol, known)}
+}
+
+// Suggest returns the known symbols closest to symbol, upper-cased: those
+// within a small edit distance or starting with symbol. They are ranked by
+// edit distance, then by the length of the shared prefix, then
+// alphabetically.
+func Suggest(symbol string, known []string) []string {
+	symbol = strings.ToUpper(symbol)
+
+	type candidate struct {
+		symbol   string
+		distance int
+		prefix   int
+	}
+	seen := make(map[string]bool)
+	var candidates []candidate
+	for _, k := range known {
+		k = strings.ToUpper(k)
+		if k == symbol || seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		distance := editDistance(symbol, k)
+		if distance > maxSuggestionDistance &&
+			(len(symbol) < minPrefixLength || !strings.HasPrefix(k, symbol)) {
+			continue
+		}
+		candidates = append(candidates, candidate{k, distance, commonPrefix(symbol, k)})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.prefix != b.prefix {
+			return a.prefix > b.prefix
+		}
+		return a.symbol < b.symbol
+	})
+
+	if len(candidates) > maxSuggestions {
+		candidates = candidates[:maxSuggestions]
+	}
+	suggestions := make([]string, len(candidates))
+	for i, c := range candidates {
+		suggestions[i] = c.symbol
+	}
+	return suggestions
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and swaps of adjacent characters turning a into b, so BTCUDST is one edit
+// from BTCUSDT
+func editDistance(a, b string) int {
+	// d[i][j] is the distance between a[:i] and b[:j]
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = d[i-1][j-1] + cost
+			if d[i-1][j]+1 < d[i][j] {
+				d[i][j] = d[i-1][j] + 1
+			}
+			if d[i][j-1]+1 < d[i][j] {
+				d[i][j] = d[i][j-1] + 1
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// commonPrefix returns the length of the prefix a and b share
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
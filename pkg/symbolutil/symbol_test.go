@@ -0,0 +1,109 @@
+package symbolutil
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"binance-redis-streamer/pkg/exchange"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: "BTCUSDT", want: "BTCUSDT"},
+		{in: "btcusdt", want: "BTCUSDT"},
+		{in: "btc/usdt", want: "BTCUSDT"},
+		{in: "BTC-USDT", want: "BTCUSDT"},
+		{in: " eth_btc ", want: "ETHBTC"},
+		{in: "1000SATS:USDT", want: "1000SATSUSDT"},
+
+		{in: "", wantErr: "empty symbol"},
+		{in: " / ", wantErr: "empty symbol"},
+		{in: "BTC$USDT", wantErr: "only contain letters and digits"},
+		{in: "btc*", wantErr: "only contain letters and digits"},
+		{in: "ÄBCUSDT", wantErr: "only contain letters and digits"},
+		{in: strings.Repeat("A", MaxLength+1), wantErr: "longer than 20 characters"},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Normalize(%q): expected error containing %q, got %v", tt.in, tt.wantErr, err)
+			}
+			if !errors.Is(err, exchange.ErrBadSymbol) {
+				t.Errorf("Normalize(%q): expected ErrBadSymbol, got %v", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	known := []string{"btcusdt", "ethusdt", "ETHBTC"}
+
+	if got, err := Validate("btc/usdt", known); err != nil || got != "BTCUSDT" {
+		t.Errorf("Expected BTCUSDT, got %q, %v", got, err)
+	}
+	if got, err := Validate("ethbtc", known); err != nil || got != "ETHBTC" {
+		t.Errorf("Expected ETHBTC, got %q, %v", got, err)
+	}
+
+	_, err := Validate("BTCUSD", known)
+	var unknown *UnknownSymbolError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("Expected *UnknownSymbolError, got %v", err)
+	}
+	if unknown.Symbol != "BTCUSD" || !reflect.DeepEqual(unknown.Suggestions, []string{"BTCUSDT"}) {
+		t.Errorf("Unexpected error details: %+v", unknown)
+	}
+	if !errors.Is(err, exchange.ErrBadSymbol) {
+		t.Error("Expected the error to match ErrBadSymbol")
+	}
+	if want := `unknown symbol "BTCUSD" (did you mean BTCUSDT?)`; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+
+	_, err = Validate("XYZ", known)
+	if err == nil || err.Error() != `unknown symbol "XYZ"` {
+		t.Errorf("Expected an error without suggestions, got %v", err)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	known := []string{"BTCUSDT", "BTCFDUSD", "BTCUSDC", "ETHUSDT", "ETHBTC", "OMUSDC", "OPUSDT", "SOLUSDT", "btcusdt"}
+
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		// Equally close symbols rank by shared prefix, then alphabetically
+		{"BTCUSD", []string{"BTCUSDC", "BTCUSDT"}},
+		{"OPUSDC", []string{"OPUSDT", "OMUSDC"}},
+		{"ETHUSTD", []string{"ETHUSDT"}}, // Swapped letters are one edit
+		{"btcusdc", []string{"BTCUSDT"}}, // Known symbols are not suggested for themselves
+		// Prefixes reach past the edit distance, closest first
+		{"ETH", []string{"ETHBTC", "ETHUSDT"}},
+		{"SO", nil},
+		{"BNBUSDT", nil}, // Other base assets are more than an edit away
+		{"DOGEUSDT", nil},
+	}
+
+	for _, tt := range tests {
+		got := Suggest(tt.in, known)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Suggest(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
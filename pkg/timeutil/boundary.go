@@ -0,0 +1,67 @@
+package timeutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBoundaryOffset bounds day boundaries to the offsets of real time zones
+const maxBoundaryOffset = 14 * time.Hour
+
+// ParseDayBoundary parses where days begin as a UTC offset: UTC for
+// midnight UTC, or UTC+8, UTC-5 and UTC+5:30 for midnight at that offset.
+// It returns the offset east of UTC.
+func ParseDayBoundary(s string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(strings.ToUpper(strings.TrimSpace(s)), "UTC")
+	if !ok {
+		return 0, fmt.Errorf("invalid day boundary %q: use UTC or an offset such as UTC+8, UTC-5 or UTC+5:30", s)
+	}
+	if rest == "" {
+		return 0, nil
+	}
+
+	sign := time.Duration(1)
+	switch rest[0] {
+	case '+':
+	case '-':
+		sign = -1
+	default:
+		return 0, fmt.Errorf("invalid day boundary %q: use UTC or an offset such as UTC+8, UTC-5 or UTC+5:30", s)
+	}
+
+	hours, minutes, hasMinutes := strings.Cut(rest[1:], ":")
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || strings.HasPrefix(hours, "+") {
+		return 0, fmt.Errorf("invalid day boundary %q: use UTC or an offset such as UTC+8, UTC-5 or UTC+5:30", s)
+	}
+	m := 0
+	if hasMinutes {
+		if m, err = strconv.Atoi(minutes); err != nil || len(minutes) != 2 || m < 0 || m >= 60 {
+			return 0, fmt.Errorf("invalid day boundary %q: minutes must be 00-59", s)
+		}
+	}
+
+	offset := sign * (time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+	if offset < -maxBoundaryOffset || offset > maxBoundaryOffset {
+		return 0, fmt.Errorf("invalid day boundary %q: offset must be within 14 hours of UTC", s)
+	}
+	return offset, nil
+}
+
+// FormatDayBoundary renders offset as ParseDayBoundary accepts it, e.g. UTC+8
+func FormatDayBoundary(offset time.Duration) string {
+	if offset == 0 {
+		return "UTC"
+	}
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	h, m := offset/time.Hour, (offset%time.Hour)/time.Minute
+	if m == 0 {
+		return fmt.Sprintf("UTC%s%d", sign, h)
+	}
+	return fmt.Sprintf("UTC%s%d:%02d", sign, h, m)
+}
@@ -0,0 +1,63 @@
+package timeutil
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDayBoundary(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr string
+	}{
+		{in: "UTC", want: 0},
+		{in: " utc ", want: 0},
+		{in: "UTC+0", want: 0},
+		{in: "UTC+8", want: 8 * time.Hour},
+		{in: "utc-5", want: -5 * time.Hour},
+		{in: "UTC+5:30", want: 5*time.Hour + 30*time.Minute},
+		{in: "UTC-9:30", want: -(9*time.Hour + 30*time.Minute)},
+		{in: "UTC+14", want: 14 * time.Hour},
+
+		{in: "", wantErr: "invalid day boundary"},
+		{in: "GMT+8", wantErr: "invalid day boundary"},
+		{in: "UTC8", wantErr: "invalid day boundary"},
+		{in: "UTC+", wantErr: "invalid day boundary"},
+		{in: "UTC++8", wantErr: "invalid day boundary"},
+		{in: "UTC+8:3", wantErr: "minutes must be 00-59"},
+		{in: "UTC+8:60", wantErr: "minutes must be 00-59"},
+		{in: "UTC+15", wantErr: "within 14 hours"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDayBoundary(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseDayBoundary(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDayBoundary(%q) returned error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDayBoundary(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDayBoundary(t *testing.T) {
+	for _, in := range []string{"UTC", "UTC+8", "UTC-5", "UTC+5:30", "UTC-9:30"} {
+		offset, err := ParseDayBoundary(in)
+		if err != nil {
+			t.Fatalf("ParseDayBoundary(%q) returned error: %v", in, err)
+		}
+		if got := FormatDayBoundary(offset); got != in {
+			t.Errorf("FormatDayBoundary(%v) = %q, want %q", offset, got, in)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Package timeutil parses the look-back periods and day boundaries accepted
+// by CLI commands.
+package timeutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// Forms lists the accepted duration forms for help and error messages
+const Forms = "a positive number followed by s, m, h, d (days), w (weeks) or mo (months), e.g. 30m, 24h, 7d, 2w, 3mo"
+
+// ParseDuration parses a look-back period ending now. Besides Go durations
+// such as 90m or 1h30m it accepts whole days (7d), weeks (2w) and calendar
+// months (3mo). The result must be positive and, when max is non-zero, at
+// most max.
+func ParseDuration(s string, max time.Duration) (time.Duration, error) {
+	return ParseDurationAt(s, time.Now(), max)
+}
+
+// ParseDurationAt is ParseDuration with months counted back from end, so a
+// month is as long as the calendar month it spans
+func ParseDurationAt(s string, end time.Time, max time.Duration) (time.Duration, error) {
+	d, err := parse(strings.ToLower(strings.TrimSpace(s)), end)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: use %s", s, Forms)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q: must be positive", s)
+	}
+	if max > 0 && d > max {
+		return 0, fmt.Errorf("duration %q exceeds the maximum of %s", s, Format(max))
+	}
+	return d, nil
+}
+
+// parse converts a lower-cased duration to its length ending at end
+func parse(s string, end time.Time) (time.Duration, error) {
+	switch {
+	case strings.HasSuffix(s, "mo"):
+		n, err := count(strings.TrimSuffix(s, "mo"))
+		if err != nil {
+			return 0, err
+		}
+		return end.Sub(end.AddDate(0, -n, 0)), nil
+	case strings.HasSuffix(s, "w"):
+		n, err := count(strings.TrimSuffix(s, "w"))
+		return time.Duration(n) * week, err
+	case strings.HasSuffix(s, "d"):
+		n, err := count(strings.TrimSuffix(s, "d"))
+		return time.Duration(n) * day, err
+	default:
+		return time.ParseDuration(s)
+	}
+}
+
+// count parses the whole number in front of a day, week or month suffix
+func count(s string) (int, error) {
+	if s == "" || strings.HasPrefix(s, "+") {
+		return 0, fmt.Errorf("missing count")
+	}
+	return strconv.Atoi(s)
+}
+
+// Format renders d in whole days when it has no smaller part, like 90d,
+// and as a Go duration otherwise
+func Format(d time.Duration) string {
+	if d >= day && d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
@@ -0,0 +1,88 @@
+package timeutil
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDurationAt(t *testing.T) {
+	end := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		in      string
+		max     time.Duration
+		want    time.Duration
+		wantErr string
+	}{
+		{in: "30s", want: 30 * time.Second},
+		{in: "15m", want: 15 * time.Minute},
+		{in: "24h", want: 24 * time.Hour},
+		{in: "1h30m", want: 90 * time.Minute},
+		{in: "7d", want: 7 * day},
+		{in: "7D", want: 7 * day},
+		{in: " 2w ", want: 14 * day},
+		// Months follow the calendar: Mar 31 minus a month normalises Feb 31 to
+		// Mar 2, and Dec 31 to Mar 31 spans 91 days
+		{in: "1mo", want: 29 * day},
+		{in: "3mo", want: 91 * day},
+		{in: "1MO", want: 29 * day},
+
+		{in: "90d", max: 90 * day, want: 90 * day},
+		{in: "91d", max: 90 * day, wantErr: "exceeds the maximum of 90d"},
+		{in: "3mo", max: 90 * day, wantErr: "exceeds the maximum of 90d"},
+		{in: "2h", max: 90 * time.Minute, wantErr: "exceeds the maximum of 1h30m0s"},
+
+		{in: "0h", wantErr: "must be positive"},
+		{in: "0d", wantErr: "must be positive"},
+		{in: "-1h", wantErr: "must be positive"},
+		{in: "-2w", wantErr: "must be positive"},
+		{in: "", wantErr: "invalid duration"},
+		{in: "d", wantErr: "invalid duration"},
+		{in: "mo", wantErr: "invalid duration"},
+		{in: "1.5d", wantErr: "invalid duration"},
+		{in: "+3d", wantErr: "invalid duration"},
+		{in: "7days", wantErr: "invalid duration"},
+		{in: "1y", wantErr: "invalid duration"},
+		{in: "abc", wantErr: "invalid duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDurationAt(tt.in, end, tt.max)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseDurationAt(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDurationAt(%q) returned error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDurationAt(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuration_ErrorListsForms(t *testing.T) {
+	_, err := ParseDuration("soon", 0)
+	if err == nil || !strings.Contains(err.Error(), Forms) {
+		t.Errorf("Expected the accepted forms in the error, got %v", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[time.Duration]string{
+		90 * day:         "90d",
+		day:              "1d",
+		36 * time.Hour:   "36h0m0s",
+		15 * time.Minute: "15m0s",
+	}
+	for d, want := range tests {
+		if got := Format(d); got != want {
+			t.Errorf("Format(%s) = %s, want %s", d, got, want)
+		}
+	}
+}
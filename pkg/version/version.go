@@ -0,0 +1,6 @@
+// Package version holds the release version of the binaries
+package version
+
+// Version is the release version, kept in step with project.json by
+// cmd/version --bump
+const Version = "1.1.5"